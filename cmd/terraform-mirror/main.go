@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -14,8 +16,8 @@ import (
 	"github.com/ned1313/terraform-mirror/internal/cache"
 	"github.com/ned1313/terraform-mirror/internal/config"
 	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/logging"
 	"github.com/ned1313/terraform-mirror/internal/server"
-	"github.com/ned1313/terraform-mirror/internal/storage"
 	"github.com/ned1313/terraform-mirror/internal/version"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -26,6 +28,10 @@ func main() {
 		switch os.Args[1] {
 		case "healthcheck":
 			os.Exit(runHealthCheck())
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "import":
+			os.Exit(runImport(os.Args[2:]))
 		case "version":
 			fmt.Printf("Terraform Mirror %s (built %s, commit %s)\n",
 				version.Version, version.BuildTime, version.GitCommit)
@@ -37,7 +43,7 @@ func main() {
 		version.Version, version.BuildTime, version.GitCommit)
 
 	// Parse command line flags
-	configPath := flag.String("config", "", "Path to configuration file (HCL)")
+	configPath := flag.String("config", "", "Path to an HCL configuration file or a directory of *.hcl files (default $TFM_CONFIG)")
 	flag.Parse()
 
 	// Load configuration
@@ -46,6 +52,13 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Keep recent log output in memory for the admin API
+	var logBuffer *logging.RingBuffer
+	if cfg.Logging.BufferEntries > 0 {
+		logBuffer = logging.NewRingBuffer(cfg.Logging.BufferEntries)
+		log.SetOutput(io.MultiWriter(log.Writer(), logBuffer))
+	}
+
 	log.Printf("Configuration loaded successfully")
 	log.Printf("Server will listen on port %d", cfg.Server.Port)
 	log.Printf("Storage: %s (bucket: %s)", cfg.Storage.Type, cfg.Storage.Bucket)
@@ -53,12 +66,18 @@ func main() {
 	log.Printf("Cache: %dMB memory, %dGB disk", cfg.Cache.MemorySizeMB, cfg.Cache.DiskSizeGB)
 
 	// Initialize database
-	db, err := database.New(cfg.Database.Path)
+	db, err := database.NewWithOptions(cfg.Database.Path, databaseOptions(&cfg.Database))
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.Close()
 
+	// Fail fast if the database cannot be written
+	ctx := context.Background()
+	if err := checkDatabase(ctx, db); err != nil {
+		log.Fatalf("Startup check failed: %v", err)
+	}
+
 	// Create initial admin user from environment variables if provided
 	adminUsername := os.Getenv("TFM_ADMIN_USERNAME")
 	adminPassword := os.Getenv("TFM_ADMIN_PASSWORD")
@@ -74,29 +93,27 @@ func main() {
 		}
 	}
 
-	// Initialize storage
-	ctx := context.Background()
-
 	// Construct base URL for local storage to serve files via HTTP
 	var storageBaseURL string
 	if cfg.Storage.Type == "local" {
-		scheme := "http"
-		if cfg.Server.TLSEnabled {
-			scheme = "https"
+		// Requests derive blob URLs from their own host when public_url is
+		// unset; this base URL only covers URLs built outside a request
+		if cfg.Server.PublicURL == "" {
+			log.Printf("server.public_url is not set; blob URLs will use the request host")
 		}
-		// Use localhost for local development; in production this should be configured
-		storageBaseURL = fmt.Sprintf("%s://localhost:%d", scheme, cfg.Server.Port)
+		storageBaseURL = cfg.Server.GetPublicURL()
 	}
 
-	store, err := storage.NewFromConfigWithBaseURL(ctx, cfg.Storage, storageBaseURL)
+	// Initialize storage, checking its credentials before serving
+	store, err := openStorage(ctx, cfg.Storage, storageBaseURL)
 	if err != nil {
-		log.Fatalf("Failed to initialize storage: %v", err)
+		log.Fatalf("Startup check failed: %v", err)
 	}
 	defer store.Close()
 
 	// Initialize cache
 	var cacheInstance cache.Cache
-	if cfg.Cache.MemorySizeMB > 0 || (cfg.Cache.DiskPath != "" && cfg.Cache.DiskSizeGB > 0) {
+	if cfg.Cache.IsEnabled() {
 		cacheInstance, err = cache.NewFromConfig(cfg.Cache)
 		if err != nil {
 			log.Printf("Warning: Failed to initialize cache, running without cache: %v", err)
@@ -112,10 +129,16 @@ func main() {
 
 	// Initialize server
 	srv := server.NewWithCache(cfg, db, store, cacheInstance)
+	if logBuffer != nil {
+		srv.SetLogBuffer(logBuffer)
+	}
+
+	log.Printf("Ready: storage=%s database=%s port=%d",
+		cfg.Storage.Type, cfg.Database.Path, cfg.Server.Port)
 
 	// Start server in goroutine
 	go func() {
-		if err := srv.Start(); err != nil {
+		if err := srv.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("Server failed: %v", err)
 		}
 	}()
@@ -163,3 +186,12 @@ func runHealthCheck() int {
 
 	return 0
 }
+
+// databaseOptions maps the database config onto SQLite connection options
+func databaseOptions(cfg *config.DatabaseConfig) database.Options {
+	return database.Options{
+		BusyTimeout:  cfg.GetBusyTimeout(),
+		Synchronous:  cfg.Synchronous,
+		MaxOpenConns: cfg.MaxOpenConns,
+	}
+}
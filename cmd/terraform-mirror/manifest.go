@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ned1313/terraform-mirror/internal/config"
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/manifest"
+)
+
+// runExport writes a manifest of everything in the mirror to a file
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to an HCL configuration file or a directory of *.hcl files (default $TFM_CONFIG)")
+	output := fs.String("output", "mirror-manifest.json", "Manifest file to write (- for stdout)")
+	fs.Parse(args)
+
+	db, err := openDatabase(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	m, err := manifest.Export(context.Background(), db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		return 1
+	}
+
+	out := os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := m.Write(out); err != nil {
+		fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		return 1
+	}
+
+	if *output != "-" {
+		fmt.Printf("Exported %d providers and %d modules to %s\n", len(m.Providers), len(m.Modules), *output)
+	}
+	return 0
+}
+
+// runImport reads a manifest and creates download jobs for its entries.
+// The jobs are processed by the background processor of a running server.
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to an HCL configuration file or a directory of *.hcl files (default $TFM_CONFIG)")
+	input := fs.String("input", "mirror-manifest.json", "Manifest file to read")
+	fs.Parse(args)
+
+	f, err := os.Open(*input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+
+	m, err := manifest.Read(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		return 1
+	}
+
+	db, err := openDatabase(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	result, err := manifest.Import(context.Background(), db, m, sql.NullInt64{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Queued %d provider items (job %d) and %d module items (job %d)\n",
+		result.ProviderItems, result.ProviderJobID, result.ModuleItems, result.ModuleJobID)
+	return 0
+}
+
+// openDatabase loads the configuration and opens the database it points to
+func openDatabase(configPath string) (*database.DB, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	db, err := database.NewWithOptions(cfg.Database.Path, databaseOptions(&cfg.Database))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/config"
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+)
+
+// startupCheckTimeout bounds each readiness check run before serving
+const startupCheckTimeout = 15 * time.Second
+
+// startupSentinelKey is probed to confirm storage credentials and
+// permissions. The object does not need to exist.
+const startupSentinelKey = "diagnostics/startup-check"
+
+// openStorage creates the configured storage backend and checks that it is
+// usable, so bad credentials fail at startup rather than on first download
+func openStorage(ctx context.Context, cfg config.StorageConfig, baseURL string) (storage.Storage, error) {
+	store, err := storage.NewFromConfigWithBaseURL(ctx, cfg, baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	if err := checkStorage(ctx, store); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("storage (%s) is not usable, check its credentials and permissions: %w", cfg.Type, err)
+	}
+	return store, nil
+}
+
+// checkStorage looks up the sentinel key; a missing object is fine, an
+// error means storage cannot be reached or read
+func checkStorage(ctx context.Context, store storage.Storage) error {
+	ctx, cancel := context.WithTimeout(ctx, startupCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := store.Exists(ctx, startupSentinelKey); err != nil {
+		return err
+	}
+	log.Printf("Startup check passed: storage (%s)", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// checkDatabase confirms the database accepts writes
+func checkDatabase(ctx context.Context, db *database.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, startupCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := db.CheckReadWrite(ctx); err != nil {
+		return fmt.Errorf("database is not writable: %w", err)
+	}
+	log.Printf("Startup check passed: database (%s)", time.Since(start).Round(time.Millisecond))
+	return nil
+}
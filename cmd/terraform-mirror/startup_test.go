@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/config"
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenStorage_RejectsUnusableStorage(t *testing.T) {
+	// An S3 endpoint that refuses every credential
+	denied := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer denied.Close()
+
+	store, err := openStorage(context.Background(), config.StorageConfig{
+		Type:           "s3",
+		Bucket:         "mirror",
+		Region:         "us-east-1",
+		Endpoint:       denied.URL,
+		AccessKey:      "wrong",
+		SecretKey:      "wrong",
+		ForcePathStyle: true,
+	}, "")
+	require.Error(t, err)
+	assert.Nil(t, store)
+	assert.Contains(t, err.Error(), "storage (s3) is not usable")
+}
+
+func TestOpenStorage_Local(t *testing.T) {
+	store, err := openStorage(context.Background(), config.StorageConfig{
+		Type:     "local",
+		Endpoint: t.TempDir(),
+	}, "")
+	require.NoError(t, err)
+	defer store.Close()
+}
+
+func TestCheckDatabase(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.NoError(t, checkDatabase(context.Background(), db))
+}
@@ -10,6 +10,24 @@ server {
   # Set to true if running behind a reverse proxy
   behind_proxy = false
   trusted_proxies = ["10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"]
+
+  # Externally reachable URL of the mirror. With local storage, provider
+  # download URLs are built from this; when unset the request host is used.
+  # public_url = "https://mirror.example.com"
+
+  # Restrict the admin UI and API to these source ranges (empty = allow all)
+  # admin_allowed_cidrs = ["10.0.0.0/8"]
+
+  # Limit concurrent blob downloads to protect the storage backend (0 = unlimited).
+  # Excess requests wait up to download_queue_timeout_seconds, then get a 503.
+  # max_concurrent_downloads = 50
+  # download_queue_timeout_seconds = 10
+
+  # How long shutdown waits for in-flight blob downloads to finish
+  # shutdown_download_wait_seconds = 120
+
+  # Log blob downloads slower than this, split into storage and client time (0 = off)
+  # slow_download_seconds = 30
 }
 
 storage {
@@ -37,6 +55,11 @@ database {
   backup_interval_hours = 24
   backup_to_s3 = true
   backup_s3_prefix = "backups/"
+
+  # SQLite runs in WAL mode; these tune lock waits and durability
+  # busy_timeout_ms = 5000
+  # synchronous = "NORMAL"
+  # max_open_conns = 8
 }
 
 cache {
@@ -57,6 +80,28 @@ cache {
   # Items older than this are automatically removed
   # Environment variable: TFM_CACHE_TTL_SECONDS
   ttl_seconds = 3600
+
+  # Per-content-type TTLs; 0 uses ttl_seconds
+  # Provider archives never change once mirrored, so they can be kept longer.
+  # Mirror index.json and version JSON change as new versions arrive.
+  # Environment variables: TFM_CACHE_BLOB_TTL_SECONDS, TFM_CACHE_INDEX_TTL_SECONDS
+  blob_ttl_seconds  = 86400
+  index_ttl_seconds = 60
+
+  # Encrypt disk cache entries at rest (AES-GCM). Adds CPU overhead to
+  # every disk cache read and write; leave unset to disable.
+  # Environment variable: TFM_CACHE_DISK_ENCRYPTION_KEY
+  # disk_encryption_key = "change-me-to-a-long-random-secret"
+
+  # Serve an expired cached archive when storage is unavailable, as long as it
+  # expired at most max_stale_seconds ago (default: 1 hour)
+  # Environment variables: TFM_CACHE_SERVE_STALE_ON_ERROR, TFM_CACHE_MAX_STALE_SECONDS
+  # serve_stale_on_error = true
+  # max_stale_seconds    = 3600
+
+  # Store cached mirror index JSON gzip-compressed; archives are cached as is
+  # Environment variable: TFM_CACHE_COMPRESS_INDEX
+  # compress_index = true
 }
 
 features {
@@ -69,6 +114,11 @@ features {
 }
 
 auth {
+  # JWT signing secret (required). To rotate, use
+  # jwt_secrets = ["new-secret", "old-secret"] instead: the first signs new
+  # tokens and all are accepted until removed.
+  jwt_secret = "change-me-to-a-long-random-string"
+
   # JWT token expiration in hours
   jwt_expiration_hours = 8
   
@@ -86,6 +136,9 @@ logging {
   # Output: stdout, stderr, file, both
   output = "stdout"
   file_path = "/var/log/tf-mirror/app.log"
+
+  # Record public provider/module downloads in the audit log (one row per download)
+  # audit_downloads = false
 }
 
 telemetry {
@@ -109,6 +162,18 @@ providers {
   download_retry_attempts = 5
   download_retry_initial_delay_ms = 1000
   download_timeout_seconds = 60
+
+  # Default platforms mirrored when a load, platform fill, or auto-download
+  # does not specify its own
+  platforms = ["linux_amd64", "windows_amd64"]
+
+  # Store a SHA256SUMS document once every platform of a version is
+  # mirrored, and serve it instead of building it per request
+  store_shasums = false
+
+  # Show blocked versions, flagged as blocked, in mirror responses to
+  # requests carrying an admin token
+  include_blocked_for_admins = false
 }
 
 # Storage quota management
@@ -118,6 +183,14 @@ quota {
   warning_threshold_percent = 80
 }
 
+# Scheduled re-sync of mirrored providers and modules
+# Queues downloads of upstream versions newer than those already mirrored
+# Environment variables: TFM_SYNC_ENABLED, TFM_SYNC_SCHEDULE
+sync {
+  enabled = false
+  schedule = "0 3 * * *"  # Cron syntax: minute hour day-of-month month day-of-week
+}
+
 # Auto-download configuration for on-demand provider downloads
 # When enabled, providers not in the cache will be fetched from the upstream registry
 auto_download {
@@ -128,15 +201,26 @@ auto_download {
   # blocked_namespaces takes precedence over allowed_namespaces
   allowed_namespaces = []
   blocked_namespaces = []
+
+  # Specific providers ("namespace/type") to allow or block, checked with the
+  # namespace lists. allowed_providers admits a provider even when its
+  # namespace is not in allowed_namespaces; a block on either list wins.
+  # With allowed_providers set and allowed_namespaces empty, only the listed
+  # providers are downloaded.
+  # allowed_providers = ["hashicorp/aws", "integrations/github"]
+  # blocked_providers = ["hashicorp/null"]
   
   # Platforms to auto-download when a provider is requested
   # When a provider version is requested, it will be downloaded for all these platforms
-  # Default: ["linux_amd64", "windows_amd64"]
-  platforms = ["linux_amd64", "windows_amd64"]
+  # Default: providers.platforms
+  # platforms = ["linux_amd64", "windows_amd64"]
   
   # Rate limiting
   rate_limit_per_minute = 10
   max_concurrent_downloads = 3
+
+  # Maximum background platform downloads waiting for a free download slot;
+  # extra platforms are dropped (and counted) when the queue is full
   queue_size = 100
   timeout_seconds = 300
   
@@ -146,4 +230,9 @@ auto_download {
   # Cache negative (not found) results to avoid repeated upstream requests
   cache_negative_results = true
   negative_cache_ttl_seconds = 300
+
+  # Store at most this many versions of each provider, counting those already
+  # mirrored; a cold request also pulls the newest releases up to the cap.
+  # 0 (the default) fetches only requested versions
+  # max_versions_per_provider = 5
 }
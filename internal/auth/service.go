@@ -12,7 +12,9 @@ import (
 
 // Service handles authentication operations
 type Service struct {
-	jwtSecret     []byte
+	// jwtSecrets are the accepted signing secrets; the first one signs new
+	// tokens and every one verifies, so a rotation has a grace period
+	jwtSecrets    [][]byte
 	jwtExpiration time.Duration
 	bcryptCost    int
 }
@@ -24,10 +26,20 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// NewService creates a new authentication service
+// NewService creates a new authentication service with a single JWT secret
 func NewService(jwtSecret string, jwtExpirationHours int, bcryptCost int) *Service {
+	return NewServiceWithSecrets([]string{jwtSecret}, jwtExpirationHours, bcryptCost)
+}
+
+// NewServiceWithSecrets creates a new authentication service that signs
+// tokens with the first secret and accepts tokens signed with any of them
+func NewServiceWithSecrets(jwtSecrets []string, jwtExpirationHours int, bcryptCost int) *Service {
+	secrets := make([][]byte, len(jwtSecrets))
+	for i, secret := range jwtSecrets {
+		secrets[i] = []byte(secret)
+	}
 	return &Service{
-		jwtSecret:     []byte(jwtSecret),
+		jwtSecrets:    secrets,
 		jwtExpiration: time.Duration(jwtExpirationHours) * time.Hour,
 		bcryptCost:    bcryptCost,
 	}
@@ -69,8 +81,12 @@ func (s *Service) GenerateToken(userID int64, username string) (string, string,
 		},
 	}
 
+	if len(s.jwtSecrets) == 0 {
+		return "", "", time.Time{}, fmt.Errorf("no JWT signing secret configured")
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(s.jwtSecret)
+	tokenString, err := token.SignedString(s.jwtSecrets[0])
 	if err != nil {
 		return "", "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -85,7 +101,12 @@ func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return s.jwtSecret, nil
+		// Any configured secret may have signed the token
+		keys := make([]jwt.VerificationKey, len(s.jwtSecrets))
+		for i, secret := range s.jwtSecrets {
+			keys[i] = secret
+		}
+		return jwt.VerificationKeySet{Keys: keys}, nil
 	})
 
 	if err != nil {
@@ -13,7 +13,7 @@ func TestNewService(t *testing.T) {
 	service := NewService("test-secret", 24, 10)
 
 	assert.NotNil(t, service)
-	assert.Equal(t, []byte("test-secret"), service.jwtSecret)
+	assert.Equal(t, [][]byte{[]byte("test-secret")}, service.jwtSecrets)
 	assert.Equal(t, 24*time.Hour, service.jwtExpiration)
 	assert.Equal(t, 10, service.bcryptCost)
 }
@@ -52,7 +52,7 @@ func TestNewService_DifferentConfigs(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			service := NewService(tt.secret, tt.expirationHrs, tt.bcryptCost)
-			assert.Equal(t, []byte(tt.secret), service.jwtSecret)
+			assert.Equal(t, [][]byte{[]byte(tt.secret)}, service.jwtSecrets)
 			assert.Equal(t, tt.wantExpiration, service.jwtExpiration)
 			assert.Equal(t, tt.bcryptCost, service.bcryptCost)
 		})
@@ -234,6 +234,31 @@ func TestValidateToken_InvalidSignature(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestValidateToken_SecretRotation(t *testing.T) {
+	old := NewService("old-secret", 24, 10)
+	token, _, _, err := old.GenerateToken(1, "user")
+	require.NoError(t, err)
+
+	// During the grace period the new secret signs and the old one still verifies
+	rotating := NewServiceWithSecrets([]string{"new-secret", "old-secret"}, 24, 10)
+	claims, err := rotating.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), claims.UserID)
+
+	newToken, _, _, err := rotating.GenerateToken(2, "other")
+	require.NoError(t, err)
+	_, err = old.ValidateToken(newToken)
+	assert.Error(t, err, "new tokens should be signed with the first secret")
+
+	// Once the old secret is removed its tokens stop verifying
+	rotated := NewServiceWithSecrets([]string{"new-secret"}, 24, 10)
+	_, err = rotated.ValidateToken(token)
+	assert.Error(t, err)
+	claims, err = rotated.ValidateToken(newToken)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), claims.UserID)
+}
+
 func TestValidateToken_ExpiredToken(t *testing.T) {
 	// Create service with 0 hour expiration
 	service := NewService("test-secret", 0, 10)
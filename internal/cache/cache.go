@@ -1,6 +1,6 @@
 // Package cache provides caching functionality for terraform-mirror.
-// It includes in-memory LRU cache, disk-based cache, and a two-tier
-// cache coordinator that manages both layers.
+// It includes an in-memory cache with LRU, LFU, or TTL-only eviction, a
+// disk-based cache, and a two-tier cache coordinator that manages both layers.
 package cache
 
 import (
@@ -35,6 +35,14 @@ type Cache interface {
 	Close() error
 }
 
+// StaleCache is implemented by caches that can return entries shortly after
+// they expire, so callers can fall back to them when the origin fails
+type StaleCache interface {
+	// GetStale retrieves an item that is fresh or expired at most maxStale
+	// ago. It does not count toward hits or misses.
+	GetStale(ctx context.Context, key string, maxStale time.Duration) (io.ReadCloser, string, bool)
+}
+
 // CacheStats contains statistics about cache usage
 type CacheStats struct {
 	// Hits is the number of successful cache retrievals
@@ -57,6 +65,10 @@ type CacheStats struct {
 
 	// Expirations is the number of items that expired
 	Expirations int64 `json:"expirations"`
+
+	// EvictionPolicy is the memory eviction policy; empty for caches
+	// without one
+	EvictionPolicy string `json:"eviction_policy,omitempty"`
 }
 
 // CacheItem represents a cached item with metadata
@@ -94,6 +106,11 @@ func (c *CacheItem) IsExpired() bool {
 	return time.Now().After(c.ExpiresAt)
 }
 
+// expiredBefore reports whether expiresAt lies more than grace before now
+func expiredBefore(expiresAt, now time.Time, grace time.Duration) bool {
+	return !expiresAt.IsZero() && now.After(expiresAt.Add(grace))
+}
+
 // Config contains cache configuration
 type Config struct {
 	// MemorySizeMB is the maximum memory cache size in megabytes
@@ -3,9 +3,11 @@ package cache
 import (
 	"bytes"
 	"context"
+	"crypto/cipher"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -13,6 +15,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -32,12 +35,18 @@ type DiskCache struct {
 	// defaultTTL is the default TTL for items
 	defaultTTL time.Duration
 
+	// staleRetention is how long expired entries are kept for GetStale
+	staleRetention time.Duration
+
 	// stats tracks cache statistics
 	stats CacheStats
 
 	// index keeps track of cached items and their metadata
 	index map[string]*diskCacheEntry
 
+	// aead encrypts entry files at rest; nil when encryption is disabled
+	aead cipher.AEAD
+
 	// cleanupTicker for periodic cleanup
 	cleanupTicker *time.Ticker
 	cleanupDone   chan struct{}
@@ -53,6 +62,7 @@ type diskCacheEntry struct {
 	ExpiresAt    time.Time `json:"expires_at"`
 	LastAccessed time.Time `json:"last_accessed"`
 	AccessCount  int64     `json:"access_count"`
+	Encrypted    bool      `json:"encrypted,omitempty"`
 }
 
 // DiskCacheConfig contains configuration for the disk cache
@@ -68,6 +78,15 @@ type DiskCacheConfig struct {
 
 	// CleanupInterval is how often to run cleanup
 	CleanupInterval time.Duration
+
+	// EncryptionKey enables AES-GCM encryption of entry files at rest when set.
+	// Encryption adds CPU cost to every Set and disk read, and entries written
+	// with a different key (or without encryption) are treated as misses.
+	EncryptionKey string
+
+	// StaleRetention keeps expired entries this long so GetStale can still
+	// return them; they still count toward the cache size until removed
+	StaleRetention time.Duration
 }
 
 // NewDiskCache creates a new disk-based cache
@@ -96,11 +115,21 @@ func NewDiskCache(cfg DiskCacheConfig) (*DiskCache, error) {
 
 	maxSize := int64(cfg.MaxSizeGB) * 1024 * 1024 * 1024
 
+	var aead cipher.AEAD
+	if cfg.EncryptionKey != "" {
+		var err error
+		if aead, err = newCacheAEAD(cfg.EncryptionKey); err != nil {
+			return nil, err
+		}
+	}
+
 	dc := &DiskCache{
-		basePath:   cfg.BasePath,
-		maxSize:    maxSize,
-		defaultTTL: cfg.DefaultTTL,
-		index:      make(map[string]*diskCacheEntry),
+		basePath:       cfg.BasePath,
+		maxSize:        maxSize,
+		defaultTTL:     cfg.DefaultTTL,
+		staleRetention: cfg.StaleRetention,
+		index:          make(map[string]*diskCacheEntry),
+		aead:           aead,
 		stats: CacheStats{
 			MaxSize: maxSize,
 		},
@@ -135,7 +164,7 @@ func (dc *DiskCache) cleanupLoop() {
 	}
 }
 
-// removeExpired removes all expired items from the cache
+// removeExpired removes all items expired longer than the stale retention
 func (dc *DiskCache) removeExpired() {
 	dc.mu.Lock()
 	defer dc.mu.Unlock()
@@ -144,7 +173,7 @@ func (dc *DiskCache) removeExpired() {
 	var expired []string
 
 	for key, entry := range dc.index {
-		if !entry.ExpiresAt.IsZero() && now.After(entry.ExpiresAt) {
+		if expiredBefore(entry.ExpiresAt, now, dc.staleRetention) {
 			expired = append(expired, key)
 		}
 	}
@@ -169,20 +198,19 @@ func (dc *DiskCache) Get(ctx context.Context, key string) (io.ReadCloser, string
 		return nil, "", false
 	}
 
-	// Check if expired
-	if !entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt) {
-		dc.removeItemLocked(key)
-		atomic.AddInt64(&dc.stats.Expirations, 1)
+	// Check if expired, keeping entries GetStale may still serve
+	now := time.Now()
+	if expiredBefore(entry.ExpiresAt, now, 0) {
+		if expiredBefore(entry.ExpiresAt, now, dc.staleRetention) {
+			dc.removeItemLocked(key)
+			atomic.AddInt64(&dc.stats.Expirations, 1)
+		}
 		atomic.AddInt64(&dc.stats.Misses, 1)
 		return nil, "", false
 	}
 
-	// Open the file
-	filePath := dc.dataFilePath(entry.Filename)
-	file, err := os.Open(filePath)
-	if err != nil {
-		// File doesn't exist, remove from index
-		dc.removeItemLocked(key)
+	reader, ok := dc.openLocked(key, entry)
+	if !ok {
 		atomic.AddInt64(&dc.stats.Misses, 1)
 		return nil, "", false
 	}
@@ -193,7 +221,56 @@ func (dc *DiskCache) Get(ctx context.Context, key string) (io.ReadCloser, string
 
 	atomic.AddInt64(&dc.stats.Hits, 1)
 
-	return file, entry.ContentType, true
+	return reader, entry.ContentType, true
+}
+
+// GetStale retrieves an item that is fresh or expired at most maxStale ago
+func (dc *DiskCache) GetStale(ctx context.Context, key string, maxStale time.Duration) (io.ReadCloser, string, bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	entry, exists := dc.index[key]
+	if !exists || expiredBefore(entry.ExpiresAt, time.Now(), maxStale) {
+		return nil, "", false
+	}
+
+	reader, ok := dc.openLocked(key, entry)
+	if !ok {
+		return nil, "", false
+	}
+	return reader, entry.ContentType, true
+}
+
+// openLocked opens an entry's data file, removing entries that can no longer
+// be read (must hold lock)
+func (dc *DiskCache) openLocked(key string, entry *diskCacheEntry) (io.ReadCloser, bool) {
+	// Entries written before encryption was enabled or disabled can't be served
+	if entry.Encrypted != (dc.aead != nil) {
+		dc.removeItemLocked(key)
+		return nil, false
+	}
+
+	// Open the file
+	filePath := dc.dataFilePath(entry.Filename)
+	file, err := os.Open(filePath)
+	if err != nil {
+		// File doesn't exist, remove from index
+		dc.removeItemLocked(key)
+		return nil, false
+	}
+
+	if dc.aead == nil {
+		return file, true
+	}
+
+	decrypted, err := newDecryptReader(dc.aead, key, file)
+	if err != nil {
+		// Wrong key or corrupt file
+		file.Close()
+		dc.removeItemLocked(key)
+		return nil, false
+	}
+	return decrypted, true
 }
 
 // Set stores an item in the cache
@@ -222,23 +299,39 @@ func (dc *DiskCache) Set(ctx context.Context, key string, data io.Reader, conten
 		ttl = dc.defaultTTL
 	}
 
+	// Only ciphertext is written to disk; sizes track the on-disk footprint
+	if dc.aead != nil {
+		if dataBytes, err = encryptEntry(dc.aead, key, dataBytes); err != nil {
+			return err
+		}
+		actualSize = int64(len(dataBytes))
+	}
+
 	dc.mu.Lock()
 	defer dc.mu.Unlock()
 
-	// If item already exists, remove it first
-	if existing, exists := dc.index[key]; exists {
-		dc.currentSize -= existing.Size
-		dc.removeFileLocked(existing.Filename)
-	}
+	// If item already exists, remove it first. Its entry goes with the file,
+	// so a failed write below leaves no entry for a missing file and eviction
+	// cannot count its size twice.
+	dc.removeItemLocked(key)
 
 	// Make room if necessary
 	for dc.currentSize+actualSize > dc.maxSize && len(dc.index) > 0 {
 		dc.evictLRU()
 	}
 
-	// Write data to file (ensuring subdirectory exists)
-	if err := dc.writeDataFile(filename, dataBytes); err != nil {
-		return fmt.Errorf("failed to write cache file: %w", err)
+	// Write data to file (ensuring subdirectory exists). If the underlying
+	// disk is full, evict least recently used items and try again.
+	for {
+		err := dc.writeDataFile(filename, dataBytes)
+		if err == nil {
+			break
+		}
+		dc.removeFileLocked(filename)
+		if !errors.Is(err, syscall.ENOSPC) || len(dc.index) == 0 {
+			return fmt.Errorf("failed to write cache file: %w", err)
+		}
+		dc.evictLRU()
 	}
 
 	now := time.Now()
@@ -251,6 +344,7 @@ func (dc *DiskCache) Set(ctx context.Context, key string, data io.Reader, conten
 		ExpiresAt:    now.Add(ttl),
 		LastAccessed: now,
 		AccessCount:  0,
+		Encrypted:    dc.aead != nil,
 	}
 
 	dc.index[key] = entry
@@ -295,6 +389,10 @@ func (dc *DiskCache) Exists(ctx context.Context, key string) bool {
 		return false
 	}
 
+	if entry.Encrypted != (dc.aead != nil) {
+		return false
+	}
+
 	// Verify file exists
 	filePath := dc.dataFilePath(entry.Filename)
 	_, err := os.Stat(filePath)
@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encrypted cache files are a header followed by a sequence of AES-GCM sealed
+// chunks. Every chunk except the last holds exactly encChunkSize plaintext bytes,
+// so a reader can decrypt one chunk at a time without buffering the whole entry.
+//
+//	header: magic (4 bytes) | nonce prefix (7 bytes)
+//	chunk:  ciphertext | tag (16 bytes)
+//
+// Chunk nonces are the prefix, a big-endian chunk counter, and a final-chunk flag,
+// which detects reordered, dropped, or truncated chunks. The cache key is used as
+// additional data so a file cannot be swapped in for a different key.
+const (
+	encMagic        = "TFC1"
+	encPrefixSize   = 7
+	encHeaderSize   = len(encMagic) + encPrefixSize
+	encChunkSize    = 64 * 1024
+	encKeyInfo      = "terraform-mirror disk cache"
+	encMinSecretLen = 16
+)
+
+var errCacheDecrypt = errors.New("failed to decrypt cache entry")
+
+// newCacheAEAD derives an AES-256-GCM cipher from the configured secret
+func newCacheAEAD(secret string) (cipher.AEAD, error) {
+	if len(secret) < encMinSecretLen {
+		return nil, fmt.Errorf("encryption key must be at least %d characters", encMinSecretLen)
+	}
+
+	key, err := hkdf.Key(sha256.New, []byte(secret), nil, encKeyInfo, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce builds the nonce for chunk n
+func chunkNonce(prefix []byte, n uint32, final bool) []byte {
+	nonce := make([]byte, encPrefixSize+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encPrefixSize:], n)
+	if final {
+		nonce[encPrefixSize+4] = 1
+	}
+	return nonce
+}
+
+// encryptEntry seals plaintext into the chunked on-disk format
+func encryptEntry(aead cipher.AEAD, key string, plaintext []byte) ([]byte, error) {
+	prefix := make([]byte, encPrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	chunks := len(plaintext)/encChunkSize + 1
+	out := make([]byte, 0, encHeaderSize+len(plaintext)+chunks*aead.Overhead())
+	out = append(out, encMagic...)
+	out = append(out, prefix...)
+
+	// An exact multiple of the chunk size ends with an empty final chunk
+	for n := 0; ; n++ {
+		end := min((n+1)*encChunkSize, len(plaintext))
+		chunk := plaintext[n*encChunkSize : end]
+		final := end == len(plaintext) && len(chunk) < encChunkSize
+		out = aead.Seal(out, chunkNonce(prefix, uint32(n), final), chunk, []byte(key))
+		if final {
+			return out, nil
+		}
+	}
+}
+
+// decryptReader decrypts an encrypted cache file one chunk at a time
+type decryptReader struct {
+	src    *bufio.Reader
+	closer io.Closer
+	aead   cipher.AEAD
+	key    []byte
+	prefix []byte
+	sealed []byte
+	n      uint32
+	plain  []byte // Decrypted chunk, reused between chunks
+	buf    []byte // Decrypted bytes not yet returned
+	done   bool
+	err    error
+}
+
+// newDecryptReader validates the header and decrypts the first chunk, so a
+// wrong key or corrupt file is reported before any bytes are returned
+func newDecryptReader(aead cipher.AEAD, key string, file io.ReadCloser) (*decryptReader, error) {
+	src := bufio.NewReaderSize(file, encChunkSize+aead.Overhead())
+
+	header := make([]byte, encHeaderSize)
+	if _, err := io.ReadFull(src, header); err != nil || string(header[:len(encMagic)]) != encMagic {
+		return nil, errCacheDecrypt
+	}
+
+	r := &decryptReader{
+		src:    src,
+		closer: file,
+		aead:   aead,
+		key:    []byte(key),
+		prefix: header[len(encMagic):],
+		sealed: make([]byte, encChunkSize+aead.Overhead()),
+	}
+	if err := r.nextChunk(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// nextChunk reads and opens the next sealed chunk
+func (r *decryptReader) nextChunk() error {
+	n, err := io.ReadFull(r.src, r.sealed)
+	final := false
+	switch {
+	case err == io.ErrUnexpectedEOF:
+		final = true
+	case err != nil:
+		// A missing final chunk means the file was truncated
+		return errCacheDecrypt
+	default:
+		if _, peekErr := r.src.Peek(1); peekErr == io.EOF {
+			final = true
+		}
+	}
+
+	plaintext, err := r.aead.Open(r.plain[:0], chunkNonce(r.prefix, r.n, final), r.sealed[:n], r.key)
+	if err != nil {
+		return errCacheDecrypt
+	}
+	r.plain = plaintext
+	r.buf = plaintext
+	r.n++
+	r.done = final
+	return nil
+}
+
+// Read implements io.Reader
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.nextChunk(); err != nil {
+			r.err = err
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Close closes the underlying file
+func (r *decryptReader) Close() error {
+	return r.closer.Close()
+}
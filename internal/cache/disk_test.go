@@ -236,6 +236,60 @@ func TestDiskCache_LRUEviction(t *testing.T) {
 	}
 }
 
+func TestDiskCache_OverwriteWriteFails(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "disk-cache-overwrite-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	cache, err := NewDiskCache(DiskCacheConfig{
+		BasePath:        tempDir,
+		MaxSizeGB:       1,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	ctx := context.Background()
+	other := []byte("other data")
+	if err := cache.Set(ctx, "other", bytes.NewReader(other), "text/plain", int64(len(other)), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	data := []byte("original data")
+	if err := cache.Set(ctx, "key", bytes.NewReader(data), "text/plain", int64(len(data)), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// Put a non-empty directory where the entry's file goes, so removing the
+	// old file and writing the new one both fail
+	path := cache.dataFilePath(cache.hashKey("key"))
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove data file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(path, "blocker"), 0755); err != nil {
+		t.Fatalf("failed to create blocking directory: %v", err)
+	}
+
+	replacement := []byte("replacement data")
+	if err := cache.Set(ctx, "key", bytes.NewReader(replacement), "text/plain", int64(len(replacement)), 0); err == nil {
+		t.Fatal("expected Set to fail")
+	}
+
+	if _, _, found := cache.Get(ctx, "key"); found {
+		t.Error("expected a miss for the key whose overwrite failed")
+	}
+	if got := cache.Stats().Size; got != int64(len(other)) {
+		t.Errorf("expected size %d, got %d", len(other), got)
+	}
+	if got := cache.Stats().ItemCount; got != 1 {
+		t.Errorf("expected 1 item, got %d", got)
+	}
+}
+
 func TestDiskCache_Stats(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "disk-cache-stats-test")
 	if err != nil {
@@ -359,3 +413,129 @@ func TestDiskCache_InvalidBasePath(t *testing.T) {
 		t.Error("expected error for empty base path")
 	}
 }
+
+func TestDiskCache_Encryption(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "disk-cache-encryption-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	newCache := func(key string) *DiskCache {
+		cache, err := NewDiskCache(DiskCacheConfig{
+			BasePath:        tempDir,
+			MaxSizeGB:       1,
+			DefaultTTL:      time.Hour,
+			CleanupInterval: time.Hour,
+			EncryptionKey:   key,
+		})
+		if err != nil {
+			t.Fatalf("failed to create cache: %v", err)
+		}
+		return cache
+	}
+
+	ctx := context.Background()
+	cache := newCache("correct-horse-battery-staple")
+
+	// Span several chunks, including an exact chunk boundary
+	marker := []byte("terraform-provider-secret")
+	entries := map[string][]byte{
+		"empty":    {},
+		"small":    marker,
+		"boundary": bytes.Repeat([]byte("a"), encChunkSize),
+		"large":    append(bytes.Repeat(marker, 10000), 'x'),
+	}
+	for key, data := range entries {
+		if err := cache.Set(ctx, key, bytes.NewReader(data), "application/zip", int64(len(data)), 0); err != nil {
+			t.Fatalf("Set(%s) failed: %v", key, err)
+		}
+	}
+
+	for key, data := range entries {
+		reader, _, found := cache.Get(ctx, key)
+		if !found {
+			t.Fatalf("Get(%s) returned not found", key)
+		}
+		retrieved, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", key, err)
+		}
+		if !bytes.Equal(retrieved, data) {
+			t.Errorf("data mismatch for %s: got %d bytes, want %d", key, len(retrieved), len(data))
+		}
+	}
+
+	// Neither the data files nor the index contain plaintext
+	onDisk, err := os.ReadFile(cache.dataFilePath(cache.hashKey("small")))
+	if err != nil {
+		t.Fatalf("failed to read cache file: %v", err)
+	}
+	if bytes.Contains(onDisk, marker) {
+		t.Error("cache file contains plaintext")
+	}
+	cache.Close()
+
+	index, err := os.ReadFile(filepath.Join(tempDir, "index.json"))
+	if err != nil {
+		t.Fatalf("failed to read index: %v", err)
+	}
+	if bytes.Contains(index, marker) {
+		t.Error("index contains plaintext")
+	}
+
+	// A different key cannot read the entries and treats them as misses
+	other := newCache("a-completely-different-key")
+	defer other.Close()
+	if _, _, found := other.Get(ctx, "small"); found {
+		t.Error("Get with a different key should miss")
+	}
+}
+
+func TestDiskCache_EncryptionKeyTooShort(t *testing.T) {
+	_, err := NewDiskCache(DiskCacheConfig{
+		BasePath:      t.TempDir(),
+		EncryptionKey: "short",
+	})
+	if err == nil {
+		t.Error("expected error for short encryption key")
+	}
+}
+
+func TestDiskCache_GetStale(t *testing.T) {
+	cache, err := NewDiskCache(DiskCacheConfig{
+		BasePath:        t.TempDir(),
+		MaxSizeGB:       1,
+		CleanupInterval: time.Hour,
+		StaleRetention:  time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	ctx := context.Background()
+	data := []byte("stale data")
+	cache.Set(ctx, "key", bytes.NewReader(data), "text/plain", int64(len(data)), 10*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+
+	if _, _, found := cache.Get(ctx, "key"); found {
+		t.Error("Get should not return an expired item")
+	}
+	cache.removeExpired()
+
+	reader, _, found := cache.GetStale(ctx, "key", time.Minute)
+	if !found {
+		t.Fatal("GetStale should return an item within maxStale")
+	}
+	retrieved, _ := io.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(retrieved, data) {
+		t.Errorf("GetStale returned %q, want %q", retrieved, data)
+	}
+
+	if _, _, found := cache.GetStale(ctx, "key", time.Millisecond); found {
+		t.Error("GetStale should not return an item expired longer than maxStale")
+	}
+}
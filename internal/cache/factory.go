@@ -20,6 +20,9 @@ func NewFromConfig(cfg config.CacheConfig) (Cache, error) {
 			DiskCleanupInterval:   10 * time.Minute,
 			PromoteOnHit:          true,
 			WriteThrough:          false,
+			DiskEncryptionKey:     cfg.DiskEncryptionKey,
+			MemoryEvictionPolicy:  EvictionPolicy(cfg.MemoryEvictionPolicy),
+			StaleRetention:        cfg.GetMaxStale(),
 		})
 	}
 
@@ -29,6 +32,8 @@ func NewFromConfig(cfg config.CacheConfig) (Cache, error) {
 			MaxSizeMB:       cfg.MemorySizeMB,
 			DefaultTTL:      cfg.GetCacheTTL(),
 			CleanupInterval: 5 * time.Minute,
+			EvictionPolicy:  EvictionPolicy(cfg.MemoryEvictionPolicy),
+			StaleRetention:  cfg.GetMaxStale(),
 		})
 	}
 
@@ -39,6 +44,8 @@ func NewFromConfig(cfg config.CacheConfig) (Cache, error) {
 			MaxSizeGB:       cfg.DiskSizeGB,
 			DefaultTTL:      cfg.GetCacheTTL(),
 			CleanupInterval: 10 * time.Minute,
+			EncryptionKey:   cfg.DiskEncryptionKey,
+			StaleRetention:  cfg.GetMaxStale(),
 		})
 	}
 
@@ -3,23 +3,48 @@ package cache
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-// MemoryCache implements an in-memory LRU cache with TTL support
+// EvictionPolicy selects which item the memory cache drops when it is full
+type EvictionPolicy string
+
+const (
+	// EvictionLRU evicts the least recently used item
+	EvictionLRU EvictionPolicy = "lru"
+
+	// EvictionLFU evicts the least frequently used item, breaking ties by
+	// recency, so a few hot entries survive bursts of one-off requests
+	EvictionLFU EvictionPolicy = "lfu"
+
+	// EvictionTTLOnly never evicts early: items leave only when they expire,
+	// and sets that do not fit are rejected with ErrCacheFull
+	EvictionTTLOnly EvictionPolicy = "ttl_only"
+)
+
+// ErrCacheFull is returned by Set when the ttl_only policy has no room
+var ErrCacheFull = errors.New("cache is full")
+
+// MemoryCache implements an in-memory cache with TTL support and a
+// configurable eviction policy (LRU by default)
 type MemoryCache struct {
 	mu sync.RWMutex
 
 	// items stores the cached items
 	items map[string]*CacheItem
 
-	// lruOrder tracks access order for LRU eviction
+	// lruOrder tracks access order, most recent first
 	lruOrder []string
 
+	// policy decides which item to evict when the cache is full
+	policy EvictionPolicy
+
 	// maxSize is the maximum cache size in bytes
 	maxSize int64
 
@@ -29,6 +54,9 @@ type MemoryCache struct {
 	// defaultTTL is the default TTL for items
 	defaultTTL time.Duration
 
+	// staleRetention is how long expired items are kept for GetStale
+	staleRetention time.Duration
+
 	// stats tracks cache statistics
 	stats CacheStats
 
@@ -47,9 +75,16 @@ type MemoryCacheConfig struct {
 
 	// CleanupInterval is how often to run cleanup
 	CleanupInterval time.Duration
+
+	// EvictionPolicy is the eviction policy; empty means EvictionLRU
+	EvictionPolicy EvictionPolicy
+
+	// StaleRetention keeps expired items this long so GetStale can still
+	// return them; they still count toward the cache size until removed
+	StaleRetention time.Duration
 }
 
-// NewMemoryCache creates a new in-memory LRU cache
+// NewMemoryCache creates a new in-memory cache
 func NewMemoryCache(cfg MemoryCacheConfig) (*MemoryCache, error) {
 	if cfg.MaxSizeMB <= 0 {
 		return nil, fmt.Errorf("max size must be positive")
@@ -63,15 +98,26 @@ func NewMemoryCache(cfg MemoryCacheConfig) (*MemoryCache, error) {
 		cfg.CleanupInterval = 5 * time.Minute
 	}
 
+	switch cfg.EvictionPolicy {
+	case "":
+		cfg.EvictionPolicy = EvictionLRU
+	case EvictionLRU, EvictionLFU, EvictionTTLOnly:
+	default:
+		return nil, fmt.Errorf("unknown eviction policy %q", cfg.EvictionPolicy)
+	}
+
 	maxSize := int64(cfg.MaxSizeMB) * 1024 * 1024
 
 	mc := &MemoryCache{
-		items:      make(map[string]*CacheItem),
-		lruOrder:   make([]string, 0),
-		maxSize:    maxSize,
-		defaultTTL: cfg.DefaultTTL,
+		items:          make(map[string]*CacheItem),
+		lruOrder:       make([]string, 0),
+		policy:         cfg.EvictionPolicy,
+		maxSize:        maxSize,
+		defaultTTL:     cfg.DefaultTTL,
+		staleRetention: cfg.StaleRetention,
 		stats: CacheStats{
-			MaxSize: maxSize,
+			MaxSize:        maxSize,
+			EvictionPolicy: string(cfg.EvictionPolicy),
 		},
 		cleanupDone: make(chan struct{}),
 	}
@@ -95,16 +141,21 @@ func (mc *MemoryCache) cleanupLoop() {
 	}
 }
 
-// removeExpired removes all expired items from the cache
+// removeExpired removes all items expired longer than the stale retention
 func (mc *MemoryCache) removeExpired() {
 	mc.mu.Lock()
 	defer mc.mu.Unlock()
 
+	mc.removeExpiredLocked(mc.staleRetention)
+}
+
+// removeExpiredLocked removes all items expired more than grace ago (must hold lock)
+func (mc *MemoryCache) removeExpiredLocked(grace time.Duration) {
 	now := time.Now()
 	var expired []string
 
 	for key, item := range mc.items {
-		if !item.ExpiresAt.IsZero() && now.After(item.ExpiresAt) {
+		if expiredBefore(item.ExpiresAt, now, grace) {
 			expired = append(expired, key)
 		}
 	}
@@ -126,10 +177,12 @@ func (mc *MemoryCache) Get(ctx context.Context, key string) (io.ReadCloser, stri
 		return nil, "", false
 	}
 
-	// Check if expired
+	// Check if expired, keeping items GetStale may still serve
 	if item.IsExpired() {
-		mc.removeItemLocked(key)
-		atomic.AddInt64(&mc.stats.Expirations, 1)
+		if expiredBefore(item.ExpiresAt, time.Now(), mc.staleRetention) {
+			mc.removeItemLocked(key)
+			atomic.AddInt64(&mc.stats.Expirations, 1)
+		}
 		atomic.AddInt64(&mc.stats.Misses, 1)
 		return nil, "", false
 	}
@@ -145,6 +198,19 @@ func (mc *MemoryCache) Get(ctx context.Context, key string) (io.ReadCloser, stri
 	return io.NopCloser(bytes.NewReader(item.Data)), item.ContentType, true
 }
 
+// GetStale retrieves an item that is fresh or expired at most maxStale ago
+func (mc *MemoryCache) GetStale(ctx context.Context, key string, maxStale time.Duration) (io.ReadCloser, string, bool) {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	item, exists := mc.items[key]
+	if !exists || expiredBefore(item.ExpiresAt, time.Now(), maxStale) {
+		return nil, "", false
+	}
+
+	return io.NopCloser(bytes.NewReader(item.Data)), item.ContentType, true
+}
+
 // Set stores an item in the cache
 func (mc *MemoryCache) Set(ctx context.Context, key string, data io.Reader, contentType string, size int64, ttl time.Duration) error {
 	if key == "" {
@@ -183,6 +249,14 @@ func (mc *MemoryCache) Set(ctx context.Context, key string, data io.Reader, cont
 	mc.mu.Lock()
 	defer mc.mu.Unlock()
 
+	if mc.policy == EvictionTTLOnly && !mc.fitsLocked(key, actualSize) {
+		// Expired items are the only ones this policy may drop
+		mc.removeExpiredLocked(0)
+		if !mc.fitsLocked(key, actualSize) {
+			return fmt.Errorf("%w: %d bytes needed, %d of %d bytes in use", ErrCacheFull, actualSize, mc.currentSize, mc.maxSize)
+		}
+	}
+
 	// If item already exists, remove it first
 	if existing, exists := mc.items[key]; exists {
 		mc.currentSize -= existing.Size
@@ -191,7 +265,7 @@ func (mc *MemoryCache) Set(ctx context.Context, key string, data io.Reader, cont
 
 	// Make room if necessary
 	for mc.currentSize+actualSize > mc.maxSize && len(mc.lruOrder) > 0 {
-		mc.evictLRU()
+		mc.evict()
 	}
 
 	// Add the new item
@@ -259,9 +333,16 @@ func (mc *MemoryCache) Stats() CacheStats {
 		ItemCount:   int64(len(mc.items)),
 		Evictions:   atomic.LoadInt64(&mc.stats.Evictions),
 		Expirations: atomic.LoadInt64(&mc.stats.Expirations),
+
+		EvictionPolicy: string(mc.policy),
 	}
 }
 
+// Policy returns the active eviction policy
+func (mc *MemoryCache) Policy() EvictionPolicy {
+	return mc.policy
+}
+
 // Close performs cleanup
 func (mc *MemoryCache) Close() error {
 	mc.cleanupTicker.Stop()
@@ -284,15 +365,36 @@ func (mc *MemoryCache) removeItemLocked(key string) {
 	mc.stats.ItemCount = int64(len(mc.items))
 }
 
-// evictLRU evicts the least recently used item (must hold lock)
-func (mc *MemoryCache) evictLRU() {
+// fitsLocked reports whether an item of size can be stored under key
+// without evicting anything (must hold lock)
+func (mc *MemoryCache) fitsLocked(key string, size int64) bool {
+	used := mc.currentSize
+	if existing, exists := mc.items[key]; exists {
+		used -= existing.Size
+	}
+	return used+size <= mc.maxSize
+}
+
+// evict removes one item chosen by the eviction policy (must hold lock)
+func (mc *MemoryCache) evict() {
 	if len(mc.lruOrder) == 0 {
 		return
 	}
 
-	// Get the LRU item (last in the list)
-	lruKey := mc.lruOrder[len(mc.lruOrder)-1]
-	mc.removeItemLocked(lruKey)
+	// The LRU item is last in the list
+	victim := mc.lruOrder[len(mc.lruOrder)-1]
+	if mc.policy == EvictionLFU {
+		// Walk from least to most recent so ties go to the older item
+		lowest := mc.items[victim].AccessCount
+		for i := len(mc.lruOrder) - 2; i >= 0; i-- {
+			key := mc.lruOrder[i]
+			if count := mc.items[key].AccessCount; count < lowest {
+				victim, lowest = key, count
+			}
+		}
+	}
+
+	mc.removeItemLocked(victim)
 	atomic.AddInt64(&mc.stats.Evictions, 1)
 }
 
@@ -326,3 +428,18 @@ func (mc *MemoryCache) GetItem(key string) (*CacheItem, bool) {
 	itemCopy := *item
 	return &itemCopy, true
 }
+
+// HasPrefix returns all non-expired keys with the given prefix
+func (mc *MemoryCache) HasPrefix(prefix string) []string {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	var keys []string
+	for key, item := range mc.items {
+		if strings.HasPrefix(key, prefix) && !item.IsExpired() {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"testing"
 	"time"
@@ -320,3 +321,215 @@ func TestMemoryCache_OverwriteExisting(t *testing.T) {
 		t.Errorf("expected 1 item, got %d", stats.ItemCount)
 	}
 }
+
+func TestMemoryCache_HasPrefix(t *testing.T) {
+	cache, err := NewMemoryCache(MemoryCacheConfig{
+		MaxSizeMB:       1,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	ctx := context.Background()
+	cache.Set(ctx, "prefix/item1", bytes.NewReader([]byte("data")), "text/plain", 4, 0)
+	cache.Set(ctx, "prefix/item2", bytes.NewReader([]byte("data")), "text/plain", 4, 0)
+	cache.Set(ctx, "other/item3", bytes.NewReader([]byte("data")), "text/plain", 4, 0)
+	cache.Set(ctx, "prefix/expired", bytes.NewReader([]byte("data")), "text/plain", 4, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	if keys := cache.HasPrefix(""); len(keys) != 3 {
+		t.Errorf("expected 3 keys, got %d", len(keys))
+	}
+	if keys := cache.HasPrefix("prefix/"); len(keys) != 2 {
+		t.Errorf("expected 2 prefix keys, got %d", len(keys))
+	}
+}
+
+func TestMemoryCache_EvictionPolicies(t *testing.T) {
+	tests := []struct {
+		policy     EvictionPolicy
+		wantKept   []string
+		wantGone   []string
+		wantSetErr error
+	}{
+		// "hot" was read most often but "warm" most recently
+		{policy: EvictionLRU, wantKept: []string{"warm", "new"}, wantGone: []string{"hot"}},
+		{policy: EvictionLFU, wantKept: []string{"hot", "new"}, wantGone: []string{"warm"}},
+		{policy: EvictionTTLOnly, wantKept: []string{"hot", "warm"}, wantGone: []string{"new"}, wantSetErr: ErrCacheFull},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			cache, err := NewMemoryCache(MemoryCacheConfig{
+				MaxSizeMB:       1,
+				DefaultTTL:      time.Hour,
+				CleanupInterval: time.Hour,
+				EvictionPolicy:  tt.policy,
+			})
+			if err != nil {
+				t.Fatalf("failed to create cache: %v", err)
+			}
+			defer cache.Close()
+
+			ctx := context.Background()
+			data := make([]byte, 400*1024)
+			set := func(key string) error {
+				return cache.Set(ctx, key, bytes.NewReader(data), "application/octet-stream", int64(len(data)), 0)
+			}
+
+			set("hot")
+			for i := 0; i < 3; i++ {
+				cache.Get(ctx, "hot")
+			}
+			set("warm")
+			cache.Get(ctx, "warm")
+
+			// Only two items fit, so this puts the cache under pressure
+			err = set("new")
+			if !errors.Is(err, tt.wantSetErr) {
+				t.Fatalf("expected set error %v, got %v", tt.wantSetErr, err)
+			}
+
+			for _, key := range tt.wantKept {
+				if !cache.Exists(ctx, key) {
+					t.Errorf("%s should have been kept", key)
+				}
+			}
+			for _, key := range tt.wantGone {
+				if cache.Exists(ctx, key) {
+					t.Errorf("%s should not be cached", key)
+				}
+			}
+
+			stats := cache.Stats()
+			if stats.EvictionPolicy != string(tt.policy) {
+				t.Errorf("expected policy %s in stats, got %q", tt.policy, stats.EvictionPolicy)
+			}
+			wantEvictions := int64(1)
+			if tt.policy == EvictionTTLOnly {
+				wantEvictions = 0
+			}
+			if stats.Evictions != wantEvictions {
+				t.Errorf("expected %d evictions, got %d", wantEvictions, stats.Evictions)
+			}
+		})
+	}
+}
+
+func TestMemoryCache_TTLOnlyReclaimsExpired(t *testing.T) {
+	cache, err := NewMemoryCache(MemoryCacheConfig{
+		MaxSizeMB:       1,
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Hour,
+		EvictionPolicy:  EvictionTTLOnly,
+	})
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	ctx := context.Background()
+	data := make([]byte, 600*1024)
+
+	if err := cache.Set(ctx, "short", bytes.NewReader(data), "application/octet-stream", int64(len(data)), 50*time.Millisecond); err != nil {
+		t.Fatalf("failed to set item: %v", err)
+	}
+
+	// Overwriting an existing key reuses its space
+	if err := cache.Set(ctx, "short", bytes.NewReader(data), "application/octet-stream", int64(len(data)), 50*time.Millisecond); err != nil {
+		t.Fatalf("failed to overwrite item: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	// The expired item makes room without counting as an eviction
+	if err := cache.Set(ctx, "long", bytes.NewReader(data), "application/octet-stream", int64(len(data)), 0); err != nil {
+		t.Fatalf("expected expired item to make room, got %v", err)
+	}
+	if !cache.Exists(ctx, "long") {
+		t.Error("long should exist")
+	}
+
+	stats := cache.Stats()
+	if stats.Evictions != 0 {
+		t.Errorf("expected no evictions, got %d", stats.Evictions)
+	}
+	if stats.Expirations != 1 {
+		t.Errorf("expected 1 expiration, got %d", stats.Expirations)
+	}
+}
+
+func TestMemoryCache_DefaultAndUnknownPolicy(t *testing.T) {
+	cache, err := NewMemoryCache(MemoryCacheConfig{MaxSizeMB: 1})
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	if cache.Policy() != EvictionLRU {
+		t.Errorf("expected default policy lru, got %s", cache.Policy())
+	}
+
+	if _, err := NewMemoryCache(MemoryCacheConfig{MaxSizeMB: 1, EvictionPolicy: "fifo"}); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+func TestMemoryCache_GetStale(t *testing.T) {
+	cache, err := NewMemoryCache(MemoryCacheConfig{
+		MaxSizeMB:       1,
+		CleanupInterval: time.Hour,
+		StaleRetention:  time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	ctx := context.Background()
+	data := []byte("stale data")
+	cache.Set(ctx, "key", bytes.NewReader(data), "text/plain", int64(len(data)), 10*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+
+	// Expired items are misses for Get but are retained for GetStale
+	if _, _, found := cache.Get(ctx, "key"); found {
+		t.Error("Get should not return an expired item")
+	}
+	cache.removeExpired()
+
+	reader, ct, found := cache.GetStale(ctx, "key", time.Minute)
+	if !found {
+		t.Fatal("GetStale should return an item within maxStale")
+	}
+	retrieved, _ := io.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(retrieved, data) || ct != "text/plain" {
+		t.Errorf("GetStale returned %q (%s), want %q (text/plain)", retrieved, ct, data)
+	}
+
+	if _, _, found := cache.GetStale(ctx, "key", time.Millisecond); found {
+		t.Error("GetStale should not return an item expired longer than maxStale")
+	}
+}
+
+func TestMemoryCache_NoStaleRetention(t *testing.T) {
+	cache, err := NewMemoryCache(MemoryCacheConfig{MaxSizeMB: 1, CleanupInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	ctx := context.Background()
+	data := []byte("data")
+	cache.Set(ctx, "key", bytes.NewReader(data), "text/plain", int64(len(data)), 10*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+
+	// Without retention, Get drops the expired item as before
+	cache.Get(ctx, "key")
+	if _, _, found := cache.GetStale(ctx, "key", time.Hour); found {
+		t.Error("expired item should have been removed")
+	}
+}
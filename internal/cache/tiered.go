@@ -50,6 +50,16 @@ type TieredCacheConfig struct {
 
 	// WriteThrough writes to both caches on Set (vs write to memory only)
 	WriteThrough bool
+
+	// DiskEncryptionKey enables encryption at rest for the disk layer
+	DiskEncryptionKey string
+
+	// MemoryEvictionPolicy is the eviction policy of the memory layer
+	MemoryEvictionPolicy EvictionPolicy
+
+	// StaleRetention keeps expired items in both layers this long so
+	// GetStale can still return them
+	StaleRetention time.Duration
 }
 
 // TieredCacheStats contains combined statistics for both cache tiers
@@ -63,6 +73,8 @@ type TieredCacheStats struct {
 	MemoryEvictions   int64 `json:"memory_evictions"`
 	MemoryExpirations int64 `json:"memory_expirations"`
 
+	MemoryEvictionPolicy string `json:"memory_eviction_policy"`
+
 	// Disk cache stats
 	DiskHits        int64 `json:"disk_hits"`
 	DiskMisses      int64 `json:"disk_misses"`
@@ -99,6 +111,8 @@ func NewTieredCache(cfg TieredCacheConfig) (*TieredCache, error) {
 		MaxSizeMB:       cfg.MemorySizeMB,
 		DefaultTTL:      cfg.DefaultTTL,
 		CleanupInterval: cfg.MemoryCleanupInterval,
+		EvictionPolicy:  cfg.MemoryEvictionPolicy,
+		StaleRetention:  cfg.StaleRetention,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create memory cache: %w", err)
@@ -110,6 +124,8 @@ func NewTieredCache(cfg TieredCacheConfig) (*TieredCache, error) {
 		MaxSizeGB:       cfg.DiskSizeGB,
 		DefaultTTL:      cfg.DefaultTTL,
 		CleanupInterval: cfg.DiskCleanupInterval,
+		EncryptionKey:   cfg.DiskEncryptionKey,
+		StaleRetention:  cfg.StaleRetention,
 	})
 	if err != nil {
 		memoryCache.Close()
@@ -143,27 +159,37 @@ func (tc *TieredCache) Get(ctx context.Context, key string) (io.ReadCloser, stri
 	atomic.AddInt64(&tc.stats.DiskHits, 1)
 	atomic.AddInt64(&tc.stats.TotalHits, 1)
 
-	// Promote to memory cache if configured
+	// Promote to memory cache if configured. Promotion consumes the disk
+	// reader, so the bytes it read are served whether or not memory had room.
 	if tc.config.PromoteOnHit {
-		tc.promoteToMemory(ctx, key, reader, contentType)
-		atomic.AddInt64(&tc.stats.Promotions, 1)
-
-		// Return fresh reader from memory
-		if memReader, memContentType, found := tc.memory.Get(ctx, key); found {
-			return memReader, memContentType, true
+		data, err := tc.promoteToMemory(ctx, key, reader, contentType)
+		if err != nil {
+			return nil, "", false
 		}
+		atomic.AddInt64(&tc.stats.Promotions, 1)
+		return io.NopCloser(bytes.NewReader(data)), contentType, true
 	}
 
 	return reader, contentType, true
 }
 
-// promoteToMemory copies an item from disk to memory
-func (tc *TieredCache) promoteToMemory(ctx context.Context, key string, reader io.ReadCloser, contentType string) {
+// GetStale retrieves an item that is fresh or expired at most maxStale ago,
+// checking memory first, then disk. Stale items are not promoted.
+func (tc *TieredCache) GetStale(ctx context.Context, key string, maxStale time.Duration) (io.ReadCloser, string, bool) {
+	if reader, contentType, found := tc.memory.GetStale(ctx, key, maxStale); found {
+		return reader, contentType, true
+	}
+	return tc.disk.GetStale(ctx, key, maxStale)
+}
+
+// promoteToMemory copies an item from disk to memory, closing the disk reader.
+// It returns the data read, which is valid even when memory has no room.
+func (tc *TieredCache) promoteToMemory(ctx context.Context, key string, reader io.ReadCloser, contentType string) ([]byte, error) {
 	// Read all data
 	data, err := io.ReadAll(reader)
 	reader.Close()
 	if err != nil {
-		return
+		return nil, err
 	}
 
 	// Get TTL from disk entry if available
@@ -177,6 +203,7 @@ func (tc *TieredCache) promoteToMemory(ctx context.Context, key string, reader i
 
 	// Store in memory
 	tc.memory.Set(ctx, key, bytes.NewReader(data), contentType, int64(len(data)), ttl)
+	return data, nil
 }
 
 // Set stores an item in the cache
@@ -247,6 +274,8 @@ func (tc *TieredCache) Stats() CacheStats {
 		ItemCount:   memStats.ItemCount + diskStats.ItemCount,
 		Evictions:   memStats.Evictions + diskStats.Evictions,
 		Expirations: memStats.Expirations + diskStats.Expirations,
+
+		EvictionPolicy: memStats.EvictionPolicy,
 	}
 }
 
@@ -264,6 +293,8 @@ func (tc *TieredCache) DetailedStats() TieredCacheStats {
 		MemoryEvictions:   memStats.Evictions,
 		MemoryExpirations: memStats.Expirations,
 
+		MemoryEvictionPolicy: memStats.EvictionPolicy,
+
 		DiskHits:        diskStats.Hits,
 		DiskMisses:      diskStats.Misses,
 		DiskSize:        diskStats.Size,
@@ -173,6 +173,61 @@ func TestTieredCache_DiskFallback(t *testing.T) {
 	}
 }
 
+func TestTieredCache_DiskFallbackWithFullMemory(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "tiered-cache-fullmemory-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	cache, err := NewTieredCache(TieredCacheConfig{
+		MemorySizeMB:          1,
+		MemoryEvictionPolicy:  EvictionTTLOnly,
+		DiskPath:              tempDir,
+		DiskSizeGB:            1,
+		DefaultTTL:            time.Hour,
+		MemoryCleanupInterval: time.Hour,
+		DiskCleanupInterval:   time.Hour,
+		PromoteOnHit:          true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	ctx := context.Background()
+
+	// Fill memory so the ttl_only policy has no room to promote into
+	filler := bytes.Repeat([]byte("x"), 1024*1024)
+	if err := cache.memory.Set(ctx, "filler", bytes.NewReader(filler), "text/plain", int64(len(filler)), 0); err != nil {
+		t.Fatalf("failed to fill memory: %v", err)
+	}
+
+	key := "disk-key"
+	data := []byte("disk data")
+	cache.SetToDisk(ctx, key, bytes.NewReader(data), "text/plain", int64(len(data)), 0)
+
+	reader, contentType, found := cache.Get(ctx, key)
+	if !found {
+		t.Fatal("key not found")
+	}
+	defer reader.Close()
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("expected %q, got %q", data, got)
+	}
+	if contentType != "text/plain" {
+		t.Errorf("expected text/plain, got %q", contentType)
+	}
+	if cache.memory.Exists(ctx, key) {
+		t.Error("key should not fit in full memory")
+	}
+}
+
 func TestTieredCache_WriteThrough(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "tiered-cache-writethrough-test")
 	if err != nil {
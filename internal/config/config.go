@@ -1,10 +1,20 @@
 package config
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 )
 
+// platformRegex validates platform format (os_arch)
+var platformRegex = regexp.MustCompile(`^[a-z0-9]+_[a-z0-9]+$`)
+
+// namespaceRegex validates registry namespace names (e.g., hashicorp)
+var namespaceRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
 // Config represents the complete application configuration
+// Fields tagged secret:"true" are redacted when the configuration is rendered
 type Config struct {
 	Server              ServerConfig               `hcl:"server,block"`
 	Storage             StorageConfig              `hcl:"storage,block"`
@@ -20,6 +30,8 @@ type Config struct {
 	Quota               QuotaConfig                `hcl:"quota,block"`
 	AutoDownload        *AutoDownloadConfig        `hcl:"auto_download,block"`
 	AutoDownloadModules *AutoDownloadModulesConfig `hcl:"auto_download_modules,block"`
+	Discovery           *DiscoveryConfig           `hcl:"discovery,block"`
+	Sync                *SyncConfig                `hcl:"sync,block"`
 }
 
 // ServerConfig contains HTTP server settings
@@ -30,6 +42,29 @@ type ServerConfig struct {
 	TLSKeyPath     string   `hcl:"tls_key_path,optional"`
 	BehindProxy    bool     `hcl:"behind_proxy,optional"`
 	TrustedProxies []string `hcl:"trusted_proxies,optional"`
+
+	// Externally reachable base URL of the mirror (e.g. https://mirror.example.com),
+	// used to build absolute blob URLs for local storage
+	PublicURL string `hcl:"public_url,optional"`
+
+	// Source ranges allowed to reach the admin UI and API; empty allows all
+	AdminAllowedCIDRs []string `hcl:"admin_allowed_cidrs,optional"`
+
+	// Limit on concurrent /blobs/ downloads; 0 means unlimited. Requests over
+	// the limit wait up to DownloadQueueTimeoutSeconds for a slot, then get a 503.
+	MaxConcurrentDownloads      int `hcl:"max_concurrent_downloads,optional"`
+	DownloadQueueTimeoutSeconds int `hcl:"download_queue_timeout_seconds,optional"`
+
+	// How long shutdown waits for in-flight blob downloads beyond the HTTP drain
+	ShutdownDownloadWaitSeconds int `hcl:"shutdown_download_wait_seconds,optional"`
+
+	// Blob downloads taking at least this long are logged; 0 disables the log
+	SlowDownloadSeconds int `hcl:"slow_download_seconds,optional"`
+
+	// Per-route-group request timeouts; 0 uses the default. Blob downloads
+	// have no timeout so large archives can stream for as long as they need.
+	RequestTimeoutSeconds     int `hcl:"request_timeout_seconds,optional"`      // Admin JSON and protocol metadata
+	LongRequestTimeoutSeconds int `hcl:"long_request_timeout_seconds,optional"` // Uploads, loads, imports, exports and verification
 }
 
 // StorageConfig contains object storage settings
@@ -38,8 +73,8 @@ type StorageConfig struct {
 	Bucket         string `hcl:"bucket,optional"`
 	Region         string `hcl:"region,optional"`
 	Endpoint       string `hcl:"endpoint,optional"`
-	AccessKey      string `hcl:"access_key,optional"`
-	SecretKey      string `hcl:"secret_key,optional"`
+	AccessKey      string `hcl:"access_key,optional" secret:"true"`
+	SecretKey      string `hcl:"secret_key,optional" secret:"true"`
 	ForcePathStyle bool   `hcl:"force_path_style,optional"`
 }
 
@@ -50,6 +85,9 @@ type DatabaseConfig struct {
 	BackupIntervalHours int    `hcl:"backup_interval_hours,optional"`
 	BackupToS3          bool   `hcl:"backup_to_s3,optional"`
 	BackupS3Prefix      string `hcl:"backup_s3_prefix,optional"`
+	BusyTimeoutMS       int    `hcl:"busy_timeout_ms,optional"` // How long SQLite waits for a lock before "database is locked"
+	Synchronous         string `hcl:"synchronous,optional"`     // PRAGMA synchronous level: OFF, NORMAL, FULL or EXTRA
+	MaxOpenConns        int    `hcl:"max_open_conns,optional"`  // SQLite connection pool size
 }
 
 // CacheConfig contains caching settings
@@ -58,6 +96,27 @@ type CacheConfig struct {
 	DiskPath     string `hcl:"disk_path,optional"`
 	DiskSizeGB   int    `hcl:"disk_size_gb,optional"`
 	TTLSeconds   int    `hcl:"ttl_seconds,optional"`
+
+	// Per-content-type TTLs; 0 falls back to TTLSeconds
+	BlobTTLSeconds  int `hcl:"blob_ttl_seconds,optional"`  // Provider and module archives (immutable)
+	IndexTTLSeconds int `hcl:"index_ttl_seconds,optional"` // Mirror protocol index.json and version JSON
+
+	// MemoryEvictionPolicy picks what the memory cache drops when full:
+	// "lru", "lfu", or "ttl_only" (never evict early, reject new entries)
+	MemoryEvictionPolicy string `hcl:"memory_eviction_policy,optional"`
+
+	// DiskEncryptionKey enables AES-GCM encryption of disk cache entries at rest.
+	// Opt-in: encryption adds CPU overhead to every disk cache read and write.
+	DiskEncryptionKey string `hcl:"disk_encryption_key,optional" secret:"true"`
+
+	// ServeStaleOnError serves an expired blob from the cache when it can't be
+	// read from storage, as long as it expired at most MaxStaleSeconds ago
+	ServeStaleOnError bool `hcl:"serve_stale_on_error,optional"`
+	MaxStaleSeconds   int  `hcl:"max_stale_seconds,optional"`
+
+	// CompressIndex stores cached mirror protocol JSON gzip-compressed.
+	// Archives are already compressed and are always cached as is.
+	CompressIndex bool `hcl:"compress_index,optional"`
 }
 
 // FeaturesConfig contains feature flags
@@ -72,6 +131,8 @@ type AutoDownloadConfig struct {
 	Enabled              bool     `hcl:"enabled,optional"`
 	AllowedNamespaces    []string `hcl:"allowed_namespaces,optional"`    // Empty = all allowed
 	BlockedNamespaces    []string `hcl:"blocked_namespaces,optional"`    // Takes precedence over allowed
+	AllowedProviders     []string `hcl:"allowed_providers,optional"`     // namespace/type addresses allowed alongside allowed_namespaces
+	BlockedProviders     []string `hcl:"blocked_providers,optional"`     // namespace/type addresses blocked even in an allowed namespace
 	Platforms            []string `hcl:"platforms,optional"`             // Platforms to download (e.g., linux_amd64)
 	RateLimitPerMinute   int      `hcl:"rate_limit_per_minute,optional"` // Max downloads per minute
 	MaxConcurrentDL      int      `hcl:"max_concurrent_downloads,optional"`
@@ -80,13 +141,21 @@ type AutoDownloadConfig struct {
 	RetryOnFailure       bool     `hcl:"retry_on_failure,optional"`
 	CacheNegativeResults bool     `hcl:"cache_negative_results,optional"` // Cache "not found" responses
 	NegativeCacheTTL     int      `hcl:"negative_cache_ttl_seconds,optional"`
+	// MaxVersionsPerProvider caps the versions of a provider that
+	// auto-download will store, counting those already mirrored. When set,
+	// a cold request also pulls the newest releases in the background up to
+	// the cap. 0 means no cap and only requested versions are fetched.
+	MaxVersionsPerProvider int `hcl:"max_versions_per_provider,optional"`
 }
 
 // AuthConfig contains authentication settings
 type AuthConfig struct {
-	JWTSecret          string `hcl:"jwt_secret,optional"`
-	JWTExpirationHours int    `hcl:"jwt_expiration_hours,optional"`
-	BCryptCost         int    `hcl:"bcrypt_cost,optional"`
+	JWTSecret string `hcl:"jwt_secret,optional" secret:"true"`
+	// JWTSecrets is an ordered list of secrets for rotation: the first signs
+	// new tokens and all of them are accepted. Use instead of jwt_secret.
+	JWTSecrets         []string `hcl:"jwt_secrets,optional" secret:"true"`
+	JWTExpirationHours int      `hcl:"jwt_expiration_hours,optional"`
+	BCryptCost         int      `hcl:"bcrypt_cost,optional"`
 }
 
 // ProcessorConfig contains background job processor settings
@@ -96,6 +165,13 @@ type ProcessorConfig struct {
 	RetryAttempts          int `hcl:"retry_attempts,optional"`
 	RetryDelaySeconds      int `hcl:"retry_delay_seconds,optional"`
 	WorkerShutdownSeconds  int `hcl:"worker_shutdown_seconds,optional"`
+	// MaxPollingIntervalSeconds is the longest wait between polls. The
+	// interval doubles toward it while polls start no jobs and resets once
+	// one is started. At or below the polling interval it stays fixed.
+	MaxPollingIntervalSeconds int `hcl:"max_polling_interval_seconds,optional"`
+	// MaxPendingItems caps the download items waiting to be processed; loads
+	// that would push the backlog past it are refused. 0 means no limit.
+	MaxPendingItems int `hcl:"max_pending_items,optional"`
 }
 
 // LoggingConfig contains logging settings
@@ -104,6 +180,12 @@ type LoggingConfig struct {
 	Format   string `hcl:"format,optional"`
 	Output   string `hcl:"output,optional"`
 	FilePath string `hcl:"file_path,optional"`
+	// AuditDownloads records public provider and module downloads in the
+	// audit log. Off by default because it writes a row per download.
+	AuditDownloads bool `hcl:"audit_downloads,optional"`
+	// BufferEntries is how many recent log entries are kept in memory for
+	// the admin API; 0 disables the buffer
+	BufferEntries int `hcl:"buffer_entries,optional"`
 }
 
 // TelemetryConfig contains observability settings
@@ -123,6 +205,42 @@ type ProvidersConfig struct {
 	DownloadRetryAttempts       int    `hcl:"download_retry_attempts,optional"`
 	DownloadRetryInitialDelayMs int    `hcl:"download_retry_initial_delay_ms,optional"`
 	DownloadTimeoutSeconds      int    `hcl:"download_timeout_seconds,optional"`
+	// Platforms is the default set of platforms (os_arch) mirrored for every
+	// provider version when a load, fill, or auto-download omits them
+	Platforms []string `hcl:"platforms,optional"`
+	// ImmutableArtifacts refuses to replace a stored provider archive with
+	// content of a different shasum
+	ImmutableArtifacts bool `hcl:"immutable_artifacts,optional"`
+	// ReindexFromStorage lets jobs record an archive already present in
+	// storage instead of downloading it again when its database row is missing
+	ReindexFromStorage bool `hcl:"reindex_from_storage,optional"`
+	// ReindexVerifyShasum hashes a stored archive before re-indexing it and
+	// downloads afresh when the content does not match its recorded shasum
+	ReindexVerifyShasum bool `hcl:"reindex_verify_shasum,optional"`
+	// StoreShasums writes a SHA256SUMS document to storage once every
+	// platform of a version in a job is mirrored, and the registry protocol
+	// serves it instead of building the document per request
+	StoreShasums bool `hcl:"store_shasums,optional"`
+	// IncludeBlockedForAdmins lists blocked versions, flagged as blocked, in
+	// mirror protocol responses to requests carrying an admin token.
+	// Anonymous clients never see them.
+	IncludeBlockedForAdmins bool `hcl:"include_blocked_for_admins,optional"`
+	// Upstreams are registries to download providers from in place of the
+	// public registry, chosen by namespace
+	Upstreams []UpstreamRegistryConfig `hcl:"upstream,block"`
+}
+
+// UpstreamRegistryConfig is a provider registry serving a set of namespaces.
+// Providers from it are stored under its hostname.
+type UpstreamRegistryConfig struct {
+	Hostname   string   `hcl:"hostname,label"`               // e.g., "registry.example.com"
+	URL        string   `hcl:"url,optional"`                 // Providers API base URL, default https://{hostname}/v1/providers
+	Namespaces []string `hcl:"namespaces"`                   // Namespaces served by this registry; "*" matches any
+	Token      string   `hcl:"token,optional" secret:"true"` // Bearer token for the registry API
+
+	// Username and Password authenticate with HTTP basic auth instead of a token
+	Username string `hcl:"username,optional"`
+	Password string `hcl:"password,optional" secret:"true"`
 }
 
 // ModulesConfig contains module-specific settings
@@ -132,6 +250,10 @@ type ModulesConfig struct {
 	DownloadRetryInitialDelayMs int    `hcl:"download_retry_initial_delay_ms,optional"`
 	DownloadTimeoutSeconds      int    `hcl:"download_timeout_seconds,optional"`
 	MirrorHostname              string `hcl:"mirror_hostname,optional"` // Hostname to use for rewriting nested module sources
+
+	// Store module archives under blobs/ by the SHA256 of their uncompressed
+	// content, so versions with identical content share one object
+	DeduplicateArchives bool `hcl:"deduplicate_archives,optional"`
 }
 
 // AutoDownloadModulesConfig contains module auto-download specific settings
@@ -146,6 +268,22 @@ type AutoDownloadModulesConfig struct {
 	NegativeCacheTTL     int      `hcl:"negative_cache_ttl_seconds,optional"`
 }
 
+// DiscoveryConfig contains settings for the .well-known/terraform.json service discovery document
+type DiscoveryConfig struct {
+	ProvidersPath  string `hcl:"providers_path,optional"`  // Advertised as providers.v1
+	ModulesPath    string `hcl:"modules_path,optional"`    // Advertised as modules.v1 and used to mount the module registry
+	DisableModules bool   `hcl:"disable_modules,optional"` // Do not serve or advertise the module registry protocol
+}
+
+// SyncConfig contains settings for the scheduled upstream re-sync, which
+// enqueues downloads of newly released versions of mirrored providers and modules
+type SyncConfig struct {
+	Enabled          bool   `hcl:"enabled,optional"`
+	Schedule         string `hcl:"schedule,optional"`          // Cron expression, e.g. "0 3 * * *"
+	DisableProviders bool   `hcl:"disable_providers,optional"` // Do not re-sync providers
+	DisableModules   bool   `hcl:"disable_modules,optional"`   // Do not re-sync modules
+}
+
 // QuotaConfig contains storage quota settings
 type QuotaConfig struct {
 	Enabled                 bool `hcl:"enabled,optional"`
@@ -157,12 +295,19 @@ type QuotaConfig struct {
 func DefaultConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:           8080,
-			TLSEnabled:     false,
-			TLSCertPath:    "",
-			TLSKeyPath:     "",
-			BehindProxy:    false,
-			TrustedProxies: []string{},
+			Port:              8080,
+			TLSEnabled:        false,
+			TLSCertPath:       "",
+			TLSKeyPath:        "",
+			BehindProxy:       false,
+			TrustedProxies:    []string{},
+			AdminAllowedCIDRs: []string{},
+
+			MaxConcurrentDownloads:      0,
+			DownloadQueueTimeoutSeconds: 10,
+			ShutdownDownloadWaitSeconds: 120,
+			RequestTimeoutSeconds:       DefaultRequestTimeoutSeconds,
+			LongRequestTimeoutSeconds:   DefaultLongRequestTimeoutSeconds,
 		},
 		Storage: StorageConfig{
 			Type:           "s3",
@@ -179,12 +324,21 @@ func DefaultConfig() *Config {
 			BackupIntervalHours: 24,
 			BackupToS3:          false,
 			BackupS3Prefix:      "backups/",
+			BusyTimeoutMS:       5000,
+			Synchronous:         "NORMAL",
+			MaxOpenConns:        8,
 		},
 		Cache: CacheConfig{
-			MemorySizeMB: 256,
-			DiskPath:     "/var/cache/tf-mirror",
-			DiskSizeGB:   10,
-			TTLSeconds:   3600,
+			MemorySizeMB:         256,
+			DiskPath:             "/var/cache/tf-mirror",
+			DiskSizeGB:           10,
+			TTLSeconds:           3600,
+			BlobTTLSeconds:       0,
+			IndexTTLSeconds:      60,
+			MemoryEvictionPolicy: "lru",
+			ServeStaleOnError:    false,
+			MaxStaleSeconds:      3600,
+			CompressIndex:        false,
 		},
 		Features: FeaturesConfig{
 			AutoDownloadProviders: false,
@@ -197,17 +351,21 @@ func DefaultConfig() *Config {
 			JWTSecret:          "", // Must be set via environment variable or config file
 		},
 		Processor: ProcessorConfig{
-			PollingIntervalSeconds: 10,
-			MaxConcurrentJobs:      3,
-			RetryAttempts:          3,
-			RetryDelaySeconds:      5,
-			WorkerShutdownSeconds:  30,
+			PollingIntervalSeconds:    10,
+			MaxConcurrentJobs:         3,
+			RetryAttempts:             3,
+			RetryDelaySeconds:         5,
+			WorkerShutdownSeconds:     30,
+			MaxPendingItems:           100000,
+			MaxPollingIntervalSeconds: 60,
 		},
 		Logging: LoggingConfig{
 			Level:    "info",
 			Format:   "text",
 			Output:   "stdout",
 			FilePath: "",
+
+			BufferEntries: 1000,
 		},
 		Telemetry: TelemetryConfig{
 			Enabled:       false,
@@ -219,10 +377,13 @@ func DefaultConfig() *Config {
 		},
 		Providers: ProvidersConfig{
 			GPGVerificationEnabled:      true,
+			ImmutableArtifacts:          true,
+			ReindexVerifyShasum:         true,
 			GPGKeyURL:                   "https://www.hashicorp.com/.well-known/pgp-key.txt",
 			DownloadRetryAttempts:       5,
 			DownloadRetryInitialDelayMs: 1000,
 			DownloadTimeoutSeconds:      60,
+			Platforms:                   []string{"linux_amd64", "windows_amd64"},
 		},
 		Modules: ModulesConfig{
 			UpstreamRegistry:            "registry.terraform.io",
@@ -240,7 +401,9 @@ func DefaultConfig() *Config {
 			Enabled:              false, // Disabled by default for security
 			AllowedNamespaces:    []string{},
 			BlockedNamespaces:    []string{},
-			Platforms:            []string{"linux_amd64", "windows_amd64"}, // Default platforms
+			AllowedProviders:     []string{},
+			BlockedProviders:     []string{},
+			Platforms:            []string{}, // Empty = inherit providers.platforms
 			RateLimitPerMinute:   10,
 			MaxConcurrentDL:      3,
 			QueueSize:            100,
@@ -259,7 +422,41 @@ func DefaultConfig() *Config {
 			CacheNegativeResults: true,
 			NegativeCacheTTL:     300, // 5 minutes
 		},
+		Discovery: &DiscoveryConfig{
+			ProvidersPath:  "/v1/providers/",
+			ModulesPath:    "/v1/modules/",
+			DisableModules: false,
+		},
+		Sync: &SyncConfig{
+			Enabled:  false, // Opt-in
+			Schedule: DefaultSyncSchedule,
+		},
+	}
+}
+
+// GetPublicURL returns the public base URL without a trailing slash. When
+// public_url is unset it falls back to localhost on the configured port, which
+// is only reachable from the mirror host itself.
+func (c *ServerConfig) GetPublicURL() string {
+	if c.PublicURL != "" {
+		return strings.TrimSuffix(c.PublicURL, "/")
+	}
+	scheme := "http"
+	if c.TLSEnabled {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://localhost:%d", scheme, c.Port)
+}
+
+// GetJWTSecrets returns the JWT secrets in order, signing secret first
+func (c *AuthConfig) GetJWTSecrets() []string {
+	if len(c.JWTSecrets) > 0 {
+		return c.JWTSecrets
+	}
+	if c.JWTSecret != "" {
+		return []string{c.JWTSecret}
 	}
+	return nil
 }
 
 // GetJWTExpiration returns the JWT expiration as a duration
@@ -272,6 +469,29 @@ func (c *ProvidersConfig) GetDownloadRetryDelay() time.Duration {
 	return time.Duration(c.DownloadRetryInitialDelayMs) * time.Millisecond
 }
 
+// GetPlatforms returns the default provider platforms, with defaults if empty
+func (c *ProvidersConfig) GetPlatforms() []string {
+	if len(c.Platforms) == 0 {
+		return defaultPlatforms()
+	}
+	return c.Platforms
+}
+
+// defaultPlatforms returns the platforms mirrored when none are configured
+func defaultPlatforms() []string {
+	return []string{"linux_amd64", "windows_amd64"}
+}
+
+// IsValidPlatform reports whether platform is in os_arch form (e.g., linux_amd64)
+func IsValidPlatform(platform string) bool {
+	return platformRegex.MatchString(platform)
+}
+
+// IsValidNamespace reports whether namespace is a valid registry namespace
+func IsValidNamespace(namespace string) bool {
+	return namespaceRegex.MatchString(namespace)
+}
+
 // GetDownloadTimeout returns the download timeout as a duration
 func (c *ProvidersConfig) GetDownloadTimeout() time.Duration {
 	return time.Duration(c.DownloadTimeoutSeconds) * time.Second
@@ -282,11 +502,47 @@ func (c *DatabaseConfig) GetBackupInterval() time.Duration {
 	return time.Duration(c.BackupIntervalHours) * time.Hour
 }
 
+// GetBusyTimeout returns the SQLite busy timeout as a duration
+func (c *DatabaseConfig) GetBusyTimeout() time.Duration {
+	return time.Duration(c.BusyTimeoutMS) * time.Millisecond
+}
+
 // GetCacheTTL returns the cache TTL as a duration
 func (c *CacheConfig) GetCacheTTL() time.Duration {
 	return time.Duration(c.TTLSeconds) * time.Second
 }
 
+// IsEnabled reports whether a memory or disk cache is configured. Without
+// one the server runs with a no-op cache.
+func (c *CacheConfig) IsEnabled() bool {
+	return c.MemorySizeMB > 0 || (c.DiskPath != "" && c.DiskSizeGB > 0)
+}
+
+// GetBlobTTL returns the TTL for cached archives, defaulting to the general TTL
+func (c *CacheConfig) GetBlobTTL() time.Duration {
+	if c.BlobTTLSeconds > 0 {
+		return time.Duration(c.BlobTTLSeconds) * time.Second
+	}
+	return c.GetCacheTTL()
+}
+
+// GetIndexTTL returns the TTL for cached mirror protocol JSON, defaulting to the general TTL
+func (c *CacheConfig) GetIndexTTL() time.Duration {
+	if c.IndexTTLSeconds > 0 {
+		return time.Duration(c.IndexTTLSeconds) * time.Second
+	}
+	return c.GetCacheTTL()
+}
+
+// GetMaxStale returns how long past expiry a cached blob may still be served
+// when storage fails, or 0 when stale serving is disabled
+func (c *CacheConfig) GetMaxStale() time.Duration {
+	if !c.ServeStaleOnError {
+		return 0
+	}
+	return time.Duration(c.MaxStaleSeconds) * time.Second
+}
+
 // GetTimeout returns the auto-download timeout as a duration
 func (c *AutoDownloadConfig) GetTimeout() time.Duration {
 	return time.Duration(c.TimeoutSeconds) * time.Second
@@ -300,7 +556,7 @@ func (c *AutoDownloadConfig) GetNegativeCacheTTL() time.Duration {
 // GetPlatforms returns the configured platforms, with defaults if empty
 func (c *AutoDownloadConfig) GetPlatforms() []string {
 	if len(c.Platforms) == 0 {
-		return []string{"linux_amd64", "windows_amd64"}
+		return defaultPlatforms()
 	}
 	return c.Platforms
 }
@@ -329,6 +585,41 @@ func (c *AutoDownloadConfig) IsNamespaceAllowed(namespace string) bool {
 	return false
 }
 
+// IsProviderAllowed checks a provider against both the namespace and the
+// provider address lists. A block on either list wins. When any allow list
+// is set, the provider must be in an allowed namespace or be listed itself.
+func (c *AutoDownloadConfig) IsProviderAllowed(namespace, providerType string) bool {
+	address := namespace + "/" + providerType
+	for _, blocked := range c.BlockedNamespaces {
+		if blocked == namespace {
+			return false
+		}
+	}
+	for _, blocked := range c.BlockedProviders {
+		if blocked == address {
+			return false
+		}
+	}
+
+	// With no allow lists, everything not blocked is allowed
+	if len(c.AllowedNamespaces) == 0 && len(c.AllowedProviders) == 0 {
+		return true
+	}
+
+	for _, allowed := range c.AllowedNamespaces {
+		if allowed == namespace {
+			return true
+		}
+	}
+	for _, allowed := range c.AllowedProviders {
+		if allowed == address {
+			return true
+		}
+	}
+
+	return false
+}
+
 // GetDownloadRetryDelay returns the initial retry delay as a duration for modules
 func (c *ModulesConfig) GetDownloadRetryDelay() time.Duration {
 	return time.Duration(c.DownloadRetryInitialDelayMs) * time.Millisecond
@@ -339,6 +630,29 @@ func (c *ModulesConfig) GetDownloadTimeout() time.Duration {
 	return time.Duration(c.DownloadTimeoutSeconds) * time.Second
 }
 
+// GetURL returns the providers API base URL of the upstream registry
+func (c *UpstreamRegistryConfig) GetURL() string {
+	if c.URL == "" {
+		return "https://" + c.Hostname + "/v1/providers"
+	}
+	return strings.TrimSuffix(c.URL, "/")
+}
+
+// MatchesNamespace reports whether the upstream registry serves namespace
+func (c *UpstreamRegistryConfig) MatchesNamespace(namespace string) bool {
+	for _, ns := range c.Namespaces {
+		if ns == "*" || strings.EqualFold(ns, namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMaxDownloadSize returns the download size limit in bytes; 0 means unlimited
+func (c *FeaturesConfig) GetMaxDownloadSize() int64 {
+	return int64(c.MaxDownloadSizeMB) << 20
+}
+
 // GetUpstreamRegistry returns the upstream registry, defaulting to registry.terraform.io
 func (c *ModulesConfig) GetUpstreamRegistry() string {
 	if c.UpstreamRegistry == "" {
@@ -380,3 +694,73 @@ func (c *AutoDownloadModulesConfig) IsNamespaceAllowed(namespace string) bool {
 
 	return false
 }
+
+// GetProvidersPath returns the advertised providers.v1 path, defaulting to /v1/providers/
+func (c *DiscoveryConfig) GetProvidersPath() string {
+	if c == nil || c.ProvidersPath == "" {
+		return "/v1/providers/"
+	}
+	return c.ProvidersPath
+}
+
+// GetModulesPath returns the advertised modules.v1 path, defaulting to /v1/modules/
+func (c *DiscoveryConfig) GetModulesPath() string {
+	if c == nil || c.ModulesPath == "" {
+		return "/v1/modules/"
+	}
+	return c.ModulesPath
+}
+
+// Default per-route-group request timeouts
+const (
+	DefaultRequestTimeoutSeconds     = 30
+	DefaultLongRequestTimeoutSeconds = 1800
+)
+
+// GetRequestTimeout returns the timeout for admin JSON and protocol metadata requests
+func (c *ServerConfig) GetRequestTimeout() time.Duration {
+	if c.RequestTimeoutSeconds > 0 {
+		return time.Duration(c.RequestTimeoutSeconds) * time.Second
+	}
+	return DefaultRequestTimeoutSeconds * time.Second
+}
+
+// GetLongRequestTimeout returns the timeout for long-running admin requests
+// such as uploads, imports and exports
+func (c *ServerConfig) GetLongRequestTimeout() time.Duration {
+	if c.LongRequestTimeoutSeconds > 0 {
+		return time.Duration(c.LongRequestTimeoutSeconds) * time.Second
+	}
+	return DefaultLongRequestTimeoutSeconds * time.Second
+}
+
+// IsModulesEnabled returns whether the module registry protocol is served and advertised
+func (c *DiscoveryConfig) IsModulesEnabled() bool {
+	return c == nil || !c.DisableModules
+}
+
+// DefaultSyncSchedule runs the upstream re-sync daily at 03:00
+const DefaultSyncSchedule = "0 3 * * *"
+
+// IsEnabled returns whether the scheduled re-sync is enabled
+func (c *SyncConfig) IsEnabled() bool {
+	return c != nil && c.Enabled
+}
+
+// GetSchedule returns the re-sync cron expression, defaulting to daily at 03:00
+func (c *SyncConfig) GetSchedule() string {
+	if c == nil || c.Schedule == "" {
+		return DefaultSyncSchedule
+	}
+	return c.Schedule
+}
+
+// SyncProviders returns whether providers are re-synced
+func (c *SyncConfig) SyncProviders() bool {
+	return c == nil || !c.DisableProviders
+}
+
+// SyncModules returns whether modules are re-synced
+func (c *SyncConfig) SyncModules() bool {
+	return c == nil || !c.DisableModules
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,6 +23,9 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, 12, cfg.Auth.BCryptCost)
 	assert.Equal(t, "info", cfg.Logging.Level)
 	assert.True(t, cfg.Providers.GPGVerificationEnabled)
+	assert.True(t, cfg.Providers.ImmutableArtifacts)
+	assert.False(t, cfg.Providers.ReindexFromStorage)
+	assert.True(t, cfg.Providers.ReindexVerifyShasum)
 	assert.False(t, cfg.Features.AutoDownloadProviders)
 }
 
@@ -58,6 +62,7 @@ features {
 }
 
 auth {
+  jwt_secret = "file-secret"
   jwt_expiration_hours = 24
   bcrypt_cost = 10
 }
@@ -82,6 +87,11 @@ processor {
 providers {
   gpg_verification_enabled = true
   gpg_key_url = "https://www.hashicorp.com/.well-known/pgp-key.txt"
+
+  upstream "registry.example.com" {
+    namespaces = ["acme"]
+    token      = "file-token"
+  }
 }
 
 modules {
@@ -122,6 +132,28 @@ auto_download {
 	assert.Equal(t, 10, cfg.Auth.BCryptCost)
 	assert.Equal(t, "debug", cfg.Logging.Level)
 	assert.Equal(t, "json", cfg.Logging.Format)
+	require.Len(t, cfg.Providers.Upstreams, 1)
+	assert.Equal(t, "registry.example.com", cfg.Providers.Upstreams[0].Hostname)
+	assert.Equal(t, "https://registry.example.com/v1/providers", cfg.Providers.Upstreams[0].GetURL())
+	assert.Equal(t, "file-token", cfg.Providers.Upstreams[0].Token)
+	assert.True(t, cfg.Providers.Upstreams[0].MatchesNamespace("ACME"))
+	assert.False(t, cfg.Providers.Upstreams[0].MatchesNamespace("hashicorp"))
+
+	// Tokens can be kept out of the file
+	t.Setenv("TFM_UPSTREAM_TOKEN_registry_example_com", "env-token")
+	cfg, err = Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "env-token", cfg.Providers.Upstreams[0].Token)
+
+	// So can basic auth passwords
+	basicAuth := strings.Replace(configContent, `token      = "file-token"`, `username   = "ci"`, 1)
+	require.NoError(t, os.WriteFile(configPath, []byte(basicAuth), 0644))
+	t.Setenv("TFM_UPSTREAM_TOKEN_registry_example_com", "")
+	t.Setenv("TFM_UPSTREAM_PASSWORD_registry_example_com", "env-password")
+	cfg, err = Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "ci", cfg.Providers.Upstreams[0].Username)
+	assert.Equal(t, "env-password", cfg.Providers.Upstreams[0].Password)
 }
 
 func TestLoadNonExistentFile(t *testing.T) {
@@ -130,6 +162,129 @@ func TestLoadNonExistentFile(t *testing.T) {
 	assert.Contains(t, err.Error(), "config file not found")
 }
 
+func TestLoadDirectory(t *testing.T) {
+	t.Setenv("TFM_AUTH_JWT_SECRET", "test-secret")
+	dir := t.TempDir()
+
+	base := `
+server {
+  port       = 8080
+  public_url = "https://mirror.example.com"
+}
+
+storage {
+  type   = "s3"
+  bucket = "base-bucket"
+  region = "us-east-1"
+}
+
+auto_download {
+  enabled   = true
+  platforms = ["linux_amd64", "windows_amd64"]
+}
+
+providers {
+  upstream "registry.example.com" {
+    namespaces = ["acme"]
+  }
+}
+`
+	overlay := `
+server {
+  port = 9090
+}
+
+storage {
+  bucket = "prod-bucket"
+}
+
+auto_download {
+  platforms = ["linux_arm64"]
+}
+
+providers {
+  upstream "artifactory.example.com" {
+    namespaces = ["*"]
+  }
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "00-base.hcl"), []byte(base), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "10-prod.hcl"), []byte(overlay), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not config"), 0644))
+
+	cfg, err := Load(dir)
+	require.NoError(t, err)
+
+	// The overlay wins for what it sets
+	assert.Equal(t, 9090, cfg.Server.Port)
+	assert.Equal(t, "prod-bucket", cfg.Storage.Bucket)
+	assert.Equal(t, []string{"linux_arm64"}, cfg.AutoDownload.Platforms)
+
+	// Values only the base sets are kept, as are defaults neither sets
+	assert.Equal(t, "https://mirror.example.com", cfg.Server.PublicURL)
+	assert.Equal(t, "us-east-1", cfg.Storage.Region)
+	assert.True(t, cfg.AutoDownload.Enabled)
+	assert.Equal(t, DefaultConfig().Database.Path, cfg.Database.Path)
+
+	// Upstream registries accumulate
+	require.Len(t, cfg.Providers.Upstreams, 2)
+	assert.Equal(t, "registry.example.com", cfg.Providers.Upstreams[0].Hostname)
+	assert.Equal(t, "artifactory.example.com", cfg.Providers.Upstreams[1].Hostname)
+
+	// Environment variables still take precedence over every file
+	t.Setenv("TFM_SERVER_PORT", "7070")
+	cfg, err = Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 7070, cfg.Server.Port)
+}
+
+func TestLoadDirectory_Conflicts(t *testing.T) {
+	t.Setenv("TFM_AUTH_JWT_SECRET", "test-secret")
+
+	t.Run("upstream defined twice", func(t *testing.T) {
+		dir := t.TempDir()
+		upstream := `
+providers {
+  upstream "registry.example.com" {
+    namespaces = ["acme"]
+  }
+}
+`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "a.hcl"), []byte(upstream), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "b.hcl"), []byte(upstream), 0644))
+
+		_, err := Load(dir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `b.hcl: upstream "registry.example.com" is already defined in a.hcl`)
+	})
+
+	t.Run("block repeated in one file", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "a.hcl"), []byte("server {\n  port = 1\n}\nserver {\n  port = 2\n}\n"), 0644))
+
+		_, err := Load(dir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "duplicate server block")
+	})
+
+	t.Run("empty directory", func(t *testing.T) {
+		_, err := Load(t.TempDir())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no *.hcl files")
+	})
+}
+
+func TestLoad_ConfigEnvVar(t *testing.T) {
+	t.Setenv("TFM_AUTH_JWT_SECRET", "test-secret")
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.hcl"), []byte("server {\n  port = 9191\n}\n"), 0644))
+
+	t.Setenv("TFM_CONFIG", dir)
+	cfg, err := Load("")
+	require.NoError(t, err)
+	assert.Equal(t, 9191, cfg.Server.Port)
+}
+
 func TestEnvOverrides(t *testing.T) {
 	// Set environment variables
 	os.Setenv("TFM_SERVER_PORT", "3000")
@@ -138,6 +293,7 @@ func TestEnvOverrides(t *testing.T) {
 	os.Setenv("TFM_AUTH_JWT_EXPIRATION_HOURS", "12")
 	os.Setenv("TFM_LOGGING_LEVEL", "error")
 	os.Setenv("TFM_FEATURES_AUTO_DOWNLOAD_PROVIDERS", "true")
+	os.Setenv("TFM_AUTH_JWT_SECRETS", "new-secret,old-secret")
 
 	defer func() {
 		os.Unsetenv("TFM_SERVER_PORT")
@@ -146,6 +302,7 @@ func TestEnvOverrides(t *testing.T) {
 		os.Unsetenv("TFM_AUTH_JWT_EXPIRATION_HOURS")
 		os.Unsetenv("TFM_LOGGING_LEVEL")
 		os.Unsetenv("TFM_FEATURES_AUTO_DOWNLOAD_PROVIDERS")
+		os.Unsetenv("TFM_AUTH_JWT_SECRETS")
 	}()
 
 	// Load config with no file (uses defaults + env overrides)
@@ -159,6 +316,64 @@ func TestEnvOverrides(t *testing.T) {
 	assert.Equal(t, 12, cfg.Auth.JWTExpirationHours)
 	assert.Equal(t, "error", cfg.Logging.Level)
 	assert.True(t, cfg.Features.AutoDownloadProviders)
+	assert.Equal(t, []string{"new-secret", "old-secret"}, cfg.Auth.GetJWTSecrets())
+}
+
+func TestAutoDownloadPlatformsInheritProviderDefaults(t *testing.T) {
+	t.Setenv("TFM_AUTH_JWT_SECRET", "test-secret")
+	os.Setenv("TFM_PROVIDERS_PLATFORMS", "linux_arm64,darwin_arm64")
+	defer os.Unsetenv("TFM_PROVIDERS_PLATFORMS")
+
+	cfg, err := Load("")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"linux_arm64", "darwin_arm64"}, cfg.Providers.GetPlatforms())
+	assert.Equal(t, []string{"linux_arm64", "darwin_arm64"}, cfg.AutoDownload.GetPlatforms())
+
+	// An explicit auto-download list takes precedence
+	os.Setenv("TFM_AUTO_DOWNLOAD_PLATFORMS", "windows_amd64")
+	defer os.Unsetenv("TFM_AUTO_DOWNLOAD_PLATFORMS")
+
+	cfg, err = Load("")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"linux_arm64", "darwin_arm64"}, cfg.Providers.GetPlatforms())
+	assert.Equal(t, []string{"windows_amd64"}, cfg.AutoDownload.GetPlatforms())
+}
+
+func TestAutoDownloadConfig_IsProviderAllowed(t *testing.T) {
+	// A blocked provider is rejected even though its namespace is allowed
+	cfg := &AutoDownloadConfig{
+		AllowedNamespaces: []string{"hashicorp"},
+		BlockedProviders:  []string{"hashicorp/null"},
+	}
+	assert.True(t, cfg.IsProviderAllowed("hashicorp", "aws"))
+	assert.False(t, cfg.IsProviderAllowed("hashicorp", "null"))
+	assert.False(t, cfg.IsProviderAllowed("acme", "widget"))
+
+	// An allowed provider is accepted even though its namespace is not
+	cfg = &AutoDownloadConfig{
+		AllowedNamespaces: []string{"hashicorp"},
+		AllowedProviders:  []string{"integrations/github"},
+	}
+	assert.True(t, cfg.IsProviderAllowed("integrations", "github"))
+	assert.False(t, cfg.IsProviderAllowed("integrations", "other"))
+	assert.True(t, cfg.IsProviderAllowed("hashicorp", "aws"))
+
+	// Only listed providers when there is no namespace allow list
+	cfg = &AutoDownloadConfig{AllowedProviders: []string{"hashicorp/aws"}}
+	assert.True(t, cfg.IsProviderAllowed("hashicorp", "aws"))
+	assert.False(t, cfg.IsProviderAllowed("hashicorp", "azurerm"))
+
+	// A blocked namespace wins over an allowed provider
+	cfg = &AutoDownloadConfig{
+		BlockedNamespaces: []string{"integrations"},
+		AllowedProviders:  []string{"integrations/github"},
+	}
+	assert.False(t, cfg.IsProviderAllowed("integrations", "github"))
+
+	// No lists allow everything
+	assert.True(t, (&AutoDownloadConfig{}).IsProviderAllowed("acme", "widget"))
 }
 
 func TestParseBool(t *testing.T) {
@@ -221,6 +436,12 @@ func TestValidateServer(t *testing.T) {
 			shouldError: true,
 			errorMsg:    "tls_cert_path is required",
 		},
+		{
+			name:        "relative public url",
+			config:      ServerConfig{Port: 8080, PublicURL: "mirror.example.com"},
+			shouldError: true,
+			errorMsg:    "public_url must be an absolute",
+		},
 	}
 
 	for _, tt := range tests {
@@ -236,6 +457,12 @@ func TestValidateServer(t *testing.T) {
 	}
 }
 
+func TestGetPublicURL(t *testing.T) {
+	assert.Equal(t, "http://localhost:8080", (&ServerConfig{Port: 8080}).GetPublicURL())
+	assert.Equal(t, "https://localhost:8443", (&ServerConfig{Port: 8443, TLSEnabled: true}).GetPublicURL())
+	assert.Equal(t, "https://mirror.example.com", (&ServerConfig{Port: 8080, PublicURL: "https://mirror.example.com/"}).GetPublicURL())
+}
+
 func TestValidateStorage(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -259,7 +486,7 @@ func TestValidateStorage(t *testing.T) {
 				Bucket: "test-bucket",
 			},
 			shouldError: true,
-			errorMsg:    "storage type must be one of",
+			errorMsg:    "type must be one of",
 		},
 		{
 			name: "missing bucket",
@@ -268,7 +495,7 @@ func TestValidateStorage(t *testing.T) {
 				Region: "us-east-1",
 			},
 			shouldError: true,
-			errorMsg:    "bucket name is required",
+			errorMsg:    "bucket is required",
 		},
 		{
 			name: "S3 missing region and endpoint",
@@ -277,7 +504,7 @@ func TestValidateStorage(t *testing.T) {
 				Bucket: "test-bucket",
 			},
 			shouldError: true,
-			errorMsg:    "either region or endpoint must be specified",
+			errorMsg:    "region or endpoint must be specified",
 		},
 	}
 
@@ -304,6 +531,7 @@ func TestValidateAuth(t *testing.T) {
 		{
 			name: "valid config",
 			config: AuthConfig{
+				JWTSecret:          "secret",
 				JWTExpirationHours: 8,
 				BCryptCost:         12,
 			},
@@ -312,6 +540,7 @@ func TestValidateAuth(t *testing.T) {
 		{
 			name: "invalid JWT expiration",
 			config: AuthConfig{
+				JWTSecret:          "secret",
 				JWTExpirationHours: 0,
 				BCryptCost:         12,
 			},
@@ -321,6 +550,7 @@ func TestValidateAuth(t *testing.T) {
 		{
 			name: "bcrypt cost too low",
 			config: AuthConfig{
+				JWTSecret:          "secret",
 				JWTExpirationHours: 8,
 				BCryptCost:         3,
 			},
@@ -330,12 +560,52 @@ func TestValidateAuth(t *testing.T) {
 		{
 			name: "bcrypt cost too high",
 			config: AuthConfig{
+				JWTSecret:          "secret",
 				JWTExpirationHours: 8,
 				BCryptCost:         32,
 			},
 			shouldError: true,
 			errorMsg:    "bcrypt_cost must be between 4 and 31",
 		},
+		{
+			name: "rotation list",
+			config: AuthConfig{
+				JWTSecrets:         []string{"new-secret", "old-secret"},
+				JWTExpirationHours: 8,
+				BCryptCost:         12,
+			},
+			shouldError: false,
+		},
+		{
+			name: "no secret",
+			config: AuthConfig{
+				JWTExpirationHours: 8,
+				BCryptCost:         12,
+			},
+			shouldError: true,
+			errorMsg:    "jwt_secret or jwt_secrets is required",
+		},
+		{
+			name: "both secret forms",
+			config: AuthConfig{
+				JWTSecret:          "secret",
+				JWTSecrets:         []string{"new-secret"},
+				JWTExpirationHours: 8,
+				BCryptCost:         12,
+			},
+			shouldError: true,
+			errorMsg:    "jwt_secret and jwt_secrets cannot both be set",
+		},
+		{
+			name: "empty rotation entry",
+			config: AuthConfig{
+				JWTSecrets:         []string{"new-secret", " "},
+				JWTExpirationHours: 8,
+				BCryptCost:         12,
+			},
+			shouldError: true,
+			errorMsg:    "jwt_secrets[1] is empty",
+		},
 	}
 
 	for _, tt := range tests {
@@ -375,7 +645,7 @@ func TestValidateLogging(t *testing.T) {
 				Output: "stdout",
 			},
 			shouldError: true,
-			errorMsg:    "logging level must be one of",
+			errorMsg:    "level must be one of",
 		},
 		{
 			name: "invalid format",
@@ -385,7 +655,7 @@ func TestValidateLogging(t *testing.T) {
 				Output: "stdout",
 			},
 			shouldError: true,
-			errorMsg:    "logging format must be one of",
+			errorMsg:    "format must be one of",
 		},
 		{
 			name: "file output without path",
@@ -419,6 +689,14 @@ func TestConfigHelperMethods(t *testing.T) {
 	jwtExp := cfg.Auth.GetJWTExpiration()
 	assert.Equal(t, 8*time.Hour, jwtExp)
 
+	// Test JWT secrets, signing secret first
+	assert.Nil(t, cfg.Auth.GetJWTSecrets())
+	cfg.Auth.JWTSecret = "secret"
+	assert.Equal(t, []string{"secret"}, cfg.Auth.GetJWTSecrets())
+	cfg.Auth.JWTSecret = ""
+	cfg.Auth.JWTSecrets = []string{"new-secret", "old-secret"}
+	assert.Equal(t, []string{"new-secret", "old-secret"}, cfg.Auth.GetJWTSecrets())
+
 	// Test download retry delay
 	retryDelay := cfg.Providers.GetDownloadRetryDelay()
 	assert.Equal(t, 1000*time.Millisecond, retryDelay)
@@ -434,10 +712,32 @@ func TestConfigHelperMethods(t *testing.T) {
 	// Test cache TTL
 	cacheTTL := cfg.Cache.GetCacheTTL()
 	assert.Equal(t, 3600*time.Second, cacheTTL)
+
+	// Test per-content-type cache TTLs
+	assert.Equal(t, 3600*time.Second, cfg.Cache.GetBlobTTL())
+	assert.Equal(t, 60*time.Second, cfg.Cache.GetIndexTTL())
+
+	// The cache is enabled by a memory size, or a disk path with a size
+	assert.True(t, cfg.Cache.IsEnabled())
+	assert.False(t, (&CacheConfig{DiskPath: "/var/cache/tf-mirror"}).IsEnabled())
+	assert.True(t, (&CacheConfig{DiskPath: "/var/cache/tf-mirror", DiskSizeGB: 1}).IsEnabled())
+	assert.False(t, (&CacheConfig{}).IsEnabled())
+
+	// Stale serving is off by default
+	assert.Zero(t, cfg.Cache.GetMaxStale())
+	cfg.Cache.ServeStaleOnError = true
+	assert.Equal(t, time.Hour, cfg.Cache.GetMaxStale())
 }
 
-func TestFullValidation(t *testing.T) {
+// validConfig returns the defaults plus the settings that have no default
+func validConfig() *Config {
 	cfg := DefaultConfig()
+	cfg.Auth.JWTSecret = "test-secret"
+	return cfg
+}
+
+func TestFullValidation(t *testing.T) {
+	cfg := validConfig()
 
 	// Default config should be valid
 	err := Validate(cfg)
@@ -447,12 +747,20 @@ func TestFullValidation(t *testing.T) {
 	cfg.Server.Port = -1
 	err = Validate(cfg)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "server config")
+	assert.Contains(t, err.Error(), "server.port must be between")
 
 	// Reset and test storage
-	cfg = DefaultConfig()
+	cfg = validConfig()
 	cfg.Storage.Bucket = ""
 	err = Validate(cfg)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "storage config")
+	assert.Contains(t, err.Error(), "storage.bucket is required")
+
+	// Reset and test sync schedule
+	cfg = validConfig()
+	cfg.Sync.Enabled = true
+	cfg.Sync.Schedule = "every night"
+	err = Validate(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "sync.schedule is invalid")
 }
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 
@@ -10,14 +12,24 @@ import (
 	"github.com/hashicorp/hcl/v2/hclparse"
 )
 
-// Load reads configuration from a file and applies environment variable overrides
+// Load reads configuration from a file, or every *.hcl file in a directory,
+// and applies environment variable overrides. An empty path falls back to
+// TFM_CONFIG.
 func Load(configPath string) (*Config, error) {
 	// Start with defaults
 	cfg := DefaultConfig()
 
-	// Load from HCL file if provided
+	if configPath == "" {
+		configPath = os.Getenv("TFM_CONFIG")
+	}
+
+	// Load from HCL file or directory if provided
 	if configPath != "" {
-		if err := loadFromFile(configPath, cfg); err != nil {
+		if info, err := os.Stat(configPath); err == nil && info.IsDir() {
+			if err := loadFromDir(configPath, cfg); err != nil {
+				return nil, fmt.Errorf("failed to load config directory: %w", err)
+			}
+		} else if err := loadFromFile(configPath, cfg); err != nil {
 			return nil, fmt.Errorf("failed to load config file: %w", err)
 		}
 	}
@@ -56,6 +68,99 @@ func loadFromFile(path string, cfg *Config) error {
 	return nil
 }
 
+// loadFromDir merges every *.hcl file in dir in lexical order, so a base
+// file can be followed by environment overlays. Unlike a single file, each
+// may hold any subset of blocks. Attributes a later file sets replace earlier
+// values, including lists; attributes it omits keep them. Upstream registries
+// accumulate across files, but a hostname may only be defined once.
+func loadFromDir(dir string, cfg *Config) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.hcl"))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no *.hcl files in %s", dir)
+	}
+
+	upstreamFiles := make(map[string]string) // hostname -> file defining it
+	for _, path := range paths {
+		if err := mergeFile(path, cfg, upstreamFiles); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
+
+// mergeFile decodes the blocks present in one file over cfg
+func mergeFile(path string, cfg *Config, upstreamFiles map[string]string) error {
+	parser := hclparse.NewParser()
+	file, diags := parser.ParseHCLFile(path)
+	if diags.HasErrors() {
+		return fmt.Errorf("failed to parse HCL file: %s", diags.Error())
+	}
+
+	schema, _ := gohcl.ImpliedBodySchema(cfg)
+	content, diags := file.Body.Content(schema)
+	if diags.HasErrors() {
+		return fmt.Errorf("failed to decode HCL: %s", diags.Error())
+	}
+
+	fields := blockFields(cfg)
+	seen := make(map[string]bool)
+	for _, block := range content.Blocks {
+		// Files merge with each other, but within one file a block that may
+		// only appear once is still ambiguous
+		if seen[block.Type] {
+			return fmt.Errorf("duplicate %s block; it may only appear once per file", block.Type)
+		}
+		seen[block.Type] = true
+
+		field := fields[block.Type]
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+		} else {
+			field = field.Addr()
+		}
+
+		// Decode upstream blocks on their own so they are added to, not
+		// merged by position into, those from earlier files
+		var upstreams []UpstreamRegistryConfig
+		if block.Type == "providers" {
+			upstreams, cfg.Providers.Upstreams = cfg.Providers.Upstreams, nil
+		}
+
+		if diags := gohcl.DecodeBody(block.Body, nil, field.Interface()); diags.HasErrors() {
+			return fmt.Errorf("failed to decode HCL: %s", diags.Error())
+		}
+
+		if block.Type == "providers" {
+			for _, upstream := range cfg.Providers.Upstreams {
+				if previous, ok := upstreamFiles[upstream.Hostname]; ok {
+					return fmt.Errorf("upstream %q is already defined in %s", upstream.Hostname, previous)
+				}
+				upstreamFiles[upstream.Hostname] = filepath.Base(path)
+			}
+			cfg.Providers.Upstreams = append(upstreams, cfg.Providers.Upstreams...)
+		}
+	}
+	return nil
+}
+
+// blockFields maps each top-level block name to its field in cfg
+func blockFields(cfg *Config) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value)
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name, kind, _ := strings.Cut(v.Type().Field(i).Tag.Get("hcl"), ",")
+		if kind == "block" {
+			fields[name] = v.Field(i)
+		}
+	}
+	return fields
+}
+
 // applyEnvOverrides applies environment variable overrides with TFM_ prefix
 func applyEnvOverrides(cfg *Config) {
 	// Server configuration
@@ -76,6 +181,39 @@ func applyEnvOverrides(cfg *Config) {
 	if val := os.Getenv("TFM_SERVER_BEHIND_PROXY"); val != "" {
 		cfg.Server.BehindProxy = parseBool(val)
 	}
+	if val := os.Getenv("TFM_SERVER_PUBLIC_URL"); val != "" {
+		cfg.Server.PublicURL = val
+	}
+	if val := os.Getenv("TFM_SERVER_MAX_CONCURRENT_DOWNLOADS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.Server.MaxConcurrentDownloads = n
+		}
+	}
+	if val := os.Getenv("TFM_SERVER_DOWNLOAD_QUEUE_TIMEOUT_SECONDS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.Server.DownloadQueueTimeoutSeconds = n
+		}
+	}
+	if val := os.Getenv("TFM_SERVER_SHUTDOWN_DOWNLOAD_WAIT_SECONDS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.Server.ShutdownDownloadWaitSeconds = n
+		}
+	}
+	if val := os.Getenv("TFM_SERVER_SLOW_DOWNLOAD_SECONDS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.Server.SlowDownloadSeconds = n
+		}
+	}
+	if val := os.Getenv("TFM_SERVER_REQUEST_TIMEOUT_SECONDS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.Server.RequestTimeoutSeconds = n
+		}
+	}
+	if val := os.Getenv("TFM_SERVER_LONG_REQUEST_TIMEOUT_SECONDS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.Server.LongRequestTimeoutSeconds = n
+		}
+	}
 
 	// Storage configuration
 	if val := os.Getenv("TFM_STORAGE_TYPE"); val != "" {
@@ -107,6 +245,19 @@ func applyEnvOverrides(cfg *Config) {
 	if val := os.Getenv("TFM_DATABASE_BACKUP_ENABLED"); val != "" {
 		cfg.Database.BackupEnabled = parseBool(val)
 	}
+	if val := os.Getenv("TFM_DATABASE_BUSY_TIMEOUT_MS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.Database.BusyTimeoutMS = n
+		}
+	}
+	if val := os.Getenv("TFM_DATABASE_SYNCHRONOUS"); val != "" {
+		cfg.Database.Synchronous = val
+	}
+	if val := os.Getenv("TFM_DATABASE_MAX_OPEN_CONNS"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.Database.MaxOpenConns = n
+		}
+	}
 
 	// Cache configuration
 	if val := os.Getenv("TFM_CACHE_MEMORY_SIZE_MB"); val != "" {
@@ -127,6 +278,33 @@ func applyEnvOverrides(cfg *Config) {
 			cfg.Cache.TTLSeconds = ttl
 		}
 	}
+	if val := os.Getenv("TFM_CACHE_BLOB_TTL_SECONDS"); val != "" {
+		if ttl, err := strconv.Atoi(val); err == nil {
+			cfg.Cache.BlobTTLSeconds = ttl
+		}
+	}
+	if val := os.Getenv("TFM_CACHE_INDEX_TTL_SECONDS"); val != "" {
+		if ttl, err := strconv.Atoi(val); err == nil {
+			cfg.Cache.IndexTTLSeconds = ttl
+		}
+	}
+	if val := os.Getenv("TFM_CACHE_MEMORY_EVICTION_POLICY"); val != "" {
+		cfg.Cache.MemoryEvictionPolicy = val
+	}
+	if val := os.Getenv("TFM_CACHE_DISK_ENCRYPTION_KEY"); val != "" {
+		cfg.Cache.DiskEncryptionKey = val
+	}
+	if val := os.Getenv("TFM_CACHE_SERVE_STALE_ON_ERROR"); val != "" {
+		cfg.Cache.ServeStaleOnError = parseBool(val)
+	}
+	if val := os.Getenv("TFM_CACHE_MAX_STALE_SECONDS"); val != "" {
+		if seconds, err := strconv.Atoi(val); err == nil {
+			cfg.Cache.MaxStaleSeconds = seconds
+		}
+	}
+	if val := os.Getenv("TFM_CACHE_COMPRESS_INDEX"); val != "" {
+		cfg.Cache.CompressIndex = parseBool(val)
+	}
 
 	// Features configuration
 	if val := os.Getenv("TFM_FEATURES_AUTO_DOWNLOAD_PROVIDERS"); val != "" {
@@ -137,6 +315,12 @@ func applyEnvOverrides(cfg *Config) {
 	}
 
 	// Auth configuration
+	if val := os.Getenv("TFM_AUTH_JWT_SECRET"); val != "" {
+		cfg.Auth.JWTSecret = val
+	}
+	if val := os.Getenv("TFM_AUTH_JWT_SECRETS"); val != "" {
+		cfg.Auth.JWTSecrets = strings.Split(val, ",")
+	}
 	if val := os.Getenv("TFM_AUTH_JWT_EXPIRATION_HOURS"); val != "" {
 		if hours, err := strconv.Atoi(val); err == nil {
 			cfg.Auth.JWTExpirationHours = hours
@@ -161,6 +345,14 @@ func applyEnvOverrides(cfg *Config) {
 	if val := os.Getenv("TFM_LOGGING_FILE_PATH"); val != "" {
 		cfg.Logging.FilePath = val
 	}
+	if val := os.Getenv("TFM_LOGGING_AUDIT_DOWNLOADS"); val != "" {
+		cfg.Logging.AuditDownloads = parseBool(val)
+	}
+	if val := os.Getenv("TFM_LOGGING_BUFFER_ENTRIES"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			cfg.Logging.BufferEntries = n
+		}
+	}
 
 	// Telemetry configuration
 	if val := os.Getenv("TFM_TELEMETRY_ENABLED"); val != "" {
@@ -180,6 +372,35 @@ func applyEnvOverrides(cfg *Config) {
 	if val := os.Getenv("TFM_PROVIDERS_GPG_KEY_URL"); val != "" {
 		cfg.Providers.GPGKeyURL = val
 	}
+	if val := os.Getenv("TFM_PROVIDERS_PLATFORMS"); val != "" {
+		cfg.Providers.Platforms = strings.Split(val, ",")
+	}
+	if val := os.Getenv("TFM_PROVIDERS_IMMUTABLE_ARTIFACTS"); val != "" {
+		cfg.Providers.ImmutableArtifacts = parseBool(val)
+	}
+	if val := os.Getenv("TFM_PROVIDERS_REINDEX_FROM_STORAGE"); val != "" {
+		cfg.Providers.ReindexFromStorage = parseBool(val)
+	}
+	if val := os.Getenv("TFM_PROVIDERS_REINDEX_VERIFY_SHASUM"); val != "" {
+		cfg.Providers.ReindexVerifyShasum = parseBool(val)
+	}
+	if val := os.Getenv("TFM_PROVIDERS_STORE_SHASUMS"); val != "" {
+		cfg.Providers.StoreShasums = parseBool(val)
+	}
+	if val := os.Getenv("TFM_PROVIDERS_INCLUDE_BLOCKED_FOR_ADMINS"); val != "" {
+		cfg.Providers.IncludeBlockedForAdmins = parseBool(val)
+	}
+	// Upstream tokens and passwords can be kept out of the config file, e.g.
+	// TFM_UPSTREAM_TOKEN_registry_example_com for registry.example.com
+	for i := range cfg.Providers.Upstreams {
+		upstream := &cfg.Providers.Upstreams[i]
+		if val := os.Getenv(UpstreamTokenEnv(upstream.Hostname)); val != "" {
+			upstream.Token = val
+		}
+		if val := os.Getenv(UpstreamPasswordEnv(upstream.Hostname)); val != "" {
+			upstream.Password = val
+		}
+	}
 
 	// Quota configuration
 	if val := os.Getenv("TFM_QUOTA_ENABLED"); val != "" {
@@ -198,7 +419,9 @@ func applyEnvOverrides(cfg *Config) {
 			Enabled:              false,
 			AllowedNamespaces:    []string{},
 			BlockedNamespaces:    []string{},
-			Platforms:            []string{"linux_amd64", "windows_amd64"},
+			AllowedProviders:     []string{},
+			BlockedProviders:     []string{},
+			Platforms:            []string{},
 			RateLimitPerMinute:   10,
 			MaxConcurrentDL:      3,
 			QueueSize:            100,
@@ -208,10 +431,6 @@ func applyEnvOverrides(cfg *Config) {
 			NegativeCacheTTL:     300,
 		}
 	}
-	// Set default platforms if empty
-	if len(cfg.AutoDownload.Platforms) == 0 {
-		cfg.AutoDownload.Platforms = []string{"linux_amd64", "windows_amd64"}
-	}
 	if val := os.Getenv("TFM_AUTO_DOWNLOAD_ENABLED"); val != "" {
 		cfg.AutoDownload.Enabled = parseBool(val)
 	}
@@ -230,15 +449,58 @@ func applyEnvOverrides(cfg *Config) {
 			cfg.AutoDownload.TimeoutSeconds = timeout
 		}
 	}
+	if val := os.Getenv("TFM_AUTO_DOWNLOAD_MAX_VERSIONS_PER_PROVIDER"); val != "" {
+		if max, err := strconv.Atoi(val); err == nil {
+			cfg.AutoDownload.MaxVersionsPerProvider = max
+		}
+	}
 	if val := os.Getenv("TFM_AUTO_DOWNLOAD_ALLOWED_NAMESPACES"); val != "" {
 		cfg.AutoDownload.AllowedNamespaces = strings.Split(val, ",")
 	}
 	if val := os.Getenv("TFM_AUTO_DOWNLOAD_BLOCKED_NAMESPACES"); val != "" {
 		cfg.AutoDownload.BlockedNamespaces = strings.Split(val, ",")
 	}
+	if val := os.Getenv("TFM_AUTO_DOWNLOAD_ALLOWED_PROVIDERS"); val != "" {
+		cfg.AutoDownload.AllowedProviders = strings.Split(val, ",")
+	}
+	if val := os.Getenv("TFM_AUTO_DOWNLOAD_BLOCKED_PROVIDERS"); val != "" {
+		cfg.AutoDownload.BlockedProviders = strings.Split(val, ",")
+	}
 	if val := os.Getenv("TFM_AUTO_DOWNLOAD_PLATFORMS"); val != "" {
 		cfg.AutoDownload.Platforms = strings.Split(val, ",")
 	}
+	// Inherit the provider platform defaults if none were set for auto-download
+	if len(cfg.AutoDownload.Platforms) == 0 {
+		cfg.AutoDownload.Platforms = append([]string(nil), cfg.Providers.GetPlatforms()...)
+	}
+
+	// Scheduled re-sync configuration
+	if cfg.Sync == nil {
+		cfg.Sync = &SyncConfig{Schedule: DefaultSyncSchedule}
+	}
+	if val := os.Getenv("TFM_SYNC_ENABLED"); val != "" {
+		cfg.Sync.Enabled = parseBool(val)
+	}
+	if val := os.Getenv("TFM_SYNC_SCHEDULE"); val != "" {
+		cfg.Sync.Schedule = val
+	}
+}
+
+// UpstreamTokenEnv returns the environment variable holding the token for an
+// upstream registry, with dots and dashes in the hostname as underscores
+func UpstreamTokenEnv(hostname string) string {
+	return "TFM_UPSTREAM_TOKEN_" + upstreamEnvSuffix(hostname)
+}
+
+// UpstreamPasswordEnv returns the environment variable holding the basic
+// auth password of an upstream registry
+func UpstreamPasswordEnv(hostname string) string {
+	return "TFM_UPSTREAM_PASSWORD_" + upstreamEnvSuffix(hostname)
+}
+
+// upstreamEnvSuffix turns a hostname into an environment variable suffix
+func upstreamEnvSuffix(hostname string) string {
+	return strings.NewReplacer(".", "_", "-", "_").Replace(hostname)
 }
 
 // parseBool parses a boolean value from string (supports: true/false, yes/no, 1/0)
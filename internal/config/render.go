@@ -0,0 +1,78 @@
+package config
+
+import (
+	"reflect"
+
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// RedactedSecret replaces the value of secret settings in rendered output
+const RedactedSecret = "[REDACTED]"
+
+// RenderHCL renders the configuration back to HCL. Settings tagged
+// secret:"true" are replaced with RedactedSecret. The output is generated
+// from the struct tags, so new settings and blocks appear without changes
+// here.
+func RenderHCL(cfg *Config) []byte {
+	redacted := redactSecrets(reflect.ValueOf(cfg).Elem())
+
+	f := hclwrite.NewEmptyFile()
+	gohcl.EncodeIntoBody(redacted.Addr().Interface(), f.Body())
+	return f.Bytes()
+}
+
+// redactSecrets returns a copy of v with every secret field redacted. The
+// copy shares no structs with v, so the live configuration is never touched.
+func redactSecrets(v reflect.Value) reflect.Value {
+	out := reflect.New(v.Type()).Elem()
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.Tag.Get("secret") == "true" {
+				out.Field(i).Set(redactValue(v.Field(i)))
+				continue
+			}
+			out.Field(i).Set(redactSecrets(v.Field(i)))
+		}
+	case reflect.Ptr:
+		if !v.IsNil() {
+			out.Set(redactSecrets(v.Elem()).Addr())
+		}
+	case reflect.Slice:
+		if !v.IsNil() {
+			out.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+			for i := 0; i < v.Len(); i++ {
+				out.Index(i).Set(redactSecrets(v.Index(i)))
+			}
+		}
+	default:
+		out.Set(v)
+	}
+	return out
+}
+
+// redactValue replaces a secret string, or each entry of a secret list.
+// Unset values stay empty so the output shows which secrets are configured.
+func redactValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.String:
+		if v.String() == "" {
+			return v
+		}
+		return reflect.ValueOf(RedactedSecret).Convert(v.Type())
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(redactValue(v.Index(i)))
+		}
+		return out
+	default:
+		return v
+	}
+}
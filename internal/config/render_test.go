@@ -0,0 +1,63 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderHCL(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Storage.AccessKey = "AKIAEXAMPLE"
+	cfg.Storage.SecretKey = "storage-secret"
+	cfg.Cache.DiskEncryptionKey = "0123456789abcdef"
+	cfg.Auth.JWTSecret = ""
+	cfg.Auth.JWTSecrets = []string{"current-secret", "previous-secret"}
+	cfg.Quota.Enabled = true
+	cfg.Quota.MaxStorageGB = 50
+	cfg.AutoDownload.MaxVersionsPerProvider = 3
+	cfg.Providers.Upstreams = []UpstreamRegistryConfig{
+		{Hostname: "registry.example.com", Namespaces: []string{"acme"}, Token: "registry-token"},
+		{Hostname: "mirror.example.org", Namespaces: []string{"*"}, Username: "ci", Password: "registry-password"},
+	}
+
+	rendered := RenderHCL(cfg)
+	out := string(rendered)
+
+	for _, secret := range []string{"AKIAEXAMPLE", "storage-secret", "0123456789abcdef", "current-secret", "previous-secret", "registry-token", "registry-password"} {
+		assert.NotContains(t, out, secret)
+	}
+	assert.Regexp(t, `secret_key\s+= "\[REDACTED\]"`, out)
+	assert.Regexp(t, `jwt_secret\s+= ""`, out, "unset secrets stay empty")
+
+	// Blocks that the JSON config view leaves out are rendered too
+	for _, block := range []string{"quota {", "auto_download {", "auto_download_modules {", "discovery {", "sync {", `upstream "registry.example.com" {`} {
+		assert.Contains(t, out, block)
+	}
+
+	// The rendered HCL decodes back to the same configuration, minus secrets
+	file, diags := hclparse.NewParser().ParseHCL(rendered, "rendered.hcl")
+	require.False(t, diags.HasErrors(), diags.Error())
+	var decoded Config
+	diags = gohcl.DecodeBody(file.Body, nil, &decoded)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	expected := *cfg
+	expected.Storage.AccessKey = RedactedSecret
+	expected.Storage.SecretKey = RedactedSecret
+	expected.Cache.DiskEncryptionKey = RedactedSecret
+	expected.Auth.JWTSecrets = []string{RedactedSecret, RedactedSecret}
+	expected.Providers.Upstreams = []UpstreamRegistryConfig{
+		{Hostname: "registry.example.com", Namespaces: []string{"acme"}, Token: RedactedSecret},
+		{Hostname: "mirror.example.org", Namespaces: []string{"*"}, Username: "ci", Password: RedactedSecret},
+	}
+	assert.Equal(t, expected, decoded)
+
+	// The live configuration is left untouched
+	assert.Equal(t, "storage-secret", cfg.Storage.SecretKey)
+	assert.Equal(t, "registry-token", cfg.Providers.Upstreams[0].Token)
+	assert.Equal(t, []string{"current-secret", "previous-secret"}, cfg.Auth.JWTSecrets)
+}
@@ -1,210 +1,514 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
 	"strings"
+
+	"github.com/ned1313/terraform-mirror/internal/schedule"
 )
 
-// Validate checks if the configuration is valid
-func Validate(cfg *Config) error {
-	if err := validateServer(&cfg.Server); err != nil {
-		return fmt.Errorf("server config: %w", err)
-	}
+// FieldError is one problem found in the configuration. Field is the dotted
+// path of the setting, e.g. cache.memory_size_mb.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// ValidationError lists every problem Validate found, so they can all be
+// fixed at once
+type ValidationError struct {
+	Errors []FieldError
+}
 
-	if err := validateStorage(&cfg.Storage); err != nil {
-		return fmt.Errorf("storage config: %w", err)
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
 	}
+	messages := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		messages[i] = fe.Error()
+	}
+	return fmt.Sprintf("%d configuration problems: %s", len(e.Errors), strings.Join(messages, "; "))
+}
+
+// problems collects the FieldErrors of one validation pass
+type problems struct {
+	errs []FieldError
+}
 
-	if err := validateDatabase(&cfg.Database); err != nil {
-		return fmt.Errorf("database config: %w", err)
+func (p *problems) add(field, format string, args ...interface{}) {
+	p.errs = append(p.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// merge adds the problems of a nested validation under prefix
+func (p *problems) merge(prefix string, err error) {
+	if err == nil {
+		return
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		p.add(prefix, "%v", err)
+		return
+	}
+	for _, fe := range verr.Errors {
+		p.errs = append(p.errs, FieldError{Field: joinField(prefix, fe.Field), Message: fe.Message})
 	}
+}
 
-	if err := validateCache(&cfg.Cache); err != nil {
-		return fmt.Errorf("cache config: %w", err)
+// err returns the collected problems, or nil if there are none
+func (p *problems) err() error {
+	if len(p.errs) == 0 {
+		return nil
 	}
+	return &ValidationError{Errors: p.errs}
+}
 
-	if err := validateAuth(&cfg.Auth); err != nil {
-		return fmt.Errorf("auth config: %w", err)
+// joinField appends a field to a path; indexes attach without a dot
+func joinField(prefix, field string) string {
+	if prefix == "" {
+		return field
 	}
+	if strings.HasPrefix(field, "[") {
+		return prefix + field
+	}
+	return prefix + "." + field
+}
 
-	if err := validateLogging(&cfg.Logging); err != nil {
-		return fmt.Errorf("logging config: %w", err)
+// Validate checks if the configuration is valid. It reports every problem
+// rather than stopping at the first, as a *ValidationError.
+func Validate(cfg *Config) error {
+	var p problems
+
+	p.merge("server", validateServer(&cfg.Server))
+	p.merge("storage", validateStorage(&cfg.Storage))
+	p.merge("database", validateDatabase(&cfg.Database))
+	p.merge("cache", validateCache(&cfg.Cache))
+	p.merge("auth", validateAuth(&cfg.Auth))
+	p.merge("logging", validateLogging(&cfg.Logging))
+	p.merge("telemetry", validateTelemetry(&cfg.Telemetry))
+
+	if cfg.Processor.MaxPollingIntervalSeconds < 0 {
+		p.add("processor.max_polling_interval_seconds", "cannot be negative")
 	}
 
-	if err := validateTelemetry(&cfg.Telemetry); err != nil {
-		return fmt.Errorf("telemetry config: %w", err)
+	if cfg.Processor.MaxPendingItems < 0 {
+		p.add("processor.max_pending_items", "cannot be negative")
 	}
 
-	if err := validateProviders(&cfg.Providers); err != nil {
-		return fmt.Errorf("providers config: %w", err)
+	if cfg.Features.MaxDownloadSizeMB < 0 {
+		p.add("features.max_download_size_mb", "cannot be negative")
 	}
 
-	if err := validateQuota(&cfg.Quota); err != nil {
-		return fmt.Errorf("quota config: %w", err)
+	p.merge("providers", validateProviders(&cfg.Providers))
+	p.merge("quota", validateQuota(&cfg.Quota))
+	p.merge("discovery", validateDiscovery(cfg.Discovery))
+
+	if cfg.AutoDownload != nil {
+		p.merge("auto_download.platforms", validatePlatforms(cfg.AutoDownload.Platforms))
+		p.merge("auto_download.allowed_providers", validateProviderAddresses(cfg.AutoDownload.AllowedProviders))
+		p.merge("auto_download.blocked_providers", validateProviderAddresses(cfg.AutoDownload.BlockedProviders))
+		if cfg.AutoDownload.MaxVersionsPerProvider < 0 {
+			p.add("auto_download.max_versions_per_provider", "cannot be negative")
+		}
 	}
 
-	return nil
+	p.merge("sync", validateSync(cfg.Sync))
+
+	return p.err()
 }
 
 func validateServer(cfg *ServerConfig) error {
+	var p problems
+
 	if cfg.Port < 1 || cfg.Port > 65535 {
-		return fmt.Errorf("port must be between 1 and 65535, got %d", cfg.Port)
+		p.add("port", "must be between 1 and 65535, got %d", cfg.Port)
 	}
 
 	if cfg.TLSEnabled {
 		if cfg.TLSCertPath == "" {
-			return fmt.Errorf("tls_cert_path is required when TLS is enabled")
+			p.add("tls_cert_path", "is required when TLS is enabled")
+		} else if _, err := os.Stat(cfg.TLSCertPath); os.IsNotExist(err) {
+			p.add("tls_cert_path", "file not found: %s", cfg.TLSCertPath)
 		}
 		if cfg.TLSKeyPath == "" {
-			return fmt.Errorf("tls_key_path is required when TLS is enabled")
+			p.add("tls_key_path", "is required when TLS is enabled")
+		} else if _, err := os.Stat(cfg.TLSKeyPath); os.IsNotExist(err) {
+			p.add("tls_key_path", "file not found: %s", cfg.TLSKeyPath)
 		}
-		if _, err := os.Stat(cfg.TLSCertPath); os.IsNotExist(err) {
-			return fmt.Errorf("tls_cert_path file not found: %s", cfg.TLSCertPath)
+	}
+
+	for i, proxy := range cfg.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err == nil {
+			continue
 		}
-		if _, err := os.Stat(cfg.TLSKeyPath); os.IsNotExist(err) {
-			return fmt.Errorf("tls_key_path file not found: %s", cfg.TLSKeyPath)
+		if _, err := netip.ParseAddr(proxy); err != nil {
+			p.add(fmt.Sprintf("trusted_proxies[%d]", i), "must be an IP address or CIDR range, got %q", proxy)
 		}
 	}
 
-	return nil
+	if cfg.PublicURL != "" {
+		u, err := url.Parse(cfg.PublicURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			p.add("public_url", "must be an absolute http(s) URL, got %q", cfg.PublicURL)
+		}
+	}
+
+	for i, cidr := range cfg.AdminAllowedCIDRs {
+		if _, err := netip.ParsePrefix(cidr); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(cidr); err != nil {
+			p.add(fmt.Sprintf("admin_allowed_cidrs[%d]", i), "must be an IP address or CIDR range, got %q", cidr)
+		}
+	}
+
+	if cfg.MaxConcurrentDownloads < 0 {
+		p.add("max_concurrent_downloads", "cannot be negative")
+	}
+
+	if cfg.DownloadQueueTimeoutSeconds < 0 {
+		p.add("download_queue_timeout_seconds", "cannot be negative")
+	}
+
+	if cfg.ShutdownDownloadWaitSeconds < 0 {
+		p.add("shutdown_download_wait_seconds", "cannot be negative")
+	}
+
+	if cfg.SlowDownloadSeconds < 0 {
+		p.add("slow_download_seconds", "cannot be negative")
+	}
+
+	if cfg.RequestTimeoutSeconds < 0 {
+		p.add("request_timeout_seconds", "cannot be negative")
+	}
+
+	if cfg.LongRequestTimeoutSeconds < 0 {
+		p.add("long_request_timeout_seconds", "cannot be negative")
+	}
+
+	return p.err()
 }
 
 func validateStorage(cfg *StorageConfig) error {
+	var p problems
+
 	validTypes := []string{"s3", "local"}
 	if !contains(validTypes, cfg.Type) {
-		return fmt.Errorf("storage type must be one of %v, got %s", validTypes, cfg.Type)
+		p.add("type", "must be one of %v, got %s", validTypes, cfg.Type)
 	}
 
 	if cfg.Bucket == "" {
-		return fmt.Errorf("bucket name is required")
+		p.add("bucket", "is required")
 	}
 
 	if cfg.Type == "s3" {
 		if cfg.Region == "" && cfg.Endpoint == "" {
-			return fmt.Errorf("either region or endpoint must be specified for S3 storage")
+			p.add("region", "or endpoint must be specified for S3 storage")
 		}
 	}
 
-	return nil
+	return p.err()
 }
 
 func validateDatabase(cfg *DatabaseConfig) error {
+	var p problems
+
 	if cfg.Path == "" {
-		return fmt.Errorf("database path is required")
+		p.add("path", "is required")
 	}
 
 	if cfg.BackupEnabled {
 		if cfg.BackupIntervalHours < 1 {
-			return fmt.Errorf("backup_interval_hours must be at least 1")
+			p.add("backup_interval_hours", "must be at least 1")
 		}
 	}
 
-	return nil
+	if cfg.BusyTimeoutMS < 0 {
+		p.add("busy_timeout_ms", "cannot be negative")
+	}
+
+	switch strings.ToUpper(cfg.Synchronous) {
+	case "", "OFF", "NORMAL", "FULL", "EXTRA":
+	default:
+		p.add("synchronous", "must be OFF, NORMAL, FULL or EXTRA, got %q", cfg.Synchronous)
+	}
+
+	if cfg.MaxOpenConns < 0 {
+		p.add("max_open_conns", "cannot be negative")
+	}
+
+	return p.err()
 }
 
 func validateCache(cfg *CacheConfig) error {
+	var p problems
+
 	if cfg.MemorySizeMB < 0 {
-		return fmt.Errorf("memory_size_mb cannot be negative")
+		p.add("memory_size_mb", "cannot be negative")
 	}
 
 	if cfg.DiskSizeGB < 0 {
-		return fmt.Errorf("disk_size_gb cannot be negative")
+		p.add("disk_size_gb", "cannot be negative")
 	}
 
 	if cfg.TTLSeconds < 0 {
-		return fmt.Errorf("ttl_seconds cannot be negative")
+		p.add("ttl_seconds", "cannot be negative")
+	}
+
+	if cfg.BlobTTLSeconds < 0 {
+		p.add("blob_ttl_seconds", "cannot be negative")
+	}
+
+	if cfg.IndexTTLSeconds < 0 {
+		p.add("index_ttl_seconds", "cannot be negative")
+	}
+
+	if cfg.MaxStaleSeconds < 0 {
+		p.add("max_stale_seconds", "cannot be negative")
+	}
+
+	if cfg.MemoryEvictionPolicy != "" {
+		validPolicies := []string{"lru", "lfu", "ttl_only"}
+		if !contains(validPolicies, cfg.MemoryEvictionPolicy) {
+			p.add("memory_eviction_policy", "must be one of %v, got %s", validPolicies, cfg.MemoryEvictionPolicy)
+		}
 	}
 
 	if cfg.DiskPath == "" && cfg.DiskSizeGB > 0 {
-		return fmt.Errorf("disk_path is required when disk_size_gb > 0")
+		p.add("disk_path", "is required when disk_size_gb > 0")
+	}
+
+	if cfg.DiskEncryptionKey != "" && len(cfg.DiskEncryptionKey) < 16 {
+		p.add("disk_encryption_key", "must be at least 16 characters")
 	}
 
-	return nil
+	return p.err()
 }
 
 func validateAuth(cfg *AuthConfig) error {
+	var p problems
+
+	if cfg.JWTSecret != "" && len(cfg.JWTSecrets) > 0 {
+		p.add("jwt_secret", "and jwt_secrets cannot both be set")
+	}
+
+	secrets := cfg.GetJWTSecrets()
+	if len(secrets) == 0 {
+		p.add("jwt_secret", "or jwt_secrets is required")
+	}
+	for i, secret := range secrets {
+		if strings.TrimSpace(secret) == "" {
+			p.add(fmt.Sprintf("jwt_secrets[%d]", i), "is empty")
+		}
+	}
+
 	if cfg.JWTExpirationHours < 1 {
-		return fmt.Errorf("jwt_expiration_hours must be at least 1")
+		p.add("jwt_expiration_hours", "must be at least 1")
 	}
 
 	if cfg.BCryptCost < 4 || cfg.BCryptCost > 31 {
-		return fmt.Errorf("bcrypt_cost must be between 4 and 31, got %d", cfg.BCryptCost)
+		p.add("bcrypt_cost", "must be between 4 and 31, got %d", cfg.BCryptCost)
 	}
 
-	return nil
+	return p.err()
 }
 
 func validateLogging(cfg *LoggingConfig) error {
+	var p problems
+
 	validLevels := []string{"debug", "info", "warn", "error"}
 	if !contains(validLevels, cfg.Level) {
-		return fmt.Errorf("logging level must be one of %v, got %s", validLevels, cfg.Level)
+		p.add("level", "must be one of %v, got %s", validLevels, cfg.Level)
 	}
 
 	validFormats := []string{"text", "json"}
 	if !contains(validFormats, cfg.Format) {
-		return fmt.Errorf("logging format must be one of %v, got %s", validFormats, cfg.Format)
+		p.add("format", "must be one of %v, got %s", validFormats, cfg.Format)
 	}
 
 	validOutputs := []string{"stdout", "stderr", "file", "both"}
 	if !contains(validOutputs, cfg.Output) {
-		return fmt.Errorf("logging output must be one of %v, got %s", validOutputs, cfg.Output)
+		p.add("output", "must be one of %v, got %s", validOutputs, cfg.Output)
 	}
 
 	if (cfg.Output == "file" || cfg.Output == "both") && cfg.FilePath == "" {
-		return fmt.Errorf("file_path is required when output is 'file' or 'both'")
+		p.add("file_path", "is required when output is 'file' or 'both'")
 	}
 
-	return nil
+	if cfg.BufferEntries < 0 {
+		p.add("buffer_entries", "cannot be negative")
+	}
+
+	return p.err()
 }
 
 func validateTelemetry(cfg *TelemetryConfig) error {
+	var p problems
+
 	if cfg.OtelEnabled {
 		if cfg.OtelEndpoint == "" {
-			return fmt.Errorf("otel_endpoint is required when OpenTelemetry is enabled")
+			p.add("otel_endpoint", "is required when OpenTelemetry is enabled")
 		}
 
 		validProtocols := []string{"grpc", "http"}
 		if !contains(validProtocols, cfg.OtelProtocol) {
-			return fmt.Errorf("otel_protocol must be one of %v, got %s", validProtocols, cfg.OtelProtocol)
+			p.add("otel_protocol", "must be one of %v, got %s", validProtocols, cfg.OtelProtocol)
 		}
 	}
 
-	return nil
+	return p.err()
 }
 
 func validateProviders(cfg *ProvidersConfig) error {
+	var p problems
+
 	if cfg.GPGVerificationEnabled && cfg.GPGKeyURL == "" {
-		return fmt.Errorf("gpg_key_url is required when GPG verification is enabled")
+		p.add("gpg_key_url", "is required when GPG verification is enabled")
 	}
 
 	if cfg.DownloadRetryAttempts < 0 {
-		return fmt.Errorf("download_retry_attempts cannot be negative")
+		p.add("download_retry_attempts", "cannot be negative")
 	}
 
 	if cfg.DownloadRetryInitialDelayMs < 0 {
-		return fmt.Errorf("download_retry_initial_delay_ms cannot be negative")
+		p.add("download_retry_initial_delay_ms", "cannot be negative")
 	}
 
 	if cfg.DownloadTimeoutSeconds < 1 {
-		return fmt.Errorf("download_timeout_seconds must be at least 1")
+		p.add("download_timeout_seconds", "must be at least 1")
+	}
+
+	p.merge("platforms", validatePlatforms(cfg.Platforms))
+
+	seen := make(map[string]bool)
+	for _, upstream := range cfg.Upstreams {
+		field := fmt.Sprintf("upstream[%q]", upstream.Hostname)
+		p.merge(field, validateUpstream(&upstream))
+		if seen[strings.ToLower(upstream.Hostname)] {
+			p.add(field, "is defined more than once")
+		}
+		seen[strings.ToLower(upstream.Hostname)] = true
 	}
 
-	return nil
+	return p.err()
+}
+
+// validateUpstream checks a single upstream provider registry
+func validateUpstream(cfg *UpstreamRegistryConfig) error {
+	var p problems
+
+	if cfg.Hostname == "" || strings.ContainsAny(cfg.Hostname, "/: ") {
+		p.add("hostname", "must be a bare hostname (e.g., registry.example.com)")
+	}
+
+	if cfg.URL != "" {
+		u, err := url.Parse(cfg.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			p.add("url", "must be an absolute http or https URL")
+		}
+	}
+
+	if cfg.Token != "" && cfg.Username != "" {
+		p.add("token", "and username are mutually exclusive")
+	}
+	if (cfg.Username == "") != (cfg.Password == "") {
+		p.add("username", "and password must be set together")
+	}
+
+	if len(cfg.Namespaces) == 0 {
+		p.add("namespaces", "must list at least one namespace")
+	}
+	for i, ns := range cfg.Namespaces {
+		if ns != "*" && !IsValidNamespace(ns) {
+			p.add(fmt.Sprintf("namespaces[%d]", i), "is not a valid namespace: %q", ns)
+		}
+	}
+
+	return p.err()
+}
+
+// validatePlatforms checks that every platform is in os_arch form
+func validatePlatforms(platforms []string) error {
+	var p problems
+	for i, platform := range platforms {
+		if !IsValidPlatform(platform) {
+			p.add(fmt.Sprintf("[%d]", i), "is not a valid platform: %q, expected 'os_arch' (e.g., linux_amd64)", platform)
+		}
+	}
+	return p.err()
+}
+
+// validateProviderAddresses checks that each entry is a namespace/type address
+func validateProviderAddresses(addresses []string) error {
+	var p problems
+	for i, address := range addresses {
+		namespace, providerType, ok := strings.Cut(address, "/")
+		if !ok || !IsValidNamespace(namespace) || !IsValidNamespace(providerType) {
+			p.add(fmt.Sprintf("[%d]", i), "is not a valid provider address: %q, expected 'namespace/type' (e.g., hashicorp/aws)", address)
+		}
+	}
+	return p.err()
 }
 
 func validateQuota(cfg *QuotaConfig) error {
+	var p problems
+
 	if cfg.Enabled {
 		if cfg.MaxStorageGB < 1 {
-			return fmt.Errorf("max_storage_gb must be at least 1 when quota is enabled")
+			p.add("max_storage_gb", "must be at least 1 when quota is enabled")
 		}
 
 		if cfg.WarningThresholdPercent < 1 || cfg.WarningThresholdPercent > 100 {
-			return fmt.Errorf("warning_threshold_percent must be between 1 and 100")
+			p.add("warning_threshold_percent", "must be between 1 and 100")
 		}
 	}
 
-	return nil
+	return p.err()
+}
+
+func validateDiscovery(cfg *DiscoveryConfig) error {
+	if cfg == nil {
+		return nil
+	}
+
+	var p problems
+	validateDiscoveryPath(&p, "providers_path", cfg.ProvidersPath)
+	validateDiscoveryPath(&p, "modules_path", cfg.ModulesPath)
+	// Both registries are mounted at their advertised paths
+	if cfg.IsModulesEnabled() && cfg.GetProvidersPath() == cfg.GetModulesPath() {
+		p.add("providers_path", "must differ from modules_path, got %s", cfg.GetProvidersPath())
+	}
+	return p.err()
+}
+
+func validateSync(cfg *SyncConfig) error {
+	if !cfg.IsEnabled() {
+		return nil
+	}
+
+	var p problems
+	if _, err := schedule.Parse(cfg.GetSchedule()); err != nil {
+		p.add("schedule", "is invalid: %v", err)
+	}
+	return p.err()
+}
+
+// validateDiscoveryPath checks that an advertised service path is an absolute path ending in '/'
+func validateDiscoveryPath(p *problems, name, path string) {
+	if path == "" {
+		return
+	}
+	if !strings.HasPrefix(path, "/") || !strings.HasSuffix(path, "/") {
+		p.add(name, "must begin and end with '/', got %s", path)
+	}
 }
 
 // contains checks if a string slice contains a value
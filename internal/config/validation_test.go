@@ -4,8 +4,10 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestValidateDatabase(t *testing.T) {
@@ -28,7 +30,25 @@ func TestValidateDatabase(t *testing.T) {
 				Path: "",
 			},
 			shouldError: true,
-			errorMsg:    "database path is required",
+			errorMsg:    "path is required",
+		},
+		{
+			name: "invalid synchronous level",
+			config: DatabaseConfig{
+				Path:        "/data/test.db",
+				Synchronous: "SOMETIMES",
+			},
+			shouldError: true,
+			errorMsg:    "synchronous must be",
+		},
+		{
+			name: "negative busy timeout",
+			config: DatabaseConfig{
+				Path:          "/data/test.db",
+				BusyTimeoutMS: -1,
+			},
+			shouldError: true,
+			errorMsg:    "busy_timeout_ms cannot be negative",
 		},
 		{
 			name: "backup enabled with valid interval",
@@ -119,6 +139,33 @@ func TestValidateCache(t *testing.T) {
 			shouldError: true,
 			errorMsg:    "disk_path is required when disk_size_gb > 0",
 		},
+		{
+			name: "short disk encryption key",
+			config: CacheConfig{
+				DiskPath:          "/tmp/cache",
+				DiskSizeGB:        1,
+				DiskEncryptionKey: "too-short",
+			},
+			shouldError: true,
+			errorMsg:    "disk_encryption_key must be at least 16 characters",
+		},
+		{
+			name: "lfu eviction policy",
+			config: CacheConfig{
+				MemorySizeMB:         256,
+				MemoryEvictionPolicy: "lfu",
+			},
+			shouldError: false,
+		},
+		{
+			name: "unknown eviction policy",
+			config: CacheConfig{
+				MemorySizeMB:         256,
+				MemoryEvictionPolicy: "fifo",
+			},
+			shouldError: true,
+			errorMsg:    "memory_eviction_policy must be one of [lru lfu ttl_only], got fifo",
+		},
 	}
 
 	for _, tt := range tests {
@@ -257,6 +304,110 @@ func TestValidateProviders(t *testing.T) {
 			shouldError: true,
 			errorMsg:    "download_timeout_seconds must be at least 1",
 		},
+		{
+			name: "valid platforms",
+			config: ProvidersConfig{
+				DownloadRetryAttempts:       3,
+				DownloadRetryInitialDelayMs: 1000,
+				DownloadTimeoutSeconds:      60,
+				Platforms:                   []string{"linux_amd64", "darwin_arm64", "freebsd_386"},
+			},
+			shouldError: false,
+		},
+		{
+			name: "platform not in os_arch form",
+			config: ProvidersConfig{
+				DownloadRetryAttempts:       3,
+				DownloadRetryInitialDelayMs: 1000,
+				DownloadTimeoutSeconds:      60,
+				Platforms:                   []string{"linux_amd64", "linux"},
+			},
+			shouldError: true,
+			errorMsg:    `platforms[1] is not a valid platform: "linux"`,
+		},
+		{
+			name: "valid upstreams",
+			config: ProvidersConfig{
+				DownloadRetryAttempts:       3,
+				DownloadRetryInitialDelayMs: 1000,
+				DownloadTimeoutSeconds:      60,
+				Upstreams: []UpstreamRegistryConfig{
+					{Hostname: "registry.example.com", Namespaces: []string{"acme"}, Token: "secret"},
+					{Hostname: "mirror.example.org", URL: "https://mirror.example.org/api/v1/providers", Namespaces: []string{"*"}},
+					{Hostname: "artifactory.example.net", Namespaces: []string{"internal"}, Username: "ci", Password: "pw"},
+				},
+			},
+			shouldError: false,
+		},
+		{
+			name: "upstream hostname with scheme",
+			config: ProvidersConfig{
+				DownloadRetryAttempts:       3,
+				DownloadRetryInitialDelayMs: 1000,
+				DownloadTimeoutSeconds:      60,
+				Upstreams:                   []UpstreamRegistryConfig{{Hostname: "https://registry.example.com", Namespaces: []string{"acme"}}},
+			},
+			shouldError: true,
+			errorMsg:    "hostname must be a bare hostname",
+		},
+		{
+			name: "upstream relative url",
+			config: ProvidersConfig{
+				DownloadRetryAttempts:       3,
+				DownloadRetryInitialDelayMs: 1000,
+				DownloadTimeoutSeconds:      60,
+				Upstreams:                   []UpstreamRegistryConfig{{Hostname: "registry.example.com", URL: "/v1/providers", Namespaces: []string{"acme"}}},
+			},
+			shouldError: true,
+			errorMsg:    "url must be an absolute http or https URL",
+		},
+		{
+			name: "upstream with token and basic auth",
+			config: ProvidersConfig{
+				DownloadRetryAttempts:       3,
+				DownloadRetryInitialDelayMs: 1000,
+				DownloadTimeoutSeconds:      60,
+				Upstreams:                   []UpstreamRegistryConfig{{Hostname: "registry.example.com", Namespaces: []string{"acme"}, Token: "secret", Username: "ci", Password: "pw"}},
+			},
+			shouldError: true,
+			errorMsg:    "token and username are mutually exclusive",
+		},
+		{
+			name: "upstream username without password",
+			config: ProvidersConfig{
+				DownloadRetryAttempts:       3,
+				DownloadRetryInitialDelayMs: 1000,
+				DownloadTimeoutSeconds:      60,
+				Upstreams:                   []UpstreamRegistryConfig{{Hostname: "registry.example.com", Namespaces: []string{"acme"}, Username: "ci"}},
+			},
+			shouldError: true,
+			errorMsg:    "username and password must be set together",
+		},
+		{
+			name: "upstream without namespaces",
+			config: ProvidersConfig{
+				DownloadRetryAttempts:       3,
+				DownloadRetryInitialDelayMs: 1000,
+				DownloadTimeoutSeconds:      60,
+				Upstreams:                   []UpstreamRegistryConfig{{Hostname: "registry.example.com"}},
+			},
+			shouldError: true,
+			errorMsg:    `upstream["registry.example.com"].namespaces must list at least one namespace`,
+		},
+		{
+			name: "duplicate upstream",
+			config: ProvidersConfig{
+				DownloadRetryAttempts:       3,
+				DownloadRetryInitialDelayMs: 1000,
+				DownloadTimeoutSeconds:      60,
+				Upstreams: []UpstreamRegistryConfig{
+					{Hostname: "registry.example.com", Namespaces: []string{"acme"}},
+					{Hostname: "Registry.Example.com", Namespaces: []string{"other"}},
+				},
+			},
+			shouldError: true,
+			errorMsg:    "defined more than once",
+		},
 	}
 
 	for _, tt := range tests {
@@ -340,6 +491,78 @@ func TestValidateQuota(t *testing.T) {
 	}
 }
 
+func TestValidateDiscovery(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *DiscoveryConfig
+		shouldError bool
+		errorMsg    string
+	}{
+		{
+			name:        "nil config",
+			config:      nil,
+			shouldError: false,
+		},
+		{
+			name:        "default config",
+			config:      DefaultConfig().Discovery,
+			shouldError: false,
+		},
+		{
+			name: "custom paths",
+			config: &DiscoveryConfig{
+				ProvidersPath: "/mirror/providers/",
+				ModulesPath:   "/mirror/modules/",
+			},
+			shouldError: false,
+		},
+		{
+			name: "providers path without leading slash",
+			config: &DiscoveryConfig{
+				ProvidersPath: "v1/providers/",
+			},
+			shouldError: true,
+			errorMsg:    "providers_path must begin and end with '/'",
+		},
+		{
+			name: "modules path without trailing slash",
+			config: &DiscoveryConfig{
+				ModulesPath: "/v1/modules",
+			},
+			shouldError: true,
+			errorMsg:    "modules_path must begin and end with '/'",
+		},
+		{
+			name: "providers path shared with modules",
+			config: &DiscoveryConfig{
+				ProvidersPath: "/v1/modules/",
+			},
+			shouldError: true,
+			errorMsg:    "providers_path must differ from modules_path",
+		},
+		{
+			name: "providers path shared with disabled modules",
+			config: &DiscoveryConfig{
+				ProvidersPath:  "/v1/modules/",
+				DisableModules: true,
+			},
+			shouldError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDiscovery(tt.config)
+			if tt.shouldError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestValidateServerTLS(t *testing.T) {
 	// Create temp cert/key files
 	tmpDir := t.TempDir()
@@ -424,6 +647,45 @@ func TestValidateServerTLS(t *testing.T) {
 	}
 }
 
+func TestValidateServerTimeouts(t *testing.T) {
+	cfg := ServerConfig{Port: 8080, RequestTimeoutSeconds: -1}
+	err := validateServer(&cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "request_timeout_seconds cannot be negative")
+
+	cfg = ServerConfig{Port: 8080, LongRequestTimeoutSeconds: -1}
+	err = validateServer(&cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "long_request_timeout_seconds cannot be negative")
+
+	// Unset timeouts fall back to the defaults
+	cfg = ServerConfig{Port: 8080}
+	assert.NoError(t, validateServer(&cfg))
+	assert.Equal(t, DefaultRequestTimeoutSeconds*time.Second, cfg.GetRequestTimeout())
+	assert.Equal(t, DefaultLongRequestTimeoutSeconds*time.Second, cfg.GetLongRequestTimeout())
+
+	cfg = ServerConfig{Port: 8080, RequestTimeoutSeconds: 5, LongRequestTimeoutSeconds: 600}
+	assert.Equal(t, 5*time.Second, cfg.GetRequestTimeout())
+	assert.Equal(t, 10*time.Minute, cfg.GetLongRequestTimeout())
+}
+
+func TestValidateProviderAddresses(t *testing.T) {
+	assert.NoError(t, validateProviderAddresses([]string{"hashicorp/aws", "integrations/github"}))
+
+	for _, address := range []string{"hashicorp", "hashicorp/", "/aws", "registry.terraform.io/hashicorp/aws"} {
+		err := validateProviderAddresses([]string{address})
+		assert.Error(t, err, address)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Auth.JWTSecret = "secret"
+	cfg.AutoDownload.BlockedProviders = []string{"hashicorp"}
+	err := Validate(cfg)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "auto_download.blocked_providers[0] is not a valid provider address")
+	}
+}
+
 func TestContainsHelper(t *testing.T) {
 	slice := []string{"a", "b", "c"}
 
@@ -436,57 +698,93 @@ func TestContainsHelper(t *testing.T) {
 
 func TestFullValidation_AllPaths(t *testing.T) {
 	// Test that all validation paths are covered
-	cfg := DefaultConfig()
+	cfg := validConfig()
 
 	// Database error
 	cfg.Database.Path = ""
 	err := Validate(cfg)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "database config")
+	assert.Contains(t, err.Error(), "database.path is required")
 
 	// Cache error
-	cfg = DefaultConfig()
+	cfg = validConfig()
 	cfg.Cache.MemorySizeMB = -1
 	err = Validate(cfg)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "cache config")
+	assert.Contains(t, err.Error(), "cache.memory_size_mb cannot be negative")
 
 	// Auth error
-	cfg = DefaultConfig()
+	cfg = validConfig()
 	cfg.Auth.JWTExpirationHours = 0
 	err = Validate(cfg)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "auth config")
+	assert.Contains(t, err.Error(), "auth.jwt_expiration_hours must be at least 1")
 
 	// Logging error
-	cfg = DefaultConfig()
+	cfg = validConfig()
 	cfg.Logging.Level = "invalid"
 	err = Validate(cfg)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "logging config")
+	assert.Contains(t, err.Error(), "logging.level must be one of")
 
 	// Telemetry error
-	cfg = DefaultConfig()
+	cfg = validConfig()
 	cfg.Telemetry.OtelEnabled = true
 	cfg.Telemetry.OtelEndpoint = ""
 	err = Validate(cfg)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "telemetry config")
+	assert.Contains(t, err.Error(), "telemetry.otel_endpoint is required")
 
 	// Providers error
-	cfg = DefaultConfig()
+	cfg = validConfig()
 	cfg.Providers.DownloadTimeoutSeconds = 0
 	err = Validate(cfg)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "providers config")
+	assert.Contains(t, err.Error(), "providers.download_timeout_seconds must be at least 1")
 
 	// Quota error
-	cfg = DefaultConfig()
+	cfg = validConfig()
 	cfg.Quota.Enabled = true
 	cfg.Quota.MaxStorageGB = 0
 	err = Validate(cfg)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "quota config")
+	assert.Contains(t, err.Error(), "quota.max_storage_gb must be at least 1")
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Port = 0
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "not-an-ip"}
+	cfg.Cache.MemorySizeMB = -1
+	cfg.Cache.TTLSeconds = -1
+	cfg.Auth.BCryptCost = 2
+	cfg.Providers.Upstreams = []UpstreamRegistryConfig{{Hostname: "registry.example.com"}}
+	cfg.AutoDownload.Platforms = []string{"linux"}
+
+	err := Validate(cfg)
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+
+	fields := make([]string, len(verr.Errors))
+	for i, fe := range verr.Errors {
+		fields[i] = fe.Field
+	}
+	assert.Equal(t, []string{
+		"server.port",
+		"server.trusted_proxies[1]",
+		"cache.memory_size_mb",
+		"cache.ttl_seconds",
+		"auth.bcrypt_cost",
+		`providers.upstream["registry.example.com"].namespaces`,
+		"auto_download.platforms[0]",
+	}, fields)
+	assert.Equal(t, "cannot be negative", verr.Errors[2].Message)
+
+	// The message names every problem
+	assert.Contains(t, err.Error(), "7 configuration problems")
+	for _, field := range fields {
+		assert.Contains(t, err.Error(), field)
+	}
 }
 
 func TestValidateLogging_OutputBoth(t *testing.T) {
@@ -516,5 +814,22 @@ func TestValidateLogging_InvalidOutput(t *testing.T) {
 
 	err := validateLogging(&config)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "logging output must be one of")
+	assert.Contains(t, err.Error(), "output must be one of")
+}
+
+func TestValidateLogging_BufferEntries(t *testing.T) {
+	config := LoggingConfig{
+		Level:         "info",
+		Format:        "text",
+		Output:        "stdout",
+		BufferEntries: -1,
+	}
+
+	err := validateLogging(&config)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "buffer_entries cannot be negative")
+
+	// Zero disables the buffer
+	config.BufferEntries = 0
+	assert.NoError(t, validateLogging(&config))
 }
@@ -24,7 +24,7 @@ func (r *AuditRepository) Log(ctx context.Context, action *AdminAction) error {
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query,
+	result, err := r.db.exec(ctx, "audit.log", query,
 		action.UserID,
 		action.Action,
 		action.ResourceType,
@@ -60,7 +60,7 @@ func (r *AuditRepository) ListByUser(ctx context.Context, userID int64, limit, o
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.conn.QueryContext(ctx, query, userID, limit, offset)
+	rows, err := r.db.query(ctx, "audit.list_by_user", query, userID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list actions: %w", err)
 	}
@@ -101,7 +101,7 @@ func (r *AuditRepository) ListByResource(ctx context.Context, resourceType, reso
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.conn.QueryContext(ctx, query, resourceType, resourceID, limit, offset)
+	rows, err := r.db.query(ctx, "audit.list_by_resource", query, resourceType, resourceID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list actions: %w", err)
 	}
@@ -141,7 +141,7 @@ func (r *AuditRepository) List(ctx context.Context, limit, offset int) ([]*Admin
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.conn.QueryContext(ctx, query, limit, offset)
+	rows, err := r.db.query(ctx, "audit.list", query, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list actions: %w", err)
 	}
@@ -182,7 +182,7 @@ func (r *AuditRepository) ListByAction(ctx context.Context, action string, limit
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.conn.QueryContext(ctx, query, action, limit, offset)
+	rows, err := r.db.query(ctx, "audit.list_by_action", query, action, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list actions: %w", err)
 	}
@@ -216,7 +216,7 @@ func (r *AuditRepository) ListByAction(ctx context.Context, action string, limit
 func (r *AuditRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
 	query := `DELETE FROM admin_actions WHERE created_at < ?`
 
-	result, err := r.db.conn.ExecContext(ctx, query, before)
+	result, err := r.db.exec(ctx, "audit.delete_older_than", query, before)
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete old actions: %w", err)
 	}
@@ -228,3 +228,65 @@ func (r *AuditRepository) DeleteOlderThan(ctx context.Context, before time.Time)
 
 	return rows, nil
 }
+
+// auditTimestampFormat matches the CURRENT_TIMESTAMP text stored in
+// admin_actions.created_at, so range comparisons sort correctly
+const auditTimestampFormat = "2006-01-02 15:04:05"
+
+// AuditFilter limits audit log queries to entries created in [From, To).
+// A zero time leaves that end of the range open.
+type AuditFilter struct {
+	From time.Time
+	To   time.Time
+}
+
+// ListAfter retrieves up to limit entries with IDs above afterID, oldest
+// first. Passing the last ID of each batch as afterID walks the whole log
+// without holding it in memory.
+func (r *AuditRepository) ListAfter(ctx context.Context, filter AuditFilter, afterID int64, limit int) ([]*AdminAction, error) {
+	query := `
+		SELECT id, user_id, action, resource_type, resource_id, ip_address, user_agent, 
+		       success, error_message, metadata, created_at
+		FROM admin_actions
+		WHERE id > ?`
+	args := []interface{}{afterID}
+	if !filter.From.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, filter.From.UTC().Format(auditTimestampFormat))
+	}
+	if !filter.To.IsZero() {
+		query += " AND created_at < ?"
+		args = append(args, filter.To.UTC().Format(auditTimestampFormat))
+	}
+	query += " ORDER BY id LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.query(ctx, "audit.list_after", query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list actions: %w", err)
+	}
+	defer rows.Close()
+
+	var actions []*AdminAction
+	for rows.Next() {
+		var action AdminAction
+		if err := rows.Scan(
+			&action.ID,
+			&action.UserID,
+			&action.Action,
+			&action.ResourceType,
+			&action.ResourceID,
+			&action.IPAddress,
+			&action.UserAgent,
+			&action.Success,
+			&action.ErrorMessage,
+			&action.Metadata,
+			&action.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan action: %w", err)
+		}
+		actions = append(actions, &action)
+	}
+
+	return actions, rows.Err()
+}
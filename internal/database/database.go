@@ -3,24 +3,67 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/ned1313/terraform-mirror/internal/metrics"
 	_ "modernc.org/sqlite"
 )
 
 // DB wraps the database connection and provides access to repositories
 type DB struct {
-	conn *sql.DB
-	path string
+	conn    *sql.DB
+	path    string
+	metrics *metrics.Metrics
+
+	// exclusiveOp names the backup or restore currently running, if any
+	exclusiveMu sync.Mutex
+	exclusiveOp string
+
+	// jobEnqueued is signalled when a pending job is ready to start
+	jobEnqueued chan struct{}
+}
+
+// ErrOperationInProgress is returned by BeginExclusive while another backup
+// or restore holds the database file
+var ErrOperationInProgress = errors.New("a backup or restore is already in progress")
+
+// Options tunes how the SQLite connection pool is opened
+type Options struct {
+	// BusyTimeout is how long a connection waits for a lock before
+	// failing with "database is locked"
+	BusyTimeout time.Duration
+	// Synchronous is the PRAGMA synchronous level (OFF, NORMAL, FULL or EXTRA)
+	Synchronous string
+	// MaxOpenConns caps the pool size. WAL lets readers run alongside the
+	// single writer; immediate transactions queue writers on the busy timeout.
+	MaxOpenConns int
+}
+
+// DefaultOptions returns the connection settings used by New
+func DefaultOptions() Options {
+	return Options{
+		BusyTimeout:  5 * time.Second,
+		Synchronous:  "NORMAL",
+		MaxOpenConns: 8,
+	}
 }
 
-// New creates a new database connection and runs migrations
+// New creates a new database connection with default options and runs migrations
 func New(dbPath string) (*DB, error) {
+	return NewWithOptions(dbPath, DefaultOptions())
+}
+
+// NewWithOptions creates a new database connection and runs migrations
+func NewWithOptions(dbPath string, opts Options) (*DB, error) {
 	// Handle in-memory database for testing
 	connString := dbPath
 	if dbPath == ":memory:" {
@@ -34,6 +77,10 @@ func New(dbPath string) (*DB, error) {
 		}
 	}
 
+	// PRAGMAs are per connection, so they go in the DSN where the driver
+	// applies them to every connection the pool opens
+	connString += connParams(connString, opts)
+
 	// Open database connection
 	conn, err := sql.Open("sqlite", connString)
 	if err != nil {
@@ -41,31 +88,24 @@ func New(dbPath string) (*DB, error) {
 	}
 
 	// Configure connection pool
-	conn.SetMaxOpenConns(25)
-	conn.SetMaxIdleConns(5)
-	conn.SetConnMaxLifetime(5 * time.Minute)
-
-	// Enable WAL mode for better concurrency
-	if _, err := conn.Exec("PRAGMA journal_mode=WAL"); err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
-	}
-
-	// Set busy timeout to wait for locks instead of failing immediately
-	if _, err := conn.Exec("PRAGMA busy_timeout=5000"); err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to set busy timeout: %w", err)
+	maxOpen := opts.MaxOpenConns
+	if maxOpen < 1 {
+		maxOpen = DefaultOptions().MaxOpenConns
 	}
+	conn.SetMaxOpenConns(maxOpen)
+	conn.SetMaxIdleConns(maxOpen)
+	conn.SetConnMaxLifetime(5 * time.Minute)
 
-	// Enable foreign keys
-	if _, err := conn.Exec("PRAGMA foreign_keys=ON"); err != nil {
+	// Open a connection now so a bad path or PRAGMA fails at startup
+	if err := conn.Ping(); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+		return nil, fmt.Errorf("failed to configure database: %w", err)
 	}
 
 	db := &DB{
-		conn: conn,
-		path: dbPath,
+		conn:        conn,
+		path:        dbPath,
+		jobEnqueued: make(chan struct{}, 1),
 	}
 
 	// Run migrations
@@ -78,6 +118,30 @@ func New(dbPath string) (*DB, error) {
 	return db, nil
 }
 
+// connParams builds the DSN query string that configures each connection:
+// WAL so reads don't block on the writer, a busy timeout so lock contention
+// waits instead of failing, and immediate transactions so a transaction takes
+// the write lock up front rather than failing to upgrade a read lock.
+func connParams(connString string, opts Options) string {
+	synchronous := opts.Synchronous
+	if synchronous == "" {
+		synchronous = DefaultOptions().Synchronous
+	}
+
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", opts.BusyTimeout.Milliseconds()))
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", fmt.Sprintf("synchronous(%s)", strings.ToUpper(synchronous)))
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Set("_txlock", "immediate")
+
+	sep := "?"
+	if strings.Contains(connString, "?") {
+		sep = "&"
+	}
+	return sep + params.Encode()
+}
+
 // Close closes the database connection
 func (db *DB) Close() error {
 	if db.conn != nil {
@@ -101,13 +165,80 @@ func (db *DB) Ping(ctx context.Context) error {
 	return db.conn.PingContext(ctx)
 }
 
+// CheckReadWrite confirms the database accepts writes and reads them back.
+// It works in a temporary table inside a transaction that is rolled back, so
+// nothing is left behind.
+func (db *DB) CheckReadWrite(ctx context.Context) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "CREATE TEMP TABLE diagnostics_probe (value TEXT)"); err != nil {
+		return fmt.Errorf("failed to create probe table: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO diagnostics_probe (value) VALUES (?)", "ok"); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+
+	var value string
+	if err := tx.QueryRowContext(ctx, "SELECT value FROM diagnostics_probe").Scan(&value); err != nil {
+		return fmt.Errorf("failed to read: %w", err)
+	}
+	if value != "ok" {
+		return fmt.Errorf("read back %q, expected %q", value, "ok")
+	}
+	return nil
+}
+
+// JobEnqueued returns a channel that receives when a pending job and its
+// items have been created, so the processor can start it without waiting for
+// its next poll. Signals are coalesced: several jobs enqueued between
+// receives yield one.
+func (db *DB) JobEnqueued() <-chan struct{} {
+	return db.jobEnqueued
+}
+
+// notifyJobEnqueued signals JobEnqueued without blocking
+func (db *DB) notifyJobEnqueued() {
+	select {
+	case db.jobEnqueued <- struct{}{}:
+	default:
+	}
+}
+
 // BeginTx starts a new transaction
 func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
 	return db.conn.BeginTx(ctx, opts)
 }
 
+// BeginExclusive claims the database file for a backup or restore. Only one
+// such operation runs at a time; while one does, further attempts fail with
+// ErrOperationInProgress instead of waiting. The returned function releases
+// the claim and is safe to call more than once.
+func (db *DB) BeginExclusive(operation string) (func(), error) {
+	db.exclusiveMu.Lock()
+	defer db.exclusiveMu.Unlock()
+
+	if db.exclusiveOp != "" {
+		return nil, fmt.Errorf("%w: %s", ErrOperationInProgress, db.exclusiveOp)
+	}
+	db.exclusiveOp = operation
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			db.exclusiveMu.Lock()
+			db.exclusiveOp = ""
+			db.exclusiveMu.Unlock()
+		})
+	}, nil
+}
+
 // Backup creates a backup of the database to the specified path
-// For SQLite, this uses the VACUUM INTO command to create a complete copy
+// For SQLite, this uses the VACUUM INTO command to create a complete copy.
+// Callers should hold BeginExclusive so backups and restores never overlap.
 func (db *DB) Backup(ctx context.Context, backupPath string) error {
 	// Ensure backup directory exists
 	dir := filepath.Dir(backupPath)
@@ -130,6 +261,42 @@ func (db *DB) Backup(ctx context.Context, backupPath string) error {
 	return nil
 }
 
+// VerifyBackup opens a backup read-only and runs PRAGMA integrity_check on
+// it, returning an error if the file is not a SQLite database or the check
+// reports any problem
+func VerifyBackup(ctx context.Context, backupPath string) error {
+	dsn := (&url.URL{Scheme: "file", Path: backupPath, RawQuery: "mode=ro"}).String()
+	conn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return fmt.Errorf("failed to check backup integrity: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return fmt.Errorf("failed to read integrity check result: %w", err)
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to check backup integrity: %w", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("backup failed integrity check: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
 // migrate runs database migrations
 func (db *DB) migrate() error {
 	// Create migrations table if it doesn't exist
@@ -200,8 +367,18 @@ func (db *DB) migrate() error {
 // getMigrations returns all database migrations
 func getMigrations() map[int]string {
 	return map[int]string{
-		1: migration001Initial,
-		2: migration002Modules,
+		1:  migration001Initial,
+		2:  migration002Modules,
+		3:  migration003ProviderProtocols,
+		4:  migration004Labels,
+		5:  migration005ExpectedShasums,
+		6:  migration006ProviderH1Hash,
+		7:  migration007IdempotencyKeys,
+		8:  migration008ProviderAliases,
+		9:  migration009ProviderShasums,
+		10: migration010ProviderVerified,
+		11: migration011ModuleContentHash,
+		12: migration012ProviderShasumsStale,
 	}
 }
 
@@ -464,3 +641,164 @@ CREATE INDEX idx_module_job_items_module ON module_job_items(namespace, name, sy
 -- Add job_type column to download_jobs to distinguish provider vs module jobs
 ALTER TABLE download_jobs ADD COLUMN job_type TEXT NOT NULL DEFAULT 'provider';
 `
+
+// migration003ProviderProtocols records the plugin protocols each provider
+// release supports, as reported by the upstream registry
+const migration003ProviderProtocols = `
+ALTER TABLE providers ADD COLUMN protocols TEXT;
+`
+
+// migration004Labels adds free-form labels for organizing providers and
+// modules, such as "approved" or "team-x"
+const migration004Labels = `
+CREATE TABLE provider_labels (
+    provider_id INTEGER NOT NULL,
+    label TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (provider_id, label),
+    FOREIGN KEY (provider_id) REFERENCES providers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_provider_labels_label ON provider_labels(label);
+
+CREATE TABLE module_labels (
+    module_id INTEGER NOT NULL,
+    label TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (module_id, label),
+    FOREIGN KEY (module_id) REFERENCES modules(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_module_labels_label ON module_labels(label);
+`
+
+// migration005ExpectedShasums lets a job item pin the archive shasums it will
+// accept, such as the zh: hashes recorded in a Terraform lockfile
+const migration005ExpectedShasums = `
+ALTER TABLE download_job_items ADD COLUMN expected_shasums TEXT;
+`
+
+// migration006ProviderH1Hash records the h1: hash of each provider archive's
+// contents so the network mirror can serve it alongside the zh: shasum
+const migration006ProviderH1Hash = `
+ALTER TABLE providers ADD COLUMN h1_hash TEXT;
+`
+
+// migration007IdempotencyKeys remembers the job each Idempotency-Key created
+// so a retried request returns that job instead of starting another
+const migration007IdempotencyKeys = `
+CREATE TABLE idempotency_keys (
+    scope TEXT NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    job_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL,
+
+    PRIMARY KEY (scope, idempotency_key),
+    FOREIGN KEY (job_id) REFERENCES download_jobs(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys(created_at);
+`
+
+// migration008ProviderAliases lets a mirrored provider be served under other
+// namespace/type addresses without storing its archives twice
+const migration008ProviderAliases = `
+CREATE TABLE provider_aliases (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    namespace TEXT NOT NULL,
+    type TEXT NOT NULL,
+    target_namespace TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(namespace, type)
+);
+
+CREATE INDEX idx_provider_aliases_target ON provider_aliases(target_namespace, target_type);
+`
+
+// migration009ProviderShasums records SHA256SUMS documents stored for
+// complete provider versions. Any change to the version's platforms drops
+// the record, so a stale document is never served.
+const migration009ProviderShasums = `
+CREATE TABLE provider_shasums (
+    namespace TEXT NOT NULL,
+    type TEXT NOT NULL,
+    version TEXT NOT NULL,
+    s3_key TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (namespace, type, version)
+);
+
+CREATE TRIGGER provider_shasums_on_insert AFTER INSERT ON providers
+BEGIN
+    DELETE FROM provider_shasums
+    WHERE namespace = NEW.namespace AND type = NEW.type AND version = NEW.version;
+END;
+
+CREATE TRIGGER provider_shasums_on_update AFTER UPDATE OF shasum, filename ON providers
+BEGIN
+    DELETE FROM provider_shasums
+    WHERE namespace = NEW.namespace AND type = NEW.type AND version = NEW.version;
+END;
+
+CREATE TRIGGER provider_shasums_on_delete AFTER DELETE ON providers
+BEGIN
+    DELETE FROM provider_shasums
+    WHERE namespace = OLD.namespace AND type = OLD.type AND version = OLD.version;
+END;
+`
+
+// migration010ProviderVerified records whether each provider archive passed
+// GPG verification of the upstream SHA256SUMS. Rows from before the check
+// existed start out unverified.
+const migration010ProviderVerified = `
+ALTER TABLE providers ADD COLUMN verified BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_providers_verified ON providers(verified);
+`
+
+// migration011ModuleContentHash records the content hash of module archives
+// stored content-addressed, and indexes storage keys so deleting a version
+// can count the other versions sharing its archive
+const migration011ModuleContentHash = `
+ALTER TABLE modules ADD COLUMN content_hash TEXT;
+
+CREATE INDEX idx_modules_s3_key ON modules(s3_key);
+`
+
+// migration012ProviderShasumsStale marks SHA256SUMS records stale instead of
+// dropping them when a version's platforms change, so the stored document
+// can still be found and removed from storage. Updates that rewrite a
+// platform's shasum or filename with the same value leave the record current.
+const migration012ProviderShasumsStale = `
+ALTER TABLE provider_shasums ADD COLUMN stale BOOLEAN NOT NULL DEFAULT 0;
+
+DROP TRIGGER provider_shasums_on_insert;
+DROP TRIGGER provider_shasums_on_update;
+DROP TRIGGER provider_shasums_on_delete;
+
+CREATE TRIGGER provider_shasums_on_insert AFTER INSERT ON providers
+BEGIN
+    UPDATE provider_shasums SET stale = 1
+    WHERE namespace = NEW.namespace AND type = NEW.type AND version = NEW.version;
+END;
+
+CREATE TRIGGER provider_shasums_on_update AFTER UPDATE OF shasum, filename ON providers
+WHEN OLD.shasum IS NOT NEW.shasum OR OLD.filename IS NOT NEW.filename
+BEGIN
+    UPDATE provider_shasums SET stale = 1
+    WHERE namespace = NEW.namespace AND type = NEW.type AND version = NEW.version;
+END;
+
+CREATE TRIGGER provider_shasums_on_delete AFTER DELETE ON providers
+BEGIN
+    UPDATE provider_shasums SET stale = 1
+    WHERE namespace = OLD.namespace AND type = OLD.type AND version = OLD.version;
+END;
+
+CREATE INDEX idx_provider_shasums_stale ON provider_shasums(stale);
+`
@@ -2,11 +2,18 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/ned1313/terraform-mirror/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -50,6 +57,20 @@ func TestPing(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestCheckReadWrite(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	require.NoError(t, db.CheckReadWrite(ctx))
+	// The probe table is rolled back, so the check can run again
+	require.NoError(t, db.CheckReadWrite(ctx))
+
+	db.Close()
+	assert.Error(t, db.CheckReadWrite(ctx))
+}
+
 func TestMigrations(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -68,7 +89,7 @@ func TestMigrations(t *testing.T) {
 	var version int
 	err = db.conn.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version)
 	require.NoError(t, err)
-	assert.Equal(t, 2, version)
+	assert.Equal(t, 12, version)
 
 	// Check that all expected tables exist
 	expectedTables := []string{
@@ -80,6 +101,10 @@ func TestMigrations(t *testing.T) {
 		"download_job_items",
 		"modules",
 		"module_job_items",
+		"provider_labels",
+		"module_labels",
+		"provider_aliases",
+		"provider_shasums",
 	}
 
 	for _, table := range expectedTables {
@@ -104,17 +129,17 @@ func TestMigrationsIdempotent(t *testing.T) {
 	require.NoError(t, err)
 	defer db2.Close()
 
-	// Check version is still 2
+	// Check version is still 9
 	var version int
 	err = db2.conn.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version)
 	require.NoError(t, err)
-	assert.Equal(t, 2, version)
+	assert.Equal(t, 12, version)
 
 	// Check count of migration records
 	var count int
 	err = db2.conn.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&count)
 	require.NoError(t, err)
-	assert.Equal(t, 2, count)
+	assert.Equal(t, 12, count)
 }
 
 func TestWALMode(t *testing.T) {
@@ -131,6 +156,97 @@ func TestWALMode(t *testing.T) {
 	assert.Equal(t, "wal", journalMode)
 }
 
+func TestNewWithOptions(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := NewWithOptions(dbPath, Options{BusyTimeout: 2 * time.Second, Synchronous: "FULL", MaxOpenConns: 4})
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Per-connection settings hold on every pooled connection, not just the first
+	conns := make([]*sql.Conn, 4)
+	for i := range conns {
+		conns[i], err = db.conn.Conn(context.Background())
+		require.NoError(t, err)
+		defer conns[i].Close()
+	}
+	for _, c := range conns {
+		var busyTimeout, synchronous, foreignKeys int
+		require.NoError(t, c.QueryRowContext(context.Background(), "PRAGMA busy_timeout").Scan(&busyTimeout))
+		require.NoError(t, c.QueryRowContext(context.Background(), "PRAGMA synchronous").Scan(&synchronous))
+		require.NoError(t, c.QueryRowContext(context.Background(), "PRAGMA foreign_keys").Scan(&foreignKeys))
+		assert.Equal(t, 2000, busyTimeout)
+		assert.Equal(t, 2, synchronous) // FULL
+		assert.Equal(t, 1, foreignKeys)
+	}
+	assert.Equal(t, 4, db.conn.Stats().MaxOpenConnections)
+}
+
+func TestConcurrentReadsAndWrites(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewProviderRepository(db)
+	ctx := context.Background()
+
+	const workers = 8
+	const perWorker = 25
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker*2)
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				// Read then write in one transaction, the pattern that fails to
+				// upgrade its lock with deferred transactions
+				tx, err := db.BeginTx(ctx, nil)
+				if err != nil {
+					errs <- err
+					continue
+				}
+				var count int
+				if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM providers").Scan(&count); err != nil {
+					tx.Rollback()
+					errs <- err
+					continue
+				}
+				_, err = tx.ExecContext(ctx, `INSERT INTO providers (namespace, type, version, platform, filename, download_url, shasum, s3_key, size_bytes)
+					VALUES ('hashicorp', 'aws', ?, 'linux_amd64', 'provider.zip', '', '', ?, 0)`,
+					fmt.Sprintf("%d.%d.0", w, i), fmt.Sprintf("key/%d/%d", w, i))
+				if err != nil {
+					tx.Rollback()
+					errs <- err
+					continue
+				}
+				if err := tx.Commit(); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if _, err := repo.ListVersions(ctx, "hashicorp", "aws"); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent operation failed: %v", err)
+	}
+
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(workers*perWorker), count)
+}
+
 func TestForeignKeys(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -163,6 +279,94 @@ func TestBeginTx(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestVerifyBackup(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	backupPath := filepath.Join(t.TempDir(), "backup.db")
+	require.NoError(t, db.Backup(ctx, backupPath))
+	assert.NoError(t, VerifyBackup(ctx, backupPath))
+
+	t.Run("corrupt page", func(t *testing.T) {
+		data, err := os.ReadFile(backupPath)
+		require.NoError(t, err)
+		corrupt := filepath.Join(t.TempDir(), "corrupt.db")
+		// Keep the header page intact and scribble over the rest
+		for i := 4096; i < len(data); i++ {
+			data[i] = 0xff
+		}
+		require.NoError(t, os.WriteFile(corrupt, data, 0644))
+		assert.Error(t, VerifyBackup(ctx, corrupt))
+	})
+
+	t.Run("not a database", func(t *testing.T) {
+		garbage := filepath.Join(t.TempDir(), "garbage.db")
+		require.NoError(t, os.WriteFile(garbage, []byte("definitely not sqlite, just some bytes padding it out"), 0644))
+		assert.Error(t, VerifyBackup(ctx, garbage))
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		assert.Error(t, VerifyBackup(ctx, filepath.Join(t.TempDir(), "missing.db")))
+	})
+}
+
+func TestBeginExclusive(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Two backups start together; the winner keeps its claim until both have
+	// tried, so exactly one of them may run
+	var attempted, done sync.WaitGroup
+	attempted.Add(2)
+	done.Add(2)
+	errs := make([]error, 2)
+	for i := range errs {
+		go func() {
+			defer done.Done()
+			release, err := db.BeginExclusive("backup")
+			attempted.Done()
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer release()
+			attempted.Wait()
+			errs[i] = db.Backup(ctx, filepath.Join(dir, fmt.Sprintf("backup-%d.db", i)))
+		}()
+	}
+	done.Wait()
+
+	succeeded, rejected := 0, 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, ErrOperationInProgress):
+			rejected++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+	assert.Equal(t, 1, rejected)
+
+	// Once released the file can be claimed again, and releasing twice is harmless
+	release, err := db.BeginExclusive("restore")
+	require.NoError(t, err)
+	_, err = db.BeginExclusive("backup")
+	assert.ErrorContains(t, err, "restore")
+	release()
+	release()
+	release, err = db.BeginExclusive("backup")
+	require.NoError(t, err)
+	release()
+}
+
 func TestClose(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -207,3 +411,27 @@ func TestIndexes(t *testing.T) {
 		assert.Equal(t, index, name)
 	}
 }
+
+func TestDB_QueryMetrics(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	reg := prometheus.NewRegistry()
+	m := metrics.NewWithRegistry(reg)
+	db.SetMetrics(m)
+
+	repo := NewProviderRepository(db)
+
+	// A missing row is still a successful query
+	p, err := repo.GetByID(ctx, 12345)
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.DBQueryTotal.WithLabelValues("provider.get_by_id", "success")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.DBQueryDuration, "terraform_mirror_db_query_duration_seconds"))
+
+	// Failed statements are labeled as errors
+	_, err = db.exec(ctx, "test.bad_query", "SELECT * FROM no_such_table")
+	require.Error(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.DBQueryTotal.WithLabelValues("test.bad_query", "error")))
+}
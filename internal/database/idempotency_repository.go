@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// IdempotencyRepository maps Idempotency-Key headers to the jobs they created
+type IdempotencyRepository struct {
+	db *DB
+}
+
+// NewIdempotencyRepository creates a new idempotency key repository
+func NewIdempotencyRepository(db *DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Find returns the job recorded for a key in scope since the given time
+func (r *IdempotencyRepository) Find(ctx context.Context, scope, key string, since time.Time) (int64, bool, error) {
+	query := `
+		SELECT job_id FROM idempotency_keys
+		WHERE scope = ? AND idempotency_key = ? AND created_at >= ?
+	`
+
+	var jobID int64
+	err := r.db.queryRow(ctx, "idempotency.find", query, scope, key, since).Scan(&jobID)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to find idempotency key: %w", err)
+	}
+	return jobID, true, nil
+}
+
+// Record maps a key in scope to the job it created, replacing an expired
+// mapping for the same key
+func (r *IdempotencyRepository) Record(ctx context.Context, scope, key string, jobID int64) error {
+	query := `
+		INSERT OR REPLACE INTO idempotency_keys (scope, idempotency_key, job_id, created_at)
+		VALUES (?, ?, ?, ?)
+	`
+
+	if _, err := r.db.exec(ctx, "idempotency.record", query, scope, key, jobID, time.Now()); err != nil {
+		return fmt.Errorf("failed to record idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteOlderThan removes keys recorded before the cutoff
+func (r *IdempotencyRepository) DeleteOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE created_at < ?`
+
+	result, err := r.db.exec(ctx, "idempotency.delete_older_than", query, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return result.RowsAffected()
+}
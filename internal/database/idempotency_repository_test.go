@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyRepository(t *testing.T) {
+	db := setupTestDB(t)
+	jobRepo := NewJobRepository(db)
+	repo := NewIdempotencyRepository(db)
+	ctx := context.Background()
+
+	job := &DownloadJob{SourceType: "hcl", SourceData: "{}", Status: "pending"}
+	require.NoError(t, jobRepo.Create(ctx, job))
+
+	hourAgo := time.Now().Add(-time.Hour)
+	_, found, err := repo.Find(ctx, "providers/load", "abc", hourAgo)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, repo.Record(ctx, "providers/load", "abc", job.ID))
+
+	jobID, found, err := repo.Find(ctx, "providers/load", "abc", hourAgo)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, job.ID, jobID)
+
+	// Keys are scoped to the endpoint that recorded them
+	_, found, err = repo.Find(ctx, "modules/load", "abc", hourAgo)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// Keys recorded before the window are not returned and can be expired
+	_, found, err = repo.Find(ctx, "providers/load", "abc", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	deleted, err := repo.DeleteOlderThan(ctx, hourAgo)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	deleted, err = repo.DeleteOlderThan(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/metrics"
+)
+
+// SetMetrics enables recording of query metrics; nil disables it
+func (db *DB) SetMetrics(m *metrics.Metrics) {
+	db.metrics = m
+}
+
+// observe records the duration and outcome of a named query. Names identify
+// the repository method (e.g. "provider.get_by_id") rather than the raw SQL so
+// that label cardinality stays bounded.
+func (db *DB) observe(name string, start time.Time, err error) {
+	if db.metrics == nil {
+		return
+	}
+
+	status := "success"
+	if err != nil && err != sql.ErrNoRows {
+		status = "error"
+	}
+
+	db.metrics.DBQueryDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	db.metrics.DBQueryTotal.WithLabelValues(name, status).Inc()
+}
+
+// exec runs a named statement that returns no rows
+func (db *DB) exec(ctx context.Context, name, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := db.conn.ExecContext(ctx, query, args...)
+	db.observe(name, start, err)
+	return result, err
+}
+
+// query runs a named query that returns rows
+func (db *DB) query(ctx context.Context, name, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	db.observe(name, start, err)
+	return rows, err
+}
+
+// queryRow runs a named query that returns at most one row
+func (db *DB) queryRow(ctx context.Context, name, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := db.conn.QueryRowContext(ctx, query, args...)
+	db.observe(name, start, row.Err())
+	return row
+}
@@ -17,14 +17,19 @@ func NewJobRepository(db *DB) *JobRepository {
 	return &JobRepository{db: db}
 }
 
+const createJobQuery = `
+	INSERT INTO download_jobs (user_id, job_type, source_type, source_data, status, total_items, completed_items, failed_items, started_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+const createItemQuery = `
+	INSERT INTO download_job_items (job_id, namespace, type, version, platform, status, expected_shasums)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
 // Create creates a new download job
 func (r *JobRepository) Create(ctx context.Context, job *DownloadJob) error {
-	query := `
-		INSERT INTO download_jobs (user_id, job_type, source_type, source_data, status, total_items, completed_items, failed_items, started_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-
-	result, err := r.db.conn.ExecContext(ctx, query,
+	result, err := r.db.exec(ctx, "job.create", createJobQuery,
 		job.UserID,
 		job.JobType,
 		job.SourceType,
@@ -59,7 +64,7 @@ func (r *JobRepository) GetByID(ctx context.Context, id int64) (*DownloadJob, er
 	`
 
 	var job DownloadJob
-	err := r.db.conn.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.queryRow(ctx, "job.get_by_id", query, id).Scan(
 		&job.ID,
 		&job.UserID,
 		&job.JobType,
@@ -87,61 +92,36 @@ func (r *JobRepository) GetByID(ctx context.Context, id int64) (*DownloadJob, er
 
 // List retrieves all jobs ordered by creation time
 func (r *JobRepository) List(ctx context.Context, limit, offset int) ([]*DownloadJob, error) {
-	query := `
-		SELECT id, user_id, job_type, source_type, source_data, status, progress, total_items, 
-		       completed_items, failed_items, error_message, created_at, started_at, completed_at
-		FROM download_jobs
-		ORDER BY created_at DESC
-		LIMIT ? OFFSET ?
-	`
-
-	rows, err := r.db.conn.QueryContext(ctx, query, limit, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list jobs: %w", err)
-	}
-	defer rows.Close()
-
-	var jobs []*DownloadJob
-	for rows.Next() {
-		var job DownloadJob
-		if err := rows.Scan(
-			&job.ID,
-			&job.UserID,
-			&job.JobType,
-			&job.SourceType,
-			&job.SourceData,
-			&job.Status,
-			&job.Progress,
-			&job.TotalItems,
-			&job.CompletedItems,
-			&job.FailedItems,
-			&job.ErrorMessage,
-			&job.CreatedAt,
-			&job.StartedAt,
-			&job.CompletedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan job: %w", err)
-		}
-		jobs = append(jobs, &job)
-	}
-
-	return jobs, rows.Err()
+	return r.ListSorted(ctx, "", newestFirst, limit, offset)
 }
 
 // ListByStatus retrieves jobs with a specific status
 func (r *JobRepository) ListByStatus(ctx context.Context, status string, limit, offset int) ([]*DownloadJob, error) {
+	return r.ListSorted(ctx, status, newestFirst, limit, offset)
+}
+
+// ListSorted retrieves jobs with pagination in the given order, restricted
+// to a status unless it is empty. Fields outside JobSortFields fall back to
+// newest first.
+func (r *JobRepository) ListSorted(ctx context.Context, status string, sort Sort, limit, offset int) ([]*DownloadJob, error) {
+	name, where := "job.list", ""
+	var args []interface{}
+	if status != "" {
+		name, where = "job.list_by_status", " WHERE status = ?"
+		args = append(args, status)
+	}
+
 	query := `
 		SELECT id, user_id, job_type, source_type, source_data, status, progress, total_items, 
 		       completed_items, failed_items, error_message, created_at, started_at, completed_at
-		FROM download_jobs
-		WHERE status = ?
-		ORDER BY created_at DESC
+		FROM download_jobs` + where + sort.orderBy(JobSortFields) + `
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.conn.QueryContext(ctx, query, status, limit, offset)
+	args = append(args, limit, offset)
+	rows, err := r.db.query(ctx, name, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list jobs by status: %w", err)
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
 	}
 	defer rows.Close()
 
@@ -183,7 +163,7 @@ func (r *JobRepository) ListPending(ctx context.Context, limit int) ([]*Download
 		LIMIT ?
 	`
 
-	rows, err := r.db.conn.QueryContext(ctx, query, limit)
+	rows, err := r.db.query(ctx, "job.list_pending", query, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list pending jobs: %w", err)
 	}
@@ -225,7 +205,7 @@ func (r *JobRepository) Update(ctx context.Context, job *DownloadJob) error {
 		WHERE id = ?
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query,
+	result, err := r.db.exec(ctx, "job.update", query,
 		job.Status,
 		job.Progress,
 		job.CompletedItems,
@@ -251,20 +231,165 @@ func (r *JobRepository) Update(ctx context.Context, job *DownloadJob) error {
 	return nil
 }
 
-// CreateItem creates a new job item
-func (r *JobRepository) CreateItem(ctx context.Context, item *DownloadJobItem) error {
+// Claim marks a pending job as running. It returns false, leaving the job
+// untouched, when the job is no longer pending, e.g. because it was cancelled
+// after the processor listed it.
+func (r *JobRepository) Claim(ctx context.Context, job *DownloadJob) (bool, error) {
+	now := time.Now()
+	query := `UPDATE download_jobs SET status = 'running', started_at = ? WHERE id = ? AND status = 'pending'`
+
+	result, err := r.db.exec(ctx, "job.claim", query, now, job.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim job: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	job.Status = "running"
+	job.StartedAt = sql.NullTime{Time: now, Valid: true}
+	return true, nil
+}
+
+// Cancel marks a pending or running job as cancelled with the given message.
+// It returns false when the job has already finished.
+func (r *JobRepository) Cancel(ctx context.Context, id int64, message string) (bool, error) {
+	query := `
+		UPDATE download_jobs
+		SET status = 'cancelled', error_message = ?, completed_at = ?
+		WHERE id = ? AND status IN ('pending', 'running')
+	`
+
+	result, err := r.db.exec(ctx, "job.cancel", query, message, time.Now(), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel job: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// CancelPending marks every pending job as cancelled in a single statement
+// and returns how many were cancelled
+func (r *JobRepository) CancelPending(ctx context.Context, message string) (int64, error) {
 	query := `
-		INSERT INTO download_job_items (job_id, namespace, type, version, platform, status)
-		VALUES (?, ?, ?, ?, ?, ?)
+		UPDATE download_jobs
+		SET status = 'cancelled', error_message = ?, completed_at = ?
+		WHERE status = 'pending'
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query,
+	result, err := r.db.exec(ctx, "job.cancel_pending", query, message, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel pending jobs: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows, nil
+}
+
+// CreateWithItems creates a job and its items in a single transaction, so a
+// failure part way leaves no job without its items for the processor to run
+func (r *JobRepository) CreateWithItems(ctx context.Context, job *DownloadJob, items []*DownloadJobItem) error {
+	start := time.Now()
+	err := r.createWithItems(ctx, job, items)
+	r.db.observe("job.create_with_items", start, err)
+	return err
+}
+
+func (r *JobRepository) createWithItems(ctx context.Context, job *DownloadJob, items []*DownloadJobItem) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, createJobQuery,
+		job.UserID,
+		job.JobType,
+		job.SourceType,
+		job.SourceData,
+		job.Status,
+		job.TotalItems,
+		job.CompletedItems,
+		job.FailedItems,
+		job.StartedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+	jobID, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get job ID: %w", err)
+	}
+
+	itemIDs := make([]int64, len(items))
+	for i, item := range items {
+		result, err := tx.ExecContext(ctx, createItemQuery,
+			jobID,
+			item.Namespace,
+			item.Type,
+			item.Version,
+			item.Platform,
+			item.Status,
+			item.ExpectedShasums,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create job item: %w", err)
+		}
+		if itemIDs[i], err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get job item ID: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit job: %w", err)
+	}
+
+	// Only report IDs once they exist
+	now := time.Now()
+	job.ID = jobID
+	job.CreatedAt = now
+	for i, item := range items {
+		item.ID = itemIDs[i]
+		item.JobID = jobID
+		item.CreatedAt = now
+	}
+	if job.Status == "pending" {
+		r.NotifyEnqueued()
+	}
+	return nil
+}
+
+// NotifyEnqueued wakes the processor to start pending jobs without waiting
+// for its next poll. Call it once a pending job's items have all been
+// created; CreateWithItems does so itself.
+func (r *JobRepository) NotifyEnqueued() {
+	r.db.notifyJobEnqueued()
+}
+
+// CreateItem creates a new job item
+func (r *JobRepository) CreateItem(ctx context.Context, item *DownloadJobItem) error {
+	result, err := r.db.exec(ctx, "job.create_item", createItemQuery,
 		item.JobID,
 		item.Namespace,
 		item.Type,
 		item.Version,
 		item.Platform,
 		item.Status,
+		item.ExpectedShasums,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create job item: %w", err)
@@ -290,7 +415,7 @@ func (r *JobRepository) UpdateItem(ctx context.Context, item *DownloadJobItem) e
 		WHERE id = ?
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query,
+	result, err := r.db.exec(ctx, "job.update_item", query,
 		item.Status,
 		item.ProviderID,
 		item.ErrorMessage,
@@ -317,18 +442,58 @@ func (r *JobRepository) UpdateItem(ctx context.Context, item *DownloadJobItem) e
 	return nil
 }
 
+// GetItem retrieves a download job item by ID
+func (r *JobRepository) GetItem(ctx context.Context, id int64) (*DownloadJobItem, error) {
+	query := `
+		SELECT id, job_id, namespace, type, version, platform, status,
+		       download_url, size_bytes, downloaded_bytes, provider_id, error_message,
+		       retry_count, created_at, started_at, completed_at, expected_shasums
+		FROM download_job_items
+		WHERE id = ?
+	`
+
+	item := &DownloadJobItem{}
+	err := r.db.queryRow(ctx, "job.get_item", query, id).Scan(
+		&item.ID,
+		&item.JobID,
+		&item.Namespace,
+		&item.Type,
+		&item.Version,
+		&item.Platform,
+		&item.Status,
+		&item.DownloadURL,
+		&item.SizeBytes,
+		&item.DownloadedBytes,
+		&item.ProviderID,
+		&item.ErrorMessage,
+		&item.RetryCount,
+		&item.CreatedAt,
+		&item.StartedAt,
+		&item.CompletedAt,
+		&item.ExpectedShasums,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job item: %w", err)
+	}
+
+	return item, nil
+}
+
 // GetItems retrieves all items for a job
 func (r *JobRepository) GetItems(ctx context.Context, jobID int64) ([]*DownloadJobItem, error) {
 	query := `
 		SELECT id, job_id, namespace, type, version, platform, status, 
 		       download_url, size_bytes, downloaded_bytes, provider_id, error_message, 
-		       retry_count, created_at, started_at, completed_at
+		       retry_count, created_at, started_at, completed_at, expected_shasums
 		FROM download_job_items
 		WHERE job_id = ?
 		ORDER BY created_at ASC
 	`
 
-	rows, err := r.db.conn.QueryContext(ctx, query, jobID)
+	rows, err := r.db.query(ctx, "job.get_items", query, jobID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get job items: %w", err)
 	}
@@ -354,6 +519,7 @@ func (r *JobRepository) GetItems(ctx context.Context, jobID int64) ([]*DownloadJ
 			&item.CreatedAt,
 			&item.StartedAt,
 			&item.CompletedAt,
+			&item.ExpectedShasums,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan job item: %w", err)
 		}
@@ -368,7 +534,7 @@ func (r *JobRepository) CountByStatus(ctx context.Context, status string) (int64
 	query := `SELECT COUNT(*) FROM download_jobs WHERE status = ?`
 
 	var count int64
-	err := r.db.conn.QueryRowContext(ctx, query, status).Scan(&count)
+	err := r.db.queryRow(ctx, "job.count_by_status", query, status).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count jobs: %w", err)
 	}
@@ -376,6 +542,24 @@ func (r *JobRepository) CountByStatus(ctx context.Context, status string) (int64
 	return count, nil
 }
 
+// CountPendingItems counts provider and module items still waiting to be
+// processed in pending or running jobs
+func (r *JobRepository) CountPendingItems(ctx context.Context) (int64, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM download_job_items i JOIN download_jobs j ON j.id = i.job_id
+			 WHERE i.status = 'pending' AND j.status IN ('pending', 'running')) +
+			(SELECT COUNT(*) FROM module_job_items i JOIN download_jobs j ON j.id = i.job_id
+			 WHERE i.status = 'pending' AND j.status IN ('pending', 'running'))
+	`
+
+	var count int64
+	if err := r.db.queryRow(ctx, "job.count_pending_items", query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count pending job items: %w", err)
+	}
+	return count, nil
+}
+
 // ResetFailedItems resets all failed items in a job back to pending status
 func (r *JobRepository) ResetFailedItems(ctx context.Context, jobID int64) (int64, error) {
 	query := `
@@ -388,7 +572,7 @@ func (r *JobRepository) ResetFailedItems(ctx context.Context, jobID int64) (int6
 		WHERE job_id = ? AND status = 'failed'
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query, jobID)
+	result, err := r.db.exec(ctx, "job.reset_failed_items", query, jobID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to reset failed items: %w", err)
 	}
@@ -400,3 +584,70 @@ func (r *JobRepository) ResetFailedItems(ctx context.Context, jobID int64) (int6
 
 	return rows, nil
 }
+
+// FailedDownload aggregates failed job items for a single provider artifact
+type FailedDownload struct {
+	Namespace    string
+	Type         string
+	Version      string
+	Platform     string
+	FailureCount int64 // Failed items across all jobs
+	JobCount     int64 // Distinct jobs with a failed item
+	LastJobID    int64 // Job of the most recent failure
+	LastError    sql.NullString
+	LastFailedAt sql.NullTime
+}
+
+// ListFailedDownloads groups failed job items by namespace, type, version, and
+// platform, most failures first. Artifacts that have since been mirrored are
+// excluded, as are groups with fewer than minFailures failures.
+func (r *JobRepository) ListFailedDownloads(ctx context.Context, minFailures, limit int) ([]*FailedDownload, error) {
+	query := `
+		SELECT g.namespace, g.type, g.version, g.platform,
+		       g.failure_count, g.job_count,
+		       i.job_id, i.error_message, i.completed_at
+		FROM (
+			SELECT namespace, type, version, platform,
+			       COUNT(*) AS failure_count,
+			       COUNT(DISTINCT job_id) AS job_count,
+			       MAX(id) AS last_id
+			FROM download_job_items
+			WHERE status = 'failed'
+			GROUP BY namespace, type, version, platform
+		) g
+		JOIN download_job_items i ON i.id = g.last_id
+		WHERE g.failure_count >= ?
+		  AND NOT EXISTS (
+			SELECT 1 FROM providers p
+			WHERE p.namespace = g.namespace AND p.type = g.type
+			  AND p.version = g.version AND p.platform = g.platform
+		  )
+		ORDER BY g.failure_count DESC, g.last_id DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.query(ctx, "job.list_failed_downloads", query, minFailures, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list failed downloads: %w", err)
+	}
+	defer rows.Close()
+
+	var failures []*FailedDownload
+	for rows.Next() {
+		f := &FailedDownload{}
+		if err := rows.Scan(
+			&f.Namespace, &f.Type, &f.Version, &f.Platform,
+			&f.FailureCount, &f.JobCount,
+			&f.LastJobID, &f.LastError, &f.LastFailedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan failed download: %w", err)
+		}
+		failures = append(failures, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating failed downloads: %w", err)
+	}
+
+	return failures, nil
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// LabelTarget identifies the kind of artifact a label is attached to
+type LabelTarget string
+
+const (
+	LabelProvider LabelTarget = "provider"
+	LabelModule   LabelTarget = "module"
+)
+
+// table returns the join table and its artifact ID column for the target
+func (t LabelTarget) table() (string, string) {
+	if t == LabelModule {
+		return "module_labels", "module_id"
+	}
+	return "provider_labels", "provider_id"
+}
+
+// LabelRepository handles labels on providers and modules
+type LabelRepository struct {
+	db *DB
+}
+
+// NewLabelRepository creates a new label repository
+func NewLabelRepository(db *DB) *LabelRepository {
+	return &LabelRepository{db: db}
+}
+
+// Add attaches labels to an artifact. Labels it already has are left as is.
+func (r *LabelRepository) Add(ctx context.Context, target LabelTarget, id int64, labels []string) error {
+	table, column := target.table()
+	query := fmt.Sprintf("INSERT OR IGNORE INTO %s (%s, label) VALUES (?, ?)", table, column)
+
+	for _, label := range labels {
+		if _, err := r.db.exec(ctx, "label.add", query, id, label); err != nil {
+			return fmt.Errorf("failed to add label %q: %w", label, err)
+		}
+	}
+	return nil
+}
+
+// Remove detaches a label from an artifact, reporting whether it was attached
+func (r *LabelRepository) Remove(ctx context.Context, target LabelTarget, id int64, label string) (bool, error) {
+	table, column := target.table()
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ? AND label = ?", table, column)
+
+	result, err := r.db.exec(ctx, "label.remove", query, id, label)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove label %q: %w", label, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// List returns the sorted labels of each artifact, keyed by artifact ID.
+// Artifacts without labels are absent from the map.
+func (r *LabelRepository) List(ctx context.Context, target LabelTarget, ids []int64) (map[int64][]string, error) {
+	labels := make(map[int64][]string)
+	if len(ids) == 0 {
+		return labels, nil
+	}
+
+	table, column := target.table()
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	query := fmt.Sprintf("SELECT %s, label FROM %s WHERE %s IN (%s) ORDER BY label",
+		column, table, column, placeholders)
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := r.db.query(ctx, "label.list", query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list labels: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var label string
+		if err := rows.Scan(&id, &label); err != nil {
+			return nil, fmt.Errorf("failed to scan label: %w", err)
+		}
+		labels[id] = append(labels[id], label)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating labels: %w", err)
+	}
+
+	return labels, nil
+}
+
+// labelCondition returns a WHERE condition matching artifacts that carry label
+func labelCondition(target LabelTarget) string {
+	table, column := target.table()
+	return fmt.Sprintf("id IN (SELECT %s FROM %s WHERE label = ?)", column, table)
+}
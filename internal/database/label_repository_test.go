@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelRepository_Providers(t *testing.T) {
+	db := setupTestDB(t)
+	providerRepo := NewProviderRepository(db)
+	repo := NewLabelRepository(db)
+	ctx := context.Background()
+
+	var ids []int64
+	for _, platform := range []string{"linux_amd64", "darwin_arm64"} {
+		p := &Provider{
+			Namespace: "hashicorp",
+			Type:      "aws",
+			Version:   "5.0.0",
+			Platform:  platform,
+			Filename:  "terraform-provider-aws_5.0.0_" + platform + ".zip",
+			S3Key:     "providers/hashicorp/aws/5.0.0/" + platform + ".zip",
+		}
+		require.NoError(t, providerRepo.Create(ctx, p))
+		ids = append(ids, p.ID)
+	}
+
+	t.Run("add is idempotent", func(t *testing.T) {
+		require.NoError(t, repo.Add(ctx, LabelProvider, ids[0], []string{"team-x", "approved"}))
+		require.NoError(t, repo.Add(ctx, LabelProvider, ids[0], []string{"approved"}))
+
+		labels, err := repo.List(ctx, LabelProvider, ids)
+		require.NoError(t, err)
+		assert.Equal(t, map[int64][]string{ids[0]: {"approved", "team-x"}}, labels)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		removed, err := repo.Remove(ctx, LabelProvider, ids[0], "team-x")
+		require.NoError(t, err)
+		assert.True(t, removed)
+
+		removed, err = repo.Remove(ctx, LabelProvider, ids[0], "team-x")
+		require.NoError(t, err)
+		assert.False(t, removed)
+
+		labels, err := repo.List(ctx, LabelProvider, ids[:1])
+		require.NoError(t, err)
+		assert.Equal(t, []string{"approved"}, labels[ids[0]])
+	})
+
+	t.Run("deleting the provider drops its labels", func(t *testing.T) {
+		require.NoError(t, providerRepo.Delete(ctx, ids[0]))
+
+		labels, err := repo.List(ctx, LabelProvider, ids[:1])
+		require.NoError(t, err)
+		assert.Empty(t, labels)
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		err := repo.Add(ctx, LabelProvider, 9999, []string{"approved"})
+		assert.Error(t, err)
+	})
+}
+
+func TestLabelRepository_ModuleFilter(t *testing.T) {
+	db := setupTestDB(t)
+	moduleRepo := NewModuleRepository(db)
+	repo := NewLabelRepository(db)
+	ctx := context.Background()
+
+	var ids []int64
+	for _, version := range []string{"1.0.0", "2.0.0", "3.0.0"} {
+		m := &Module{
+			Namespace: "terraform-aws-modules",
+			Name:      "vpc",
+			System:    "aws",
+			Version:   version,
+			S3Key:     "modules/terraform-aws-modules/vpc/aws/" + version + ".tar.gz",
+			Filename:  version + ".tar.gz",
+		}
+		require.NoError(t, moduleRepo.Create(ctx, m))
+		ids = append(ids, m.ID)
+	}
+
+	require.NoError(t, repo.Add(ctx, LabelModule, ids[0], []string{"approved"}))
+	require.NoError(t, repo.Add(ctx, LabelModule, ids[2], []string{"approved", "team-x"}))
+
+	filter := ModuleFilter{Label: "approved"}
+	modules, err := moduleRepo.List(ctx, filter, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, modules, 2)
+	for _, m := range modules {
+		assert.Contains(t, []int64{ids[0], ids[2]}, m.ID)
+	}
+
+	count, err := moduleRepo.Count(ctx, filter)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = moduleRepo.Count(ctx, ModuleFilter{Label: "team-x", Namespace: "terraform-aws-modules"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// A label nothing carries matches nothing
+	count, err = moduleRepo.Count(ctx, ModuleFilter{Label: "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+}
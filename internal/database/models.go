@@ -18,6 +18,8 @@ type Provider struct {
 	DownloadURL string
 	Shasum      string
 	SigningKeys sql.NullString
+	Protocols   sql.NullString
+	H1Hash      sql.NullString
 
 	// Storage information
 	S3Key     string
@@ -27,6 +29,10 @@ type Provider struct {
 	Deprecated bool
 	Blocked    bool
 
+	// Verified is set when the archive matched a SHA256SUMS document signed
+	// by one of the registry's signing keys
+	Verified bool
+
 	// Timestamps
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -143,6 +149,10 @@ type DownloadJobItem struct {
 	SizeBytes       sql.NullInt64
 	DownloadedBytes sql.NullInt64
 
+	// ExpectedShasums is a comma-separated list of archive SHA-256 sums the
+	// download must match; unset accepts whatever upstream serves
+	ExpectedShasums sql.NullString
+
 	// Results
 	ProviderID   sql.NullInt64
 	ErrorMessage sql.NullString
@@ -169,6 +179,11 @@ type Module struct {
 	Filename  string
 	SizeBytes int64
 
+	// ContentHash is the SHA256 of the uncompressed archive when it is stored
+	// content-addressed under blobs/, where versions with identical content
+	// share one object
+	ContentHash sql.NullString
+
 	// Original source tracking
 	OriginalSourceURL sql.NullString
 
@@ -24,7 +24,7 @@ func (r *ModuleJobRepository) CreateItem(ctx context.Context, item *ModuleJobIte
 		VALUES (?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query,
+	result, err := r.db.exec(ctx, "module_job.create_item", query,
 		item.JobID,
 		item.Namespace,
 		item.Name,
@@ -56,7 +56,7 @@ func (r *ModuleJobRepository) GetItem(ctx context.Context, id int64) (*ModuleJob
 	`
 
 	item := &ModuleJobItem{}
-	err := r.db.conn.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.queryRow(ctx, "module_job.get_item", query, id).Scan(
 		&item.ID,
 		&item.JobID,
 		&item.Namespace,
@@ -90,7 +90,7 @@ func (r *ModuleJobRepository) ListByJob(ctx context.Context, jobID int64) ([]*Mo
 		ORDER BY id ASC
 	`
 
-	rows, err := r.db.conn.QueryContext(ctx, query, jobID)
+	rows, err := r.db.query(ctx, "module_job.list_by_job", query, jobID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list module job items: %w", err)
 	}
@@ -135,7 +135,7 @@ func (r *ModuleJobRepository) ListPendingByJob(ctx context.Context, jobID int64)
 		ORDER BY id ASC
 	`
 
-	rows, err := r.db.conn.QueryContext(ctx, query, jobID)
+	rows, err := r.db.query(ctx, "module_job.list_pending_by_job", query, jobID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list pending module job items: %w", err)
 	}
@@ -178,7 +178,7 @@ func (r *ModuleJobRepository) UpdateItem(ctx context.Context, item *ModuleJobIte
 		WHERE id = ?
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query,
+	result, err := r.db.exec(ctx, "module_job.update_item", query,
 		item.Status,
 		item.ModuleID,
 		item.ErrorMessage,
@@ -214,7 +214,7 @@ func (r *ModuleJobRepository) CountByStatus(ctx context.Context, jobID int64) (p
 		WHERE job_id = ?
 	`
 
-	err = r.db.conn.QueryRowContext(ctx, query, jobID).Scan(&pending, &downloading, &completed, &failed)
+	err = r.db.queryRow(ctx, "module_job.count_by_status", query, jobID).Scan(&pending, &downloading, &completed, &failed)
 	if err != nil {
 		return 0, 0, 0, 0, fmt.Errorf("failed to count module job items: %w", err)
 	}
@@ -230,7 +230,7 @@ func (r *ModuleJobRepository) ResetFailedItems(ctx context.Context, jobID int64)
 		WHERE job_id = ? AND status = 'failed'
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query, jobID)
+	result, err := r.db.exec(ctx, "module_job.reset_failed_items", query, jobID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to reset failed module items: %w", err)
 	}
@@ -247,7 +247,7 @@ func (r *ModuleJobRepository) ResetFailedItems(ctx context.Context, jobID int64)
 func (r *ModuleJobRepository) DeleteByJob(ctx context.Context, jobID int64) error {
 	query := "DELETE FROM module_job_items WHERE job_id = ?"
 
-	_, err := r.db.conn.ExecContext(ctx, query, jobID)
+	_, err := r.db.exec(ctx, "module_job.delete_by_job", query, jobID)
 	if err != nil {
 		return fmt.Errorf("failed to delete module job items: %w", err)
 	}
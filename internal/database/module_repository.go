@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -22,14 +23,14 @@ func (r *ModuleRepository) Create(ctx context.Context, m *Module) error {
 	query := `
 		INSERT INTO modules (
 			namespace, name, system, version,
-			s3_key, filename, size_bytes,
+			s3_key, filename, size_bytes, content_hash,
 			original_source_url, deprecated, blocked
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query,
+	result, err := r.db.exec(ctx, "module.create", query,
 		m.Namespace, m.Name, m.System, m.Version,
-		m.S3Key, m.Filename, m.SizeBytes,
+		m.S3Key, m.Filename, m.SizeBytes, m.ContentHash,
 		m.OriginalSourceURL, m.Deprecated, m.Blocked,
 	)
 	if err != nil {
@@ -51,7 +52,7 @@ func (r *ModuleRepository) Create(ctx context.Context, m *Module) error {
 func (r *ModuleRepository) GetByID(ctx context.Context, id int64) (*Module, error) {
 	query := `
 		SELECT id, namespace, name, system, version,
-			   s3_key, filename, size_bytes,
+			   s3_key, filename, size_bytes, content_hash,
 			   original_source_url, deprecated, blocked,
 			   created_at, updated_at
 		FROM modules
@@ -59,9 +60,9 @@ func (r *ModuleRepository) GetByID(ctx context.Context, id int64) (*Module, erro
 	`
 
 	m := &Module{}
-	err := r.db.conn.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.queryRow(ctx, "module.get_by_id", query, id).Scan(
 		&m.ID, &m.Namespace, &m.Name, &m.System, &m.Version,
-		&m.S3Key, &m.Filename, &m.SizeBytes,
+		&m.S3Key, &m.Filename, &m.SizeBytes, &m.ContentHash,
 		&m.OriginalSourceURL, &m.Deprecated, &m.Blocked,
 		&m.CreatedAt, &m.UpdatedAt,
 	)
@@ -79,7 +80,7 @@ func (r *ModuleRepository) GetByID(ctx context.Context, id int64) (*Module, erro
 func (r *ModuleRepository) GetByIdentity(ctx context.Context, namespace, name, system, version string) (*Module, error) {
 	query := `
 		SELECT id, namespace, name, system, version,
-			   s3_key, filename, size_bytes,
+			   s3_key, filename, size_bytes, content_hash,
 			   original_source_url, deprecated, blocked,
 			   created_at, updated_at
 		FROM modules
@@ -87,9 +88,9 @@ func (r *ModuleRepository) GetByIdentity(ctx context.Context, namespace, name, s
 	`
 
 	m := &Module{}
-	err := r.db.conn.QueryRowContext(ctx, query, namespace, name, system, version).Scan(
+	err := r.db.queryRow(ctx, "module.get_by_identity", query, namespace, name, system, version).Scan(
 		&m.ID, &m.Namespace, &m.Name, &m.System, &m.Version,
-		&m.S3Key, &m.Filename, &m.SizeBytes,
+		&m.S3Key, &m.Filename, &m.SizeBytes, &m.ContentHash,
 		&m.OriginalSourceURL, &m.Deprecated, &m.Blocked,
 		&m.CreatedAt, &m.UpdatedAt,
 	)
@@ -107,7 +108,7 @@ func (r *ModuleRepository) GetByIdentity(ctx context.Context, namespace, name, s
 func (r *ModuleRepository) ListVersions(ctx context.Context, namespace, name, system string) ([]*Module, error) {
 	query := `
 		SELECT id, namespace, name, system, version,
-			   s3_key, filename, size_bytes,
+			   s3_key, filename, size_bytes, content_hash,
 			   original_source_url, deprecated, blocked,
 			   created_at, updated_at
 		FROM modules
@@ -116,7 +117,7 @@ func (r *ModuleRepository) ListVersions(ctx context.Context, namespace, name, sy
 		ORDER BY version DESC
 	`
 
-	rows, err := r.db.conn.QueryContext(ctx, query, namespace, name, system)
+	rows, err := r.db.query(ctx, "module.list_versions", query, namespace, name, system)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list module versions: %w", err)
 	}
@@ -127,7 +128,7 @@ func (r *ModuleRepository) ListVersions(ctx context.Context, namespace, name, sy
 		m := &Module{}
 		if err := rows.Scan(
 			&m.ID, &m.Namespace, &m.Name, &m.System, &m.Version,
-			&m.S3Key, &m.Filename, &m.SizeBytes,
+			&m.S3Key, &m.Filename, &m.SizeBytes, &m.ContentHash,
 			&m.OriginalSourceURL, &m.Deprecated, &m.Blocked,
 			&m.CreatedAt, &m.UpdatedAt,
 		); err != nil {
@@ -143,19 +144,99 @@ func (r *ModuleRepository) ListVersions(ctx context.Context, namespace, name, sy
 	return modules, nil
 }
 
-// List retrieves all modules with optional pagination
-func (r *ModuleRepository) List(ctx context.Context, limit, offset int) ([]*Module, error) {
+// ModuleName identifies a mirrored module independent of version
+type ModuleName struct {
+	Namespace string
+	Name      string
+	System    string
+}
+
+// ListNames returns each distinct module namespace, name, and system in the mirror
+func (r *ModuleRepository) ListNames(ctx context.Context) ([]ModuleName, error) {
+	query := `
+		SELECT DISTINCT namespace, name, system
+		FROM modules
+		ORDER BY namespace, name, system
+	`
+
+	rows, err := r.db.query(ctx, "module.list_names", query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list module names: %w", err)
+	}
+	defer rows.Close()
+
+	var names []ModuleName
+	for rows.Next() {
+		var n ModuleName
+		if err := rows.Scan(&n.Namespace, &n.Name, &n.System); err != nil {
+			return nil, fmt.Errorf("failed to scan module name: %w", err)
+		}
+		names = append(names, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating module names: %w", err)
+	}
+
+	return names, nil
+}
+
+// ModuleFilter restricts module listings to exact namespace, name, and
+// system matches, and to modules carrying a label. Empty fields match any
+// value.
+type ModuleFilter struct {
+	Namespace string
+	Name      string
+	System    string
+	Label     string
+}
+
+// where returns the SQL WHERE clause and arguments for the filter
+func (f ModuleFilter) where() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if f.Namespace != "" {
+		conditions = append(conditions, "namespace = ?")
+		args = append(args, f.Namespace)
+	}
+	if f.Name != "" {
+		conditions = append(conditions, "name = ?")
+		args = append(args, f.Name)
+	}
+	if f.System != "" {
+		conditions = append(conditions, "system = ?")
+		args = append(args, f.System)
+	}
+	if f.Label != "" {
+		conditions = append(conditions, labelCondition(LabelModule))
+		args = append(args, f.Label)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// List retrieves modules matching the filter with pagination, newest first
+func (r *ModuleRepository) List(ctx context.Context, filter ModuleFilter, limit, offset int) ([]*Module, error) {
+	return r.ListSorted(ctx, filter, newestFirst, limit, offset)
+}
+
+// ListSorted retrieves modules matching the filter with pagination in the
+// given order. Fields outside ModuleSortFields fall back to newest first.
+func (r *ModuleRepository) ListSorted(ctx context.Context, filter ModuleFilter, sort Sort, limit, offset int) ([]*Module, error) {
+	where, args := filter.where()
 	query := `
 		SELECT id, namespace, name, system, version,
-			   s3_key, filename, size_bytes,
+			   s3_key, filename, size_bytes, content_hash,
 			   original_source_url, deprecated, blocked,
 			   created_at, updated_at
-		FROM modules
-		ORDER BY created_at DESC
+		FROM modules` + where + sort.orderBy(ModuleSortFields) + `
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.conn.QueryContext(ctx, query, limit, offset)
+	args = append(args, limit, offset)
+	rows, err := r.db.query(ctx, "module.list", query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list modules: %w", err)
 	}
@@ -166,7 +247,7 @@ func (r *ModuleRepository) List(ctx context.Context, limit, offset int) ([]*Modu
 		m := &Module{}
 		if err := rows.Scan(
 			&m.ID, &m.Namespace, &m.Name, &m.System, &m.Version,
-			&m.S3Key, &m.Filename, &m.SizeBytes,
+			&m.S3Key, &m.Filename, &m.SizeBytes, &m.ContentHash,
 			&m.OriginalSourceURL, &m.Deprecated, &m.Blocked,
 			&m.CreatedAt, &m.UpdatedAt,
 		); err != nil {
@@ -190,7 +271,7 @@ func (r *ModuleRepository) Update(ctx context.Context, m *Module) error {
 		WHERE id = ?
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query, m.Deprecated, m.Blocked, m.SizeBytes, m.ID)
+	result, err := r.db.exec(ctx, "module.update", query, m.Deprecated, m.Blocked, m.SizeBytes, m.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update module: %w", err)
 	}
@@ -208,11 +289,38 @@ func (r *ModuleRepository) Update(ctx context.Context, m *Module) error {
 	return nil
 }
 
+// UpdateArchive records a replaced module archive: its storage key, size and
+// content hash
+func (r *ModuleRepository) UpdateArchive(ctx context.Context, m *Module) error {
+	query := `
+		UPDATE modules
+		SET s3_key = ?, size_bytes = ?, content_hash = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, "module.update_archive", query, m.S3Key, m.SizeBytes, m.ContentHash, m.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update module archive: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("module not found")
+	}
+
+	m.UpdatedAt = time.Now()
+	return nil
+}
+
 // Delete deletes a module
 func (r *ModuleRepository) Delete(ctx context.Context, id int64) error {
 	query := "DELETE FROM modules WHERE id = ?"
 
-	result, err := r.db.conn.ExecContext(ctx, query, id)
+	result, err := r.db.exec(ctx, "module.delete", query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete module: %w", err)
 	}
@@ -229,10 +337,40 @@ func (r *ModuleRepository) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
-// Count returns the total number of modules
-func (r *ModuleRepository) Count(ctx context.Context) (int64, error) {
+// DeleteAllVersions deletes every version of a module in a single statement
+// and returns the number of rows removed
+func (r *ModuleRepository) DeleteAllVersions(ctx context.Context, namespace, name, system string) (int64, error) {
+	query := "DELETE FROM modules WHERE namespace = ? AND name = ? AND system = ?"
+
+	result, err := r.db.exec(ctx, "module.delete_all_versions", query, namespace, name, system)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete module versions: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows, nil
+}
+
+// CountByS3Key returns the number of module versions whose archive is stored
+// at key. Content-addressed archives are shared, so an archive may only be
+// removed from storage once no version references it.
+func (r *ModuleRepository) CountByS3Key(ctx context.Context, key string) (int64, error) {
+	var count int64
+	err := r.db.queryRow(ctx, "module.count_by_s3_key", "SELECT COUNT(*) FROM modules WHERE s3_key = ?", key).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count module references: %w", err)
+	}
+	return count, nil
+}
+
+// Count returns the number of modules matching the filter
+func (r *ModuleRepository) Count(ctx context.Context, filter ModuleFilter) (int64, error) {
+	where, args := filter.where()
 	var count int64
-	err := r.db.conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM modules").Scan(&count)
+	err := r.db.queryRow(ctx, "module.count", "SELECT COUNT(*) FROM modules"+where, args...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count modules: %w", err)
 	}
@@ -259,13 +397,13 @@ func (r *ModuleRepository) GetStorageStats(ctx context.Context) (*ModuleStorageS
 			COUNT(DISTINCT namespace) as unique_namespaces,
 			COUNT(DISTINCT namespace || '/' || name || '/' || system) as unique_names,
 			COUNT(DISTINCT namespace || '/' || name || '/' || system || '/' || version) as unique_versions,
-			SUM(CASE WHEN deprecated = 1 THEN 1 ELSE 0 END) as deprecated_count,
-			SUM(CASE WHEN blocked = 1 THEN 1 ELSE 0 END) as blocked_count
+			COALESCE(SUM(CASE WHEN deprecated = 1 THEN 1 ELSE 0 END), 0) as deprecated_count,
+			COALESCE(SUM(CASE WHEN blocked = 1 THEN 1 ELSE 0 END), 0) as blocked_count
 		FROM modules
 	`
 
 	stats := &ModuleStorageStats{}
-	err := r.db.conn.QueryRowContext(ctx, query).Scan(
+	err := r.db.queryRow(ctx, "module.get_storage_stats", query).Scan(
 		&stats.TotalModules,
 		&stats.TotalSizeBytes,
 		&stats.UniqueNamespaces,
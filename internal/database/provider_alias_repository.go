@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ProviderAlias serves the provider at TargetNamespace/TargetType under
+// Namespace/Type as well. Both addresses share the same stored archives.
+type ProviderAlias struct {
+	ID              int64
+	Namespace       string
+	Type            string
+	TargetNamespace string
+	TargetType      string
+	CreatedAt       time.Time
+}
+
+// ProviderAliasRepository handles provider alias mappings
+type ProviderAliasRepository struct {
+	db *DB
+}
+
+// NewProviderAliasRepository creates a new provider alias repository
+func NewProviderAliasRepository(db *DB) *ProviderAliasRepository {
+	return &ProviderAliasRepository{db: db}
+}
+
+const providerAliasColumns = `id, namespace, type, target_namespace, target_type, created_at`
+
+// Create records a new alias
+func (r *ProviderAliasRepository) Create(ctx context.Context, a *ProviderAlias) error {
+	query := `
+		INSERT INTO provider_aliases (namespace, type, target_namespace, target_type)
+		VALUES (?, ?, ?, ?)
+	`
+
+	result, err := r.db.exec(ctx, "provider_alias.create", query,
+		a.Namespace, a.Type, a.TargetNamespace, a.TargetType)
+	if err != nil {
+		return fmt.Errorf("failed to create provider alias: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	a.ID = id
+	a.CreatedAt = time.Now()
+	return nil
+}
+
+// Get returns the alias registered for namespace/type, or nil if there is none
+func (r *ProviderAliasRepository) Get(ctx context.Context, namespace, providerType string) (*ProviderAlias, error) {
+	query := `SELECT ` + providerAliasColumns + ` FROM provider_aliases WHERE namespace = ? AND type = ?`
+	return r.scanOne(r.db.queryRow(ctx, "provider_alias.get", query, namespace, providerType))
+}
+
+// GetByID returns an alias by ID, or nil if it does not exist
+func (r *ProviderAliasRepository) GetByID(ctx context.Context, id int64) (*ProviderAlias, error) {
+	query := `SELECT ` + providerAliasColumns + ` FROM provider_aliases WHERE id = ?`
+	return r.scanOne(r.db.queryRow(ctx, "provider_alias.get_by_id", query, id))
+}
+
+// IsTarget reports whether any alias points at namespace/type
+func (r *ProviderAliasRepository) IsTarget(ctx context.Context, namespace, providerType string) (bool, error) {
+	query := `SELECT COUNT(*) FROM provider_aliases WHERE target_namespace = ? AND target_type = ?`
+
+	var count int64
+	if err := r.db.queryRow(ctx, "provider_alias.is_target", query, namespace, providerType).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to look up provider alias targets: %w", err)
+	}
+	return count > 0, nil
+}
+
+// List returns every alias ordered by address
+func (r *ProviderAliasRepository) List(ctx context.Context) ([]*ProviderAlias, error) {
+	query := `SELECT ` + providerAliasColumns + ` FROM provider_aliases ORDER BY namespace, type`
+
+	rows, err := r.db.query(ctx, "provider_alias.list", query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list provider aliases: %w", err)
+	}
+	defer rows.Close()
+
+	var aliases []*ProviderAlias
+	for rows.Next() {
+		a := &ProviderAlias{}
+		if err := rows.Scan(&a.ID, &a.Namespace, &a.Type, &a.TargetNamespace, &a.TargetType, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan provider alias: %w", err)
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// Delete removes an alias, reporting whether it existed
+func (r *ProviderAliasRepository) Delete(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.exec(ctx, "provider_alias.delete", `DELETE FROM provider_aliases WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete provider alias: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (r *ProviderAliasRepository) scanOne(row *sql.Row) (*ProviderAlias, error) {
+	a := &ProviderAlias{}
+	err := row.Scan(&a.ID, &a.Namespace, &a.Type, &a.TargetNamespace, &a.TargetType, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider alias: %w", err)
+	}
+	return a, nil
+}
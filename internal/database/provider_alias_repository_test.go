@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderAliasRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewProviderAliasRepository(db)
+	ctx := context.Background()
+
+	alias, err := repo.Get(ctx, "mycorp", "aws")
+	require.NoError(t, err)
+	assert.Nil(t, alias)
+
+	created := &ProviderAlias{Namespace: "mycorp", Type: "aws", TargetNamespace: "hashicorp", TargetType: "aws"}
+	require.NoError(t, repo.Create(ctx, created))
+	assert.NotZero(t, created.ID)
+
+	// An address can only be aliased once
+	assert.Error(t, repo.Create(ctx, &ProviderAlias{Namespace: "mycorp", Type: "aws", TargetNamespace: "other", TargetType: "aws"}))
+
+	alias, err = repo.Get(ctx, "mycorp", "aws")
+	require.NoError(t, err)
+	require.NotNil(t, alias)
+	assert.Equal(t, "hashicorp", alias.TargetNamespace)
+	assert.Equal(t, "aws", alias.TargetType)
+
+	byID, err := repo.GetByID(ctx, created.ID)
+	require.NoError(t, err)
+	require.NotNil(t, byID)
+	assert.Equal(t, "mycorp", byID.Namespace)
+
+	isTarget, err := repo.IsTarget(ctx, "hashicorp", "aws")
+	require.NoError(t, err)
+	assert.True(t, isTarget)
+	isTarget, err = repo.IsTarget(ctx, "mycorp", "aws")
+	require.NoError(t, err)
+	assert.False(t, isTarget)
+
+	aliases, err := repo.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, aliases, 1)
+
+	deleted, err := repo.Delete(ctx, created.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = repo.Delete(ctx, created.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
@@ -3,10 +3,16 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
+// ErrShasumMismatch is returned when an artifact already exists with
+// different content than the one being stored
+var ErrShasumMismatch = errors.New("artifact already exists with a different shasum")
+
 // ProviderRepository handles provider database operations
 type ProviderRepository struct {
 	db *DB
@@ -22,15 +28,15 @@ func (r *ProviderRepository) Create(ctx context.Context, p *Provider) error {
 	query := `
 		INSERT INTO providers (
 			namespace, type, version, platform,
-			filename, download_url, shasum, signing_keys,
-			s3_key, size_bytes, deprecated, blocked
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			filename, download_url, shasum, signing_keys, protocols,
+			s3_key, size_bytes, deprecated, blocked, h1_hash, verified
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query,
+	result, err := r.db.exec(ctx, "provider.create", query,
 		p.Namespace, p.Type, p.Version, p.Platform,
-		p.Filename, p.DownloadURL, p.Shasum, p.SigningKeys,
-		p.S3Key, p.SizeBytes, p.Deprecated, p.Blocked,
+		p.Filename, p.DownloadURL, p.Shasum, p.SigningKeys, p.Protocols,
+		p.S3Key, p.SizeBytes, p.Deprecated, p.Blocked, p.H1Hash, p.Verified,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create provider: %w", err)
@@ -51,18 +57,18 @@ func (r *ProviderRepository) Create(ctx context.Context, p *Provider) error {
 func (r *ProviderRepository) GetByID(ctx context.Context, id int64) (*Provider, error) {
 	query := `
 		SELECT id, namespace, type, version, platform,
-			   filename, download_url, shasum, signing_keys,
-			   s3_key, size_bytes, deprecated, blocked,
+			   filename, download_url, shasum, signing_keys, protocols,
+			   s3_key, size_bytes, deprecated, blocked, h1_hash, verified,
 			   created_at, updated_at
 		FROM providers
 		WHERE id = ?
 	`
 
 	p := &Provider{}
-	err := r.db.conn.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.queryRow(ctx, "provider.get_by_id", query, id).Scan(
 		&p.ID, &p.Namespace, &p.Type, &p.Version, &p.Platform,
-		&p.Filename, &p.DownloadURL, &p.Shasum, &p.SigningKeys,
-		&p.S3Key, &p.SizeBytes, &p.Deprecated, &p.Blocked,
+		&p.Filename, &p.DownloadURL, &p.Shasum, &p.SigningKeys, &p.Protocols,
+		&p.S3Key, &p.SizeBytes, &p.Deprecated, &p.Blocked, &p.H1Hash, &p.Verified,
 		&p.CreatedAt, &p.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -79,18 +85,18 @@ func (r *ProviderRepository) GetByID(ctx context.Context, id int64) (*Provider,
 func (r *ProviderRepository) GetByIdentity(ctx context.Context, namespace, typ, version, platform string) (*Provider, error) {
 	query := `
 		SELECT id, namespace, type, version, platform,
-			   filename, download_url, shasum, signing_keys,
-			   s3_key, size_bytes, deprecated, blocked,
+			   filename, download_url, shasum, signing_keys, protocols,
+			   s3_key, size_bytes, deprecated, blocked, h1_hash, verified,
 			   created_at, updated_at
 		FROM providers
 		WHERE namespace = ? AND type = ? AND version = ? AND platform = ?
 	`
 
 	p := &Provider{}
-	err := r.db.conn.QueryRowContext(ctx, query, namespace, typ, version, platform).Scan(
+	err := r.db.queryRow(ctx, "provider.get_by_identity", query, namespace, typ, version, platform).Scan(
 		&p.ID, &p.Namespace, &p.Type, &p.Version, &p.Platform,
-		&p.Filename, &p.DownloadURL, &p.Shasum, &p.SigningKeys,
-		&p.S3Key, &p.SizeBytes, &p.Deprecated, &p.Blocked,
+		&p.Filename, &p.DownloadURL, &p.Shasum, &p.SigningKeys, &p.Protocols,
+		&p.S3Key, &p.SizeBytes, &p.Deprecated, &p.Blocked, &p.H1Hash, &p.Verified,
 		&p.CreatedAt, &p.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -103,19 +109,107 @@ func (r *ProviderRepository) GetByIdentity(ctx context.Context, namespace, typ,
 	return p, nil
 }
 
+// GetByIdentityWithShasum retrieves a provider like GetByIdentity and checks
+// that it holds the content with the given shasum. If it holds different
+// content, the provider is returned along with an error wrapping
+// ErrShasumMismatch.
+func (r *ProviderRepository) GetByIdentityWithShasum(ctx context.Context, namespace, typ, version, platform, shasum string) (*Provider, error) {
+	p, err := r.GetByIdentity(ctx, namespace, typ, version, platform)
+	if err != nil || p == nil {
+		return p, err
+	}
+
+	if !strings.EqualFold(p.Shasum, shasum) {
+		return p, fmt.Errorf("%w: %s/%s %s (%s) is stored with shasum %s, not %s",
+			ErrShasumMismatch, namespace, typ, version, platform, p.Shasum, shasum)
+	}
+
+	return p, nil
+}
+
+// Identity uniquely identifies a provider artifact
+type Identity struct {
+	Namespace string
+	Type      string
+	Version   string
+	Platform  string
+}
+
+// identityBatchSize limits the number of tuples per query to stay well below
+// SQLite's bound parameter limit (4 parameters per tuple)
+const identityBatchSize = 200
+
+// ExistingIdentities looks up many provider identities at once and returns the
+// providers that already exist, keyed by identity. Identities that are not
+// present are omitted from the result.
+func (r *ProviderRepository) ExistingIdentities(ctx context.Context, identities []Identity) (map[Identity]*Provider, error) {
+	existing := make(map[Identity]*Provider)
+
+	for start := 0; start < len(identities); start += identityBatchSize {
+		end := start + identityBatchSize
+		if end > len(identities) {
+			end = len(identities)
+		}
+		batch := identities[start:end]
+
+		placeholders := make([]string, len(batch))
+		args := make([]interface{}, 0, len(batch)*4)
+		for i, id := range batch {
+			placeholders[i] = "(?, ?, ?, ?)"
+			args = append(args, id.Namespace, id.Type, id.Version, id.Platform)
+		}
+
+		query := `
+			SELECT id, namespace, type, version, platform,
+				   filename, download_url, shasum, signing_keys, protocols,
+				   s3_key, size_bytes, deprecated, blocked, h1_hash, verified,
+				   created_at, updated_at
+			FROM providers
+			WHERE (namespace, type, version, platform) IN (VALUES ` + strings.Join(placeholders, ", ") + `)
+		`
+
+		rows, err := r.db.query(ctx, "provider.existing_identities", query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query existing providers: %w", err)
+		}
+
+		for rows.Next() {
+			p := &Provider{}
+			if err := rows.Scan(
+				&p.ID, &p.Namespace, &p.Type, &p.Version, &p.Platform,
+				&p.Filename, &p.DownloadURL, &p.Shasum, &p.SigningKeys, &p.Protocols,
+				&p.S3Key, &p.SizeBytes, &p.Deprecated, &p.Blocked, &p.H1Hash, &p.Verified,
+				&p.CreatedAt, &p.UpdatedAt,
+			); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan provider: %w", err)
+			}
+			existing[Identity{Namespace: p.Namespace, Type: p.Type, Version: p.Version, Platform: p.Platform}] = p
+		}
+
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error iterating providers: %w", err)
+		}
+		rows.Close()
+	}
+
+	return existing, nil
+}
+
 // ListVersions retrieves all versions of a provider for a namespace and type
 func (r *ProviderRepository) ListVersions(ctx context.Context, namespace, typ string) ([]*Provider, error) {
 	query := `
 		SELECT id, namespace, type, version, platform,
-			   filename, download_url, shasum, signing_keys,
-			   s3_key, size_bytes, deprecated, blocked,
+			   filename, download_url, shasum, signing_keys, protocols,
+			   s3_key, size_bytes, deprecated, blocked, h1_hash, verified,
 			   created_at, updated_at
 		FROM providers
 		WHERE namespace = ? AND type = ?
 		ORDER BY version DESC, platform ASC
 	`
 
-	rows, err := r.db.conn.QueryContext(ctx, query, namespace, typ)
+	rows, err := r.db.query(ctx, "provider.list_versions", query, namespace, typ)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list provider versions: %w", err)
 	}
@@ -126,8 +220,8 @@ func (r *ProviderRepository) ListVersions(ctx context.Context, namespace, typ st
 		p := &Provider{}
 		if err := rows.Scan(
 			&p.ID, &p.Namespace, &p.Type, &p.Version, &p.Platform,
-			&p.Filename, &p.DownloadURL, &p.Shasum, &p.SigningKeys,
-			&p.S3Key, &p.SizeBytes, &p.Deprecated, &p.Blocked,
+			&p.Filename, &p.DownloadURL, &p.Shasum, &p.SigningKeys, &p.Protocols,
+			&p.S3Key, &p.SizeBytes, &p.Deprecated, &p.Blocked, &p.H1Hash, &p.Verified,
 			&p.CreatedAt, &p.UpdatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan provider: %w", err)
@@ -142,19 +236,100 @@ func (r *ProviderRepository) ListVersions(ctx context.Context, namespace, typ st
 	return providers, nil
 }
 
-// List retrieves all providers with optional filters
-func (r *ProviderRepository) List(ctx context.Context, limit, offset int) ([]*Provider, error) {
+// ListUnverified retrieves every provider archive that was stored without
+// passing signature verification, ordered by address
+func (r *ProviderRepository) ListUnverified(ctx context.Context) ([]*Provider, error) {
 	query := `
 		SELECT id, namespace, type, version, platform,
-			   filename, download_url, shasum, signing_keys,
-			   s3_key, size_bytes, deprecated, blocked,
+			   filename, download_url, shasum, signing_keys, protocols,
+			   s3_key, size_bytes, deprecated, blocked, h1_hash, verified,
 			   created_at, updated_at
 		FROM providers
-		ORDER BY created_at DESC
+		WHERE verified = 0
+		ORDER BY namespace ASC, type ASC, version DESC, platform ASC
+	`
+
+	rows, err := r.db.query(ctx, "provider.list_unverified", query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unverified providers: %w", err)
+	}
+	defer rows.Close()
+
+	var providers []*Provider
+	for rows.Next() {
+		p := &Provider{}
+		if err := rows.Scan(
+			&p.ID, &p.Namespace, &p.Type, &p.Version, &p.Platform,
+			&p.Filename, &p.DownloadURL, &p.Shasum, &p.SigningKeys, &p.Protocols,
+			&p.S3Key, &p.SizeBytes, &p.Deprecated, &p.Blocked, &p.H1Hash, &p.Verified,
+			&p.CreatedAt, &p.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan provider: %w", err)
+		}
+		providers = append(providers, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating providers: %w", err)
+	}
+
+	return providers, nil
+}
+
+// ProviderName identifies a mirrored provider independent of version and platform
+type ProviderName struct {
+	Namespace string
+	Type      string
+}
+
+// ListNames returns each distinct provider namespace and type in the mirror
+func (r *ProviderRepository) ListNames(ctx context.Context) ([]ProviderName, error) {
+	query := `
+		SELECT DISTINCT namespace, type
+		FROM providers
+		ORDER BY namespace, type
+	`
+
+	rows, err := r.db.query(ctx, "provider.list_names", query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list provider names: %w", err)
+	}
+	defer rows.Close()
+
+	var names []ProviderName
+	for rows.Next() {
+		var n ProviderName
+		if err := rows.Scan(&n.Namespace, &n.Type); err != nil {
+			return nil, fmt.Errorf("failed to scan provider name: %w", err)
+		}
+		names = append(names, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating provider names: %w", err)
+	}
+
+	return names, nil
+}
+
+// List retrieves providers with pagination, newest first
+func (r *ProviderRepository) List(ctx context.Context, limit, offset int) ([]*Provider, error) {
+	return r.ListSorted(ctx, newestFirst, limit, offset)
+}
+
+// ListSorted retrieves providers with pagination in the given order. Fields
+// outside ProviderSortFields fall back to newest first.
+func (r *ProviderRepository) ListSorted(ctx context.Context, sort Sort, limit, offset int) ([]*Provider, error) {
+	query := `
+		SELECT id, namespace, type, version, platform,
+			   filename, download_url, shasum, signing_keys, protocols,
+			   s3_key, size_bytes, deprecated, blocked, h1_hash, verified,
+			   created_at, updated_at
+		FROM providers` + sort.orderBy(ProviderSortFields) + `
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.conn.QueryContext(ctx, query, limit, offset)
+	rows, err := r.db.query(ctx, "provider.list", query, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list providers: %w", err)
 	}
@@ -165,8 +340,8 @@ func (r *ProviderRepository) List(ctx context.Context, limit, offset int) ([]*Pr
 		p := &Provider{}
 		if err := rows.Scan(
 			&p.ID, &p.Namespace, &p.Type, &p.Version, &p.Platform,
-			&p.Filename, &p.DownloadURL, &p.Shasum, &p.SigningKeys,
-			&p.S3Key, &p.SizeBytes, &p.Deprecated, &p.Blocked,
+			&p.Filename, &p.DownloadURL, &p.Shasum, &p.SigningKeys, &p.Protocols,
+			&p.S3Key, &p.SizeBytes, &p.Deprecated, &p.Blocked, &p.H1Hash, &p.Verified,
 			&p.CreatedAt, &p.UpdatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan provider: %w", err)
@@ -189,7 +364,7 @@ func (r *ProviderRepository) Update(ctx context.Context, p *Provider) error {
 		WHERE id = ?
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query, p.Deprecated, p.Blocked, p.SizeBytes, p.ID)
+	result, err := r.db.exec(ctx, "provider.update", query, p.Deprecated, p.Blocked, p.SizeBytes, p.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update provider: %w", err)
 	}
@@ -207,11 +382,59 @@ func (r *ProviderRepository) Update(ctx context.Context, p *Provider) error {
 	return nil
 }
 
+// SetVersionBlocked blocks or unblocks every platform of a provider version
+// in a single statement. It returns the number of platforms matched.
+func (r *ProviderRepository) SetVersionBlocked(ctx context.Context, namespace, typ, version string, blocked bool) (int64, error) {
+	query := `
+		UPDATE providers
+		SET blocked = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE namespace = ? AND type = ? AND version = ?
+	`
+
+	result, err := r.db.exec(ctx, "provider.set_version_blocked", query, blocked, namespace, typ, version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update provider version: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows, nil
+}
+
+// UpdateMetadata replaces the upstream registry metadata of a provider,
+// leaving its storage location and status flags untouched
+func (r *ProviderRepository) UpdateMetadata(ctx context.Context, p *Provider) error {
+	query := `
+		UPDATE providers
+		SET shasum = ?, download_url = ?, signing_keys = ?, protocols = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, "provider.update_metadata", query, p.Shasum, p.DownloadURL, p.SigningKeys, p.Protocols, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update provider metadata: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("provider not found")
+	}
+
+	p.UpdatedAt = time.Now()
+	return nil
+}
+
 // Delete deletes a provider
 func (r *ProviderRepository) Delete(ctx context.Context, id int64) error {
 	query := "DELETE FROM providers WHERE id = ?"
 
-	result, err := r.db.conn.ExecContext(ctx, query, id)
+	result, err := r.db.exec(ctx, "provider.delete", query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete provider: %w", err)
 	}
@@ -228,10 +451,28 @@ func (r *ProviderRepository) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
+// DeleteAllVersions removes every version and platform of a provider. It is
+// a single statement, so either all rows go or none do. It returns the number
+// of rows deleted.
+func (r *ProviderRepository) DeleteAllVersions(ctx context.Context, namespace, typ string) (int64, error) {
+	query := "DELETE FROM providers WHERE namespace = ? AND type = ?"
+
+	result, err := r.db.exec(ctx, "provider.delete_all_versions", query, namespace, typ)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete provider versions: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows, nil
+}
+
 // Count returns the total number of providers
 func (r *ProviderRepository) Count(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.db.conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM providers").Scan(&count)
+	err := r.db.queryRow(ctx, "provider.count", "SELECT COUNT(*) FROM providers").Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count providers: %w", err)
 	}
@@ -264,7 +505,7 @@ func (r *ProviderRepository) GetStorageStats(ctx context.Context) (*StorageStats
 	`
 
 	stats := &StorageStats{}
-	err := r.db.conn.QueryRowContext(ctx, query).Scan(
+	err := r.db.queryRow(ctx, "provider.get_storage_stats", query).Scan(
 		&stats.TotalProviders,
 		&stats.TotalSizeBytes,
 		&stats.UniqueNamespaces,
@@ -279,3 +520,79 @@ func (r *ProviderRepository) GetStorageStats(ctx context.Context) (*StorageStats
 
 	return stats, nil
 }
+
+// SetShasumsKey records the storage key of the SHA256SUMS document stored
+// for a provider version, replacing any earlier record
+func (r *ProviderRepository) SetShasumsKey(ctx context.Context, namespace, typ, version, key string) error {
+	query := `
+		INSERT OR REPLACE INTO provider_shasums (namespace, type, version, s3_key, stale, created_at)
+		VALUES (?, ?, ?, ?, 0, CURRENT_TIMESTAMP)
+	`
+
+	if _, err := r.db.exec(ctx, "provider.set_shasums_key", query, namespace, typ, version, key); err != nil {
+		return fmt.Errorf("failed to record SHA256SUMS key: %w", err)
+	}
+	return nil
+}
+
+// GetShasumsKey returns the storage key of the SHA256SUMS document stored
+// for a provider version, or "" when none is current. Adding, changing or
+// deleting any platform of the version marks the record stale.
+func (r *ProviderRepository) GetShasumsKey(ctx context.Context, namespace, typ, version string) (string, error) {
+	query := `SELECT s3_key FROM provider_shasums WHERE namespace = ? AND type = ? AND version = ? AND stale = 0`
+
+	var key string
+	err := r.db.queryRow(ctx, "provider.get_shasums_key", query, namespace, typ, version).Scan(&key)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get SHA256SUMS key: %w", err)
+	}
+	return key, nil
+}
+
+// ListShasumsKeys returns the storage keys of every recorded SHA256SUMS
+// document, including stale ones that have not been removed yet
+func (r *ProviderRepository) ListShasumsKeys(ctx context.Context) ([]string, error) {
+	return r.listShasumsKeys(ctx, "provider.list_shasums_keys", `SELECT s3_key FROM provider_shasums`)
+}
+
+// ListStaleShasumsKeys returns the storage keys of SHA256SUMS documents
+// whose version's platforms changed after they were stored
+func (r *ProviderRepository) ListStaleShasumsKeys(ctx context.Context) ([]string, error) {
+	return r.listShasumsKeys(ctx, "provider.list_stale_shasums_keys", `SELECT s3_key FROM provider_shasums WHERE stale = 1`)
+}
+
+// DeleteStaleShasumsKey drops a stale SHA256SUMS record, reporting whether
+// it did. A record stored again since it went stale is kept.
+func (r *ProviderRepository) DeleteStaleShasumsKey(ctx context.Context, key string) (bool, error) {
+	result, err := r.db.exec(ctx, "provider.delete_stale_shasums_key",
+		`DELETE FROM provider_shasums WHERE s3_key = ? AND stale = 1`, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete SHA256SUMS record: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func (r *ProviderRepository) listShasumsKeys(ctx context.Context, operation, query string) ([]string, error) {
+	rows, err := r.db.query(ctx, operation, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SHA256SUMS keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan SHA256SUMS key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
@@ -136,6 +136,54 @@ func TestJobRepository_CreateItem(t *testing.T) {
 	assert.NotZero(t, item.ID)
 }
 
+func TestJobRepository_CreateWithItems(t *testing.T) {
+	db := setupTestDB(t)
+	jobRepo := NewJobRepository(db)
+	ctx := context.Background()
+
+	newItems := func(versions ...string) []*DownloadJobItem {
+		var items []*DownloadJobItem
+		for _, version := range versions {
+			items = append(items, &DownloadJobItem{
+				Namespace: "hashicorp",
+				Type:      "aws",
+				Version:   version,
+				Platform:  "linux_amd64",
+				Status:    "pending",
+			})
+		}
+		return items
+	}
+
+	job := &DownloadJob{JobType: "provider", SourceType: "mirror_all", Status: "pending", TotalItems: 2}
+	items := newItems("5.0.0", "5.1.0")
+	require.NoError(t, jobRepo.CreateWithItems(ctx, job, items))
+	assert.NotZero(t, job.ID)
+	for _, item := range items {
+		assert.NotZero(t, item.ID)
+		assert.Equal(t, job.ID, item.JobID)
+	}
+	stored, err := jobRepo.GetItems(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Len(t, stored, 2)
+
+	// An item that cannot be created leaves neither the job nor its other items
+	_, err = db.conn.Exec(`CREATE TRIGGER reject_item BEFORE INSERT ON download_job_items
+		WHEN NEW.version = 'bad' BEGIN SELECT RAISE(ABORT, 'item rejected'); END`)
+	require.NoError(t, err)
+
+	failed := &DownloadJob{JobType: "provider", SourceType: "mirror_all", Status: "pending", TotalItems: 2}
+	err = jobRepo.CreateWithItems(ctx, failed, newItems("6.0.0", "bad"))
+	require.Error(t, err)
+	assert.Zero(t, failed.ID)
+
+	var jobs, jobItems int
+	require.NoError(t, db.conn.QueryRow("SELECT COUNT(*) FROM download_jobs").Scan(&jobs))
+	require.NoError(t, db.conn.QueryRow("SELECT COUNT(*) FROM download_job_items").Scan(&jobItems))
+	assert.Equal(t, 1, jobs)
+	assert.Equal(t, 2, jobItems)
+}
+
 func TestJobRepository_UpdateItem(t *testing.T) {
 	db := setupTestDB(t)
 	jobRepo := NewJobRepository(db)
@@ -363,6 +411,17 @@ func TestProviderRepository_GetStorageStats(t *testing.T) {
 	assert.Equal(t, int64(1024000+2048000+3072000), stats.TotalSizeBytes) // 1+2+3 MB
 }
 
+func TestModuleRepository_GetStorageStats_Empty(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewModuleRepository(db)
+
+	stats, err := repo.GetStorageStats(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, stats.TotalModules)
+	assert.Zero(t, stats.DeprecatedCount)
+	assert.Zero(t, stats.BlockedCount)
+}
+
 // User Repository Additional Tests
 
 func TestUserRepository_GetByID(t *testing.T) {
@@ -683,6 +742,47 @@ func TestAuditRepository_DeleteOlderThan(t *testing.T) {
 	_ = count
 }
 
+func TestAuditRepository_ListAfter(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewAuditRepository(db)
+	ctx := context.Background()
+
+	// Backdate entries so each falls on its own day
+	var ids []int64
+	for _, created := range []string{"2025-01-01 09:00:00", "2025-01-02 09:00:00", "2025-01-03 09:00:00", "2025-01-04 09:00:00"} {
+		action := &AdminAction{Action: "login", ResourceType: "session", Success: true}
+		require.NoError(t, repo.Log(ctx, action))
+		_, err := db.exec(ctx, "test.backdate", "UPDATE admin_actions SET created_at = ? WHERE id = ?", created, action.ID)
+		require.NoError(t, err)
+		ids = append(ids, action.ID)
+	}
+
+	idsOf := func(actions []*AdminAction) []int64 {
+		var out []int64
+		for _, a := range actions {
+			out = append(out, a.ID)
+		}
+		return out
+	}
+
+	// Batches continue from the last ID seen
+	first, err := repo.ListAfter(ctx, AuditFilter{}, 0, 3)
+	require.NoError(t, err)
+	assert.Equal(t, ids[:3], idsOf(first))
+	rest, err := repo.ListAfter(ctx, AuditFilter{}, first[2].ID, 3)
+	require.NoError(t, err)
+	assert.Equal(t, ids[3:], idsOf(rest))
+
+	// From is inclusive and To is exclusive
+	filter := AuditFilter{
+		From: time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC),
+		To:   time.Date(2025, 1, 4, 9, 0, 0, 0, time.UTC),
+	}
+	inRange, err := repo.ListAfter(ctx, filter, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, ids[1:3], idsOf(inRange))
+}
+
 // Database Tests
 
 func TestDatabase_Backup(t *testing.T) {
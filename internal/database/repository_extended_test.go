@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"testing"
 	"time"
 
@@ -370,3 +371,275 @@ func TestAuditRepository_List(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, actions, 2)
 }
+
+func TestSessionRepository_CountActive(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewSessionRepository(db)
+	ctx := context.Background()
+
+	user := createTestUser(t, db, "testuser")
+
+	for _, s := range []*AdminSession{
+		{UserID: user.ID, TokenJTI: "active-jti", ExpiresAt: time.Now().Add(time.Hour)},
+		{UserID: user.ID, TokenJTI: "expired-jti", ExpiresAt: time.Now().Add(-time.Hour)},
+		{UserID: user.ID, TokenJTI: "revoked-jti", ExpiresAt: time.Now().Add(time.Hour)},
+	} {
+		require.NoError(t, repo.Create(ctx, s))
+	}
+	require.NoError(t, repo.RevokeByTokenJTI(ctx, "revoked-jti"))
+
+	count, err := repo.CountActive(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestRepository_ListNames(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	providerRepo := NewProviderRepository(db)
+	for _, p := range []*Provider{
+		{Namespace: "hashicorp", Type: "random", Version: "3.0.0", Platform: "linux_amd64"},
+		{Namespace: "hashicorp", Type: "random", Version: "3.1.0", Platform: "linux_amd64"},
+		{Namespace: "hashicorp", Type: "aws", Version: "5.0.0", Platform: "linux_amd64"},
+	} {
+		p.Filename = p.Type + "_" + p.Version + "_" + p.Platform + ".zip"
+		p.S3Key = "providers/" + p.Filename
+		require.NoError(t, providerRepo.Create(ctx, p))
+	}
+
+	providerNames, err := providerRepo.ListNames(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []ProviderName{
+		{Namespace: "hashicorp", Type: "aws"},
+		{Namespace: "hashicorp", Type: "random"},
+	}, providerNames)
+
+	moduleRepo := NewModuleRepository(db)
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		require.NoError(t, moduleRepo.Create(ctx, &Module{
+			Namespace: "terraform-aws-modules",
+			Name:      "vpc",
+			System:    "aws",
+			Version:   version,
+			S3Key:     "modules/vpc-" + version + ".tar.gz",
+			Filename:  "vpc-" + version + ".tar.gz",
+		}))
+	}
+
+	moduleNames, err := moduleRepo.ListNames(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []ModuleName{{Namespace: "terraform-aws-modules", Name: "vpc", System: "aws"}}, moduleNames)
+}
+
+func TestModuleRepository_ListFiltered(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewModuleRepository(db)
+	ctx := context.Background()
+
+	// Interleave two modules so a filtered page spans unfiltered pages
+	for i, version := range []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0"} {
+		for _, name := range []string{"vpc", "eks"} {
+			require.NoError(t, repo.Create(ctx, &Module{
+				Namespace: "terraform-aws-modules",
+				Name:      name,
+				System:    "aws",
+				Version:   version,
+				S3Key:     fmt.Sprintf("modules/%s-%d.tar.gz", name, i),
+				Filename:  fmt.Sprintf("%s-%d.tar.gz", name, i),
+			}))
+		}
+	}
+
+	filter := ModuleFilter{Namespace: "terraform-aws-modules", Name: "vpc"}
+
+	total, err := repo.Count(ctx, filter)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+
+	page, err := repo.List(ctx, filter, 3, 0)
+	require.NoError(t, err)
+	require.Len(t, page, 3)
+	for _, m := range page {
+		assert.Equal(t, "vpc", m.Name)
+	}
+
+	page, err = repo.List(ctx, filter, 3, 3)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "vpc", page[0].Name)
+
+	none, err := repo.Count(ctx, ModuleFilter{System: "azurerm"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), none)
+
+	all, err := repo.Count(ctx, ModuleFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), all)
+}
+
+func TestModuleRepository_DeleteAllVersions(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewModuleRepository(db)
+	ctx := context.Background()
+
+	for _, name := range []string{"vpc", "eks"} {
+		for _, version := range []string{"1.0.0", "1.1.0"} {
+			require.NoError(t, repo.Create(ctx, &Module{
+				Namespace: "terraform-aws-modules",
+				Name:      name,
+				System:    "aws",
+				Version:   version,
+				S3Key:     fmt.Sprintf("modules/%s-%s.tar.gz", name, version),
+				Filename:  fmt.Sprintf("%s-%s.tar.gz", name, version),
+			}))
+		}
+	}
+
+	deleted, err := repo.DeleteAllVersions(ctx, "terraform-aws-modules", "vpc", "aws")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	vpc, err := repo.ListVersions(ctx, "terraform-aws-modules", "vpc", "aws")
+	require.NoError(t, err)
+	assert.Empty(t, vpc)
+	eks, err := repo.ListVersions(ctx, "terraform-aws-modules", "eks", "aws")
+	require.NoError(t, err)
+	assert.Len(t, eks, 2)
+
+	deleted, err = repo.DeleteAllVersions(ctx, "terraform-aws-modules", "vpc", "aws")
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestModuleRepository_CountByS3Key(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewModuleRepository(db)
+	ctx := context.Background()
+
+	// Two versions with identical content share one content-addressed archive
+	blobKey := "blobs/sha256/abc123"
+	for _, version := range []string{"1.0.0", "1.0.1"} {
+		require.NoError(t, repo.Create(ctx, &Module{
+			Namespace:   "terraform-aws-modules",
+			Name:        "vpc",
+			System:      "aws",
+			Version:     version,
+			S3Key:       blobKey,
+			Filename:    "vpc-" + version + ".tar.gz",
+			ContentHash: sql.NullString{String: "abc123", Valid: true},
+		}))
+	}
+
+	count, err := repo.CountByS3Key(ctx, blobKey)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	m, err := repo.GetByIdentity(ctx, "terraform-aws-modules", "vpc", "aws", "1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", m.ContentHash.String)
+	require.NoError(t, repo.Delete(ctx, m.ID))
+
+	count, err = repo.CountByS3Key(ctx, blobKey)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	count, err = repo.CountByS3Key(ctx, "blobs/sha256/unknown")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestModuleRepository_UpdateArchive(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewModuleRepository(db)
+	ctx := context.Background()
+
+	m := &Module{
+		Namespace: "terraform-aws-modules",
+		Name:      "vpc",
+		System:    "aws",
+		Version:   "1.0.0",
+		S3Key:     "modules/vpc-1.0.0.tar.gz",
+		Filename:  "vpc-1.0.0.tar.gz",
+		SizeBytes: 100,
+	}
+	require.NoError(t, repo.Create(ctx, m))
+
+	m.S3Key = "blobs/sha256/abc123"
+	m.SizeBytes = 120
+	m.ContentHash = sql.NullString{String: "abc123", Valid: true}
+	require.NoError(t, repo.UpdateArchive(ctx, m))
+
+	got, err := repo.GetByID(ctx, m.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "blobs/sha256/abc123", got.S3Key)
+	assert.Equal(t, int64(120), got.SizeBytes)
+	assert.Equal(t, "abc123", got.ContentHash.String)
+	assert.Equal(t, "vpc-1.0.0.tar.gz", got.Filename)
+
+	assert.Error(t, repo.UpdateArchive(ctx, &Module{ID: 9999}))
+}
+
+func TestJobRepository_ListFailedDownloads(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewJobRepository(db)
+	ctx := context.Background()
+
+	// addItems creates a job with one item per platform in the given status
+	addItems := func(version, status, errMsg string, platforms ...string) int64 {
+		job := &DownloadJob{SourceType: "hcl", Status: "completed", TotalItems: len(platforms), CreatedAt: time.Now()}
+		require.NoError(t, repo.Create(ctx, job))
+		for _, platform := range platforms {
+			item := &DownloadJobItem{
+				JobID:     job.ID,
+				Namespace: "hashicorp",
+				Type:      "aws",
+				Version:   version,
+				Platform:  platform,
+				Status:    "pending",
+			}
+			require.NoError(t, repo.CreateItem(ctx, item))
+			item.Status = status
+			if errMsg != "" {
+				item.ErrorMessage = sql.NullString{String: errMsg, Valid: true}
+			}
+			item.CompletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			require.NoError(t, repo.UpdateItem(ctx, item))
+		}
+		return job.ID
+	}
+
+	addItems("5.0.0", "failed", "checksum mismatch", "linux_amd64", "darwin_arm64")
+	addItems("5.0.0", "failed", "timeout", "linux_amd64")
+	lastJob := addItems("5.0.0", "failed", "404 not found", "linux_amd64")
+	addItems("5.0.0", "completed", "", "windows_amd64")
+	addItems("5.1.0", "failed", "timeout", "linux_amd64")
+
+	// 5.1.0 has since been mirrored, so its failure is resolved
+	require.NoError(t, NewProviderRepository(db).Create(ctx, &Provider{
+		Namespace: "hashicorp", Type: "aws", Version: "5.1.0", Platform: "linux_amd64",
+		Filename: "terraform-provider-aws_5.1.0_linux_amd64.zip", S3Key: "providers/aws-5.1.0.zip",
+	}))
+
+	failures, err := repo.ListFailedDownloads(ctx, 1, 50)
+	require.NoError(t, err)
+	require.Len(t, failures, 2)
+
+	assert.Equal(t, "5.0.0", failures[0].Version)
+	assert.Equal(t, "linux_amd64", failures[0].Platform)
+	assert.Equal(t, int64(3), failures[0].FailureCount)
+	assert.Equal(t, int64(3), failures[0].JobCount)
+	assert.Equal(t, lastJob, failures[0].LastJobID)
+	assert.Equal(t, "404 not found", failures[0].LastError.String)
+	assert.True(t, failures[0].LastFailedAt.Valid)
+
+	assert.Equal(t, "darwin_arm64", failures[1].Platform)
+	assert.Equal(t, int64(1), failures[1].FailureCount)
+
+	t.Run("minimum failures", func(t *testing.T) {
+		failures, err := repo.ListFailedDownloads(ctx, 2, 50)
+		require.NoError(t, err)
+		require.Len(t, failures, 1)
+		assert.Equal(t, "linux_amd64", failures[0].Platform)
+	})
+}
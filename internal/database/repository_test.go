@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"path/filepath"
 	"testing"
 
@@ -113,6 +114,113 @@ func TestProviderRepository_GetByIdentity(t *testing.T) {
 	assert.Nil(t, notFound)
 }
 
+// seedProviders creates count providers across several versions and platforms
+// and returns their identities
+func seedProviders(tb testing.TB, repo *ProviderRepository, count int) []Identity {
+	tb.Helper()
+	ctx := context.Background()
+	platforms := []string{"linux_amd64", "linux_arm64", "darwin_amd64", "darwin_arm64", "windows_amd64"}
+
+	identities := make([]Identity, 0, count)
+	for i := 0; i < count; i++ {
+		id := Identity{
+			Namespace: "hashicorp",
+			Type:      fmt.Sprintf("type%d", i/50),
+			Version:   fmt.Sprintf("1.%d.0", i/len(platforms)),
+			Platform:  platforms[i%len(platforms)],
+		}
+		p := &Provider{
+			Namespace: id.Namespace,
+			Type:      id.Type,
+			Version:   id.Version,
+			Platform:  id.Platform,
+			Filename:  fmt.Sprintf("terraform-provider-%s_%s_%s.zip", id.Type, id.Version, id.Platform),
+			S3Key:     fmt.Sprintf("providers/%s/%s/%s/%s.zip", id.Namespace, id.Type, id.Version, id.Platform),
+		}
+		if err := repo.Create(ctx, p); err != nil {
+			tb.Fatalf("failed to seed provider: %v", err)
+		}
+		identities = append(identities, id)
+	}
+	return identities
+}
+
+func TestProviderRepository_ExistingIdentities(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewProviderRepository(db)
+	ctx := context.Background()
+
+	// Seed more providers than fit in a single batch
+	stored := seedProviders(t, repo, 2*identityBatchSize+37)
+
+	// Query every other stored identity plus a set of missing ones
+	var query []Identity
+	want := make(map[Identity]bool)
+	for i, id := range stored {
+		if i%2 == 0 {
+			query = append(query, id)
+			want[id] = true
+		}
+	}
+	for i := 0; i < 100; i++ {
+		query = append(query, Identity{
+			Namespace: "hashicorp",
+			Type:      "missing",
+			Version:   fmt.Sprintf("9.%d.0", i),
+			Platform:  "linux_amd64",
+		})
+	}
+
+	existing, err := repo.ExistingIdentities(ctx, query)
+	require.NoError(t, err)
+	assert.Len(t, existing, len(want))
+
+	for id := range want {
+		p, ok := existing[id]
+		require.True(t, ok, "expected %v to be found", id)
+		assert.Equal(t, id.Namespace, p.Namespace)
+		assert.Equal(t, id.Type, p.Type)
+		assert.Equal(t, id.Version, p.Version)
+		assert.Equal(t, id.Platform, p.Platform)
+		assert.Greater(t, p.ID, int64(0))
+	}
+
+	// Empty input returns an empty map
+	existing, err = repo.ExistingIdentities(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, existing)
+}
+
+func BenchmarkProviderRepository_ExistingIdentities(b *testing.B) {
+	db, err := New(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewProviderRepository(db)
+	identities := seedProviders(b, repo, 1000)
+	ctx := context.Background()
+
+	b.Run("Batched", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := repo.ExistingIdentities(ctx, identities); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("PerItem", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, id := range identities {
+				if _, err := repo.GetByIdentity(ctx, id.Namespace, id.Type, id.Version, id.Platform); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
 func TestProviderRepository_Update(t *testing.T) {
 	db := setupTestDB(t)
 	repo := NewProviderRepository(db)
@@ -178,6 +286,189 @@ func TestProviderRepository_Delete(t *testing.T) {
 	assert.Nil(t, found)
 }
 
+func TestProviderRepository_DeleteAllVersions(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewProviderRepository(db)
+	ctx := context.Background()
+
+	for _, p := range []*Provider{
+		{Namespace: "hashicorp", Type: "aws", Version: "5.0.0", Platform: "linux_amd64"},
+		{Namespace: "hashicorp", Type: "aws", Version: "5.0.0", Platform: "darwin_arm64"},
+		{Namespace: "hashicorp", Type: "aws", Version: "5.1.0", Platform: "linux_amd64"},
+		{Namespace: "hashicorp", Type: "azurerm", Version: "3.0.0", Platform: "linux_amd64"},
+	} {
+		p.Filename = "terraform-provider-" + p.Type + "_" + p.Version + "_" + p.Platform + ".zip"
+		p.S3Key = "providers/" + p.Namespace + "/" + p.Type + "/" + p.Version + "/" + p.Filename
+		require.NoError(t, repo.Create(ctx, p))
+	}
+
+	deleted, err := repo.DeleteAllVersions(ctx, "hashicorp", "aws")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+
+	remaining, err := repo.ListVersions(ctx, "hashicorp", "aws")
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+	other, err := repo.ListVersions(ctx, "hashicorp", "azurerm")
+	require.NoError(t, err)
+	assert.Len(t, other, 1)
+
+	deleted, err = repo.DeleteAllVersions(ctx, "hashicorp", "aws")
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestProviderRepository_SetVersionBlocked(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewProviderRepository(db)
+	ctx := context.Background()
+
+	for _, p := range []*Provider{
+		{Namespace: "hashicorp", Type: "aws", Version: "5.0.0", Platform: "linux_amd64"},
+		{Namespace: "hashicorp", Type: "aws", Version: "5.0.0", Platform: "darwin_arm64"},
+		{Namespace: "hashicorp", Type: "aws", Version: "5.1.0", Platform: "linux_amd64"},
+	} {
+		p.Filename = "terraform-provider-" + p.Type + "_" + p.Version + "_" + p.Platform + ".zip"
+		p.S3Key = "providers/" + p.Namespace + "/" + p.Type + "/" + p.Version + "/" + p.Filename
+		require.NoError(t, repo.Create(ctx, p))
+	}
+
+	blocked := func() map[string]bool {
+		providers, err := repo.ListVersions(ctx, "hashicorp", "aws")
+		require.NoError(t, err)
+		result := make(map[string]bool)
+		for _, p := range providers {
+			result[p.Version+"/"+p.Platform] = p.Blocked
+		}
+		return result
+	}
+
+	matched, err := repo.SetVersionBlocked(ctx, "hashicorp", "aws", "5.0.0", true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), matched)
+	assert.Equal(t, map[string]bool{
+		"5.0.0/linux_amd64":  true,
+		"5.0.0/darwin_arm64": true,
+		"5.1.0/linux_amd64":  false,
+	}, blocked())
+
+	matched, err = repo.SetVersionBlocked(ctx, "hashicorp", "aws", "5.0.0", false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), matched)
+	assert.False(t, blocked()["5.0.0/linux_amd64"])
+
+	matched, err = repo.SetVersionBlocked(ctx, "hashicorp", "aws", "9.9.9", true)
+	require.NoError(t, err)
+	assert.Zero(t, matched)
+}
+
+func TestProviderRepository_ListUnverified(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewProviderRepository(db)
+	ctx := context.Background()
+
+	for _, p := range []*Provider{
+		{Namespace: "hashicorp", Type: "aws", Version: "5.0.0", Platform: "linux_amd64", Verified: true},
+		{Namespace: "hashicorp", Type: "aws", Version: "5.0.0", Platform: "darwin_arm64"},
+		{Namespace: "acme", Type: "internal", Version: "1.0.0", Platform: "linux_amd64"},
+	} {
+		p.Filename = "terraform-provider-" + p.Type + "_" + p.Version + "_" + p.Platform + ".zip"
+		p.S3Key = "providers/" + p.Namespace + "/" + p.Type + "/" + p.Version + "/" + p.Filename
+		require.NoError(t, repo.Create(ctx, p))
+	}
+
+	verified, err := repo.GetByIdentity(ctx, "hashicorp", "aws", "5.0.0", "linux_amd64")
+	require.NoError(t, err)
+	assert.True(t, verified.Verified)
+
+	unverified, err := repo.ListUnverified(ctx)
+	require.NoError(t, err)
+	require.Len(t, unverified, 2)
+	assert.Equal(t, "acme", unverified[0].Namespace)
+	assert.Equal(t, "darwin_arm64", unverified[1].Platform)
+	for _, p := range unverified {
+		assert.False(t, p.Verified)
+	}
+}
+
+func TestProviderRepository_ShasumsKey(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewProviderRepository(db)
+	ctx := context.Background()
+
+	create := func(platform string) *Provider {
+		p := &Provider{
+			Namespace: "hashicorp",
+			Type:      "aws",
+			Version:   "5.0.0",
+			Platform:  platform,
+			Filename:  "terraform-provider-aws_5.0.0_" + platform + ".zip",
+			Shasum:    "abc123",
+			S3Key:     "providers/hashicorp/aws/5.0.0/" + platform + ".zip",
+		}
+		require.NoError(t, repo.Create(ctx, p))
+		return p
+	}
+	key := "providers/hashicorp/aws/5.0.0/terraform-provider-aws_5.0.0_SHA256SUMS"
+	current := func() string {
+		got, err := repo.GetShasumsKey(ctx, "hashicorp", "aws", "5.0.0")
+		require.NoError(t, err)
+		return got
+	}
+
+	linux := create("linux_amd64")
+	assert.Empty(t, current())
+
+	require.NoError(t, repo.SetShasumsKey(ctx, "hashicorp", "aws", "5.0.0", key))
+	assert.Equal(t, key, current())
+	keys, err := repo.ListShasumsKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{key}, keys)
+
+	// Flag changes leave the document current
+	linux.Blocked = true
+	require.NoError(t, repo.Update(ctx, linux))
+	assert.Equal(t, key, current())
+
+	// Adding, changing or deleting a platform makes it stale
+	darwin := create("darwin_arm64")
+	assert.Empty(t, current())
+
+	require.NoError(t, repo.SetShasumsKey(ctx, "hashicorp", "aws", "5.0.0", key))
+	darwin.Shasum = "def456"
+	require.NoError(t, repo.UpdateMetadata(ctx, darwin))
+	assert.Empty(t, current())
+
+	require.NoError(t, repo.SetShasumsKey(ctx, "hashicorp", "aws", "5.0.0", key))
+	require.NoError(t, repo.UpdateMetadata(ctx, darwin))
+	assert.Equal(t, key, current(), "rewriting the same shasum keeps the document current")
+
+	require.NoError(t, repo.Delete(ctx, darwin.ID))
+	assert.Empty(t, current())
+
+	// Stale records stay listed until they are dropped
+	keys, err = repo.ListShasumsKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{key}, keys)
+	stale, err := repo.ListStaleShasumsKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{key}, stale)
+
+	dropped, err := repo.DeleteStaleShasumsKey(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, dropped)
+	keys, err = repo.ListShasumsKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	// A record stored again after going stale is kept
+	require.NoError(t, repo.SetShasumsKey(ctx, "hashicorp", "aws", "5.0.0", key))
+	dropped, err = repo.DeleteStaleShasumsKey(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, dropped)
+	assert.Equal(t, key, current())
+}
+
 func TestProviderRepository_Count(t *testing.T) {
 	db := setupTestDB(t)
 	repo := NewProviderRepository(db)
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SearchResult is one provider or module matching a search. Versions and
+// platforms are folded together, so each artifact appears once.
+type SearchResult struct {
+	Kind      string // "provider" or "module"
+	Namespace string
+	Name      string // Provider type or module name
+	System    string // Module system; empty for providers
+	Versions  int64
+}
+
+// SearchRepository searches providers and modules together
+type SearchRepository struct {
+	db *DB
+}
+
+// NewSearchRepository creates a new search repository
+func NewSearchRepository(db *DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+// searchQuery ranks exact name matches first, then names or namespaces that
+// start with the query, then any other substring match. Each rank is
+// ordered by address so results are stable across pages.
+const searchQuery = `
+	SELECT kind, namespace, name, system, versions FROM (
+		SELECT 'provider' AS kind, namespace, type AS name, '' AS system,
+			   COUNT(DISTINCT version) AS versions,
+			   CASE
+				   WHEN lower(type) = ? OR lower(namespace || '/' || type) = ? THEN 0
+				   WHEN type LIKE ? ESCAPE '\' OR namespace LIKE ? ESCAPE '\' THEN 1
+				   ELSE 2
+			   END AS match_rank
+		FROM providers
+		WHERE namespace LIKE ? ESCAPE '\' OR type LIKE ? ESCAPE '\'
+		   OR namespace || '/' || type LIKE ? ESCAPE '\'
+		GROUP BY namespace, type
+
+		UNION ALL
+
+		SELECT 'module' AS kind, namespace, name, system,
+			   COUNT(DISTINCT version) AS versions,
+			   CASE
+				   WHEN lower(name) = ? OR lower(namespace || '/' || name || '/' || system) = ? THEN 0
+				   WHEN name LIKE ? ESCAPE '\' OR namespace LIKE ? ESCAPE '\' THEN 1
+				   ELSE 2
+			   END AS match_rank
+		FROM modules
+		WHERE namespace LIKE ? ESCAPE '\' OR name LIKE ? ESCAPE '\'
+		   OR namespace || '/' || name || '/' || system LIKE ? ESCAPE '\'
+		GROUP BY namespace, name, system
+	)
+	ORDER BY match_rank, namespace, name, system, kind DESC
+	LIMIT ?
+`
+
+// escapeLike escapes LIKE wildcards so the query matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Search finds providers whose namespace or type, and modules whose
+// namespace or name, contain q (case-insensitively), best matches first
+func (r *SearchRepository) Search(ctx context.Context, q string, limit int) ([]SearchResult, error) {
+	exact := strings.ToLower(q)
+	prefix := escapeLike(q) + "%"
+	contains := "%" + escapeLike(q) + "%"
+
+	rows, err := r.db.query(ctx, "search.search", searchQuery,
+		exact, exact, prefix, prefix, contains, contains, contains,
+		exact, exact, prefix, prefix, contains, contains, contains,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var result SearchResult
+		if err := rows.Scan(&result.Kind, &result.Namespace, &result.Name, &result.System, &result.Versions); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchRepository_Search(t *testing.T) {
+	db := setupTestDB(t)
+	providerRepo := NewProviderRepository(db)
+	moduleRepo := NewModuleRepository(db)
+	repo := NewSearchRepository(db)
+	ctx := context.Background()
+
+	for _, p := range []struct{ namespace, typ, version, platform string }{
+		{"hashicorp", "aws", "5.0.0", "linux_amd64"},
+		{"hashicorp", "aws", "5.0.0", "darwin_arm64"},
+		{"hashicorp", "aws", "5.1.0", "linux_amd64"},
+		{"hashicorp", "awscc", "1.0.0", "linux_amd64"},
+		{"acme", "my_aws", "1.0.0", "linux_amd64"},
+		{"hashicorp", "random", "3.5.0", "linux_amd64"},
+	} {
+		require.NoError(t, providerRepo.Create(ctx, &Provider{
+			Namespace: p.namespace,
+			Type:      p.typ,
+			Version:   p.version,
+			Platform:  p.platform,
+			Filename:  "terraform-provider-" + p.typ + ".zip",
+			S3Key:     "providers/" + p.namespace + "/" + p.typ + "/" + p.version + "/" + p.platform + ".zip",
+		}))
+	}
+	require.NoError(t, moduleRepo.Create(ctx, &Module{
+		Namespace: "terraform-aws-modules",
+		Name:      "vpc",
+		System:    "aws",
+		Version:   "5.0.0",
+		S3Key:     "modules/terraform-aws-modules/vpc/aws/5.0.0.tar.gz",
+		Filename:  "5.0.0.tar.gz",
+	}))
+
+	results, err := repo.Search(ctx, "AWS", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	// Exact type match first, versions folded together, then prefix, then substrings
+	assert.Equal(t, SearchResult{Kind: "provider", Namespace: "hashicorp", Name: "aws", Versions: 2}, results[0])
+	assert.Equal(t, "awscc", results[1].Name)
+	assert.Equal(t, "my_aws", results[2].Name)
+	assert.Equal(t, SearchResult{Kind: "module", Namespace: "terraform-aws-modules", Name: "vpc", System: "aws", Versions: 1}, results[3])
+
+	results, err = repo.Search(ctx, "aws", 2)
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	// LIKE wildcards in the query match literally
+	results, err = repo.Search(ctx, "_", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "my_aws", results[0].Name)
+}
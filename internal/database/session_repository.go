@@ -24,7 +24,7 @@ func (r *SessionRepository) Create(ctx context.Context, session *AdminSession) e
 		VALUES (?, ?, ?, ?, ?)
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query,
+	result, err := r.db.exec(ctx, "session.create", query,
 		session.UserID,
 		session.TokenJTI,
 		session.IPAddress,
@@ -54,7 +54,7 @@ func (r *SessionRepository) GetByTokenJTI(ctx context.Context, jti string) (*Adm
 	`
 
 	var session AdminSession
-	err := r.db.conn.QueryRowContext(ctx, query, jti).Scan(
+	err := r.db.queryRow(ctx, "session.get_by_token_jti", query, jti).Scan(
 		&session.ID,
 		&session.UserID,
 		&session.TokenJTI,
@@ -83,7 +83,7 @@ func (r *SessionRepository) GetByID(ctx context.Context, id int64) (*AdminSessio
 	`
 
 	var session AdminSession
-	err := r.db.conn.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.queryRow(ctx, "session.get_by_id", query, id).Scan(
 		&session.ID,
 		&session.UserID,
 		&session.TokenJTI,
@@ -112,7 +112,7 @@ func (r *SessionRepository) ListByUserID(ctx context.Context, userID int64) ([]*
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.conn.QueryContext(ctx, query, userID)
+	rows, err := r.db.query(ctx, "session.list_by_user_id", query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list sessions: %w", err)
 	}
@@ -143,7 +143,7 @@ func (r *SessionRepository) ListByUserID(ctx context.Context, userID int64) ([]*
 func (r *SessionRepository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM admin_sessions WHERE id = ?`
 
-	result, err := r.db.conn.ExecContext(ctx, query, id)
+	result, err := r.db.exec(ctx, "session.delete", query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
@@ -164,7 +164,7 @@ func (r *SessionRepository) Delete(ctx context.Context, id int64) error {
 func (r *SessionRepository) DeleteByTokenJTI(ctx context.Context, jti string) error {
 	query := `DELETE FROM admin_sessions WHERE token_jti = ?`
 
-	result, err := r.db.conn.ExecContext(ctx, query, jti)
+	result, err := r.db.exec(ctx, "session.delete_by_token_jti", query, jti)
 	if err != nil {
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
@@ -185,7 +185,7 @@ func (r *SessionRepository) DeleteByTokenJTI(ctx context.Context, jti string) er
 func (r *SessionRepository) RevokeByTokenJTI(ctx context.Context, jti string) error {
 	query := `UPDATE admin_sessions SET revoked = 1 WHERE token_jti = ?`
 
-	result, err := r.db.conn.ExecContext(ctx, query, jti)
+	result, err := r.db.exec(ctx, "session.revoke_by_token_jti", query, jti)
 	if err != nil {
 		return fmt.Errorf("failed to revoke session: %w", err)
 	}
@@ -206,7 +206,7 @@ func (r *SessionRepository) RevokeByTokenJTI(ctx context.Context, jti string) er
 func (r *SessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
 	query := `DELETE FROM admin_sessions WHERE expires_at < ?`
 
-	result, err := r.db.conn.ExecContext(ctx, query, time.Now())
+	result, err := r.db.exec(ctx, "session.delete_expired", query, time.Now())
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
 	}
@@ -223,10 +223,22 @@ func (r *SessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
 func (r *SessionRepository) DeleteByUserID(ctx context.Context, userID int64) error {
 	query := `DELETE FROM admin_sessions WHERE user_id = ?`
 
-	_, err := r.db.conn.ExecContext(ctx, query, userID)
+	_, err := r.db.exec(ctx, "session.delete_by_user_id", query, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete user sessions: %w", err)
 	}
 
 	return nil
 }
+
+// CountActive returns the number of sessions that are neither revoked nor expired
+func (r *SessionRepository) CountActive(ctx context.Context) (int, error) {
+	query := `SELECT COUNT(*) FROM admin_sessions WHERE revoked = 0 AND expires_at > ?`
+
+	var count int
+	if err := r.db.queryRow(ctx, "session.count_active", query, time.Now()).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count active sessions: %w", err)
+	}
+
+	return count, nil
+}
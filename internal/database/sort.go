@@ -0,0 +1,63 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Sort fields accepted by each list query. Field names are placed into SQL
+// directly, so only fields from these allowlists may be used. Versions are
+// left out because SQLite would order them as text, putting 1.10.0 before 1.9.0.
+var (
+	ProviderSortFields = []string{"created_at", "size_bytes", "namespace"}
+	ModuleSortFields   = []string{"created_at", "size_bytes", "namespace"}
+	JobSortFields      = []string{"created_at", "completed_at", "status"}
+)
+
+// Sort orders a list query by a single column
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// newestFirst is the default order of every list query
+var newestFirst = Sort{Field: "created_at", Desc: true}
+
+// ParseSort builds a Sort from the sort and order query parameters. An empty
+// field keeps the default newest-first order, and an empty order sorts
+// ascending, except created_at which sorts newest first.
+func ParseSort(field, order string, allowed []string) (Sort, error) {
+	if field == "" {
+		field = newestFirst.Field
+	}
+	if !slices.Contains(allowed, field) {
+		return Sort{}, fmt.Errorf("invalid sort field %q, expected one of: %s", field, strings.Join(allowed, ", "))
+	}
+
+	s := Sort{Field: field}
+	switch strings.ToLower(order) {
+	case "":
+		s.Desc = field == newestFirst.Field
+	case "asc":
+	case "desc":
+		s.Desc = true
+	default:
+		return Sort{}, fmt.Errorf("invalid sort order %q, expected asc or desc", order)
+	}
+	return s, nil
+}
+
+// orderBy returns the ORDER BY clause for the sort, falling back to newest
+// first if the field is not allowed. Rows are tied by ID so that pages stay
+// stable.
+func (s Sort) orderBy(allowed []string) string {
+	if !slices.Contains(allowed, s.Field) {
+		s = newestFirst
+	}
+	direction := "ASC"
+	if s.Desc {
+		direction = "DESC"
+	}
+	return fmt.Sprintf(" ORDER BY %s %s, id %s", s.Field, direction, direction)
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		field, order string
+		want         Sort
+		wantErr      string
+	}{
+		{"", "", Sort{Field: "created_at", Desc: true}, ""},
+		{"created_at", "asc", Sort{Field: "created_at"}, ""},
+		{"size_bytes", "", Sort{Field: "size_bytes"}, ""},
+		{"size_bytes", "DESC", Sort{Field: "size_bytes", Desc: true}, ""},
+		{"id; DROP TABLE providers", "", Sort{}, "invalid sort field"},
+		{"namespace", "sideways", Sort{}, "invalid sort order"},
+		{"version", "", Sort{}, "invalid sort field"},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSort(tt.field, tt.order, ProviderSortFields)
+		if tt.wantErr != "" {
+			require.Error(t, err, "sort=%q order=%q", tt.field, tt.order)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			continue
+		}
+		require.NoError(t, err, "sort=%q order=%q", tt.field, tt.order)
+		assert.Equal(t, tt.want, got)
+	}
+
+	_, err := ParseSort("size_bytes", "", JobSortFields)
+	assert.Error(t, err, "jobs have no size")
+}
+
+func TestSort_OrderByRejectsUnknownFields(t *testing.T) {
+	assert.Equal(t, " ORDER BY size_bytes DESC, id DESC", Sort{Field: "size_bytes", Desc: true}.orderBy(ProviderSortFields))
+	assert.Equal(t, " ORDER BY created_at DESC, id DESC", Sort{Field: "1; DROP TABLE providers"}.orderBy(ProviderSortFields))
+}
+
+func TestProviderRepository_ListSorted(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewProviderRepository(db)
+	ctx := context.Background()
+
+	for _, p := range []struct {
+		namespace string
+		size      int64
+	}{{"small", 100}, {"large", 3000}, {"medium", 2000}} {
+		require.NoError(t, repo.Create(ctx, &Provider{
+			Namespace: p.namespace,
+			Type:      "example",
+			Version:   "1.0.0",
+			Platform:  "linux_amd64",
+			Filename:  "terraform-provider-example_1.0.0_linux_amd64.zip",
+			Shasum:    "abc123",
+			S3Key:     "providers/" + p.namespace + "/example/1.0.0/linux_amd64.zip",
+			SizeBytes: p.size,
+		}))
+	}
+
+	namespaces := func(providers []*Provider) []string {
+		var names []string
+		for _, p := range providers {
+			names = append(names, p.Namespace)
+		}
+		return names
+	}
+
+	providers, err := repo.ListSorted(ctx, Sort{Field: "size_bytes", Desc: true}, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"large", "medium", "small"}, namespaces(providers))
+
+	providers, err = repo.ListSorted(ctx, Sort{Field: "namespace"}, 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"medium", "small"}, namespaces(providers))
+}
+
+func TestModuleRepository_ListSorted(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewModuleRepository(db)
+	ctx := context.Background()
+
+	for _, m := range []struct {
+		version string
+		size    int64
+	}{{"1.0.0", 500}, {"2.0.0", 1500}, {"3.0.0", 1000}} {
+		require.NoError(t, repo.Create(ctx, &Module{
+			Namespace: "hashicorp",
+			Name:      "consul",
+			System:    "aws",
+			Version:   m.version,
+			S3Key:     "modules/hashicorp/consul/aws/" + m.version + ".tar.gz",
+			Filename:  m.version + ".tar.gz",
+			SizeBytes: m.size,
+		}))
+	}
+
+	modules, err := repo.ListSorted(ctx, ModuleFilter{}, Sort{Field: "size_bytes", Desc: true}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, modules, 3)
+	assert.Equal(t, "2.0.0", modules[0].Version)
+	assert.Equal(t, "3.0.0", modules[1].Version)
+	assert.Equal(t, "1.0.0", modules[2].Version)
+}
+
+func TestJobRepository_ListSorted(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewJobRepository(db)
+	ctx := context.Background()
+
+	// Jobs are created out of order and backdated so created_at differs
+	// from insertion order
+	var ids []int64
+	for _, created := range []string{"2024-01-03 00:00:00", "2024-01-01 00:00:00", "2024-01-02 00:00:00"} {
+		job := &DownloadJob{SourceType: "hcl", SourceData: "{}", Status: "pending"}
+		require.NoError(t, repo.Create(ctx, job))
+		_, err := db.exec(ctx, "test.backdate", "UPDATE download_jobs SET created_at = ? WHERE id = ?", created, job.ID)
+		require.NoError(t, err)
+		ids = append(ids, job.ID)
+	}
+
+	jobs, err := repo.ListSorted(ctx, "", Sort{Field: "created_at"}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, jobs, 3)
+	assert.Equal(t, []int64{ids[1], ids[2], ids[0]}, []int64{jobs[0].ID, jobs[1].ID, jobs[2].ID})
+
+	// The default order stays newest first
+	jobs, err = repo.List(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, jobs, 3)
+	assert.Equal(t, []int64{ids[0], ids[2], ids[1]}, []int64{jobs[0].ID, jobs[1].ID, jobs[2].ID})
+
+	jobs, err = repo.ListSorted(ctx, "running", Sort{Field: "created_at"}, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+}
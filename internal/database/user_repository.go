@@ -24,7 +24,7 @@ func (r *UserRepository) Create(ctx context.Context, u *AdminUser) error {
 		VALUES (?, ?, ?, ?, ?)
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query,
+	result, err := r.db.exec(ctx, "user.create", query,
 		u.Username, u.PasswordHash, u.FullName, u.Email, u.Active,
 	)
 	if err != nil {
@@ -52,7 +52,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id int64) (*AdminUser, err
 	`
 
 	u := &AdminUser{}
-	err := r.db.conn.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.queryRow(ctx, "user.get_by_id", query, id).Scan(
 		&u.ID, &u.Username, &u.PasswordHash, &u.FullName, &u.Email, &u.Active,
 		&u.CreatedAt, &u.UpdatedAt, &u.LastLoginAt,
 	)
@@ -76,7 +76,7 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*A
 	`
 
 	u := &AdminUser{}
-	err := r.db.conn.QueryRowContext(ctx, query, username).Scan(
+	err := r.db.queryRow(ctx, "user.get_by_username", query, username).Scan(
 		&u.ID, &u.Username, &u.PasswordHash, &u.FullName, &u.Email, &u.Active,
 		&u.CreatedAt, &u.UpdatedAt, &u.LastLoginAt,
 	)
@@ -98,7 +98,7 @@ func (r *UserRepository) UpdateLastLogin(ctx context.Context, id int64) error {
 		WHERE id = ?
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query, id)
+	result, err := r.db.exec(ctx, "user.update_last_login", query, id)
 	if err != nil {
 		return fmt.Errorf("failed to update last login: %w", err)
 	}
@@ -123,7 +123,7 @@ func (r *UserRepository) Update(ctx context.Context, u *AdminUser) error {
 		WHERE id = ?
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query,
+	result, err := r.db.exec(ctx, "user.update", query,
 		u.FullName, u.Email, u.Active, u.ID,
 	)
 	if err != nil {
@@ -151,7 +151,7 @@ func (r *UserRepository) UpdatePassword(ctx context.Context, id int64, passwordH
 		WHERE id = ?
 	`
 
-	result, err := r.db.conn.ExecContext(ctx, query, passwordHash, id)
+	result, err := r.db.exec(ctx, "user.update_password", query, passwordHash, id)
 	if err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
@@ -177,7 +177,7 @@ func (r *UserRepository) List(ctx context.Context) ([]*AdminUser, error) {
 		ORDER BY username ASC
 	`
 
-	rows, err := r.db.conn.QueryContext(ctx, query)
+	rows, err := r.db.query(ctx, "user.list", query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
@@ -206,7 +206,7 @@ func (r *UserRepository) List(ctx context.Context) ([]*AdminUser, error) {
 func (r *UserRepository) Delete(ctx context.Context, id int64) error {
 	query := "DELETE FROM admin_users WHERE id = ?"
 
-	result, err := r.db.conn.ExecContext(ctx, query, id)
+	result, err := r.db.exec(ctx, "user.delete", query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
@@ -0,0 +1,80 @@
+// Package definition reports validation problems in provider and module
+// definition files with the line they occur on.
+package definition
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Problem is a single validation failure in a definition file
+type Problem struct {
+	Filename string
+	Line     int // 0 when the problem has no position
+	Column   int
+	Message  string
+}
+
+// String formats the problem as "file:line,column: message"
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("%s:%d,%d: %s", p.Filename, p.Line, p.Column, p.Message)
+}
+
+// Error collects every problem found in a definition file, so authors can
+// fix them all in one pass
+type Error struct {
+	Problems []Problem
+}
+
+// Error lists the problems, one per line when there are several
+func (e *Error) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].String()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d problems found:", len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  ")
+		b.WriteString(p.String())
+	}
+	return b.String()
+}
+
+// Add records a problem at rng; a nil rng records it without a position
+func (e *Error) Add(rng *hcl.Range, format string, args ...interface{}) {
+	p := Problem{Message: fmt.Sprintf(format, args...)}
+	if rng != nil {
+		p.Filename = rng.Filename
+		p.Line = rng.Start.Line
+		p.Column = rng.Start.Column
+	}
+	e.Problems = append(e.Problems, p)
+}
+
+// AddDiagnostics records the errors among HCL diagnostics
+func (e *Error) AddDiagnostics(diags hcl.Diagnostics) {
+	for _, diag := range diags {
+		if diag.Severity != hcl.DiagError {
+			continue
+		}
+		message := diag.Summary
+		if diag.Detail != "" {
+			message += ": " + diag.Detail
+		}
+		e.Add(diag.Subject, "%s", message)
+	}
+}
+
+// ErrOrNil returns e if it holds any problems, and nil otherwise
+func (e *Error) ErrOrNil() error {
+	if len(e.Problems) == 0 {
+		return nil
+	}
+	return e
+}
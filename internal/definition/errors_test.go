@@ -0,0 +1,31 @@
+package definition
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError(t *testing.T) {
+	e := &Error{}
+	require.NoError(t, e.ErrOrNil())
+
+	rng := &hcl.Range{Filename: "providers.hcl", Start: hcl.Pos{Line: 3, Column: 5}}
+	e.Add(rng, "bad value %q", "x")
+	require.Error(t, e.ErrOrNil())
+	assert.Equal(t, `providers.hcl:3,5: bad value "x"`, e.Error())
+
+	e.Add(nil, "no position")
+	e.AddDiagnostics(hcl.Diagnostics{
+		{Severity: hcl.DiagWarning, Summary: "ignored"},
+		{Severity: hcl.DiagError, Summary: "Unsupported argument", Detail: "not expected here.", Subject: rng},
+	})
+
+	require.Len(t, e.Problems, 3)
+	assert.Equal(t, "3 problems found:\n"+
+		"  providers.hcl:3,5: bad value \"x\"\n"+
+		"  no position\n"+
+		"  providers.hcl:3,5: Unsupported argument: not expected here.", e.Error())
+}
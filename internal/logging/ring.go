@@ -0,0 +1,140 @@
+// Package logging keeps recent log output in memory so it can be read back
+// through the admin API on installs without log aggregation.
+package logging
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log levels, in increasing severity
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+var levelSeverity = map[string]int{
+	LevelDebug: 0,
+	LevelInfo:  1,
+	LevelWarn:  2,
+	LevelError: 3,
+}
+
+// ValidLevel reports whether level is a known log level
+func ValidLevel(level string) bool {
+	_, ok := levelSeverity[level]
+	return ok
+}
+
+// stdlibTimestamp is the prefix the standard log package writes with LstdFlags
+const stdlibTimestamp = "2006/01/02 15:04:05 "
+
+// Entry is one captured log line
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// RingBuffer is an io.Writer that keeps the most recent log entries, dropping
+// the oldest once it is full. Point the standard logger at it (alongside the
+// real output) with log.SetOutput(io.MultiWriter(os.Stderr, buf)).
+type RingBuffer struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int  // index the next entry is written to
+	full    bool // whether entries has wrapped
+}
+
+// NewRingBuffer creates a buffer holding up to size entries
+func NewRingBuffer(size int) *RingBuffer {
+	if size <= 0 {
+		size = 1
+	}
+	return &RingBuffer{entries: make([]Entry, size)}
+}
+
+// Write records one log write as an entry. The standard logger makes a
+// single Write per message, so multi-line messages stay together.
+func (b *RingBuffer) Write(p []byte) (int, error) {
+	message := strings.TrimRight(string(p), "\n")
+	if len(message) > len(stdlibTimestamp) {
+		if _, err := time.ParseInLocation(stdlibTimestamp, message[:len(stdlibTimestamp)], time.Local); err == nil {
+			message = message[len(stdlibTimestamp):]
+		}
+	}
+
+	entry := Entry{
+		Time:    time.Now().UTC(),
+		Level:   DetectLevel(message),
+		Message: message,
+	}
+
+	b.mu.Lock()
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+	b.mu.Unlock()
+
+	return len(p), nil
+}
+
+// Recent returns up to limit of the newest entries at or above minLevel,
+// oldest first. An empty minLevel matches every entry and a limit of zero or
+// less returns all matches.
+func (b *RingBuffer) Recent(minLevel string, limit int) []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := b.next
+	if b.full {
+		count = len(b.entries)
+	}
+	threshold := levelSeverity[minLevel]
+
+	// Walk backwards from the newest entry, then restore chronological order
+	var matched []Entry
+	for i := 0; i < count && (limit <= 0 || len(matched) < limit); i++ {
+		entry := b.entries[(b.next-1-i+len(b.entries))%len(b.entries)]
+		if levelSeverity[entry.Level] >= threshold {
+			matched = append(matched, entry)
+		}
+	}
+	for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+		matched[i], matched[j] = matched[j], matched[i]
+	}
+	return matched
+}
+
+// Size returns the number of entries the buffer can hold
+func (b *RingBuffer) Size() int {
+	return len(b.entries)
+}
+
+// DetectLevel infers the level of a plain log message. The codebase logs
+// through the standard library without levels, so this relies on how
+// messages are worded: explicit "[LEVEL]" or "LEVEL:" prefixes win, then
+// messages starting with "error", "failed", "fatal" or "panic" are errors
+// and ones starting with "warning" are warnings. Everything else is info.
+func DetectLevel(message string) string {
+	lower := strings.ToLower(strings.TrimLeft(message, "[ "))
+
+	switch {
+	case strings.HasPrefix(lower, "debug"):
+		return LevelDebug
+	case strings.HasPrefix(lower, "warn"):
+		return LevelWarn
+	case strings.HasPrefix(lower, "error"),
+		strings.HasPrefix(lower, "failed"),
+		strings.HasPrefix(lower, "fatal"),
+		strings.HasPrefix(lower, "panic"):
+		return LevelError
+	default:
+		return LevelInfo
+	}
+}
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRingBuffer_Recent(t *testing.T) {
+	buf := NewRingBuffer(3)
+	logger := log.New(buf, "", log.LstdFlags)
+
+	logger.Printf("first")
+	logger.Printf("Warning: second")
+	logger.Printf("Failed to do third: %v", fmt.Errorf("boom"))
+
+	entries := buf.Recent("", 0)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "first", entries[0].Message)
+	assert.Equal(t, LevelInfo, entries[0].Level)
+	assert.Equal(t, "Warning: second", entries[1].Message)
+	assert.Equal(t, LevelWarn, entries[1].Level)
+	assert.Equal(t, "Failed to do third: boom", entries[2].Message)
+	assert.Equal(t, LevelError, entries[2].Level)
+	assert.False(t, entries[0].Time.IsZero())
+
+	// The oldest entry is dropped once the buffer is full
+	logger.Printf("fourth")
+	entries = buf.Recent("", 0)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "Warning: second", entries[0].Message)
+	assert.Equal(t, "fourth", entries[2].Message)
+
+	// Limits keep the newest entries, still oldest first
+	entries = buf.Recent("", 2)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "Failed to do third: boom", entries[0].Message)
+	assert.Equal(t, "fourth", entries[1].Message)
+
+	// Level filtering includes more severe levels
+	entries = buf.Recent(LevelWarn, 0)
+	require.Len(t, entries, 2)
+	assert.Equal(t, LevelWarn, entries[0].Level)
+	assert.Equal(t, LevelError, entries[1].Level)
+}
+
+func TestRingBuffer_Empty(t *testing.T) {
+	buf := NewRingBuffer(5)
+	assert.Empty(t, buf.Recent("", 0))
+	assert.Equal(t, 5, buf.Size())
+}
+
+func TestDetectLevel(t *testing.T) {
+	tests := map[string]string{
+		"Server will listen on port 8080": LevelInfo,
+		"Warning: cache disabled":         LevelWarn,
+		"[WARN] slow query":               LevelWarn,
+		"Error closing cache: closed":     LevelError,
+		"Failed to download blob x: nope": LevelError,
+		"[ERROR] boom":                    LevelError,
+		"panic in poll_loop: nil pointer": LevelError,
+		"DEBUG: polling for jobs":         LevelDebug,
+		"Job 5: Failed items are retried": LevelInfo,
+	}
+	for message, want := range tests {
+		assert.Equal(t, want, DetectLevel(message), message)
+	}
+}
@@ -0,0 +1,271 @@
+package manifest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/provider"
+)
+
+// FormatVersion is the manifest format version written by Export
+const FormatVersion = 1
+
+// pageSize is the number of rows read per query while exporting
+const pageSize = 500
+
+// Manifest is a portable description of everything in the mirror. Entries are
+// sorted so that two manifests of the same mirror are byte-for-byte identical.
+type Manifest struct {
+	FormatVersion int        `json:"format_version"`
+	Providers     []Provider `json:"providers"`
+	Modules       []Module   `json:"modules"`
+}
+
+// Provider is a single mirrored provider platform binary
+type Provider struct {
+	Namespace string `json:"namespace"`
+	Type      string `json:"type"`
+	Version   string `json:"version"`
+	Platform  string `json:"platform"`
+	Filename  string `json:"filename"`
+	Shasum    string `json:"shasum"`
+}
+
+// Module is a single mirrored module version
+type Module struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	System    string `json:"system"`
+	Version   string `json:"version"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// ImportResult describes the jobs created by Import
+type ImportResult struct {
+	ProviderJobID int64 `json:"provider_job_id,omitempty"`
+	ProviderItems int   `json:"provider_items"`
+	ModuleJobID   int64 `json:"module_job_id,omitempty"`
+	ModuleItems   int   `json:"module_items"`
+}
+
+// Export builds a manifest of all providers and modules in the database
+func Export(ctx context.Context, db *database.DB) (*Manifest, error) {
+	m := &Manifest{
+		FormatVersion: FormatVersion,
+		Providers:     []Provider{},
+		Modules:       []Module{},
+	}
+
+	providerRepo := database.NewProviderRepository(db)
+	for offset := 0; ; offset += pageSize {
+		providers, err := providerRepo.List(ctx, pageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list providers: %w", err)
+		}
+		for _, p := range providers {
+			m.Providers = append(m.Providers, Provider{
+				Namespace: p.Namespace,
+				Type:      p.Type,
+				Version:   p.Version,
+				Platform:  p.Platform,
+				Filename:  p.Filename,
+				Shasum:    p.Shasum,
+			})
+		}
+		if len(providers) < pageSize {
+			break
+		}
+	}
+
+	moduleRepo := database.NewModuleRepository(db)
+	for offset := 0; ; offset += pageSize {
+		modules, err := moduleRepo.List(ctx, database.ModuleFilter{}, pageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list modules: %w", err)
+		}
+		for _, mod := range modules {
+			m.Modules = append(m.Modules, Module{
+				Namespace: mod.Namespace,
+				Name:      mod.Name,
+				System:    mod.System,
+				Version:   mod.Version,
+				SizeBytes: mod.SizeBytes,
+			})
+		}
+		if len(modules) < pageSize {
+			break
+		}
+	}
+
+	m.sort()
+	return m, nil
+}
+
+// sort orders entries by identity so the manifest diffs cleanly
+func (m *Manifest) sort() {
+	sort.Slice(m.Providers, func(i, j int) bool {
+		a, b := m.Providers[i], m.Providers[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Platform < b.Platform
+	})
+	sort.Slice(m.Modules, func(i, j int) bool {
+		a, b := m.Modules[i], m.Modules[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.System != b.System {
+			return a.System < b.System
+		}
+		return a.Version < b.Version
+	})
+}
+
+// Write encodes the manifest as indented JSON
+func (m *Manifest) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	return nil
+}
+
+// Read decodes and validates a manifest
+func Read(r io.Reader) (*Manifest, error) {
+	var m Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	if m.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("unsupported manifest format version %d (expected %d)", m.FormatVersion, FormatVersion)
+	}
+
+	for i, p := range m.Providers {
+		if p.Namespace == "" || p.Type == "" || p.Version == "" || p.Platform == "" {
+			return nil, fmt.Errorf("provider entry %d: namespace, type, version, and platform are required", i)
+		}
+		os, arch, err := provider.ParsePlatform(p.Platform)
+		if err != nil {
+			return nil, fmt.Errorf("provider entry %d: %w", i, err)
+		}
+		if p.Filename != "" {
+			if err := provider.CheckProviderFilename(p.Filename, p.Type, p.Version, os, arch); err != nil {
+				return nil, fmt.Errorf("provider entry %d: %w", i, err)
+			}
+		}
+	}
+	for i, mod := range m.Modules {
+		if mod.Namespace == "" || mod.Name == "" || mod.System == "" || mod.Version == "" {
+			return nil, fmt.Errorf("module entry %d: namespace, name, system, and version are required", i)
+		}
+	}
+
+	if m.Providers == nil {
+		m.Providers = []Provider{}
+	}
+	if m.Modules == nil {
+		m.Modules = []Module{}
+	}
+
+	m.sort()
+	return &m, nil
+}
+
+// Import creates pending download jobs for every entry in the manifest. One
+// job is created for providers and one for modules; the background processor
+// picks them up and skips anything that is already mirrored. Provider items
+// pin the manifest's shasum, so a download with other content fails.
+func Import(ctx context.Context, db *database.DB, m *Manifest, userID sql.NullInt64) (*ImportResult, error) {
+	jobRepo := database.NewJobRepository(db)
+	result := &ImportResult{}
+
+	if len(m.Providers) > 0 {
+		job := &database.DownloadJob{
+			UserID:     userID,
+			JobType:    "provider",
+			SourceType: "manifest",
+			SourceData: fmt.Sprintf("%d providers", len(m.Providers)),
+			Status:     "pending",
+			TotalItems: len(m.Providers),
+			CreatedAt:  time.Now(),
+		}
+		if err := jobRepo.Create(ctx, job); err != nil {
+			return nil, fmt.Errorf("failed to create provider job: %w", err)
+		}
+
+		for _, p := range m.Providers {
+			item := &database.DownloadJobItem{
+				JobID:     job.ID,
+				Namespace: p.Namespace,
+				Type:      p.Type,
+				Version:   p.Version,
+				Platform:  p.Platform,
+				Status:    "pending",
+			}
+			if p.Shasum != "" {
+				item.ExpectedShasums = sql.NullString{String: p.Shasum, Valid: true}
+			}
+			if err := jobRepo.CreateItem(ctx, item); err != nil {
+				return nil, fmt.Errorf("failed to create provider job item: %w", err)
+			}
+		}
+		jobRepo.NotifyEnqueued()
+
+		result.ProviderJobID = job.ID
+		result.ProviderItems = len(m.Providers)
+	}
+
+	if len(m.Modules) > 0 {
+		job := &database.DownloadJob{
+			UserID:     userID,
+			JobType:    "module",
+			SourceType: "manifest",
+			SourceData: fmt.Sprintf("%d modules", len(m.Modules)),
+			Status:     "pending",
+			TotalItems: len(m.Modules),
+			CreatedAt:  time.Now(),
+		}
+		if err := jobRepo.Create(ctx, job); err != nil {
+			return nil, fmt.Errorf("failed to create module job: %w", err)
+		}
+
+		moduleJobRepo := database.NewModuleJobRepository(db)
+		for _, mod := range m.Modules {
+			item := &database.ModuleJobItem{
+				JobID:     job.ID,
+				Namespace: mod.Namespace,
+				Name:      mod.Name,
+				System:    mod.System,
+				Version:   mod.Version,
+				Status:    "pending",
+			}
+			if err := moduleJobRepo.CreateItem(ctx, item); err != nil {
+				return nil, fmt.Errorf("failed to create module job item: %w", err)
+			}
+		}
+		jobRepo.NotifyEnqueued()
+
+		result.ModuleJobID = job.ID
+		result.ModuleItems = len(m.Modules)
+	}
+
+	return result, nil
+}
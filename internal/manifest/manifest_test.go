@@ -0,0 +1,164 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestDB(t *testing.T) *database.DB {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func seedMirror(t *testing.T, db *database.DB) {
+	ctx := context.Background()
+	providerRepo := database.NewProviderRepository(db)
+	moduleRepo := database.NewModuleRepository(db)
+
+	// Inserted out of order to verify the manifest is sorted
+	for _, p := range []*database.Provider{
+		{Namespace: "hashicorp", Type: "random", Version: "3.5.0", Platform: "linux_amd64", Shasum: "ccc"},
+		{Namespace: "hashicorp", Type: "aws", Version: "5.0.0", Platform: "linux_amd64", Shasum: "aaa"},
+		{Namespace: "hashicorp", Type: "aws", Version: "5.0.0", Platform: "darwin_arm64", Shasum: "bbb"},
+	} {
+		p.Filename = "terraform-provider-" + p.Type + "_" + p.Version + "_" + p.Platform + ".zip"
+		p.S3Key = "providers/registry.terraform.io/" + p.Namespace + "/" + p.Type + "/" + p.Version + "/" + p.Platform + "/" + p.Filename
+		require.NoError(t, providerRepo.Create(ctx, p))
+	}
+
+	for _, m := range []*database.Module{
+		{Namespace: "terraform-aws-modules", Name: "vpc", System: "aws", Version: "5.1.0", SizeBytes: 200},
+		{Namespace: "terraform-aws-modules", Name: "vpc", System: "aws", Version: "5.0.0", SizeBytes: 100},
+	} {
+		m.Filename = m.Name + "-" + m.Version + ".tar.gz"
+		m.S3Key = "modules/registry.terraform.io/" + m.Namespace + "/" + m.Name + "/" + m.System + "/" + m.Version + "/" + m.Filename
+		require.NoError(t, moduleRepo.Create(ctx, m))
+	}
+}
+
+func TestExport(t *testing.T) {
+	db := setupTestDB(t)
+	seedMirror(t, db)
+
+	m, err := Export(context.Background(), db)
+	require.NoError(t, err)
+
+	assert.Equal(t, FormatVersion, m.FormatVersion)
+	require.Len(t, m.Providers, 3)
+	assert.Equal(t, "darwin_arm64", m.Providers[0].Platform)
+	assert.Equal(t, "bbb", m.Providers[0].Shasum)
+	assert.Equal(t, "linux_amd64", m.Providers[1].Platform)
+	assert.Equal(t, "random", m.Providers[2].Type)
+
+	require.Len(t, m.Modules, 2)
+	assert.Equal(t, "5.0.0", m.Modules[0].Version)
+	assert.Equal(t, "5.1.0", m.Modules[1].Version)
+
+	// Exporting again produces identical output
+	var first, second bytes.Buffer
+	require.NoError(t, m.Write(&first))
+	again, err := Export(context.Background(), db)
+	require.NoError(t, err)
+	require.NoError(t, again.Write(&second))
+	assert.Equal(t, first.String(), second.String())
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	source := setupTestDB(t)
+	seedMirror(t, source)
+
+	exported, err := Export(context.Background(), source)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, exported.Write(&buf))
+
+	m, err := Read(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, exported, m)
+
+	// Import into an empty mirror
+	target := setupTestDB(t)
+	ctx := context.Background()
+
+	result, err := Import(ctx, target, m, sql.NullInt64{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.ProviderItems)
+	assert.Equal(t, 2, result.ModuleItems)
+
+	jobRepo := database.NewJobRepository(target)
+
+	providerJob, err := jobRepo.GetByID(ctx, result.ProviderJobID)
+	require.NoError(t, err)
+	assert.Equal(t, "provider", providerJob.JobType)
+	assert.Equal(t, "pending", providerJob.Status)
+	assert.Equal(t, 3, providerJob.TotalItems)
+
+	items, err := jobRepo.GetItems(ctx, result.ProviderJobID)
+	require.NoError(t, err)
+	var imported []Provider
+	for _, item := range items {
+		imported = append(imported, Provider{
+			Namespace: item.Namespace,
+			Type:      item.Type,
+			Version:   item.Version,
+			Platform:  item.Platform,
+			Shasum:    item.ExpectedShasums.String,
+		})
+	}
+	var expected []Provider
+	for _, p := range exported.Providers {
+		expected = append(expected, Provider{Namespace: p.Namespace, Type: p.Type, Version: p.Version, Platform: p.Platform, Shasum: p.Shasum})
+	}
+	assert.ElementsMatch(t, expected, imported)
+
+	moduleJob, err := jobRepo.GetByID(ctx, result.ModuleJobID)
+	require.NoError(t, err)
+	assert.Equal(t, "module", moduleJob.JobType)
+	assert.Equal(t, "pending", moduleJob.Status)
+
+	moduleItems, err := database.NewModuleJobRepository(target).ListByJob(ctx, result.ModuleJobID)
+	require.NoError(t, err)
+	require.Len(t, moduleItems, 2)
+	var versions []string
+	for _, item := range moduleItems {
+		assert.Equal(t, "terraform-aws-modules", item.Namespace)
+		assert.Equal(t, "vpc", item.Name)
+		versions = append(versions, item.Version)
+	}
+	assert.ElementsMatch(t, []string{"5.0.0", "5.1.0"}, versions)
+}
+
+func TestRead_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		errorMsg string
+	}{
+		{name: "malformed", input: `{`, errorMsg: "failed to decode manifest"},
+		{name: "wrong format version", input: `{"format_version": 99}`, errorMsg: "unsupported manifest format version"},
+		{name: "incomplete provider", input: `{"format_version": 1, "providers": [{"namespace": "hashicorp"}]}`, errorMsg: "provider entry 0"},
+		{name: "invalid provider platform", input: `{"format_version": 1, "providers": [{"namespace": "hashicorp", "type": "aws", "version": "5.0.0", "platform": "linux"}]}`, errorMsg: "invalid platform format"},
+		{name: "bad provider filename", input: `{"format_version": 1, "providers": [{"namespace": "hashicorp", "type": "aws", "version": "5.0.0", "platform": "linux_amd64", "filename": "aws.zip"}]}`, errorMsg: "invalid provider filename"},
+		{name: "mismatched provider filename", input: `{"format_version": 1, "providers": [{"namespace": "hashicorp", "type": "aws", "version": "5.0.0", "platform": "linux_amd64", "filename": "terraform-provider-aws_4.0.0_linux_amd64.zip"}]}`, errorMsg: "does not match"},
+		{name: "incomplete module", input: `{"format_version": 1, "modules": [{"name": "vpc"}]}`, errorMsg: "module entry 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tt.input))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
@@ -30,13 +30,29 @@ type Metrics struct {
 	ProviderDownloads    *prometheus.CounterVec
 	ProviderDownloadSize *prometheus.CounterVec
 
+	// Provider auto-download metrics
+	AutoDownloadRequests          prometheus.Counter
+	AutoDownloadOutcomes          *prometheus.CounterVec
+	AutoDownloadCacheHits         prometheus.Counter
+	AutoDownloadNegativeCacheHits prometheus.Counter
+	AutoDownloadRateLimited       prometheus.Counter
+	AutoDownloadCoalesced         prometheus.Counter
+	AutoDownloadBytes             prometheus.Counter
+	AutoDownloadsInFlight         prometheus.Gauge
+
+	// Blob transfer metrics
+	BlobDownloadBytes    *prometheus.HistogramVec
+	BlobDownloadDuration *prometheus.HistogramVec
+
 	// Job metrics
-	JobsTotal       *prometheus.GaugeVec
-	JobsProcessed   *prometheus.CounterVec
-	JobDuration     *prometheus.HistogramVec
-	JobItemsTotal   *prometheus.CounterVec
-	ActiveJobs      prometheus.Gauge
-	ProcessorStatus prometheus.Gauge
+	JobsTotal         *prometheus.GaugeVec
+	JobsProcessed     *prometheus.CounterVec
+	JobDuration       *prometheus.HistogramVec
+	JobItemsTotal     *prometheus.CounterVec
+	ActiveJobs        prometheus.Gauge
+	ProcessorStatus   prometheus.Gauge
+	ProcessorPanics   *prometheus.CounterVec
+	ProcessorRestarts prometheus.Counter
 
 	// Storage metrics
 	StorageBytesUsed   prometheus.Gauge
@@ -140,6 +156,94 @@ func newMetrics(reg prometheus.Registerer) *Metrics {
 		[]string{"namespace", "type"},
 	)
 
+	// Provider auto-download metrics, mirroring the service's in-memory stats
+	m.AutoDownloadRequests = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auto_download_requests_total",
+			Help:      "Total number of on-demand provider download requests",
+		},
+	)
+
+	m.AutoDownloadOutcomes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auto_download_outcomes_total",
+			Help:      "Total number of on-demand provider downloads by outcome",
+		},
+		[]string{"outcome"},
+	)
+
+	m.AutoDownloadCacheHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auto_download_cache_hits_total",
+			Help:      "Total number of on-demand requests answered by an artifact already mirrored",
+		},
+	)
+
+	m.AutoDownloadNegativeCacheHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auto_download_negative_cache_hits_total",
+			Help:      "Total number of on-demand requests answered by a cached not-found result",
+		},
+	)
+
+	m.AutoDownloadRateLimited = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auto_download_rate_limited_total",
+			Help:      "Total number of on-demand requests refused by the rate limiter",
+		},
+	)
+
+	m.AutoDownloadCoalesced = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auto_download_coalesced_total",
+			Help:      "Total number of on-demand requests that waited on a download already in flight",
+		},
+	)
+
+	m.AutoDownloadBytes = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auto_download_bytes_total",
+			Help:      "Total bytes downloaded from upstream on demand",
+		},
+	)
+
+	m.AutoDownloadsInFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "auto_downloads_in_flight",
+			Help:      "Number of on-demand provider downloads currently in flight",
+		},
+	)
+
+	// Blob transfer metrics, labeled by where the blob was read from so slow
+	// storage reads can be told apart from slow clients
+	m.BlobDownloadBytes = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "blob_download_bytes",
+			Help:      "Bytes served per blob download",
+			Buckets:   prometheus.ExponentialBuckets(1024, 4, 12), // 1 KiB to 4 GiB
+		},
+		[]string{"source"},
+	)
+
+	m.BlobDownloadDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "blob_download_duration_seconds",
+			Help:      "Time spent writing a blob to the client in seconds",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 120, 300},
+		},
+		[]string{"source"},
+	)
+
 	// Job metrics
 	m.JobsTotal = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -194,6 +298,23 @@ func newMetrics(reg prometheus.Registerer) *Metrics {
 		},
 	)
 
+	m.ProcessorPanics = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "processor_panics_total",
+			Help:      "Total number of panics recovered in the job processor",
+		},
+		[]string{"component"},
+	)
+
+	m.ProcessorRestarts = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "processor_restarts_total",
+			Help:      "Total number of times the job processor poll loop was restarted",
+		},
+	)
+
 	// Storage metrics
 	m.StorageBytesUsed = prometheus.NewGauge(
 		prometheus.GaugeOpts{
@@ -308,12 +429,24 @@ func newMetrics(reg prometheus.Registerer) *Metrics {
 		m.ProviderVersions,
 		m.ProviderDownloads,
 		m.ProviderDownloadSize,
+		m.AutoDownloadRequests,
+		m.AutoDownloadOutcomes,
+		m.AutoDownloadCacheHits,
+		m.AutoDownloadNegativeCacheHits,
+		m.AutoDownloadRateLimited,
+		m.AutoDownloadCoalesced,
+		m.AutoDownloadBytes,
+		m.AutoDownloadsInFlight,
+		m.BlobDownloadBytes,
+		m.BlobDownloadDuration,
 		m.JobsTotal,
 		m.JobsProcessed,
 		m.JobDuration,
 		m.JobItemsTotal,
 		m.ActiveJobs,
 		m.ProcessorStatus,
+		m.ProcessorPanics,
+		m.ProcessorRestarts,
 		m.StorageBytesUsed,
 		m.StorageObjectCount,
 		m.StorageOperations,
@@ -343,6 +476,23 @@ func (m *Metrics) RecordProviderDownload(namespace, providerType, version, os, a
 	m.ProviderDownloadSize.WithLabelValues(namespace, providerType).Add(float64(sizeBytes))
 }
 
+// RecordAutoDownloadOutcome records how an on-demand provider download
+// ended: success, failed, not_allowed or version_cap
+func (m *Metrics) RecordAutoDownloadOutcome(outcome string) {
+	m.AutoDownloadOutcomes.WithLabelValues(outcome).Inc()
+}
+
+// RecordAutoDownloadBytes records bytes downloaded from upstream on demand
+func (m *Metrics) RecordAutoDownloadBytes(bytes int64) {
+	m.AutoDownloadBytes.Add(float64(bytes))
+}
+
+// RecordBlobDownload records the size and transfer time of a served blob
+func (m *Metrics) RecordBlobDownload(source string, bytes int64, durationSeconds float64) {
+	m.BlobDownloadBytes.WithLabelValues(source).Observe(float64(bytes))
+	m.BlobDownloadDuration.WithLabelValues(source).Observe(durationSeconds)
+}
+
 // RecordJobProcessed records a completed job
 func (m *Metrics) RecordJobProcessed(status string, durationSeconds float64, jobType string) {
 	m.JobsProcessed.WithLabelValues(status).Inc()
@@ -409,6 +559,11 @@ func (m *Metrics) SetProcessorStatus(running bool) {
 	}
 }
 
+// RecordProcessorPanic records a recovered panic in a processor component
+func (m *Metrics) RecordProcessorPanic(component string) {
+	m.ProcessorPanics.WithLabelValues(component).Inc()
+}
+
 // SetActiveSessions sets the number of active sessions
 func (m *Metrics) SetActiveSessions(count int) {
 	m.ActiveSessions.Set(float64(count))
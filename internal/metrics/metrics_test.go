@@ -100,6 +100,26 @@ func TestRecordProviderDownload(t *testing.T) {
 	}
 }
 
+func TestRecordAutoDownload(t *testing.T) {
+	m := newTestMetrics()
+
+	m.RecordAutoDownloadOutcome("success")
+	m.RecordAutoDownloadOutcome("success")
+	m.RecordAutoDownloadOutcome("failed")
+	m.RecordAutoDownloadBytes(1024)
+	m.RecordAutoDownloadBytes(512)
+
+	if count := testutil.ToFloat64(m.AutoDownloadOutcomes.WithLabelValues("success")); count != 2 {
+		t.Errorf("Expected 2 successful downloads, got %v", count)
+	}
+	if count := testutil.ToFloat64(m.AutoDownloadOutcomes.WithLabelValues("failed")); count != 1 {
+		t.Errorf("Expected 1 failed download, got %v", count)
+	}
+	if bytes := testutil.ToFloat64(m.AutoDownloadBytes); bytes != 1536 {
+		t.Errorf("Expected 1536 bytes, got %v", bytes)
+	}
+}
+
 func TestRecordJobProcessed(t *testing.T) {
 	m := newTestMetrics()
 
@@ -245,6 +265,21 @@ func TestSetProcessorStatus(t *testing.T) {
 	}
 }
 
+func TestRecordProcessorPanic(t *testing.T) {
+	m := newTestMetrics()
+
+	m.RecordProcessorPanic("worker")
+	m.RecordProcessorPanic("worker")
+	m.RecordProcessorPanic("poll_loop")
+
+	if got := testutil.ToFloat64(m.ProcessorPanics.WithLabelValues("worker")); got != 2 {
+		t.Errorf("Expected 2 worker panics, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.ProcessorPanics.WithLabelValues("poll_loop")); got != 1 {
+		t.Errorf("Expected 1 poll loop panic, got %v", got)
+	}
+}
+
 func TestSetActiveSessions(t *testing.T) {
 	m := newTestMetrics()
 
@@ -351,3 +386,34 @@ func TestSetActiveSessions_Multiple(t *testing.T) {
 		t.Errorf("Expected 0 active sessions, got %v", sessions)
 	}
 }
+
+func TestRecordBlobDownload(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewWithRegistry(reg)
+
+	m.RecordBlobDownload("storage", 2048, 0.5)
+	m.RecordBlobDownload("storage", 1024, 1.5)
+	m.RecordBlobDownload("cache", 512, 0.01)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() failed: %v", err)
+	}
+
+	sums := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "terraform_mirror_blob_download_bytes" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			sums[metric.GetLabel()[0].GetValue()] = metric.GetHistogram().GetSampleSum()
+		}
+	}
+
+	if sums["storage"] != 3072 {
+		t.Errorf("Expected 3072 bytes from storage, got %v", sums["storage"])
+	}
+	if sums["cache"] != 512 {
+		t.Errorf("Expected 512 bytes from cache, got %v", sums["cache"])
+	}
+}
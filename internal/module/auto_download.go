@@ -1,7 +1,6 @@
 package module
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"fmt"
@@ -76,10 +75,13 @@ func NewAutoDownloadService(
 	ratePerSecond := float64(cfg.RateLimitPerMinute) / 60.0
 	limiter := rate.NewLimiter(rate.Limit(ratePerSecond), cfg.MaxConcurrentDL)
 
+	registry := NewRegistryClient(moduleCfg.GetUpstreamRegistry())
+	registry.SetRetryPolicy(moduleCfg.DownloadRetryAttempts, moduleCfg.GetDownloadRetryDelay())
+
 	return &AutoDownloadService{
 		config:        cfg,
 		moduleCfg:     moduleCfg,
-		registry:      NewRegistryClient(moduleCfg.GetUpstreamRegistry()),
+		registry:      registry,
 		rewriter:      NewRewriter(moduleCfg.MirrorHostname),
 		storage:       storage,
 		moduleRepo:    database.NewModuleRepository(db),
@@ -97,6 +99,14 @@ func (s *AutoDownloadService) SetRegistry(r RegistryDownloader) {
 	s.registry = r
 }
 
+// SetMaxDownloadSize caps module downloads in bytes when using the default
+// registry client; zero or less means unlimited
+func (s *AutoDownloadService) SetMaxDownloadSize(limit int64) {
+	if client, ok := s.registry.(*RegistryClient); ok {
+		client.SetMaxDownloadSize(limit)
+	}
+}
+
 // GetStats returns current statistics
 func (s *AutoDownloadService) GetStats() ModuleAutoDownloadStats {
 	s.statsMu.RLock()
@@ -256,10 +266,12 @@ func (s *AutoDownloadService) performDownload(
 		return nil, fmt.Errorf("download failed: %w", result.Error)
 	}
 
+	defer result.Cleanup()
+
 	// Rewrite module sources if configured
-	moduleData, err := s.rewriter.RewriteModule(result.Data)
+	open, size, err := moduleContent(s.rewriter, result)
 	if err != nil {
-		return nil, fmt.Errorf("source rewriting failed: %w", err)
+		return nil, err
 	}
 
 	// Build storage key
@@ -268,21 +280,22 @@ func (s *AutoDownloadService) performDownload(
 		namespace, name, system, version, filename)
 
 	// Upload to storage
-	reader := bytes.NewReader(moduleData)
-	err = s.storage.Upload(downloadCtx, storageKey, reader, "application/gzip", nil)
+	metadata := storage.ModuleMetadata(namespace, name, system, version, filename, result.Info.DownloadURL, false)
+	stored, err := storeArchive(downloadCtx, s.storage, s.moduleCfg.DeduplicateArchives, storageKey, open, size, metadata)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload to storage: %w", err)
 	}
 
 	// Create database record
 	module := &database.Module{
-		Namespace: namespace,
-		Name:      name,
-		System:    system,
-		Version:   version,
-		Filename:  filename,
-		S3Key:     storageKey,
-		SizeBytes: int64(len(moduleData)),
+		Namespace:   namespace,
+		Name:        name,
+		System:      system,
+		Version:     version,
+		Filename:    filename,
+		S3Key:       stored.Key,
+		SizeBytes:   stored.Size,
+		ContentHash: stored.ContentHash,
 		OriginalSourceURL: sql.NullString{
 			String: result.Info.DownloadURL,
 			Valid:  result.Info.DownloadURL != "",
@@ -298,13 +311,20 @@ func (s *AutoDownloadService) performDownload(
 		}
 		return nil, fmt.Errorf("failed to store module record: %w", err)
 	}
+	storedSize, err := ensureStored(downloadCtx, s.storage, stored, open, size, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload to storage: %w", err)
+	}
+	if err := recordStoredSize(downloadCtx, s.moduleRepo, module, storedSize); err != nil {
+		return nil, err
+	}
 
 	s.statsMu.Lock()
-	s.stats.BytesDownloaded += int64(len(moduleData))
+	s.stats.BytesDownloaded += size
 	s.statsMu.Unlock()
 
 	s.logger.Printf("Auto-download complete: %s/%s/%s %s - %d bytes in %v",
-		namespace, name, system, version, len(moduleData), result.Duration)
+		namespace, name, system, version, size, result.Duration)
 
 	return module, nil
 }
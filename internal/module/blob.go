@@ -0,0 +1,186 @@
+package module
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+)
+
+// gzipMagic is the header every gzip stream starts with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ContentHash returns the hex SHA256 of a module archive's uncompressed tar
+// stream, so tarballs that differ only in their gzip header hash the same.
+// Input that is not gzip-compressed is hashed as is.
+func ContentHash(r io.Reader) (string, error) {
+	br := bufio.NewReader(r)
+	src := io.Reader(br)
+	if magic, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		gzr, err := gzip.NewReader(br)
+		if err != nil {
+			return "", fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gzr.Close()
+		src = gzr
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, src); err != nil {
+		return "", fmt.Errorf("failed to hash archive: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// storedArchive describes where a module archive was stored
+type storedArchive struct {
+	Key         string
+	ContentHash sql.NullString
+	Size        int64 // size of the stored object, which may be another version's tarball
+	Uploaded    bool  // false when an identical archive was already stored
+}
+
+// storeArchive uploads a module archive of size bytes to key, or with dedup
+// set to the content-addressed key for its content, skipping the upload when
+// that object already exists. An existing object keeps its own bytes, which
+// may differ from these in gzip output and so in size. open is called once
+// per pass over the archive.
+func storeArchive(ctx context.Context, store storage.Storage, dedup bool, key string, open func() (io.ReadCloser, error), size int64, metadata map[string]string) (*storedArchive, error) {
+	stored := &storedArchive{Key: key, Size: size}
+
+	if dedup {
+		reader, err := open()
+		if err != nil {
+			return nil, err
+		}
+		hash, err := ContentHash(reader)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		stored.Key = storage.BuildModuleBlobKey(hash)
+		stored.ContentHash = sql.NullString{String: hash, Valid: true}
+
+		exists, err := store.Exists(ctx, stored.Key)
+		if err != nil {
+			return nil, fmt.Errorf("storage check failed: %w", err)
+		}
+		if exists {
+			if stored.Size, err = store.GetObjectSize(ctx, stored.Key); err != nil {
+				return nil, fmt.Errorf("storage check failed: %w", err)
+			}
+			// An archive first stored unpinned is stored again so the
+			// version requested explicitly keeps it protected
+			if metadata[storage.MetadataPinned] == "true" && !archivePinned(ctx, store, stored.Key) {
+				if err := repinArchive(ctx, store, stored.Key, metadata); err != nil {
+					return nil, err
+				}
+				stored.Uploaded = true
+			}
+			return stored, nil
+		}
+	}
+
+	reader, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	if err := store.Upload(ctx, stored.Key, reader, "application/gzip", metadata); err != nil {
+		return nil, fmt.Errorf("storage upload failed: %w", err)
+	}
+	stored.Uploaded = true
+	return stored, nil
+}
+
+// repinArchive stores an existing archive again with the given metadata,
+// keeping its bytes so the versions already sharing it keep their size
+func repinArchive(ctx context.Context, store storage.Storage, key string, metadata map[string]string) error {
+	reader, err := store.Download(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to read stored archive: %w", err)
+	}
+	// Read it whole first; the upload replaces the object being read
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read stored archive: %w", err)
+	}
+
+	if err := store.Upload(ctx, key, bytes.NewReader(data), "application/gzip", metadata); err != nil {
+		return fmt.Errorf("storage upload failed: %w", err)
+	}
+	return nil
+}
+
+// ensureStored re-uploads a deduplicated archive that ReleaseArchive deleted
+// while it had no references, between storeArchive finding or uploading it
+// and the caller recording its reference. Call it once the reference is
+// recorded, with the arguments given to storeArchive. It returns the size of
+// the stored object, which differs from stored.Size when it was re-uploaded.
+func ensureStored(ctx context.Context, store storage.Storage, stored *storedArchive, open func() (io.ReadCloser, error), size int64, metadata map[string]string) (int64, error) {
+	if !stored.ContentHash.Valid {
+		return stored.Size, nil
+	}
+	exists, err := store.Exists(ctx, stored.Key)
+	if err != nil {
+		return 0, fmt.Errorf("storage check failed: %w", err)
+	}
+	if exists {
+		return stored.Size, nil
+	}
+
+	reader, err := open()
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	if err := store.Upload(ctx, stored.Key, reader, "application/gzip", metadata); err != nil {
+		return 0, fmt.Errorf("storage upload failed: %w", err)
+	}
+	return size, nil
+}
+
+// recordStoredSize updates a module's recorded size to that of the object it
+// references, when ensureStored re-uploaded it with different bytes
+func recordStoredSize(ctx context.Context, moduleRepo *database.ModuleRepository, m *database.Module, size int64) error {
+	if m.SizeBytes == size {
+		return nil
+	}
+	m.SizeBytes = size
+	return moduleRepo.UpdateArchive(ctx, m)
+}
+
+// archivePinned reports whether a stored archive is marked pinned. An
+// archive whose metadata cannot be read is treated as unpinned.
+func archivePinned(ctx context.Context, store storage.Storage, key string) bool {
+	metadata, err := store.GetMetadata(ctx, key)
+	return err == nil && metadata[storage.MetadataPinned] == "true"
+}
+
+// ReleaseArchive deletes a module archive from storage once no module
+// version references it any more, and reports whether it was deleted.
+// Call it after removing the version's record.
+func ReleaseArchive(ctx context.Context, store storage.Storage, moduleRepo *database.ModuleRepository, key string) (bool, error) {
+	refs, err := moduleRepo.CountByS3Key(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if refs > 0 {
+		return false, nil
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		return false, err
+	}
+	return true, nil
+}
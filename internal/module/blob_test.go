@@ -0,0 +1,216 @@
+package module
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recompress returns tarball gzip-compressed again with a different header
+func recompress(t *testing.T, tarball []byte, modTime time.Time) []byte {
+	t.Helper()
+	gzr, err := gzip.NewReader(bytes.NewReader(tarball))
+	require.NoError(t, err)
+	raw, err := io.ReadAll(gzr)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	gzw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	require.NoError(t, err)
+	gzw.ModTime = modTime
+	_, err = gzw.Write(raw)
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+	return buf.Bytes()
+}
+
+func TestContentHash(t *testing.T) {
+	tarball := createTestTarball(t, map[string]string{"main.tf": `variable "x" {}`})
+	other := createTestTarball(t, map[string]string{"main.tf": `variable "y" {}`})
+	recompressed := recompress(t, tarball, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NotEqual(t, tarball, recompressed)
+
+	hash, err := ContentHash(bytes.NewReader(tarball))
+	require.NoError(t, err)
+	assert.Len(t, hash, 64)
+
+	same, err := ContentHash(bytes.NewReader(recompressed))
+	require.NoError(t, err)
+	assert.Equal(t, hash, same, "only the compression differs")
+
+	different, err := ContentHash(bytes.NewReader(other))
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, different)
+
+	plain, err := ContentHash(bytes.NewReader([]byte("not gzip")))
+	require.NoError(t, err)
+	sum := sha256.Sum256([]byte("not gzip"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), plain, "input that is not gzip is hashed as is")
+}
+
+func TestService_DeduplicatesArchives(t *testing.T) {
+	db, err := database.New(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := storage.NewMockStorage()
+	tarball := createTestTarball(t, map[string]string{"main.tf": `variable "x" {}`})
+
+	service := NewService(store, db, "")
+	service.SetDeduplication(true)
+	service.SetRegistry(&staticRegistry{data: tarball})
+	ctx := context.Background()
+	moduleRepo := database.NewModuleRepository(db)
+
+	// Two versions with identical content, one downloaded and one imported
+	// with a different gzip header
+	result := service.LoadSingleModule(ctx, "hashicorp", "consul", "aws", "1.0.0")
+	require.True(t, result.Success, "load failed: %v", result.Error)
+	imported, err := service.ImportModule(ctx, "hashicorp", "consul", "aws", "1.0.1",
+		recompress(t, tarball, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)), false)
+	require.NoError(t, err)
+
+	first, err := moduleRepo.GetByIdentity(ctx, "hashicorp", "consul", "aws", "1.0.0")
+	require.NoError(t, err)
+	require.True(t, first.ContentHash.Valid)
+	assert.Equal(t, storage.BuildModuleBlobKey(first.ContentHash.String), first.S3Key)
+	assert.Equal(t, first.S3Key, imported.S3Key)
+	assert.Equal(t, first.ContentHash, imported.ContentHash)
+
+	keys, err := store.ListObjects(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{first.S3Key}, keys, "identical archives are stored once")
+
+	// Deleting one version keeps the archive the other still references
+	require.NoError(t, service.DeleteModule(ctx, first.ID))
+	exists, err := store.Exists(ctx, imported.S3Key)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	require.NoError(t, service.DeleteModule(ctx, imported.ID))
+	exists, err = store.Exists(ctx, imported.S3Key)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestService_DeduplicatedArchiveSize(t *testing.T) {
+	db, err := database.New(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := storage.NewMockStorage()
+	tarball := createTestTarball(t, map[string]string{"main.tf": `variable "x" {}`})
+	recompressed := recompress(t, tarball, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NotEqual(t, len(tarball), len(recompressed))
+
+	registry := &staticRegistry{data: tarball}
+	service := NewService(store, db, "")
+	service.SetDeduplication(true)
+	service.SetRegistry(registry)
+	ctx := context.Background()
+	moduleRepo := database.NewModuleRepository(db)
+
+	// The second version has the same tar content in different gzip output
+	result := service.LoadSingleModule(ctx, "hashicorp", "consul", "aws", "1.0.0")
+	require.True(t, result.Success, "load failed: %v", result.Error)
+	registry.data = recompressed
+	result = service.LoadSingleModule(ctx, "hashicorp", "consul", "aws", "1.0.1")
+	require.True(t, result.Success, "load failed: %v", result.Error)
+
+	first, err := moduleRepo.GetByIdentity(ctx, "hashicorp", "consul", "aws", "1.0.0")
+	require.NoError(t, err)
+	second, err := moduleRepo.GetByIdentity(ctx, "hashicorp", "consul", "aws", "1.0.1")
+	require.NoError(t, err)
+	require.Equal(t, first.S3Key, second.S3Key)
+
+	size, err := store.GetObjectSize(ctx, first.S3Key)
+	require.NoError(t, err)
+	assert.Equal(t, size, first.SizeBytes)
+	assert.Equal(t, size, second.SizeBytes, "the record reports the shared object's size")
+}
+
+func TestService_DeduplicationOff(t *testing.T) {
+	db, err := database.New(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := storage.NewMockStorage()
+	tarball := createTestTarball(t, map[string]string{"main.tf": `variable "x" {}`})
+	service := NewService(store, db, "")
+	ctx := context.Background()
+
+	for _, version := range []string{"1.0.0", "1.0.1"} {
+		m, err := service.ImportModule(ctx, "hashicorp", "consul", "aws", version, tarball, false)
+		require.NoError(t, err)
+		assert.False(t, m.ContentHash.Valid)
+		assert.Equal(t, "modules/hashicorp/consul/aws/"+version+"/hashicorp-consul-aws-"+version+".tar.gz", m.S3Key)
+	}
+
+	keys, err := store.ListObjects(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+}
+
+func TestStoreArchive_PinsSharedArchive(t *testing.T) {
+	store := storage.NewMockStorage()
+	tarball := createTestTarball(t, map[string]string{"main.tf": `variable "x" {}`})
+	ctx := context.Background()
+
+	// First stored by auto-download, unpinned
+	unpinned := storage.ModuleMetadata("hashicorp", "consul", "aws", "1.0.0", "a.tar.gz", "", false)
+	stored, err := storeArchive(ctx, store, true, "unused", openBytes(tarball), int64(len(tarball)), unpinned)
+	require.NoError(t, err)
+	assert.True(t, stored.Uploaded)
+
+	again, err := storeArchive(ctx, store, true, "unused", openBytes(tarball), int64(len(tarball)), unpinned)
+	require.NoError(t, err)
+	assert.False(t, again.Uploaded, "an identical archive is not uploaded again")
+
+	// An explicitly requested version pins the shared archive, keeping the
+	// bytes the other version's record describes
+	recompressed := recompress(t, tarball, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	pinned := storage.ModuleMetadata("hashicorp", "consul", "aws", "1.0.1", "b.tar.gz", "", true)
+	again, err = storeArchive(ctx, store, true, "unused", openBytes(recompressed), int64(len(recompressed)), pinned)
+	require.NoError(t, err)
+	assert.True(t, again.Uploaded)
+	assert.Equal(t, int64(len(tarball)), again.Size)
+	metadata, err := store.GetMetadata(ctx, stored.Key)
+	require.NoError(t, err)
+	assert.Equal(t, "true", metadata[storage.MetadataPinned])
+	data, ok := store.GetData(stored.Key)
+	require.True(t, ok)
+	assert.Equal(t, tarball, data)
+}
+
+func TestEnsureStored_ReuploadsReleasedArchive(t *testing.T) {
+	store := storage.NewMockStorage()
+	tarball := createTestTarball(t, map[string]string{"main.tf": `variable "x" {}`})
+	ctx := context.Background()
+	metadata := storage.ModuleMetadata("hashicorp", "consul", "aws", "1.0.0", "a.tar.gz", "", true)
+
+	_, err := storeArchive(ctx, store, true, "unused", openBytes(tarball), int64(len(tarball)), metadata)
+	require.NoError(t, err)
+	stored, err := storeArchive(ctx, store, true, "unused", openBytes(tarball), int64(len(tarball)), metadata)
+	require.NoError(t, err)
+	require.False(t, stored.Uploaded)
+
+	// The version that shared the archive is deleted before this one is recorded
+	require.NoError(t, store.Delete(ctx, stored.Key))
+
+	size, err := ensureStored(ctx, store, stored, openBytes(tarball), int64(len(tarball)), metadata)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(tarball)), size)
+	exists, err := store.Exists(ctx, stored.Key)
+	require.NoError(t, err)
+	assert.True(t, exists, "the released archive is uploaded again")
+}
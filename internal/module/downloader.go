@@ -1,12 +1,19 @@
 package module
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/storage"
 )
 
 const (
@@ -40,6 +47,9 @@ type RegistryClient struct {
 	httpClient       *http.Client
 	upstreamRegistry string
 	gitDownloader    *GitDownloader
+	retryAttempts    int
+	retryDelay       time.Duration
+	maxDownloadSize  int64 // Caps a module download in bytes; 0 means unlimited
 }
 
 // NewRegistryClient creates a new Module Registry API client
@@ -58,7 +68,40 @@ func NewRegistryClient(upstreamRegistry string) *RegistryClient {
 		},
 		upstreamRegistry: upstreamRegistry,
 		gitDownloader:    NewGitDownloader(),
+		retryAttempts:    MaxRetries,
+		retryDelay:       RetryDelay,
+	}
+}
+
+// SetRetryPolicy sets the maximum number of attempts for a module download,
+// including resumed attempts after a dropped connection, and the initial
+// backoff delay. Non-positive values keep the defaults.
+func (c *RegistryClient) SetRetryPolicy(attempts int, initialDelay time.Duration) {
+	if attempts > 0 {
+		c.retryAttempts = attempts
+	}
+	if initialDelay > 0 {
+		c.retryDelay = initialDelay
+	}
+}
+
+// SetMaxDownloadSize caps the size of module downloads in bytes. Larger
+// downloads are aborted; zero or less means unlimited.
+func (c *RegistryClient) SetMaxDownloadSize(limit int64) {
+	c.maxDownloadSize = limit
+}
+
+// downloadFromGit downloads a git module, enforcing the size limit on the
+// resulting tarball
+func (c *RegistryClient) downloadFromGit(ctx context.Context, downloadURL string) ([]byte, error) {
+	data, err := c.gitDownloader.DownloadFromGit(ctx, downloadURL)
+	if err != nil {
+		return nil, err
+	}
+	if c.maxDownloadSize > 0 && int64(len(data)) > c.maxDownloadSize {
+		return nil, storage.SizeLimitError(c.maxDownloadSize)
 	}
+	return data, nil
 }
 
 // ModuleDownloadInfo contains information about a module download
@@ -185,68 +228,226 @@ func (c *RegistryClient) GetDownloadURL(ctx context.Context, namespace, name, sy
 func (c *RegistryClient) DownloadModule(ctx context.Context, downloadURL string) ([]byte, error) {
 	// Check if this is a Git URL
 	if IsGitURL(downloadURL) {
-		return c.gitDownloader.DownloadFromGit(ctx, downloadURL)
+		return c.downloadFromGit(ctx, downloadURL)
+	}
+
+	path, _, err := c.downloadToFile(ctx, downloadURL)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read download: %w", err)
 	}
 
-	// Handle HTTP/HTTPS download
+	return data, nil
+}
+
+// downloadToFile streams an HTTP download into a temporary file and returns
+// its path and size. If the transfer is interrupted and the server advertised
+// "Accept-Ranges: bytes", the next attempt resumes from the last byte received
+// using a Range request; otherwise it starts over. The caller must remove the file.
+func (c *RegistryClient) downloadToFile(ctx context.Context, downloadURL string) (string, int64, error) {
 	// Create a new client without redirect restriction for actual download
 	downloadClient := &http.Client{
 		Timeout: DownloadTimeout,
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	f, err := os.CreateTemp("", "tf-module-download-*.tar.gz")
 	if err != nil {
-		return nil, fmt.Errorf("failed to create download request: %w", err)
+		return "", 0, fmt.Errorf("failed to create temp file: %w", err)
 	}
+	path := f.Name()
+	defer f.Close()
 
-	// Execute with retries
-	var resp *http.Response
+	var written int64
+	total := int64(-1)
+	resumable := false
 	var lastErr error
 
-	for attempt := 0; attempt < MaxRetries; attempt++ {
+	for attempt := 0; attempt < c.retryAttempts; attempt++ {
 		if attempt > 0 {
 			// Exponential backoff
-			delay := RetryDelay * time.Duration(1<<uint(attempt-1))
+			delay := c.retryDelay * time.Duration(1<<uint(attempt-1))
 			select {
 			case <-time.After(delay):
 			case <-ctx.Done():
-				return nil, ctx.Err()
+				os.Remove(path)
+				return "", 0, ctx.Err()
 			}
 		}
 
-		resp, lastErr = downloadClient.Do(req)
-		if lastErr == nil {
-			break
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+		if err != nil {
+			os.Remove(path)
+			return "", 0, fmt.Errorf("failed to create download request: %w", err)
+		}
+		if written > 0 && resumable {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", written))
 		}
-	}
 
-	if lastErr != nil {
-		return nil, fmt.Errorf("download failed after %d attempts: %w", MaxRetries, lastErr)
+		resp, err := downloadClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			// Full content; discard anything from an earlier attempt
+			if written > 0 {
+				if err := resetFile(f); err != nil {
+					resp.Body.Close()
+					os.Remove(path)
+					return "", 0, err
+				}
+				written = 0
+			}
+			resumable = resp.Header.Get("Accept-Ranges") == "bytes"
+			total = resp.ContentLength
+			if c.maxDownloadSize > 0 && total > c.maxDownloadSize {
+				resp.Body.Close()
+				os.Remove(path)
+				return "", 0, storage.SizeLimitError(c.maxDownloadSize)
+			}
+		case http.StatusPartialContent:
+			start, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+			if err != nil || start != written {
+				// Unusable partial response; start over on the next attempt
+				resp.Body.Close()
+				resumable = false
+				lastErr = fmt.Errorf("unexpected partial response %q", resp.Header.Get("Content-Range"))
+				continue
+			}
+			if size >= 0 {
+				total = size
+			}
+		default:
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			os.Remove(path)
+			return "", 0, fmt.Errorf("download returned status %d: %s", resp.StatusCode, string(body))
+		}
+
+		// The limit covers the whole module, including earlier partial attempts
+		var n int64
+		var copyErr error
+		switch {
+		case c.maxDownloadSize <= 0:
+			n, copyErr = io.Copy(f, resp.Body)
+		case written >= c.maxDownloadSize:
+			// Already at the limit with the module still incomplete
+			copyErr = storage.ErrSizeLimitExceeded
+		default:
+			n, copyErr = io.Copy(f, storage.LimitReader(resp.Body, c.maxDownloadSize-written))
+		}
+		resp.Body.Close()
+		written += n
+
+		// An oversized module will not shrink on retry
+		if errors.Is(copyErr, storage.ErrSizeLimitExceeded) {
+			os.Remove(path)
+			return "", 0, storage.SizeLimitError(c.maxDownloadSize)
+		}
+
+		if copyErr == nil && (total < 0 || written == total) {
+			return path, written, nil
+		}
+		if copyErr == nil {
+			copyErr = fmt.Errorf("incomplete download: received %d of %d bytes", written, total)
+		}
+		lastErr = copyErr
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("download returned status %d: %s", resp.StatusCode, string(body))
+	os.Remove(path)
+	return "", 0, fmt.Errorf("download failed after %d attempts: %w", c.retryAttempts, lastErr)
+}
+
+// resetFile truncates f and rewinds it to the beginning
+func resetFile(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to reset download file: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to reset download file: %w", err)
 	}
+	return nil
+}
 
-	// Read the entire file
-	data, err := io.ReadAll(resp.Body)
+// parseContentRange parses a "bytes start-end/size" Content-Range header.
+// size is -1 when the complete length is unknown ("*").
+func parseContentRange(header string) (start, size int64, err error) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	rng, sizeStr, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	startStr, _, ok := strings.Cut(rng, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	start, err = strconv.ParseInt(startStr, 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read download: %w", err)
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
 	}
-
-	return data, nil
+	size = -1
+	if sizeStr != "*" {
+		size, err = strconv.ParseInt(sizeStr, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid Content-Range %q", header)
+		}
+	}
+	return start, size, nil
 }
 
-// DownloadResult represents the result of downloading a module
+// DownloadResult represents the result of downloading a module.
+// HTTP downloads are spooled to a temporary file at Path instead of being
+// held in Data; call Cleanup when done with the result.
 type DownloadResult struct {
 	Info     *ModuleDownloadInfo
 	Data     []byte
+	Path     string
+	Size     int64 // Size of the file at Path
 	Error    error
 	Duration time.Duration
 }
 
+// Open returns a reader over the downloaded tarball
+func (r *DownloadResult) Open() (io.ReadCloser, error) {
+	if r.Path != "" {
+		return os.Open(r.Path)
+	}
+	return io.NopCloser(bytes.NewReader(r.Data)), nil
+}
+
+// Bytes returns the downloaded tarball, reading it from disk if needed
+func (r *DownloadResult) Bytes() ([]byte, error) {
+	if r.Path != "" {
+		return os.ReadFile(r.Path)
+	}
+	return r.Data, nil
+}
+
+// Len returns the size of the downloaded tarball in bytes
+func (r *DownloadResult) Len() int64 {
+	if r.Path != "" {
+		return r.Size
+	}
+	return int64(len(r.Data))
+}
+
+// Cleanup removes the temporary file backing the result, if any
+func (r *DownloadResult) Cleanup() {
+	if r.Path != "" {
+		os.Remove(r.Path)
+	}
+}
+
 // DownloadModuleComplete performs the complete download workflow:
 // 1. Get download URL from registry
 // 2. Download the module tarball
@@ -271,14 +472,25 @@ func (c *RegistryClient) DownloadModuleComplete(ctx context.Context, namespace,
 	result.Info.DownloadURL = downloadURL
 	result.Info.Filename = fmt.Sprintf("%s-%s-%s-%s.tar.gz", namespace, name, system, version)
 
-	// Download module
-	data, err := c.DownloadModule(ctx, downloadURL)
-	if err != nil {
-		result.Error = fmt.Errorf("failed to download module: %w", err)
-		result.Duration = time.Since(start)
-		return result
+	// Download module; HTTP downloads are streamed to disk so they can be resumed
+	if IsGitURL(downloadURL) {
+		data, err := c.downloadFromGit(ctx, downloadURL)
+		if err != nil {
+			result.Error = fmt.Errorf("failed to download module: %w", err)
+			result.Duration = time.Since(start)
+			return result
+		}
+		result.Data = data
+	} else {
+		path, size, err := c.downloadToFile(ctx, downloadURL)
+		if err != nil {
+			result.Error = fmt.Errorf("failed to download module: %w", err)
+			result.Duration = time.Since(start)
+			return result
+		}
+		result.Path = path
+		result.Size = size
 	}
-	result.Data = data
 
 	result.Duration = time.Since(start)
 	return result
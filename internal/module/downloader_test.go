@@ -0,0 +1,226 @@
+package module
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyServer serves content but drops the connection partway through the
+// first response, recording the Range header of every request
+type flakyServer struct {
+	content     []byte
+	acceptRange bool
+	dropAfter   int
+
+	mu     sync.Mutex
+	ranges []string
+}
+
+func (f *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.ranges = append(f.ranges, r.Header.Get("Range"))
+	first := len(f.ranges) == 1
+	f.mu.Unlock()
+
+	if f.acceptRange {
+		w.Header().Set("Accept-Ranges", "bytes")
+	}
+
+	if first {
+		// Promise the full body, send part of it, then drop the connection
+		w.Header().Set("Content-Length", strconv.Itoa(len(f.content)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(f.content[:f.dropAfter])
+		w.(http.Flusher).Flush()
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+		return
+	}
+
+	if f.acceptRange {
+		http.ServeContent(w, r, "module.tar.gz", time.Time{}, bytes.NewReader(f.content))
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(f.content)))
+	w.Write(f.content)
+}
+
+func testContent(size int) []byte {
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	return content
+}
+
+func TestDownloadModule_ResumesAfterDroppedConnection(t *testing.T) {
+	content := testContent(256 * 1024)
+	fs := &flakyServer{content: content, acceptRange: true, dropAfter: 100 * 1024}
+	server := httptest.NewServer(fs)
+	defer server.Close()
+
+	client := NewRegistryClient("")
+	client.SetRetryPolicy(3, time.Millisecond)
+
+	data, err := client.DownloadModule(context.Background(), server.URL+"/module.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	// The second request resumed from where the first one stopped
+	require.Len(t, fs.ranges, 2)
+	assert.Equal(t, "", fs.ranges[0])
+	assert.Equal(t, fmt.Sprintf("bytes=%d-", fs.dropAfter), fs.ranges[1])
+}
+
+func TestDownloadModule_RestartsWithoutRangeSupport(t *testing.T) {
+	content := testContent(64 * 1024)
+	fs := &flakyServer{content: content, acceptRange: false, dropAfter: 10 * 1024}
+	server := httptest.NewServer(fs)
+	defer server.Close()
+
+	client := NewRegistryClient("")
+	client.SetRetryPolicy(3, time.Millisecond)
+
+	data, err := client.DownloadModule(context.Background(), server.URL+"/module.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	// Without Accept-Ranges the download starts over
+	require.Len(t, fs.ranges, 2)
+	assert.Equal(t, "", fs.ranges[1])
+}
+
+func TestDownloadModule_GivesUpAfterRetryAttempts(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Length", "1024")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	client := NewRegistryClient("")
+	client.SetRetryPolicy(2, time.Millisecond)
+
+	_, err := client.DownloadModule(context.Background(), server.URL+"/module.tar.gz")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "download failed after 2 attempts")
+	assert.Equal(t, 2, requests)
+}
+
+func TestDownloadModule_SizeLimit(t *testing.T) {
+	content := testContent(64 * 1024)
+
+	for _, announced := range []bool{true, false} {
+		t.Run(fmt.Sprintf("announced=%v", announced), func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.Header().Set("Accept-Ranges", "bytes")
+				if announced {
+					w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+				}
+				for i := 0; i < len(content); i += 4096 {
+					if _, err := w.Write(content[i : i+4096]); err != nil {
+						return
+					}
+					w.(http.Flusher).Flush()
+				}
+			}))
+			defer server.Close()
+
+			client := NewRegistryClient("")
+			client.SetRetryPolicy(3, time.Millisecond)
+			client.SetMaxDownloadSize(16 * 1024)
+
+			data, err := client.DownloadModule(context.Background(), server.URL+"/module.tar.gz")
+			require.ErrorIs(t, err, storage.ErrSizeLimitExceeded)
+			assert.Nil(t, data)
+
+			// An oversized module is not retried
+			assert.Equal(t, 1, requests)
+		})
+	}
+
+	t.Run("within limit", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(content)
+		}))
+		defer server.Close()
+
+		client := NewRegistryClient("")
+		client.SetMaxDownloadSize(int64(len(content)))
+
+		data, err := client.DownloadModule(context.Background(), server.URL+"/module.tar.gz")
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+	})
+}
+
+func TestDownloadModuleComplete_StreamsToFile(t *testing.T) {
+	content := testContent(32 * 1024)
+	fs := &flakyServer{content: content, acceptRange: true, dropAfter: 8 * 1024}
+	files := httptest.NewServer(fs)
+	defer files.Close()
+
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Terraform-Get", files.URL+"/module.tar.gz")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer registry.Close()
+
+	client := NewRegistryClient(registry.Listener.Addr().String())
+	client.httpClient.Transport = registry.Client().Transport
+	client.SetRetryPolicy(3, time.Millisecond)
+
+	result := client.DownloadModuleComplete(context.Background(), "acme", "vpc", "aws", "1.0.0")
+	require.NoError(t, result.Error)
+	defer result.Cleanup()
+
+	assert.Nil(t, result.Data)
+	assert.NotEmpty(t, result.Path)
+	assert.Equal(t, int64(len(content)), result.Len())
+
+	data, err := result.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	result.Cleanup()
+	_, err = os.Stat(result.Path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestParseContentRange(t *testing.T) {
+	start, size, err := parseContentRange("bytes 100-199/200")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), start)
+	assert.Equal(t, int64(200), size)
+
+	start, size, err = parseContentRange("bytes 5-9/*")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), start)
+	assert.Equal(t, int64(-1), size)
+
+	_, _, err = parseContentRange("items 0-1/2")
+	assert.Error(t, err)
+}
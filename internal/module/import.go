@@ -0,0 +1,128 @@
+package module
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+)
+
+// ErrModuleExists is returned when importing a module version that is already mirrored
+var ErrModuleExists = errors.New("module version already exists")
+
+// ValidateModuleIdentity checks the namespace, name, system, and version of a module
+func ValidateModuleIdentity(namespace, name, system, version string) error {
+	if !moduleSourceRegex.MatchString(namespace + "/" + name + "/" + system) {
+		return fmt.Errorf("invalid module address %q, expected 'namespace/name/system'", namespace+"/"+name+"/"+system)
+	}
+	if !moduleVersionRegex.MatchString(version) {
+		return fmt.Errorf("invalid version format %q, expected semantic version (e.g., 1.2.3)", version)
+	}
+	return nil
+}
+
+// ValidateTarball checks that data is a readable gzipped tar archive with at
+// least one regular file and no entries that escape the archive root
+func ValidateTarball(data []byte) error {
+	gzr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("not a gzip archive: %w", err)
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	files := 0
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar entry: %w", err)
+		}
+
+		name := path.Clean(strings.ReplaceAll(header.Name, "\\", "/"))
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("tar entry %q escapes the archive root", header.Name)
+		}
+
+		if header.Typeflag == tar.TypeReg {
+			files++
+		}
+	}
+
+	if files == 0 {
+		return fmt.Errorf("archive contains no files")
+	}
+	return nil
+}
+
+// ImportModule stores a module tarball supplied directly rather than downloaded
+// from the upstream registry, using the same key layout as downloaded modules.
+// Callers validate the identity and tarball first. Module sources are
+// rewritten when rewrite is set and a mirror hostname is configured.
+// ErrModuleExists is returned if the version is already mirrored.
+func (s *Service) ImportModule(ctx context.Context, namespace, name, system, version string, tarball []byte, rewrite bool) (*database.Module, error) {
+	moduleRepo := database.NewModuleRepository(s.db)
+
+	existing, err := moduleRepo.GetByIdentity(ctx, namespace, name, system, version)
+	if err != nil {
+		return nil, fmt.Errorf("database check failed: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrModuleExists
+	}
+
+	data := tarball
+	if rewrite {
+		data, err = s.rewriter.RewriteModule(tarball)
+		if err != nil {
+			return nil, fmt.Errorf("source rewriting failed: %w", err)
+		}
+	}
+
+	filename := fmt.Sprintf("%s-%s-%s-%s.tar.gz", namespace, name, system, version)
+	s3Key := s.buildS3Key(namespace, name, system, version, filename)
+
+	metadata := storage.ModuleMetadata(namespace, name, system, version, filename, "", true)
+	stored, err := storeArchive(ctx, s.storage, s.dedup, s3Key, openBytes(data), int64(len(data)), metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	module := &database.Module{
+		Namespace:   namespace,
+		Name:        name,
+		System:      system,
+		Version:     version,
+		S3Key:       stored.Key,
+		Filename:    filename,
+		SizeBytes:   stored.Size,
+		ContentHash: stored.ContentHash,
+	}
+
+	if err := moduleRepo.Create(ctx, module); err != nil {
+		// Try to clean up the storage upload, unless another version shares it
+		if stored.Uploaded {
+			_, _ = ReleaseArchive(ctx, s.storage, moduleRepo, stored.Key)
+		}
+		return nil, fmt.Errorf("database save failed: %w", err)
+	}
+	storedSize, err := ensureStored(ctx, s.storage, stored, openBytes(data), int64(len(data)), metadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordStoredSize(ctx, moduleRepo, module, storedSize); err != nil {
+		return nil, err
+	}
+
+	return module, nil
+}
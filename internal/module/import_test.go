@@ -0,0 +1,44 @@
+package module
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTarball(t *testing.T) {
+	t.Run("valid module", func(t *testing.T) {
+		data := createTestTarball(t, map[string]string{"main.tf": `variable "x" {}`})
+		assert.NoError(t, ValidateTarball(data))
+	})
+
+	t.Run("not gzip", func(t *testing.T) {
+		assert.Error(t, ValidateTarball([]byte("plain text")))
+	})
+
+	t.Run("no files", func(t *testing.T) {
+		var buf bytes.Buffer
+		gzw := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gzw)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "empty/", Typeflag: tar.TypeDir, Mode: 0755}))
+		require.NoError(t, tw.Close())
+		require.NoError(t, gzw.Close())
+		assert.ErrorContains(t, ValidateTarball(buf.Bytes()), "no files")
+	})
+
+	t.Run("path traversal", func(t *testing.T) {
+		data := createTestTarball(t, map[string]string{"../escape.tf": "x"})
+		assert.ErrorContains(t, ValidateTarball(data), "escapes")
+	})
+}
+
+func TestValidateModuleIdentity(t *testing.T) {
+	assert.NoError(t, ValidateModuleIdentity("hashicorp", "consul", "aws", "0.1.0"))
+	assert.Error(t, ValidateModuleIdentity("hashicorp", "", "aws", "0.1.0"))
+	assert.Error(t, ValidateModuleIdentity("hashicorp", "consul", "a/ws", "0.1.0"))
+	assert.Error(t, ValidateModuleIdentity("hashicorp", "consul", "aws", "v1"))
+}
@@ -354,7 +354,7 @@ func TestIntegration_ModuleServiceLoadFromDefinitions(t *testing.T) {
 
 	// Verify all modules in database
 	moduleRepo := database.NewModuleRepository(db)
-	modules, err := moduleRepo.List(context.Background(), 100, 0)
+	modules, err := moduleRepo.List(context.Background(), database.ModuleFilter{}, 100, 0)
 	if err != nil {
 		t.Fatalf("Failed to list modules: %v", err)
 	}
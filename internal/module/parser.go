@@ -5,7 +5,10 @@ import (
 	"regexp"
 	"strings"
 
-	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/ned1313/terraform-mirror/internal/definition"
 )
 
 // ModuleDefinition represents a parsed module definition from HCL
@@ -29,51 +32,70 @@ type hclModuleConfig struct {
 
 // hclModule represents a single module block in HCL
 type hclModule struct {
-	Source   string   `hcl:"source,label"`
-	Versions []string `hcl:"versions"`
+	Source        string    `hcl:"source,label"`
+	Versions      []string  `hcl:"versions,optional"`
+	SourceRange   hcl.Range `hcl:"source,label_range"`
+	VersionsRange hcl.Range `hcl:"versions,attr_range"`
+	DefRange      hcl.Range `hcl:",def_range"`
 }
 
+// moduleFilename names the definition file in problem positions
+const moduleFilename = "modules.hcl"
+
 var (
 	// moduleSourceRegex validates module source format (namespace/name/system)
 	moduleSourceRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*/[a-zA-Z0-9][a-zA-Z0-9_-]*/[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
 
+	// moduleKeyPartRegex validates each part of a module key (namespace/name/system)
+	moduleKeyPartRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
 	// moduleVersionRegex validates semantic version format for modules
 	moduleVersionRegex = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+(-[a-zA-Z0-9.]+)?(\+[a-zA-Z0-9.]+)?$`)
 )
 
-// ParseModuleHCL parses a module definition HCL file
+// ParseModuleHCL parses a module definition HCL file. Syntax errors stop
+// parsing; otherwise every problem in the file (unknown attributes, bad
+// module keys, missing or malformed versions, duplicates) is reported
+// together in a *definition.Error.
 func ParseModuleHCL(content []byte) (*ModuleDefinitions, error) {
-	var hclConfig hclModuleConfig
-
-	// Parse HCL
-	if err := hclsimple.Decode("modules.hcl", content, nil, &hclConfig); err != nil {
-		return nil, fmt.Errorf("failed to parse HCL: %w", err)
+	file, diags := hclparse.NewParser().ParseHCL(content, moduleFilename)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse HCL: %w", diags)
 	}
 
+	var hclConfig hclModuleConfig
+	problems := &definition.Error{}
+	problems.AddDiagnostics(gohcl.DecodeBody(file.Body, nil, &hclConfig))
+
 	// Convert to ModuleDefinitions
 	defs := &ModuleDefinitions{
 		Modules: make([]*ModuleDefinition, 0, len(hclConfig.Modules)),
 	}
 
 	// Track seen modules to detect duplicates
-	seen := make(map[string]bool)
+	seen := make(map[string]hcl.Range)
 
-	for _, m := range hclConfig.Modules {
-		// Validate and parse
-		def, err := parseModule(&m)
-		if err != nil {
-			return nil, fmt.Errorf("module %q: %w", m.Source, err)
+	for i := range hclConfig.Modules {
+		m := &hclConfig.Modules[i]
+		def := parseModule(m, problems)
+		if def == nil {
+			continue
 		}
 
 		// Check for duplicates
-		if seen[def.Source] {
-			return nil, fmt.Errorf("duplicate module definition: %q", def.Source)
+		if first, ok := seen[def.Source]; ok {
+			problems.Add(&m.SourceRange, "duplicate module definition: %q (first defined on line %d)", def.Source, first.Start.Line)
+			continue
 		}
-		seen[def.Source] = true
+		seen[def.Source] = m.SourceRange
 
 		defs.Modules = append(defs.Modules, def)
 	}
 
+	if err := problems.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
 	if len(defs.Modules) == 0 {
 		return nil, fmt.Errorf("no module definitions found")
 	}
@@ -81,40 +103,53 @@ func ParseModuleHCL(content []byte) (*ModuleDefinitions, error) {
 	return defs, nil
 }
 
-// parseModule validates and converts an HCL module block
-func parseModule(m *hclModule) (*ModuleDefinition, error) {
-	// Validate source format
-	if !moduleSourceRegex.MatchString(m.Source) {
-		return nil, fmt.Errorf("invalid source format, expected 'namespace/name/system'")
+// parseModule validates and converts an HCL module block, recording any
+// problems. It returns nil if the block is invalid.
+func parseModule(m *hclModule, problems *definition.Error) *ModuleDefinition {
+	valid := true
+	fail := func(rng *hcl.Range, format string, args ...interface{}) {
+		problems.Add(rng, "module %q: "+format, append([]interface{}{m.Source}, args...)...)
+		valid = false
 	}
 
-	// Split source into namespace, name, and system
-	parts := strings.SplitN(m.Source, "/", 3)
+	// Validate the key shape: namespace/name/system
+	parts := strings.Split(m.Source, "/")
 	if len(parts) != 3 {
-		return nil, fmt.Errorf("invalid source format, expected 'namespace/name/system'")
+		fail(&m.SourceRange, "invalid source format, expected 'namespace/name/system' (e.g., hashicorp/consul/aws), got %d part(s)", len(parts))
+	} else {
+		for i, part := range parts {
+			if !moduleKeyPartRegex.MatchString(part) {
+				fail(&m.SourceRange, "invalid source format, %s %q must start with a letter or digit and contain only letters, digits, '-' and '_'",
+					[]string{"namespace", "name", "system"}[i], part)
+			}
+		}
 	}
-	namespace := parts[0]
-	name := parts[1]
-	system := parts[2]
 
 	// Validate versions
-	if len(m.Versions) == 0 {
-		return nil, fmt.Errorf("at least one version is required")
+	switch {
+	case m.VersionsRange == (hcl.Range{}):
+		fail(&m.DefRange, "the versions attribute is required, e.g. versions = [\"1.0.0\"]")
+	case len(m.Versions) == 0:
+		fail(&m.VersionsRange, "at least one version is required")
 	}
 
 	for _, v := range m.Versions {
 		if !moduleVersionRegex.MatchString(v) {
-			return nil, fmt.Errorf("invalid version format %q, expected semantic version (e.g., 1.2.3)", v)
+			fail(&m.VersionsRange, "invalid version format %q, expected semantic version (e.g., 1.2.3)", v)
 		}
 	}
 
+	if !valid {
+		return nil
+	}
+
 	return &ModuleDefinition{
 		Source:    m.Source,
-		Namespace: namespace,
-		Name:      name,
-		System:    system,
+		Namespace: parts[0],
+		Name:      parts[1],
+		System:    parts[2],
 		Versions:  m.Versions,
-	}, nil
+	}
 }
 
 // CountItems returns the total number of download items (modules × versions)
@@ -1,7 +1,10 @@
 package module
 
 import (
+	"errors"
 	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/definition"
 )
 
 func TestParseModuleHCL(t *testing.T) {
@@ -217,6 +220,121 @@ func TestModuleSourceValidation(t *testing.T) {
 	}
 }
 
+func TestParseModuleHCL_Problems(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name: "unknown attribute",
+			content: `
+module "hashicorp/consul/aws" {
+  versions = ["0.1.0"]
+  version  = "0.2.0"
+}`,
+			want: `modules.hcl:4,3: Unsupported argument`,
+		},
+		{
+			name: "empty versions list",
+			content: `
+module "hashicorp/consul/aws" {
+  versions = []
+}`,
+			want: `modules.hcl:3,3: module "hashicorp/consul/aws": at least one version is required`,
+		},
+		{
+			name: "missing versions",
+			content: `
+module "hashicorp/consul/aws" {
+}`,
+			want: `modules.hcl:2,1: module "hashicorp/consul/aws": the versions attribute is required`,
+		},
+		{
+			name: "malformed module key",
+			content: `
+module "hashicorp/consul" {
+  versions = ["0.1.0"]
+}`,
+			want: `modules.hcl:2,8: module "hashicorp/consul": invalid source format, expected 'namespace/name/system'`,
+		},
+		{
+			name: "empty key part",
+			content: `
+module "hashicorp//aws" {
+  versions = ["0.1.0"]
+}`,
+			want: `module "hashicorp//aws": invalid source format, name "" must start with a letter or digit`,
+		},
+		{
+			name: "duplicate references first definition",
+			content: `
+module "hashicorp/consul/aws" {
+  versions = ["0.1.0"]
+}
+
+module "hashicorp/consul/aws" {
+  versions = ["0.2.0"]
+}`,
+			want: `modules.hcl:6,8: duplicate module definition: "hashicorp/consul/aws" (first defined on line 2)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseModuleHCL([]byte(tt.content))
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %q", tt.want, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseModuleHCL_ReportsAllProblems(t *testing.T) {
+	content := `
+module "hashicorp/consul" {
+  versions = ["0.1.0"]
+}
+
+module "hashicorp/vault/aws" {
+  versions = []
+  source   = "somewhere"
+}
+
+module "hashicorp/nomad/aws" {
+  versions = ["0.1.0"]
+}`
+
+	_, err := ParseModuleHCL([]byte(content))
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	var defErr *definition.Error
+	if !errors.As(err, &defErr) {
+		t.Fatalf("expected *definition.Error, got %T", err)
+	}
+	if len(defErr.Problems) != 3 {
+		t.Fatalf("expected 3 problems, got %d: %v", len(defErr.Problems), err)
+	}
+
+	lines := map[int]bool{}
+	for _, p := range defErr.Problems {
+		lines[p.Line] = true
+	}
+	for _, line := range []int{2, 7, 8} {
+		if !lines[line] {
+			t.Errorf("expected a problem on line %d, got %v", line, err)
+		}
+	}
+	if !contains(err.Error(), "3 problems found:") {
+		t.Errorf("expected a problem count in %q", err.Error())
+	}
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
 }
@@ -0,0 +1,93 @@
+package module
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+)
+
+// ErrRewriteDisabled is returned when re-applying source rewriting while no
+// mirror hostname is configured
+var ErrRewriteDisabled = errors.New("module source rewriting is disabled: no mirror hostname is configured")
+
+// RewriteResult describes re-applying source rewriting to a stored module
+type RewriteResult struct {
+	Module      *database.Module
+	Changed     bool   // false when every source already pointed at the mirror
+	PreviousKey string // storage key the archive was read from
+}
+
+// RewriteStoredModule re-applies the current source rewriter to a module
+// archive already in storage, so a changed mirror hostname or an improved
+// rewriter reaches modules mirrored earlier without downloading them from
+// upstream again. When a source changes the archive is re-uploaded and the
+// record's size and content hash updated; otherwise nothing is written.
+// A content-addressed archive moves to the key for its new content, and the
+// old archive is released once no other version references it.
+func (s *Service) RewriteStoredModule(ctx context.Context, m *database.Module) (*RewriteResult, error) {
+	if !s.rewriter.Enabled() {
+		return nil, ErrRewriteDisabled
+	}
+
+	reader, err := s.storage.Download(ctx, m.S3Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored archive: %w", err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored archive: %w", err)
+	}
+
+	rewritten, changed, err := s.rewriter.rewriteModule(data)
+	if err != nil {
+		return nil, fmt.Errorf("source rewriting failed: %w", err)
+	}
+
+	result := &RewriteResult{Module: m, PreviousKey: m.S3Key}
+	if !changed {
+		return result, nil
+	}
+
+	// A shared archive's metadata may describe another version, so it is
+	// rebuilt for this one, keeping whether it was explicitly requested
+	pinned := archivePinned(ctx, s.storage, m.S3Key)
+	metadata := storage.ModuleMetadata(m.Namespace, m.Name, m.System, m.Version, m.Filename, m.OriginalSourceURL.String, pinned)
+
+	stored, err := storeArchive(ctx, s.storage, s.dedup || m.ContentHash.Valid, m.S3Key, openBytes(rewritten), int64(len(rewritten)), metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	moduleRepo := database.NewModuleRepository(s.db)
+	updated := *m
+	updated.S3Key = stored.Key
+	updated.SizeBytes = stored.Size
+	updated.ContentHash = stored.ContentHash
+	if err := moduleRepo.UpdateArchive(ctx, &updated); err != nil {
+		if stored.Uploaded && stored.Key != m.S3Key {
+			_, _ = ReleaseArchive(ctx, s.storage, moduleRepo, stored.Key)
+		}
+		return nil, fmt.Errorf("database update failed: %w", err)
+	}
+	storedSize, err := ensureStored(ctx, s.storage, stored, openBytes(rewritten), int64(len(rewritten)), metadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordStoredSize(ctx, moduleRepo, &updated, storedSize); err != nil {
+		return nil, err
+	}
+
+	// An archive left behind here is reported as an orphan by storage verification
+	if stored.Key != m.S3Key {
+		_, _ = ReleaseArchive(ctx, s.storage, moduleRepo, m.S3Key)
+	}
+
+	result.Module = &updated
+	result.Changed = true
+	return result, nil
+}
@@ -0,0 +1,100 @@
+package module
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const nestedModuleTF = `module "vpc" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+`
+
+// storedMainTF returns main.tf from the archive stored at key
+func storedMainTF(t *testing.T, store storage.Storage, key string) string {
+	t.Helper()
+	reader, err := store.Download(context.Background(), key)
+	require.NoError(t, err)
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(extractTestTarball(t, data)["main.tf"])
+}
+
+func TestService_RewriteStoredModule(t *testing.T) {
+	for _, dedup := range []bool{false, true} {
+		name := "per-version keys"
+		if dedup {
+			name = "content-addressed"
+		}
+		t.Run(name, func(t *testing.T) {
+			db, err := database.New(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+			store := storage.NewMockStorage()
+			ctx := context.Background()
+			tarball := createTestTarball(t, map[string]string{"main.tf": nestedModuleTF})
+
+			// Mirrored while the mirror was reachable under its old hostname
+			old := NewService(store, db, "old-mirror.example.com")
+			old.SetDeduplication(dedup)
+			m, err := old.ImportModule(ctx, "acme", "network", "aws", "1.0.0", tarball, true)
+			require.NoError(t, err)
+			require.Contains(t, storedMainTF(t, store, m.S3Key), `"old-mirror.example.com/terraform-aws-modules/vpc/aws"`)
+
+			service := NewService(store, db, "mirror.example.com")
+			service.SetDeduplication(dedup)
+			result, err := service.RewriteStoredModule(ctx, m)
+			require.NoError(t, err)
+			assert.True(t, result.Changed)
+			assert.Equal(t, m.S3Key, result.PreviousKey)
+			assert.Contains(t, storedMainTF(t, store, result.Module.S3Key), `"mirror.example.com/terraform-aws-modules/vpc/aws"`)
+
+			// The record follows the re-uploaded archive
+			saved, err := database.NewModuleRepository(db).GetByID(ctx, m.ID)
+			require.NoError(t, err)
+			assert.Equal(t, result.Module.S3Key, saved.S3Key)
+			assert.Equal(t, result.Module.SizeBytes, saved.SizeBytes)
+			size, err := store.GetObjectSize(ctx, saved.S3Key)
+			require.NoError(t, err)
+			assert.Equal(t, size, saved.SizeBytes)
+
+			keys, err := store.ListObjects(ctx, "")
+			require.NoError(t, err)
+			assert.Equal(t, []string{saved.S3Key}, keys, "the superseded archive is released")
+			if dedup {
+				assert.NotEqual(t, m.S3Key, saved.S3Key)
+				assert.NotEqual(t, m.ContentHash, saved.ContentHash)
+				assert.Equal(t, storage.BuildModuleBlobKey(saved.ContentHash.String), saved.S3Key)
+			} else {
+				assert.Equal(t, m.S3Key, saved.S3Key)
+			}
+
+			metadata, err := store.GetMetadata(ctx, saved.S3Key)
+			require.NoError(t, err)
+			assert.Equal(t, "1.0.0", metadata[storage.MetadataVersion])
+			assert.Equal(t, "true", metadata[storage.MetadataPinned])
+
+			// Sources already pointing at the mirror are left alone
+			again, err := service.RewriteStoredModule(ctx, saved)
+			require.NoError(t, err)
+			assert.False(t, again.Changed)
+			assert.Equal(t, saved.S3Key, again.Module.S3Key)
+		})
+	}
+
+	t.Run("rewriting disabled", func(t *testing.T) {
+		db, err := database.New(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		_, err = NewService(storage.NewMockStorage(), db, "").RewriteStoredModule(context.Background(), &database.Module{S3Key: "modules/x"})
+		assert.ErrorIs(t, err, ErrRewriteDisabled)
+	})
+}
@@ -28,37 +28,50 @@ func NewRewriter(mirrorHostname string) *Rewriter {
 	}
 }
 
+// Enabled reports whether module sources will be rewritten
+func (r *Rewriter) Enabled() bool {
+	return r.mirrorHostname != ""
+}
+
 // RewriteModule extracts a tarball, rewrites remote module sources, and repacks
 func (r *Rewriter) RewriteModule(tarball []byte) ([]byte, error) {
+	data, _, err := r.rewriteModule(tarball)
+	return data, err
+}
+
+// rewriteModule rewrites a tarball like RewriteModule and also reports
+// whether any module source was changed. The tarball is repacked either way.
+func (r *Rewriter) rewriteModule(tarball []byte) ([]byte, bool, error) {
 	if r.mirrorHostname == "" {
 		// No mirror hostname configured, return original tarball
-		return tarball, nil
+		return tarball, false, nil
 	}
 
 	// Create temp directory
 	tempDir, err := os.MkdirTemp("", "tf-module-rewrite-*")
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		return nil, false, fmt.Errorf("failed to create temp directory: %w", err)
 	}
 	defer os.RemoveAll(tempDir)
 
 	// Extract tarball
 	if err := r.extractTarGz(tarball, tempDir); err != nil {
-		return nil, fmt.Errorf("failed to extract tarball: %w", err)
+		return nil, false, fmt.Errorf("failed to extract tarball: %w", err)
 	}
 
 	// Rewrite .tf files
-	if err := r.rewriteTerraformFiles(tempDir); err != nil {
-		return nil, fmt.Errorf("failed to rewrite terraform files: %w", err)
+	changed, err := r.rewriteTerraformFiles(tempDir)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to rewrite terraform files: %w", err)
 	}
 
 	// Repack as tarball
 	repackedData, err := r.createTarGz(tempDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create tarball: %w", err)
+		return nil, false, fmt.Errorf("failed to create tarball: %w", err)
 	}
 
-	return repackedData, nil
+	return repackedData, changed, nil
 }
 
 // extractTarGz extracts a gzipped tarball to the destination directory
@@ -180,9 +193,11 @@ func (r *Rewriter) createTarGz(srcDir string) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// rewriteTerraformFiles finds and rewrites .tf files with remote module sources
-func (r *Rewriter) rewriteTerraformFiles(dir string) error {
-	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+// rewriteTerraformFiles finds and rewrites .tf files with remote module
+// sources, reporting whether any file was changed
+func (r *Rewriter) rewriteTerraformFiles(dir string) (bool, error) {
+	anyChanged := false
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -210,10 +225,12 @@ func (r *Rewriter) rewriteTerraformFiles(dir string) error {
 			if err := os.WriteFile(path, rewritten, info.Mode()); err != nil {
 				return fmt.Errorf("failed to write %s: %w", path, err)
 			}
+			anyChanged = true
 		}
 
 		return nil
 	})
+	return anyChanged, err
 }
 
 // rewriteModuleSources rewrites module source attributes in HCL content
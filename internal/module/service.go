@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"path"
+	"time"
 
 	"github.com/ned1313/terraform-mirror/internal/database"
 	"github.com/ned1313/terraform-mirror/internal/storage"
@@ -18,6 +19,7 @@ type Service struct {
 	rewriter *Rewriter
 	storage  storage.Storage
 	db       *database.DB
+	dedup    bool
 }
 
 // NewService creates a new module service
@@ -45,6 +47,27 @@ func (s *Service) SetRegistry(registry RegistryDownloader) {
 	s.registry = registry
 }
 
+// SetRetryPolicy configures download retries when using the default registry client
+func (s *Service) SetRetryPolicy(attempts int, initialDelay time.Duration) {
+	if client, ok := s.registry.(*RegistryClient); ok {
+		client.SetRetryPolicy(attempts, initialDelay)
+	}
+}
+
+// SetMaxDownloadSize caps module downloads in bytes when using the default
+// registry client; zero or less means unlimited
+func (s *Service) SetMaxDownloadSize(limit int64) {
+	if client, ok := s.registry.(*RegistryClient); ok {
+		client.SetMaxDownloadSize(limit)
+	}
+}
+
+// SetDeduplication stores new module archives content-addressed under blobs/,
+// so versions with identical content share one object
+func (s *Service) SetDeduplication(enabled bool) {
+	s.dedup = enabled
+}
+
 // LoadResult represents the result of loading a single module version
 type LoadResult struct {
 	Namespace string
@@ -64,7 +87,10 @@ func (s *Service) LoadFromDefinitions(ctx context.Context, defs *ModuleDefinitio
 	return s.LoadFromDefinitionsWithProgress(ctx, defs, nil)
 }
 
-// LoadFromDefinitionsWithProgress loads all modules with a progress callback
+// LoadFromDefinitionsWithProgress loads all modules with a progress callback.
+// When ctx is cancelled it stops before the next module and returns ctx.Err()
+// with the results so far. A module interrupted by the cancellation has no
+// result, like the modules that were never started.
 func (s *Service) LoadFromDefinitionsWithProgress(ctx context.Context, defs *ModuleDefinitions, onProgress ProgressCallback) ([]*LoadResult, error) {
 	results := make([]*LoadResult, 0, defs.CountItems())
 
@@ -80,6 +106,9 @@ func (s *Service) LoadFromDefinitionsWithProgress(ctx context.Context, defs *Mod
 			}
 
 			result := s.loadModuleVersion(ctx, moduleRepo, def, version)
+			if !result.Success && ctx.Err() != nil {
+				return results, ctx.Err()
+			}
 			results = append(results, result)
 
 			// Call progress callback if provided
@@ -101,6 +130,11 @@ func (s *Service) loadModuleVersion(ctx context.Context, moduleRepo *database.Mo
 		Version:   version,
 	}
 
+	if err := ctx.Err(); err != nil {
+		result.Error = err
+		return result
+	}
+
 	// Check if already exists
 	existing, err := moduleRepo.GetByIdentity(ctx, def.Namespace, def.Name, def.System, version)
 	if err != nil {
@@ -122,10 +156,18 @@ func (s *Service) loadModuleVersion(ctx context.Context, moduleRepo *database.Mo
 		return result
 	}
 
+	defer downloadResult.Cleanup()
+
+	// Stop before storing if cancelled during the download
+	if err := ctx.Err(); err != nil {
+		result.Error = err
+		return result
+	}
+
 	// Rewrite module sources (if mirror hostname is configured)
-	moduleData, err := s.rewriter.RewriteModule(downloadResult.Data)
+	open, size, err := moduleContent(s.rewriter, downloadResult)
 	if err != nil {
-		result.Error = fmt.Errorf("source rewriting failed: %w", err)
+		result.Error = err
 		return result
 	}
 
@@ -134,21 +176,23 @@ func (s *Service) loadModuleVersion(ctx context.Context, moduleRepo *database.Mo
 	s3Key := s.buildS3Key(def.Namespace, def.Name, def.System, version, filename)
 
 	// Upload to S3
-	reader := bytes.NewReader(moduleData)
-	if err := s.storage.Upload(ctx, s3Key, reader, "application/gzip", nil); err != nil {
-		result.Error = fmt.Errorf("storage upload failed: %w", err)
+	metadata := storage.ModuleMetadata(def.Namespace, def.Name, def.System, version, filename, downloadResult.Info.DownloadURL, true)
+	stored, err := storeArchive(ctx, s.storage, s.dedup, s3Key, open, size, metadata)
+	if err != nil {
+		result.Error = err
 		return result
 	}
 
 	// Save to database
 	module := &database.Module{
-		Namespace: def.Namespace,
-		Name:      def.Name,
-		System:    def.System,
-		Version:   version,
-		S3Key:     s3Key,
-		Filename:  filename,
-		SizeBytes: int64(len(moduleData)),
+		Namespace:   def.Namespace,
+		Name:        def.Name,
+		System:      def.System,
+		Version:     version,
+		S3Key:       stored.Key,
+		Filename:    filename,
+		SizeBytes:   stored.Size,
+		ContentHash: stored.ContentHash,
 		OriginalSourceURL: sql.NullString{
 			String: downloadResult.Info.DownloadURL,
 			Valid:  downloadResult.Info.DownloadURL != "",
@@ -156,17 +200,62 @@ func (s *Service) loadModuleVersion(ctx context.Context, moduleRepo *database.Mo
 	}
 
 	if err := moduleRepo.Create(ctx, module); err != nil {
-		// Try to clean up S3 upload
-		_ = s.storage.Delete(ctx, s3Key)
+		// Try to clean up S3 upload, unless another version shares it
+		if stored.Uploaded {
+			_, _ = ReleaseArchive(ctx, s.storage, moduleRepo, stored.Key)
+		}
 		result.Error = fmt.Errorf("database save failed: %w", err)
 		return result
 	}
+	storedSize, err := ensureStored(ctx, s.storage, stored, open, size, metadata)
+	if err == nil {
+		err = recordStoredSize(ctx, moduleRepo, module, storedSize)
+	}
+	if err != nil {
+		result.Error = err
+		return result
+	}
 
 	// Success!
 	result.Success = true
 	return result
 }
 
+// moduleContent returns a function opening a reader over the tarball to store
+// for a download, rewriting module sources first if configured. When no
+// rewriting is needed the tarball is streamed from the download's temporary file.
+func moduleContent(rewriter *Rewriter, download *DownloadResult) (func() (io.ReadCloser, error), int64, error) {
+	if !rewriter.Enabled() {
+		open := func() (io.ReadCloser, error) {
+			reader, err := download.Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to open download: %w", err)
+			}
+			return reader, nil
+		}
+		return open, download.Len(), nil
+	}
+
+	data, err := download.Bytes()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read download: %w", err)
+	}
+
+	moduleData, err := rewriter.RewriteModule(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("source rewriting failed: %w", err)
+	}
+
+	return openBytes(moduleData), int64(len(moduleData)), nil
+}
+
+// openBytes returns a function opening a reader over data
+func openBytes(data []byte) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
 // LoadSingleModule loads a specific module version
 func (s *Service) LoadSingleModule(ctx context.Context, namespace, name, system, version string) *LoadResult {
 	def := &ModuleDefinition{
@@ -299,15 +388,16 @@ func (s *Service) DeleteModule(ctx context.Context, moduleID int64) error {
 		return fmt.Errorf("module not found: %d", moduleID)
 	}
 
-	// Delete from storage
-	if err := s.storage.Delete(ctx, module.S3Key); err != nil {
-		return fmt.Errorf("storage delete failed: %w", err)
-	}
-
-	// Delete from database
+	// Delete from database first, so the archive's remaining references can
+	// be counted
 	if err := moduleRepo.Delete(ctx, moduleID); err != nil {
 		return fmt.Errorf("database delete failed: %w", err)
 	}
 
+	// Delete from storage unless another version shares the archive
+	if _, err := ReleaseArchive(ctx, s.storage, moduleRepo, module.S3Key); err != nil {
+		return fmt.Errorf("storage delete failed: %w", err)
+	}
+
 	return nil
 }
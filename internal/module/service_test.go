@@ -0,0 +1,153 @@
+package module
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticRegistry serves a single module tarball for any request
+type staticRegistry struct {
+	data []byte
+}
+
+func (r *staticRegistry) GetAvailableVersions(ctx context.Context, namespace, name, system string) ([]string, error) {
+	return []string{"1.0.0"}, nil
+}
+
+func (r *staticRegistry) GetDownloadURL(ctx context.Context, namespace, name, system, version string) (string, error) {
+	return "https://example.com/" + namespace + "/" + name + "/" + system + "/" + version + ".tar.gz", nil
+}
+
+func (r *staticRegistry) DownloadModule(ctx context.Context, downloadURL string) ([]byte, error) {
+	return r.data, nil
+}
+
+func (r *staticRegistry) DownloadModuleComplete(ctx context.Context, namespace, name, system, version string) *DownloadResult {
+	url, _ := r.GetDownloadURL(ctx, namespace, name, system, version)
+	return &DownloadResult{
+		Data: r.data,
+		Info: &ModuleDownloadInfo{
+			Namespace:   namespace,
+			Name:        name,
+			System:      system,
+			Version:     version,
+			DownloadURL: url,
+		},
+	}
+}
+
+func TestService_UploadMetadata(t *testing.T) {
+	db, err := database.New(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := storage.NewMockStorage()
+	tarball := createTestTarball(t, map[string]string{"main.tf": `variable "x" {}`})
+
+	service := NewService(store, db, "")
+	service.SetRegistry(&staticRegistry{data: tarball})
+	ctx := context.Background()
+
+	t.Run("downloaded module", func(t *testing.T) {
+		result := service.LoadSingleModule(ctx, "hashicorp", "consul", "aws", "1.0.0")
+		require.True(t, result.Success, "load failed: %v", result.Error)
+
+		m, err := database.NewModuleRepository(db).GetByIdentity(ctx, "hashicorp", "consul", "aws", "1.0.0")
+		require.NoError(t, err)
+		require.NotNil(t, m)
+
+		metadata, err := store.GetMetadata(ctx, m.S3Key)
+		require.NoError(t, err)
+		assert.Equal(t, storage.ArtifactModule, metadata[storage.MetadataArtifact])
+		assert.Equal(t, "hashicorp", metadata[storage.MetadataNamespace])
+		assert.Equal(t, "consul", metadata[storage.MetadataName])
+		assert.Equal(t, "aws", metadata[storage.MetadataSystem])
+		assert.Equal(t, "1.0.0", metadata[storage.MetadataVersion])
+		assert.Equal(t, "hashicorp-consul-aws-1.0.0.tar.gz", metadata[storage.MetadataFilename])
+		assert.Equal(t, "https://example.com/hashicorp/consul/aws/1.0.0.tar.gz", metadata[storage.MetadataSourceURL])
+		assert.Equal(t, "true", metadata[storage.MetadataPinned])
+		assert.NotEmpty(t, metadata[storage.MetadataMirroredAt])
+	})
+
+	t.Run("imported module", func(t *testing.T) {
+		m, err := service.ImportModule(ctx, "acme", "network", "aws", "2.0.0", tarball, false)
+		require.NoError(t, err)
+
+		metadata, err := store.GetMetadata(ctx, m.S3Key)
+		require.NoError(t, err)
+		assert.Equal(t, storage.ArtifactModule, metadata[storage.MetadataArtifact])
+		assert.Equal(t, "network", metadata[storage.MetadataName])
+		assert.Equal(t, "2.0.0", metadata[storage.MetadataVersion])
+		assert.Equal(t, "true", metadata[storage.MetadataPinned])
+		assert.NotContains(t, metadata, storage.MetadataSourceURL)
+	})
+}
+
+// cancellingRegistry serves module tarballs, cancelling the load while the
+// module at cancelAt is downloading
+type cancellingRegistry struct {
+	staticRegistry
+	cancel    context.CancelFunc
+	cancelAt  int
+	requested []string
+}
+
+func (r *cancellingRegistry) DownloadModuleComplete(ctx context.Context, namespace, name, system, version string) *DownloadResult {
+	r.requested = append(r.requested, version)
+	if len(r.requested) == r.cancelAt {
+		r.cancel()
+		return &DownloadResult{Error: ctx.Err()}
+	}
+	return r.staticRegistry.DownloadModuleComplete(ctx, namespace, name, system, version)
+}
+
+func TestService_LoadFromDefinitionsStopsWhenCancelled(t *testing.T) {
+	db, err := database.New(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registry := &cancellingRegistry{
+		staticRegistry: staticRegistry{data: createTestTarball(t, map[string]string{"main.tf": `variable "x" {}`})},
+		cancel:         cancel,
+		cancelAt:       2,
+	}
+	service := NewService(storage.NewMockStorage(), db, "")
+	service.SetRegistry(registry)
+
+	defs := &ModuleDefinitions{Modules: []*ModuleDefinition{{
+		Namespace: "hashicorp",
+		Name:      "consul",
+		System:    "aws",
+		Versions:  []string{"1.0.0", "2.0.0", "3.0.0"},
+	}}}
+
+	var progress []string
+	results, err := service.LoadFromDefinitionsWithProgress(ctx, defs, func(result *LoadResult) {
+		progress = append(progress, result.Version)
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// Only the module finished before the cancellation has a result, and the
+	// last module is never requested
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Success)
+	assert.Equal(t, []string{"1.0.0"}, progress)
+	assert.Equal(t, []string{"1.0.0", "2.0.0"}, registry.requested)
+
+	count, err := database.NewModuleRepository(db).Count(context.Background(), database.ModuleFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// A single load with a cancelled context does nothing
+	result := service.LoadSingleModule(ctx, "hashicorp", "consul", "aws", "3.0.0")
+	assert.ErrorIs(t, result.Error, context.Canceled)
+	assert.Len(t, registry.requested, 2)
+}
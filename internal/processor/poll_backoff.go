@@ -0,0 +1,46 @@
+package processor
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// pollJitter is the fraction of the interval by which each wait is randomly
+// lengthened or shortened, so replicas started together drift apart
+const pollJitter = 0.1
+
+// pollBackoff tracks the wait between polls. Polls that start no work double
+// the interval up to max; a poll that starts work resets it to base.
+type pollBackoff struct {
+	base     time.Duration
+	max      time.Duration
+	interval time.Duration
+	random   func() float64 // returns [0.0, 1.0); replaced in tests
+}
+
+// newPollBackoff creates a backoff starting at base. A max at or below base
+// keeps the interval fixed.
+func newPollBackoff(base, max time.Duration) *pollBackoff {
+	if max < base {
+		max = base
+	}
+	return &pollBackoff{base: base, max: max, interval: base, random: rand.Float64}
+}
+
+// observe records whether a poll started work and returns how long to wait
+// before the next one
+func (b *pollBackoff) observe(found bool) time.Duration {
+	if found {
+		b.interval = b.base
+	} else if b.interval < b.max {
+		b.interval = min(b.interval*2, b.max)
+	}
+	return b.jittered()
+}
+
+// jittered returns the current interval moved randomly by up to pollJitter
+// of its length in either direction
+func (b *pollBackoff) jittered() time.Duration {
+	spread := float64(b.interval) * pollJitter
+	return b.interval + time.Duration((b.random()*2-1)*spread)
+}
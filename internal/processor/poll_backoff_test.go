@@ -0,0 +1,35 @@
+package processor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPollBackoff_Jitter(t *testing.T) {
+	b := newPollBackoff(10*time.Second, time.Minute)
+
+	for _, tc := range []struct {
+		random float64
+		want   time.Duration
+	}{
+		{0, 9 * time.Second},
+		{0.5, 10 * time.Second},
+		{0.999999, 11 * time.Second},
+	} {
+		b.random = func() float64 { return tc.random }
+		if got := b.observe(true); got.Round(time.Millisecond) != tc.want {
+			t.Errorf("random %v: expected %v, got %v", tc.random, tc.want, got)
+		}
+	}
+}
+
+func TestPollBackoff_FixedWhenMaxBelowBase(t *testing.T) {
+	b := newPollBackoff(10*time.Second, 0)
+	b.random = func() float64 { return 0.5 }
+
+	for i := 0; i < 3; i++ {
+		if got := b.observe(false); got != 10*time.Second {
+			t.Fatalf("poll %d: expected fixed 10s interval, got %v", i, got)
+		}
+	}
+}
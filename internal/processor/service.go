@@ -3,14 +3,19 @@ package processor
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"log"
+	"runtime/debug"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/metrics"
 	"github.com/ned1313/terraform-mirror/internal/module"
 	"github.com/ned1313/terraform-mirror/internal/provider"
 	"github.com/ned1313/terraform-mirror/internal/storage"
@@ -19,10 +24,17 @@ import (
 // Config holds the processor configuration
 type Config struct {
 	PollingInterval    time.Duration // How often to check for new jobs
+	MaxPollingInterval time.Duration // Longest wait between polls while no jobs are found; at or below PollingInterval disables backoff
 	MaxConcurrentJobs  int           // Maximum number of jobs to process concurrently
 	RetryAttempts      int           // Number of retry attempts for failed downloads
 	RetryDelay         time.Duration // Delay between retry attempts
 	WorkerShutdownTime time.Duration // Time to wait for workers to finish during shutdown
+	ImmutableArtifacts bool          // Refuse to replace stored artifacts with different content
+	ReindexFromStorage bool          // Record archives already in storage instead of downloading them again
+	VerifyReindex      bool          // Hash stored archives against their recorded shasum before re-indexing
+	MaxDownloadSize    int64         // Abort provider and module downloads larger than this many bytes; 0 means unlimited
+	StoreShasums       bool          // Store a SHA256SUMS document for each version whose platforms all completed
+	DedupModules       bool          // Store module archives content-addressed so identical versions share one object
 }
 
 // Service manages background job processing
@@ -37,6 +49,8 @@ type Service struct {
 	registry      provider.RegistryDownloader
 	moduleService *module.Service
 	hostname      string // Hostname for storage keys (e.g., "registry.terraform.io")
+	upstreams     *provider.UpstreamRouter
+	metrics       *metrics.Metrics
 
 	mu       sync.Mutex
 	running  bool
@@ -46,11 +60,23 @@ type Service struct {
 
 	// Track active jobs
 	activeJobs map[int64]context.CancelFunc
+
+	// trackedJobs are jobs run outside the poll loop; they can be cancelled
+	// but do not count against MaxConcurrentJobs
+	trackedJobs map[int64]context.CancelFunc
+
+	// pollFunc and processJobFunc run one poll and one job; tests replace
+	// them to inject failures. pollFunc reports whether it started any jobs.
+	pollFunc       func(ctx context.Context) bool
+	processJobFunc func(ctx context.Context, job *database.DownloadJob) error
 }
 
 // NewService creates a new processor service
 func NewService(config Config, db *database.DB, store storage.Storage, hostname string) *Service {
-	return &Service{
+	registry := provider.NewRegistryClient()
+	registry.SetMaxDownloadSize(config.MaxDownloadSize)
+
+	s := &Service{
 		config:        config,
 		db:            db,
 		jobRepo:       database.NewJobRepository(db),
@@ -58,13 +84,19 @@ func NewService(config Config, db *database.DB, store storage.Storage, hostname
 		moduleRepo:    database.NewModuleRepository(db),
 		moduleJobRepo: database.NewModuleJobRepository(db),
 		storage:       store,
-		registry:      provider.NewRegistryClient(),
+		registry:      registry,
 		moduleService: module.NewService(store, db, hostname),
 		hostname:      hostname,
 		stopCh:        make(chan struct{}),
 		doneCh:        make(chan struct{}),
 		activeJobs:    make(map[int64]context.CancelFunc),
+		trackedJobs:   make(map[int64]context.CancelFunc),
 	}
+	s.pollFunc = s.processPendingJobs
+	s.processJobFunc = s.processJob
+	s.moduleService.SetMaxDownloadSize(config.MaxDownloadSize)
+	s.moduleService.SetDeduplication(config.DedupModules)
+	return s
 }
 
 // SetRegistry allows injection of a mock registry client for testing
@@ -72,6 +104,26 @@ func (s *Service) SetRegistry(registry provider.RegistryDownloader) {
 	s.registry = registry
 }
 
+// SetUpstreams downloads each provider from the upstream registry serving
+// its namespace and stores it under that registry's hostname
+func (s *Service) SetUpstreams(upstreams *provider.UpstreamRouter) {
+	s.registry = upstreams
+	s.upstreams = upstreams
+}
+
+// providerHostname returns the hostname segment of a provider's storage keys
+func (s *Service) providerHostname(namespace string) string {
+	if s.upstreams != nil {
+		return s.upstreams.Hostname(namespace)
+	}
+	return s.hostname
+}
+
+// SetMetrics enables recording of job metrics; nil disables it
+func (s *Service) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
 // Start begins processing jobs
 func (s *Service) Start(ctx context.Context) error {
 	s.mu.Lock()
@@ -111,6 +163,10 @@ func (s *Service) Stop() error {
 		log.Printf("Cancelling active job %d", jobID)
 		cancel()
 	}
+	for jobID, cancel := range s.trackedJobs {
+		log.Printf("Cancelling tracked job %d", jobID)
+		cancel()
+	}
 	s.mu.Unlock()
 
 	// Wait for workers to finish with timeout
@@ -145,28 +201,56 @@ func (s *Service) CancelJob(jobID int64) bool {
 		cancel()
 		return true
 	}
+	if cancel, exists := s.trackedJobs[jobID]; exists {
+		log.Printf("Cancelling tracked job %d", jobID)
+		cancel()
+		return true
+	}
 	return false
 }
 
+// TrackJob registers a job that runs outside the poll loop, such as an admin
+// module load, so that CancelJob and Stop cancel it like a processor job. It
+// returns the job's context and a function to call once the job finishes.
+func (s *Service) TrackJob(ctx context.Context, jobID int64) (context.Context, func()) {
+	jobCtx, cancel := context.WithCancel(ctx)
+
+	s.mu.Lock()
+	s.trackedJobs[jobID] = cancel
+	s.mu.Unlock()
+
+	return jobCtx, func() {
+		s.mu.Lock()
+		delete(s.trackedJobs, jobID)
+		s.mu.Unlock()
+		cancel()
+	}
+}
+
 // IsJobActive returns true if the job is currently being processed
 func (s *Service) IsJobActive(jobID int64) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	_, exists := s.activeJobs[jobID]
-	return exists
+	_, active := s.activeJobs[jobID]
+	_, tracked := s.trackedJobs[jobID]
+	return active || tracked
 }
 
-// pollLoop continuously checks for pending jobs
+// pollLoop supervises the polling loop, restarting it after a polling
+// interval whenever it dies from a panic, until the service is stopped
 func (s *Service) pollLoop(ctx context.Context) {
 	defer close(s.doneCh)
 
-	ticker := time.NewTicker(s.config.PollingInterval)
-	defer ticker.Stop()
+	for {
+		if s.runPollLoop(ctx) {
+			return
+		}
 
-	// Process immediately on start
-	s.processPendingJobs(ctx)
+		log.Println("Poll loop died, restarting")
+		if s.metrics != nil {
+			s.metrics.ProcessorRestarts.Inc()
+		}
 
-	for {
 		select {
 		case <-ctx.Done():
 			log.Println("Poll loop stopped: context cancelled")
@@ -174,37 +258,83 @@ func (s *Service) pollLoop(ctx context.Context) {
 		case <-s.stopCh:
 			log.Println("Poll loop stopped: stop signal received")
 			return
-		case <-ticker.C:
-			s.processPendingJobs(ctx)
+		case <-time.After(s.config.PollingInterval):
+		}
+	}
+}
+
+// runPollLoop continuously checks for pending jobs, backing off while polls
+// find nothing to start. Creating a pending job wakes it to poll at once. It
+// returns true when stopped and false if a panic was recovered.
+func (s *Service) runPollLoop(ctx context.Context) (stopped bool) {
+	defer s.recoverPanic("poll_loop")
+
+	backoff := newPollBackoff(s.config.PollingInterval, s.config.MaxPollingInterval)
+
+	// Process immediately on start
+	timer := time.NewTimer(backoff.observe(s.pollFunc(ctx)))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Poll loop stopped: context cancelled")
+			return true
+		case <-s.stopCh:
+			log.Println("Poll loop stopped: stop signal received")
+			return true
+		case <-timer.C:
+			timer.Reset(backoff.observe(s.pollFunc(ctx)))
+		case <-s.db.JobEnqueued():
+			// Reset discards a pending tick, so the next poll is timed from now
+			timer.Reset(backoff.observe(s.pollFunc(ctx)))
 		}
 	}
 }
 
-// processPendingJobs fetches and processes pending jobs
-func (s *Service) processPendingJobs(ctx context.Context) {
+// recoverPanic logs and counts a panic in the given processor component.
+// It must be deferred directly so recover sees the panic.
+func (s *Service) recoverPanic(component string) {
+	if r := recover(); r != nil {
+		s.logPanic(component, r)
+	}
+}
+
+// logPanic logs a recovered panic with its stack and records the metric
+func (s *Service) logPanic(component string, r interface{}) {
+	log.Printf("Recovered panic in processor %s: %v\n%s", component, r, debug.Stack())
+	if s.metrics != nil {
+		s.metrics.RecordProcessorPanic(component)
+	}
+}
+
+// processPendingJobs fetches and processes pending jobs, reporting whether
+// any were started
+func (s *Service) processPendingJobs(ctx context.Context) bool {
 	s.mu.Lock()
 	activeCount := len(s.activeJobs)
+	maxJobs := s.config.MaxConcurrentJobs
 	s.mu.Unlock()
 
 	// Check if we've reached the max concurrent jobs limit
-	if activeCount >= s.config.MaxConcurrentJobs {
+	if activeCount >= maxJobs {
 		log.Printf("Max concurrent jobs reached (%d/%d), skipping poll",
-			activeCount, s.config.MaxConcurrentJobs)
-		return
+			activeCount, maxJobs)
+		return false
 	}
 
 	// Calculate how many jobs we can start
-	availableSlots := s.config.MaxConcurrentJobs - activeCount
+	availableSlots := maxJobs - activeCount
 
 	// Fetch pending jobs directly
 	pendingJobs, err := s.jobRepo.ListPending(ctx, availableSlots)
 	if err != nil {
 		log.Printf("Error fetching pending jobs: %v", err)
-		return
+		return false
 	}
 
 	if len(pendingJobs) == 0 {
-		return
+		return false
 	}
 
 	log.Printf("Found %d pending jobs, starting processing...", len(pendingJobs))
@@ -213,6 +343,7 @@ func (s *Service) processPendingJobs(ctx context.Context) {
 	for _, job := range pendingJobs {
 		s.startJobWorker(ctx, job)
 	}
+	return true
 }
 
 // startJobWorker starts processing a job in a new goroutine
@@ -234,7 +365,7 @@ func (s *Service) startJobWorker(ctx context.Context, job *database.DownloadJob)
 		}()
 
 		log.Printf("Starting job %d", job.ID)
-		err := s.processJob(jobCtx, job)
+		err := s.runJob(jobCtx, job)
 		if err != nil {
 			log.Printf("Job %d failed: %v", job.ID, err)
 		} else {
@@ -243,26 +374,73 @@ func (s *Service) startJobWorker(ctx context.Context, job *database.DownloadJob)
 	}()
 }
 
+// runJob processes a job, failing it if processing panics so a bug in one
+// job neither kills the processor nor leaves the job stuck running
+func (s *Service) runJob(ctx context.Context, job *database.DownloadJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logPanic("worker", r)
+			err = s.failJob(context.WithoutCancel(ctx), job, fmt.Errorf("job panicked: %v", r))
+		}
+	}()
+
+	return s.processJobFunc(ctx, job)
+}
+
 // processJob processes a single download job
 func (s *Service) processJob(ctx context.Context, job *database.DownloadJob) error {
-	// Update job status to running
-	job.Status = "running"
-	now := time.Now()
-	job.StartedAt.Time = now
-	job.StartedAt.Valid = true
-	if err := s.jobRepo.Update(ctx, job); err != nil {
+	// Claim the job; it may have been cancelled since it was listed
+	claimed, err := s.jobRepo.Claim(ctx, job)
+	if err != nil {
 		return fmt.Errorf("failed to update job status: %w", err)
 	}
+	if !claimed {
+		log.Printf("Job %d is no longer pending, skipping", job.ID)
+		return nil
+	}
 
 	// Dispatch based on job type
 	switch job.JobType {
 	case "module":
-		return s.processModuleJob(ctx, job)
+		err = s.processModuleJob(ctx, job)
 	case "provider", "":
 		// Empty job type defaults to provider for backwards compatibility
-		return s.processProviderJob(ctx, job)
+		err = s.processProviderJob(ctx, job)
 	default:
-		return s.failJob(ctx, job, fmt.Errorf("unknown job type: %s", job.JobType))
+		err = s.failJob(ctx, job, fmt.Errorf("unknown job type: %s", job.JobType))
+	}
+
+	s.recordJobMetrics(job)
+	return err
+}
+
+// recordJobMetrics records the duration and outcome of a job that reached a terminal state
+func (s *Service) recordJobMetrics(job *database.DownloadJob) {
+	if s.metrics == nil {
+		return
+	}
+	if job.Status != "completed" && job.Status != "failed" {
+		return
+	}
+
+	end := time.Now()
+	if job.CompletedAt.Valid {
+		end = job.CompletedAt.Time
+	}
+	duration := end.Sub(job.StartedAt.Time).Seconds()
+
+	jobType := job.JobType
+	if jobType == "" {
+		jobType = "provider"
+	}
+
+	s.metrics.RecordJobProcessed(job.Status, duration, jobType)
+}
+
+// recordItemMetrics counts a job item that reached the given status
+func (s *Service) recordItemMetrics(status string) {
+	if s.metrics != nil {
+		s.metrics.JobItemsTotal.WithLabelValues(status).Inc()
 	}
 }
 
@@ -278,6 +456,11 @@ func (s *Service) processProviderJob(ctx context.Context, job *database.Download
 		return s.failJob(ctx, job, fmt.Errorf("job has no items to process"))
 	}
 
+	// Pre-mark items whose providers are already stored, using a single batched lookup
+	if err := s.markExistingItems(ctx, job, items); err != nil {
+		return s.failJob(ctx, job, err)
+	}
+
 	// Process each item
 	for _, item := range items {
 		if item.Status == "completed" {
@@ -295,8 +478,10 @@ func (s *Service) processProviderJob(ctx context.Context, job *database.Download
 		if err := s.processJobItem(ctx, job, item); err != nil {
 			log.Printf("Job %d item %d failed: %v", job.ID, item.ID, err)
 			job.FailedItems++
+			s.recordItemMetrics("failed")
 		} else {
 			job.CompletedItems++
+			s.recordItemMetrics("completed")
 		}
 
 		// Update job progress
@@ -306,6 +491,11 @@ func (s *Service) processProviderJob(ctx context.Context, job *database.Download
 		}
 	}
 
+	if s.config.StoreShasums {
+		s.storeCompleteShasums(ctx, job, items)
+	}
+	s.pruneStaleShasums(ctx, job)
+
 	// Mark job as completed or failed
 	if job.FailedItems == 0 {
 		job.Status = "completed"
@@ -325,14 +515,125 @@ func (s *Service) processProviderJob(ctx context.Context, job *database.Download
 	return nil
 }
 
+// storeCompleteShasums stores the SHA256SUMS document of every version whose
+// platforms in the job all completed. Failures are logged; the registry
+// protocol builds the document on request instead.
+func (s *Service) storeCompleteShasums(ctx context.Context, job *database.DownloadJob, items []*database.DownloadJobItem) {
+	type versionKey struct{ namespace, providerType, version string }
+	var order []versionKey
+	complete := make(map[versionKey]bool)
+	for _, item := range items {
+		key := versionKey{item.Namespace, item.Type, item.Version}
+		done, seen := complete[key]
+		if !seen {
+			order = append(order, key)
+			done = true
+		}
+		complete[key] = done && item.Status == "completed"
+	}
+
+	for _, key := range order {
+		if !complete[key] {
+			continue
+		}
+		stored, err := provider.StoreShasums(ctx, s.providerRepo, s.storage,
+			s.providerHostname(key.namespace), key.namespace, key.providerType, key.version)
+		if err != nil {
+			log.Printf("Job %d: failed to store SHA256SUMS for %s/%s %s: %v",
+				job.ID, key.namespace, key.providerType, key.version, err)
+			continue
+		}
+		log.Printf("Job %d: stored %s", job.ID, stored)
+	}
+}
+
+// pruneStaleShasums removes stored SHA256SUMS documents of versions whose
+// platforms changed, including those the job added without completing the
+// version. Failures are logged; the next job retries them.
+func (s *Service) pruneStaleShasums(ctx context.Context, job *database.DownloadJob) {
+	removed, err := provider.PruneStaleShasums(ctx, s.providerRepo, s.storage)
+	if err != nil {
+		log.Printf("Job %d: failed to remove stale SHA256SUMS documents: %v", job.ID, err)
+	}
+	if removed > 0 {
+		log.Printf("Job %d: removed %d stale SHA256SUMS documents", job.ID, removed)
+	}
+}
+
+// markExistingItems marks pending items as completed when the provider they
+// refer to is already present in the database. A failed existence check is
+// logged and left for the per-item check in processJobItem to handle; a failure
+// to record an item as completed is returned.
+func (s *Service) markExistingItems(ctx context.Context, job *database.DownloadJob, items []*database.DownloadJobItem) error {
+	identities := make([]database.Identity, 0, len(items))
+	for _, item := range items {
+		if item.Status == "completed" {
+			continue
+		}
+		identities = append(identities, database.Identity{
+			Namespace: item.Namespace,
+			Type:      item.Type,
+			Version:   item.Version,
+			Platform:  item.Platform,
+		})
+	}
+	if len(identities) == 0 {
+		return nil
+	}
+
+	existing, err := s.providerRepo.ExistingIdentities(ctx, identities)
+	if err != nil {
+		log.Printf("Job %d: batched existence check failed, falling back to per-item checks: %v", job.ID, err)
+		return nil
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+
+	marked := 0
+	for _, item := range items {
+		if item.Status == "completed" {
+			continue
+		}
+		p, ok := existing[database.Identity{
+			Namespace: item.Namespace,
+			Type:      item.Type,
+			Version:   item.Version,
+			Platform:  item.Platform,
+		}]
+		if !ok {
+			continue
+		}
+
+		item.Status = "completed"
+		item.ProviderID = sql.NullInt64{Int64: p.ID, Valid: true}
+		item.CompletedAt.Time = time.Now()
+		item.CompletedAt.Valid = true
+		if err := s.jobRepo.UpdateItem(ctx, item); err != nil {
+			return fmt.Errorf("failed to mark item %d as completed: %w", item.ID, err)
+		}
+		job.CompletedItems++
+		s.recordItemMetrics("completed")
+		marked++
+	}
+
+	if marked > 0 {
+		job.Progress = (job.CompletedItems * 100) / job.TotalItems
+		if err := s.jobRepo.Update(ctx, job); err != nil {
+			log.Printf("Failed to update job progress: %v", err)
+		}
+		log.Printf("Job %d: %d of %d items already exist, skipping download", job.ID, marked, len(items))
+	}
+	return nil
+}
+
 // processJobItem processes a single job item (provider download)
 func (s *Service) processJobItem(ctx context.Context, job *database.DownloadJob, item *database.DownloadJobItem) error {
 	// Parse platform into OS and arch
-	parts := strings.Split(item.Platform, "_")
-	if len(parts) != 2 {
-		return s.failItem(ctx, item, fmt.Errorf("invalid platform format: %s", item.Platform))
+	osName, arch, err := provider.ParsePlatform(item.Platform)
+	if err != nil {
+		return s.failItem(ctx, item, err)
 	}
-	osName, arch := parts[0], parts[1]
 
 	// Check if provider already exists in database
 	existingProvider, err := s.providerRepo.GetByIdentity(ctx, item.Namespace, item.Type, item.Version, item.Platform)
@@ -340,6 +641,10 @@ func (s *Service) processJobItem(ctx context.Context, job *database.DownloadJob,
 		return s.failItem(ctx, item, fmt.Errorf("failed to check existing provider: %w", err))
 	}
 	if existingProvider != nil {
+		if err := checkExpectedShasum(item, existingProvider.Shasum); err != nil {
+			return s.failItem(ctx, item, fmt.Errorf("mirrored archive does not match: %w", err))
+		}
+
 		// Provider already exists, mark as completed and link to existing provider
 		item.Status = "completed"
 		item.ProviderID = sql.NullInt64{Int64: existingProvider.ID, Valid: true}
@@ -353,6 +658,27 @@ func (s *Service) processJobItem(ctx context.Context, job *database.DownloadJob,
 		return nil
 	}
 
+	// The database may have lost the row while storage kept the archive
+	if s.config.ReindexFromStorage {
+		reindexed, err := s.reindexFromStorage(ctx, item, osName, arch)
+		if err != nil {
+			log.Printf("Job %d item %d: Could not re-index from storage, downloading instead: %v", job.ID, item.ID, err)
+		}
+		if reindexed != nil {
+			item.Status = "completed"
+			item.ProviderID = sql.NullInt64{Int64: reindexed.ID, Valid: true}
+			item.SizeBytes = sql.NullInt64{Int64: reindexed.SizeBytes, Valid: true}
+			item.CompletedAt.Time = time.Now()
+			item.CompletedAt.Valid = true
+			if err := s.jobRepo.UpdateItem(ctx, item); err != nil {
+				return fmt.Errorf("failed to update item: %w", err)
+			}
+			log.Printf("Job %d item %d: Re-indexed %s/%s %s (%s) from storage, skipping download",
+				job.ID, item.ID, item.Namespace, item.Type, item.Version, item.Platform)
+			return nil
+		}
+	}
+
 	// Update item status to downloading
 	item.Status = "downloading"
 	now := time.Now()
@@ -370,6 +696,10 @@ func (s *Service) processJobItem(ctx context.Context, job *database.DownloadJob,
 	if result.Error != nil {
 		return s.failItem(ctx, item, result.Error)
 	}
+	sum := sha256.Sum256(result.Data)
+	if err := checkExpectedShasum(item, hex.EncodeToString(sum[:])); err != nil {
+		return s.failItem(ctx, item, fmt.Errorf("downloaded archive does not match: %w", err))
+	}
 
 	// Update item with download info
 	item.DownloadURL = sql.NullString{String: result.Info.DownloadURL, Valid: true}
@@ -381,7 +711,7 @@ func (s *Service) processJobItem(ctx context.Context, job *database.DownloadJob,
 
 	// Generate S3 key
 	s3Key := storage.BuildProviderKey(
-		s.hostname,
+		s.providerHostname(item.Namespace),
 		item.Namespace,
 		item.Type,
 		item.Version,
@@ -390,15 +720,31 @@ func (s *Service) processJobItem(ctx context.Context, job *database.DownloadJob,
 		result.Info.Filename,
 	)
 
+	// Never replace a mirrored artifact with different bytes
+	if s.config.ImmutableArtifacts {
+		existing, err := provider.CheckImmutable(ctx, s.providerRepo, s.storage, s3Key,
+			item.Namespace, item.Type, item.Version, item.Platform, result.Info.Shasum)
+		if err != nil {
+			log.Printf("Job %d item %d: Refusing to replace %s: %v", job.ID, item.ID, s3Key, err)
+			return s.failItem(ctx, item, err)
+		}
+		if existing != nil {
+			// Same content is already mirrored, link to it
+			item.Status = "completed"
+			item.ProviderID = sql.NullInt64{Int64: existing.ID, Valid: true}
+			item.CompletedAt.Time = time.Now()
+			item.CompletedAt.Valid = true
+			if err := s.jobRepo.UpdateItem(ctx, item); err != nil {
+				return fmt.Errorf("failed to update item: %w", err)
+			}
+			return nil
+		}
+	}
+
 	// Upload to storage
 	log.Printf("Job %d item %d: Uploading to storage: %s", job.ID, item.ID, s3Key)
-	metadata := map[string]string{
-		"namespace": item.Namespace,
-		"type":      item.Type,
-		"version":   item.Version,
-		"platform":  item.Platform,
-		"shasum":    result.Info.Shasum,
-	}
+	metadata := storage.ProviderMetadata(item.Namespace, item.Type, item.Version, item.Platform,
+		result.Info.Filename, result.Info.Shasum, result.Info.DownloadURL, true)
 
 	if err := s.storage.Upload(ctx, s3Key, bytes.NewReader(result.Data), "application/zip", metadata); err != nil {
 		return s.failItem(ctx, item, fmt.Errorf("failed to upload to storage: %w", err))
@@ -413,10 +759,14 @@ func (s *Service) processJobItem(ctx context.Context, job *database.DownloadJob,
 		Filename:    result.Info.Filename,
 		DownloadURL: result.Info.DownloadURL,
 		Shasum:      result.Info.Shasum,
+		SigningKeys: provider.EncodeSigningKeys(result.Info.SigningKeys),
+		Protocols:   provider.EncodeProtocols(result.Info.Protocols),
+		H1Hash:      provider.EncodePackageHash(result.Data),
 		S3Key:       s3Key,
 		SizeBytes:   int64(len(result.Data)),
 		Deprecated:  false,
 		Blocked:     false,
+		Verified:    result.Verified,
 	}
 
 	if err := s.providerRepo.Create(ctx, providerRecord); err != nil {
@@ -442,6 +792,91 @@ func (s *Service) processJobItem(ctx context.Context, job *database.DownloadJob,
 	return nil
 }
 
+// reindexFromStorage creates the provider record for an archive that is
+// already at its expected storage key, using the object metadata in place of
+// upstream. It returns nil when there is no usable archive, so the caller
+// downloads as usual.
+func (s *Service) reindexFromStorage(ctx context.Context, item *database.DownloadJobItem, osName, arch string) (*database.Provider, error) {
+	filename := provider.FormatProviderFilename(item.Type, item.Version, osName, arch)
+	s3Key := storage.BuildProviderKey(s.providerHostname(item.Namespace), item.Namespace, item.Type, item.Version, osName, arch, filename)
+
+	exists, err := s.storage.Exists(ctx, s3Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check storage for %s: %w", s3Key, err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	metadata, err := s.storage.GetMetadata(ctx, s3Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata for %s: %w", s3Key, err)
+	}
+	if artifact := metadata[storage.MetadataArtifact]; artifact != "" && artifact != storage.ArtifactProvider {
+		return nil, fmt.Errorf("%s holds a %s, not a provider", s3Key, artifact)
+	}
+
+	// The archive is read in full since its h1: hash covers the files inside it
+	reader, err := s.storage.Download(ctx, s3Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s3Key, err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s3Key, err)
+	}
+	sum := sha256.Sum256(data)
+	actual := hex.EncodeToString(sum[:])
+
+	shasum := metadata[storage.MetadataShasum]
+	if s.config.VerifyReindex {
+		if shasum == "" {
+			return nil, fmt.Errorf("%s has no recorded shasum to verify", s3Key)
+		}
+		if actual != shasum {
+			return nil, fmt.Errorf("%s content shasum %s does not match recorded %s", s3Key, actual, shasum)
+		}
+	} else if shasum == "" {
+		shasum = actual
+	}
+
+	if err := checkExpectedShasum(item, shasum); err != nil {
+		return nil, fmt.Errorf("%s does not match: %w", s3Key, err)
+	}
+
+	providerRecord := &database.Provider{
+		Namespace:   item.Namespace,
+		Type:        item.Type,
+		Version:     item.Version,
+		Platform:    item.Platform,
+		Filename:    filename,
+		DownloadURL: metadata[storage.MetadataSourceURL],
+		Shasum:      shasum,
+		S3Key:       s3Key,
+		SizeBytes:   int64(len(data)),
+		H1Hash:      provider.EncodePackageHash(data),
+	}
+	if err := s.providerRepo.Create(ctx, providerRecord); err != nil {
+		return nil, fmt.Errorf("failed to create provider record: %w", err)
+	}
+	return providerRecord, nil
+}
+
+// checkExpectedShasum returns an error when the item pins the shasums it
+// accepts and shasum is not one of them
+func checkExpectedShasum(item *database.DownloadJobItem, shasum string) error {
+	if !item.ExpectedShasums.Valid || item.ExpectedShasums.String == "" {
+		return nil
+	}
+	for _, expected := range strings.Split(item.ExpectedShasums.String, ",") {
+		if strings.EqualFold(expected, shasum) {
+			return nil
+		}
+	}
+	return fmt.Errorf("shasum %s is not one of the expected shasums", shasum)
+}
+
 // failItem marks an item as failed with an error message
 func (s *Service) failItem(ctx context.Context, item *database.DownloadJobItem, err error) error {
 	item.Status = "failed"
@@ -471,6 +906,27 @@ func (s *Service) failJob(ctx context.Context, job *database.DownloadJob, err er
 	return err
 }
 
+// GetConfig returns the processor configuration currently in effect
+func (s *Service) GetConfig() Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config
+}
+
+// SetMaxConcurrentJobs changes how many jobs may run at once. The poll loop
+// uses the new limit from its next tick; lowering it lets running jobs
+// finish rather than cancelling them.
+func (s *Service) SetMaxConcurrentJobs(n int) error {
+	if n < 1 {
+		return fmt.Errorf("max concurrent jobs must be at least 1, got %d", n)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.MaxConcurrentJobs = n
+	return nil
+}
+
 // GetStatus returns the current processor status
 func (s *Service) GetStatus() map[string]interface{} {
 	s.mu.Lock()
@@ -512,8 +968,10 @@ func (s *Service) processModuleJob(ctx context.Context, job *database.DownloadJo
 		if err := s.processModuleJobItem(ctx, job, item); err != nil {
 			log.Printf("Job %d module item %d failed: %v", job.ID, item.ID, err)
 			job.FailedItems++
+			s.recordItemMetrics("failed")
 		} else {
 			job.CompletedItems++
+			s.recordItemMetrics("completed")
 		}
 
 		// Update job progress
@@ -1,27 +1,40 @@
 package processor
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ned1313/terraform-mirror/internal/config"
 	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/metrics"
 	"github.com/ned1313/terraform-mirror/internal/provider"
 	"github.com/ned1313/terraform-mirror/internal/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 // mockStorage implements storage.Storage for testing
 type mockStorage struct {
-	mu      sync.Mutex
-	objects map[string][]byte
+	mu       sync.Mutex
+	objects  map[string][]byte
+	metadata map[string]map[string]string
 }
 
 func newMockStorage() *mockStorage {
 	return &mockStorage{
-		objects: make(map[string][]byte),
+		objects:  make(map[string][]byte),
+		metadata: make(map[string]map[string]string),
 	}
 }
 
@@ -30,11 +43,18 @@ func (m *mockStorage) Upload(ctx context.Context, key string, reader io.Reader,
 	defer m.mu.Unlock()
 	data, _ := io.ReadAll(reader)
 	m.objects[key] = data
+	m.metadata[key] = metadata
 	return nil
 }
 
 func (m *mockStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
-	return nil, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, exists := m.objects[key]
+	if !exists {
+		return nil, fmt.Errorf("object not found: %s", key)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
 }
 
 func (m *mockStorage) Delete(ctx context.Context, key string) error {
@@ -84,13 +104,19 @@ func (m *mockStorage) GetPresignedURL(ctx context.Context, key string, expiratio
 }
 
 func (m *mockStorage) GetMetadata(ctx context.Context, key string) (map[string]string, error) {
-	return nil, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.metadata[key], nil
 }
 
 func (m *mockStorage) ListObjects(ctx context.Context, prefix string) ([]string, error) {
 	return nil, nil
 }
 
+func (m *mockStorage) WalkObjects(ctx context.Context, prefix string, fn func(key string) error) error {
+	return nil
+}
+
 func (m *mockStorage) GetObjectSize(ctx context.Context, key string) (int64, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -100,6 +126,13 @@ func (m *mockStorage) GetObjectSize(ctx context.Context, key string) (int64, err
 	return 0, nil
 }
 
+func (m *mockStorage) Copy(ctx context.Context, srcKey, dstKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[dstKey] = m.objects[srcKey]
+	return nil
+}
+
 func (m *mockStorage) Close() error {
 	return nil
 }
@@ -176,7 +209,7 @@ func TestService_StartStop(t *testing.T) {
 func TestService_ProcessJob(t *testing.T) {
 	db := setupTestDB(t)
 
-	service, _ := setupTestService(t, db)
+	service, store := setupTestService(t, db)
 	jobRepo := database.NewJobRepository(db)
 
 	// Create a test job
@@ -282,6 +315,22 @@ func TestService_ProcessJob(t *testing.T) {
 						t.Errorf("Expected item status 'completed', got '%s'", item.Status)
 					}
 				}
+
+				// Verify every upload carries the full object metadata
+				for _, item := range items {
+					osName, arch, _ := strings.Cut(item.Platform, "_")
+					key := storage.BuildProviderKey("registry.terraform.io", item.Namespace, item.Type, item.Version, osName, arch,
+						fmt.Sprintf("terraform-provider-%s_%s_%s.zip", item.Type, item.Version, item.Platform))
+					metadata, _ := store.GetMetadata(context.Background(), key)
+					if metadata[storage.MetadataArtifact] != storage.ArtifactProvider ||
+						metadata[storage.MetadataPlatform] != item.Platform ||
+						metadata[storage.MetadataShasum] != "abc123def456" ||
+						metadata[storage.MetadataPinned] != "true" ||
+						metadata[storage.MetadataSourceURL] == "" ||
+						metadata[storage.MetadataMirroredAt] == "" {
+						t.Errorf("Unexpected metadata for %s: %v", key, metadata)
+					}
+				}
 			}
 		}
 	}
@@ -291,6 +340,175 @@ func TestService_ProcessJob(t *testing.T) {
 	db.Close()
 }
 
+func TestService_ProcessJobRecordsMetrics(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	service, _ := setupTestService(t, db)
+	jobRepo := database.NewJobRepository(db)
+
+	reg := prometheus.NewRegistry()
+	m := metrics.NewWithRegistry(reg)
+	service.SetMetrics(m)
+
+	ctx := context.Background()
+	job := &database.DownloadJob{
+		JobType:    "provider",
+		SourceType: "api",
+		SourceData: `{"namespace":"hashicorp","type":"aws"}`,
+		Status:     "pending",
+		TotalItems: 2,
+	}
+	if err := jobRepo.Create(ctx, job); err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+	for _, platform := range []string{"linux_amd64", "darwin_amd64"} {
+		item := &database.DownloadJobItem{
+			JobID:     job.ID,
+			Namespace: "hashicorp",
+			Type:      "aws",
+			Version:   "5.0.0",
+			Platform:  platform,
+			Status:    "pending",
+		}
+		if err := jobRepo.CreateItem(ctx, item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	if err := service.processJob(ctx, job); err != nil {
+		t.Fatalf("processJob failed: %v", err)
+	}
+
+	// The duration histogram observed exactly one job
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	var samples uint64
+	for _, mf := range families {
+		if mf.GetName() != "terraform_mirror_job_duration_seconds" {
+			continue
+		}
+		for _, metric := range mf.GetMetric() {
+			samples += metric.GetHistogram().GetSampleCount()
+		}
+	}
+	if samples != 1 {
+		t.Errorf("Expected 1 job duration sample, got %d", samples)
+	}
+
+	if got := testutil.ToFloat64(m.JobsProcessed.WithLabelValues("completed")); got != 1 {
+		t.Errorf("Expected 1 completed job, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.JobItemsTotal.WithLabelValues("completed")); got != 2 {
+		t.Errorf("Expected 2 completed items, got %v", got)
+	}
+}
+
+func TestService_RecoversFromPanics(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	service, _ := setupTestService(t, db)
+	jobRepo := database.NewJobRepository(db)
+
+	reg := prometheus.NewRegistry()
+	m := metrics.NewWithRegistry(reg)
+	service.SetMetrics(m)
+
+	// The first poll panics, killing the poll loop
+	var polls atomic.Int32
+	service.pollFunc = func(ctx context.Context) bool {
+		if polls.Add(1) == 1 {
+			panic("poll exploded")
+		}
+		return service.processPendingJobs(ctx)
+	}
+
+	// Jobs from the "panic" source panic mid-processing
+	service.processJobFunc = func(ctx context.Context, job *database.DownloadJob) error {
+		if job.SourceType == "panic" {
+			panic("job exploded")
+		}
+		return service.processJob(ctx, job)
+	}
+
+	ctx := context.Background()
+	createJob := func(sourceType string) *database.DownloadJob {
+		job := &database.DownloadJob{
+			JobType:    "provider",
+			SourceType: sourceType,
+			Status:     "pending",
+			TotalItems: 1,
+		}
+		if err := jobRepo.Create(ctx, job); err != nil {
+			t.Fatalf("Failed to create job: %v", err)
+		}
+		item := &database.DownloadJobItem{
+			JobID:     job.ID,
+			Namespace: "hashicorp",
+			Type:      "aws",
+			Version:   "5.0.0",
+			Platform:  "linux_amd64",
+			Status:    "pending",
+		}
+		if err := jobRepo.CreateItem(ctx, item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+		return job
+	}
+	bad := createJob("panic")
+	good := createJob("api")
+
+	if err := service.Start(ctx); err != nil {
+		t.Fatalf("Failed to start service: %v", err)
+	}
+	defer service.Stop()
+
+	waitForStatus := func(jobID int64, want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			job, err := jobRepo.GetByID(ctx, jobID)
+			if err != nil {
+				t.Fatalf("Failed to get job: %v", err)
+			}
+			if job.Status == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Job %d: expected status %q, got %q", jobID, want, job.Status)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	waitForStatus(bad.ID, "failed")
+	waitForStatus(good.ID, "completed")
+
+	// A job queued after the panics is still picked up
+	later := createJob("api")
+	waitForStatus(later.ID, "completed")
+
+	failed, err := jobRepo.GetByID(ctx, bad.ID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if !strings.Contains(failed.ErrorMessage.String, "job panicked") {
+		t.Errorf("Expected panic error message, got %q", failed.ErrorMessage.String)
+	}
+
+	if got := testutil.ToFloat64(m.ProcessorPanics.WithLabelValues("poll_loop")); got != 1 {
+		t.Errorf("Expected 1 poll loop panic, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.ProcessorPanics.WithLabelValues("worker")); got != 1 {
+		t.Errorf("Expected 1 worker panic, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.ProcessorRestarts); got != 1 {
+		t.Errorf("Expected 1 poll loop restart, got %v", got)
+	}
+}
+
 func TestService_GetStatus(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -401,6 +619,94 @@ func TestService_MaxConcurrentJobs(t *testing.T) {
 	}
 }
 
+func TestService_PollIntervalBacksOffWhileIdle(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	service, _ := setupTestService(t, db)
+	jobRepo := database.NewJobRepository(db)
+	ctx := context.Background()
+
+	backoff := newPollBackoff(service.config.PollingInterval, 800*time.Millisecond)
+	backoff.random = func() float64 { return 0.5 } // no jitter
+
+	// Empty polls double the interval up to the maximum
+	for _, want := range []time.Duration{200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, 800 * time.Millisecond} {
+		if got := backoff.observe(service.processPendingJobs(ctx)); got != want {
+			t.Fatalf("idle poll: expected interval %v, got %v", want, got)
+		}
+	}
+
+	job := &database.DownloadJob{JobType: "provider", SourceType: "api", Status: "pending", TotalItems: 1}
+	if err := jobRepo.Create(ctx, job); err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+	item := &database.DownloadJobItem{
+		JobID:     job.ID,
+		Namespace: "hashicorp",
+		Type:      "aws",
+		Version:   "5.0.0",
+		Platform:  "linux_amd64",
+		Status:    "pending",
+	}
+	if err := jobRepo.CreateItem(ctx, item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	// Finding the job resets the interval
+	if got := backoff.observe(service.processPendingJobs(ctx)); got != service.config.PollingInterval {
+		t.Errorf("expected interval to reset to %v, got %v", service.config.PollingInterval, got)
+	}
+	service.workerWg.Wait()
+}
+
+func TestService_EnqueuedJobWakesPollLoop(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	service, _ := setupTestService(t, db)
+	// The first empty poll backs off far longer than the test waits
+	service.config.PollingInterval = time.Minute
+	service.config.MaxPollingInterval = time.Hour
+	jobRepo := database.NewJobRepository(db)
+	ctx := context.Background()
+
+	if err := service.Start(ctx); err != nil {
+		t.Fatalf("Failed to start service: %v", err)
+	}
+	defer service.Stop()
+
+	// Let the initial poll find nothing
+	time.Sleep(100 * time.Millisecond)
+
+	job := &database.DownloadJob{JobType: "provider", SourceType: "api", Status: "pending", TotalItems: 1}
+	items := []*database.DownloadJobItem{{
+		Namespace: "hashicorp",
+		Type:      "aws",
+		Version:   "5.0.0",
+		Platform:  "linux_amd64",
+		Status:    "pending",
+	}}
+	if err := jobRepo.CreateWithItems(ctx, job, items); err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := jobRepo.GetByID(ctx, job.ID)
+		if err != nil {
+			t.Fatalf("Failed to get job: %v", err)
+		}
+		if got.Status != "pending" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("enqueued job did not start without waiting out the poll interval")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
 func TestService_CancelJob(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -474,3 +780,622 @@ func TestService_CancelJob(t *testing.T) {
 		}
 	})
 }
+
+func TestService_ProcessJobItemPlatforms(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	service, _ := setupTestService(t, db)
+	jobRepo := database.NewJobRepository(db)
+	ctx := context.Background()
+
+	platforms := []string{"linux_amd64", "linux_arm", "windows_386", "darwin_amd64_v2", "linux", "Linux_amd64", "_amd64"}
+
+	for _, platform := range platforms {
+		t.Run(platform, func(t *testing.T) {
+			job := &database.DownloadJob{SourceType: "api", Status: "running", TotalItems: 1}
+			if err := jobRepo.Create(ctx, job); err != nil {
+				t.Fatalf("Failed to create job: %v", err)
+			}
+			item := &database.DownloadJobItem{
+				JobID:     job.ID,
+				Namespace: "hashicorp",
+				Type:      "aws",
+				Version:   "5.0.0",
+				Platform:  platform,
+				Status:    "pending",
+			}
+			if err := jobRepo.CreateItem(ctx, item); err != nil {
+				t.Fatalf("Failed to create item: %v", err)
+			}
+
+			err := service.processJobItem(ctx, job, item)
+
+			// The processor must accept exactly the platforms the shared parser accepts
+			if _, _, parseErr := provider.ParsePlatform(platform); parseErr != nil {
+				if err == nil || err.Error() != parseErr.Error() {
+					t.Errorf("expected %v, got %v", parseErr, err)
+				}
+				if item.Status != "failed" {
+					t.Errorf("expected item to fail, got status %q", item.Status)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected %s to be processed, got %v", platform, err)
+			}
+		})
+	}
+}
+
+// countingRegistry counts downloads made through the mock registry
+type countingRegistry struct {
+	mockRegistryClient
+	downloads atomic.Int32
+}
+
+func (c *countingRegistry) DownloadProviderComplete(ctx context.Context, namespace, providerType, version, os, arch string) *provider.DownloadResult {
+	c.downloads.Add(1)
+	return c.mockRegistryClient.DownloadProviderComplete(ctx, namespace, providerType, version, os, arch)
+}
+
+func TestService_ReindexFromStorage(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create("terraform-provider-aws_v5.0.0")
+	if err != nil {
+		t.Fatalf("Failed to build archive: %v", err)
+	}
+	f.Write([]byte("stored provider binary"))
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to build archive: %v", err)
+	}
+	data := buf.Bytes()
+	sum := sha256.Sum256(data)
+	shasum := hex.EncodeToString(sum[:])
+	h1, err := provider.PackageHashV1(data)
+	if err != nil {
+		t.Fatalf("Failed to hash archive: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		reindex        bool
+		recordedShasum string
+		wantDownloads  int32
+	}{
+		{name: "stored archive is re-indexed", reindex: true, recordedShasum: shasum, wantDownloads: 0},
+		{name: "mismatched archive is downloaded", reindex: true, recordedShasum: "0000", wantDownloads: 1},
+		{name: "disabled by default", reindex: false, recordedShasum: shasum, wantDownloads: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			defer db.Close()
+
+			service, store := setupTestService(t, db)
+			service.config.ReindexFromStorage = tt.reindex
+			service.config.VerifyReindex = true
+			registry := &countingRegistry{}
+			service.SetRegistry(registry)
+
+			ctx := context.Background()
+			filename := provider.FormatProviderFilename("aws", "5.0.0", "linux", "amd64")
+			key := storage.BuildProviderKey("registry.terraform.io", "hashicorp", "aws", "5.0.0", "linux", "amd64", filename)
+			metadata := storage.ProviderMetadata("hashicorp", "aws", "5.0.0", "linux_amd64",
+				filename, tt.recordedShasum, "https://releases.example.com/"+filename, true)
+			if err := store.Upload(ctx, key, bytes.NewReader(data), "application/zip", metadata); err != nil {
+				t.Fatalf("Failed to seed storage: %v", err)
+			}
+
+			jobRepo := database.NewJobRepository(db)
+			job := &database.DownloadJob{SourceType: "api", Status: "running", TotalItems: 1}
+			if err := jobRepo.Create(ctx, job); err != nil {
+				t.Fatalf("Failed to create job: %v", err)
+			}
+			item := &database.DownloadJobItem{
+				JobID:     job.ID,
+				Namespace: "hashicorp",
+				Type:      "aws",
+				Version:   "5.0.0",
+				Platform:  "linux_amd64",
+				Status:    "pending",
+			}
+			if err := jobRepo.CreateItem(ctx, item); err != nil {
+				t.Fatalf("Failed to create item: %v", err)
+			}
+
+			if err := service.processJobItem(ctx, job, item); err != nil {
+				t.Fatalf("processJobItem failed: %v", err)
+			}
+			if item.Status != "completed" {
+				t.Errorf("expected item to complete, got status %q", item.Status)
+			}
+			if got := registry.downloads.Load(); got != tt.wantDownloads {
+				t.Errorf("expected %d upstream downloads, got %d", tt.wantDownloads, got)
+			}
+
+			record, err := database.NewProviderRepository(db).GetByIdentity(ctx, "hashicorp", "aws", "5.0.0", "linux_amd64")
+			if err != nil || record == nil {
+				t.Fatalf("expected provider record, got %v (err %v)", record, err)
+			}
+			if tt.wantDownloads == 0 {
+				if record.Shasum != shasum {
+					t.Errorf("expected shasum %s, got %s", shasum, record.Shasum)
+				}
+				if record.S3Key != key {
+					t.Errorf("expected key %s, got %s", key, record.S3Key)
+				}
+				if record.SizeBytes != int64(len(data)) {
+					t.Errorf("expected size %d, got %d", len(data), record.SizeBytes)
+				}
+				if record.DownloadURL != "https://releases.example.com/"+filename {
+					t.Errorf("unexpected download URL %s", record.DownloadURL)
+				}
+				if record.Verified {
+					t.Error("expected a re-indexed archive to be unverified")
+				}
+				if record.H1Hash.String != h1 {
+					t.Errorf("expected h1 hash %s, got %q", h1, record.H1Hash.String)
+				}
+			}
+		})
+	}
+}
+
+// signedRegistry reports every download as matching a signed SHA256SUMS
+type signedRegistry struct {
+	mockRegistryClient
+}
+
+func (s *signedRegistry) DownloadProviderComplete(ctx context.Context, namespace, providerType, version, os, arch string) *provider.DownloadResult {
+	result := s.mockRegistryClient.DownloadProviderComplete(ctx, namespace, providerType, version, os, arch)
+	result.Verified = true
+	return result
+}
+
+func TestService_ProcessJobItemRecordsVerification(t *testing.T) {
+	tests := []struct {
+		name     string
+		registry provider.RegistryDownloader
+		want     bool
+	}{
+		{name: "signed download is verified", registry: &signedRegistry{}, want: true},
+		{name: "unsigned download is unverified", registry: &mockRegistryClient{}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			defer db.Close()
+
+			service, _ := setupTestService(t, db)
+			service.SetRegistry(tt.registry)
+			jobRepo := database.NewJobRepository(db)
+			ctx := context.Background()
+
+			job := &database.DownloadJob{SourceType: "api", Status: "running", TotalItems: 1}
+			if err := jobRepo.Create(ctx, job); err != nil {
+				t.Fatalf("Failed to create job: %v", err)
+			}
+			item := &database.DownloadJobItem{
+				JobID:     job.ID,
+				Namespace: "hashicorp",
+				Type:      "aws",
+				Version:   "5.0.0",
+				Platform:  "linux_amd64",
+				Status:    "pending",
+			}
+			if err := jobRepo.CreateItem(ctx, item); err != nil {
+				t.Fatalf("Failed to create item: %v", err)
+			}
+
+			if err := service.processJobItem(ctx, job, item); err != nil {
+				t.Fatalf("processJobItem failed: %v", err)
+			}
+
+			record, err := database.NewProviderRepository(db).GetByIdentity(ctx, "hashicorp", "aws", "5.0.0", "linux_amd64")
+			if err != nil || record == nil {
+				t.Fatalf("expected provider record, got %v (err %v)", record, err)
+			}
+			if record.Verified != tt.want {
+				t.Errorf("expected verified %v, got %v", tt.want, record.Verified)
+			}
+		})
+	}
+}
+
+func TestService_ProcessJobItemExpectedShasums(t *testing.T) {
+	sum := sha256.Sum256([]byte("mock provider binary data"))
+	mockShasum := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name     string
+		expected string
+		want     string
+	}{
+		{name: "unpinned", expected: "", want: "completed"},
+		{name: "matching", expected: "0000," + strings.ToUpper(mockShasum), want: "completed"},
+		{name: "mismatched", expected: "0000,1111", want: "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			defer db.Close()
+
+			service, _ := setupTestService(t, db)
+			jobRepo := database.NewJobRepository(db)
+			ctx := context.Background()
+
+			job := &database.DownloadJob{SourceType: "lockfile", Status: "running", TotalItems: 1}
+			if err := jobRepo.Create(ctx, job); err != nil {
+				t.Fatalf("Failed to create job: %v", err)
+			}
+			item := &database.DownloadJobItem{
+				JobID:     job.ID,
+				Namespace: "hashicorp",
+				Type:      "aws",
+				Version:   "5.0.0",
+				Platform:  "linux_amd64",
+				Status:    "pending",
+			}
+			if tt.expected != "" {
+				item.ExpectedShasums = sql.NullString{String: tt.expected, Valid: true}
+			}
+			if err := jobRepo.CreateItem(ctx, item); err != nil {
+				t.Fatalf("Failed to create item: %v", err)
+			}
+
+			service.processJobItem(ctx, job, item)
+
+			if item.Status != tt.want {
+				t.Errorf("expected status %q, got %q (%s)", tt.want, item.Status, item.ErrorMessage.String)
+			}
+			record, err := database.NewProviderRepository(db).GetByIdentity(ctx, "hashicorp", "aws", "5.0.0", "linux_amd64")
+			if err != nil {
+				t.Fatalf("Failed to look up provider: %v", err)
+			}
+			if (record != nil) != (tt.want == "completed") {
+				t.Errorf("expected provider record only on success, got %v", record)
+			}
+		})
+	}
+}
+
+func TestService_StoresUnderUpstreamHostname(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	service, store := setupTestService(t, db)
+	service.SetUpstreams(provider.NewUpstreamRouter([]config.UpstreamRegistryConfig{
+		{Hostname: "registry.example.com", Namespaces: []string{"acme"}},
+	}))
+	// Keep the routing for storage keys but serve downloads from the mock
+	service.SetRegistry(&mockRegistryClient{})
+
+	jobRepo := database.NewJobRepository(db)
+	ctx := context.Background()
+	job := &database.DownloadJob{SourceType: "api", Status: "running", TotalItems: 2}
+	if err := jobRepo.Create(ctx, job); err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	want := map[string]string{
+		"acme":      storage.BuildProviderKey("registry.example.com", "acme", "widget", "1.0.0", "linux", "amd64", "terraform-provider-widget_1.0.0_linux_amd64.zip"),
+		"hashicorp": storage.BuildProviderKey("registry.terraform.io", "hashicorp", "widget", "1.0.0", "linux", "amd64", "terraform-provider-widget_1.0.0_linux_amd64.zip"),
+	}
+	for namespace, key := range want {
+		item := &database.DownloadJobItem{
+			JobID:     job.ID,
+			Namespace: namespace,
+			Type:      "widget",
+			Version:   "1.0.0",
+			Platform:  "linux_amd64",
+			Status:    "pending",
+		}
+		if err := jobRepo.CreateItem(ctx, item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+		if err := service.processJobItem(ctx, job, item); err != nil {
+			t.Fatalf("processJobItem failed: %v", err)
+		}
+		if exists, _ := store.Exists(ctx, key); !exists {
+			t.Errorf("expected %s provider at %s, have %v", namespace, key, store.objects)
+		}
+	}
+}
+
+func TestService_StoresShasumsForCompleteVersions(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	service, store := setupTestService(t, db)
+	service.config.StoreShasums = true
+	jobRepo := database.NewJobRepository(db)
+	providerRepo := database.NewProviderRepository(db)
+	ctx := context.Background()
+
+	job := &database.DownloadJob{JobType: "provider", SourceType: "api", Status: "running", TotalItems: 3}
+	if err := jobRepo.Create(ctx, job); err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+	// 1.0.0 downloads completely; 2.0.0 has a platform that cannot be parsed
+	for _, item := range []*database.DownloadJobItem{
+		{Version: "1.0.0", Platform: "linux_amd64"},
+		{Version: "1.0.0", Platform: "darwin_arm64"},
+		{Version: "2.0.0", Platform: "invalid"},
+	} {
+		item.JobID = job.ID
+		item.Namespace = "hashicorp"
+		item.Type = "widget"
+		item.Status = "pending"
+		if err := jobRepo.CreateItem(ctx, item); err != nil {
+			t.Fatalf("Failed to create item: %v", err)
+		}
+	}
+
+	if err := service.processProviderJob(ctx, job); err != nil {
+		t.Fatalf("processProviderJob failed: %v", err)
+	}
+
+	key := storage.BuildProviderShasumsKey("registry.terraform.io", "hashicorp", "widget", "1.0.0")
+	want := "abc123def456  terraform-provider-widget_1.0.0_darwin_arm64.zip\n" +
+		"abc123def456  terraform-provider-widget_1.0.0_linux_amd64.zip\n"
+	if got := string(store.objects[key]); got != want {
+		t.Errorf("expected SHA256SUMS %q at %s, got %q", want, key, got)
+	}
+	if recorded, err := providerRepo.GetShasumsKey(ctx, "hashicorp", "widget", "1.0.0"); err != nil || recorded != key {
+		t.Errorf("expected recorded key %s, got %q (%v)", key, recorded, err)
+	}
+
+	incomplete := storage.BuildProviderShasumsKey("registry.terraform.io", "hashicorp", "widget", "2.0.0")
+	if _, ok := store.objects[incomplete]; ok {
+		t.Errorf("expected no SHA256SUMS for an incomplete version at %s", incomplete)
+	}
+
+	// A later job that adds a platform without storing a new document
+	// removes the one that no longer lists every platform
+	service.config.StoreShasums = false
+	next := &database.DownloadJob{JobType: "provider", SourceType: "api", Status: "running", TotalItems: 1}
+	if err := jobRepo.Create(ctx, next); err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+	item := &database.DownloadJobItem{
+		JobID:     next.ID,
+		Namespace: "hashicorp",
+		Type:      "widget",
+		Version:   "1.0.0",
+		Platform:  "windows_amd64",
+		Status:    "pending",
+	}
+	if err := jobRepo.CreateItem(ctx, item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	if err := service.processProviderJob(ctx, next); err != nil {
+		t.Fatalf("processProviderJob failed: %v", err)
+	}
+
+	if _, ok := store.objects[key]; ok {
+		t.Errorf("expected stale SHA256SUMS at %s to be removed", key)
+	}
+	if keys, err := providerRepo.ListShasumsKeys(ctx); err != nil || len(keys) != 0 {
+		t.Errorf("expected no recorded SHA256SUMS, got %v (%v)", keys, err)
+	}
+}
+
+func TestService_MarkExistingItemsUpdateFails(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	service, _ := setupTestService(t, db)
+	jobRepo := database.NewJobRepository(db)
+	ctx := context.Background()
+
+	if err := database.NewProviderRepository(db).Create(ctx, &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "aws",
+		Version:   "5.0.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-aws_5.0.0_linux_amd64.zip",
+		Shasum:    "abc123",
+		S3Key:     "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip",
+	}); err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	job := &database.DownloadJob{JobType: "provider", SourceType: "api", Status: "running", TotalItems: 1}
+	if err := jobRepo.Create(ctx, job); err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+	item := &database.DownloadJobItem{
+		JobID:     job.ID,
+		Namespace: "hashicorp",
+		Type:      "aws",
+		Version:   "5.0.0",
+		Platform:  "linux_amd64",
+		Status:    "pending",
+	}
+	if err := jobRepo.CreateItem(ctx, item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	// Items can no longer be updated
+	if _, err := db.Conn().Exec(`CREATE TRIGGER fail_item_updates BEFORE UPDATE ON download_job_items
+		BEGIN SELECT RAISE(FAIL, 'item updates disabled'); END`); err != nil {
+		t.Fatalf("Failed to create trigger: %v", err)
+	}
+
+	err := service.processProviderJob(ctx, job)
+	if err == nil || !strings.Contains(err.Error(), "item updates disabled") {
+		t.Fatalf("expected the item update error, got %v", err)
+	}
+
+	updated, err := jobRepo.GetByID(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if updated.Status != "failed" {
+		t.Errorf("expected job status failed, got %q", updated.Status)
+	}
+	if !strings.Contains(updated.ErrorMessage.String, "failed to mark item") {
+		t.Errorf("expected the error to be recorded, got %q", updated.ErrorMessage.String)
+	}
+}
+
+func TestService_ProcessJobSkipsCancelledJob(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	service, store := setupTestService(t, db)
+	jobRepo := database.NewJobRepository(db)
+	ctx := context.Background()
+
+	job := &database.DownloadJob{JobType: "provider", SourceType: "api", Status: "pending", TotalItems: 1}
+	if err := jobRepo.Create(ctx, job); err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+	item := &database.DownloadJobItem{
+		JobID:     job.ID,
+		Namespace: "hashicorp",
+		Type:      "aws",
+		Version:   "5.0.0",
+		Platform:  "linux_amd64",
+		Status:    "pending",
+	}
+	if err := jobRepo.CreateItem(ctx, item); err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	// The poll loop lists the job, then it is cancelled before the worker claims it
+	listed, err := jobRepo.ListPending(ctx, 10)
+	if err != nil || len(listed) != 1 {
+		t.Fatalf("Failed to list pending job: %v (%d jobs)", err, len(listed))
+	}
+	if _, err := jobRepo.CancelPending(ctx, "cancelled"); err != nil {
+		t.Fatalf("Failed to cancel pending jobs: %v", err)
+	}
+
+	if err := service.processJob(ctx, listed[0]); err != nil {
+		t.Fatalf("processJob failed: %v", err)
+	}
+
+	updated, err := jobRepo.GetByID(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if updated.Status != "cancelled" {
+		t.Errorf("expected job to stay cancelled, got %q", updated.Status)
+	}
+	if len(store.objects) != 0 {
+		t.Errorf("expected no downloads for a cancelled job, got %d objects", len(store.objects))
+	}
+}
+
+func TestService_SetMaxConcurrentJobs(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	service, _ := setupTestService(t, db)
+	service.config.MaxConcurrentJobs = 1
+	jobRepo := database.NewJobRepository(db)
+	ctx := context.Background()
+
+	if err := service.SetMaxConcurrentJobs(0); err == nil {
+		t.Error("expected an error for a limit of 0")
+	}
+
+	// Jobs claim themselves and then block until released, recording how
+	// many run at once
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	service.processJobFunc = func(ctx context.Context, job *database.DownloadJob) error {
+		if _, err := jobRepo.Claim(ctx, job); err != nil {
+			return err
+		}
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		return nil
+	}
+
+	for i := 0; i < 3; i++ {
+		job := &database.DownloadJob{JobType: "provider", SourceType: "api", Status: "pending", TotalItems: 1}
+		if err := jobRepo.Create(ctx, job); err != nil {
+			t.Fatalf("Failed to create job %d: %v", i, err)
+		}
+	}
+
+	if err := service.Start(ctx); err != nil {
+		t.Fatalf("Failed to start service: %v", err)
+	}
+	defer func() {
+		close(release)
+		service.Stop()
+	}()
+
+	waitForRunning := func(want int32) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for running.Load() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d running jobs, got %d", want, running.Load())
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	// Several polls pass with only one job running
+	waitForRunning(1)
+	time.Sleep(3 * service.config.PollingInterval)
+	if got := peak.Load(); got != 1 {
+		t.Fatalf("expected 1 job at a time before raising the limit, got %d", got)
+	}
+
+	if err := service.SetMaxConcurrentJobs(3); err != nil {
+		t.Fatalf("SetMaxConcurrentJobs failed: %v", err)
+	}
+	waitForRunning(3)
+
+	if got := service.GetStatus()["max_concurrent_jobs"]; got != 3 {
+		t.Errorf("expected status to report 3 max concurrent jobs, got %v", got)
+	}
+	if got := service.GetConfig().MaxConcurrentJobs; got != 3 {
+		t.Errorf("expected config to report 3 max concurrent jobs, got %d", got)
+	}
+}
+
+func TestService_TrackJob(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	service, _ := setupTestService(t, db)
+
+	ctx, done := service.TrackJob(context.Background(), 42)
+	if !service.IsJobActive(42) {
+		t.Error("expected tracked job to be active")
+	}
+	if got := service.GetStatus()["active_jobs"]; got != 0 {
+		t.Errorf("tracked jobs should not use processor slots, got %v active jobs", got)
+	}
+
+	if !service.CancelJob(42) {
+		t.Fatal("expected CancelJob to cancel the tracked job")
+	}
+	select {
+	case <-ctx.Done():
+	default:
+		t.Fatal("expected the tracked job's context to be cancelled")
+	}
+
+	done()
+	if service.IsJobActive(42) || service.CancelJob(42) {
+		t.Error("expected the job to be untracked once done")
+	}
+}
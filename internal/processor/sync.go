@@ -0,0 +1,422 @@
+package processor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/module"
+	"github.com/ned1313/terraform-mirror/internal/provider"
+	"github.com/ned1313/terraform-mirror/internal/schedule"
+)
+
+// syncSourceType marks jobs created by the scheduled re-sync
+const syncSourceType = "sync"
+
+// SyncConfig holds the scheduled re-sync configuration
+type SyncConfig struct {
+	Schedule  *schedule.Schedule // When to run
+	Providers bool               // Re-sync mirrored providers
+	Modules   bool               // Re-sync mirrored modules
+
+	// Platforms are used for providers that have no mirrored platforms to copy
+	Platforms []string
+
+	// MaxStorageBytes skips runs while mirrored content is at or above this
+	// size; 0 disables the check
+	MaxStorageBytes int64
+
+	// ProviderAllowed and ModuleNamespaceAllowed filter which providers and
+	// module namespaces are re-synced; nil allows everything
+	ProviderAllowed        func(namespace, providerType string) bool
+	ModuleNamespaceAllowed func(namespace string) bool
+}
+
+// SyncResult summarizes a single re-sync run
+type SyncResult struct {
+	ProviderJobID int64
+	ProviderItems int
+	ModuleJobID   int64
+	ModuleItems   int
+	SkippedReason string // Set when the run was skipped entirely
+}
+
+// SyncScheduler periodically looks for upstream versions newer than those
+// mirrored and enqueues download jobs for them. The jobs are picked up by the
+// processor like any other pending job.
+type SyncScheduler struct {
+	config           SyncConfig
+	jobRepo          *database.JobRepository
+	providerRepo     *database.ProviderRepository
+	moduleRepo       *database.ModuleRepository
+	moduleJobRepo    *database.ModuleJobRepository
+	providerRegistry provider.RegistryDownloader
+	moduleRegistry   module.RegistryDownloader
+
+	mu      sync.Mutex
+	running bool
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+// NewSyncScheduler creates a re-sync scheduler using the given upstream module registry
+func NewSyncScheduler(config SyncConfig, db *database.DB, moduleRegistry string) *SyncScheduler {
+	return &SyncScheduler{
+		config:           config,
+		jobRepo:          database.NewJobRepository(db),
+		providerRepo:     database.NewProviderRepository(db),
+		moduleRepo:       database.NewModuleRepository(db),
+		moduleJobRepo:    database.NewModuleJobRepository(db),
+		providerRegistry: provider.NewRegistryClient(),
+		moduleRegistry:   module.NewRegistryClient(moduleRegistry),
+		stopCh:           make(chan struct{}),
+		doneCh:           make(chan struct{}),
+	}
+}
+
+// SetProviderRegistry allows injection of a mock provider registry for testing
+func (s *SyncScheduler) SetProviderRegistry(registry provider.RegistryDownloader) {
+	s.providerRegistry = registry
+}
+
+// SetModuleRegistry allows injection of a mock module registry for testing
+func (s *SyncScheduler) SetModuleRegistry(registry module.RegistryDownloader) {
+	s.moduleRegistry = registry
+}
+
+// Start begins running the re-sync on its schedule
+func (s *SyncScheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return fmt.Errorf("sync scheduler already running")
+	}
+	if s.config.Schedule == nil {
+		return fmt.Errorf("sync schedule is not set")
+	}
+	s.running = true
+
+	log.Printf("Starting upstream sync scheduler (schedule %q)", s.config.Schedule)
+	go s.loop(ctx)
+	return nil
+}
+
+// Stop stops the scheduler, waiting for a run in progress to finish
+func (s *SyncScheduler) Stop() error {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return fmt.Errorf("sync scheduler not running")
+	}
+	s.running = false
+	s.mu.Unlock()
+
+	close(s.stopCh)
+	<-s.doneCh
+	log.Println("Upstream sync scheduler stopped")
+	return nil
+}
+
+// loop waits for each scheduled time and runs the re-sync
+func (s *SyncScheduler) loop(ctx context.Context) {
+	defer close(s.doneCh)
+
+	for {
+		next := s.config.Schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("Sync schedule %q never fires, stopping scheduler", s.config.Schedule)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-s.stopCh:
+			timer.Stop()
+			return
+		case <-timer.C:
+			result, err := s.RunOnce(ctx)
+			if err != nil {
+				log.Printf("Upstream sync failed: %v", err)
+				continue
+			}
+			if result.SkippedReason != "" {
+				log.Printf("Upstream sync skipped: %s", result.SkippedReason)
+				continue
+			}
+			log.Printf("Upstream sync queued %d provider items (job %d) and %d module items (job %d)",
+				result.ProviderItems, result.ProviderJobID, result.ModuleItems, result.ModuleJobID)
+		}
+	}
+}
+
+// RunOnce checks upstream for new versions of every mirrored provider and
+// module and enqueues a pending job for each kind that has new versions
+func (s *SyncScheduler) RunOnce(ctx context.Context) (*SyncResult, error) {
+	result := &SyncResult{}
+
+	// Don't stack runs while an earlier re-sync is still waiting or running
+	busy, err := s.syncJobOutstanding(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if busy {
+		result.SkippedReason = "a previous sync job has not finished"
+		return result, nil
+	}
+
+	if s.config.MaxStorageBytes > 0 {
+		used, err := s.storageUsed(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if used >= s.config.MaxStorageBytes {
+			result.SkippedReason = fmt.Sprintf("storage quota reached (%d of %d bytes used)", used, s.config.MaxStorageBytes)
+			return result, nil
+		}
+	}
+
+	if s.config.Providers {
+		if err := s.syncProviders(ctx, result); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.config.Modules {
+		if err := s.syncModules(ctx, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// syncJobOutstanding reports whether a re-sync job is pending or running
+func (s *SyncScheduler) syncJobOutstanding(ctx context.Context) (bool, error) {
+	for _, status := range []string{"pending", "running"} {
+		jobs, err := s.jobRepo.ListByStatus(ctx, status, 1000, 0)
+		if err != nil {
+			return false, fmt.Errorf("failed to list %s jobs: %w", status, err)
+		}
+		for _, job := range jobs {
+			if job.SourceType == syncSourceType {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// storageUsed returns the total size of mirrored providers and modules
+func (s *SyncScheduler) storageUsed(ctx context.Context) (int64, error) {
+	providerStats, err := s.providerRepo.GetStorageStats(ctx)
+	if err != nil {
+		return 0, err
+	}
+	moduleStats, err := s.moduleRepo.GetStorageStats(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return providerStats.TotalSizeBytes + moduleStats.TotalSizeBytes, nil
+}
+
+// syncProviders enqueues new versions of mirrored providers on the platforms
+// already mirrored for each provider
+func (s *SyncScheduler) syncProviders(ctx context.Context, result *SyncResult) error {
+	names, err := s.providerRepo.ListNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	var items []*database.DownloadJobItem
+	var synced []string
+	for _, name := range names {
+		if s.config.ProviderAllowed != nil && !s.config.ProviderAllowed(name.Namespace, name.Type) {
+			continue
+		}
+
+		stored, err := s.providerRepo.ListVersions(ctx, name.Namespace, name.Type)
+		if err != nil {
+			return err
+		}
+
+		var versions []string
+		platformSet := make(map[string]bool)
+		for _, p := range stored {
+			versions = append(versions, p.Version)
+			platformSet[p.Platform] = true
+		}
+		platforms := make([]string, 0, len(platformSet))
+		for platform := range platformSet {
+			platforms = append(platforms, platform)
+		}
+		sort.Strings(platforms)
+		if len(platforms) == 0 {
+			platforms = s.config.Platforms
+		}
+
+		upstream, err := s.providerRegistry.GetAvailableVersions(ctx, name.Namespace, name.Type)
+		if err != nil {
+			log.Printf("Sync: failed to list upstream versions for %s/%s: %v", name.Namespace, name.Type, err)
+			continue
+		}
+
+		newVersions := newerVersions(upstream, versions)
+		for _, version := range newVersions {
+			for _, platform := range platforms {
+				items = append(items, &database.DownloadJobItem{
+					Namespace: name.Namespace,
+					Type:      name.Type,
+					Version:   version,
+					Platform:  platform,
+					Status:    "pending",
+				})
+			}
+		}
+		if len(newVersions) > 0 {
+			synced = append(synced, fmt.Sprintf("%s/%s: %s", name.Namespace, name.Type, strings.Join(newVersions, ",")))
+		}
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	job := &database.DownloadJob{
+		JobType:    "provider",
+		UserID:     sql.NullInt64{},
+		SourceType: syncSourceType,
+		SourceData: strings.Join(synced, "\n"),
+		Status:     "pending",
+		TotalItems: len(items),
+		CreatedAt:  time.Now(),
+	}
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		return fmt.Errorf("failed to create provider sync job: %w", err)
+	}
+	for _, item := range items {
+		item.JobID = job.ID
+		if err := s.jobRepo.CreateItem(ctx, item); err != nil {
+			return fmt.Errorf("failed to create provider sync job item: %w", err)
+		}
+	}
+	s.jobRepo.NotifyEnqueued()
+
+	result.ProviderJobID = job.ID
+	result.ProviderItems = len(items)
+	return nil
+}
+
+// syncModules enqueues new versions of mirrored modules
+func (s *SyncScheduler) syncModules(ctx context.Context, result *SyncResult) error {
+	names, err := s.moduleRepo.ListNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	var items []*database.ModuleJobItem
+	var synced []string
+	for _, name := range names {
+		if s.config.ModuleNamespaceAllowed != nil && !s.config.ModuleNamespaceAllowed(name.Namespace) {
+			continue
+		}
+
+		stored, err := s.moduleRepo.ListVersions(ctx, name.Namespace, name.Name, name.System)
+		if err != nil {
+			return err
+		}
+		if len(stored) == 0 {
+			// Every version is blocked; leave the module alone
+			continue
+		}
+
+		var versions []string
+		for _, m := range stored {
+			versions = append(versions, m.Version)
+		}
+
+		upstream, err := s.moduleRegistry.GetAvailableVersions(ctx, name.Namespace, name.Name, name.System)
+		if err != nil {
+			log.Printf("Sync: failed to list upstream versions for %s/%s/%s: %v", name.Namespace, name.Name, name.System, err)
+			continue
+		}
+
+		newVersions := newerVersions(upstream, versions)
+		for _, version := range newVersions {
+			items = append(items, &database.ModuleJobItem{
+				Namespace: name.Namespace,
+				Name:      name.Name,
+				System:    name.System,
+				Version:   version,
+				Status:    "pending",
+			})
+		}
+		if len(newVersions) > 0 {
+			synced = append(synced, fmt.Sprintf("%s/%s/%s: %s", name.Namespace, name.Name, name.System, strings.Join(newVersions, ",")))
+		}
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	job := &database.DownloadJob{
+		JobType:    "module",
+		UserID:     sql.NullInt64{},
+		SourceType: syncSourceType,
+		SourceData: strings.Join(synced, "\n"),
+		Status:     "pending",
+		TotalItems: len(items),
+		CreatedAt:  time.Now(),
+	}
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		return fmt.Errorf("failed to create module sync job: %w", err)
+	}
+	for _, item := range items {
+		item.JobID = job.ID
+		if err := s.moduleJobRepo.CreateItem(ctx, item); err != nil {
+			return fmt.Errorf("failed to create module sync job item: %w", err)
+		}
+	}
+	s.jobRepo.NotifyEnqueued()
+
+	result.ModuleJobID = job.ID
+	result.ModuleItems = len(items)
+	return nil
+}
+
+// newerVersions returns the upstream release versions newer than every stored
+// version, oldest first. Pre-releases are never picked up by the re-sync.
+func newerVersions(upstream, stored []string) []string {
+	var latest string
+	for _, v := range stored {
+		if latest == "" || provider.CompareVersions(v, latest) > 0 {
+			latest = v
+		}
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, v := range upstream {
+		if seen[v] || strings.ContainsAny(v, "-+") {
+			continue
+		}
+		if latest == "" || provider.CompareVersions(v, latest) > 0 {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return provider.CompareVersions(result[i], result[j]) < 0
+	})
+	return result
+}
@@ -0,0 +1,167 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/module"
+	"github.com/ned1313/terraform-mirror/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncProviderRegistry lists fixed upstream provider versions
+type syncProviderRegistry struct {
+	versions map[string][]string // key: namespace/type
+}
+
+func (r *syncProviderRegistry) DownloadProviderComplete(ctx context.Context, namespace, providerType, version, os, arch string) *provider.DownloadResult {
+	return &provider.DownloadResult{Error: fmt.Errorf("not implemented")}
+}
+
+func (r *syncProviderRegistry) GetAvailableVersions(ctx context.Context, namespace, providerType string) ([]string, error) {
+	return r.versions[namespace+"/"+providerType], nil
+}
+
+// syncModuleRegistry lists fixed upstream module versions
+type syncModuleRegistry struct {
+	versions map[string][]string // key: namespace/name/system
+}
+
+func (r *syncModuleRegistry) GetAvailableVersions(ctx context.Context, namespace, name, system string) ([]string, error) {
+	return r.versions[namespace+"/"+name+"/"+system], nil
+}
+
+func (r *syncModuleRegistry) GetDownloadURL(ctx context.Context, namespace, name, system, version string) (string, error) {
+	return "", fmt.Errorf("not implemented")
+}
+
+func (r *syncModuleRegistry) DownloadModule(ctx context.Context, downloadURL string) ([]byte, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r *syncModuleRegistry) DownloadModuleComplete(ctx context.Context, namespace, name, system, version string) *module.DownloadResult {
+	return &module.DownloadResult{Error: fmt.Errorf("not implemented")}
+}
+
+// seedSyncMirror stores hashicorp/random 3.0.0 on two platforms and one module version
+func seedSyncMirror(t *testing.T, db *database.DB) {
+	ctx := context.Background()
+	providerRepo := database.NewProviderRepository(db)
+	for _, platform := range []string{"linux_amd64", "darwin_arm64"} {
+		require.NoError(t, providerRepo.Create(ctx, &database.Provider{
+			Namespace: "hashicorp",
+			Type:      "random",
+			Version:   "3.0.0",
+			Platform:  platform,
+			Filename:  "terraform-provider-random_3.0.0_" + platform + ".zip",
+			S3Key:     "providers/registry.terraform.io/hashicorp/random/3.0.0/" + platform + "/terraform-provider-random_3.0.0_" + platform + ".zip",
+			SizeBytes: 100,
+		}))
+	}
+	require.NoError(t, database.NewModuleRepository(db).Create(ctx, &database.Module{
+		Namespace: "terraform-aws-modules",
+		Name:      "vpc",
+		System:    "aws",
+		Version:   "5.0.0",
+		S3Key:     "modules/terraform-aws-modules/vpc/aws/5.0.0/vpc.tar.gz",
+		Filename:  "vpc.tar.gz",
+		SizeBytes: 100,
+	}))
+}
+
+func newTestSyncScheduler(t *testing.T, db *database.DB, config SyncConfig) *SyncScheduler {
+	s := NewSyncScheduler(config, db, "")
+	s.SetProviderRegistry(&syncProviderRegistry{versions: map[string][]string{
+		"hashicorp/random": {"2.9.0", "3.0.0", "3.1.0-beta1", "3.1.0"},
+	}})
+	s.SetModuleRegistry(&syncModuleRegistry{versions: map[string][]string{
+		"terraform-aws-modules/vpc/aws": {"4.0.0", "5.0.0", "5.1.0", "5.2.0"},
+	}})
+	return s
+}
+
+func TestSyncScheduler_RunOnceEnqueuesNewVersions(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	seedSyncMirror(t, db)
+	ctx := context.Background()
+
+	s := newTestSyncScheduler(t, db, SyncConfig{Providers: true, Modules: true})
+	result, err := s.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, result.SkippedReason)
+
+	// 3.1.0 is new; 2.9.0 is older and 3.1.0-beta1 is a pre-release
+	require.NotZero(t, result.ProviderJobID)
+	assert.Equal(t, 2, result.ProviderItems)
+
+	jobRepo := database.NewJobRepository(db)
+	job, err := jobRepo.GetByID(ctx, result.ProviderJobID)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", job.Status)
+	assert.Equal(t, "provider", job.JobType)
+	assert.Equal(t, "sync", job.SourceType)
+
+	items, err := jobRepo.GetItems(ctx, result.ProviderJobID)
+	require.NoError(t, err)
+	var tuples []string
+	for _, item := range items {
+		tuples = append(tuples, item.Version+"/"+item.Platform)
+	}
+	assert.ElementsMatch(t, []string{"3.1.0/linux_amd64", "3.1.0/darwin_arm64"}, tuples)
+
+	require.NotZero(t, result.ModuleJobID)
+	assert.Equal(t, 2, result.ModuleItems)
+	moduleItems, err := database.NewModuleJobRepository(db).ListByJob(ctx, result.ModuleJobID)
+	require.NoError(t, err)
+	var moduleVersions []string
+	for _, item := range moduleItems {
+		moduleVersions = append(moduleVersions, item.Version)
+	}
+	assert.ElementsMatch(t, []string{"5.1.0", "5.2.0"}, moduleVersions)
+
+	// A second run waits for the outstanding sync jobs
+	result, err = s.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.SkippedReason)
+	assert.Zero(t, result.ProviderJobID)
+}
+
+func TestSyncScheduler_RespectsNamespaceFilter(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	seedSyncMirror(t, db)
+
+	s := newTestSyncScheduler(t, db, SyncConfig{
+		Providers:              true,
+		Modules:                true,
+		ProviderAllowed:        func(namespace, providerType string) bool { return namespace != "hashicorp" },
+		ModuleNamespaceAllowed: func(namespace string) bool { return false },
+	})
+	result, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, result.ProviderJobID)
+	assert.Zero(t, result.ModuleJobID)
+}
+
+func TestSyncScheduler_RespectsQuota(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	seedSyncMirror(t, db)
+
+	// The seeded content is 300 bytes
+	s := newTestSyncScheduler(t, db, SyncConfig{Providers: true, Modules: true, MaxStorageBytes: 300})
+	result, err := s.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, result.SkippedReason, "quota")
+	assert.Zero(t, result.ProviderJobID)
+}
+
+func TestNewerVersions(t *testing.T) {
+	assert.Equal(t, []string{"1.10.0", "2.0.0"}, newerVersions([]string{"2.0.0", "1.9.0", "1.10.0", "1.2.0"}, []string{"1.9.0", "1.2.0"}))
+	assert.Equal(t, []string{"1.0.0"}, newerVersions([]string{"1.0.0", "1.1.0-rc1"}, nil))
+	assert.Empty(t, newerVersions([]string{"1.0.0"}, []string{"1.0.0"}))
+}
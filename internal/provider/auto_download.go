@@ -3,13 +3,17 @@ package provider
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/ned1313/terraform-mirror/internal/config"
 	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/metrics"
 	"github.com/ned1313/terraform-mirror/internal/storage"
 	"golang.org/x/time/rate"
 )
@@ -19,6 +23,7 @@ type AutoDownloadService struct {
 	config       *config.AutoDownloadConfig
 	providerCfg  *config.ProvidersConfig
 	registry     RegistryDownloader
+	upstreams    *UpstreamRouter
 	storage      storage.Storage
 	providerRepo *database.ProviderRepository
 	logger       *log.Logger
@@ -29,18 +34,41 @@ type AutoDownloadService struct {
 	// Concurrency control
 	semaphore chan struct{}
 
+	// Bounded queue for background platform downloads
+	backgroundQueue chan backgroundDownload
+	backgroundOnce  sync.Once
+
+	// Background work runs under ctx until Close cancels it. closed stops
+	// new work from being queued while workers wait for it to drain.
+	ctx       context.Context
+	cancel    context.CancelFunc
+	workers   sync.WaitGroup
+	closed    bool
+	closedMu  sync.Mutex
+	closeOnce sync.Once
+
 	// In-flight download tracking to prevent duplicate downloads
-	inFlight   map[string]chan *downloadResult
+	inFlight   map[string]*inFlightDownload
 	inFlightMu sync.Mutex
 
 	// Negative cache for "not found" responses
 	negativeCache   map[string]time.Time
 	negativeCacheMu sync.RWMutex
 
-	// Metrics
+	// Guards the allow/block namespace lists, which can change at runtime
+	namespacesMu sync.RWMutex
+
+	// Versions being downloaded, per provider and version, that count
+	// against MaxVersionsPerProvider before their rows exist
+	reservedVersions map[string]map[string]int
+	reservedMu       sync.Mutex
+
+	// Metrics. The in-memory stats back the JSON endpoint; the Prometheus
+	// metrics, when set, are updated alongside them.
 	stats     AutoDownloadStats
 	statsMu   sync.RWMutex
 	startTime time.Time
+	metrics   *metrics.Metrics
 }
 
 // AutoDownloadStats contains statistics about auto-download operations
@@ -48,20 +76,57 @@ type AutoDownloadStats struct {
 	TotalRequests       int64
 	SuccessfulDownloads int64
 	FailedDownloads     int64
-	CacheHits           int64
+	CacheHits           int64 // Requests answered by an identical artifact already mirrored
 	NegativeCacheHits   int64
 	RateLimitedCount    int64
-	NamespaceBlocked    int64
+	NamespaceBlocked    int64 // Requests refused by the namespace or provider allow/block lists
+	VersionCapRefused   int64 // Requests refused because the provider already has MaxVersionsPerProvider versions
 	InFlightCoalesced   int64
 	BytesDownloaded     int64
+	BackgroundQueued    int64 // Background platform downloads accepted into the queue
+	BackgroundDropped   int64 // Background platform downloads dropped because the queue was full
 }
 
-// downloadResult is used for coalescing in-flight requests
-type downloadResult struct {
+// backgroundDownload is a queued download of an additional platform
+type backgroundDownload struct {
+	namespace    string
+	providerType string
+	version      string
+	os           string
+	arch         string
+}
+
+// inFlightDownload is a download that concurrent requests for the same
+// artifact wait on. provider and err are set before done is closed, so every
+// waiter reads the same result once done is closed.
+type inFlightDownload struct {
+	done     chan struct{}
+	once     sync.Once
+	started  time.Time
 	provider *database.Provider
 	err      error
 }
 
+// finish publishes a result to the waiters. Only the first call has any
+// effect, so a download cleared by an administrator can still finish.
+func (c *inFlightDownload) finish(provider *database.Provider, err error) {
+	c.once.Do(func() {
+		c.provider, c.err = provider, err
+		close(c.done)
+	})
+}
+
+// errInFlightCleared is returned to requests waiting on a download that an
+// administrator cleared
+var errInFlightCleared = errors.New("in-flight download was cleared by an administrator")
+
+// InFlightDownload describes a download that requests for the same artifact
+// are currently waiting on
+type InFlightDownload struct {
+	Key     string // namespace/type/version/os_arch
+	Started time.Time
+}
+
 // NewAutoDownloadService creates a new auto-download service
 func NewAutoDownloadService(
 	cfg *config.AutoDownloadConfig,
@@ -74,26 +139,70 @@ func NewAutoDownloadService(
 	ratePerSecond := float64(cfg.RateLimitPerMinute) / 60.0
 	limiter := rate.NewLimiter(rate.Limit(ratePerSecond), cfg.MaxConcurrentDL)
 
+	queueSize := cfg.QueueSize
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &AutoDownloadService{
-		config:        cfg,
-		providerCfg:   providerCfg,
-		registry:      NewRegistryClient(),
-		storage:       storage,
-		providerRepo:  database.NewProviderRepository(db),
-		logger:        log.Default(),
-		rateLimiter:   limiter,
-		semaphore:     make(chan struct{}, cfg.MaxConcurrentDL),
-		inFlight:      make(map[string]chan *downloadResult),
-		negativeCache: make(map[string]time.Time),
-		startTime:     time.Now(),
+		config:           cfg,
+		providerCfg:      providerCfg,
+		registry:         NewRegistryClient(),
+		storage:          storage,
+		providerRepo:     database.NewProviderRepository(db),
+		logger:           log.Default(),
+		rateLimiter:      limiter,
+		semaphore:        make(chan struct{}, cfg.MaxConcurrentDL),
+		backgroundQueue:  make(chan backgroundDownload, queueSize),
+		inFlight:         make(map[string]*inFlightDownload),
+		negativeCache:    make(map[string]time.Time),
+		reservedVersions: make(map[string]map[string]int),
+		startTime:        time.Now(),
+		ctx:              ctx,
+		cancel:           cancel,
 	}
 }
 
+// Close stops the background workers, cancelling downloads in progress and
+// dropping queued ones, and waits for the workers to exit. Foreground
+// downloads are left to their callers' contexts.
+func (s *AutoDownloadService) Close() {
+	s.closeOnce.Do(func() {
+		s.closedMu.Lock()
+		s.closed = true
+		s.closedMu.Unlock()
+
+		s.cancel()
+		s.workers.Wait()
+	})
+}
+
 // SetRegistry allows setting a custom registry client (for testing)
 func (s *AutoDownloadService) SetRegistry(r RegistryDownloader) {
 	s.registry = r
 }
 
+// SetUpstreams downloads each provider from the upstream registry serving
+// its namespace and stores it under that registry's hostname
+func (s *AutoDownloadService) SetUpstreams(upstreams *UpstreamRouter) {
+	s.registry = upstreams
+	s.upstreams = upstreams
+}
+
+// SetMetrics enables recording of auto-download metrics; nil disables it
+func (s *AutoDownloadService) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
+// recordMetric applies record to the Prometheus metrics when they are enabled
+func (s *AutoDownloadService) recordMetric(record func(*metrics.Metrics)) {
+	if s.metrics != nil {
+		record(s.metrics)
+	}
+}
+
 // GetStats returns current statistics
 func (s *AutoDownloadService) GetStats() AutoDownloadStats {
 	s.statsMu.RLock()
@@ -101,6 +210,37 @@ func (s *AutoDownloadService) GetStats() AutoDownloadStats {
 	return s.stats
 }
 
+// Namespaces returns copies of the current allowed and blocked namespace lists
+func (s *AutoDownloadService) Namespaces() (allowed, blocked []string) {
+	s.namespacesMu.RLock()
+	defer s.namespacesMu.RUnlock()
+	return append([]string{}, s.config.AllowedNamespaces...), append([]string{}, s.config.BlockedNamespaces...)
+}
+
+// SetNamespaces replaces the allowed and blocked namespace lists. The change
+// applies to the next request; nothing already downloaded is removed.
+func (s *AutoDownloadService) SetNamespaces(allowed, blocked []string) {
+	s.namespacesMu.Lock()
+	defer s.namespacesMu.Unlock()
+	s.config.AllowedNamespaces = append([]string{}, allowed...)
+	s.config.BlockedNamespaces = append([]string{}, blocked...)
+}
+
+// IsNamespaceAllowed checks a namespace against the live allow/block lists
+func (s *AutoDownloadService) IsNamespaceAllowed(namespace string) bool {
+	s.namespacesMu.RLock()
+	defer s.namespacesMu.RUnlock()
+	return s.config.IsNamespaceAllowed(namespace)
+}
+
+// IsProviderAllowed checks a provider against the live namespace lists and
+// the configured provider address lists
+func (s *AutoDownloadService) IsProviderAllowed(namespace, providerType string) bool {
+	s.namespacesMu.RLock()
+	defer s.namespacesMu.RUnlock()
+	return s.config.IsProviderAllowed(namespace, providerType)
+}
+
 // IsEnabled returns whether auto-download is enabled
 func (s *AutoDownloadService) IsEnabled() bool {
 	return s.config.Enabled
@@ -112,9 +252,8 @@ func (s *AutoDownloadService) GetAvailableVersions(ctx context.Context, namespac
 		return nil, fmt.Errorf("auto-download is disabled")
 	}
 
-	// Check if namespace is allowed
-	if !s.config.IsNamespaceAllowed(namespace) {
-		return nil, fmt.Errorf("namespace %s is not allowed for auto-download", namespace)
+	if !s.IsProviderAllowed(namespace, providerType) {
+		return nil, fmt.Errorf("provider %s/%s is not allowed for auto-download", namespace, providerType)
 	}
 
 	return s.registry.GetAvailableVersions(ctx, namespace, providerType)
@@ -125,7 +264,7 @@ func (s *AutoDownloadService) GetAvailableVersions(ctx context.Context, namespac
 func (s *AutoDownloadService) DownloadProvider(
 	ctx context.Context,
 	namespace, providerType, version, os, arch string,
-) (*database.Provider, error) {
+) (provider *database.Provider, err error) {
 	if !s.config.Enabled {
 		return nil, fmt.Errorf("auto-download is disabled")
 	}
@@ -133,13 +272,15 @@ func (s *AutoDownloadService) DownloadProvider(
 	s.statsMu.Lock()
 	s.stats.TotalRequests++
 	s.statsMu.Unlock()
+	s.recordMetric(func(m *metrics.Metrics) { m.AutoDownloadRequests.Inc() })
 
-	// Check if namespace is allowed
-	if !s.config.IsNamespaceAllowed(namespace) {
+	// Check the namespace and provider allow/block lists
+	if !s.IsProviderAllowed(namespace, providerType) {
 		s.statsMu.Lock()
 		s.stats.NamespaceBlocked++
 		s.statsMu.Unlock()
-		return nil, fmt.Errorf("namespace %s is not allowed for auto-download", namespace)
+		s.recordMetric(func(m *metrics.Metrics) { m.RecordAutoDownloadOutcome("not_allowed") })
+		return nil, fmt.Errorf("provider %s/%s is not allowed for auto-download", namespace, providerType)
 	}
 
 	platform := os + "_" + arch
@@ -154,6 +295,7 @@ func (s *AutoDownloadService) DownloadProvider(
 				s.statsMu.Lock()
 				s.stats.NegativeCacheHits++
 				s.statsMu.Unlock()
+				s.recordMetric(func(m *metrics.Metrics) { m.AutoDownloadNegativeCacheHits.Inc() })
 				return nil, fmt.Errorf("provider %s not found (cached)", cacheKey)
 			}
 		}
@@ -162,42 +304,54 @@ func (s *AutoDownloadService) DownloadProvider(
 
 	// Check if already downloading (coalesce duplicate requests)
 	s.inFlightMu.Lock()
-	if ch, exists := s.inFlight[cacheKey]; exists {
+	if call, exists := s.inFlight[cacheKey]; exists {
 		s.inFlightMu.Unlock()
 		s.statsMu.Lock()
 		s.stats.InFlightCoalesced++
 		s.statsMu.Unlock()
+		s.recordMetric(func(m *metrics.Metrics) { m.AutoDownloadCoalesced.Inc() })
 
 		// Wait for the in-flight download to complete
 		select {
-		case result := <-ch:
-			return result.provider, result.err
+		case <-call.done:
+			return call.provider, call.err
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		}
 	}
 
-	// Create channel for this download
-	resultCh := make(chan *downloadResult, 1)
-	s.inFlight[cacheKey] = resultCh
+	call := &inFlightDownload{done: make(chan struct{}), started: time.Now()}
+	s.inFlight[cacheKey] = call
 	s.inFlightMu.Unlock()
+	s.recordMetric(func(m *metrics.Metrics) { m.AutoDownloadsInFlight.Inc() })
 
-	// Ensure we clean up and broadcast result when done
+	// Publish the result to every waiter, even on a panic. An administrator
+	// may have cleared the entry already, and a new download may have taken
+	// its key.
 	defer func() {
 		s.inFlightMu.Lock()
-		delete(s.inFlight, cacheKey)
+		if s.inFlight[cacheKey] == call {
+			delete(s.inFlight, cacheKey)
+		}
 		s.inFlightMu.Unlock()
-		close(resultCh)
+		call.finish(provider, err)
+		s.recordMetric(func(m *metrics.Metrics) { m.AutoDownloadsInFlight.Dec() })
 	}()
 
+	// Never store more versions than the configured cap
+	release, err := s.reserveVersion(ctx, namespace, providerType, version)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	// Apply rate limiting
 	if err := s.rateLimiter.Wait(ctx); err != nil {
 		s.statsMu.Lock()
 		s.stats.RateLimitedCount++
 		s.statsMu.Unlock()
-		result := &downloadResult{err: fmt.Errorf("rate limited: %w", err)}
-		resultCh <- result
-		return nil, result.err
+		s.recordMetric(func(m *metrics.Metrics) { m.AutoDownloadRateLimited.Inc() })
+		return nil, fmt.Errorf("rate limited: %w", err)
 	}
 
 	// Acquire semaphore for concurrent download limit
@@ -205,17 +359,11 @@ func (s *AutoDownloadService) DownloadProvider(
 	case s.semaphore <- struct{}{}:
 		defer func() { <-s.semaphore }()
 	case <-ctx.Done():
-		result := &downloadResult{err: ctx.Err()}
-		resultCh <- result
-		return nil, result.err
+		return nil, ctx.Err()
 	}
 
 	// Perform the download
-	provider, err := s.performDownload(ctx, namespace, providerType, version, os, arch)
-
-	result := &downloadResult{provider: provider, err: err}
-	resultCh <- result
-
+	provider, err = s.performDownload(ctx, namespace, providerType, version, os, arch)
 	if err != nil {
 		// Cache negative result
 		if s.config.CacheNegativeResults {
@@ -226,12 +374,14 @@ func (s *AutoDownloadService) DownloadProvider(
 		s.statsMu.Lock()
 		s.stats.FailedDownloads++
 		s.statsMu.Unlock()
+		s.recordMetric(func(m *metrics.Metrics) { m.RecordAutoDownloadOutcome("failed") })
 		return nil, err
 	}
 
 	s.statsMu.Lock()
 	s.stats.SuccessfulDownloads++
 	s.statsMu.Unlock()
+	s.recordMetric(func(m *metrics.Metrics) { m.RecordAutoDownloadOutcome("success") })
 
 	return provider, nil
 }
@@ -256,12 +406,34 @@ func (s *AutoDownloadService) performDownload(
 
 	// Build storage key
 	platform := os + "_" + arch
-	storageKey := fmt.Sprintf("providers/registry.terraform.io/%s/%s/%s/%s/%s",
-		namespace, providerType, version, platform, result.Info.Filename)
+	hostname := DefaultRegistryHost
+	if s.upstreams != nil {
+		hostname = s.upstreams.Hostname(namespace)
+	}
+	storageKey := storage.BuildProviderKey(hostname, namespace, providerType, version, os, arch, result.Info.Filename)
+
+	// Never replace a mirrored artifact with different bytes
+	if s.providerCfg != nil && s.providerCfg.ImmutableArtifacts {
+		existing, err := CheckImmutable(downloadCtx, s.providerRepo, s.storage, storageKey,
+			namespace, providerType, version, platform, result.Info.Shasum)
+		if err != nil {
+			s.logger.Printf("Refusing to replace %s/%s %s (%s): %v", namespace, providerType, version, platform, err)
+			return nil, err
+		}
+		if existing != nil {
+			s.statsMu.Lock()
+			s.stats.CacheHits++
+			s.statsMu.Unlock()
+			s.recordMetric(func(m *metrics.Metrics) { m.AutoDownloadCacheHits.Inc() })
+			return existing, nil
+		}
+	}
 
 	// Upload to storage
 	reader := bytes.NewReader(result.Data)
-	err := s.storage.Upload(downloadCtx, storageKey, reader, "application/zip", nil)
+	metadata := storage.ProviderMetadata(namespace, providerType, version, platform,
+		result.Info.Filename, result.Info.Shasum, result.Info.DownloadURL, false)
+	err := s.storage.Upload(downloadCtx, storageKey, reader, "application/zip", metadata)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload to storage: %w", err)
 	}
@@ -275,8 +447,12 @@ func (s *AutoDownloadService) performDownload(
 		Filename:    result.Info.Filename,
 		DownloadURL: result.Info.DownloadURL,
 		Shasum:      result.Info.Shasum,
+		SigningKeys: EncodeSigningKeys(result.Info.SigningKeys),
+		Protocols:   EncodeProtocols(result.Info.Protocols),
+		H1Hash:      EncodePackageHash(result.Data),
 		S3Key:       storageKey,
 		SizeBytes:   int64(len(result.Data)),
+		Verified:    result.Verified,
 	}
 
 	err = s.providerRepo.Create(downloadCtx, provider)
@@ -289,9 +465,15 @@ func (s *AutoDownloadService) performDownload(
 		return nil, fmt.Errorf("failed to store provider record: %w", err)
 	}
 
+	// A new platform makes any stored SHA256SUMS of the version stale
+	if _, err := PruneStaleShasums(downloadCtx, s.providerRepo, s.storage); err != nil {
+		s.logger.Printf("Failed to remove stale SHA256SUMS documents: %v", err)
+	}
+
 	s.statsMu.Lock()
 	s.stats.BytesDownloaded += int64(len(result.Data))
 	s.statsMu.Unlock()
+	s.recordMetric(func(m *metrics.Metrics) { m.RecordAutoDownloadBytes(int64(len(result.Data))) })
 
 	s.logger.Printf("Auto-download complete: %s/%s %s (%s) - %d bytes in %v",
 		namespace, providerType, version, platform, len(result.Data), result.Duration)
@@ -299,6 +481,49 @@ func (s *AutoDownloadService) performDownload(
 	return provider, nil
 }
 
+// InFlight returns the downloads currently in flight, oldest first
+func (s *AutoDownloadService) InFlight() []InFlightDownload {
+	s.inFlightMu.Lock()
+	defer s.inFlightMu.Unlock()
+
+	downloads := make([]InFlightDownload, 0, len(s.inFlight))
+	for key, call := range s.inFlight {
+		downloads = append(downloads, InFlightDownload{Key: key, Started: call.started})
+	}
+	sort.Slice(downloads, func(i, j int) bool {
+		return downloads[i].Started.Before(downloads[j].Started)
+	})
+	return downloads
+}
+
+// ClearInFlight drops in-flight downloads so new requests for them start
+// afresh, returning how many were dropped. With no keys every entry is
+// dropped. Requests waiting on a dropped download fail with
+// errInFlightCleared; the download itself runs on and stores its result.
+func (s *AutoDownloadService) ClearInFlight(keys ...string) int {
+	s.inFlightMu.Lock()
+	var cleared []*inFlightDownload
+	if len(keys) == 0 {
+		for key, call := range s.inFlight {
+			cleared = append(cleared, call)
+			delete(s.inFlight, key)
+		}
+	} else {
+		for _, key := range keys {
+			if call, ok := s.inFlight[key]; ok {
+				cleared = append(cleared, call)
+				delete(s.inFlight, key)
+			}
+		}
+	}
+	s.inFlightMu.Unlock()
+
+	for _, call := range cleared {
+		call.finish(nil, errInFlightCleared)
+	}
+	return len(cleared)
+}
+
 // ClearNegativeCache clears the negative cache (useful for admin operations)
 func (s *AutoDownloadService) ClearNegativeCache() {
 	s.negativeCacheMu.Lock()
@@ -331,7 +556,8 @@ type PlatformDownloadResult struct {
 
 // DownloadProviderAllPlatforms downloads a provider for all configured platforms
 // It downloads the requested platform first, then queues downloads for other platforms
-// Returns the provider for the requested platform and starts background downloads for others
+// Returns the provider for the requested platform; other platforms are downloaded by
+// background workers, and dropped if the queue (QueueSize) is full
 func (s *AutoDownloadService) DownloadProviderAllPlatforms(
 	ctx context.Context,
 	namespace, providerType, version, requestedOS, requestedArch string,
@@ -353,37 +579,227 @@ func (s *AutoDownloadService) DownloadProviderAllPlatforms(
 		}
 
 		// Parse platform into os and arch
-		platformOS, platformArch := parsePlatform(platform)
-		if platformOS == "" || platformArch == "" {
-			s.logger.Printf("Invalid platform format: %s (expected os_arch)", platform)
+		platformOS, platformArch, err := ParsePlatform(platform)
+		if err != nil {
+			s.logger.Printf("Skipping background download: %v", err)
 			continue
 		}
 
-		// Launch background download
-		go func(os, arch string) {
-			bgCtx, cancel := context.WithTimeout(context.Background(), s.config.GetTimeout())
-			defer cancel()
-
-			_, bgErr := s.DownloadProvider(bgCtx, namespace, providerType, version, os, arch)
-			if bgErr != nil {
-				s.logger.Printf("Background download failed for %s/%s %s (%s_%s): %v",
-					namespace, providerType, version, os, arch, bgErr)
-			} else {
-				s.logger.Printf("Background download complete for %s/%s %s (%s_%s)",
-					namespace, providerType, version, os, arch)
-			}
-		}(platformOS, platformArch)
+		s.enqueueBackground(backgroundDownload{
+			namespace:    namespace,
+			providerType: providerType,
+			version:      version,
+			os:           platformOS,
+			arch:         platformArch,
+		})
+	}
+
+	// Fill the provider up to its version cap with the newest releases
+	if s.config.MaxVersionsPerProvider > 0 {
+		s.goBackground(func() { s.enqueueRecentVersions(namespace, providerType) })
 	}
 
 	return provider, nil
 }
 
-// parsePlatform splits a platform string (e.g., "linux_amd64") into os and arch
-func parsePlatform(platform string) (os, arch string) {
-	for i := len(platform) - 1; i >= 0; i-- {
-		if platform[i] == '_' {
-			return platform[:i], platform[i+1:]
+// reserveVersion counts a version against MaxVersionsPerProvider until the
+// returned release is called. Versions already stored or being downloaded
+// are always allowed; a new one is refused once the provider has reached the
+// cap.
+func (s *AutoDownloadService) reserveVersion(ctx context.Context, namespace, providerType, version string) (func(), error) {
+	max := s.config.MaxVersionsPerProvider
+	if max <= 0 {
+		return func() {}, nil
+	}
+
+	s.reservedMu.Lock()
+	defer s.reservedMu.Unlock()
+
+	stored, err := s.providerRepo.ListVersions(ctx, namespace, providerType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count stored versions: %w", err)
+	}
+	address := namespace + "/" + providerType
+	versions := make(map[string]bool)
+	for _, p := range stored {
+		versions[p.Version] = true
+	}
+	for v := range s.reservedVersions[address] {
+		versions[v] = true
+	}
+
+	if !versions[version] && len(versions) >= max {
+		s.statsMu.Lock()
+		s.stats.VersionCapRefused++
+		s.statsMu.Unlock()
+		s.recordMetric(func(m *metrics.Metrics) { m.RecordAutoDownloadOutcome("version_cap") })
+		return nil, fmt.Errorf("provider %s already has %d versions, the auto-download maximum", address, max)
+	}
+
+	if s.reservedVersions[address] == nil {
+		s.reservedVersions[address] = make(map[string]int)
+	}
+	s.reservedVersions[address][version]++
+
+	return func() {
+		s.reservedMu.Lock()
+		defer s.reservedMu.Unlock()
+		if s.reservedVersions[address][version]--; s.reservedVersions[address][version] == 0 {
+			delete(s.reservedVersions[address], version)
+		}
+		if len(s.reservedVersions[address]) == 0 {
+			delete(s.reservedVersions, address)
+		}
+	}, nil
+}
+
+// enqueueRecentVersions queues the newest upstream releases of a provider
+// that are not mirrored yet, as many as fit under MaxVersionsPerProvider.
+// The cap is checked again when each download runs.
+func (s *AutoDownloadService) enqueueRecentVersions(namespace, providerType string) {
+	ctx, cancel := context.WithTimeout(s.ctx, s.config.GetTimeout())
+	defer cancel()
+
+	available, err := s.registry.GetAvailableVersions(ctx, namespace, providerType)
+	if err != nil {
+		s.logger.Printf("Failed to list versions of %s/%s for background download: %v", namespace, providerType, err)
+		return
+	}
+	stored, err := s.providerRepo.ListVersions(ctx, namespace, providerType)
+	if err != nil {
+		s.logger.Printf("Failed to list stored versions of %s/%s: %v", namespace, providerType, err)
+		return
+	}
+	storedVersions := make(map[string]bool)
+	for _, p := range stored {
+		storedVersions[p.Version] = true
+	}
+
+	remaining := s.config.MaxVersionsPerProvider - len(storedVersions)
+	if remaining <= 0 {
+		return
+	}
+
+	// Pre-releases are only fetched when requested
+	var candidates []string
+	for _, v := range available {
+		if !storedVersions[v] && !strings.ContainsAny(v, "-+") {
+			candidates = append(candidates, v)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return CompareVersions(candidates[i], candidates[j]) > 0
+	})
+	if len(candidates) > remaining {
+		candidates = candidates[:remaining]
+	}
+
+	for _, version := range candidates {
+		for _, platform := range s.config.GetPlatforms() {
+			platformOS, platformArch, err := ParsePlatform(platform)
+			if err != nil {
+				continue
+			}
+			s.enqueueBackground(backgroundDownload{
+				namespace:    namespace,
+				providerType: providerType,
+				version:      version,
+				os:           platformOS,
+				arch:         platformArch,
+			})
+		}
+	}
+}
+
+// goBackground runs fn on its own goroutine as background work that Close
+// waits for. Nothing is started once the service is closed.
+func (s *AutoDownloadService) goBackground(fn func()) {
+	s.closedMu.Lock()
+	defer s.closedMu.Unlock()
+	if s.closed {
+		return
+	}
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		fn()
+	}()
+}
+
+// enqueueBackground queues a background platform download without blocking.
+// The download is dropped if the queue is full.
+func (s *AutoDownloadService) enqueueBackground(task backgroundDownload) {
+	// Workers are started under closedMu so Close never waits while more
+	// are being added
+	s.closedMu.Lock()
+	if s.closed {
+		s.closedMu.Unlock()
+		return
+	}
+	s.backgroundOnce.Do(s.startBackgroundWorkers)
+	s.closedMu.Unlock()
+
+	select {
+	case s.backgroundQueue <- task:
+		s.statsMu.Lock()
+		s.stats.BackgroundQueued++
+		s.statsMu.Unlock()
+	default:
+		s.statsMu.Lock()
+		s.stats.BackgroundDropped++
+		s.statsMu.Unlock()
+		s.logger.Printf("Background download queue full, dropping %s/%s %s (%s_%s)",
+			task.namespace, task.providerType, task.version, task.os, task.arch)
+	}
+}
+
+// startBackgroundWorkers starts one worker per concurrent download slot.
+// Downloads still pass through the semaphore in DownloadProvider.
+func (s *AutoDownloadService) startBackgroundWorkers() {
+	workers := cap(s.semaphore)
+	if workers < 1 {
+		workers = 1
+	}
+	s.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go s.backgroundWorker()
+	}
+}
+
+// backgroundWorker processes queued background platform downloads until the
+// service is closed
+func (s *AutoDownloadService) backgroundWorker() {
+	defer s.workers.Done()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case task := <-s.backgroundQueue:
+			if s.ctx.Err() != nil {
+				return
+			}
+			s.runBackgroundDownload(task)
 		}
 	}
-	return "", ""
+}
+
+// runBackgroundDownload downloads a queued platform unless it is already mirrored
+func (s *AutoDownloadService) runBackgroundDownload(task backgroundDownload) {
+	bgCtx, cancel := context.WithTimeout(s.ctx, s.config.GetTimeout())
+	defer cancel()
+
+	platform := task.os + "_" + task.arch
+	existing, err := s.providerRepo.GetByIdentity(bgCtx, task.namespace, task.providerType, task.version, platform)
+	if err == nil && existing != nil {
+		return
+	}
+
+	_, bgErr := s.DownloadProvider(bgCtx, task.namespace, task.providerType, task.version, task.os, task.arch)
+	if bgErr != nil {
+		s.logger.Printf("Background download failed for %s/%s %s (%s): %v",
+			task.namespace, task.providerType, task.version, platform, bgErr)
+	} else {
+		s.logger.Printf("Background download complete for %s/%s %s (%s)",
+			task.namespace, task.providerType, task.version, platform)
+	}
 }
@@ -0,0 +1,463 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/config"
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/metrics"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowRegistry is a registry that takes a while to download and tracks how
+// many downloads run at the same time
+type slowRegistry struct {
+	delay     time.Duration
+	versions  []string
+	active    atomic.Int64
+	maxActive atomic.Int64
+	total     atomic.Int64
+}
+
+func (r *slowRegistry) DownloadProviderComplete(ctx context.Context, namespace, providerType, version, os, arch string) *DownloadResult {
+	active := r.active.Add(1)
+	defer r.active.Add(-1)
+	r.total.Add(1)
+
+	for {
+		max := r.maxActive.Load()
+		if active <= max || r.maxActive.CompareAndSwap(max, active) {
+			break
+		}
+	}
+
+	time.Sleep(r.delay)
+
+	platform := os + "_" + arch
+	return &DownloadResult{
+		Info: &ProviderDownloadInfo{
+			Namespace: namespace,
+			Type:      providerType,
+			Version:   version,
+			OS:        os,
+			Arch:      arch,
+			Platform:  platform,
+			Filename:  fmt.Sprintf("terraform-provider-%s_%s_%s.zip", providerType, version, platform),
+			Shasum:    "abc123",
+		},
+		Data: []byte("provider-binary"),
+	}
+}
+
+func (r *slowRegistry) GetAvailableVersions(ctx context.Context, namespace, providerType string) ([]string, error) {
+	return r.versions, nil
+}
+
+func TestDownloadProviderAllPlatforms_BoundedBackgroundQueue(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	// Serialize database access; this test measures download concurrency, not SQLite locking
+	db.Conn().SetMaxOpenConns(1)
+
+	store, err := storage.NewLocalStorage(storage.LocalConfig{BasePath: t.TempDir()})
+	require.NoError(t, err)
+	defer store.Close()
+
+	cfg := &config.AutoDownloadConfig{
+		Enabled:            true,
+		Platforms:          []string{"linux_amd64", "linux_arm64", "darwin_amd64", "darwin_arm64", "windows_amd64"},
+		RateLimitPerMinute: 60000,
+		MaxConcurrentDL:    2,
+		QueueSize:          3,
+		TimeoutSeconds:     30,
+	}
+
+	svc := NewAutoDownloadService(cfg, &config.ProvidersConfig{}, store, db)
+	registry := &slowRegistry{delay: 50 * time.Millisecond}
+	svc.SetRegistry(registry)
+
+	// Flood with cold requests for distinct versions; each one queues four
+	// additional platforms in the background
+	const requests = 10
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := svc.DownloadProviderAllPlatforms(context.Background(),
+				"hashicorp", "random", fmt.Sprintf("3.%d.0", i), "linux", "amd64")
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	// Let the queued background work drain
+	require.Eventually(t, func() bool {
+		return len(svc.backgroundQueue) == 0 && registry.active.Load() == 0
+	}, 10*time.Second, 10*time.Millisecond)
+
+	stats := svc.GetStats()
+
+	// Every extra platform was either queued or dropped
+	assert.Equal(t, int64(requests*4), stats.BackgroundQueued+stats.BackgroundDropped)
+	assert.Greater(t, stats.BackgroundDropped, int64(0), "a full queue should drop work")
+
+	// Downloads never exceed the configured concurrency
+	assert.LessOrEqual(t, registry.maxActive.Load(), int64(cfg.MaxConcurrentDL))
+
+	// Only foreground requests plus accepted background work reached the registry
+	assert.LessOrEqual(t, registry.total.Load(), int64(requests)+stats.BackgroundQueued)
+}
+
+func TestSetNamespaces_BlocksImmediately(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	store, err := storage.NewLocalStorage(storage.LocalConfig{BasePath: t.TempDir()})
+	require.NoError(t, err)
+	defer store.Close()
+
+	cfg := &config.AutoDownloadConfig{
+		Enabled:            true,
+		Platforms:          []string{"linux_amd64"},
+		RateLimitPerMinute: 600,
+		MaxConcurrentDL:    1,
+		QueueSize:          1,
+		TimeoutSeconds:     30,
+	}
+
+	svc := NewAutoDownloadService(cfg, &config.ProvidersConfig{}, store, db)
+	registry := &slowRegistry{}
+	svc.SetRegistry(registry)
+
+	_, err = svc.DownloadProvider(context.Background(), "hashicorp", "random", "3.0.0", "linux", "amd64")
+	require.NoError(t, err)
+
+	svc.SetNamespaces(nil, []string{"hashicorp"})
+
+	_, err = svc.DownloadProvider(context.Background(), "hashicorp", "random", "3.1.0", "linux", "amd64")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not allowed")
+	assert.Equal(t, int64(1), registry.total.Load())
+	assert.Equal(t, int64(1), svc.GetStats().NamespaceBlocked)
+
+	allowed, blocked := svc.Namespaces()
+	assert.Empty(t, allowed)
+	assert.Equal(t, []string{"hashicorp"}, blocked)
+}
+
+func TestDownloadProvider_ProviderLists(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	store, err := storage.NewLocalStorage(storage.LocalConfig{BasePath: t.TempDir()})
+	require.NoError(t, err)
+	defer store.Close()
+
+	cfg := &config.AutoDownloadConfig{
+		Enabled:            true,
+		AllowedNamespaces:  []string{"hashicorp"},
+		AllowedProviders:   []string{"integrations/github"},
+		BlockedProviders:   []string{"hashicorp/null"},
+		Platforms:          []string{"linux_amd64"},
+		RateLimitPerMinute: 600,
+		MaxConcurrentDL:    1,
+		QueueSize:          1,
+		TimeoutSeconds:     30,
+	}
+
+	svc := NewAutoDownloadService(cfg, &config.ProvidersConfig{}, store, db)
+	registry := &slowRegistry{}
+	svc.SetRegistry(registry)
+	ctx := context.Background()
+
+	// Blocked by address even though the namespace is allowed
+	_, err = svc.DownloadProvider(ctx, "hashicorp", "null", "3.0.0", "linux", "amd64")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "hashicorp/null is not allowed")
+	_, err = svc.GetAvailableVersions(ctx, "hashicorp", "null")
+	assert.Error(t, err)
+
+	// Allowed by address even though the namespace is not
+	_, err = svc.DownloadProvider(ctx, "integrations", "github", "6.0.0", "linux", "amd64")
+	require.NoError(t, err)
+	_, err = svc.GetAvailableVersions(ctx, "integrations", "github")
+	assert.NoError(t, err)
+
+	// Other providers in that namespace stay out
+	_, err = svc.DownloadProvider(ctx, "integrations", "other", "1.0.0", "linux", "amd64")
+	assert.Error(t, err)
+
+	_, err = svc.DownloadProvider(ctx, "hashicorp", "random", "3.0.0", "linux", "amd64")
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), registry.total.Load())
+	assert.Equal(t, int64(2), svc.GetStats().NamespaceBlocked)
+}
+
+func TestDownloadProvider_CoalescedCallersShareResult(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	store, err := storage.NewLocalStorage(storage.LocalConfig{BasePath: t.TempDir()})
+	require.NoError(t, err)
+	defer store.Close()
+
+	cfg := &config.AutoDownloadConfig{
+		Enabled:            true,
+		Platforms:          []string{"linux_amd64"},
+		RateLimitPerMinute: 600,
+		MaxConcurrentDL:    1,
+		QueueSize:          1,
+		TimeoutSeconds:     30,
+	}
+
+	svc := NewAutoDownloadService(cfg, &config.ProvidersConfig{}, store, db)
+	registry := &slowRegistry{delay: 100 * time.Millisecond}
+	svc.SetRegistry(registry)
+
+	const callers = 20
+	providers := make([]*database.Provider, callers)
+	errs := make([]error, callers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			providers[i], errs[i] = svc.DownloadProvider(context.Background(),
+				"hashicorp", "random", "3.0.0", "linux", "amd64")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for i := 0; i < callers; i++ {
+		require.NoError(t, errs[i])
+		require.NotNil(t, providers[i])
+		assert.Equal(t, providers[0].ID, providers[i].ID)
+	}
+	assert.Equal(t, int64(1), registry.total.Load())
+}
+
+// sqlWatchersRunning reports whether database/sql or the SQLite driver still
+// has a goroutine watching a finished transaction or statement
+func sqlWatchersRunning() bool {
+	buf := make([]byte, 1<<20)
+	stacks := string(buf[:runtime.Stack(buf, true)])
+	for _, watcher := range []string{"database/sql.(*Tx).awaitDone", "database/sql.(*DB).beginDC", "sqlite.interruptOnDone"} {
+		if strings.Contains(stacks, watcher) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAutoDownloadService_CloseStopsBackgroundWorkers(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	store, err := storage.NewLocalStorage(storage.LocalConfig{BasePath: t.TempDir()})
+	require.NoError(t, err)
+	defer store.Close()
+
+	cfg := &config.AutoDownloadConfig{
+		Enabled:            true,
+		Platforms:          []string{"linux_amd64", "linux_arm64", "darwin_amd64", "darwin_arm64"},
+		RateLimitPerMinute: 60000,
+		MaxConcurrentDL:    4,
+		QueueSize:          10,
+		TimeoutSeconds:     30,
+	}
+
+	// Opening the database leaves goroutines watching its finished migration
+	// transactions until they are next scheduled. They are not the service's,
+	// so the baseline is taken once they are gone. It is taken from an
+	// Eventually check, like the comparison after Close.
+	var before int
+	require.Eventually(t, func() bool {
+		before = runtime.NumGoroutine()
+		return !sqlWatchersRunning()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	svc := NewAutoDownloadService(cfg, &config.ProvidersConfig{}, store, db)
+	svc.SetRegistry(&slowRegistry{delay: 20 * time.Millisecond})
+
+	// Start the workers with background downloads still queued
+	_, err = svc.DownloadProviderAllPlatforms(context.Background(), "hashicorp", "random", "3.0.0", "linux", "amd64")
+	require.NoError(t, err)
+	assert.Greater(t, runtime.NumGoroutine(), before)
+
+	svc.Close()
+
+	// No goroutine started by the service outlives Close
+	require.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= before
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Closing again is harmless, and nothing is queued after Close
+	svc.Close()
+	_, err = svc.DownloadProviderAllPlatforms(context.Background(), "hashicorp", "random", "3.1.0", "linux", "amd64")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= before
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestDownloadProviderAllPlatforms_MaxVersionsPerProvider(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	db.Conn().SetMaxOpenConns(1)
+
+	store, err := storage.NewLocalStorage(storage.LocalConfig{BasePath: t.TempDir()})
+	require.NoError(t, err)
+	defer store.Close()
+
+	cfg := &config.AutoDownloadConfig{
+		Enabled:                true,
+		Platforms:              []string{"linux_amd64"},
+		RateLimitPerMinute:     60000,
+		MaxConcurrentDL:        2,
+		QueueSize:              10,
+		TimeoutSeconds:         30,
+		MaxVersionsPerProvider: 3,
+	}
+
+	svc := NewAutoDownloadService(cfg, &config.ProvidersConfig{}, store, db)
+	svc.SetRegistry(&slowRegistry{
+		delay:    10 * time.Millisecond,
+		versions: []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0", "2.1.0", "3.0.0-beta1"},
+	})
+	repo := database.NewProviderRepository(db)
+
+	storedVersions := func(providerType string) []string {
+		providers, err := repo.ListVersions(context.Background(), "hashicorp", providerType)
+		require.NoError(t, err)
+		seen := make(map[string]bool)
+		var versions []string
+		for _, p := range providers {
+			if !seen[p.Version] {
+				seen[p.Version] = true
+				versions = append(versions, p.Version)
+			}
+		}
+		return versions
+	}
+
+	// A cold request pulls the newest releases up to the cap in the background
+	_, err = svc.DownloadProviderAllPlatforms(context.Background(), "hashicorp", "random", "1.0.0", "linux", "amd64")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(storedVersions("random")) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{"1.0.0", "2.0.0", "2.1.0"}, storedVersions("random"))
+
+	// Stored versions count against the cap; their other platforms do not
+	_, err = svc.DownloadProvider(context.Background(), "hashicorp", "random", "1.2.0", "linux", "amd64")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "auto-download maximum")
+	_, err = svc.DownloadProvider(context.Background(), "hashicorp", "random", "1.0.0", "darwin", "arm64")
+	require.NoError(t, err)
+
+	// Concurrent cold requests for distinct versions never exceed the cap
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			svc.DownloadProvider(context.Background(), "hashicorp", "null", fmt.Sprintf("3.%d.0", i), "linux", "amd64")
+		}(i)
+	}
+	wg.Wait()
+
+	svc.Close()
+	assert.Len(t, storedVersions("random"), 3)
+	assert.Len(t, storedVersions("null"), 3)
+	assert.GreaterOrEqual(t, svc.GetStats().VersionCapRefused, int64(8))
+}
+
+// failingRegistry fails every download
+type failingRegistry struct{}
+
+func (failingRegistry) DownloadProviderComplete(ctx context.Context, namespace, providerType, version, os, arch string) *DownloadResult {
+	return &DownloadResult{Error: fmt.Errorf("provider not found")}
+}
+
+func (failingRegistry) GetAvailableVersions(ctx context.Context, namespace, providerType string) ([]string, error) {
+	return nil, nil
+}
+
+func TestDownloadProvider_RecordsMetrics(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	cfg := &config.AutoDownloadConfig{
+		Enabled:              true,
+		Platforms:            []string{"linux_amd64"},
+		RateLimitPerMinute:   600,
+		MaxConcurrentDL:      1,
+		QueueSize:            1,
+		TimeoutSeconds:       30,
+		CacheNegativeResults: true,
+		NegativeCacheTTL:     300,
+	}
+	svc := NewAutoDownloadService(cfg, &config.ProvidersConfig{ImmutableArtifacts: true}, storage.NewMockStorage(), db)
+	data := []byte("provider-binary")
+	svc.SetRegistry(&contentRegistry{data: data})
+	m := metrics.NewWithRegistry(prometheus.NewRegistry())
+	svc.SetMetrics(m)
+	ctx := context.Background()
+
+	_, err = svc.DownloadProvider(ctx, "hashicorp", "random", "3.0.0", "linux", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.AutoDownloadRequests))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.AutoDownloadOutcomes.WithLabelValues("success")))
+	assert.Equal(t, float64(len(data)), testutil.ToFloat64(m.AutoDownloadBytes))
+
+	// The artifact is already mirrored, so nothing is stored again
+	_, err = svc.DownloadProvider(ctx, "hashicorp", "random", "3.0.0", "linux", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.AutoDownloadCacheHits))
+	assert.Equal(t, float64(len(data)), testutil.ToFloat64(m.AutoDownloadBytes))
+
+	// A failed download is cached and answers the next request
+	svc.SetRegistry(failingRegistry{})
+	for i := 0; i < 2; i++ {
+		_, err = svc.DownloadProvider(ctx, "hashicorp", "random", "9.9.9", "linux", "amd64")
+		require.Error(t, err)
+	}
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.AutoDownloadOutcomes.WithLabelValues("failed")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.AutoDownloadNegativeCacheHits))
+
+	assert.Equal(t, float64(4), testutil.ToFloat64(m.AutoDownloadRequests))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.AutoDownloadOutcomes.WithLabelValues("success")))
+	assert.Zero(t, testutil.ToFloat64(m.AutoDownloadsInFlight))
+
+	// The in-memory stats used by the JSON endpoint agree
+	stats := svc.GetStats()
+	assert.Equal(t, int64(4), stats.TotalRequests)
+	assert.Equal(t, int64(2), stats.SuccessfulDownloads)
+	assert.Equal(t, int64(1), stats.CacheHits)
+	assert.Equal(t, int64(1), stats.NegativeCacheHits)
+	assert.Equal(t, int64(len(data)), stats.BytesDownloaded)
+}
@@ -5,11 +5,16 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/storage"
 )
 
 const (
@@ -34,10 +39,40 @@ type RegistryDownloader interface {
 	GetAvailableVersions(ctx context.Context, namespace, providerType string) ([]string, error)
 }
 
+// MetadataFetcher retrieves provider download metadata from a registry
+// without downloading the provider binary
+type MetadataFetcher interface {
+	GetDownloadInfo(ctx context.Context, namespace, providerType, version, os, arch string) (*ProviderDownloadInfo, error)
+}
+
+// RegistryPinger checks that upstream registries can be reached
+type RegistryPinger interface {
+	// Ping returns the result of reaching each registry by hostname
+	Ping(ctx context.Context) map[string]error
+}
+
+// PlatformLister lists the platforms a registry publishes for each version
+// of a provider
+type PlatformLister interface {
+	// GetVersionPlatforms maps each upstream version to its os_arch platforms
+	GetVersionPlatforms(ctx context.Context, namespace, providerType string) (map[string][]string, error)
+}
+
 // RegistryClient handles communication with the Terraform Registry API
 type RegistryClient struct {
 	httpClient *http.Client
 	baseURL    string
+	token      string // Bearer token sent to the registry host, if any
+
+	// username and password are sent with basic auth when there is no token
+	username string
+	password string
+
+	// maxDownloadSize caps a provider archive in bytes; 0 means unlimited
+	maxDownloadSize int64
+
+	// verifySignatures checks downloads against the signed SHA256SUMS
+	verifySignatures bool
 }
 
 // NewRegistryClient creates a new Terraform Registry API client
@@ -46,10 +81,56 @@ func NewRegistryClient() *RegistryClient {
 		httpClient: &http.Client{
 			Timeout: DownloadTimeout,
 		},
-		baseURL: TerraformRegistryBaseURL,
+		baseURL:          TerraformRegistryBaseURL,
+		verifySignatures: true,
 	}
 }
 
+// NewUpstreamRegistryClient creates a client for a registry serving the
+// providers API at baseURL, authenticating with token when it is set
+func NewUpstreamRegistryClient(baseURL, token string) *RegistryClient {
+	c := NewRegistryClient()
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+	c.token = token
+	return c
+}
+
+// SetBasicAuth authenticates with the registry using HTTP basic auth. It is
+// ignored when the client has a token.
+func (c *RegistryClient) SetBasicAuth(username, password string) {
+	c.username = username
+	c.password = password
+}
+
+// SetMaxDownloadSize caps the size of downloaded archives in bytes. Larger
+// downloads are aborted; zero or less means unlimited.
+func (c *RegistryClient) SetMaxDownloadSize(limit int64) {
+	c.maxDownloadSize = limit
+}
+
+// SetSignatureVerification sets whether downloads are checked against the
+// release's GPG-signed SHA256SUMS. It is on by default.
+func (c *RegistryClient) SetSignatureVerification(enabled bool) {
+	c.verifySignatures = enabled
+}
+
+// authorize adds the registry credentials to requests for the registry's own
+// host. Download URLs often point at other hosts, which must not see them.
+func (c *RegistryClient) authorize(req *http.Request) {
+	if c.token == "" && c.username == "" {
+		return
+	}
+	base, err := url.Parse(c.baseURL)
+	if err != nil || !strings.EqualFold(base.Host, req.URL.Host) {
+		return
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		return
+	}
+	req.SetBasicAuth(c.username, c.password)
+}
+
 // ProviderDownloadInfo contains information needed to download a provider
 type ProviderDownloadInfo struct {
 	Namespace   string
@@ -61,6 +142,13 @@ type ProviderDownloadInfo struct {
 	Filename    string
 	DownloadURL string
 	Shasum      string
+	SigningKeys []GPGPublicKey
+	Protocols   []string
+
+	// ShasumsURL and ShasumsSignatureURL locate the release's SHA256SUMS
+	// document and its detached GPG signature
+	ShasumsURL          string
+	ShasumsSignatureURL string
 }
 
 // registryDownloadResponse represents the API response from the download endpoint
@@ -71,6 +159,13 @@ type registryDownloadResponse struct {
 	Filename    string   `json:"filename"`
 	DownloadURL string   `json:"download_url"`
 	Shasum      string   `json:"shasum"`
+
+	ShasumsURL          string `json:"shasums_url"`
+	ShasumsSignatureURL string `json:"shasums_signature_url"`
+
+	SigningKeys struct {
+		GPGPublicKeys []GPGPublicKey `json:"gpg_public_keys"`
+	} `json:"signing_keys"`
 }
 
 // registryVersionsResponse represents the API response from the versions endpoint
@@ -86,13 +181,70 @@ type registryVersionsResponse struct {
 
 // GetAvailableVersions retrieves available versions from the Terraform Registry
 func (c *RegistryClient) GetAvailableVersions(ctx context.Context, namespace, providerType string) ([]string, error) {
+	data, err := c.getVersions(ctx, namespace, providerType)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(data.Versions))
+	for _, v := range data.Versions {
+		versions = append(versions, v.Version)
+	}
+
+	return versions, nil
+}
+
+// GetVersionPlatforms retrieves the platforms published for each version from
+// the Terraform Registry, keyed by version in os_arch form
+func (c *RegistryClient) GetVersionPlatforms(ctx context.Context, namespace, providerType string) (map[string][]string, error) {
+	data, err := c.getVersions(ctx, namespace, providerType)
+	if err != nil {
+		return nil, err
+	}
+
+	platforms := make(map[string][]string, len(data.Versions))
+	for _, v := range data.Versions {
+		list := make([]string, 0, len(v.Platforms))
+		for _, p := range v.Platforms {
+			list = append(list, p.OS+"_"+p.Arch)
+		}
+		platforms[v.Version] = list
+	}
+
+	return platforms, nil
+}
+
+// Ping checks that the registry answers requests. Any response below 500
+// counts, since the providers API has no document at its base URL.
+func (c *RegistryClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach registry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("registry returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// getVersions queries the versions endpoint for a provider
+func (c *RegistryClient) getVersions(ctx context.Context, namespace, providerType string) (*registryVersionsResponse, error) {
 	// Construct URL: /v1/providers/{namespace}/{type}/versions
-	url := fmt.Sprintf("%s/%s/%s/versions", c.baseURL, namespace, providerType)
+	versionsURL := fmt.Sprintf("%s/%s/%s/versions", c.baseURL, namespace, providerType)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, versionsURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	c.authorize(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -114,25 +266,21 @@ func (c *RegistryClient) GetAvailableVersions(ctx context.Context, namespace, pr
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	versions := make([]string, 0, len(data.Versions))
-	for _, v := range data.Versions {
-		versions = append(versions, v.Version)
-	}
-
-	return versions, nil
+	return &data, nil
 }
 
 // GetDownloadInfo retrieves download metadata from the Terraform Registry
 func (c *RegistryClient) GetDownloadInfo(ctx context.Context, namespace, providerType, version, os, arch string) (*ProviderDownloadInfo, error) {
 	// Construct URL: /v1/providers/{namespace}/{type}/{version}/download/{os}/{arch}
-	url := fmt.Sprintf("%s/%s/%s/%s/download/%s/%s",
+	downloadURL := fmt.Sprintf("%s/%s/%s/%s/download/%s/%s",
 		c.baseURL, namespace, providerType, version, os, arch)
 
 	// Create request
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	c.authorize(req)
 
 	// Execute with retries
 	var resp *http.Response
@@ -176,6 +324,14 @@ func (c *RegistryClient) GetDownloadInfo(ctx context.Context, namespace, provide
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
+	// The mirror protocol derives filenames from the provider identity, so a
+	// non-standard upstream filename would be stored under the wrong key
+	filename := data.Filename
+	if err := CheckProviderFilename(filename, providerType, version, data.OS, data.Arch); err != nil {
+		filename = FormatProviderFilename(providerType, version, data.OS, data.Arch)
+		log.Printf("Correcting upstream provider filename: %v; using %s", err, filename)
+	}
+
 	return &ProviderDownloadInfo{
 		Namespace:   namespace,
 		Type:        providerType,
@@ -183,9 +339,14 @@ func (c *RegistryClient) GetDownloadInfo(ctx context.Context, namespace, provide
 		OS:          data.OS,
 		Arch:        data.Arch,
 		Platform:    data.OS + "_" + data.Arch,
-		Filename:    data.Filename,
+		Filename:    filename,
 		DownloadURL: data.DownloadURL,
 		Shasum:      data.Shasum,
+		SigningKeys: data.SigningKeys.GPGPublicKeys,
+		Protocols:   data.Protocols,
+
+		ShasumsURL:          data.ShasumsURL,
+		ShasumsSignatureURL: data.ShasumsSignatureURL,
 	}, nil
 }
 
@@ -196,6 +357,7 @@ func (c *RegistryClient) DownloadProvider(ctx context.Context, info *ProviderDow
 	if err != nil {
 		return nil, fmt.Errorf("failed to create download request: %w", err)
 	}
+	c.authorize(req)
 
 	// Execute with retries
 	var resp *http.Response
@@ -227,8 +389,14 @@ func (c *RegistryClient) DownloadProvider(ctx context.Context, info *ProviderDow
 		return nil, fmt.Errorf("download returned status %d", resp.StatusCode)
 	}
 
+	// Refuse an announced oversized archive up front, and abort mid-stream
+	// when the size is not announced or understated
+	if c.maxDownloadSize > 0 && resp.ContentLength > c.maxDownloadSize {
+		return nil, storage.SizeLimitError(c.maxDownloadSize)
+	}
+
 	// Read the entire file
-	data, err := io.ReadAll(resp.Body)
+	data, err := io.ReadAll(storage.LimitReader(resp.Body, c.maxDownloadSize))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read download: %w", err)
 	}
@@ -251,12 +419,74 @@ type DownloadResult struct {
 	Data     []byte
 	Error    error
 	Duration time.Duration
+
+	// Verified is set when the archive's shasum is listed in a SHA256SUMS
+	// document signed by one of the release's signing keys
+	Verified bool
+}
+
+// maxShasumsSize caps the SHA256SUMS document and signature downloads
+const maxShasumsSize = 1 << 20
+
+// ErrSignatureUnpublished is returned by VerifySignature when the registry
+// published no signed SHA256SUMS or no keys to check it against
+var ErrSignatureUnpublished = errors.New("registry did not publish a signed SHA256SUMS")
+
+// VerifySignature checks that the archive described by info is listed in
+// its release's SHA256SUMS and that the document carries a valid signature
+// from one of the release's signing keys
+func (c *RegistryClient) VerifySignature(ctx context.Context, info *ProviderDownloadInfo) error {
+	if info.ShasumsURL == "" || info.ShasumsSignatureURL == "" || len(info.SigningKeys) == 0 {
+		return ErrSignatureUnpublished
+	}
+
+	shasums, err := c.fetchDocument(ctx, info.ShasumsURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch SHA256SUMS: %w", err)
+	}
+	signature, err := c.fetchDocument(ctx, info.ShasumsSignatureURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch SHA256SUMS signature: %w", err)
+	}
+
+	if err := VerifyShasumsSignature(shasums, signature, info.SigningKeys); err != nil {
+		return err
+	}
+	if !ShasumsListed(shasums, info.Shasum) {
+		return fmt.Errorf("SHA256SUMS does not list shasum %s", info.Shasum)
+	}
+	return nil
+}
+
+// fetchDocument downloads a small release document such as SHA256SUMS
+func (c *RegistryClient) fetchDocument(ctx context.Context, documentURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, documentURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("returned status %d", resp.StatusCode)
+	}
+	return io.ReadAll(storage.LimitReader(resp.Body, maxShasumsSize))
 }
 
 // DownloadProviderComplete performs the complete download workflow:
 // 1. Get download info from registry
 // 2. Download the provider binary
 // 3. Verify checksum
+// 4. Verify the signed SHA256SUMS, unless signature verification is off
+//
+// An archive whose release publishes no signature is still returned, with
+// Verified unset, so it can be stored and reported as unverified. A published
+// signature that does not verify fails the download.
 func (c *RegistryClient) DownloadProviderComplete(ctx context.Context, namespace, providerType, version, os, arch string) *DownloadResult {
 	start := time.Now()
 	result := &DownloadResult{}
@@ -277,6 +507,20 @@ func (c *RegistryClient) DownloadProviderComplete(ctx context.Context, namespace
 		result.Duration = time.Since(start)
 		return result
 	}
+
+	if c.verifySignatures {
+		err := c.VerifySignature(ctx, info)
+		switch {
+		case errors.Is(err, ErrSignatureUnpublished):
+			log.Printf("Provider %s/%s %s (%s) is unverified: %v", namespace, providerType, version, info.Platform, err)
+		case err != nil:
+			result.Error = fmt.Errorf("signature verification failed: %w", err)
+			result.Duration = time.Since(start)
+			return result
+		default:
+			result.Verified = true
+		}
+	}
 	result.Data = data
 
 	result.Duration = time.Since(start)
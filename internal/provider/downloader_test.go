@@ -1,15 +1,18 @@
 package provider
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/ned1313/terraform-mirror/internal/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -35,6 +38,8 @@ func TestGetDownloadInfo_Success(t *testing.T) {
 			DownloadURL: "http://example.com/download.zip",
 			Shasum:      shasum,
 		}
+		resp.SigningKeys.GPGPublicKeys = []GPGPublicKey{{KeyID: "34365D9472D7468F", ASCIIArmor: "-----BEGIN PGP PUBLIC KEY BLOCK-----"}}
+		resp.Protocols = []string{"5.0", "6.0"}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(resp)
@@ -61,6 +66,33 @@ func TestGetDownloadInfo_Success(t *testing.T) {
 	assert.Equal(t, "terraform-provider-aws_5.0.0_linux_amd64.zip", info.Filename)
 	assert.Equal(t, "http://example.com/download.zip", info.DownloadURL)
 	assert.Equal(t, shasum, info.Shasum)
+	require.Len(t, info.SigningKeys, 1)
+	assert.Equal(t, "34365D9472D7468F", info.SigningKeys[0].KeyID)
+	assert.Equal(t, []string{"5.0", "6.0"}, info.Protocols)
+}
+
+func TestGetDownloadInfo_CorrectsNonStandardFilename(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := registryDownloadResponse{
+			OS:          "linux",
+			Arch:        "amd64",
+			Filename:    "aws-latest.zip",
+			DownloadURL: "http://example.com/download.zip",
+			Shasum:      "abc123",
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := &RegistryClient{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		baseURL:    server.URL + "/v1/providers",
+	}
+
+	info, err := client.GetDownloadInfo(context.Background(), "hashicorp", "aws", "5.0.0", "linux", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, "terraform-provider-aws_5.0.0_linux_amd64.zip", info.Filename)
 }
 
 func TestGetDownloadInfo_NotFound(t *testing.T) {
@@ -121,6 +153,30 @@ func TestGetDownloadInfo_ContextCancellation(t *testing.T) {
 	assert.Nil(t, info)
 }
 
+func TestGetVersionPlatforms(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/providers/hashicorp/aws/versions", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"versions": [
+			{"version": "5.0.0", "platforms": [{"os": "linux", "arch": "amd64"}, {"os": "darwin", "arch": "arm64"}]},
+			{"version": "4.0.0", "platforms": [{"os": "linux", "arch": "amd64"}]}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := &RegistryClient{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		baseURL:    server.URL + "/v1/providers",
+	}
+
+	platforms, err := client.GetVersionPlatforms(context.Background(), "hashicorp", "aws")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"5.0.0": {"linux_amd64", "darwin_arm64"},
+		"4.0.0": {"linux_amd64"},
+	}, platforms)
+}
+
 func TestDownloadProvider_Success(t *testing.T) {
 	// Mock provider data
 	providerZip := []byte("fake-provider-binary-content")
@@ -185,6 +241,56 @@ func TestDownloadProvider_ChecksumMismatch(t *testing.T) {
 	assert.Contains(t, err.Error(), "checksum mismatch")
 }
 
+func TestDownloadProvider_SizeLimit(t *testing.T) {
+	providerZip := bytes.Repeat([]byte("x"), 4096)
+	hash := sha256.Sum256(providerZip)
+	shasum := hex.EncodeToString(hash[:])
+
+	tests := []struct {
+		name    string
+		limit   int64
+		chunked bool // Stream the body without announcing its length
+		wantErr bool
+	}{
+		{name: "announced size over limit", limit: 1024, wantErr: true},
+		{name: "streamed body over limit", limit: 1024, chunked: true, wantErr: true},
+		{name: "within limit", limit: 4096, chunked: true},
+		{name: "unlimited", limit: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			downloadServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !tt.chunked {
+					w.Header().Set("Content-Length", strconv.Itoa(len(providerZip)))
+				}
+				for i := 0; i < len(providerZip); i += 512 {
+					if _, err := w.Write(providerZip[i : i+512]); err != nil {
+						return
+					}
+					w.(http.Flusher).Flush()
+				}
+			}))
+			defer downloadServer.Close()
+
+			client := NewRegistryClient()
+			client.SetMaxDownloadSize(tt.limit)
+
+			data, err := client.DownloadProvider(context.Background(), &ProviderDownloadInfo{
+				DownloadURL: downloadServer.URL,
+				Shasum:      shasum,
+			})
+			if tt.wantErr {
+				require.ErrorIs(t, err, storage.ErrSizeLimitExceeded)
+				assert.Nil(t, data)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, providerZip, data)
+		})
+	}
+}
+
 func TestDownloadProvider_DownloadFails(t *testing.T) {
 	// Create server that returns error
 	downloadServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
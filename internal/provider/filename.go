@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	providerFilenamePrefix = "terraform-provider-"
+	providerFilenameSuffix = ".zip"
+)
+
+var (
+	// providerTypeRegex validates the type component of a provider filename
+	providerTypeRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9-]*$`)
+
+	// platformPartRegex validates the os and arch components of a platform.
+	// Neither may contain an underscore, which keeps "os_arch" unambiguous.
+	platformPartRegex = regexp.MustCompile(`^[a-z0-9]+$`)
+)
+
+// ParsePlatform splits a platform string (e.g., "linux_amd64") into os and
+// arch. All platform parsing goes through here so a value is accepted or
+// rejected the same way everywhere: exactly one lowercase os and one
+// lowercase arch joined by a single underscore, as Terraform requires.
+func ParsePlatform(platform string) (os, arch string, err error) {
+	os, arch, found := strings.Cut(platform, "_")
+	if !found || !platformPartRegex.MatchString(os) || !platformPartRegex.MatchString(arch) {
+		return "", "", fmt.Errorf("invalid platform format %q, expected 'os_arch' (e.g., linux_amd64)", platform)
+	}
+	return os, arch, nil
+}
+
+// ProviderFilename holds the components of a provider package filename
+type ProviderFilename struct {
+	Type    string
+	Version string
+	OS      string
+	Arch    string
+}
+
+// Platform returns the os_arch platform string
+func (f *ProviderFilename) Platform() string {
+	return f.OS + "_" + f.Arch
+}
+
+// String returns the canonical filename
+func (f *ProviderFilename) String() string {
+	return FormatProviderFilename(f.Type, f.Version, f.OS, f.Arch)
+}
+
+// FormatProviderFilename builds the canonical provider package filename,
+// e.g. terraform-provider-aws_5.0.0_linux_amd64.zip
+func FormatProviderFilename(providerType, version, os, arch string) string {
+	return fmt.Sprintf("%s%s_%s_%s_%s%s", providerFilenamePrefix, providerType, version, os, arch, providerFilenameSuffix)
+}
+
+// ParseProviderFilename parses a provider package filename of the form
+// terraform-provider-<type>_<version>_<os>_<arch>.zip
+func ParseProviderFilename(name string) (*ProviderFilename, error) {
+	if !strings.HasPrefix(name, providerFilenamePrefix) {
+		return nil, fmt.Errorf("invalid provider filename %q: must start with %q", name, providerFilenamePrefix)
+	}
+	if !strings.HasSuffix(name, providerFilenameSuffix) {
+		return nil, fmt.Errorf("invalid provider filename %q: must end with %q", name, providerFilenameSuffix)
+	}
+
+	base := strings.TrimSuffix(strings.TrimPrefix(name, providerFilenamePrefix), providerFilenameSuffix)
+	parts := strings.Split(base, "_")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid provider filename %q: expected <type>_<version>_<os>_<arch>", name)
+	}
+
+	f := &ProviderFilename{
+		Type:    parts[0],
+		Version: parts[1],
+		OS:      parts[2],
+		Arch:    parts[3],
+	}
+
+	if !providerTypeRegex.MatchString(f.Type) {
+		return nil, fmt.Errorf("invalid provider filename %q: invalid type %q", name, f.Type)
+	}
+	if !semanticVersionRegex.MatchString(f.Version) {
+		return nil, fmt.Errorf("invalid provider filename %q: invalid version %q", name, f.Version)
+	}
+	if !platformPartRegex.MatchString(f.OS) || !platformPartRegex.MatchString(f.Arch) {
+		return nil, fmt.Errorf("invalid provider filename %q: invalid platform %q", name, f.OS+"_"+f.Arch)
+	}
+
+	return f, nil
+}
+
+// CheckProviderFilename verifies that a filename is well formed and matches
+// the provider it is stored for
+func CheckProviderFilename(name, providerType, version, os, arch string) error {
+	f, err := ParseProviderFilename(name)
+	if err != nil {
+		return err
+	}
+	if f.Type != providerType || f.Version != version || f.OS != os || f.Arch != arch {
+		return fmt.Errorf("provider filename %q does not match %s %s %s_%s", name, providerType, version, os, arch)
+	}
+	return nil
+}
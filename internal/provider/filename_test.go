@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProviderFilename(t *testing.T) {
+	f, err := ParseProviderFilename("terraform-provider-aws_5.0.0_linux_amd64.zip")
+	require.NoError(t, err)
+	assert.Equal(t, "aws", f.Type)
+	assert.Equal(t, "5.0.0", f.Version)
+	assert.Equal(t, "linux", f.OS)
+	assert.Equal(t, "amd64", f.Arch)
+	assert.Equal(t, "linux_amd64", f.Platform())
+	assert.Equal(t, "terraform-provider-aws_5.0.0_linux_amd64.zip", f.String())
+
+	f, err = ParseProviderFilename("terraform-provider-google-beta_5.1.0-rc1_darwin_arm64.zip")
+	require.NoError(t, err)
+	assert.Equal(t, "google-beta", f.Type)
+	assert.Equal(t, "5.1.0-rc1", f.Version)
+}
+
+func TestParseProviderFilename_Malformed(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		errorMsg string
+	}{
+		{name: "empty", filename: "", errorMsg: "must start with"},
+		{name: "missing prefix", filename: "aws_5.0.0_linux_amd64.zip", errorMsg: "must start with"},
+		{name: "wrong extension", filename: "terraform-provider-aws_5.0.0_linux_amd64.tar.gz", errorMsg: "must end with"},
+		{name: "missing platform", filename: "terraform-provider-aws_5.0.0.zip", errorMsg: "expected <type>_<version>_<os>_<arch>"},
+		{name: "extra component", filename: "terraform-provider-aws_5.0.0_linux_amd64_x.zip", errorMsg: "expected <type>_<version>_<os>_<arch>"},
+		{name: "empty type", filename: "terraform-provider-_5.0.0_linux_amd64.zip", errorMsg: "invalid type"},
+		{name: "bad version", filename: "terraform-provider-aws_v5_linux_amd64.zip", errorMsg: "invalid version"},
+		{name: "uppercase os", filename: "terraform-provider-aws_5.0.0_Linux_amd64.zip", errorMsg: "invalid platform"},
+		{name: "empty arch", filename: "terraform-provider-aws_5.0.0_linux_.zip", errorMsg: "invalid platform"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseProviderFilename(tt.filename)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
+
+func TestCheckProviderFilename(t *testing.T) {
+	assert.NoError(t, CheckProviderFilename("terraform-provider-aws_5.0.0_linux_amd64.zip", "aws", "5.0.0", "linux", "amd64"))
+
+	err := CheckProviderFilename("terraform-provider-aws_5.0.0_linux_amd64.zip", "aws", "5.0.0", "darwin", "arm64")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match")
+}
+
+// platformCases is shared by the platform parsing tests so every code path is
+// checked against the same table
+var platformCases = []struct {
+	platform string
+	os       string
+	arch     string
+	valid    bool
+}{
+	{platform: "linux_amd64", os: "linux", arch: "amd64", valid: true},
+	{platform: "linux_arm", os: "linux", arch: "arm", valid: true},
+	{platform: "darwin_arm64", os: "darwin", arch: "arm64", valid: true},
+	{platform: "windows_386", os: "windows", arch: "386", valid: true},
+	{platform: "freebsd_386", os: "freebsd", arch: "386", valid: true},
+	{platform: "solaris_amd64", os: "solaris", arch: "amd64", valid: true},
+	{platform: "darwin_amd64_v2"},
+	{platform: "linux"},
+	{platform: "linux_"},
+	{platform: "_amd64"},
+	{platform: "Linux_amd64"},
+	{platform: "linux-amd64"},
+	{platform: ""},
+}
+
+func TestParsePlatform(t *testing.T) {
+	for _, tt := range platformCases {
+		t.Run(tt.platform, func(t *testing.T) {
+			os, arch, err := ParsePlatform(tt.platform)
+			if !tt.valid {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "invalid platform format")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.os, os)
+			assert.Equal(t, tt.arch, arch)
+		})
+	}
+}
+
+func TestParsePlatform_AgreesWithFilenames(t *testing.T) {
+	for _, tt := range platformCases {
+		t.Run(tt.platform, func(t *testing.T) {
+			_, _, platformErr := ParsePlatform(tt.platform)
+			f, filenameErr := ParseProviderFilename("terraform-provider-aws_5.0.0_" + tt.platform + ".zip")
+
+			assert.Equal(t, platformErr == nil, filenameErr == nil,
+				"platform error %v, filename error %v", platformErr, filenameErr)
+			if tt.valid {
+				assert.Equal(t, tt.platform, f.Platform())
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+)
+
+// CheckImmutable reports whether a provider archive with the given shasum may
+// be stored under key without replacing different content. It returns the
+// existing provider when the same content is already mirrored, so callers can
+// skip the upload, and an error wrapping database.ErrShasumMismatch when the
+// identity or the object at key holds different content.
+func CheckImmutable(ctx context.Context, repo *database.ProviderRepository, store storage.Storage, key, namespace, providerType, version, platform, shasum string) (*database.Provider, error) {
+	existing, err := repo.GetByIdentityWithShasum(ctx, namespace, providerType, version, platform, shasum)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+
+	// An object without a record, e.g. left behind by a failed insert
+	exists, err := store.Exists(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check storage: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	stored, err := storage.ObjectShasum(ctx, store, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored shasum: %w", err)
+	}
+	if !strings.EqualFold(stored, shasum) {
+		return nil, fmt.Errorf("%w: %s is stored with shasum %s, not %s",
+			database.ErrShasumMismatch, key, stored, shasum)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/config"
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// contentRegistry serves the same archive bytes for every platform
+type contentRegistry struct {
+	data []byte
+}
+
+func (r *contentRegistry) DownloadProviderComplete(ctx context.Context, namespace, providerType, version, os, arch string) *DownloadResult {
+	platform := os + "_" + arch
+	return &DownloadResult{
+		Info: &ProviderDownloadInfo{
+			Namespace: namespace,
+			Type:      providerType,
+			Version:   version,
+			OS:        os,
+			Arch:      arch,
+			Platform:  platform,
+			Filename:  fmt.Sprintf("terraform-provider-%s_%s_%s.zip", providerType, version, platform),
+			Shasum:    sha256Hex(r.data),
+		},
+		Data: r.data,
+	}
+}
+
+func (r *contentRegistry) GetAvailableVersions(ctx context.Context, namespace, providerType string) ([]string, error) {
+	return nil, nil
+}
+
+func TestCheckImmutable(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := database.NewProviderRepository(db)
+	store := storage.NewMockStorage()
+	ctx := context.Background()
+
+	original := []byte("original")
+	require.NoError(t, repo.Create(ctx, &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "random",
+		Version:   "3.0.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-random_3.0.0_linux_amd64.zip",
+		Shasum:    sha256Hex(original),
+		S3Key:     "providers/random/3.0.0/linux_amd64.zip",
+	}))
+
+	t.Run("same bytes as the record", func(t *testing.T) {
+		existing, err := CheckImmutable(ctx, repo, store, "providers/random/3.0.0/linux_amd64.zip",
+			"hashicorp", "random", "3.0.0", "linux_amd64", sha256Hex(original))
+		require.NoError(t, err)
+		require.NotNil(t, existing)
+		assert.Equal(t, "3.0.0", existing.Version)
+	})
+
+	t.Run("different bytes than the record", func(t *testing.T) {
+		_, err := CheckImmutable(ctx, repo, store, "providers/random/3.0.0/linux_amd64.zip",
+			"hashicorp", "random", "3.0.0", "linux_amd64", sha256Hex([]byte("tampered")))
+		assert.ErrorIs(t, err, database.ErrShasumMismatch)
+	})
+
+	t.Run("nothing stored", func(t *testing.T) {
+		existing, err := CheckImmutable(ctx, repo, store, "providers/random/3.1.0/linux_amd64.zip",
+			"hashicorp", "random", "3.1.0", "linux_amd64", sha256Hex(original))
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+	})
+
+	t.Run("orphaned object", func(t *testing.T) {
+		key := "providers/random/3.2.0/linux_amd64.zip"
+		store.SetData(key, original) // no metadata, so the object is hashed
+
+		existing, err := CheckImmutable(ctx, repo, store, key, "hashicorp", "random", "3.2.0", "linux_amd64", sha256Hex(original))
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+
+		_, err = CheckImmutable(ctx, repo, store, key, "hashicorp", "random", "3.2.0", "linux_amd64", sha256Hex([]byte("tampered")))
+		assert.ErrorIs(t, err, database.ErrShasumMismatch)
+	})
+}
+
+func TestAutoDownload_ImmutableArtifacts(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := storage.NewMockStorage()
+	cfg := &config.AutoDownloadConfig{
+		Enabled:            true,
+		RateLimitPerMinute: 60000,
+		MaxConcurrentDL:    1,
+		TimeoutSeconds:     30,
+	}
+	svc := NewAutoDownloadService(cfg, &config.ProvidersConfig{ImmutableArtifacts: true}, store, db)
+	ctx := context.Background()
+
+	original := []byte("original")
+	svc.SetRegistry(&contentRegistry{data: original})
+	first, err := svc.DownloadProvider(ctx, "hashicorp", "random", "3.0.0", "linux", "amd64")
+	require.NoError(t, err)
+
+	t.Run("same bytes are idempotent", func(t *testing.T) {
+		again, err := svc.DownloadProvider(ctx, "hashicorp", "random", "3.0.0", "linux", "amd64")
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, again.ID)
+	})
+
+	t.Run("different bytes are rejected", func(t *testing.T) {
+		svc.SetRegistry(&contentRegistry{data: []byte("tampered")})
+		_, err := svc.DownloadProvider(ctx, "hashicorp", "random", "3.0.0", "linux", "amd64")
+		assert.ErrorIs(t, err, database.ErrShasumMismatch)
+
+		reader, err := store.Download(ctx, first.S3Key)
+		require.NoError(t, err)
+		stored, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal(original, stored), "stored archive must not change")
+	})
+}
@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/ned1313/terraform-mirror/internal/definition"
+)
+
+// DefaultRegistryHost is the hostname implied by provider addresses that
+// omit one
+const DefaultRegistryHost = "registry.terraform.io"
+
+// LockedProvider is one provider selection from a Terraform dependency lock
+// file (.terraform.lock.hcl)
+type LockedProvider struct {
+	Hostname  string // e.g., "registry.terraform.io"
+	Namespace string // e.g., "hashicorp"
+	Type      string // e.g., "aws"
+	Version   string // e.g., "5.31.0"
+
+	// ZipHashes are the lowercase hex SHA-256 sums from the "zh:" hashes, one
+	// per release archive. They are not tied to a platform, so any of them is
+	// an acceptable archive for the version.
+	ZipHashes []string
+}
+
+// Address returns the fully qualified provider address
+func (p *LockedProvider) Address() string {
+	return p.Hostname + "/" + p.Namespace + "/" + p.Type
+}
+
+// hclLockfile represents the lock file structure. Terraform may add other
+// blocks over time, so anything but provider blocks is ignored.
+type hclLockfile struct {
+	Providers []hclLockedProvider `hcl:"provider,block"`
+	Remain    hcl.Body            `hcl:",remain"`
+}
+
+// hclLockedProvider represents a single provider block in a lock file
+type hclLockedProvider struct {
+	Address      string    `hcl:"address,label"`
+	Version      string    `hcl:"version"`
+	Constraints  string    `hcl:"constraints,optional"`
+	Hashes       []string  `hcl:"hashes,optional"`
+	AddressRange hcl.Range `hcl:"address,label_range"`
+	VersionRange hcl.Range `hcl:"version,attr_range"`
+	HashesRange  hcl.Range `hcl:"hashes,attr_range"`
+}
+
+// lockfileFilename names the lock file in problem positions
+const lockfileFilename = ".terraform.lock.hcl"
+
+// zipHashRegex matches the value of a "zh:" hash
+var zipHashRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ParseLockfile parses a Terraform dependency lock file. Every problem in the
+// file is reported together in a *definition.Error. "h1:" hashes cover the
+// unpacked provider rather than the archive, so only "zh:" hashes are kept.
+func ParseLockfile(content []byte) ([]*LockedProvider, error) {
+	file, diags := hclparse.NewParser().ParseHCL(content, lockfileFilename)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse lock file: %w", diags)
+	}
+
+	var lockfile hclLockfile
+	problems := &definition.Error{}
+	problems.AddDiagnostics(gohcl.DecodeBody(file.Body, nil, &lockfile))
+
+	var providers []*LockedProvider
+	seen := make(map[string]hcl.Range)
+
+	for i := range lockfile.Providers {
+		p := &lockfile.Providers[i]
+		locked := parseLockedProvider(p, problems)
+		if locked == nil {
+			continue
+		}
+
+		if first, ok := seen[locked.Address()]; ok {
+			problems.Add(&p.AddressRange, "duplicate provider %q (first defined on line %d)", locked.Address(), first.Start.Line)
+			continue
+		}
+		seen[locked.Address()] = p.AddressRange
+
+		providers = append(providers, locked)
+	}
+
+	if err := problems.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no providers found in lock file")
+	}
+
+	return providers, nil
+}
+
+// parseLockedProvider validates and converts a lock file provider block,
+// recording any problems. It returns nil if the block is invalid.
+func parseLockedProvider(p *hclLockedProvider, problems *definition.Error) *LockedProvider {
+	valid := true
+	fail := func(rng *hcl.Range, format string, args ...interface{}) {
+		problems.Add(rng, "provider %q: "+format, append([]interface{}{p.Address}, args...)...)
+		valid = false
+	}
+
+	locked := &LockedProvider{Version: p.Version}
+
+	// Addresses are hostname/namespace/type; the hostname may be omitted
+	parts := strings.Split(p.Address, "/")
+	switch len(parts) {
+	case 2:
+		locked.Hostname = DefaultRegistryHost
+		locked.Namespace, locked.Type = parts[0], parts[1]
+	case 3:
+		locked.Hostname = strings.ToLower(parts[0])
+		locked.Namespace, locked.Type = parts[1], parts[2]
+		if locked.Hostname == "" {
+			fail(&p.AddressRange, "invalid address, the hostname is empty")
+		}
+	default:
+		fail(&p.AddressRange, "invalid address, expected 'hostname/namespace/type' (e.g., registry.terraform.io/hashicorp/aws)")
+	}
+	if len(parts) == 2 || len(parts) == 3 {
+		for i, part := range []string{locked.Namespace, locked.Type} {
+			if !providerKeyPartRegex.MatchString(part) {
+				fail(&p.AddressRange, "invalid address, %s %q must be non-empty and contain only letters, digits, '-' and '_'",
+					[]string{"namespace", "type"}[i], part)
+			}
+		}
+	}
+
+	if p.VersionRange != (hcl.Range{}) && !semanticVersionRegex.MatchString(p.Version) {
+		fail(&p.VersionRange, "invalid version format %q, expected semantic version (e.g., 1.2.3)", p.Version)
+	}
+
+	for _, hash := range p.Hashes {
+		value, ok := strings.CutPrefix(hash, "zh:")
+		if !ok {
+			continue
+		}
+		value = strings.ToLower(value)
+		if !zipHashRegex.MatchString(value) {
+			fail(&p.HashesRange, "invalid zh: hash %q, expected 64 hex characters", hash)
+			continue
+		}
+		locked.ZipHashes = append(locked.ZipHashes, value)
+	}
+
+	if !valid {
+		return nil
+	}
+	return locked
+}
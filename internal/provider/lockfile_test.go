@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/definition"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleLockfile = `# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.31.0"
+  constraints = "~> 5.0"
+  hashes = [
+    "h1:ltxyuBWIy9cq0kIKDJH1jeWJy/y7XJLjS4QrsQK4plA=",
+    "zh:0cdb9c2083bf0902442384f7309367791e4640581652dda456f2d6d7abf0de8d",
+    "zh:2FE0D0AA6C9A0E2A6E7D5E8F4C9C1B2A3D4E5F60718293A4B5C6D7E8F9012345",
+  ]
+}
+
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.6.0"
+  hashes = [
+    "h1:R5Ucn26riKIEijcsiOMBR3uOAjuOMfI1x7XvH4P6B1w=",
+  ]
+}
+
+provider "example.com/acme/widget" {
+  version = "0.1.0"
+}
+`
+
+func TestParseLockfile(t *testing.T) {
+	providers, err := ParseLockfile([]byte(sampleLockfile))
+	require.NoError(t, err)
+	require.Len(t, providers, 3)
+
+	assert.Equal(t, &LockedProvider{
+		Hostname:  "registry.terraform.io",
+		Namespace: "hashicorp",
+		Type:      "aws",
+		Version:   "5.31.0",
+		ZipHashes: []string{
+			"0cdb9c2083bf0902442384f7309367791e4640581652dda456f2d6d7abf0de8d",
+			"2fe0d0aa6c9a0e2a6e7d5e8f4c9c1b2a3d4e5f60718293a4b5c6d7e8f9012345",
+		},
+	}, providers[0])
+
+	assert.Equal(t, "registry.terraform.io/hashicorp/random", providers[1].Address())
+	assert.Equal(t, "3.6.0", providers[1].Version)
+	assert.Empty(t, providers[1].ZipHashes, "h1: hashes are not archive hashes")
+
+	assert.Equal(t, "example.com", providers[2].Hostname)
+}
+
+func TestParseLockfile_ShortAddress(t *testing.T) {
+	providers, err := ParseLockfile([]byte(`provider "hashicorp/aws" { version = "5.0.0" }`))
+	require.NoError(t, err)
+	require.Len(t, providers, 1)
+	assert.Equal(t, "registry.terraform.io/hashicorp/aws", providers[0].Address())
+}
+
+func TestParseLockfile_Problems(t *testing.T) {
+	content := `provider "registry.terraform.io/hashicorp/aws" {
+  version = "latest"
+}
+
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.6.0"
+  hashes  = ["zh:not-a-hash"]
+}
+
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.6.0"
+}
+
+provider "too/many/address/parts" {
+  version = "1.0.0"
+}
+`
+	_, err := ParseLockfile([]byte(content))
+	require.Error(t, err)
+
+	var defErr *definition.Error
+	require.True(t, errors.As(err, &defErr))
+	require.Len(t, defErr.Problems, 3, err.Error())
+
+	assert.Equal(t, 2, defErr.Problems[0].Line)
+	assert.Contains(t, defErr.Problems[0].Message, `invalid version format "latest"`)
+	assert.Equal(t, 7, defErr.Problems[1].Line)
+	assert.Contains(t, defErr.Problems[1].Message, "invalid zh: hash")
+	assert.Equal(t, 14, defErr.Problems[2].Line)
+	assert.Contains(t, defErr.Problems[2].Message, "invalid address")
+}
+
+func TestParseLockfile_Empty(t *testing.T) {
+	_, err := ParseLockfile([]byte("# nothing locked yet\n"))
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "no providers found"))
+
+	_, err = ParseLockfile([]byte(`provider "hashicorp/aws" {`))
+	assert.Error(t, err)
+}
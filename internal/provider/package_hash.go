@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// PackageHashV1 computes Terraform's "h1:" hash of a provider zip archive,
+// the form recorded in lock files. It is the dirhash Hash1 of the files the
+// archive extracts to, so it covers the archive's contents rather than its
+// bytes: each file's SHA-256 is listed by name in sorted order, and the
+// listing is hashed again. Directory entries are not part of the hash.
+func PackageHashV1(archive []byte) (string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return "", fmt.Errorf("failed to read zip archive: %w", err)
+	}
+
+	files := make(map[string]*zip.File)
+	names := make([]string, 0, len(reader.File))
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if strings.Contains(f.Name, "\n") {
+			return "", fmt.Errorf("file name %q contains a newline", f.Name)
+		}
+		if _, ok := files[f.Name]; ok {
+			return "", fmt.Errorf("duplicate file %q in zip archive", f.Name)
+		}
+		files[f.Name] = f
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+
+	summary := sha256.New()
+	for _, name := range names {
+		content, err := files[name].Open()
+		if err != nil {
+			return "", fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		fileHash := sha256.New()
+		_, err = io.Copy(fileHash, content)
+		content.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		fmt.Fprintf(summary, "%x  %s\n", fileHash.Sum(nil), name)
+	}
+
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
+
+// EncodePackageHash computes the h1: hash of archive for the providers.h1_hash
+// column, returning null when the archive cannot be read as a zip
+func EncodePackageHash(archive []byte) sql.NullString {
+	hash, err := PackageHashV1(archive)
+	if err != nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: hash, Valid: true}
+}
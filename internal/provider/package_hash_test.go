@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildZip creates a zip archive with the given entries in order; names
+// ending in "/" are directories
+func buildZip(t *testing.T, entries ...[2]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		w, err := zw.Create(entry[0])
+		require.NoError(t, err)
+		_, err = w.Write([]byte(entry[1]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestPackageHashV1(t *testing.T) {
+	// Computed independently from the dirhash Hash1 definition
+	const want = "h1:xZI3mwl3y9eMOlY5HzkUOWScTOezYcbNnsNS0UVRgJE="
+
+	archive := buildZip(t,
+		[2]string{"terraform-provider-example_v1.0.0", "provider binary"},
+		[2]string{"LICENSE", "license text\n"},
+	)
+	hash, err := PackageHashV1(archive)
+	require.NoError(t, err)
+	assert.Equal(t, want, hash)
+
+	// Entry order and directory entries do not change the hash
+	reordered := buildZip(t,
+		[2]string{"docs/", ""},
+		[2]string{"LICENSE", "license text\n"},
+		[2]string{"terraform-provider-example_v1.0.0", "provider binary"},
+	)
+	hash, err = PackageHashV1(reordered)
+	require.NoError(t, err)
+	assert.Equal(t, want, hash)
+
+	// Different content does
+	changed := buildZip(t,
+		[2]string{"terraform-provider-example_v1.0.0", "other binary"},
+		[2]string{"LICENSE", "license text\n"},
+	)
+	hash, err = PackageHashV1(changed)
+	require.NoError(t, err)
+	assert.NotEqual(t, want, hash)
+}
+
+func TestPackageHashV1_Invalid(t *testing.T) {
+	_, err := PackageHashV1([]byte("not a zip"))
+	assert.Error(t, err)
+
+	assert.False(t, EncodePackageHash([]byte("not a zip")).Valid)
+	assert.True(t, EncodePackageHash(buildZip(t, [2]string{"a", "b"})).Valid)
+}
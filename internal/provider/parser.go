@@ -5,7 +5,10 @@ import (
 	"regexp"
 	"strings"
 
-	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/ned1313/terraform-mirror/internal/definition"
 )
 
 // ProviderDefinition represents a parsed provider definition from HCL
@@ -29,14 +32,21 @@ type hclProviderConfig struct {
 
 // hclProvider represents a single provider block in HCL
 type hclProvider struct {
-	Source    string   `hcl:"source,label"`
-	Versions  []string `hcl:"versions"`
-	Platforms []string `hcl:"platforms"`
+	Source         string    `hcl:"source,label"`
+	Versions       []string  `hcl:"versions,optional"`
+	Platforms      []string  `hcl:"platforms,optional"` // Omitted = use default platforms
+	SourceRange    hcl.Range `hcl:"source,label_range"`
+	VersionsRange  hcl.Range `hcl:"versions,attr_range"`
+	PlatformsRange hcl.Range `hcl:"platforms,attr_range"`
+	DefRange       hcl.Range `hcl:",def_range"`
 }
 
+// providerFilename names the definition file in problem positions
+const providerFilename = "providers.hcl"
+
 var (
-	// providerSourceRegex validates provider source format (namespace/type)
-	providerSourceRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+/[a-zA-Z0-9_-]+$`)
+	// providerKeyPartRegex validates each part of a provider source (namespace/type)
+	providerKeyPartRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 
 	// semanticVersionRegex validates semantic version format
 	semanticVersionRegex = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+(-[a-zA-Z0-9.]+)?(\+[a-zA-Z0-9.]+)?$`)
@@ -45,39 +55,49 @@ var (
 	platformRegex = regexp.MustCompile(`^(linux|darwin|windows|freebsd)_(amd64|arm64|386|arm)$`)
 )
 
-// ParseHCL parses a provider definition HCL file
+// ParseHCL parses a provider definition HCL file. Syntax errors stop
+// parsing; otherwise every problem in the file (unknown attributes, bad
+// sources, missing or malformed versions and platforms, duplicates) is
+// reported together in a *definition.Error.
 func ParseHCL(content []byte) (*ProviderDefinitions, error) {
-	var hclConfig hclProviderConfig
-
-	// Parse HCL
-	if err := hclsimple.Decode("providers.hcl", content, nil, &hclConfig); err != nil {
-		return nil, fmt.Errorf("failed to parse HCL: %w", err)
+	file, diags := hclparse.NewParser().ParseHCL(content, providerFilename)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse HCL: %w", diags)
 	}
 
+	var hclConfig hclProviderConfig
+	problems := &definition.Error{}
+	problems.AddDiagnostics(gohcl.DecodeBody(file.Body, nil, &hclConfig))
+
 	// Convert to ProviderDefinitions
 	defs := &ProviderDefinitions{
 		Providers: make([]*ProviderDefinition, 0, len(hclConfig.Providers)),
 	}
 
 	// Track seen providers to detect duplicates
-	seen := make(map[string]bool)
+	seen := make(map[string]hcl.Range)
 
-	for _, p := range hclConfig.Providers {
-		// Validate and parse
-		def, err := parseProvider(&p)
-		if err != nil {
-			return nil, fmt.Errorf("provider %q: %w", p.Source, err)
+	for i := range hclConfig.Providers {
+		p := &hclConfig.Providers[i]
+		def := parseProvider(p, problems)
+		if def == nil {
+			continue
 		}
 
 		// Check for duplicates
-		if seen[def.Source] {
-			return nil, fmt.Errorf("duplicate provider definition: %q", def.Source)
+		if first, ok := seen[def.Source]; ok {
+			problems.Add(&p.SourceRange, "duplicate provider definition: %q (first defined on line %d)", def.Source, first.Start.Line)
+			continue
 		}
-		seen[def.Source] = true
+		seen[def.Source] = p.SourceRange
 
 		defs.Providers = append(defs.Providers, def)
 	}
 
+	if err := problems.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
 	if len(defs.Providers) == 0 {
 		return nil, fmt.Errorf("no provider definitions found")
 	}
@@ -85,47 +105,75 @@ func ParseHCL(content []byte) (*ProviderDefinitions, error) {
 	return defs, nil
 }
 
-// parseProvider validates and converts an HCL provider block
-func parseProvider(p *hclProvider) (*ProviderDefinition, error) {
-	// Validate source format
-	if !providerSourceRegex.MatchString(p.Source) {
-		return nil, fmt.Errorf("invalid source format, expected 'namespace/type'")
+// parseProvider validates and converts an HCL provider block, recording any
+// problems. It returns nil if the block is invalid.
+func parseProvider(p *hclProvider, problems *definition.Error) *ProviderDefinition {
+	valid := true
+	fail := func(rng *hcl.Range, format string, args ...interface{}) {
+		problems.Add(rng, "provider %q: "+format, append([]interface{}{p.Source}, args...)...)
+		valid = false
 	}
 
-	// Split source into namespace and type
-	parts := strings.SplitN(p.Source, "/", 2)
-	namespace := parts[0]
-	providerType := parts[1]
+	// Validate the source shape: namespace/type
+	parts := strings.Split(p.Source, "/")
+	if len(parts) != 2 {
+		fail(&p.SourceRange, "invalid source format, expected 'namespace/type' (e.g., hashicorp/aws), got %d part(s)", len(parts))
+	} else {
+		for i, part := range parts {
+			if !providerKeyPartRegex.MatchString(part) {
+				fail(&p.SourceRange, "invalid source format, %s %q must be non-empty and contain only letters, digits, '-' and '_'",
+					[]string{"namespace", "type"}[i], part)
+			}
+		}
+	}
 
 	// Validate versions
-	if len(p.Versions) == 0 {
-		return nil, fmt.Errorf("at least one version is required")
+	switch {
+	case p.VersionsRange == (hcl.Range{}):
+		fail(&p.DefRange, "the versions attribute is required, e.g. versions = [\"1.0.0\"]")
+	case len(p.Versions) == 0:
+		fail(&p.VersionsRange, "at least one version is required")
 	}
 
 	for _, v := range p.Versions {
 		if !semanticVersionRegex.MatchString(v) {
-			return nil, fmt.Errorf("invalid version format %q, expected semantic version (e.g., 1.2.3)", v)
+			fail(&p.VersionsRange, "invalid version format %q, expected semantic version (e.g., 1.2.3)", v)
 		}
 	}
 
-	// Validate platforms
-	if len(p.Platforms) == 0 {
-		return nil, fmt.Errorf("at least one platform is required")
+	// Validate platforms; an omitted list is filled in later by ApplyDefaultPlatforms,
+	// but an explicitly empty list is an error
+	if p.PlatformsRange != (hcl.Range{}) && len(p.Platforms) == 0 {
+		fail(&p.PlatformsRange, "at least one platform is required")
 	}
 
 	for _, platform := range p.Platforms {
 		if !platformRegex.MatchString(platform) {
-			return nil, fmt.Errorf("invalid platform format %q, expected 'os_arch' (e.g., linux_amd64)", platform)
+			fail(&p.PlatformsRange, "invalid platform format %q, expected 'os_arch' (e.g., linux_amd64)", platform)
 		}
 	}
 
+	if !valid {
+		return nil
+	}
+
 	return &ProviderDefinition{
 		Source:    p.Source,
-		Namespace: namespace,
-		Type:      providerType,
+		Namespace: parts[0],
+		Type:      parts[1],
 		Versions:  p.Versions,
 		Platforms: p.Platforms,
-	}, nil
+	}
+}
+
+// ApplyDefaultPlatforms sets platforms on every provider definition that
+// did not specify its own
+func (d *ProviderDefinitions) ApplyDefaultPlatforms(platforms []string) {
+	for _, p := range d.Providers {
+		if len(p.Platforms) == 0 {
+			p.Platforms = append([]string(nil), platforms...)
+		}
+	}
 }
 
 // CountItems returns the total number of download items
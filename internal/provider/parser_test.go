@@ -1,8 +1,11 @@
 package provider
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/ned1313/terraform-mirror/internal/definition"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -182,9 +185,34 @@ provider "hashicorp/aws" {
 }
 `)
 
-	_, err := ParseHCL(hcl)
-	assert.Error(t, err)
-	// HCL will report missing required argument
+	defs, err := ParseHCL(hcl)
+	require.NoError(t, err)
+	assert.Nil(t, defs.Providers[0].Platforms)
+	assert.Equal(t, 0, defs.CountItems())
+}
+
+func TestProviderDefinitions_ApplyDefaultPlatforms(t *testing.T) {
+	hcl := []byte(`
+provider "hashicorp/aws" {
+  versions = ["5.0.0"]
+}
+
+provider "hashicorp/azurerm" {
+  versions  = ["3.0.0"]
+  platforms = ["darwin_arm64"]
+}
+`)
+
+	defs, err := ParseHCL(hcl)
+	require.NoError(t, err)
+
+	defs.ApplyDefaultPlatforms([]string{"linux_amd64", "windows_amd64"})
+
+	// Omitted platforms take the default
+	assert.Equal(t, []string{"linux_amd64", "windows_amd64"}, defs.Providers[0].Platforms)
+	// Explicit platforms are kept
+	assert.Equal(t, []string{"darwin_arm64"}, defs.Providers[1].Platforms)
+	assert.Equal(t, 3, defs.CountItems())
 }
 
 func TestParseHCL_EmptyPlatforms(t *testing.T) {
@@ -339,3 +367,77 @@ provider "terraform-aws-modules/vpc" {
 	assert.Equal(t, "terraform-aws-modules", vpc.Namespace)
 	assert.Equal(t, "vpc", vpc.Type)
 }
+
+func TestParseHCL_Problems(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name: "unknown attribute",
+			content: `
+provider "hashicorp/aws" {
+  versions = ["5.0.0"]
+  platform = "linux_amd64"
+}`,
+			want: `providers.hcl:4,3: Unsupported argument`,
+		},
+		{
+			name: "missing versions",
+			content: `
+provider "hashicorp/aws" {
+  platforms = ["linux_amd64"]
+}`,
+			want: `providers.hcl:2,1: provider "hashicorp/aws": the versions attribute is required`,
+		},
+		{
+			name: "empty versions list",
+			content: `
+provider "hashicorp/aws" {
+  versions = []
+}`,
+			want: `providers.hcl:3,3: provider "hashicorp/aws": at least one version is required`,
+		},
+		{
+			name: "malformed source",
+			content: `
+provider "hash/corp/aws" {
+  versions = ["5.0.0"]
+}`,
+			want: `providers.hcl:2,10: provider "hash/corp/aws": invalid source format, expected 'namespace/type'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseHCL([]byte(tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestParseHCL_ReportsAllProblems(t *testing.T) {
+	hcl := []byte(`
+provider "hashicorp/aws" {
+  versions  = ["latest"]
+  platforms = ["plan9_amd64"]
+}
+
+provider "hashicorp/azurerm" {
+  versions = ["3.0.0"]
+}
+`)
+
+	_, err := ParseHCL(hcl)
+	require.Error(t, err)
+
+	var defErr *definition.Error
+	require.True(t, errors.As(err, &defErr), "expected *definition.Error, got %T", err)
+	require.Len(t, defErr.Problems, 2)
+	assert.Equal(t, 3, defErr.Problems[0].Line)
+	assert.Contains(t, defErr.Problems[0].Message, "invalid version format")
+	assert.Equal(t, 4, defErr.Problems[1].Line)
+	assert.Contains(t, defErr.Problems[1].Message, "invalid platform format")
+}
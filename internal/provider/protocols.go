@@ -0,0 +1,23 @@
+package provider
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// EncodeProtocols serializes plugin protocol versions for the providers.protocols column
+func EncodeProtocols(protocols []string) sql.NullString {
+	if len(protocols) == 0 {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: strings.Join(protocols, ","), Valid: true}
+}
+
+// DecodeProtocols parses the providers.protocols column, returning nil when
+// the protocols were not captured
+func DecodeProtocols(stored sql.NullString) []string {
+	if !stored.Valid || stored.String == "" {
+		return nil
+	}
+	return strings.Split(stored.String, ",")
+}
@@ -4,8 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"path"
-	"strings"
+	"log"
 
 	"github.com/ned1313/terraform-mirror/internal/database"
 	"github.com/ned1313/terraform-mirror/internal/storage"
@@ -13,9 +12,11 @@ import (
 
 // Service orchestrates provider operations (parse, download, upload, store)
 type Service struct {
-	registry *RegistryClient
-	storage  storage.Storage
-	db       *database.DB
+	registry  RegistryDownloader
+	storage   storage.Storage
+	db        *database.DB
+	upstreams *UpstreamRouter
+	immutable bool
 }
 
 // NewService creates a new provider service
@@ -27,6 +28,24 @@ func NewService(storage storage.Storage, db *database.DB) *Service {
 	}
 }
 
+// SetRegistry sets the registry providers are downloaded from
+func (s *Service) SetRegistry(registry RegistryDownloader) {
+	s.registry = registry
+}
+
+// SetUpstreams routes downloads to each namespace's upstream registry and
+// stores providers under that registry's hostname
+func (s *Service) SetUpstreams(upstreams *UpstreamRouter) {
+	s.registry = upstreams
+	s.upstreams = upstreams
+}
+
+// SetImmutableArtifacts makes loads refuse to replace stored artifacts with
+// different content
+func (s *Service) SetImmutableArtifacts(immutable bool) {
+	s.immutable = immutable
+}
+
 // LoadResult represents the result of loading a single provider
 type LoadResult struct {
 	Namespace string
@@ -72,19 +91,18 @@ func (s *Service) LoadFromDefinitionsWithProgress(ctx context.Context, defs *Pro
 				}
 
 				// Split platform into OS and arch
-				parts := strings.SplitN(platform, "_", 2)
-				if len(parts) != 2 {
+				os, arch, err := ParsePlatform(platform)
+				if err != nil {
 					addResult(&LoadResult{
 						Namespace: def.Namespace,
 						Type:      def.Type,
 						Version:   version,
 						Platform:  platform,
 						Success:   false,
-						Error:     fmt.Errorf("invalid platform format: %s", platform),
+						Error:     err,
 					})
 					continue
 				}
-				os, arch := parts[0], parts[1]
 
 				// Check if already exists
 				existing, err := providerRepo.GetByIdentity(ctx, def.Namespace, def.Type, version, platform)
@@ -128,11 +146,42 @@ func (s *Service) LoadFromDefinitionsWithProgress(ctx context.Context, defs *Pro
 				}
 
 				// Build S3 key
-				s3Key := s.buildS3Key(def.Namespace, def.Type, version, platform, downloadResult.Info.Filename)
+				s3Key := s.buildS3Key(def.Namespace, def.Type, version, os, arch, downloadResult.Info.Filename)
+
+				// Never replace a mirrored artifact with different bytes
+				if s.immutable {
+					existing, err := CheckImmutable(ctx, providerRepo, s.storage, s3Key,
+						def.Namespace, def.Type, version, platform, downloadResult.Info.Shasum)
+					if err != nil {
+						log.Printf("Refusing to replace %s: %v", s3Key, err)
+						addResult(&LoadResult{
+							Namespace: def.Namespace,
+							Type:      def.Type,
+							Version:   version,
+							Platform:  platform,
+							Success:   false,
+							Error:     err,
+						})
+						continue
+					}
+					if existing != nil {
+						addResult(&LoadResult{
+							Namespace: def.Namespace,
+							Type:      def.Type,
+							Version:   version,
+							Platform:  platform,
+							Success:   true,
+							Skipped:   true,
+						})
+						continue
+					}
+				}
 
 				// Upload to S3
 				reader := bytes.NewReader(downloadResult.Data)
-				if err := s.storage.Upload(ctx, s3Key, reader, "application/zip", nil); err != nil {
+				metadata := storage.ProviderMetadata(def.Namespace, def.Type, version, platform,
+					downloadResult.Info.Filename, downloadResult.Info.Shasum, downloadResult.Info.DownloadURL, true)
+				if err := s.storage.Upload(ctx, s3Key, reader, "application/zip", metadata); err != nil {
 					addResult(&LoadResult{
 						Namespace: def.Namespace,
 						Type:      def.Type,
@@ -146,14 +195,19 @@ func (s *Service) LoadFromDefinitionsWithProgress(ctx context.Context, defs *Pro
 
 				// Save to database
 				provider := &database.Provider{
-					Namespace: def.Namespace,
-					Type:      def.Type,
-					Version:   version,
-					Platform:  platform,
-					Filename:  downloadResult.Info.Filename,
-					Shasum:    downloadResult.Info.Shasum,
-					S3Key:     s3Key,
-					SizeBytes: int64(len(downloadResult.Data)),
+					Namespace:   def.Namespace,
+					Type:        def.Type,
+					Version:     version,
+					Platform:    platform,
+					Filename:    downloadResult.Info.Filename,
+					DownloadURL: downloadResult.Info.DownloadURL,
+					Shasum:      downloadResult.Info.Shasum,
+					SigningKeys: EncodeSigningKeys(downloadResult.Info.SigningKeys),
+					Protocols:   EncodeProtocols(downloadResult.Info.Protocols),
+					H1Hash:      EncodePackageHash(downloadResult.Data),
+					S3Key:       s3Key,
+					SizeBytes:   int64(len(downloadResult.Data)),
+					Verified:    downloadResult.Verified,
 				}
 
 				if err := providerRepo.Create(ctx, provider); err != nil {
@@ -183,13 +237,22 @@ func (s *Service) LoadFromDefinitionsWithProgress(ctx context.Context, defs *Pro
 		}
 	}
 
+	// New platforms make stored SHA256SUMS documents of their versions stale
+	if _, err := PruneStaleShasums(ctx, providerRepo, s.storage); err != nil {
+		log.Printf("Failed to remove stale SHA256SUMS documents: %v", err)
+	}
+
 	return results, nil
 }
 
-// buildS3Key constructs the S3 storage key for a provider
-// Format: providers/{namespace}/{type}/{version}/{platform}/{filename}
-func (s *Service) buildS3Key(namespace, providerType, version, platform, filename string) string {
-	return path.Join("providers", namespace, providerType, version, platform, filename)
+// buildS3Key constructs the S3 storage key for a provider under the hostname
+// of the registry it is mirrored from, as auto-download and jobs store it
+func (s *Service) buildS3Key(namespace, providerType, version, os, arch, filename string) string {
+	hostname := DefaultRegistryHost
+	if s.upstreams != nil {
+		hostname = s.upstreams.Hostname(namespace)
+	}
+	return storage.BuildProviderKey(hostname, namespace, providerType, version, os, arch, filename)
 }
 
 // LoadStats returns statistics about the load operation
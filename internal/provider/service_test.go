@@ -13,6 +13,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ned1313/terraform-mirror/internal/config"
 	"github.com/ned1313/terraform-mirror/internal/database"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -64,6 +65,10 @@ func (m *mockStorage) ListObjects(ctx context.Context, prefix string) ([]string,
 	return nil, nil
 }
 
+func (m *mockStorage) WalkObjects(ctx context.Context, prefix string, fn func(key string) error) error {
+	return nil
+}
+
 func (m *mockStorage) GetObjectSize(ctx context.Context, key string) (int64, error) {
 	if data, ok := m.data[key]; ok {
 		return int64(len(data)), nil
@@ -71,6 +76,11 @@ func (m *mockStorage) GetObjectSize(ctx context.Context, key string) (int64, err
 	return 0, nil
 }
 
+func (m *mockStorage) Copy(ctx context.Context, srcKey, dstKey string) error {
+	m.data[dstKey] = m.data[srcKey]
+	return nil
+}
+
 func (m *mockStorage) Close() error {
 	return nil
 }
@@ -172,7 +182,8 @@ func TestLoadFromDefinitions_Success(t *testing.T) {
 	assert.Equal(t, "5.0.0", provider.Version)
 	assert.Equal(t, "linux_amd64", provider.Platform)
 	assert.Equal(t, shasum, provider.Shasum)
-	assert.Contains(t, provider.S3Key, "providers/hashicorp/aws/5.0.0/linux_amd64")
+	assert.Equal(t, server.URL+"/download", provider.DownloadURL, "upstream URL is recorded for provenance")
+	assert.Contains(t, provider.S3Key, "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64")
 
 	// Verify storage
 	mockStore := svc.storage.(*mockStorage)
@@ -341,8 +352,13 @@ func TestBuildS3Key(t *testing.T) {
 	svc, _, cleanup := setupServiceTest(t)
 	defer cleanup()
 
-	key := svc.buildS3Key("hashicorp", "aws", "5.0.0", "linux_amd64", "terraform-provider-aws_5.0.0_linux_amd64.zip")
-	assert.Equal(t, "providers/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip", key)
+	key := svc.buildS3Key("hashicorp", "aws", "5.0.0", "linux", "amd64", "terraform-provider-aws_5.0.0_linux_amd64.zip")
+	assert.Equal(t, "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip", key)
+
+	// Providers from a private upstream are stored under its hostname
+	svc.SetUpstreams(NewUpstreamRouter([]config.UpstreamRegistryConfig{{Hostname: "registry.example.com", Namespaces: []string{"acme"}}}))
+	key = svc.buildS3Key("acme", "widget", "1.0.0", "linux", "amd64", "terraform-provider-widget_1.0.0_linux_amd64.zip")
+	assert.Equal(t, "providers/registry.example.com/acme/widget/1.0.0/linux_amd64/terraform-provider-widget_1.0.0_linux_amd64.zip", key)
 }
 
 func TestCalculateStats(t *testing.T) {
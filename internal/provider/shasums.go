@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+)
+
+// BuildShasums renders the SHA256SUMS document for the platforms of one
+// provider version in the format of upstream releases. Platforms without a
+// recorded shasum are left out.
+func BuildShasums(providers []*database.Provider) []byte {
+	sorted := make([]*database.Provider, 0, len(providers))
+	for _, p := range providers {
+		if p.Shasum != "" {
+			sorted = append(sorted, p)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Filename < sorted[j].Filename
+	})
+
+	var b bytes.Buffer
+	for _, p := range sorted {
+		fmt.Fprintf(&b, "%s  %s\n", p.Shasum, p.Filename)
+	}
+	return b.Bytes()
+}
+
+// StoreShasums writes the SHA256SUMS document of a provider version to
+// storage and records its key, so the registry protocol can serve it without
+// rebuilding it. It returns the key.
+func StoreShasums(ctx context.Context, repo *database.ProviderRepository, store storage.Storage, hostname, namespace, providerType, version string) (string, error) {
+	providers, err := repo.ListVersions(ctx, namespace, providerType)
+	if err != nil {
+		return "", fmt.Errorf("failed to list platforms: %w", err)
+	}
+	var versionProviders []*database.Provider
+	for _, p := range providers {
+		if p.Version == version {
+			versionProviders = append(versionProviders, p)
+		}
+	}
+
+	document := BuildShasums(versionProviders)
+	if len(document) == 0 {
+		return "", fmt.Errorf("no platforms of %s/%s %s have a shasum", namespace, providerType, version)
+	}
+
+	key := storage.BuildProviderShasumsKey(hostname, namespace, providerType, version)
+	if err := store.Upload(ctx, key, bytes.NewReader(document), "text/plain; charset=utf-8", nil); err != nil {
+		return "", fmt.Errorf("failed to upload SHA256SUMS: %w", err)
+	}
+	if err := repo.SetShasumsKey(ctx, namespace, providerType, version, key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// PruneStaleShasums removes SHA256SUMS documents whose version's platforms
+// changed after they were stored, along with their records. Records go
+// first, so a document is never served after its object is removed. It
+// returns the number of documents removed.
+func PruneStaleShasums(ctx context.Context, repo *database.ProviderRepository, store storage.Storage) (int, error) {
+	keys, err := repo.ListStaleShasumsKeys(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, key := range keys {
+		dropped, err := repo.DeleteStaleShasumsKey(ctx, key)
+		if err != nil {
+			return removed, err
+		}
+		if !dropped {
+			// Stored again since it went stale
+			continue
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			return removed, fmt.Errorf("failed to delete %s: %w", key, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
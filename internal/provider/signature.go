@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// VerifyShasumsSignature checks that signature is a detached GPG signature
+// of a SHA256SUMS document made by one of the release's signing keys
+func VerifyShasumsSignature(shasums, signature []byte, keys []GPGPublicKey) error {
+	if len(keys) == 0 {
+		return errors.New("no signing keys were published")
+	}
+
+	var keyring openpgp.EntityList
+	for _, key := range keys {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key.ASCIIArmor))
+		if err != nil {
+			return fmt.Errorf("failed to read signing key %s: %w", key.KeyID, err)
+		}
+		keyring = append(keyring, entities...)
+	}
+
+	if _, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(shasums), bytes.NewReader(signature), nil); err != nil {
+		return fmt.Errorf("SHA256SUMS signature is not valid: %w", err)
+	}
+	return nil
+}
+
+// ShasumsListed reports whether a SHA256SUMS document lists an archive with
+// the given shasum. Filenames are not compared, since a non-standard
+// upstream filename is corrected before the archive is stored.
+func ShasumsListed(shasums []byte, shasum string) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(shasums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.EqualFold(fields[0], shasum) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSigningKey generates a signing key and returns it with its armored
+// public key as a registry publishes it
+func newSigningKey(t *testing.T) (*openpgp.Entity, GPGPublicKey) {
+	t.Helper()
+	entity, err := openpgp.NewEntity("Test Releases", "", "releases@example.com",
+		&packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+
+	return entity, GPGPublicKey{KeyID: entity.PrimaryKey.KeyIdString(), ASCIIArmor: buf.String()}
+}
+
+// sign returns a detached binary signature of document
+func sign(t *testing.T, signer *openpgp.Entity, document []byte) []byte {
+	t.Helper()
+	var sig bytes.Buffer
+	require.NoError(t, openpgp.DetachSign(&sig, signer, bytes.NewReader(document), nil))
+	return sig.Bytes()
+}
+
+func TestVerifyShasumsSignature(t *testing.T) {
+	signer, key := newSigningKey(t)
+	_, otherKey := newSigningKey(t)
+	shasums := []byte("abc123  terraform-provider-aws_5.0.0_linux_amd64.zip\n")
+	signature := sign(t, signer, shasums)
+
+	assert.NoError(t, VerifyShasumsSignature(shasums, signature, []GPGPublicKey{key}))
+	assert.NoError(t, VerifyShasumsSignature(shasums, signature, []GPGPublicKey{otherKey, key}), "any published key may sign")
+
+	tampered := []byte("def456  terraform-provider-aws_5.0.0_linux_amd64.zip\n")
+	assert.Error(t, VerifyShasumsSignature(tampered, signature, []GPGPublicKey{key}))
+	assert.Error(t, VerifyShasumsSignature(shasums, signature, []GPGPublicKey{otherKey}))
+	assert.Error(t, VerifyShasumsSignature(shasums, signature, nil))
+	assert.Error(t, VerifyShasumsSignature(shasums, signature, []GPGPublicKey{{KeyID: "BAD", ASCIIArmor: "not a key"}}))
+}
+
+func TestShasumsListed(t *testing.T) {
+	shasums := []byte("abc123  terraform-provider-aws_5.0.0_linux_amd64.zip\n" +
+		"def456  terraform-provider-aws_5.0.0_darwin_arm64.zip\n")
+
+	assert.True(t, ShasumsListed(shasums, "abc123"))
+	assert.True(t, ShasumsListed(shasums, "DEF456"))
+	assert.False(t, ShasumsListed(shasums, "0000"))
+	assert.False(t, ShasumsListed(nil, "abc123"))
+}
+
+func TestDownloadProviderComplete_VerifiesSignature(t *testing.T) {
+	providerZip := []byte("fake-provider-binary-content")
+	hash := sha256.Sum256(providerZip)
+	shasum := hex.EncodeToString(hash[:])
+	shasums := []byte(shasum + "  terraform-provider-aws_5.0.0_linux_amd64.zip\n")
+
+	signer, key := newSigningKey(t)
+	_, otherKey := newSigningKey(t)
+	signature := sign(t, signer, shasums)
+
+	tests := []struct {
+		name     string
+		keys     []GPGPublicKey
+		unsigned bool
+		enabled  bool
+		want     bool
+		wantErr  bool
+	}{
+		{name: "signed by a release key", keys: []GPGPublicKey{key}, enabled: true, want: true},
+		{name: "signed by an unknown key", keys: []GPGPublicKey{otherKey}, enabled: true, wantErr: true},
+		{name: "no signing keys", keys: nil, enabled: true, want: false},
+		{name: "no signature published", keys: []GPGPublicKey{key}, unsigned: true, enabled: true, want: false},
+		{name: "verification disabled", keys: []GPGPublicKey{otherKey}, enabled: false, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64":
+					resp := registryDownloadResponse{
+						OS:                  "linux",
+						Arch:                "amd64",
+						Filename:            "terraform-provider-aws_5.0.0_linux_amd64.zip",
+						DownloadURL:         "http://" + r.Host + "/download",
+						Shasum:              shasum,
+						ShasumsURL:          "http://" + r.Host + "/SHA256SUMS",
+						ShasumsSignatureURL: "http://" + r.Host + "/SHA256SUMS.sig",
+					}
+					if tt.unsigned {
+						resp.ShasumsURL, resp.ShasumsSignatureURL = "", ""
+					}
+					resp.SigningKeys.GPGPublicKeys = tt.keys
+					json.NewEncoder(w).Encode(resp)
+				case "/download":
+					w.Write(providerZip)
+				case "/SHA256SUMS":
+					w.Write(shasums)
+				case "/SHA256SUMS.sig":
+					w.Write(signature)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer registryServer.Close()
+
+			client := &RegistryClient{
+				httpClient: &http.Client{Timeout: 5 * time.Second},
+				baseURL:    registryServer.URL + "/v1/providers",
+			}
+			client.SetSignatureVerification(tt.enabled)
+
+			result := client.DownloadProviderComplete(context.Background(), "hashicorp", "aws", "5.0.0", "linux", "amd64")
+			require.NotNil(t, result)
+			if tt.wantErr {
+				assert.ErrorContains(t, result.Error, "signature verification failed")
+				assert.Nil(t, result.Data, "an archive with a bad signature is not returned")
+				return
+			}
+			require.NoError(t, result.Error, "an unverified archive is still downloaded")
+			assert.Equal(t, providerZip, result.Data)
+			assert.Equal(t, tt.want, result.Verified)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+// GPGPublicKey is an ASCII-armored public key that signed a provider release
+type GPGPublicKey struct {
+	KeyID      string `json:"key_id"`
+	ASCIIArmor string `json:"ascii_armor"`
+}
+
+// EncodeSigningKeys serializes signing keys for the providers.signing_keys column
+func EncodeSigningKeys(keys []GPGPublicKey) sql.NullString {
+	if len(keys) == 0 {
+		return sql.NullString{}
+	}
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(data), Valid: true}
+}
+
+// DecodeSigningKeys parses the providers.signing_keys column, returning nil
+// when no keys were captured or the stored value cannot be parsed
+func DecodeSigningKeys(stored sql.NullString) []GPGPublicKey {
+	if !stored.Valid || stored.String == "" {
+		return nil
+	}
+	var keys []GPGPublicKey
+	if err := json.Unmarshal([]byte(stored.String), &keys); err != nil {
+		return nil
+	}
+	return keys
+}
@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ned1313/terraform-mirror/internal/config"
+)
+
+// upstream is a provider registry together with the namespaces it serves
+type upstream struct {
+	config config.UpstreamRegistryConfig
+	client *RegistryClient
+}
+
+// UpstreamRouter sends each provider to the upstream registry serving its
+// namespace, falling back to the public registry. It can stand in for a
+// single RegistryClient wherever one is used.
+type UpstreamRouter struct {
+	upstreams []upstream
+	public    *RegistryClient
+}
+
+// NewUpstreamRouter creates a router over the configured upstream registries.
+// Upstreams are matched in order, so list specific namespaces before "*".
+func NewUpstreamRouter(upstreams []config.UpstreamRegistryConfig) *UpstreamRouter {
+	r := &UpstreamRouter{public: NewRegistryClient()}
+	for _, cfg := range upstreams {
+		client := NewUpstreamRegistryClient(cfg.GetURL(), cfg.Token)
+		if cfg.Username != "" {
+			client.SetBasicAuth(cfg.Username, cfg.Password)
+		}
+		r.upstreams = append(r.upstreams, upstream{config: cfg, client: client})
+	}
+	return r
+}
+
+// SetMaxDownloadSize caps the size of archives downloaded from every
+// upstream; zero or less means unlimited
+func (r *UpstreamRouter) SetMaxDownloadSize(limit int64) {
+	r.public.SetMaxDownloadSize(limit)
+	for i := range r.upstreams {
+		r.upstreams[i].client.SetMaxDownloadSize(limit)
+	}
+}
+
+// SetSignatureVerification sets whether downloads from every upstream are
+// checked against the release's GPG-signed SHA256SUMS
+func (r *UpstreamRouter) SetSignatureVerification(enabled bool) {
+	r.public.SetSignatureVerification(enabled)
+	for i := range r.upstreams {
+		r.upstreams[i].client.SetSignatureVerification(enabled)
+	}
+}
+
+// route returns the hostname and client of the registry serving namespace
+func (r *UpstreamRouter) route(namespace string) (string, *RegistryClient) {
+	for i := range r.upstreams {
+		if r.upstreams[i].config.MatchesNamespace(namespace) {
+			return r.upstreams[i].config.Hostname, r.upstreams[i].client
+		}
+	}
+	return DefaultRegistryHost, r.public
+}
+
+// Hostname returns the hostname of the registry serving namespace, which
+// providers from it are stored under
+func (r *UpstreamRouter) Hostname(namespace string) string {
+	hostname, _ := r.route(namespace)
+	return hostname
+}
+
+// DownloadProviderComplete downloads a provider from its upstream registry
+func (r *UpstreamRouter) DownloadProviderComplete(ctx context.Context, namespace, providerType, version, os, arch string) *DownloadResult {
+	_, client := r.route(namespace)
+	return client.DownloadProviderComplete(ctx, namespace, providerType, version, os, arch)
+}
+
+// GetAvailableVersions lists a provider's versions on its upstream registry
+func (r *UpstreamRouter) GetAvailableVersions(ctx context.Context, namespace, providerType string) ([]string, error) {
+	_, client := r.route(namespace)
+	return client.GetAvailableVersions(ctx, namespace, providerType)
+}
+
+// GetVersionPlatforms lists a provider's platforms on its upstream registry
+func (r *UpstreamRouter) GetVersionPlatforms(ctx context.Context, namespace, providerType string) (map[string][]string, error) {
+	_, client := r.route(namespace)
+	return client.GetVersionPlatforms(ctx, namespace, providerType)
+}
+
+// GetDownloadInfo retrieves download metadata from a provider's upstream registry
+func (r *UpstreamRouter) GetDownloadInfo(ctx context.Context, namespace, providerType, version, os, arch string) (*ProviderDownloadInfo, error) {
+	_, client := r.route(namespace)
+	return client.GetDownloadInfo(ctx, namespace, providerType, version, os, arch)
+}
+
+// Ping checks that the public registry and every upstream can be reached,
+// returning the result for each by hostname. Registries are pinged at once
+// so that one slow registry does not use up the others' time.
+func (r *UpstreamRouter) Ping(ctx context.Context) map[string]error {
+	clients := map[string]*RegistryClient{DefaultRegistryHost: r.public}
+	for i := range r.upstreams {
+		clients[r.upstreams[i].config.Hostname] = r.upstreams[i].client
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]error, len(clients))
+	for hostname, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := client.Ping(ctx)
+			mu.Lock()
+			results[hostname] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
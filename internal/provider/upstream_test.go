@@ -0,0 +1,212 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRegistry serves one provider version and records the authorization
+// header of each request by path
+type fakeRegistry struct {
+	*httptest.Server
+	auth map[string]string
+}
+
+func newFakeRegistry(t *testing.T, archive []byte, downloadURL string) *fakeRegistry {
+	sum := sha256.Sum256(archive)
+	f := &fakeRegistry{auth: make(map[string]string)}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.auth[r.URL.Path] = r.Header.Get("Authorization")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/versions"):
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"versions": []map[string]interface{}{{"version": "1.0.0"}},
+			})
+		case strings.Contains(r.URL.Path, "/download/"):
+			url := downloadURL
+			if url == "" {
+				url = f.URL + "/archive.zip"
+			}
+			json.NewEncoder(w).Encode(registryDownloadResponse{
+				OS:          "linux",
+				Arch:        "amd64",
+				Filename:    "terraform-provider-widget_1.0.0_linux_amd64.zip",
+				DownloadURL: url,
+				Shasum:      hex.EncodeToString(sum[:]),
+			})
+		default:
+			w.Write(archive)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestUpstreamRouter_RoutesByNamespace(t *testing.T) {
+	private := newFakeRegistry(t, []byte("private-archive"), "")
+	public := newFakeRegistry(t, []byte("public-archive"), "")
+
+	router := NewUpstreamRouter([]config.UpstreamRegistryConfig{
+		{Hostname: "registry.example.com", URL: private.URL + "/v1/providers", Namespaces: []string{"acme"}, Token: "secret"},
+		{Hostname: "mirror.example.org", URL: public.URL + "/v1/providers", Namespaces: []string{"*"}},
+	})
+
+	assert.Equal(t, "registry.example.com", router.Hostname("acme"))
+	assert.Equal(t, "registry.example.com", router.Hostname("ACME"))
+	assert.Equal(t, "mirror.example.org", router.Hostname("hashicorp"))
+
+	result := router.DownloadProviderComplete(context.Background(), "acme", "widget", "1.0.0", "linux", "amd64")
+	require.NoError(t, result.Error)
+	assert.Equal(t, []byte("private-archive"), result.Data)
+	assert.Equal(t, "Bearer secret", private.auth["/v1/providers/acme/widget/1.0.0/download/linux/amd64"])
+	assert.Equal(t, "Bearer secret", private.auth["/archive.zip"])
+
+	result = router.DownloadProviderComplete(context.Background(), "hashicorp", "widget", "1.0.0", "linux", "amd64")
+	require.NoError(t, result.Error)
+	assert.Equal(t, []byte("public-archive"), result.Data)
+	assert.Empty(t, public.auth["/v1/providers/hashicorp/widget/1.0.0/download/linux/amd64"])
+
+	versions, err := router.GetAvailableVersions(context.Background(), "acme", "widget")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0"}, versions)
+	assert.Contains(t, private.auth, "/v1/providers/acme/widget/versions")
+	assert.NotContains(t, public.auth, "/v1/providers/acme/widget/versions")
+}
+
+func TestUpstreamRouter_FallsBackToPublicRegistry(t *testing.T) {
+	router := NewUpstreamRouter([]config.UpstreamRegistryConfig{
+		{Hostname: "registry.example.com", Namespaces: []string{"acme"}},
+	})
+
+	assert.Equal(t, DefaultRegistryHost, router.Hostname("hashicorp"))
+
+	_, client := router.route("hashicorp")
+	assert.Equal(t, TerraformRegistryBaseURL, client.baseURL)
+	_, client = router.route("acme")
+	assert.Equal(t, "https://registry.example.com/v1/providers", client.baseURL)
+}
+
+func TestRegistryClient_TokenStaysOnRegistryHost(t *testing.T) {
+	archive := []byte("archive")
+	var cdnAuth string
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cdnAuth = r.Header.Get("Authorization")
+		w.Write(archive)
+	}))
+	defer cdn.Close()
+
+	registry := newFakeRegistry(t, archive, cdn.URL+"/archive.zip")
+	client := NewUpstreamRegistryClient(registry.URL+"/v1/providers/", "secret")
+
+	result := client.DownloadProviderComplete(context.Background(), "acme", "widget", "1.0.0", "linux", "amd64")
+	require.NoError(t, result.Error)
+	assert.Equal(t, "Bearer secret", registry.auth["/v1/providers/acme/widget/1.0.0/download/linux/amd64"])
+	assert.Empty(t, cdnAuth, "the token must not be sent to other hosts")
+}
+
+func TestRegistryClient_Ping(t *testing.T) {
+	var auth string
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer registry.Close()
+
+	// Any answer below 500 means the registry is reachable
+	require.NoError(t, NewUpstreamRegistryClient(registry.URL+"/v1/providers", "secret").Ping(context.Background()))
+	assert.Equal(t, "Bearer secret", auth)
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer broken.Close()
+	err := NewUpstreamRegistryClient(broken.URL+"/v1/providers", "").Ping(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "502")
+
+	broken.Close()
+	assert.Error(t, NewUpstreamRegistryClient(broken.URL+"/v1/providers", "").Ping(context.Background()))
+}
+
+// authRegistry serves a provider only to requests carrying want as their
+// Authorization header and answers 401 otherwise
+func authRegistry(t *testing.T, want string) *httptest.Server {
+	archive := []byte("private-archive")
+	sum := sha256.Sum256(archive)
+	var registry *httptest.Server
+	registry = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/versions"):
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"versions": []map[string]interface{}{{"version": "1.0.0"}},
+			})
+		case strings.Contains(r.URL.Path, "/download/"):
+			json.NewEncoder(w).Encode(registryDownloadResponse{
+				OS:          "linux",
+				Arch:        "amd64",
+				Filename:    "terraform-provider-widget_1.0.0_linux_amd64.zip",
+				DownloadURL: registry.URL + "/archive.zip",
+				Shasum:      hex.EncodeToString(sum[:]),
+			})
+		default:
+			w.Write(archive)
+		}
+	}))
+	t.Cleanup(registry.Close)
+	return registry
+}
+
+func TestRegistryClient_BearerToken(t *testing.T) {
+	registry := authRegistry(t, "Bearer secret")
+	ctx := context.Background()
+
+	client := NewUpstreamRegistryClient(registry.URL+"/v1/providers", "secret")
+	versions, err := client.GetAvailableVersions(ctx, "acme", "widget")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0"}, versions)
+
+	result := client.DownloadProviderComplete(ctx, "acme", "widget", "1.0.0", "linux", "amd64")
+	require.NoError(t, result.Error)
+	assert.Equal(t, []byte("private-archive"), result.Data)
+
+	// Requests without the token are refused
+	anonymous := NewUpstreamRegistryClient(registry.URL+"/v1/providers", "")
+	_, err = anonymous.GetAvailableVersions(ctx, "acme", "widget")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+	_, err = NewUpstreamRegistryClient(registry.URL+"/v1/providers", "wrong").GetDownloadInfo(ctx, "acme", "widget", "1.0.0", "linux", "amd64")
+	assert.Error(t, err)
+}
+
+func TestUpstreamRouter_BasicAuth(t *testing.T) {
+	registry := authRegistry(t, "Basic Y2k6cGFzc3dvcmQ=") // ci:password
+	ctx := context.Background()
+
+	router := NewUpstreamRouter([]config.UpstreamRegistryConfig{
+		{Hostname: "registry.example.com", URL: registry.URL + "/v1/providers", Namespaces: []string{"acme"}, Username: "ci", Password: "password"},
+	})
+	result := router.DownloadProviderComplete(ctx, "acme", "widget", "1.0.0", "linux", "amd64")
+	require.NoError(t, result.Error)
+	assert.Equal(t, []byte("private-archive"), result.Data)
+
+	unauthenticated := NewUpstreamRouter([]config.UpstreamRegistryConfig{
+		{Hostname: "registry.example.com", URL: registry.URL + "/v1/providers", Namespaces: []string{"acme"}},
+	})
+	_, err := unauthenticated.GetAvailableVersions(ctx, "acme", "widget")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
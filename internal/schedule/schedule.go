@@ -0,0 +1,197 @@
+// Package schedule parses cron-style schedules for background tasks
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed five-field cron expression:
+//
+//	minute hour day-of-month month day-of-week
+//
+// Each field accepts "*", numbers, ranges ("1-5"), lists ("1,15"), and steps
+// ("*/15", "0-30/10"). The descriptors @hourly, @daily, @weekly, and @monthly
+// are also accepted. Times are evaluated in the location of the time passed to Next.
+type Schedule struct {
+	expr   string
+	minute [60]bool
+	hour   [24]bool
+	dom    [32]bool
+	month  [13]bool
+	dow    [7]bool
+
+	// domAny and dowAny record whether the day fields were "*"; as in cron,
+	// when both are restricted a day matches if either field matches
+	domAny bool
+	dowAny bool
+}
+
+var descriptors = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// Parse parses a cron expression
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	spec := expr
+	if d, ok := descriptors[spec]; ok {
+		spec = d
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields (minute hour day-of-month month day-of-week)", expr)
+	}
+
+	s := &Schedule{
+		expr:   expr,
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+
+	if err := parseField(fields[0], 0, 59, s.minute[:]); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: minute: %w", expr, err)
+	}
+	if err := parseField(fields[1], 0, 23, s.hour[:]); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: hour: %w", expr, err)
+	}
+	if err := parseField(fields[2], 1, 31, s.dom[:]); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day-of-month: %w", expr, err)
+	}
+	if err := parseField(fields[3], 1, 12, s.month[:]); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: month: %w", expr, err)
+	}
+
+	// Day-of-week accepts 7 as an alias for Sunday
+	var dow [8]bool
+	if err := parseField(fields[4], 0, 7, dow[:]); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day-of-week: %w", expr, err)
+	}
+	copy(s.dow[:], dow[:7])
+	if dow[7] {
+		s.dow[0] = true
+	}
+
+	return s, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// Next returns the first matching time strictly after t, truncated to the minute.
+// A zero time is returned if nothing matches within five years (e.g., "0 0 30 2 *").
+func (s *Schedule) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for next.Before(limit) {
+		if !s.month[next.Month()] {
+			// Jump to the first day of the next month
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.dayMatches(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.hour[next.Hour()] {
+			next = next.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if !s.minute[next.Minute()] {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next
+	}
+
+	return time.Time{}
+}
+
+// dayMatches applies cron's day-of-month/day-of-week rules
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom[t.Day()]
+	dowMatch := s.dow[t.Weekday()]
+
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowMatch
+	case s.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+// parseField sets the allowed values of a single cron field
+func parseField(field string, min, max int, allowed []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		if part == "" {
+			return fmt.Errorf("empty list entry in %q", field)
+		}
+
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = parseValue(bounds[0], min, max); err != nil {
+				return err
+			}
+			if hi, err = parseValue(bounds[1], min, max); err != nil {
+				return err
+			}
+			if lo > hi {
+				return fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			v, err := parseValue(rangePart, min, max)
+			if err != nil {
+				return err
+			}
+			lo = v
+			if step == 1 {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			allowed[v] = true
+		}
+	}
+	return nil
+}
+
+// parseValue parses a single field value within bounds
+func parseValue(s string, min, max int) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, min, max)
+	}
+	return v, nil
+}
@@ -0,0 +1,65 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+	} {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	base := time.Date(2025, 3, 14, 10, 17, 42, 0, time.UTC) // Friday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 3, 14, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 3, 14, 10, 30, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2025, 3, 15, 3, 0, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2025, 3, 14, 10, 30, 0, 0, time.UTC)},
+		{"0 0 * * 1", time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1-5 6 *", time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 9,17 * * 1-5", time.Date(2025, 3, 14, 17, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2025, 3, 21, 0, 0, 0, 0, time.UTC)}, // either day field may match
+		{"@hourly", time.Date(2025, 3, 14, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, s.Next(base))
+		})
+	}
+}
+
+func TestSchedule_NextNeverMatches(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
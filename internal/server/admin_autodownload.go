@@ -0,0 +1,170 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/config"
+)
+
+// AutoDownloadNamespacesResponse is the current allow/block list for provider auto-download
+type AutoDownloadNamespacesResponse struct {
+	AllowedNamespaces []string `json:"allowed_namespaces"`
+	BlockedNamespaces []string `json:"blocked_namespaces"`
+}
+
+// UpdateAutoDownloadNamespacesRequest updates the allow/block lists. Omitted
+// fields are left unchanged; an empty allowed list allows every namespace.
+type UpdateAutoDownloadNamespacesRequest struct {
+	AllowedNamespaces *[]string `json:"allowed_namespaces"`
+	BlockedNamespaces *[]string `json:"blocked_namespaces"`
+}
+
+// handleGetAutoDownloadNamespaces returns the live auto-download namespace lists
+// GET /admin/api/autodownload/namespaces
+func (s *Server) handleGetAutoDownloadNamespaces(w http.ResponseWriter, r *http.Request) {
+	if s.autoDownloadService == nil {
+		respondError(w, http.StatusNotFound, "auto_download_disabled", "Provider auto-download is not enabled")
+		return
+	}
+
+	allowed, blocked := s.autoDownloadService.Namespaces()
+	respondJSON(w, http.StatusOK, AutoDownloadNamespacesResponse{
+		AllowedNamespaces: allowed,
+		BlockedNamespaces: blocked,
+	})
+}
+
+// handleUpdateAutoDownloadNamespaces replaces the auto-download namespace lists in memory
+// PUT /admin/api/autodownload/namespaces
+func (s *Server) handleUpdateAutoDownloadNamespaces(w http.ResponseWriter, r *http.Request) {
+	if s.autoDownloadService == nil {
+		respondError(w, http.StatusNotFound, "auto_download_disabled", "Provider auto-download is not enabled")
+		return
+	}
+
+	var req UpdateAutoDownloadNamespacesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+
+	allowed, blocked := s.autoDownloadService.Namespaces()
+	if req.AllowedNamespaces != nil {
+		list, err := normalizeNamespaces(*req.AllowedNamespaces)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid_namespace", fmt.Sprintf("allowed_namespaces: %v", err))
+			return
+		}
+		allowed = list
+	}
+	if req.BlockedNamespaces != nil {
+		list, err := normalizeNamespaces(*req.BlockedNamespaces)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid_namespace", fmt.Sprintf("blocked_namespaces: %v", err))
+			return
+		}
+		blocked = list
+	}
+
+	s.autoDownloadService.SetNamespaces(allowed, blocked)
+
+	s.logAuditEvent(r, "update_autodownload_namespaces", "config", "auto_download", true, "", map[string]interface{}{
+		"allowed_namespaces": allowed,
+		"blocked_namespaces": blocked,
+	})
+
+	respondJSON(w, http.StatusOK, AutoDownloadNamespacesResponse{
+		AllowedNamespaces: allowed,
+		BlockedNamespaces: blocked,
+	})
+}
+
+// InFlightDownloadResponse describes one in-flight auto-download
+type InFlightDownloadResponse struct {
+	Key        string    `json:"key"`
+	StartedAt  Timestamp `json:"started_at"`
+	AgeSeconds float64   `json:"age_seconds"`
+}
+
+// AutoDownloadInFlightResponse lists the in-flight auto-downloads
+type AutoDownloadInFlightResponse struct {
+	InFlight []InFlightDownloadResponse `json:"in_flight"`
+}
+
+// ClearAutoDownloadInFlightRequest names the entries to clear; none clears all
+type ClearAutoDownloadInFlightRequest struct {
+	Keys []string `json:"keys"`
+}
+
+// handleGetAutoDownloadInFlight lists the downloads that requests for the
+// same artifact are currently coalesced onto, oldest first
+// GET /admin/api/autodownload/inflight
+func (s *Server) handleGetAutoDownloadInFlight(w http.ResponseWriter, r *http.Request) {
+	if s.autoDownloadService == nil {
+		respondError(w, http.StatusNotFound, "auto_download_disabled", "Provider auto-download is not enabled")
+		return
+	}
+
+	now := time.Now()
+	downloads := s.autoDownloadService.InFlight()
+	response := AutoDownloadInFlightResponse{InFlight: make([]InFlightDownloadResponse, len(downloads))}
+	for i, d := range downloads {
+		response.InFlight[i] = InFlightDownloadResponse{
+			Key:        d.Key,
+			StartedAt:  Timestamp{d.Started},
+			AgeSeconds: now.Sub(d.Started).Seconds(),
+		}
+	}
+	respondJSON(w, http.StatusOK, response)
+}
+
+// handleClearAutoDownloadInFlight drops stuck in-flight entries so the next
+// request for the artifact downloads it afresh. Requests waiting on a dropped
+// entry fail instead of hanging.
+// POST /admin/api/autodownload/inflight/clear
+func (s *Server) handleClearAutoDownloadInFlight(w http.ResponseWriter, r *http.Request) {
+	if s.autoDownloadService == nil {
+		respondError(w, http.StatusNotFound, "auto_download_disabled", "Provider auto-download is not enabled")
+		return
+	}
+
+	// An empty body clears every entry
+	var req ClearAutoDownloadInFlightRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+
+	cleared := s.autoDownloadService.ClearInFlight(req.Keys...)
+
+	s.logAuditEvent(r, "clear_autodownload_inflight", "config", "auto_download", true, "", map[string]interface{}{
+		"keys":    req.Keys,
+		"cleared": cleared,
+	})
+
+	respondJSON(w, http.StatusOK, map[string]int{"cleared": cleared})
+}
+
+// normalizeNamespaces trims, validates, and de-duplicates a namespace list
+func normalizeNamespaces(namespaces []string) ([]string, error) {
+	result := make([]string, 0, len(namespaces))
+	seen := make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		ns = strings.TrimSpace(ns)
+		if !config.IsValidNamespace(ns) {
+			return nil, fmt.Errorf("invalid namespace %q", ns)
+		}
+		if seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		result = append(result, ns)
+	}
+	return result, nil
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/config"
+	"github.com/ned1313/terraform-mirror/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoDownloadNamespaces_Disabled(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	token := createTestToken(t, srv)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/autodownload/namespaces", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAutoDownloadNamespaces_Update(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	cfg := &config.AutoDownloadConfig{
+		Enabled:            true,
+		Platforms:          []string{"linux_amd64"},
+		RateLimitPerMinute: 600,
+		MaxConcurrentDL:    1,
+		QueueSize:          1,
+		TimeoutSeconds:     30,
+		BlockedNamespaces:  []string{},
+	}
+	srv.autoDownloadService = provider.NewAutoDownloadService(cfg, &srv.config.Providers, srv.storage, srv.db)
+
+	token := createTestToken(t, srv)
+
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin/api/autodownload/namespaces", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("invalid namespace is rejected", func(t *testing.T) {
+		w := do(http.MethodPut, `{"blocked_namespaces": ["bad namespace"]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_namespace")
+	})
+
+	t.Run("blocked namespace takes effect immediately", func(t *testing.T) {
+		w := do(http.MethodPut, `{"blocked_namespaces": [" evilcorp ", "evilcorp"]}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp AutoDownloadNamespacesResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, []string{"evilcorp"}, resp.BlockedNamespaces)
+		assert.Empty(t, resp.AllowedNamespaces)
+
+		_, err := srv.autoDownloadService.DownloadProvider(context.Background(), "evilcorp", "thing", "1.0.0", "linux", "amd64")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not allowed")
+
+		w = do(http.MethodGet, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, []string{"evilcorp"}, resp.BlockedNamespaces)
+	})
+
+	t.Run("change is audited", func(t *testing.T) {
+		// Audit entries are written asynchronously
+		assert.Eventually(t, func() bool {
+			logs, err := srv.auditRepo.ListByAction(context.Background(), "update_autodownload_namespaces", 10, 0)
+			return err == nil && len(logs) > 0
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+}
+
+// blockingRegistry holds every download until release is closed
+type blockingRegistry struct {
+	release chan struct{}
+}
+
+func (r *blockingRegistry) DownloadProviderComplete(ctx context.Context, namespace, providerType, version, os, arch string) *provider.DownloadResult {
+	select {
+	case <-r.release:
+	case <-ctx.Done():
+	}
+	return &provider.DownloadResult{Error: assert.AnError}
+}
+
+func (r *blockingRegistry) GetAvailableVersions(ctx context.Context, namespace, providerType string) ([]string, error) {
+	return nil, nil
+}
+
+func TestAutoDownloadInFlight_ListAndClear(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	cfg := &config.AutoDownloadConfig{
+		Enabled:            true,
+		Platforms:          []string{"linux_amd64"},
+		RateLimitPerMinute: 600,
+		MaxConcurrentDL:    1,
+		QueueSize:          1,
+		TimeoutSeconds:     30,
+	}
+	srv.autoDownloadService = provider.NewAutoDownloadService(cfg, &srv.config.Providers, srv.storage, srv.db)
+	registry := &blockingRegistry{release: make(chan struct{})}
+	srv.autoDownloadService.SetRegistry(registry)
+	defer close(registry.release)
+
+	token := createTestToken(t, srv)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+	list := func() AutoDownloadInFlightResponse {
+		w := do(http.MethodGet, "/admin/api/autodownload/inflight", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp AutoDownloadInFlightResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// A stuck download, and a second request coalesced onto it
+	go srv.autoDownloadService.DownloadProvider(context.Background(), "hashicorp", "random", "3.0.0", "linux", "amd64")
+	require.Eventually(t, func() bool { return len(list().InFlight) == 1 }, 2*time.Second, 10*time.Millisecond)
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := srv.autoDownloadService.DownloadProvider(context.Background(), "hashicorp", "random", "3.0.0", "linux", "amd64")
+		waiter <- err
+	}()
+	require.Eventually(t, func() bool {
+		return srv.autoDownloadService.GetStats().InFlightCoalesced == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	resp := list()
+	require.Len(t, resp.InFlight, 1)
+	assert.Equal(t, "hashicorp/random/3.0.0/linux_amd64", resp.InFlight[0].Key)
+	assert.GreaterOrEqual(t, resp.InFlight[0].AgeSeconds, 0.0)
+
+	// Clearing an unknown key leaves the entry alone
+	w := do(http.MethodPost, "/admin/api/autodownload/inflight/clear", `{"keys": ["hashicorp/null/1.0.0/linux_amd64"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"cleared": 0}`, w.Body.String())
+	assert.Len(t, list().InFlight, 1)
+
+	w = do(http.MethodPost, "/admin/api/autodownload/inflight/clear", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"cleared": 1}`, w.Body.String())
+	assert.Empty(t, list().InFlight)
+
+	// The coalesced request is released instead of waiting on the stuck download
+	select {
+	case err := <-waiter:
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cleared")
+	case <-time.After(2 * time.Second):
+		t.Fatal("waiting request was not released")
+	}
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// diagnosticsCheckTimeout bounds each diagnostics check
+const diagnosticsCheckTimeout = 5 * time.Second
+
+// diagnosticsPrefix holds the sentinel objects written by storage checks
+const diagnosticsPrefix = "diagnostics/"
+
+// DiagnosticCheck is the result of exercising one subsystem
+type DiagnosticCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"` // pass, fail or skipped
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// DiagnosticsResponse reports every check; Status is fail if any check failed
+type DiagnosticsResponse struct {
+	Status string            `json:"status"`
+	Checks []DiagnosticCheck `json:"checks"`
+}
+
+// handleDiagnostics exercises the database, storage, cache and upstream
+// registries, reporting each subsystem separately. Sentinel objects are
+// removed even when a check fails.
+// GET /admin/api/diagnostics
+func (s *Server) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	sentinel := []byte(fmt.Sprintf("terraform-mirror diagnostics %d", time.Now().UnixNano()))
+
+	response := DiagnosticsResponse{Status: "pass"}
+	add := func(check DiagnosticCheck) {
+		if check.Status == "fail" {
+			response.Status = "fail"
+		}
+		response.Checks = append(response.Checks, check)
+	}
+
+	add(runDiagnostic(ctx, "database", s.db.CheckReadWrite))
+	add(runDiagnostic(ctx, "storage", func(ctx context.Context) error {
+		return s.checkStorage(ctx, sentinel)
+	}))
+	// A disabled cache is a no-op that never stores the sentinel
+	if s.config.Cache.IsEnabled() {
+		add(runDiagnostic(ctx, "cache", func(ctx context.Context) error {
+			return s.checkCache(ctx, sentinel)
+		}))
+	} else {
+		add(DiagnosticCheck{Name: "cache", Status: "skipped", Error: "cache is disabled"})
+	}
+	for _, check := range s.checkRegistries(ctx) {
+		add(check)
+	}
+
+	status := http.StatusOK
+	if response.Status == "fail" {
+		status = http.StatusServiceUnavailable
+	}
+	respondJSON(w, status, response)
+}
+
+// runDiagnostic runs a check with the diagnostics timeout and times it
+func runDiagnostic(ctx context.Context, name string, check func(ctx context.Context) error) DiagnosticCheck {
+	ctx, cancel := context.WithTimeout(ctx, diagnosticsCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := DiagnosticCheck{Name: name, Status: "pass", DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = "fail"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// checkStorage uploads, downloads and deletes a sentinel object
+func (s *Server) checkStorage(ctx context.Context, sentinel []byte) (err error) {
+	key := fmt.Sprintf("%ssentinel-%d", diagnosticsPrefix, time.Now().UnixNano())
+
+	if err := s.storage.Upload(ctx, key, bytes.NewReader(sentinel), "text/plain", nil); err != nil {
+		return fmt.Errorf("upload failed: %w", err)
+	}
+	// Delete with a fresh context so a timed-out check still cleans up
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), diagnosticsCheckTimeout)
+		defer cancel()
+		if deleteErr := s.storage.Delete(cleanupCtx, key); deleteErr != nil && err == nil {
+			err = fmt.Errorf("delete failed: %w", deleteErr)
+		}
+	}()
+
+	reader, err := s.storage.Download(ctx, key)
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	if !bytes.Equal(data, sentinel) {
+		return fmt.Errorf("downloaded content does not match the upload")
+	}
+	return nil
+}
+
+// checkCache stores, reads back and removes a sentinel entry
+func (s *Server) checkCache(ctx context.Context, sentinel []byte) error {
+	key := fmt.Sprintf("%ssentinel-%d", diagnosticsPrefix, time.Now().UnixNano())
+
+	if err := s.cache.Set(ctx, key, bytes.NewReader(sentinel), "text/plain", int64(len(sentinel)), time.Minute); err != nil {
+		return fmt.Errorf("set failed: %w", err)
+	}
+	defer s.cache.Delete(context.Background(), key)
+
+	reader, _, found := s.cache.Get(ctx, key)
+	if !found {
+		return fmt.Errorf("entry not found after set")
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("get failed: %w", err)
+	}
+	if !bytes.Equal(data, sentinel) {
+		return fmt.Errorf("cached content does not match")
+	}
+	return nil
+}
+
+// checkRegistries pings every upstream registry at once, reporting each as
+// its own check in hostname order
+func (s *Server) checkRegistries(ctx context.Context) []DiagnosticCheck {
+	if s.registryPinger == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, diagnosticsCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	results := s.registryPinger.Ping(ctx)
+	elapsed := time.Since(start).Milliseconds()
+
+	hostnames := make([]string, 0, len(results))
+	for hostname := range results {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+
+	checks := make([]DiagnosticCheck, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		check := DiagnosticCheck{Name: "registry:" + hostname, Status: "pass", DurationMs: elapsed}
+		if err := results[hostname]; err != nil {
+			check.Status = "fail"
+			check.Error = err.Error()
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
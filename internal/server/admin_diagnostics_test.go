@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/cache"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePinger reports fixed registry results
+type fakePinger map[string]error
+
+func (p fakePinger) Ping(ctx context.Context) map[string]error {
+	return p
+}
+
+// brokenDownloadStorage accepts uploads but fails every download
+type brokenDownloadStorage struct {
+	storage.Storage
+	deleted []string
+}
+
+func (b *brokenDownloadStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	return nil, errors.New("bucket unavailable")
+}
+
+func (b *brokenDownloadStorage) Delete(ctx context.Context, key string) error {
+	b.deleted = append(b.deleted, key)
+	return b.Storage.Delete(ctx, key)
+}
+
+func getDiagnostics(t *testing.T, server *Server) (int, DiagnosticsResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/diagnostics", nil)
+	addAuthHeader(req, getAuthToken(t, server))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	var response DiagnosticsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	return w.Code, response
+}
+
+func checksByName(response DiagnosticsResponse) map[string]DiagnosticCheck {
+	checks := make(map[string]DiagnosticCheck)
+	for _, check := range response.Checks {
+		checks[check.Name] = check
+	}
+	return checks
+}
+
+func TestHandleDiagnostics_AllPass(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	mc, err := cache.NewMemoryCache(cache.MemoryCacheConfig{MaxSizeMB: 1})
+	require.NoError(t, err)
+	server.cache = mc
+	server.config.Cache.MemorySizeMB = 1
+	server.registryPinger = fakePinger{"registry.terraform.io": nil}
+
+	code, response := getDiagnostics(t, server)
+	require.Equal(t, http.StatusOK, code, response)
+	assert.Equal(t, "pass", response.Status)
+
+	checks := checksByName(response)
+	for _, name := range []string{"database", "storage", "cache", "registry:registry.terraform.io"} {
+		assert.Equal(t, "pass", checks[name].Status, name)
+	}
+
+	// Sentinel objects are cleaned up
+	keys, err := server.storage.ListObjects(context.Background(), diagnosticsPrefix)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	assert.Zero(t, mc.Stats().ItemCount)
+}
+
+func TestHandleDiagnostics_StorageFailure(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	broken := &brokenDownloadStorage{Storage: server.storage}
+	server.storage = broken
+	server.registryPinger = fakePinger{
+		"registry.terraform.io": nil,
+		"registry.example.com":  errors.New("connection refused"),
+	}
+
+	code, response := getDiagnostics(t, server)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "fail", response.Status)
+
+	checks := checksByName(response)
+	assert.Equal(t, "pass", checks["database"].Status)
+	assert.Equal(t, "fail", checks["storage"].Status)
+	assert.Contains(t, checks["storage"].Error, "bucket unavailable")
+	assert.Equal(t, "skipped", checks["cache"].Status)
+	assert.Equal(t, "pass", checks["registry:registry.terraform.io"].Status)
+	assert.Equal(t, "fail", checks["registry:registry.example.com"].Status)
+
+	// The uploaded sentinel is removed even though the check failed
+	require.Len(t, broken.deleted, 1)
+	keys, err := broken.ListObjects(context.Background(), diagnosticsPrefix)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
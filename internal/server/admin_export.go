@@ -0,0 +1,70 @@
+package server
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/ned1313/terraform-mirror/internal/manifest"
+)
+
+// handleExport returns a manifest of everything in the mirror
+// GET /admin/api/export
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	m, err := manifest.Export(r.Context(), s.db)
+	if err != nil {
+		log.Printf("Error exporting manifest: %v", err)
+		respondError(w, http.StatusInternalServerError, "export_error", "Failed to export manifest")
+		return
+	}
+
+	s.logAuditEvent(r, "export_manifest", "manifest", "", true, "", map[string]interface{}{
+		"providers": len(m.Providers),
+		"modules":   len(m.Modules),
+	})
+
+	w.Header().Set("Content-Disposition", `attachment; filename="mirror-manifest.json"`)
+	respondJSON(w, http.StatusOK, m)
+}
+
+// handleImport creates download jobs for every entry in an uploaded manifest
+// POST /admin/api/import
+// Accepts a JSON manifest as produced by GET /admin/api/export
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	// Limit manifest size to 10MB
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
+
+	m, err := manifest.Read(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_manifest", err.Error())
+		return
+	}
+
+	if len(m.Providers) == 0 && len(m.Modules) == 0 {
+		respondError(w, http.StatusBadRequest, "empty_manifest", "Manifest contains no providers or modules")
+		return
+	}
+
+	var userID sql.NullInt64
+	if id, ok := r.Context().Value(userIDKey).(int64); ok {
+		userID = sql.NullInt64{Int64: id, Valid: true}
+	}
+
+	result, err := manifest.Import(r.Context(), s.db, m, userID)
+	if err != nil {
+		log.Printf("Error importing manifest: %v", err)
+		s.logAuditEvent(r, "import_manifest", "manifest", "", false, err.Error(), nil)
+		respondError(w, http.StatusInternalServerError, "import_error", "Failed to import manifest")
+		return
+	}
+
+	s.logAuditEvent(r, "import_manifest", "manifest", "", true, "", map[string]interface{}{
+		"provider_job_id": result.ProviderJobID,
+		"provider_items":  result.ProviderItems,
+		"module_job_id":   result.ModuleJobID,
+		"module_items":    result.ModuleItems,
+	})
+
+	log.Printf("Imported manifest: %d provider items, %d module items", result.ProviderItems, result.ModuleItems)
+	respondJSON(w, http.StatusAccepted, result)
+}
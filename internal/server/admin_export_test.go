@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleExportImport(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, srv.providerRepo.Create(ctx, &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "aws",
+		Version:   "5.0.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-aws_5.0.0_linux_amd64.zip",
+		Shasum:    "abc123",
+		S3Key:     "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip",
+	}))
+
+	token := createTestToken(t, srv)
+
+	// Export
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/export", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	exported := w.Body.Bytes()
+	m, err := manifest.Read(bytes.NewReader(exported))
+	require.NoError(t, err)
+	require.Len(t, m.Providers, 1)
+	assert.Equal(t, "abc123", m.Providers[0].Shasum)
+	assert.Empty(t, m.Modules)
+
+	// Import the exported manifest back
+	req = httptest.NewRequest(http.MethodPost, "/admin/api/import", bytes.NewReader(exported))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	var result manifest.ImportResult
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, 1, result.ProviderItems)
+	assert.Equal(t, 0, result.ModuleItems)
+
+	items, err := srv.jobRepo.GetItems(ctx, result.ProviderJobID)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "linux_amd64", items[0].Platform)
+
+	// Empty and invalid manifests are rejected
+	for _, body := range []string{`{"format_version": 1}`, `not json`} {
+		req = httptest.NewRequest(http.MethodPost, "/admin/api/import", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w = httptest.NewRecorder()
+		srv.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+}
@@ -3,12 +3,18 @@ package server
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ned1313/terraform-mirror/internal/config"
 	"github.com/ned1313/terraform-mirror/internal/database"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -55,6 +61,35 @@ func TestHandleGetProvider(t *testing.T) {
 		assert.Equal(t, "5.0.0", result.Version)
 	})
 
+	t.Run("reports upstream url", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api/providers/1", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var result struct {
+			UpstreamURL string `json:"upstream_url"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		assert.Equal(t, "https://example.com/provider.zip", result.UpstreamURL)
+
+		req = httptest.NewRequest(http.MethodGet, "/admin/api/providers", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w = httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var list struct {
+			Providers []struct {
+				UpstreamURL string `json:"upstream_url"`
+			} `json:"providers"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+		require.Len(t, list.Providers, 1)
+		assert.Equal(t, "https://example.com/provider.zip", list.Providers[0].UpstreamURL)
+	})
+
 	t.Run("get non-existent provider", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/admin/api/providers/999", nil)
 		req.Header.Set("Authorization", "Bearer "+token)
@@ -441,6 +476,62 @@ func TestHandleCancelJob(t *testing.T) {
 	})
 }
 
+func TestHandleCancelAllJobs(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	token := getAuthToken(t, server)
+	jobRepo := database.NewJobRepository(server.db)
+	ctx := context.Background()
+
+	statuses := []string{"pending", "pending", "running", "running", "completed", "failed", "cancelled"}
+	jobs := make([]*database.DownloadJob, len(statuses))
+	for i, status := range statuses {
+		jobs[i] = &database.DownloadJob{
+			SourceType: "hcl",
+			SourceData: "test",
+			Status:     status,
+			TotalItems: 1,
+		}
+		require.NoError(t, jobRepo.Create(ctx, jobs[i]))
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/api/jobs/cancel-all", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var result CancelAllJobsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, int64(2), result.CancelledPending)
+	assert.Equal(t, 2, result.CancelledRunning)
+	assert.ElementsMatch(t, []int64{jobs[2].ID, jobs[3].ID}, result.RunningJobIDs)
+
+	want := []string{"cancelled", "cancelled", "cancelled", "cancelled", "completed", "failed", "cancelled"}
+	for i, job := range jobs {
+		updated, err := jobRepo.GetByID(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, want[i], updated.Status, "job %d was %s", i, statuses[i])
+		if statuses[i] == "pending" || statuses[i] == "running" {
+			assert.Equal(t, "Job cancelled by cancel-all", updated.ErrorMessage.String)
+			assert.True(t, updated.CompletedAt.Valid)
+		} else {
+			assert.NotEqual(t, "Job cancelled by cancel-all", updated.ErrorMessage.String, "finished jobs are untouched")
+		}
+	}
+
+	// Nothing is left to cancel
+	req = httptest.NewRequest(http.MethodPost, "/admin/api/jobs/cancel-all", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, int64(0), result.CancelledPending)
+	assert.Equal(t, 0, result.CancelledRunning)
+}
+
 func TestHandleStorageStats(t *testing.T) {
 	server, cleanup := setupAdminTest(t)
 	defer cleanup()
@@ -572,9 +663,122 @@ func TestHandleAuditLogs(t *testing.T) {
 	})
 }
 
+func TestHandleExportAuditLogs(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	// More entries than one batch, so the export has to continue from a cursor
+	auditRepo := database.NewAuditRepository(server.db)
+	total := auditExportBatchSize + 2
+	for i := 0; i < total; i++ {
+		require.NoError(t, auditRepo.Log(context.Background(), &database.AdminAction{
+			Action:       "login",
+			ResourceType: "session",
+			ResourceID:   sql.NullString{String: fmt.Sprintf("%d", i), Valid: true},
+			Success:      true,
+		}))
+	}
+
+	token := getAuthToken(t, server)
+	export := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api/stats/audit/export"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) []AuditLogEntry {
+		var entries []AuditLogEntry
+		decoder := json.NewDecoder(w.Body)
+		for decoder.More() {
+			var entry AuditLogEntry
+			require.NoError(t, decoder.Decode(&entry))
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format("2006-01-02")
+
+	t.Run("all entries in order", func(t *testing.T) {
+		w := export("?from=" + yesterday + "&to=" + tomorrow)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		assert.Equal(t, total, strings.Count(w.Body.String(), "\n"))
+
+		entries := decode(w)
+		require.Len(t, entries, total)
+		for i, entry := range entries {
+			require.NotNil(t, entry.ResourceID)
+			assert.Equal(t, fmt.Sprintf("%d", i), *entry.ResourceID)
+			if i > 0 {
+				assert.Greater(t, entry.ID, entries[i-1].ID)
+			}
+		}
+	})
+
+	t.Run("outside the range", func(t *testing.T) {
+		w := export("?to=" + yesterday)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, decode(w))
+
+		w = export("?from=" + time.Now().Add(time.Hour).Format(time.RFC3339))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, decode(w))
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, export("?from=last-week").Code)
+		assert.Equal(t, http.StatusBadRequest, export("?from="+tomorrow+"&to="+yesterday).Code)
+	})
+}
+
+func TestHandleFailureStats(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		job := &database.DownloadJob{SourceType: "hcl", Status: "failed", TotalItems: 1}
+		require.NoError(t, server.jobRepo.Create(ctx, job))
+		item := &database.DownloadJobItem{
+			JobID:     job.ID,
+			Namespace: "hashicorp",
+			Type:      "aws",
+			Version:   "5.0.0",
+			Platform:  "linux_amd64",
+			Status:    "pending",
+		}
+		require.NoError(t, server.jobRepo.CreateItem(ctx, item))
+		item.Status = "failed"
+		item.ErrorMessage = sql.NullString{String: fmt.Sprintf("attempt %d failed", i+1), Valid: true}
+		require.NoError(t, server.jobRepo.UpdateItem(ctx, item))
+	}
+
+	token := getAuthToken(t, server)
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/stats/failures", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var result FailureStatsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	require.Len(t, result.Failures, 1)
+	assert.Equal(t, int64(2), result.Failures[0].FailureCount)
+	assert.Equal(t, int64(2), result.Failures[0].JobCount)
+	require.NotNil(t, result.Failures[0].LastError)
+	assert.Equal(t, "attempt 2 failed", *result.Failures[0].LastError)
+}
+
 func TestHandleGetConfig(t *testing.T) {
 	server, cleanup := setupAdminTest(t)
 	defer cleanup()
+	server.config.Providers.Upstreams = []config.UpstreamRegistryConfig{
+		{Hostname: "registry.example.com", Namespaces: []string{"acme"}, Token: "upstream-token"},
+		{Hostname: "artifactory.example.com", Namespaces: []string{"*"}, Username: "ci", Password: "upstream-password"},
+	}
 
 	// Get auth token
 	token := getAuthToken(t, server)
@@ -587,8 +791,9 @@ func TestHandleGetConfig(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
+	body := w.Body.String()
 	var result SanitizedConfig
-	err := json.NewDecoder(w.Body).Decode(&result)
+	err := json.Unmarshal([]byte(body), &result)
 	require.NoError(t, err)
 
 	// Check that config is returned
@@ -597,6 +802,101 @@ func TestHandleGetConfig(t *testing.T) {
 
 	// Note: Secrets should NOT be in the response
 	// JWT secret, access keys, etc. are not exposed
+	require.Len(t, result.Providers.Upstreams, 2)
+	assert.Equal(t, redactedSecret, result.Providers.Upstreams[0].Token)
+	assert.Empty(t, result.Providers.Upstreams[0].Password)
+	assert.Equal(t, "ci", result.Providers.Upstreams[1].Username)
+	assert.Equal(t, redactedSecret, result.Providers.Upstreams[1].Password)
+	assert.NotContains(t, body, "upstream-token")
+	assert.NotContains(t, body, "upstream-password")
+}
+
+func TestHandleGetConfigHCL(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+	server.config.Providers.Upstreams = []config.UpstreamRegistryConfig{
+		{Hostname: "registry.example.com", Namespaces: []string{"acme"}, Token: "upstream-token"},
+	}
+	server.config.Sync = &config.SyncConfig{Enabled: true, Schedule: "0 3 * * *"}
+
+	token := getAuthToken(t, server)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/config/hcl", nil)
+	addAuthHeader(req, token)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	assert.Contains(t, body, "server {")
+	assert.Contains(t, body, "sync {")
+	assert.Contains(t, body, `upstream "registry.example.com" {`)
+	assert.Contains(t, body, redactedSecret)
+	assert.NotContains(t, body, "upstream-token")
+	require.NotEmpty(t, server.config.Auth.JWTSecret)
+	assert.NotContains(t, body, server.config.Auth.JWTSecret)
+
+	// Admin authentication is required
+	req = httptest.NewRequest(http.MethodGet, "/admin/api/config/hcl", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestHandleProcessorConfig(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	token := getAuthToken(t, server)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/admin/api/processor/config", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		addAuthHeader(req, token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := put(`{"max_concurrent_jobs": 7}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var result SanitizedProcessorConfig
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, 7, result.MaxConcurrentJobs)
+	assert.Equal(t, 7, server.processorService.GetConfig().MaxConcurrentJobs)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/processor/config", nil)
+	addAuthHeader(req, token)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, 7, result.MaxConcurrentJobs)
+
+	// Audit entries are written asynchronously
+	var logs []*database.AdminAction
+	require.Eventually(t, func() bool {
+		var err error
+		logs, err = database.NewAuditRepository(server.db).ListByAction(context.Background(), "update_processor_config", 10, 0)
+		return err == nil && len(logs) > 0
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Len(t, logs, 1)
+	assert.True(t, logs[0].Success)
+
+	for body, code := range map[string]string{
+		`{"max_concurrent_jobs": 0}`:   "invalid_max_concurrent_jobs",
+		`{"max_concurrent_jobs": 101}`: "invalid_max_concurrent_jobs",
+		`{}`:                           "invalid_body",
+		`not json`:                     "invalid_body",
+	} {
+		w := put(body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Contains(t, w.Body.String(), code, body)
+	}
+	assert.Equal(t, 7, server.processorService.GetConfig().MaxConcurrentJobs)
 }
 
 func TestHandleTriggerBackup(t *testing.T) {
@@ -622,6 +922,93 @@ func TestHandleTriggerBackup(t *testing.T) {
 
 		assert.Equal(t, "backup_disabled", result["error"])
 	})
+
+	server.config.Database.BackupEnabled = true
+	server.config.Database.BackupToS3 = true
+	server.config.Database.BackupS3Prefix = "backups/"
+	server.config.Database.Path = filepath.Join(t.TempDir(), "mirror.db")
+
+	backup := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/api/backup", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("verified backup is uploaded", func(t *testing.T) {
+		w := backup()
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp BackupResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.NoError(t, database.VerifyBackup(context.Background(), resp.BackupPath))
+		require.NotEmpty(t, resp.S3Key)
+		exists, err := server.storage.Exists(context.Background(), resp.S3Key)
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("corrupt backup is refused", func(t *testing.T) {
+		var corruptPath string
+		server.backupFunc = func(ctx context.Context, path string) error {
+			if err := server.db.Backup(ctx, path); err != nil {
+				return err
+			}
+			corruptPath = path
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			for i := 4096; i < len(data); i++ {
+				data[i] = 0xff
+			}
+			return os.WriteFile(path, data, 0644)
+		}
+		defer func() { server.backupFunc = server.db.Backup }()
+
+		before, err := server.storage.ListObjects(context.Background(), "backups/")
+		require.NoError(t, err)
+
+		// Backups are named by the second, so wait for a fresh name
+		time.Sleep(time.Second)
+		w := backup()
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		var result ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		assert.Equal(t, "backup_verification_failed", result.Error)
+
+		// Nothing was uploaded and the corrupt file was removed
+		after, err := server.storage.ListObjects(context.Background(), "backups/")
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+		_, err = os.Stat(corruptPath)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("concurrent backup is rejected", func(t *testing.T) {
+		started := make(chan struct{})
+		proceed := make(chan struct{})
+		server.backupFunc = func(ctx context.Context, path string) error {
+			close(started)
+			<-proceed
+			return server.db.Backup(ctx, path)
+		}
+		defer func() { server.backupFunc = server.db.Backup }()
+
+		first := make(chan *httptest.ResponseRecorder)
+		go func() { first <- backup() }()
+		<-started
+
+		w := backup()
+		require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+		var result ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		assert.Equal(t, "operation_in_progress", result.Error)
+
+		close(proceed)
+		assert.Equal(t, http.StatusOK, (<-first).Code)
+	})
 }
 
 func TestFormatBytes(t *testing.T) {
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyIntegrity(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sum := func(data string) string {
+		h := sha256.Sum256([]byte(data))
+		return hex.EncodeToString(h[:])
+	}
+
+	seed := func(version, stored, recorded string, upload bool) *database.Provider {
+		p := &database.Provider{
+			Namespace: "hashicorp",
+			Type:      "random",
+			Version:   version,
+			Platform:  "linux_amd64",
+			Filename:  "terraform-provider-random_" + version + "_linux_amd64.zip",
+			Shasum:    recorded,
+		}
+		p.S3Key = "providers/registry.terraform.io/hashicorp/random/" + version + "/linux_amd64/" + p.Filename
+		if upload {
+			require.NoError(t, server.storage.Upload(ctx, p.S3Key, strings.NewReader(stored), "application/zip", nil))
+		}
+		require.NoError(t, server.providerRepo.Create(ctx, p))
+		return p
+	}
+
+	intact := seed("3.0.0", "original", sum("original"), true)
+	tampered := seed("3.1.0", "tampered", sum("original"), true)
+	missing := seed("3.2.0", "", sum("original"), false)
+
+	token := getAuthToken(t, server)
+	verify := func(body string) VerifyIntegrityResponse {
+		req := httptest.NewRequest(http.MethodPost, "/admin/api/providers/verify-integrity", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp VerifyIntegrityResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return resp
+	}
+
+	t.Run("flags a tampered blob", func(t *testing.T) {
+		resp := verify("")
+		assert.Equal(t, int64(3), resp.Checked)
+		assert.Equal(t, int64(1), resp.Verified)
+		assert.Equal(t, int64(1), resp.MismatchCount)
+		require.Len(t, resp.Mismatches, 1)
+		assert.Equal(t, tampered.ID, resp.Mismatches[0].ID)
+		assert.Equal(t, sum("original"), resp.Mismatches[0].ExpectedShasum)
+		assert.Equal(t, sum("tampered"), resp.Mismatches[0].ActualShasum)
+
+		assert.Equal(t, int64(1), resp.ErrorCount)
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, missing.ID, resp.Errors[0].ID)
+	})
+
+	t.Run("verifies selected providers only", func(t *testing.T) {
+		resp := verify(`{"ids": [` + strconv.FormatInt(intact.ID, 10) + `, 9999]}`)
+		assert.Equal(t, int64(1), resp.Checked)
+		assert.Equal(t, int64(1), resp.Verified)
+		assert.Zero(t, resp.MismatchCount)
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, "provider not found", resp.Errors[0].Error)
+
+		resp = verify(`{"namespace": "hashicorp", "type": "random", "version": "3.1.0"}`)
+		assert.Equal(t, int64(1), resp.Checked)
+		assert.Equal(t, int64(1), resp.MismatchCount)
+	})
+
+	t.Run("does not modify storage", func(t *testing.T) {
+		actual, err := server.storage.Download(ctx, tampered.S3Key)
+		require.NoError(t, err)
+		defer actual.Close()
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(actual)
+		require.NoError(t, err)
+		assert.Equal(t, "tampered", buf.String())
+	})
+
+	t.Run("rejects malformed body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/api/providers/verify-integrity", bytes.NewBufferString("{"))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
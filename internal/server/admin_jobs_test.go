@@ -1,12 +1,17 @@
 package server
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/ned1313/terraform-mirror/internal/database"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -97,6 +102,43 @@ func TestHandleListJobs_WithCustomPagination(t *testing.T) {
 	assert.Equal(t, 10, response.Offset)
 }
 
+func TestHandleListJobs_Sorted(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		job := &database.DownloadJob{JobType: "provider", SourceType: "hcl", Status: "pending"}
+		require.NoError(t, server.jobRepo.Create(ctx, job))
+		ids = append(ids, job.ID)
+	}
+
+	token := getAuthToken(t, server)
+
+	req := httptest.NewRequest("GET", "/admin/api/jobs?sort=created_at&order=asc", nil)
+	addAuthHeader(req, token)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response jobListResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	require.Len(t, response.Jobs, 3)
+	for i, job := range response.Jobs {
+		assert.Equal(t, ids[i], job.ID)
+	}
+
+	for _, query := range []string{"sort=size_bytes", "sort=created_at&order=up"} {
+		req = httptest.NewRequest("GET", "/admin/api/jobs?"+query, nil)
+		addAuthHeader(req, token)
+		rr = httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+		assert.Contains(t, rr.Body.String(), "invalid_sort", query)
+	}
+}
+
 func TestJobIntegration_CreateAndRetrieve(t *testing.T) {
 	server, cleanup := setupAdminTest(t)
 	defer cleanup()
@@ -160,3 +202,83 @@ provider "hashicorp/random" {
 	assert.Equal(t, 1, len(listResp.Jobs))
 	assert.Equal(t, createResponse.JobID, listResp.Jobs[0].ID)
 }
+
+func TestHandleGetJobItem(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	token := getAuthToken(t, server)
+	ctx := context.Background()
+
+	// A finished provider job with one failed item
+	started := time.Now().Add(-2 * time.Second)
+	job := &database.DownloadJob{JobType: "provider", SourceType: "api", Status: "failed", TotalItems: 1, FailedItems: 1, CreatedAt: started}
+	require.NoError(t, server.jobRepo.Create(ctx, job))
+	item := &database.DownloadJobItem{JobID: job.ID, Namespace: "hashicorp", Type: "aws", Version: "5.0.0", Platform: "linux_arm64", Status: "pending"}
+	require.NoError(t, server.jobRepo.CreateItem(ctx, item))
+	item.Status = "failed"
+	item.DownloadURL = sql.NullString{String: "https://releases.example.com/aws.zip", Valid: true}
+	item.ErrorMessage = sql.NullString{String: "checksum mismatch: expected abc, got def", Valid: true}
+	item.StartedAt = sql.NullTime{Time: started, Valid: true}
+	item.CompletedAt = sql.NullTime{Time: started.Add(1500 * time.Millisecond), Valid: true}
+	require.NoError(t, server.jobRepo.UpdateItem(ctx, item))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		addAuthHeader(req, token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get(fmt.Sprintf("/admin/api/jobs/%d/items/%d", job.ID, item.ID))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var detail jobItemDetailResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&detail))
+	assert.Equal(t, "provider", detail.ItemType)
+	assert.Equal(t, "failed", detail.Status)
+	assert.Equal(t, "linux_arm64", detail.Platform)
+	require.NotNil(t, detail.ErrorMessage)
+	assert.Equal(t, "checksum mismatch: expected abc, got def", *detail.ErrorMessage)
+	require.NotNil(t, detail.DownloadURL)
+	assert.Equal(t, "https://releases.example.com/aws.zip", *detail.DownloadURL)
+	assert.NotNil(t, detail.StartedAt)
+	assert.NotNil(t, detail.CompletedAt)
+	require.NotNil(t, detail.DurationMs)
+	assert.Equal(t, int64(1500), *detail.DurationMs)
+
+	t.Run("module item", func(t *testing.T) {
+		moduleJob := &database.DownloadJob{JobType: "module", SourceType: "api", Status: "failed", TotalItems: 1, FailedItems: 1, CreatedAt: time.Now()}
+		require.NoError(t, server.jobRepo.Create(ctx, moduleJob))
+		moduleJobRepo := database.NewModuleJobRepository(server.db)
+		moduleItem := &database.ModuleJobItem{JobID: moduleJob.ID, Namespace: "terraform-aws-modules", Name: "vpc", System: "aws", Version: "5.0.0", Status: "pending"}
+		require.NoError(t, moduleJobRepo.CreateItem(ctx, moduleItem))
+		moduleItem.Status = "failed"
+		moduleItem.ErrorMessage = sql.NullString{String: "module not found upstream", Valid: true}
+		require.NoError(t, moduleJobRepo.UpdateItem(ctx, moduleItem))
+
+		rr := get(fmt.Sprintf("/admin/api/jobs/%d/items/%d", moduleJob.ID, moduleItem.ID))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var detail jobItemDetailResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&detail))
+		assert.Equal(t, "module", detail.ItemType)
+		assert.Equal(t, "vpc", detail.Name)
+		require.NotNil(t, detail.ErrorMessage)
+		assert.Equal(t, "module not found upstream", *detail.ErrorMessage)
+	})
+
+	t.Run("item from another job", func(t *testing.T) {
+		other := &database.DownloadJob{JobType: "provider", SourceType: "api", Status: "completed", CreatedAt: time.Now()}
+		require.NoError(t, server.jobRepo.Create(ctx, other))
+
+		rr := get(fmt.Sprintf("/admin/api/jobs/%d/items/%d", other.ID, item.ID))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("invalid item id", func(t *testing.T) {
+		rr := get(fmt.Sprintf("/admin/api/jobs/%d/items/abc", job.ID))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ned1313/terraform-mirror/internal/database"
+)
+
+// labelPattern keeps labels short and safe to use in URLs and query strings
+var labelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._:-]{0,62}$`)
+
+// LabelsRequest is the body for adding labels to a provider or module
+type LabelsRequest struct {
+	Labels []string `json:"labels"`
+}
+
+// LabelsResponse lists the labels an artifact carries after a change
+type LabelsResponse struct {
+	ID     int64    `json:"id"`
+	Labels []string `json:"labels"`
+}
+
+// labeledProvider is a provider together with its labels, for the provider
+// list and detail responses. UpstreamURL is the registry download URL the
+// archive was mirrored from, so operators can audit its provenance.
+type labeledProvider struct {
+	*database.Provider
+	Labels      []string
+	UpstreamURL string `json:"upstream_url"`
+}
+
+// newLabeledProvider builds the response form of a provider
+func newLabeledProvider(p *database.Provider, labels []string) labeledProvider {
+	return labeledProvider{Provider: p, Labels: labels, UpstreamURL: p.DownloadURL}
+}
+
+// normalizeLabels lowercases and deduplicates labels, rejecting any that do
+// not match labelPattern
+func normalizeLabels(labels []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, label := range labels {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if !labelPattern.MatchString(label) {
+			return nil, fmt.Errorf("invalid label %q: use up to 63 lowercase letters, digits, '.', '_', ':' or '-'", label)
+		}
+		if !seen[label] {
+			seen[label] = true
+			normalized = append(normalized, label)
+		}
+	}
+	return normalized, nil
+}
+
+// labelsOf returns the sorted labels of one artifact, never nil
+func (s *Server) labelsOf(ctx context.Context, target database.LabelTarget, id int64) ([]string, error) {
+	labels, err := s.labelRepo.List(ctx, target, []int64{id})
+	if err != nil {
+		return nil, err
+	}
+	if labels[id] == nil {
+		return []string{}, nil
+	}
+	return labels[id], nil
+}
+
+// handleAddProviderLabels attaches labels to a provider
+// POST /admin/api/providers/{id}/labels
+func (s *Server) handleAddProviderLabels(w http.ResponseWriter, r *http.Request) {
+	s.handleAddLabels(w, r, database.LabelProvider, func(ctx context.Context, id int64) (bool, error) {
+		p, err := s.providerRepo.GetByID(ctx, id)
+		return p != nil, err
+	})
+}
+
+// handleRemoveProviderLabel detaches a label from a provider
+// DELETE /admin/api/providers/{id}/labels/{label}
+func (s *Server) handleRemoveProviderLabel(w http.ResponseWriter, r *http.Request) {
+	s.handleRemoveLabel(w, r, database.LabelProvider)
+}
+
+// handleAddModuleLabels attaches labels to a module
+// POST /admin/api/modules/{id}/labels
+func (s *Server) handleAddModuleLabels(w http.ResponseWriter, r *http.Request) {
+	s.handleAddLabels(w, r, database.LabelModule, func(ctx context.Context, id int64) (bool, error) {
+		m, err := s.moduleRepo.GetByID(ctx, id)
+		return m != nil, err
+	})
+}
+
+// handleRemoveModuleLabel detaches a label from a module
+// DELETE /admin/api/modules/{id}/labels/{label}
+func (s *Server) handleRemoveModuleLabel(w http.ResponseWriter, r *http.Request) {
+	s.handleRemoveLabel(w, r, database.LabelModule)
+}
+
+// handleAddLabels implements the add endpoints; exists looks up the artifact
+func (s *Server) handleAddLabels(w http.ResponseWriter, r *http.Request, target database.LabelTarget, exists func(context.Context, int64) (bool, error)) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_id", fmt.Sprintf("Invalid %s ID", target))
+		return
+	}
+
+	var req LabelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_body", "Invalid request body")
+		return
+	}
+	if len(req.Labels) == 0 {
+		respondError(w, http.StatusBadRequest, "missing_labels", "At least one label is required")
+		return
+	}
+	labels, err := normalizeLabels(req.Labels)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_label", err.Error())
+		return
+	}
+
+	found, err := exists(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", fmt.Sprintf("Failed to get %s", target))
+		return
+	}
+	if !found {
+		respondError(w, http.StatusNotFound, "not_found", labelTargetName(target)+" not found")
+		return
+	}
+
+	if err := s.labelRepo.Add(r.Context(), target, id, labels); err != nil {
+		s.logAuditEvent(r, "add_labels", string(target), idStr, false, err.Error(), nil)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to add labels")
+		return
+	}
+
+	current, err := s.labelsOf(r.Context(), target, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list labels")
+		return
+	}
+
+	s.logAuditEvent(r, "add_labels", string(target), idStr, true, "", map[string]interface{}{
+		"labels": labels,
+	})
+
+	respondJSON(w, http.StatusOK, LabelsResponse{ID: id, Labels: current})
+}
+
+// handleRemoveLabel implements the remove endpoints
+func (s *Server) handleRemoveLabel(w http.ResponseWriter, r *http.Request, target database.LabelTarget) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_id", fmt.Sprintf("Invalid %s ID", target))
+		return
+	}
+	label := strings.ToLower(chi.URLParam(r, "label"))
+
+	removed, err := s.labelRepo.Remove(r.Context(), target, id, label)
+	if err != nil {
+		s.logAuditEvent(r, "remove_label", string(target), idStr, false, err.Error(), nil)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to remove label")
+		return
+	}
+	if !removed {
+		respondError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Label %q not found on %s %d", label, target, id))
+		return
+	}
+
+	current, err := s.labelsOf(r.Context(), target, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list labels")
+		return
+	}
+
+	s.logAuditEvent(r, "remove_label", string(target), idStr, true, "", map[string]interface{}{
+		"label": label,
+	})
+
+	respondJSON(w, http.StatusOK, LabelsResponse{ID: id, Labels: current})
+}
+
+// labelProviders attaches labels to providers and, when label is set, keeps
+// only the providers carrying it
+func (s *Server) labelProviders(ctx context.Context, providers []*database.Provider, label string) ([]labeledProvider, error) {
+	ids := make([]int64, len(providers))
+	for i, p := range providers {
+		ids[i] = p.ID
+	}
+	labels, err := s.labelRepo.List(ctx, database.LabelProvider, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]labeledProvider, 0, len(providers))
+	for _, p := range providers {
+		pl := labels[p.ID]
+		if label != "" && !hasLabel(pl, label) {
+			continue
+		}
+		if pl == nil {
+			pl = []string{}
+		}
+		result = append(result, newLabeledProvider(p, pl))
+	}
+	return result, nil
+}
+
+// hasLabel reports whether the sorted labels contain label
+func hasLabel(labels []string, label string) bool {
+	i := sort.SearchStrings(labels, label)
+	return i < len(labels) && labels[i] == label
+}
+
+// labelTargetName returns the capitalized artifact kind for messages
+func labelTargetName(target database.LabelTarget) string {
+	if target == database.LabelModule {
+		return "Module"
+	}
+	return "Provider"
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabels(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	token := getAuthToken(t, server)
+
+	var providerIDs []int64
+	for _, providerType := range []string{"aws", "random"} {
+		p := &database.Provider{
+			Namespace: "hashicorp",
+			Type:      providerType,
+			Version:   "1.0.0",
+			Platform:  "linux_amd64",
+			Filename:  "terraform-provider-" + providerType + "_1.0.0_linux_amd64.zip",
+			S3Key:     "providers/registry.terraform.io/hashicorp/" + providerType + "/1.0.0/linux_amd64/terraform-provider-" + providerType + "_1.0.0_linux_amd64.zip",
+		}
+		require.NoError(t, server.providerRepo.Create(ctx, p))
+		providerIDs = append(providerIDs, p.ID)
+	}
+
+	var moduleIDs []int64
+	for _, version := range []string{"1.0.0", "2.0.0"} {
+		m := &database.Module{
+			Namespace: "terraform-aws-modules",
+			Name:      "vpc",
+			System:    "aws",
+			Version:   version,
+			S3Key:     "modules/terraform-aws-modules/vpc/aws/" + version + "/vpc.tar.gz",
+			Filename:  "vpc.tar.gz",
+		}
+		require.NoError(t, server.moduleRepo.Create(ctx, m))
+		moduleIDs = append(moduleIDs, m.ID)
+	}
+
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	providerPath := func(id int64) string {
+		return "/admin/api/providers/" + strconv.FormatInt(id, 10)
+	}
+	modulePath := func(id int64) string {
+		return "/admin/api/modules/" + strconv.FormatInt(id, 10)
+	}
+
+	t.Run("tag a provider", func(t *testing.T) {
+		w := do(http.MethodPost, providerPath(providerIDs[0])+"/labels", LabelsRequest{Labels: []string{"Team-X", "approved", "approved"}})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp LabelsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, []string{"approved", "team-x"}, resp.Labels)
+
+		w = do(http.MethodGet, providerPath(providerIDs[0]), nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var detail struct{ Labels []string }
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&detail))
+		assert.Equal(t, []string{"approved", "team-x"}, detail.Labels)
+	})
+
+	t.Run("filter providers by label", func(t *testing.T) {
+		w := do(http.MethodGet, "/admin/api/providers?label=approved", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Providers []struct {
+				ID     int64
+				Type   string
+				Labels []string
+			} `json:"providers"`
+			Count int `json:"count"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		require.Equal(t, 1, resp.Count)
+		assert.Equal(t, providerIDs[0], resp.Providers[0].ID)
+		assert.Equal(t, []string{"approved", "team-x"}, resp.Providers[0].Labels)
+
+		w = do(http.MethodGet, "/admin/api/providers", nil)
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, 2, resp.Count)
+	})
+
+	t.Run("remove a provider label", func(t *testing.T) {
+		w := do(http.MethodDelete, providerPath(providerIDs[0])+"/labels/approved", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp LabelsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, []string{"team-x"}, resp.Labels)
+
+		w = do(http.MethodDelete, providerPath(providerIDs[0])+"/labels/approved", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = do(http.MethodGet, "/admin/api/providers?label=approved", nil)
+		var list struct {
+			Count int `json:"count"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+		assert.Equal(t, 0, list.Count)
+	})
+
+	t.Run("tag and filter modules", func(t *testing.T) {
+		w := do(http.MethodPost, modulePath(moduleIDs[1])+"/labels", LabelsRequest{Labels: []string{"approved"}})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = do(http.MethodGet, "/admin/api/modules?label=approved", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var list ModuleListResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+		require.Equal(t, 1, list.Total)
+		assert.Equal(t, moduleIDs[1], list.Modules[0].ID)
+		assert.Equal(t, []string{"approved"}, list.Modules[0].Labels)
+
+		w = do(http.MethodGet, modulePath(moduleIDs[0]), nil)
+		var detail ModuleResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&detail))
+		assert.Equal(t, []string{}, detail.Labels)
+
+		w = do(http.MethodDelete, modulePath(moduleIDs[1])+"/labels/approved", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = do(http.MethodGet, "/admin/api/modules?label=approved", nil)
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+		assert.Equal(t, 0, list.Total)
+	})
+
+	t.Run("rejects bad requests", func(t *testing.T) {
+		w := do(http.MethodPost, providerPath(providerIDs[0])+"/labels", LabelsRequest{Labels: []string{"has space"}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = do(http.MethodPost, providerPath(providerIDs[0])+"/labels", LabelsRequest{})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = do(http.MethodPost, providerPath(9999)+"/labels", LabelsRequest{Labels: []string{"approved"}})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = do(http.MethodPost, modulePath(9999)+"/labels", LabelsRequest{Labels: []string{"approved"}})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestNormalizeLabels(t *testing.T) {
+	labels, err := normalizeLabels([]string{" Approved ", "team-x", "approved", "env:prod"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"approved", "team-x", "env:prod"}, labels)
+
+	for _, bad := range []string{"", "-leading", "has space", "slash/label", string(make([]byte, 64))} {
+		_, err := normalizeLabels([]string{bad})
+		assert.Error(t, err, "label %q", bad)
+	}
+}
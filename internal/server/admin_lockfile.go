@@ -0,0 +1,152 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/provider"
+)
+
+// LockfileSkip describes a lock file provider that was not queued
+type LockfileSkip struct {
+	Address string `json:"address"`
+	Version string `json:"version"`
+	Reason  string `json:"reason"`
+}
+
+// LockfileImportResponse describes the job created from a lock file
+type LockfileImportResponse struct {
+	JobID     int64          `json:"job_id,omitempty"`
+	Message   string         `json:"message"`
+	Providers int            `json:"providers"`
+	Platforms []string       `json:"platforms"`
+	Queued    int            `json:"queued"`
+	Skipped   []LockfileSkip `json:"skipped,omitempty"`
+}
+
+// handleProvidersFromLockfile queues a download job for exactly the provider
+// versions pinned in an uploaded .terraform.lock.hcl. Lock files do not say
+// which platforms were used, so every requested platform is queued, and each
+// download must match one of the file's zh: hashes.
+// POST /admin/api/providers/from-lockfile
+func (s *Server) handleProvidersFromLockfile(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_form", fmt.Sprintf("Failed to parse form data: %v", err))
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "missing_file", fmt.Sprintf("No file uploaded: %v", err))
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, 1<<20+1))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "read_error", fmt.Sprintf("Failed to read file: %v", err))
+		return
+	}
+	if len(content) > 1<<20 {
+		respondError(w, http.StatusBadRequest, "file_too_large", "File too large (max 1MB)")
+		return
+	}
+
+	locked, err := provider.ParseLockfile(content)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "parse_error", fmt.Sprintf("Failed to parse lock file: %v", err))
+		return
+	}
+
+	platforms, err := s.requestPlatforms(r.FormValue("platforms"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_platforms", err.Error())
+		return
+	}
+
+	response := LockfileImportResponse{
+		Providers: len(locked),
+		Platforms: platforms,
+	}
+
+	var items []*database.DownloadJobItem
+	for _, p := range locked {
+		// Jobs are routed to upstreams by namespace, so the lock file must
+		// name the registry that serves the namespace
+		if hostname := s.upstreams.Hostname(p.Namespace); !strings.EqualFold(p.Hostname, hostname) {
+			response.Skipped = append(response.Skipped, LockfileSkip{
+				Address: p.Address(),
+				Version: p.Version,
+				Reason:  fmt.Sprintf("namespace %q is mirrored from %s", p.Namespace, hostname),
+			})
+			continue
+		}
+
+		var expected sql.NullString
+		if len(p.ZipHashes) > 0 {
+			expected = sql.NullString{String: strings.Join(p.ZipHashes, ","), Valid: true}
+		}
+		for _, platform := range platforms {
+			items = append(items, &database.DownloadJobItem{
+				Namespace:       p.Namespace,
+				Type:            p.Type,
+				Version:         p.Version,
+				Platform:        platform,
+				Status:          "pending",
+				ExpectedShasums: expected,
+			})
+		}
+	}
+	response.Queued = len(items)
+
+	if len(items) == 0 {
+		respondError(w, http.StatusBadRequest, "no_providers", "No providers in the lock file can be mirrored")
+		return
+	}
+
+	// Create a pending job; the background processor picks it up
+	job := &database.DownloadJob{
+		JobType:    "provider",
+		SourceType: "lockfile",
+		SourceData: string(content),
+		Status:     "pending",
+		TotalItems: len(items),
+		CreatedAt:  time.Now(),
+	}
+	if userID, ok := r.Context().Value(userIDKey).(int64); ok {
+		job.UserID = sql.NullInt64{Int64: userID, Valid: true}
+	}
+
+	if err := s.jobRepo.Create(r.Context(), job); err != nil {
+		respondError(w, http.StatusInternalServerError, "job_creation_error",
+			fmt.Sprintf("Failed to create job: %v", err))
+		return
+	}
+
+	for _, item := range items {
+		item.JobID = job.ID
+		if err := s.jobRepo.CreateItem(r.Context(), item); err != nil {
+			respondError(w, http.StatusInternalServerError, "job_item_error",
+				fmt.Sprintf("Failed to create job item: %v", err))
+			return
+		}
+	}
+
+	s.jobRepo.NotifyEnqueued()
+
+	s.logAuditEvent(r, "load_providers_from_lockfile", "job", fmt.Sprintf("%d", job.ID), true, "", map[string]interface{}{
+		"providers": len(locked),
+		"platforms": platforms,
+		"queued":    len(items),
+		"skipped":   len(response.Skipped),
+	})
+
+	response.JobID = job.ID
+	response.Message = fmt.Sprintf("Lock file job created: %d items for %d providers", len(items), len(locked)-len(response.Skipped))
+	respondJSON(w, http.StatusAccepted, response)
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testLockfile = `# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.31.0"
+  constraints = "~> 5.0"
+  hashes = [
+    "h1:ltxyuBWIy9cq0kIKDJH1jeWJy/y7XJLjS4QrsQK4plA=",
+    "zh:0cdb9c2083bf0902442384f7309367791e4640581652dda456f2d6d7abf0de8d",
+    "zh:1fe0d0aa6c9a0e2a6e7d5e8f4c9c1b2a3d4e5f60718293a4b5c6d7e8f9012345",
+  ]
+}
+
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.6.0"
+  hashes = [
+    "h1:R5Ucn26riKIEijcsiOMBR3uOAjuOMfI1x7XvH4P6B1w=",
+  ]
+}
+
+provider "example.com/acme/widget" {
+  version = "0.1.0"
+}
+`
+
+// lockfileRequest builds a multipart lock file upload
+func lockfileRequest(t *testing.T, content, platforms string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile("file", ".terraform.lock.hcl")
+	require.NoError(t, err)
+	_, err = io.WriteString(part, content)
+	require.NoError(t, err)
+	if platforms != "" {
+		require.NoError(t, writer.WriteField("platforms", platforms))
+	}
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/api/providers/from-lockfile", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestHandleProvidersFromLockfile(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	token := getAuthToken(t, server)
+
+	req := lockfileRequest(t, testLockfile, "linux_amd64,darwin_arm64")
+	addAuthHeader(req, token)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+
+	var resp LockfileImportResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, 3, resp.Providers)
+	assert.Equal(t, 4, resp.Queued)
+	assert.Equal(t, []string{"linux_amd64", "darwin_arm64"}, resp.Platforms)
+	require.Len(t, resp.Skipped, 1)
+	assert.Equal(t, "example.com/acme/widget", resp.Skipped[0].Address)
+
+	job, err := server.jobRepo.GetByID(context.Background(), resp.JobID)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, "lockfile", job.SourceType)
+	assert.Equal(t, 4, job.TotalItems)
+
+	items, err := server.jobRepo.GetItems(context.Background(), resp.JobID)
+	require.NoError(t, err)
+	require.Len(t, items, 4)
+
+	type key struct{ providerType, version, platform string }
+	got := make(map[key]string)
+	for _, item := range items {
+		assert.Equal(t, "hashicorp", item.Namespace)
+		assert.Equal(t, "pending", item.Status)
+		got[key{item.Type, item.Version, item.Platform}] = item.ExpectedShasums.String
+	}
+
+	awsHashes := "0cdb9c2083bf0902442384f7309367791e4640581652dda456f2d6d7abf0de8d," +
+		"1fe0d0aa6c9a0e2a6e7d5e8f4c9c1b2a3d4e5f60718293a4b5c6d7e8f9012345"
+	assert.Equal(t, map[key]string{
+		{"aws", "5.31.0", "linux_amd64"}:    awsHashes,
+		{"aws", "5.31.0", "darwin_arm64"}:   awsHashes,
+		{"random", "3.6.0", "linux_amd64"}:  "",
+		{"random", "3.6.0", "darwin_arm64"}: "",
+	}, got)
+}
+
+func TestHandleProvidersFromLockfile_Errors(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	token := getAuthToken(t, server)
+
+	tests := []struct {
+		name      string
+		content   string
+		platforms string
+		wantCode  string
+	}{
+		{"invalid lock file", `provider "hashicorp/aws" { version = "latest" }`, "", "parse_error"},
+		{"invalid platform", testLockfile, "linux-amd64", "invalid_platforms"},
+		{"nothing mirrorable", `provider "example.com/acme/widget" { version = "0.1.0" }`, "", "no_providers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := lockfileRequest(t, tt.content, tt.platforms)
+			addAuthHeader(req, token)
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+
+			var errResp ErrorResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&errResp))
+			assert.Equal(t, tt.wantCode, errResp.Error)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ned1313/terraform-mirror/internal/logging"
+)
+
+// defaultLogsLimit is the number of entries returned when no limit is given
+const defaultLogsLimit = 100
+
+// LogsResponse lists recent log entries, oldest first
+type LogsResponse struct {
+	Entries  []logging.Entry `json:"entries"`
+	Count    int             `json:"count"`
+	Capacity int             `json:"capacity"`
+}
+
+// handleGetLogs returns the most recent server log entries, optionally only
+// those at or above a level
+// GET /admin/api/logs?level=warn&limit=100
+func (s *Server) handleGetLogs(w http.ResponseWriter, r *http.Request) {
+	if s.logBuffer == nil {
+		respondError(w, http.StatusNotImplemented, "log_buffer_disabled",
+			"The log buffer is disabled (logging.buffer_entries is 0)")
+		return
+	}
+
+	level := r.URL.Query().Get("level")
+	if level != "" && !logging.ValidLevel(level) {
+		respondError(w, http.StatusBadRequest, "invalid_level",
+			fmt.Sprintf("invalid level %q, expected debug, info, warn or error", level))
+		return
+	}
+
+	limit := defaultLogsLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			respondError(w, http.StatusBadRequest, "invalid_limit", "limit must be a positive integer")
+			return
+		}
+		limit = min(l, s.logBuffer.Size())
+	}
+
+	entries := s.logBuffer.Recent(level, limit)
+	if entries == nil {
+		entries = []logging.Entry{}
+	}
+
+	respondJSON(w, http.StatusOK, LogsResponse{
+		Entries:  entries,
+		Count:    len(entries),
+		Capacity: s.logBuffer.Size(),
+	})
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLogs(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	token := getAuthToken(t, server)
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("disabled buffer", func(t *testing.T) {
+		w := get("/admin/api/logs")
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+
+	buf := logging.NewRingBuffer(10)
+	server.SetLogBuffer(buf)
+	logger := log.New(buf, "", log.LstdFlags)
+	logger.Printf("Processor started")
+	logger.Printf("Warning: cache is nearly full")
+	logger.Printf("Failed to download blob providers/x.zip: not found")
+	logger.Printf("Job 3 completed")
+
+	decode := func(w *httptest.ResponseRecorder) LogsResponse {
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp LogsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return resp
+	}
+
+	t.Run("returns entries oldest first", func(t *testing.T) {
+		resp := decode(get("/admin/api/logs"))
+		assert.Equal(t, 10, resp.Capacity)
+		require.Equal(t, 4, resp.Count)
+
+		var messages []string
+		for _, e := range resp.Entries {
+			messages = append(messages, e.Message)
+		}
+		assert.Equal(t, []string{
+			"Processor started",
+			"Warning: cache is nearly full",
+			"Failed to download blob providers/x.zip: not found",
+			"Job 3 completed",
+		}, messages)
+	})
+
+	t.Run("filters by level", func(t *testing.T) {
+		resp := decode(get("/admin/api/logs?level=warn"))
+		require.Equal(t, 2, resp.Count)
+		assert.Equal(t, logging.LevelWarn, resp.Entries[0].Level)
+		assert.Equal(t, logging.LevelError, resp.Entries[1].Level)
+
+		resp = decode(get("/admin/api/logs?level=error"))
+		require.Equal(t, 1, resp.Count)
+		assert.Contains(t, resp.Entries[0].Message, "Failed to download blob")
+	})
+
+	t.Run("limits to the newest entries", func(t *testing.T) {
+		resp := decode(get("/admin/api/logs?limit=2"))
+		require.Equal(t, 2, resp.Count)
+		assert.Contains(t, resp.Entries[0].Message, "Failed to download blob")
+		assert.Equal(t, "Job 3 completed", resp.Entries[1].Message)
+	})
+
+	t.Run("rejects bad parameters", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("/admin/api/logs?level=loud").Code)
+		assert.Equal(t, http.StatusBadRequest, get("/admin/api/logs?limit=0").Code)
+	})
+
+	t.Run("requires authentication", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/api/logs", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
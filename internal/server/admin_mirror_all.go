@@ -0,0 +1,153 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/config"
+	"github.com/ned1313/terraform-mirror/internal/database"
+)
+
+// maxMirrorAllItems caps the number of version/platform items a single
+// mirror-all request may enqueue
+const maxMirrorAllItems = 5000
+
+// MirrorAllRequest asks for every upstream version of a provider to be mirrored
+type MirrorAllRequest struct {
+	Namespace string   `json:"namespace"`
+	Type      string   `json:"type"`
+	Platforms []string `json:"platforms"`
+}
+
+// MirrorAllResponse describes the job created by a mirror-all request
+type MirrorAllResponse struct {
+	JobID     int64    `json:"job_id,omitempty"`
+	Message   string   `json:"message"`
+	Versions  int      `json:"versions"`
+	Platforms []string `json:"platforms"`
+	Queued    int      `json:"queued"`
+	Skipped   int      `json:"skipped"`
+}
+
+// handleMirrorAllProvider discovers every upstream version of a provider and
+// enqueues a download job for each version and platform not already mirrored
+// POST /admin/api/providers/mirror-all
+func (s *Server) handleMirrorAllProvider(w http.ResponseWriter, r *http.Request) {
+	var req MirrorAllRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+
+	if !config.IsValidNamespace(req.Namespace) || !config.IsValidNamespace(req.Type) {
+		respondError(w, http.StatusBadRequest, "invalid_request", "A valid namespace and type are required")
+		return
+	}
+
+	platforms, err := s.requestPlatforms(strings.Join(req.Platforms, ","))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_platforms", err.Error())
+		return
+	}
+
+	versions, err := s.providerRegistry.GetAvailableVersions(r.Context(), req.Namespace, req.Type)
+	if err != nil {
+		log.Printf("Error listing upstream versions for %s/%s: %v", req.Namespace, req.Type, err)
+		respondError(w, http.StatusBadGateway, "upstream_error",
+			fmt.Sprintf("Failed to list versions for %s/%s from the upstream registry", req.Namespace, req.Type))
+		return
+	}
+	if len(versions) == 0 {
+		respondError(w, http.StatusNotFound, "not_found",
+			fmt.Sprintf("No versions of %s/%s found in the upstream registry", req.Namespace, req.Type))
+		return
+	}
+
+	if total := len(versions) * len(platforms); total > maxMirrorAllItems {
+		respondError(w, http.StatusBadRequest, "too_many_items",
+			fmt.Sprintf("Mirroring %d versions on %d platforms would create %d items (limit %d); request fewer platforms",
+				len(versions), len(platforms), total, maxMirrorAllItems))
+		return
+	}
+
+	// Skip version/platform tuples that are already mirrored
+	existing, err := s.providerRepo.ListVersions(r.Context(), req.Namespace, req.Type)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list mirrored versions")
+		return
+	}
+	mirrored := make(map[string]bool, len(existing))
+	for _, p := range existing {
+		mirrored[p.Version+"/"+p.Platform] = true
+	}
+
+	var items []*database.DownloadJobItem
+	skipped := 0
+	for _, version := range versions {
+		for _, platform := range platforms {
+			if mirrored[version+"/"+platform] {
+				skipped++
+				continue
+			}
+			items = append(items, &database.DownloadJobItem{
+				Namespace: req.Namespace,
+				Type:      req.Type,
+				Version:   version,
+				Platform:  platform,
+				Status:    "pending",
+			})
+		}
+	}
+
+	response := MirrorAllResponse{
+		Versions:  len(versions),
+		Platforms: platforms,
+		Queued:    len(items),
+		Skipped:   skipped,
+	}
+
+	if len(items) == 0 {
+		response.Message = "All versions and platforms are already mirrored"
+		respondJSON(w, http.StatusOK, response)
+		return
+	}
+
+	// Create a pending job; the background processor picks it up
+	job := &database.DownloadJob{
+		JobType:    "provider",
+		SourceType: "mirror_all",
+		SourceData: fmt.Sprintf("%s/%s: %d versions, %s", req.Namespace, req.Type, len(versions), strings.Join(platforms, ",")),
+		Status:     "pending",
+		TotalItems: len(items),
+		CreatedAt:  time.Now(),
+	}
+	if userID, ok := r.Context().Value(userIDKey).(int64); ok {
+		job.UserID = sql.NullInt64{Int64: userID, Valid: true}
+	}
+
+	// The job and its items are created together, so the processor never
+	// picks up a job with only some of its items
+	if err := s.jobRepo.CreateWithItems(r.Context(), job, items); err != nil {
+		respondError(w, http.StatusInternalServerError, "job_creation_error",
+			fmt.Sprintf("Failed to create job: %v", err))
+		return
+	}
+
+	s.logAuditEvent(r, "mirror_all_provider", "job", fmt.Sprintf("%d", job.ID), true, "", map[string]interface{}{
+		"namespace": req.Namespace,
+		"type":      req.Type,
+		"versions":  len(versions),
+		"platforms": platforms,
+		"queued":    len(items),
+		"skipped":   skipped,
+	})
+
+	response.JobID = job.ID
+	response.Message = fmt.Sprintf("Mirror job created: %d items across %d versions", len(items), len(versions))
+	respondJSON(w, http.StatusAccepted, response)
+}
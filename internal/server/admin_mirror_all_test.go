@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// versionsRegistry is a registry that only lists versions
+type versionsRegistry struct {
+	versions []string
+}
+
+func (r *versionsRegistry) DownloadProviderComplete(ctx context.Context, namespace, providerType, version, os, arch string) *provider.DownloadResult {
+	return &provider.DownloadResult{Error: assert.AnError}
+}
+
+func (r *versionsRegistry) GetAvailableVersions(ctx context.Context, namespace, providerType string) ([]string, error) {
+	return r.versions, nil
+}
+
+func TestHandleMirrorAllProvider(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	server.providerRegistry = &versionsRegistry{versions: []string{"3.0.0", "3.1.0", "3.2.0"}}
+
+	// 3.1.0 is already mirrored for linux_amd64
+	ctx := context.Background()
+	err := server.providerRepo.Create(ctx, &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "random",
+		Version:   "3.1.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-random_3.1.0_linux_amd64.zip",
+		S3Key:     "providers/registry.terraform.io/hashicorp/random/3.1.0/linux_amd64/terraform-provider-random_3.1.0_linux_amd64.zip",
+	})
+	require.NoError(t, err)
+
+	token := getAuthToken(t, server)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/api/providers/mirror-all", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		addAuthHeader(req, token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("expands versions and platforms", func(t *testing.T) {
+		w := post(`{"namespace": "hashicorp", "type": "random", "platforms": ["linux_amd64", "darwin_arm64"]}`)
+		require.Equal(t, http.StatusAccepted, w.Code)
+
+		var resp MirrorAllResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.NotZero(t, resp.JobID)
+		assert.Equal(t, 3, resp.Versions)
+		assert.Equal(t, 5, resp.Queued)
+		assert.Equal(t, 1, resp.Skipped)
+
+		items, err := server.jobRepo.GetItems(ctx, resp.JobID)
+		require.NoError(t, err)
+		require.Len(t, items, 5)
+
+		tuples := make(map[string]bool)
+		for _, item := range items {
+			tuples[item.Version+"/"+item.Platform] = true
+		}
+		assert.False(t, tuples["3.1.0/linux_amd64"], "already mirrored tuple should be skipped")
+		assert.True(t, tuples["3.1.0/darwin_arm64"])
+		assert.True(t, tuples["3.0.0/linux_amd64"])
+		assert.True(t, tuples["3.2.0/darwin_arm64"])
+
+		job, err := server.jobRepo.GetByID(ctx, resp.JobID)
+		require.NoError(t, err)
+		assert.Equal(t, "pending", job.Status)
+		assert.Equal(t, "mirror_all", job.SourceType)
+	})
+
+	t.Run("nothing missing", func(t *testing.T) {
+		server.providerRegistry = &versionsRegistry{versions: []string{"3.1.0"}}
+		defer func() { server.providerRegistry = &versionsRegistry{versions: []string{"3.0.0", "3.1.0", "3.2.0"}} }()
+
+		w := post(`{"namespace": "hashicorp", "type": "random", "platforms": ["linux_amd64"]}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp MirrorAllResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Zero(t, resp.JobID)
+		assert.Equal(t, 0, resp.Queued)
+	})
+
+	t.Run("safety cap", func(t *testing.T) {
+		versions := make([]string, maxMirrorAllItems+1)
+		for i := range versions {
+			versions[i] = fmt.Sprintf("1.0.%d", i)
+		}
+		server.providerRegistry = &versionsRegistry{versions: versions}
+		defer func() { server.providerRegistry = &versionsRegistry{versions: []string{"3.0.0", "3.1.0", "3.2.0"}} }()
+
+		w := post(`{"namespace": "hashicorp", "type": "random", "platforms": ["linux_amd64"]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "too_many_items")
+	})
+
+	t.Run("invalid platform", func(t *testing.T) {
+		w := post(`{"namespace": "hashicorp", "type": "random", "platforms": ["linux"]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_platforms")
+	})
+
+	t.Run("missing type", func(t *testing.T) {
+		w := post(`{"namespace": "hashicorp"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
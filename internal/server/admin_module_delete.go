@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/module"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+)
+
+// DeleteAllModuleVersionsResponse reports what deleting a whole module removed
+type DeleteAllModuleVersionsResponse struct {
+	Namespace  string   `json:"namespace"`
+	Name       string   `json:"name"`
+	System     string   `json:"system"`
+	Versions   []string `json:"versions"`
+	Deleted    int64    `json:"deleted"`
+	FreedBytes int64    `json:"freed_bytes"`
+}
+
+// handleDeleteModuleVersion deletes one module version by its identity,
+// leaving the other versions in place. A pinned version (explicitly
+// requested rather than auto-downloaded) is protected unless force=true.
+// DELETE /admin/api/modules/{namespace}/{name}/{system}/{version}?force=true
+func (s *Server) handleDeleteModuleVersion(w http.ResponseWriter, r *http.Request) {
+	namespace := chi.URLParam(r, "namespace")
+	name := chi.URLParam(r, "name")
+	system := chi.URLParam(r, "system")
+	version := chi.URLParam(r, "version")
+	address := namespace + "/" + name + "/" + system
+
+	force, ok := parseForce(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	m, err := s.moduleRepo.GetByIdentity(ctx, namespace, name, system, version)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to get module")
+		return
+	}
+	if m == nil {
+		respondError(w, http.StatusNotFound, "not_found", "Module version not found")
+		return
+	}
+
+	if !force && s.moduleIsPinned(ctx, m) {
+		respondError(w, http.StatusConflict, "module_pinned",
+			fmt.Sprintf("%s %s is pinned; retry with force=true to delete it", address, version))
+		return
+	}
+
+	// The row goes first, so the registry never lists an archive that was
+	// already removed from storage
+	if err := s.moduleRepo.Delete(ctx, m.ID); err != nil {
+		s.logAuditEvent(r, "delete_module", "module", strconv.FormatInt(m.ID, 10), false, err.Error(), nil)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to delete module")
+		return
+	}
+	s.releaseModuleArchive(ctx, m.S3Key)
+
+	// Stop serving a cached copy of the deleted archive
+	s.evictCacheKeys(ctx, []string{m.S3Key})
+
+	s.logAuditEvent(r, "delete_module", "module", strconv.FormatInt(m.ID, 10), true, "", map[string]interface{}{
+		"namespace": m.Namespace,
+		"name":      m.Name,
+		"system":    m.System,
+		"version":   m.Version,
+		"force":     force,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteAllModuleVersions deletes every version of a module. Pinned
+// versions protect the module unless force=true.
+// DELETE /admin/api/modules/{namespace}/{name}/{system}?force=true
+func (s *Server) handleDeleteAllModuleVersions(w http.ResponseWriter, r *http.Request) {
+	namespace := chi.URLParam(r, "namespace")
+	name := chi.URLParam(r, "name")
+	system := chi.URLParam(r, "system")
+	address := namespace + "/" + name + "/" + system
+
+	force, ok := parseForce(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	modules, err := s.moduleRepo.ListVersions(ctx, namespace, name, system)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to query modules")
+		return
+	}
+	if len(modules) == 0 {
+		respondError(w, http.StatusNotFound, "not_found", "Module not found")
+		return
+	}
+
+	versions := make([]string, 0, len(modules))
+	var pinned []string
+	for _, m := range modules {
+		versions = append(versions, m.Version)
+		if !force && s.moduleIsPinned(ctx, m) {
+			pinned = append(pinned, m.Version)
+		}
+	}
+	if len(pinned) > 0 {
+		respondError(w, http.StatusConflict, "module_pinned",
+			fmt.Sprintf("%s has pinned versions (%s); retry with force=true to delete them", address, strings.Join(pinned, ", ")))
+		return
+	}
+
+	deleted, err := s.moduleRepo.DeleteAllVersions(ctx, namespace, name, system)
+	if err != nil {
+		s.logAuditEvent(r, "delete_module_all", "module", address, false, err.Error(), nil)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to delete module")
+		return
+	}
+
+	// Versions with identical content may share one archive, which is only
+	// freed once no other module references it
+	keys := make([]string, 0, len(modules))
+	seen := make(map[string]bool, len(modules))
+	var freedBytes int64
+	for _, m := range modules {
+		if seen[m.S3Key] {
+			continue
+		}
+		seen[m.S3Key] = true
+		if s.releaseModuleArchive(ctx, m.S3Key) {
+			freedBytes += m.SizeBytes
+		}
+		keys = append(keys, m.S3Key)
+	}
+	s.evictCacheKeys(ctx, keys)
+
+	s.logAuditEvent(r, "delete_module_all", "module", address, true, "", map[string]interface{}{
+		"versions":    versions,
+		"deleted":     deleted,
+		"freed_bytes": freedBytes,
+		"force":       force,
+	})
+
+	respondJSON(w, http.StatusOK, DeleteAllModuleVersionsResponse{
+		Namespace:  namespace,
+		Name:       name,
+		System:     system,
+		Versions:   versions,
+		Deleted:    deleted,
+		FreedBytes: freedBytes,
+	})
+}
+
+// releaseModuleArchive deletes a module archive from storage once its last
+// version has been deleted and reports whether it was deleted. Archives shared
+// with remaining versions are kept. Failures are only logged, since the rows
+// are already gone.
+func (s *Server) releaseModuleArchive(ctx context.Context, key string) bool {
+	deleted, err := module.ReleaseArchive(ctx, s.storage, s.moduleRepo, key)
+	if err != nil {
+		s.logger.Printf("Warning: failed to delete storage object %s: %v", key, err)
+	}
+	return deleted
+}
+
+// moduleIsPinned reports whether a module archive was explicitly requested.
+// An archive whose metadata cannot be read is not protected.
+func (s *Server) moduleIsPinned(ctx context.Context, m *database.Module) bool {
+	metadata, err := s.storage.GetMetadata(ctx, m.S3Key)
+	if err != nil {
+		s.logger.Printf("Warning: failed to read metadata of %s: %v", m.S3Key, err)
+		return false
+	}
+	return metadata[storage.MetadataPinned] == "true"
+}
+
+// parseForce reads the optional force query parameter, responding with an
+// error and returning false when it is not a boolean
+func parseForce(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	v := r.URL.Query().Get("force")
+	if v == "" {
+		return false, true
+	}
+	force, err := strconv.ParseBool(v)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_query", "force must be true or false")
+		return false, false
+	}
+	return force, true
+}
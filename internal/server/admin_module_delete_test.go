@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+)
+
+func TestHandleDeleteModuleVersions(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+	ctx := context.Background()
+	token := getAuthToken(t, server)
+
+	// Versions of vpc; 1.2.0 was explicitly requested. eks is a bystander.
+	keys := make(map[string]string)
+	for _, m := range []struct {
+		name, version string
+		pinned        bool
+	}{
+		{"vpc", "1.0.0", false},
+		{"vpc", "1.1.0", false},
+		{"vpc", "1.2.0", true},
+		{"eks", "1.0.0", false},
+	} {
+		filename := m.name + "-" + m.version + ".tar.gz"
+		key := "modules/terraform-aws-modules/" + m.name + "/aws/" + m.version + "/" + filename
+		metadata := storage.ModuleMetadata("terraform-aws-modules", m.name, "aws", m.version, filename, "", m.pinned)
+		require.NoError(t, server.storage.Upload(ctx, key, strings.NewReader("module-archive"), "application/gzip", metadata))
+		require.NoError(t, server.moduleRepo.Create(ctx, &database.Module{
+			Namespace: "terraform-aws-modules",
+			Name:      m.name,
+			System:    "aws",
+			Version:   m.version,
+			S3Key:     key,
+			Filename:  filename,
+			SizeBytes: 100,
+		}))
+		keys[m.name+"/"+m.version] = key
+	}
+
+	remove := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, path, nil)
+		addAuthHeader(req, token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+	versionsOf := func(name string) []string {
+		modules, err := server.moduleRepo.ListVersions(ctx, "terraform-aws-modules", name, "aws")
+		require.NoError(t, err)
+		versions := make([]string, 0, len(modules))
+		for _, m := range modules {
+			versions = append(versions, m.Version)
+		}
+		return versions
+	}
+	stored := func(key string) bool {
+		exists, err := server.storage.Exists(ctx, key)
+		require.NoError(t, err)
+		return exists
+	}
+	errorCode := func(rr *httptest.ResponseRecorder) string {
+		var errResp ErrorResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&errResp))
+		return errResp.Error
+	}
+
+	t.Run("single version leaves siblings intact", func(t *testing.T) {
+		rr := remove("/admin/api/modules/terraform-aws-modules/vpc/aws/1.0.0")
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+
+		assert.ElementsMatch(t, []string{"1.1.0", "1.2.0"}, versionsOf("vpc"))
+		assert.False(t, stored(keys["vpc/1.0.0"]))
+		assert.True(t, stored(keys["vpc/1.1.0"]))
+		assert.True(t, stored(keys["vpc/1.2.0"]))
+
+		rr = remove("/admin/api/modules/terraform-aws-modules/vpc/aws/1.0.0")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("pinned version is protected", func(t *testing.T) {
+		rr := remove("/admin/api/modules/terraform-aws-modules/vpc/aws/1.2.0")
+		require.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+		assert.Equal(t, "module_pinned", errorCode(rr))
+
+		rr = remove("/admin/api/modules/terraform-aws-modules/vpc/aws")
+		require.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+		assert.Equal(t, "module_pinned", errorCode(rr))
+		assert.ElementsMatch(t, []string{"1.1.0", "1.2.0"}, versionsOf("vpc"))
+
+		rr = remove("/admin/api/modules/terraform-aws-modules/vpc/aws?force=maybe")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, "invalid_query", errorCode(rr))
+	})
+
+	t.Run("module-wide delete removes every version", func(t *testing.T) {
+		rr := remove("/admin/api/modules/terraform-aws-modules/vpc/aws?force=true")
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var resp DeleteAllModuleVersionsResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, int64(2), resp.Deleted)
+		assert.Equal(t, int64(200), resp.FreedBytes)
+		assert.ElementsMatch(t, []string{"1.1.0", "1.2.0"}, resp.Versions)
+
+		assert.Empty(t, versionsOf("vpc"))
+		assert.False(t, stored(keys["vpc/1.1.0"]))
+		assert.False(t, stored(keys["vpc/1.2.0"]))
+
+		// Other modules are untouched
+		assert.Equal(t, []string{"1.0.0"}, versionsOf("eks"))
+		assert.True(t, stored(keys["eks/1.0.0"]))
+
+		rr = remove("/admin/api/modules/terraform-aws-modules/vpc/aws")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestHandleDeleteModuleVersion_SharedArchive(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+	server.config.Modules.DeduplicateArchives = true
+	ctx := context.Background()
+	token := getAuthToken(t, server)
+
+	// Two versions uploaded with identical content share one archive
+	archive := moduleTarball(t, map[string]string{"main.tf": `variable "name" {}`})
+	var keys []string
+	for _, version := range []string{"1.0.0", "1.0.1"} {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, moduleUploadRequest(t, token, map[string]string{
+			"namespace": "example",
+			"name":      "network",
+			"system":    "aws",
+			"version":   version,
+		}, archive))
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		var created ModuleResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
+		assert.Len(t, created.ContentHash, 64)
+		keys = append(keys, created.S3Key)
+	}
+	require.Equal(t, keys[0], keys[1])
+	assert.True(t, strings.HasPrefix(keys[0], "blobs/sha256/"), keys[0])
+
+	objects, err := server.storage.ListObjects(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{keys[0]}, objects)
+
+	remove := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, path, nil)
+		addAuthHeader(req, token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+	stored := func() bool {
+		exists, err := server.storage.Exists(ctx, keys[0])
+		require.NoError(t, err)
+		return exists
+	}
+
+	// Deleting one version keeps the archive the other still serves
+	rr := remove("/admin/api/modules/example/network/aws/1.0.0?force=true")
+	require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	assert.True(t, stored())
+
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/blobs/"+keys[0], nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, archive, rr.Body.Bytes())
+
+	// The last reference takes the archive with it
+	rr = remove("/admin/api/modules/example/network/aws?force=true")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp DeleteAllModuleVersionsResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, int64(len(archive)), resp.FreedBytes)
+	assert.False(t, stored())
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/module"
+)
+
+// RewriteModuleResponse reports re-applying source rewriting to one module
+type RewriteModuleResponse struct {
+	Module         ModuleResponse `json:"module"`
+	Rewritten      bool           `json:"rewritten"`
+	MirrorHostname string         `json:"mirror_hostname"`
+}
+
+// RewriteModulesRequest selects the stored modules to rewrite. IDs take
+// precedence; otherwise the namespace, name and system filters are combined,
+// and an empty request rewrites every module.
+type RewriteModulesRequest struct {
+	IDs       []int64 `json:"ids,omitempty"`
+	Namespace string  `json:"namespace,omitempty"`
+	Name      string  `json:"name,omitempty"`
+	System    string  `json:"system,omitempty"`
+	Limit     int     `json:"limit,omitempty"`
+}
+
+// RewriteModulesResponse reports re-applying source rewriting to stored modules
+type RewriteModulesResponse struct {
+	MirrorHostname string              `json:"mirror_hostname"`
+	Checked        int64               `json:"checked"`
+	Rewritten      int64               `json:"rewritten"`
+	Unchanged      int64               `json:"unchanged"`
+	ErrorCount     int64               `json:"error_count"`
+	Modules        []ModuleRewriteInfo `json:"modules"`
+	Errors         []ModuleRewriteInfo `json:"errors"`
+	Limit          int                 `json:"limit"`
+	Truncated      bool                `json:"truncated"`
+}
+
+// ModuleRewriteInfo describes a module that was rewritten or failed to be
+type ModuleRewriteInfo struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name,omitempty"`
+	Version     string `json:"version,omitempty"`
+	StorageKey  string `json:"storage_key,omitempty"`
+	PreviousKey string `json:"previous_key,omitempty"`
+	SizeBytes   int64  `json:"size_bytes,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// moduleRewriteService returns a module service rewriting sources to the
+// currently configured mirror hostname
+func (s *Server) moduleRewriteService() *module.Service {
+	moduleSvc := module.NewService(s.storage, s.db, s.config.Modules.MirrorHostname)
+	moduleSvc.SetDeduplication(s.config.Modules.DeduplicateArchives)
+	return moduleSvc
+}
+
+// evictRewrittenArchive stops serving a cached copy of the archive a module
+// was rewritten from, and of the archive now stored, which may have been
+// overwritten in place
+func (s *Server) evictRewrittenArchive(ctx context.Context, result *module.RewriteResult) {
+	keys := []string{result.PreviousKey}
+	if result.Module.S3Key != result.PreviousKey {
+		keys = append(keys, result.Module.S3Key)
+	}
+	s.evictCacheKeys(ctx, keys)
+}
+
+// handleRewriteModule re-applies source rewriting with the current mirror
+// hostname to one stored module. The archive is read back from storage, not
+// downloaded from upstream again.
+// POST /admin/api/modules/{id}/rewrite
+func (s *Server) handleRewriteModule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_id", "Invalid module ID")
+		return
+	}
+
+	if s.config.Modules.MirrorHostname == "" {
+		respondError(w, http.StatusBadRequest, "rewrite_disabled", "No mirror_hostname is configured, so module sources are not rewritten")
+		return
+	}
+
+	m, err := s.moduleRepo.GetByID(ctx, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to get module")
+		return
+	}
+	if m == nil {
+		respondError(w, http.StatusNotFound, "not_found", "Module not found")
+		return
+	}
+
+	result, err := s.moduleRewriteService().RewriteStoredModule(ctx, m)
+	if err != nil {
+		s.logAuditEvent(r, "rewrite_module", "module", idStr, false, err.Error(), nil)
+		respondError(w, http.StatusInternalServerError, "rewrite_error", fmt.Sprintf("Failed to rewrite module: %v", err))
+		return
+	}
+
+	if result.Changed {
+		s.evictRewrittenArchive(ctx, result)
+	}
+
+	s.logAuditEvent(r, "rewrite_module", "module", idStr, true, "", map[string]interface{}{
+		"namespace":       m.Namespace,
+		"name":            m.Name,
+		"system":          m.System,
+		"version":         m.Version,
+		"rewritten":       result.Changed,
+		"mirror_hostname": s.config.Modules.MirrorHostname,
+	})
+
+	resp := RewriteModuleResponse{
+		Module:         moduleToResponse(result.Module),
+		Rewritten:      result.Changed,
+		MirrorHostname: s.config.Modules.MirrorHostname,
+	}
+	if labels, err := s.labelsOf(ctx, database.LabelModule, id); err == nil {
+		resp.Module.Labels = labels
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// handleRewriteModules re-applies source rewriting with the current mirror
+// hostname to every selected stored module, reading archives back from
+// storage rather than upstream. Modules whose sources already point at the
+// mirror are left untouched. Counts cover the full selection; the lists are
+// capped at limit.
+// POST /admin/api/modules/rewrite
+func (s *Server) handleRewriteModules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req RewriteModulesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+
+	if s.config.Modules.MirrorHostname == "" {
+		respondError(w, http.StatusBadRequest, "rewrite_disabled", "No mirror_hostname is configured, so module sources are not rewritten")
+		return
+	}
+
+	limit := defaultVerifyLimit
+	if req.Limit > 0 && req.Limit <= maxVerifyLimit {
+		limit = req.Limit
+	}
+
+	report := &RewriteModulesResponse{
+		MirrorHostname: s.config.Modules.MirrorHostname,
+		Modules:        []ModuleRewriteInfo{},
+		Errors:         []ModuleRewriteInfo{},
+		Limit:          limit,
+	}
+
+	addEntry := func(list *[]ModuleRewriteInfo, info ModuleRewriteInfo) {
+		if len(*list) < limit {
+			*list = append(*list, info)
+		} else {
+			report.Truncated = true
+		}
+	}
+
+	moduleSvc := s.moduleRewriteService()
+	rewrite := func(m *database.Module) {
+		report.Checked++
+		info := ModuleRewriteInfo{
+			ID:         m.ID,
+			Name:       m.Namespace + "/" + m.Name + "/" + m.System,
+			Version:    m.Version,
+			StorageKey: m.S3Key,
+		}
+
+		result, err := moduleSvc.RewriteStoredModule(ctx, m)
+		if err != nil {
+			report.ErrorCount++
+			info.Error = err.Error()
+			addEntry(&report.Errors, info)
+			return
+		}
+		if !result.Changed {
+			report.Unchanged++
+			return
+		}
+
+		s.evictRewrittenArchive(ctx, result)
+		report.Rewritten++
+		info.StorageKey = result.Module.S3Key
+		info.PreviousKey = result.PreviousKey
+		info.SizeBytes = result.Module.SizeBytes
+		addEntry(&report.Modules, info)
+	}
+
+	if err := s.selectRewriteModules(ctx, req, rewrite, func(id int64) {
+		report.ErrorCount++
+		addEntry(&report.Errors, ModuleRewriteInfo{ID: id, Error: "module not found"})
+	}); err != nil {
+		log.Printf("Error listing modules for source rewriting: %v", err)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list modules")
+		return
+	}
+
+	s.logAuditEvent(r, "rewrite_modules", "module", "", report.ErrorCount == 0, "", map[string]interface{}{
+		"checked":         report.Checked,
+		"rewritten":       report.Rewritten,
+		"errors":          report.ErrorCount,
+		"mirror_hostname": report.MirrorHostname,
+	})
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// selectRewriteModules calls rewrite for every module selected by req, and
+// notFound for requested IDs that do not exist. Pages are ordered by creation
+// time, which rewriting does not change.
+func (s *Server) selectRewriteModules(ctx context.Context, req RewriteModulesRequest, rewrite func(*database.Module), notFound func(int64)) error {
+	if len(req.IDs) > 0 {
+		for _, id := range req.IDs {
+			m, err := s.moduleRepo.GetByID(ctx, id)
+			if err != nil {
+				return fmt.Errorf("failed to get module %d: %w", id, err)
+			}
+			if m == nil {
+				notFound(id)
+				continue
+			}
+			rewrite(m)
+		}
+		return nil
+	}
+
+	filter := database.ModuleFilter{Namespace: req.Namespace, Name: req.Name, System: req.System}
+	for offset := 0; ; offset += verifyPageSize {
+		modules, err := s.moduleRepo.List(ctx, filter, verifyPageSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, m := range modules {
+			rewrite(m)
+		}
+		if len(modules) < verifyPageSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// servedMainTF downloads a module archive through the blob endpoint and
+// returns its main.tf
+func servedMainTF(t *testing.T, server *Server, key string) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	gzr, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		require.NoError(t, err, "main.tf not found")
+		if header.Name == "main.tf" {
+			data, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			return string(data)
+		}
+	}
+}
+
+func TestHandleRewriteModule(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+	token := getAuthToken(t, server)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		addAuthHeader(req, token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("requires a mirror hostname", func(t *testing.T) {
+		w := post("/admin/api/modules/1/rewrite", "")
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		var errResp ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+		assert.Equal(t, "rewrite_disabled", errResp.Error)
+	})
+
+	// Two modules uploaded while the mirror was served under its old hostname
+	server.config.Modules.MirrorHostname = "old-mirror.example.com"
+	archive := moduleTarball(t, map[string]string{
+		"main.tf": "module \"vpc\" {\n  source = \"terraform-aws-modules/vpc/aws\"\n}\n",
+	})
+	var uploaded []ModuleResponse
+	for _, name := range []string{"network", "platform"} {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, moduleUploadRequest(t, token, map[string]string{
+			"namespace": "example",
+			"name":      name,
+			"system":    "aws",
+			"version":   "1.0.0",
+		}, archive))
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var created ModuleResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+		assert.Contains(t, servedMainTF(t, server, created.S3Key), `"old-mirror.example.com/terraform-aws-modules/vpc/aws"`)
+		uploaded = append(uploaded, created)
+	}
+
+	server.config.Modules.MirrorHostname = "mirror.example.com"
+
+	t.Run("single module", func(t *testing.T) {
+		w := post("/admin/api/modules/"+strconv.FormatInt(uploaded[0].ID, 10)+"/rewrite", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp RewriteModuleResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.True(t, resp.Rewritten)
+		assert.Equal(t, "mirror.example.com", resp.MirrorHostname)
+		assert.Contains(t, servedMainTF(t, server, resp.Module.S3Key), `"mirror.example.com/terraform-aws-modules/vpc/aws"`)
+
+		size, err := server.storage.GetObjectSize(context.Background(), resp.Module.S3Key)
+		require.NoError(t, err)
+		assert.Equal(t, size, resp.Module.SizeBytes)
+
+		// Running it again finds nothing left to rewrite
+		w = post("/admin/api/modules/"+strconv.FormatInt(uploaded[0].ID, 10)+"/rewrite", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.False(t, resp.Rewritten)
+
+		w = post("/admin/api/modules/9999/rewrite", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("bulk", func(t *testing.T) {
+		w := post("/admin/api/modules/rewrite", `{"namespace":"example","ids":[]}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp RewriteModulesResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, int64(2), resp.Checked)
+		assert.Equal(t, int64(1), resp.Rewritten)
+		assert.Equal(t, int64(1), resp.Unchanged)
+		assert.Zero(t, resp.ErrorCount)
+		require.Len(t, resp.Modules, 1)
+		assert.Equal(t, uploaded[1].ID, resp.Modules[0].ID)
+		assert.Contains(t, servedMainTF(t, server, resp.Modules[0].StorageKey), `"mirror.example.com/terraform-aws-modules/vpc/aws"`)
+
+		w = post("/admin/api/modules/rewrite", `{"ids":[9999]}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, int64(1), resp.ErrorCount)
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, "module not found", resp.Errors[0].Error)
+
+		w = post("/admin/api/modules/rewrite", `{not json`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
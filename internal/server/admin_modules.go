@@ -4,11 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -33,11 +35,13 @@ type ModuleResponse struct {
 	S3Key             string    `json:"s3_key"`
 	Filename          string    `json:"filename"`
 	SizeBytes         int64     `json:"size_bytes"`
+	ContentHash       string    `json:"content_hash,omitempty"`
 	OriginalSourceURL string    `json:"original_source_url,omitempty"`
 	Deprecated        bool      `json:"deprecated"`
 	Blocked           bool      `json:"blocked"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	Labels            []string  `json:"labels"`
+	CreatedAt         Timestamp `json:"created_at"`
+	UpdatedAt         Timestamp `json:"updated_at"`
 }
 
 // ModuleListResponse represents a paginated list of modules
@@ -60,6 +64,12 @@ func (s *Server) handleLoadModules(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	key, err := idempotencyKey(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_idempotency_key", err.Error())
+		return
+	}
+
 	// Get the uploaded file
 	file, _, err := r.FormFile("file")
 	if err != nil {
@@ -98,6 +108,33 @@ func (s *Server) handleLoadModules(w http.ResponseWriter, r *http.Request) {
 	// Calculate total items (each module version)
 	totalItems := defs.CountItems()
 
+	// A retry with the same Idempotency-Key returns the job it already created
+	if key != "" {
+		s.idempotencyMu.Lock()
+		defer s.idempotencyMu.Unlock()
+
+		jobID, found, err := s.findIdempotentJob(r.Context(), "modules/load", key)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "database_error", "Failed to look up idempotency key")
+			return
+		}
+		if found {
+			w.Header().Set("Idempotent-Replayed", "true")
+			respondJSON(w, http.StatusAccepted, LoadModulesResponse{
+				JobID:   jobID,
+				Message: fmt.Sprintf("Module loading job %d already created for this Idempotency-Key", jobID),
+				Total:   len(defs.Modules),
+			})
+			return
+		}
+	}
+
+	s.backlogMu.Lock()
+	defer s.backlogMu.Unlock()
+	if !s.checkJobBacklog(w, r, totalItems) {
+		return
+	}
+
 	// Create download job for modules
 	job := &database.DownloadJob{
 		JobType:    "module",
@@ -151,6 +188,10 @@ func (s *Server) handleLoadModules(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if key != "" {
+		s.recordIdempotentJob(r.Context(), "modules/load", key, job.ID)
+	}
+
 	// Return response immediately - job will be processed in the background
 	response := LoadModulesResponse{
 		JobID:   job.ID,
@@ -167,9 +208,92 @@ func (s *Server) handleLoadModules(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// processModuleLoadJob handles module loading in the background
+// maxModuleUploadSize caps the size of a directly uploaded module tarball
+const maxModuleUploadSize = 100 << 20
+
+// handleUploadModule imports a module tarball without contacting the upstream registry
+// POST /admin/api/modules/upload
+// Accepts multipart/form-data with "namespace", "name", "system", "version", a
+// "file" field containing the .tar.gz archive, and an optional
+// "rewrite_sources" field ("false" skips module source rewriting)
+func (s *Server) handleUploadModule(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxModuleUploadSize)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_form", fmt.Sprintf("Failed to parse form data: %v", err))
+		return
+	}
+
+	namespace := strings.TrimSpace(r.FormValue("namespace"))
+	name := strings.TrimSpace(r.FormValue("name"))
+	system := strings.TrimSpace(r.FormValue("system"))
+	version := strings.TrimSpace(r.FormValue("version"))
+	if err := module.ValidateModuleIdentity(namespace, name, system, version); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_module", err.Error())
+		return
+	}
+
+	rewrite := true
+	if v := r.FormValue("rewrite_sources"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid_form", "rewrite_sources must be true or false")
+			return
+		}
+		rewrite = parsed
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "missing_file", fmt.Sprintf("No file uploaded: %v", err))
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "read_error", fmt.Sprintf("Failed to read file: %v", err))
+		return
+	}
+
+	if err := module.ValidateTarball(content); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_archive", fmt.Sprintf("Invalid module archive: %v", err))
+		return
+	}
+
+	moduleSvc := module.NewService(s.storage, s.db, s.config.Modules.MirrorHostname)
+	moduleSvc.SetDeduplication(s.config.Modules.DeduplicateArchives)
+	m, err := moduleSvc.ImportModule(r.Context(), namespace, name, system, version, content, rewrite)
+	if errors.Is(err, module.ErrModuleExists) {
+		respondError(w, http.StatusConflict, "already_exists",
+			fmt.Sprintf("Module %s/%s/%s %s already exists", namespace, name, system, version))
+		return
+	}
+	if err != nil {
+		resourceID := fmt.Sprintf("%s/%s/%s/%s", namespace, name, system, version)
+		s.logAuditEvent(r, "upload_module", "module", resourceID, false, err.Error(), nil)
+		respondError(w, http.StatusInternalServerError, "import_error", fmt.Sprintf("Failed to import module: %v", err))
+		return
+	}
+
+	s.logAuditEvent(r, "upload_module", "module", fmt.Sprintf("%d", m.ID), true, "", map[string]interface{}{
+		"namespace":       namespace,
+		"name":            name,
+		"system":          system,
+		"version":         version,
+		"size_bytes":      m.SizeBytes,
+		"rewrite_sources": rewrite,
+	})
+
+	respondJSON(w, http.StatusCreated, moduleToResponse(m))
+}
+
+// processModuleLoadJob handles module loading in the background. The load is
+// tracked by the processor so cancelling the job stops it before the next
+// module; job records are written with a context that outlives the load.
 func (s *Server) processModuleLoadJob(job *database.DownloadJob, defs *module.ModuleDefinitions, moduleJobRepo *database.ModuleJobRepository) {
 	bgCtx := context.Background()
+	loadCtx, done := s.processorService.TrackJob(bgCtx, job.ID)
+	defer done()
 
 	log.Printf("Starting module load job %d with %d items", job.ID, job.TotalItems)
 
@@ -180,11 +304,14 @@ func (s *Server) processModuleLoadJob(job *database.DownloadJob, defs *module.Mo
 		s.config.Modules.GetUpstreamRegistry(),
 		s.config.Modules.MirrorHostname,
 	)
+	moduleSvc.SetRetryPolicy(s.config.Modules.DownloadRetryAttempts, s.config.Modules.GetDownloadRetryDelay())
+	moduleSvc.SetMaxDownloadSize(s.config.Features.GetMaxDownloadSize())
+	moduleSvc.SetDeduplication(s.config.Modules.DeduplicateArchives)
 
 	// Track progress during processing
 	var completedCount, failedCount int
 
-	results, err := moduleSvc.LoadFromDefinitionsWithProgress(bgCtx, defs, func(result *module.LoadResult) {
+	results, err := moduleSvc.LoadFromDefinitionsWithProgress(loadCtx, defs, func(result *module.LoadResult) {
 		// Update job item status
 		items, listErr := moduleJobRepo.ListByJob(bgCtx, job.ID)
 		if listErr == nil {
@@ -216,34 +343,109 @@ func (s *Server) processModuleLoadJob(job *database.DownloadJob, defs *module.Mo
 		job.FailedItems = failedCount
 		s.jobRepo.Update(bgCtx, job)
 	})
+
+	s.finishModuleLoadJob(bgCtx, job, results, err, moduleJobRepo)
+}
+
+// finishModuleLoadJob records the outcome of a module load job. Per-module
+// results are kept even when loading stopped early with an error: items with a
+// result take its status, and items that were never reached are marked failed,
+// or cancelled along with the job if loading was cancelled. Otherwise the job
+// only fails outright when no module succeeded, or when its items cannot be
+// listed to record the results.
+func (s *Server) finishModuleLoadJob(ctx context.Context, job *database.DownloadJob, results []*module.LoadResult, loadErr error, moduleJobRepo *database.ModuleJobRepository) {
+	cancelled := errors.Is(loadErr, context.Canceled)
+
+	resultByKey := make(map[string]*module.LoadResult, len(results))
+	for _, result := range results {
+		key := fmt.Sprintf("%s/%s/%s/%s", result.Namespace, result.Name, result.System, result.Version)
+		resultByKey[key] = result
+	}
+
+	items, err := moduleJobRepo.ListByJob(ctx, job.ID)
 	if err != nil {
-		// Update job status to failed
+		// Without its items the outcome of each module cannot be recorded, so
+		// the job fails rather than completing with nothing to show for it
+		log.Printf("Failed to list items for module load job %d: %v", job.ID, err)
+		msg := fmt.Sprintf("failed to record module results: %v", err)
+		if loadErr != nil {
+			msg = fmt.Sprintf("%v; %s", loadErr, msg)
+		}
 		job.Status = "failed"
-		job.ErrorMessage = sql.NullString{String: err.Error(), Valid: true}
+		job.ErrorMessage = sql.NullString{String: msg, Valid: true}
 		job.CompletedAt = sql.NullTime{Time: time.Now(), Valid: true}
-		s.jobRepo.Update(bgCtx, job)
-		log.Printf("Module load job %d failed: %v", job.ID, err)
+		if err := s.jobRepo.Update(ctx, job); err != nil {
+			log.Printf("Failed to finalize job %d: %v", job.ID, err)
+		}
 		return
 	}
 
-	// Calculate final statistics (progress was updated incrementally)
-	stats := module.CalculateStats(results)
-	job.CompletedItems = stats.Success
-	job.FailedItems = stats.Failed
+	var completed, failed int
+	for _, item := range items {
+		key := fmt.Sprintf("%s/%s/%s/%s", item.Namespace, item.Name, item.System, item.Version)
+		result, ok := resultByKey[key]
+
+		switch {
+		case ok && result.Success:
+			item.Status = "completed"
+			item.ErrorMessage = sql.NullString{}
+			completed++
+		case ok && result.Error != nil:
+			item.Status = "failed"
+			item.ErrorMessage = sql.NullString{String: result.Error.Error(), Valid: true}
+			failed++
+		case cancelled:
+			// Loading was cancelled before this item finished
+			item.Status = "cancelled"
+			item.ErrorMessage = sql.NullString{String: "job cancelled", Valid: true}
+		default:
+			// Never processed; loading stopped before reaching this item
+			reason := "module was not processed"
+			if loadErr != nil {
+				reason = fmt.Sprintf("module was not processed: %v", loadErr)
+			}
+			item.Status = "failed"
+			item.ErrorMessage = sql.NullString{String: reason, Valid: true}
+			failed++
+		}
+
+		if err := moduleJobRepo.UpdateItem(ctx, item); err != nil {
+			log.Printf("Failed to update module job item %d: %v", item.ID, err)
+		}
+	}
+
+	job.CompletedItems = completed
+	job.FailedItems = failed
 	job.Progress = 100
-	job.Status = "completed"
 	job.CompletedAt = sql.NullTime{Time: time.Now(), Valid: true}
 
-	if err := s.jobRepo.Update(bgCtx, job); err != nil {
+	switch {
+	case cancelled:
+		job.Status = "cancelled"
+	case completed == 0 && failed > 0:
+		job.Status = "failed"
+	default:
+		job.Status = "completed"
+	}
+
+	if cancelled {
+		job.ErrorMessage = sql.NullString{String: fmt.Sprintf("job cancelled: %v", loadErr), Valid: true}
+	} else if loadErr != nil {
+		job.ErrorMessage = sql.NullString{String: loadErr.Error(), Valid: true}
+	} else if failed > 0 {
+		job.ErrorMessage = sql.NullString{String: fmt.Sprintf("%d of %d modules failed", failed, completed+failed), Valid: true}
+	}
+
+	if err := s.jobRepo.Update(ctx, job); err != nil {
 		log.Printf("Failed to finalize job %d: %v", job.ID, err)
 		return
 	}
 
-	log.Printf("Module load job %d completed: %d success, %d failed", job.ID, stats.Success, stats.Failed)
+	log.Printf("Module load job %d %s: %d success, %d failed", job.ID, job.Status, completed, failed)
 }
 
-// handleListModules lists all modules with pagination
-// GET /admin/api/modules
+// handleListModules lists all modules with pagination and sorting
+// GET /admin/api/modules?page=1&page_size=20&sort=size_bytes&order=desc
 func (s *Server) handleListModules(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -257,13 +459,22 @@ func (s *Server) handleListModules(w http.ResponseWriter, r *http.Request) {
 		pageSize = 20
 	}
 
-	// Get query parameters for filtering
-	namespace := r.URL.Query().Get("namespace")
-	name := r.URL.Query().Get("name")
-	system := r.URL.Query().Get("system")
+	// Filters are applied in SQL so they compose with pagination
+	filter := database.ModuleFilter{
+		Namespace: r.URL.Query().Get("namespace"),
+		Name:      r.URL.Query().Get("name"),
+		System:    r.URL.Query().Get("system"),
+		Label:     strings.ToLower(r.URL.Query().Get("label")),
+	}
+
+	order, err := requestSort(r, database.ModuleSortFields)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_sort", err.Error())
+		return
+	}
 
 	// Get total count for pagination
-	total, err := s.moduleRepo.Count(ctx)
+	total, err := s.moduleRepo.Count(ctx, filter)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "database_error", "Failed to count modules")
 		return
@@ -273,34 +484,29 @@ func (s *Server) handleListModules(w http.ResponseWriter, r *http.Request) {
 	offset := (page - 1) * pageSize
 
 	// Get modules from database
-	modules, err := s.moduleRepo.List(ctx, pageSize, offset)
+	modules, err := s.moduleRepo.ListSorted(ctx, filter, order, pageSize, offset)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list modules")
 		return
 	}
 
-	// Filter if parameters provided
-	if namespace != "" || name != "" || system != "" {
-		filtered := make([]*database.Module, 0)
-		for _, m := range modules {
-			if namespace != "" && m.Namespace != namespace {
-				continue
-			}
-			if name != "" && m.Name != name {
-				continue
-			}
-			if system != "" && m.System != system {
-				continue
-			}
-			filtered = append(filtered, m)
-		}
-		modules = filtered
+	ids := make([]int64, len(modules))
+	for i, m := range modules {
+		ids[i] = m.ID
+	}
+	labels, err := s.labelRepo.List(ctx, database.LabelModule, ids)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list module labels")
+		return
 	}
 
 	// Convert to response format
 	moduleResponses := make([]ModuleResponse, len(modules))
 	for i, m := range modules {
 		moduleResponses[i] = moduleToResponse(m)
+		if labels[m.ID] != nil {
+			moduleResponses[i].Labels = labels[m.ID]
+		}
 	}
 
 	// Calculate total pages
@@ -342,7 +548,14 @@ func (s *Server) handleGetModule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, moduleToResponse(m))
+	resp := moduleToResponse(m)
+	resp.Labels, err = s.labelsOf(ctx, database.LabelModule, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list module labels")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, resp)
 }
 
 // handleUpdateModule updates a module's metadata
@@ -400,7 +613,12 @@ func (s *Server) handleUpdateModule(w http.ResponseWriter, r *http.Request) {
 		"blocked":    m.Blocked,
 	})
 
-	respondJSON(w, http.StatusOK, moduleToResponse(m))
+	resp := moduleToResponse(m)
+	if labels, err := s.labelsOf(ctx, database.LabelModule, id); err == nil {
+		resp.Labels = labels
+	}
+
+	respondJSON(w, http.StatusOK, resp)
 }
 
 // handleDeleteModule deletes a module
@@ -428,18 +646,19 @@ func (s *Server) handleDeleteModule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete from storage first
-	if err := s.storage.Delete(ctx, m.S3Key); err != nil {
-		s.logger.Printf("Warning: Failed to delete module from storage: %v", err)
-		// Continue anyway - database record is more important to delete
-	}
-
-	// Delete from database
+	// Delete from database first, so the archive's remaining references can
+	// be counted
 	if err := s.moduleRepo.Delete(ctx, id); err != nil {
 		respondError(w, http.StatusInternalServerError, "database_error", "Failed to delete module")
 		return
 	}
 
+	// Delete from storage unless another version shares the archive
+	s.releaseModuleArchive(ctx, m.S3Key)
+
+	// Stop serving a cached copy of the deleted archive
+	s.evictCacheKeys(ctx, []string{m.S3Key})
+
 	// Log audit event
 	s.logAuditEvent(r, "delete_module", "module", idStr, true, "", map[string]interface{}{
 		"namespace": m.Namespace,
@@ -464,8 +683,12 @@ func moduleToResponse(m *database.Module) ModuleResponse {
 		SizeBytes:  m.SizeBytes,
 		Deprecated: m.Deprecated,
 		Blocked:    m.Blocked,
-		CreatedAt:  m.CreatedAt,
-		UpdatedAt:  m.UpdatedAt,
+		Labels:     []string{},
+		CreatedAt:  Timestamp{m.CreatedAt},
+		UpdatedAt:  Timestamp{m.UpdatedAt},
+	}
+	if m.ContentHash.Valid {
+		resp.ContentHash = m.ContentHash.String
 	}
 	if m.OriginalSourceURL.Valid {
 		resp.OriginalSourceURL = m.OriginalSourceURL.String
@@ -0,0 +1,383 @@
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/module"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createModuleLoadJob creates a running module job with one item per version
+func createModuleLoadJob(t *testing.T, srv *Server, versions ...string) *database.DownloadJob {
+	ctx := context.Background()
+
+	job := &database.DownloadJob{
+		JobType:    "module",
+		SourceType: "hcl",
+		Status:     "running",
+		TotalItems: len(versions),
+		CreatedAt:  time.Now(),
+	}
+	require.NoError(t, srv.jobRepo.Create(ctx, job))
+
+	moduleJobRepo := database.NewModuleJobRepository(srv.db)
+	for _, version := range versions {
+		require.NoError(t, moduleJobRepo.CreateItem(ctx, &database.ModuleJobItem{
+			JobID:     job.ID,
+			Namespace: "terraform-aws-modules",
+			Name:      "vpc",
+			System:    "aws",
+			Version:   version,
+			Status:    "pending",
+		}))
+	}
+
+	return job
+}
+
+func loadResult(version string, err error) *module.LoadResult {
+	return &module.LoadResult{
+		Namespace: "terraform-aws-modules",
+		Name:      "vpc",
+		System:    "aws",
+		Version:   version,
+		Success:   err == nil,
+		Error:     err,
+	}
+}
+
+func TestFinishModuleLoadJob_PartialFailure(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	job := createModuleLoadJob(t, srv, "5.0.0", "5.1.0")
+	moduleJobRepo := database.NewModuleJobRepository(srv.db)
+
+	results := []*module.LoadResult{
+		loadResult("5.0.0", nil),
+		loadResult("5.1.0", errors.New("download failed: not found")),
+	}
+	srv.finishModuleLoadJob(ctx, job, results, errors.New("1 module failed"), moduleJobRepo)
+
+	updated, err := srv.jobRepo.GetByID(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", updated.Status)
+	assert.Equal(t, 1, updated.CompletedItems)
+	assert.Equal(t, 1, updated.FailedItems)
+	assert.Equal(t, 100, updated.Progress)
+
+	items, err := moduleJobRepo.ListByJob(ctx, job.ID)
+	require.NoError(t, err)
+	statuses := map[string]string{}
+	for _, item := range items {
+		statuses[item.Version] = item.Status
+	}
+	assert.Equal(t, "completed", statuses["5.0.0"])
+	assert.Equal(t, "failed", statuses["5.1.0"])
+}
+
+func TestFinishModuleLoadJob_AllFailed(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	job := createModuleLoadJob(t, srv, "5.0.0", "5.1.0")
+	moduleJobRepo := database.NewModuleJobRepository(srv.db)
+
+	results := []*module.LoadResult{
+		loadResult("5.0.0", errors.New("download failed")),
+		loadResult("5.1.0", errors.New("download failed")),
+	}
+	srv.finishModuleLoadJob(ctx, job, results, nil, moduleJobRepo)
+
+	updated, err := srv.jobRepo.GetByID(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "failed", updated.Status)
+	assert.Equal(t, 0, updated.CompletedItems)
+	assert.Equal(t, 2, updated.FailedItems)
+}
+
+func TestFinishModuleLoadJob_ListItemsFails(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	job := createModuleLoadJob(t, srv, "5.0.0")
+	moduleJobRepo := database.NewModuleJobRepository(srv.db)
+
+	// The items can no longer be read back
+	_, err := srv.db.Conn().Exec("DROP TABLE module_job_items")
+	require.NoError(t, err)
+
+	results := []*module.LoadResult{loadResult("5.0.0", nil)}
+	srv.finishModuleLoadJob(ctx, job, results, nil, moduleJobRepo)
+
+	updated, err := srv.jobRepo.GetByID(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "failed", updated.Status)
+	assert.Contains(t, updated.ErrorMessage.String, "failed to record module results")
+	assert.True(t, updated.CompletedAt.Valid)
+}
+
+func TestFinishModuleLoadJob_StoppedEarly(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	job := createModuleLoadJob(t, srv, "5.0.0", "5.1.0")
+	moduleJobRepo := database.NewModuleJobRepository(srv.db)
+
+	// Loading was cancelled after the first module
+	results := []*module.LoadResult{loadResult("5.0.0", nil)}
+	srv.finishModuleLoadJob(ctx, job, results, context.Canceled, moduleJobRepo)
+
+	updated, err := srv.jobRepo.GetByID(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", updated.Status)
+	assert.Equal(t, 1, updated.CompletedItems)
+	assert.Equal(t, 0, updated.FailedItems)
+	assert.Contains(t, updated.ErrorMessage.String, "context canceled")
+
+	// The module that was never loaded is cancelled rather than failed
+	items, err := moduleJobRepo.ListByJob(ctx, job.ID)
+	require.NoError(t, err)
+	statuses := map[string]string{}
+	for _, item := range items {
+		statuses[item.Version] = item.Status
+	}
+	assert.Equal(t, "completed", statuses["5.0.0"])
+	assert.Equal(t, "cancelled", statuses["5.1.0"])
+}
+
+func TestFinishModuleLoadJob_StoppedByError(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	job := createModuleLoadJob(t, srv, "5.0.0", "5.1.0")
+	moduleJobRepo := database.NewModuleJobRepository(srv.db)
+
+	// Loading stopped after the first module for a reason other than cancellation
+	results := []*module.LoadResult{loadResult("5.0.0", nil)}
+	srv.finishModuleLoadJob(ctx, job, results, context.DeadlineExceeded, moduleJobRepo)
+
+	updated, err := srv.jobRepo.GetByID(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", updated.Status)
+	assert.Equal(t, 1, updated.CompletedItems)
+	assert.Equal(t, 1, updated.FailedItems)
+	assert.Contains(t, updated.ErrorMessage.String, "deadline exceeded")
+}
+
+// moduleTarball builds a gzipped tarball containing the given files
+func moduleTarball(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+	return buf.Bytes()
+}
+
+// moduleUploadRequest builds a multipart module upload request
+func moduleUploadRequest(t *testing.T, token string, fields map[string]string, archive []byte) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		require.NoError(t, mw.WriteField(k, v))
+	}
+	if archive != nil {
+		part, err := mw.CreateFormFile("file", "module.tar.gz")
+		require.NoError(t, err)
+		_, err = part.Write(archive)
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/api/modules/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	addAuthHeader(req, token)
+	return req
+}
+
+func TestHandleUploadModule(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	token := getAuthToken(t, server)
+	archive := moduleTarball(t, map[string]string{
+		"main.tf":      `resource "null_resource" "this" {}`,
+		"variables.tf": `variable "name" {}`,
+	})
+	fields := map[string]string{
+		"namespace": "example",
+		"name":      "network",
+		"system":    "aws",
+		"version":   "1.2.0",
+	}
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, moduleUploadRequest(t, token, fields, archive))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var created ModuleResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.Equal(t, "modules/example/network/aws/1.2.0/example-network-aws-1.2.0.tar.gz", created.S3Key)
+	assert.Equal(t, int64(len(archive)), created.SizeBytes)
+
+	t.Run("listable", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api/modules", nil)
+		addAuthHeader(req, token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var list ModuleListResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+		require.Len(t, list.Modules, 1)
+		assert.Equal(t, created.ID, list.Modules[0].ID)
+	})
+
+	t.Run("downloadable", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/modules/example/network/aws/1.2.0/download", nil))
+		require.Equal(t, http.StatusNoContent, w.Code)
+		assert.NotEmpty(t, w.Header().Get("X-Terraform-Get"))
+
+		w = httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+created.S3Key, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, archive, w.Body.Bytes())
+	})
+
+	t.Run("existing identity", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, moduleUploadRequest(t, token, fields, archive))
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("invalid archive", func(t *testing.T) {
+		f := map[string]string{"namespace": "example", "name": "network", "system": "aws", "version": "1.3.0"}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, moduleUploadRequest(t, token, f, []byte("not a tarball")))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_archive")
+	})
+
+	t.Run("invalid version", func(t *testing.T) {
+		f := map[string]string{"namespace": "example", "name": "network", "system": "aws", "version": "latest"}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, moduleUploadRequest(t, token, f, archive))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_module")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		f := map[string]string{"namespace": "example", "name": "network", "system": "aws", "version": "1.4.0"}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, moduleUploadRequest(t, token, f, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandleListModules_FilteredPagination(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, version := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+		for _, name := range []string{"vpc", "eks"} {
+			require.NoError(t, server.moduleRepo.Create(ctx, &database.Module{
+				Namespace: "terraform-aws-modules",
+				Name:      name,
+				System:    "aws",
+				Version:   version,
+				S3Key:     "modules/" + name + "-" + version + ".tar.gz",
+				Filename:  name + "-" + version + ".tar.gz",
+			}))
+		}
+	}
+
+	token := getAuthToken(t, server)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/modules?name=vpc&page=1&page_size=2", nil)
+	addAuthHeader(req, token)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp ModuleListResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, 3, resp.Total)
+	assert.Equal(t, 2, resp.TotalPages)
+	require.Len(t, resp.Modules, 2)
+	for _, m := range resp.Modules {
+		assert.Equal(t, "vpc", m.Name)
+	}
+}
+
+func TestHandleListModules_Sorted(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, m := range []struct {
+		version string
+		size    int64
+	}{{"1.0.0", 200}, {"1.1.0", 900}, {"1.2.0", 500}} {
+		require.NoError(t, server.moduleRepo.Create(ctx, &database.Module{
+			Namespace: "terraform-aws-modules",
+			Name:      "vpc",
+			System:    "aws",
+			Version:   m.version,
+			S3Key:     "modules/vpc-" + m.version + ".tar.gz",
+			Filename:  "vpc-" + m.version + ".tar.gz",
+			SizeBytes: m.size,
+		}))
+	}
+
+	token := getAuthToken(t, server)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/modules?sort=size_bytes&order=desc", nil)
+	addAuthHeader(req, token)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp ModuleListResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Modules, 3)
+	assert.Equal(t, "1.1.0", resp.Modules[0].Version)
+	assert.Equal(t, "1.2.0", resp.Modules[1].Version)
+	assert.Equal(t, "1.0.0", resp.Modules[2].Version)
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/api/modules?sort=filename", nil)
+	addAuthHeader(req, token)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_sort")
+}
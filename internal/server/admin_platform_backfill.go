@@ -0,0 +1,210 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/config"
+	"github.com/ned1313/terraform-mirror/internal/database"
+)
+
+// BackfillPlatformRequest asks for a platform to be added to every mirrored provider version
+type BackfillPlatformRequest struct {
+	Platform string `json:"platform"`
+}
+
+// BackfillPlatformResponse describes the job created by a platform backfill
+type BackfillPlatformResponse struct {
+	JobID           int64    `json:"job_id,omitempty"`
+	Message         string   `json:"message"`
+	Platform        string   `json:"platform"`
+	Versions        int      `json:"versions"`
+	Queued          int      `json:"queued"`
+	AlreadyMirrored int      `json:"already_mirrored"`
+	NotPublished    int      `json:"not_published"`
+	NotAllowed      int      `json:"not_allowed"`
+	Errors          []string `json:"errors,omitempty"`
+}
+
+// handleBackfillPlatform enqueues a download job for a platform on every
+// mirrored provider version that lacks it. Versions the upstream registry does
+// not publish for the platform, blocked versions and namespaces outside the
+// auto-download allow list are skipped.
+// POST /admin/api/providers/backfill-platform
+func (s *Server) handleBackfillPlatform(w http.ResponseWriter, r *http.Request) {
+	var req BackfillPlatformRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+
+	platform := strings.TrimSpace(req.Platform)
+	if !config.IsValidPlatform(platform) {
+		respondError(w, http.StatusBadRequest, "invalid_platforms",
+			fmt.Sprintf("invalid platform %q, expected 'os_arch' (e.g., linux_amd64)", req.Platform))
+		return
+	}
+
+	// A backfill only adds data, so refuse it outright once the quota is used up
+	if s.config.Quota.Enabled {
+		used, err := s.storageUsedBytes(r)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "database_error", "Failed to compute storage usage")
+			return
+		}
+		if limit := int64(s.config.Quota.MaxStorageGB) << 30; used >= limit {
+			respondError(w, http.StatusInsufficientStorage, "quota_exceeded",
+				fmt.Sprintf("Storage quota reached (%d of %d bytes used)", used, limit))
+			return
+		}
+	}
+
+	names, err := s.providerRepo.ListNames(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list mirrored providers")
+		return
+	}
+
+	response := BackfillPlatformResponse{Platform: platform}
+	var items []*database.DownloadJobItem
+	for _, name := range names {
+		stored, err := s.providerRepo.ListVersions(r.Context(), name.Namespace, name.Type)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "database_error", "Failed to list mirrored versions")
+			return
+		}
+
+		// Group the mirrored platforms by version, in the repository's order
+		var versions []string
+		mirrored := make(map[string]bool)
+		blocked := make(map[string]bool)
+		for _, p := range stored {
+			if _, seen := mirrored[p.Version]; !seen {
+				versions = append(versions, p.Version)
+				mirrored[p.Version] = false
+			}
+			if p.Platform == platform {
+				mirrored[p.Version] = true
+			}
+			if p.Blocked {
+				blocked[p.Version] = true
+			}
+		}
+		response.Versions += len(versions)
+
+		if s.config.AutoDownload != nil && !s.config.AutoDownload.IsProviderAllowed(name.Namespace, name.Type) {
+			response.NotAllowed += len(versions)
+			continue
+		}
+
+		var missing []string
+		for _, version := range versions {
+			switch {
+			case mirrored[version]:
+				response.AlreadyMirrored++
+			case blocked[version]:
+				response.NotAllowed++
+			default:
+				missing = append(missing, version)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		upstream, err := s.providerPlatforms.GetVersionPlatforms(r.Context(), name.Namespace, name.Type)
+		if err != nil {
+			log.Printf("Error listing upstream platforms for %s/%s: %v", name.Namespace, name.Type, err)
+			response.Errors = append(response.Errors, fmt.Sprintf("%s/%s: failed to list upstream platforms", name.Namespace, name.Type))
+			continue
+		}
+
+		for _, version := range missing {
+			if !slices.Contains(upstream[version], platform) {
+				response.NotPublished++
+				continue
+			}
+			items = append(items, &database.DownloadJobItem{
+				Namespace: name.Namespace,
+				Type:      name.Type,
+				Version:   version,
+				Platform:  platform,
+				Status:    "pending",
+			})
+		}
+	}
+
+	if len(items) > maxMirrorAllItems {
+		respondError(w, http.StatusBadRequest, "too_many_items",
+			fmt.Sprintf("Backfilling %s would create %d items (limit %d)", platform, len(items), maxMirrorAllItems))
+		return
+	}
+
+	response.Queued = len(items)
+	if len(items) == 0 {
+		response.Message = fmt.Sprintf("No mirrored versions need %s", platform)
+		respondJSON(w, http.StatusOK, response)
+		return
+	}
+
+	// Create a pending job; the background processor picks it up
+	job := &database.DownloadJob{
+		JobType:    "provider",
+		SourceType: "platform_backfill",
+		SourceData: fmt.Sprintf("%s: %d versions", platform, len(items)),
+		Status:     "pending",
+		TotalItems: len(items),
+		CreatedAt:  time.Now(),
+	}
+	if userID, ok := r.Context().Value(userIDKey).(int64); ok {
+		job.UserID = sql.NullInt64{Int64: userID, Valid: true}
+	}
+
+	if err := s.jobRepo.Create(r.Context(), job); err != nil {
+		respondError(w, http.StatusInternalServerError, "job_creation_error",
+			fmt.Sprintf("Failed to create job: %v", err))
+		return
+	}
+
+	for _, item := range items {
+		item.JobID = job.ID
+		if err := s.jobRepo.CreateItem(r.Context(), item); err != nil {
+			respondError(w, http.StatusInternalServerError, "job_item_error",
+				fmt.Sprintf("Failed to create job item: %v", err))
+			return
+		}
+	}
+
+	s.jobRepo.NotifyEnqueued()
+
+	s.logAuditEvent(r, "backfill_platform", "job", fmt.Sprintf("%d", job.ID), true, "", map[string]interface{}{
+		"platform":         platform,
+		"queued":           len(items),
+		"already_mirrored": response.AlreadyMirrored,
+		"not_published":    response.NotPublished,
+		"not_allowed":      response.NotAllowed,
+	})
+
+	response.JobID = job.ID
+	response.Message = fmt.Sprintf("Backfill job created: %s for %d versions", platform, len(items))
+	respondJSON(w, http.StatusAccepted, response)
+}
+
+// storageUsedBytes returns the bytes stored across providers and modules
+func (s *Server) storageUsedBytes(r *http.Request) (int64, error) {
+	providerStats, err := s.providerRepo.GetStorageStats(r.Context())
+	if err != nil {
+		return 0, err
+	}
+	moduleStats, err := s.moduleRepo.GetStorageStats(r.Context())
+	if err != nil {
+		return 0, err
+	}
+	return providerStats.TotalSizeBytes + moduleStats.TotalSizeBytes, nil
+}
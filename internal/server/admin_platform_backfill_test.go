@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/config"
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// platformsRegistry serves upstream version platforms keyed by "namespace/type"
+type platformsRegistry map[string]map[string][]string
+
+func (r platformsRegistry) GetVersionPlatforms(ctx context.Context, namespace, providerType string) (map[string][]string, error) {
+	platforms, ok := r[namespace+"/"+providerType]
+	if !ok {
+		return nil, fmt.Errorf("provider not found: %s/%s", namespace, providerType)
+	}
+	return platforms, nil
+}
+
+func TestHandleBackfillPlatform(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	server.config.AutoDownload = &config.AutoDownloadConfig{BlockedNamespaces: []string{"acme"}}
+	server.providerPlatforms = platformsRegistry{
+		"hashicorp/random": {
+			"2.0.0": {"linux_amd64"},
+			"3.0.0": {"linux_amd64", "darwin_arm64"},
+			"3.1.0": {"linux_amd64", "darwin_arm64"},
+			"3.2.0": {"linux_amd64", "darwin_arm64"},
+		},
+		"hashicorp/aws": {
+			"5.0.0": {"linux_amd64", "darwin_arm64"},
+		},
+		"acme/widget": {
+			"1.0.0": {"linux_amd64", "darwin_arm64"},
+		},
+	}
+
+	ctx := context.Background()
+	for _, p := range []database.Provider{
+		{Namespace: "hashicorp", Type: "random", Version: "2.0.0", Platform: "linux_amd64"},
+		{Namespace: "hashicorp", Type: "random", Version: "3.0.0", Platform: "linux_amd64"},
+		{Namespace: "hashicorp", Type: "random", Version: "3.1.0", Platform: "linux_amd64"},
+		{Namespace: "hashicorp", Type: "random", Version: "3.1.0", Platform: "darwin_arm64"},
+		{Namespace: "hashicorp", Type: "random", Version: "3.2.0", Platform: "linux_amd64", Blocked: true},
+		{Namespace: "hashicorp", Type: "aws", Version: "5.0.0", Platform: "linux_amd64"},
+		{Namespace: "acme", Type: "widget", Version: "1.0.0", Platform: "linux_amd64"},
+	} {
+		p.Filename = "terraform-provider-" + p.Type + "_" + p.Version + "_" + p.Platform + ".zip"
+		p.S3Key = "providers/registry.terraform.io/" + p.Namespace + "/" + p.Type + "/" + p.Version + "/" + p.Platform + "/" + p.Filename
+		require.NoError(t, server.providerRepo.Create(ctx, &p))
+	}
+
+	token := getAuthToken(t, server)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/api/providers/backfill-platform", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		addAuthHeader(req, token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("enqueues missing published versions", func(t *testing.T) {
+		w := post(`{"platform": "darwin_arm64"}`)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+		var resp BackfillPlatformResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.NotZero(t, resp.JobID)
+		assert.Equal(t, 6, resp.Versions)
+		assert.Equal(t, 2, resp.Queued)
+		assert.Equal(t, 1, resp.AlreadyMirrored) // random 3.1.0
+		assert.Equal(t, 1, resp.NotPublished)    // random 2.0.0
+		assert.Equal(t, 2, resp.NotAllowed)      // blocked random 3.2.0, acme namespace
+		assert.Empty(t, resp.Errors)
+
+		items, err := server.jobRepo.GetItems(ctx, resp.JobID)
+		require.NoError(t, err)
+
+		var tuples []string
+		for _, item := range items {
+			tuples = append(tuples, item.Namespace+"/"+item.Type+" "+item.Version+" "+item.Platform)
+		}
+		assert.ElementsMatch(t, []string{
+			"hashicorp/random 3.0.0 darwin_arm64",
+			"hashicorp/aws 5.0.0 darwin_arm64",
+		}, tuples)
+
+		job, err := server.jobRepo.GetByID(ctx, resp.JobID)
+		require.NoError(t, err)
+		assert.Equal(t, "pending", job.Status)
+		assert.Equal(t, "platform_backfill", job.SourceType)
+	})
+
+	t.Run("nothing missing", func(t *testing.T) {
+		w := post(`{"platform": "linux_amd64"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp BackfillPlatformResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Zero(t, resp.JobID)
+		assert.Equal(t, 0, resp.Queued)
+	})
+
+	t.Run("quota reached", func(t *testing.T) {
+		server.config.Quota = config.QuotaConfig{Enabled: true, MaxStorageGB: 1}
+		defer func() { server.config.Quota = config.QuotaConfig{} }()
+		require.NoError(t, server.providerRepo.Create(ctx, &database.Provider{
+			Namespace: "hashicorp",
+			Type:      "huge",
+			Version:   "1.0.0",
+			Platform:  "linux_amd64",
+			Filename:  "terraform-provider-huge_1.0.0_linux_amd64.zip",
+			S3Key:     "providers/registry.terraform.io/hashicorp/huge/1.0.0/linux_amd64/terraform-provider-huge_1.0.0_linux_amd64.zip",
+			SizeBytes: 2 << 30,
+		}))
+
+		w := post(`{"platform": "darwin_arm64"}`)
+		assert.Equal(t, http.StatusInsufficientStorage, w.Code)
+		assert.Contains(t, w.Body.String(), "quota_exceeded")
+	})
+
+	t.Run("invalid platform", func(t *testing.T) {
+		w := post(`{"platform": "darwin"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_platforms")
+	})
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ned1313/terraform-mirror/internal/config"
+	"github.com/ned1313/terraform-mirror/internal/database"
+)
+
+// PlatformCompletenessResponse lists mirrored and missing platforms for a provider version
+type PlatformCompletenessResponse struct {
+	Namespace string   `json:"namespace"`
+	Type      string   `json:"type"`
+	Version   string   `json:"version"`
+	Desired   []string `json:"desired"`
+	Mirrored  []string `json:"mirrored"`
+	Missing   []string `json:"missing"`
+	Complete  bool     `json:"complete"`
+}
+
+// FillPlatformsResponse represents the response after enqueuing missing platforms
+type FillPlatformsResponse struct {
+	JobID   int64    `json:"job_id,omitempty"`
+	Message string   `json:"message"`
+	Queued  []string `json:"queued"`
+}
+
+// requestPlatforms returns the platforms for a request: the comma-separated
+// override if one was given, otherwise the configured provider platforms
+func (s *Server) requestPlatforms(override string) ([]string, error) {
+	if strings.TrimSpace(override) == "" {
+		return s.config.Providers.GetPlatforms(), nil
+	}
+
+	var platforms []string
+	for _, platform := range strings.Split(override, ",") {
+		platform = strings.TrimSpace(platform)
+		if platform == "" {
+			continue
+		}
+		if !config.IsValidPlatform(platform) {
+			return nil, fmt.Errorf("invalid platform %q, expected 'os_arch' (e.g., linux_amd64)", platform)
+		}
+		platforms = append(platforms, platform)
+	}
+	if len(platforms) == 0 {
+		return nil, fmt.Errorf("at least one platform is required")
+	}
+	return platforms, nil
+}
+
+// platformCompleteness compares the mirrored platforms of a provider version
+// against the desired set. It returns nil when the version is not mirrored.
+func (s *Server) platformCompleteness(r *http.Request, namespace, providerType, version string, desired []string) (*PlatformCompletenessResponse, error) {
+	providers, err := s.providerRepo.ListVersions(r.Context(), namespace, providerType)
+	if err != nil {
+		return nil, err
+	}
+
+	mirroredSet := make(map[string]bool)
+	for _, p := range providers {
+		if p.Version == version {
+			mirroredSet[p.Platform] = true
+		}
+	}
+	if len(mirroredSet) == 0 {
+		return nil, nil
+	}
+
+	mirrored := make([]string, 0, len(mirroredSet))
+	for platform := range mirroredSet {
+		mirrored = append(mirrored, platform)
+	}
+	sort.Strings(mirrored)
+
+	missing := []string{}
+	for _, platform := range desired {
+		if !mirroredSet[platform] {
+			missing = append(missing, platform)
+		}
+	}
+
+	return &PlatformCompletenessResponse{
+		Namespace: namespace,
+		Type:      providerType,
+		Version:   version,
+		Desired:   desired,
+		Mirrored:  mirrored,
+		Missing:   missing,
+		Complete:  len(missing) == 0,
+	}, nil
+}
+
+// handleProviderPlatforms lists mirrored vs. missing platforms for a provider version
+// GET /admin/api/providers/{namespace}/{type}/{version}/platforms
+// Optional query parameter "platforms" (comma-separated) overrides the configured set
+func (s *Server) handleProviderPlatforms(w http.ResponseWriter, r *http.Request) {
+	namespace := chi.URLParam(r, "namespace")
+	providerType := chi.URLParam(r, "type")
+	version := chi.URLParam(r, "version")
+
+	desired, err := s.requestPlatforms(r.URL.Query().Get("platforms"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_platforms", err.Error())
+		return
+	}
+
+	report, err := s.platformCompleteness(r, namespace, providerType, version, desired)
+	if err != nil {
+		log.Printf("Error checking platforms for %s/%s %s: %v", namespace, providerType, version, err)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list provider platforms")
+		return
+	}
+	if report == nil {
+		respondError(w, http.StatusNotFound, "not_found",
+			fmt.Sprintf("Provider %s/%s version %s is not mirrored", namespace, providerType, version))
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// handleFillProviderPlatforms enqueues a download job for the missing platforms of a provider version
+// POST /admin/api/providers/{namespace}/{type}/{version}/platforms/fill
+// Optional query parameter "platforms" (comma-separated) overrides the configured set
+func (s *Server) handleFillProviderPlatforms(w http.ResponseWriter, r *http.Request) {
+	namespace := chi.URLParam(r, "namespace")
+	providerType := chi.URLParam(r, "type")
+	version := chi.URLParam(r, "version")
+
+	desired, err := s.requestPlatforms(r.URL.Query().Get("platforms"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_platforms", err.Error())
+		return
+	}
+
+	report, err := s.platformCompleteness(r, namespace, providerType, version, desired)
+	if err != nil {
+		log.Printf("Error checking platforms for %s/%s %s: %v", namespace, providerType, version, err)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list provider platforms")
+		return
+	}
+	if report == nil {
+		respondError(w, http.StatusNotFound, "not_found",
+			fmt.Sprintf("Provider %s/%s version %s is not mirrored", namespace, providerType, version))
+		return
+	}
+
+	if len(report.Missing) == 0 {
+		respondJSON(w, http.StatusOK, FillPlatformsResponse{
+			Message: "All desired platforms are already mirrored",
+			Queued:  []string{},
+		})
+		return
+	}
+
+	// Create a pending job; the background processor picks it up
+	job := &database.DownloadJob{
+		JobType:    "provider",
+		SourceType: "platform_fill",
+		SourceData: fmt.Sprintf("%s/%s %s: %s", namespace, providerType, version, strings.Join(report.Missing, ",")),
+		Status:     "pending",
+		TotalItems: len(report.Missing),
+		CreatedAt:  time.Now(),
+	}
+	if userID, ok := r.Context().Value(userIDKey).(int64); ok {
+		job.UserID = sql.NullInt64{Int64: userID, Valid: true}
+	}
+
+	if err := s.jobRepo.Create(r.Context(), job); err != nil {
+		respondError(w, http.StatusInternalServerError, "job_creation_error",
+			fmt.Sprintf("Failed to create job: %v", err))
+		return
+	}
+
+	for _, platform := range report.Missing {
+		item := &database.DownloadJobItem{
+			JobID:     job.ID,
+			Namespace: namespace,
+			Type:      providerType,
+			Version:   version,
+			Platform:  platform,
+			Status:    "pending",
+		}
+		if err := s.jobRepo.CreateItem(r.Context(), item); err != nil {
+			respondError(w, http.StatusInternalServerError, "job_item_error",
+				fmt.Sprintf("Failed to create job item: %v", err))
+			return
+		}
+	}
+
+	s.jobRepo.NotifyEnqueued()
+
+	s.logAuditEvent(r, "fill_platforms", "job", fmt.Sprintf("%d", job.ID), true, "", map[string]interface{}{
+		"namespace": namespace,
+		"type":      providerType,
+		"version":   version,
+		"platforms": report.Missing,
+	})
+
+	respondJSON(w, http.StatusAccepted, FillPlatformsResponse{
+		JobID:   job.ID,
+		Message: fmt.Sprintf("Platform fill job created: %d platforms", len(report.Missing)),
+		Queued:  report.Missing,
+	})
+}
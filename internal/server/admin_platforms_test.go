@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleProviderPlatforms(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	server.config.Providers.Platforms = []string{"linux_amd64", "darwin_arm64"}
+
+	ctx := context.Background()
+	err := server.providerRepo.Create(ctx, &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "aws",
+		Version:   "5.0.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-aws_5.0.0_linux_amd64.zip",
+		S3Key:     "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip",
+	})
+	require.NoError(t, err)
+
+	token := getAuthToken(t, server)
+
+	t.Run("reports missing platform", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api/providers/hashicorp/aws/5.0.0/platforms", nil)
+		addAuthHeader(req, token)
+		w := httptest.NewRecorder()
+
+		server.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var report PlatformCompletenessResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+
+		assert.Equal(t, []string{"linux_amd64"}, report.Mirrored)
+		assert.Equal(t, []string{"darwin_arm64"}, report.Missing)
+		assert.False(t, report.Complete)
+	})
+
+	t.Run("platforms override", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api/providers/hashicorp/aws/5.0.0/platforms?platforms=linux_amd64,linux_arm64", nil)
+		addAuthHeader(req, token)
+		w := httptest.NewRecorder()
+
+		server.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var report PlatformCompletenessResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+
+		assert.Equal(t, []string{"linux_amd64", "linux_arm64"}, report.Desired)
+		assert.Equal(t, []string{"linux_arm64"}, report.Missing)
+	})
+
+	t.Run("invalid platforms override", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api/providers/hashicorp/aws/5.0.0/platforms?platforms=linux", nil)
+		addAuthHeader(req, token)
+		w := httptest.NewRecorder()
+
+		server.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("fill enqueues job for missing platform", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/api/providers/hashicorp/aws/5.0.0/platforms/fill", nil)
+		addAuthHeader(req, token)
+		w := httptest.NewRecorder()
+
+		server.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusAccepted, w.Code)
+
+		var resp FillPlatformsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, []string{"darwin_arm64"}, resp.Queued)
+
+		job, err := server.jobRepo.GetByID(ctx, resp.JobID)
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		assert.Equal(t, "pending", job.Status)
+		assert.Equal(t, "provider", job.JobType)
+		assert.Equal(t, 1, job.TotalItems)
+
+		items, err := server.jobRepo.GetItems(ctx, resp.JobID)
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, "hashicorp", items[0].Namespace)
+		assert.Equal(t, "aws", items[0].Type)
+		assert.Equal(t, "5.0.0", items[0].Version)
+		assert.Equal(t, "darwin_arm64", items[0].Platform)
+		assert.Equal(t, "pending", items[0].Status)
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api/providers/hashicorp/aws/9.9.9/platforms", nil)
+		addAuthHeader(req, token)
+		w := httptest.NewRecorder()
+
+		server.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ned1313/terraform-mirror/internal/database"
+)
+
+// providerAddressPattern matches a provider source address without hostname
+var providerAddressPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+/[a-zA-Z0-9_-]+$`)
+
+// ProviderAliasRequest is the body for creating an alias. Both fields are
+// "namespace/type" addresses.
+type ProviderAliasRequest struct {
+	Alias  string `json:"alias"`
+	Target string `json:"target"`
+}
+
+// ProviderAliasResponse describes an alias
+type ProviderAliasResponse struct {
+	ID        int64     `json:"id"`
+	Alias     string    `json:"alias"`
+	Target    string    `json:"target"`
+	CreatedAt Timestamp `json:"created_at"`
+}
+
+// ProviderAliasListResponse lists every alias
+type ProviderAliasListResponse struct {
+	Aliases []ProviderAliasResponse `json:"aliases"`
+}
+
+func providerAliasResponse(a *database.ProviderAlias) ProviderAliasResponse {
+	return ProviderAliasResponse{
+		ID:        a.ID,
+		Alias:     a.Namespace + "/" + a.Type,
+		Target:    a.TargetNamespace + "/" + a.TargetType,
+		CreatedAt: Timestamp{a.CreatedAt},
+	}
+}
+
+// resolveProviderAlias returns the provider an address is an alias of, or
+// the address itself when it is not aliased. Lookup failures are logged and
+// the address is served as requested.
+func (s *Server) resolveProviderAlias(ctx context.Context, namespace, providerType string) (string, string) {
+	alias, err := s.aliasRepo.Get(ctx, namespace, providerType)
+	if err != nil {
+		s.logger.Printf("Failed to resolve provider alias %s/%s: %v", namespace, providerType, err)
+		return namespace, providerType
+	}
+	if alias == nil {
+		return namespace, providerType
+	}
+	return alias.TargetNamespace, alias.TargetType
+}
+
+// handleListProviderAliases lists provider aliases
+// GET /admin/api/provider-aliases
+func (s *Server) handleListProviderAliases(w http.ResponseWriter, r *http.Request) {
+	aliases, err := s.aliasRepo.List(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list provider aliases")
+		return
+	}
+
+	response := ProviderAliasListResponse{Aliases: make([]ProviderAliasResponse, len(aliases))}
+	for i, a := range aliases {
+		response.Aliases[i] = providerAliasResponse(a)
+	}
+	respondJSON(w, http.StatusOK, response)
+}
+
+// handleCreateProviderAlias serves a mirrored provider under another address.
+// The alias shares the target's archives, so nothing is downloaded or copied.
+// POST /admin/api/provider-aliases
+func (s *Server) handleCreateProviderAlias(w http.ResponseWriter, r *http.Request) {
+	var req ProviderAliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_body", "Invalid request body")
+		return
+	}
+	req.Alias = strings.TrimSpace(req.Alias)
+	req.Target = strings.TrimSpace(req.Target)
+	for _, address := range []string{req.Alias, req.Target} {
+		if !providerAddressPattern.MatchString(address) {
+			respondError(w, http.StatusBadRequest, "invalid_address",
+				fmt.Sprintf("Invalid provider address %q: expected namespace/type", address))
+			return
+		}
+	}
+	if req.Alias == req.Target {
+		respondError(w, http.StatusBadRequest, "invalid_alias", "A provider cannot be an alias of itself")
+		return
+	}
+
+	aliasNamespace, aliasType, _ := strings.Cut(req.Alias, "/")
+	targetNamespace, targetType, _ := strings.Cut(req.Target, "/")
+	ctx := r.Context()
+
+	// Aliases resolve one level only, so neither side may be part of another alias
+	existing, err := s.aliasRepo.Get(ctx, aliasNamespace, aliasType)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to look up provider aliases")
+		return
+	}
+	if existing != nil {
+		respondError(w, http.StatusConflict, "alias_exists",
+			fmt.Sprintf("%s is already an alias of %s/%s", req.Alias, existing.TargetNamespace, existing.TargetType))
+		return
+	}
+	chained, err := s.aliasRepo.Get(ctx, targetNamespace, targetType)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to look up provider aliases")
+		return
+	}
+	if chained != nil {
+		respondError(w, http.StatusBadRequest, "alias_chain",
+			fmt.Sprintf("%s is itself an alias of %s/%s", req.Target, chained.TargetNamespace, chained.TargetType))
+		return
+	}
+	isTarget, err := s.aliasRepo.IsTarget(ctx, aliasNamespace, aliasType)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to look up provider aliases")
+		return
+	}
+	if isTarget {
+		respondError(w, http.StatusBadRequest, "alias_chain",
+			fmt.Sprintf("%s is the target of another alias", req.Alias))
+		return
+	}
+
+	// An alias would hide a provider mirrored under the same address
+	mirrored, err := s.providerRepo.ListVersions(ctx, aliasNamespace, aliasType)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to query providers")
+		return
+	}
+	if len(mirrored) > 0 {
+		respondError(w, http.StatusConflict, "provider_exists",
+			fmt.Sprintf("%s is already mirrored and cannot be an alias", req.Alias))
+		return
+	}
+
+	alias := &database.ProviderAlias{
+		Namespace:       aliasNamespace,
+		Type:            aliasType,
+		TargetNamespace: targetNamespace,
+		TargetType:      targetType,
+	}
+	if err := s.aliasRepo.Create(ctx, alias); err != nil {
+		s.logAuditEvent(r, "create_provider_alias", "provider_alias", req.Alias, false, err.Error(), nil)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to create provider alias")
+		return
+	}
+
+	// Documents cached for the address before it was aliased are stale
+	s.invalidateProviderIndexCache(ctx, aliasNamespace, aliasType)
+
+	s.logAuditEvent(r, "create_provider_alias", "provider_alias", strconv.FormatInt(alias.ID, 10), true, "",
+		map[string]interface{}{"alias": req.Alias, "target": req.Target})
+
+	respondJSON(w, http.StatusCreated, providerAliasResponse(alias))
+}
+
+// handleDeleteProviderAlias stops serving a provider under an alias. The
+// target provider and its archives are untouched.
+// DELETE /admin/api/provider-aliases/{id}
+func (s *Server) handleDeleteProviderAlias(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_id", "Invalid provider alias ID")
+		return
+	}
+
+	ctx := r.Context()
+	alias, err := s.aliasRepo.GetByID(ctx, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to get provider alias")
+		return
+	}
+	if alias == nil {
+		respondError(w, http.StatusNotFound, "not_found", "Provider alias not found")
+		return
+	}
+
+	if _, err := s.aliasRepo.Delete(ctx, id); err != nil {
+		s.logAuditEvent(r, "delete_provider_alias", "provider_alias", idStr, false, err.Error(), nil)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to delete provider alias")
+		return
+	}
+
+	// Documents served through the alias are cached under the target
+	s.invalidateProviderIndexCache(ctx, alias.TargetNamespace, alias.TargetType)
+
+	s.logAuditEvent(r, "delete_provider_alias", "provider_alias", idStr, true, "", map[string]interface{}{
+		"alias":  alias.Namespace + "/" + alias.Type,
+		"target": alias.TargetNamespace + "/" + alias.TargetType,
+	})
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Provider alias deleted"})
+}
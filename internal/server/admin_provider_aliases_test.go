@@ -0,0 +1,157 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+)
+
+func TestProviderAliases_ServeTargetArchives(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+	// Archives are served from the mirror's own /blobs/ endpoint
+	server.config.Storage.Type = "local"
+	server.setupRouter()
+	ctx := context.Background()
+	token := getAuthToken(t, server)
+
+	key := "providers/registry.terraform.io/hashicorp/random/3.5.0/linux_amd64/terraform-provider-random_3.5.0_linux_amd64.zip"
+	require.NoError(t, server.storage.Upload(ctx, key, strings.NewReader("provider-binary"), "application/zip", nil))
+	require.NoError(t, server.providerRepo.Create(ctx, &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "random",
+		Version:   "3.5.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-random_3.5.0_linux_amd64.zip",
+		Shasum:    "abcdef1234567890",
+		S3Key:     key,
+	}))
+
+	admin := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		addAuthHeader(req, token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+	mirror := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	// Before the alias exists the address is unknown
+	assert.Equal(t, http.StatusNotFound, mirror("/registry.terraform.io/mycorp/random/index.json").Code)
+
+	rr := admin(http.MethodPost, "/admin/api/provider-aliases", `{"alias": "mycorp/random", "target": "hashicorp/random"}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created ProviderAliasResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.Equal(t, "mycorp/random", created.Alias)
+	assert.Equal(t, "hashicorp/random", created.Target)
+
+	// The alias serves the same versions and archives as the target
+	for _, doc := range []string{"index.json", "3.5.0.json"} {
+		canonical := mirror("/registry.terraform.io/hashicorp/random/" + doc)
+		aliased := mirror("/registry.terraform.io/mycorp/random/" + doc)
+		require.Equal(t, http.StatusOK, canonical.Code, doc)
+		require.Equal(t, http.StatusOK, aliased.Code, doc)
+		assert.JSONEq(t, canonical.Body.String(), aliased.Body.String(), doc)
+	}
+
+	var versionDoc struct {
+		Archives map[string]struct {
+			URL    string   `json:"url"`
+			Hashes []string `json:"hashes"`
+		} `json:"archives"`
+	}
+	require.NoError(t, json.Unmarshal(mirror("/registry.terraform.io/mycorp/random/3.5.0.json").Body.Bytes(), &versionDoc))
+	archive, ok := versionDoc.Archives["linux_amd64"]
+	require.True(t, ok)
+	assert.Contains(t, archive.Hashes, "zh:abcdef1234567890")
+
+	// The archive is the target's blob; nothing was stored for the alias
+	blobURL, err := url.Parse(archive.URL)
+	require.NoError(t, err)
+	blob := mirror(blobURL.Path)
+	require.Equal(t, http.StatusOK, blob.Code)
+	assert.Equal(t, "provider-binary", blob.Body.String())
+	aliasRows, err := server.providerRepo.ListVersions(ctx, "mycorp", "random")
+	require.NoError(t, err)
+	assert.Empty(t, aliasRows)
+
+	rr = admin(http.MethodGet, "/admin/api/provider-aliases", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var list ProviderAliasListResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	require.Len(t, list.Aliases, 1)
+	assert.Equal(t, created.ID, list.Aliases[0].ID)
+
+	// Once deleted the alias address is no longer served
+	rr = admin(http.MethodDelete, fmt.Sprintf("/admin/api/provider-aliases/%d", created.ID), "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, http.StatusNotFound, mirror("/registry.terraform.io/mycorp/random/index.json").Code)
+	assert.Equal(t, http.StatusOK, mirror("/registry.terraform.io/hashicorp/random/index.json").Code)
+
+	rr = admin(http.MethodDelete, fmt.Sprintf("/admin/api/provider-aliases/%d", created.ID), "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestProviderAliases_Validation(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+	ctx := context.Background()
+	token := getAuthToken(t, server)
+
+	require.NoError(t, server.providerRepo.Create(ctx, &database.Provider{
+		Namespace: "mycorp",
+		Type:      "aws",
+		Version:   "5.0.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-aws_5.0.0_linux_amd64.zip",
+		S3Key:     "providers/registry.terraform.io/mycorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip",
+	}))
+	require.NoError(t, server.aliasRepo.Create(ctx, &database.ProviderAlias{
+		Namespace: "mycorp", Type: "random", TargetNamespace: "hashicorp", TargetType: "random",
+	}))
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{"invalid address", `{"alias": "mycorp", "target": "hashicorp/aws"}`, http.StatusBadRequest, "invalid_address"},
+		{"alias of itself", `{"alias": "hashicorp/aws", "target": "hashicorp/aws"}`, http.StatusBadRequest, "invalid_alias"},
+		{"already aliased", `{"alias": "mycorp/random", "target": "hashicorp/null"}`, http.StatusConflict, "alias_exists"},
+		{"target is an alias", `{"alias": "other/random", "target": "mycorp/random"}`, http.StatusBadRequest, "alias_chain"},
+		{"alias is a target", `{"alias": "hashicorp/random", "target": "hashicorp/null"}`, http.StatusBadRequest, "alias_chain"},
+		{"alias is mirrored", `{"alias": "mycorp/aws", "target": "hashicorp/aws"}`, http.StatusConflict, "provider_exists"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/api/provider-aliases", bytes.NewBufferString(tt.body))
+			addAuthHeader(req, token)
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, req)
+			require.Equal(t, tt.status, rr.Code, rr.Body.String())
+
+			var errResp ErrorResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&errResp))
+			assert.Equal(t, tt.code, errResp.Error)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ProviderVersionBlockRequest names the provider version to block or unblock
+type ProviderVersionBlockRequest struct {
+	Namespace string `json:"namespace"`
+	Type      string `json:"type"`
+	Version   string `json:"version"`
+}
+
+// ProviderVersionBlockResponse reports how many platforms of a version changed
+type ProviderVersionBlockResponse struct {
+	Namespace string `json:"namespace"`
+	Type      string `json:"type"`
+	Version   string `json:"version"`
+	Blocked   bool   `json:"blocked"`
+	Platforms int64  `json:"platforms"`
+}
+
+// handleBlockProviderVersion blocks every platform of a provider version, so
+// a version with a published vulnerability stops being served in one call.
+// POST /admin/api/providers/block-version
+func (s *Server) handleBlockProviderVersion(w http.ResponseWriter, r *http.Request) {
+	s.setProviderVersionBlocked(w, r, true)
+}
+
+// handleUnblockProviderVersion reverses handleBlockProviderVersion.
+// POST /admin/api/providers/unblock-version
+func (s *Server) handleUnblockProviderVersion(w http.ResponseWriter, r *http.Request) {
+	s.setProviderVersionBlocked(w, r, false)
+}
+
+func (s *Server) setProviderVersionBlocked(w http.ResponseWriter, r *http.Request, blocked bool) {
+	var req ProviderVersionBlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_body", "Invalid request body")
+		return
+	}
+	req.Namespace = strings.TrimSpace(req.Namespace)
+	req.Type = strings.TrimSpace(req.Type)
+	req.Version = strings.TrimSpace(req.Version)
+
+	address := req.Namespace + "/" + req.Type
+	if !providerAddressPattern.MatchString(address) {
+		respondError(w, http.StatusBadRequest, "invalid_address",
+			fmt.Sprintf("Invalid provider address %q: expected namespace/type", address))
+		return
+	}
+	if req.Version == "" {
+		respondError(w, http.StatusBadRequest, "invalid_request", "version is required")
+		return
+	}
+
+	action := "unblock_provider_version"
+	if blocked {
+		action = "block_provider_version"
+	}
+	resourceID := address + "/" + req.Version
+
+	ctx := r.Context()
+	platforms, err := s.providerRepo.SetVersionBlocked(ctx, req.Namespace, req.Type, req.Version, blocked)
+	if err != nil {
+		s.logAuditEvent(r, action, "provider", resourceID, false, err.Error(), nil)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to update provider version")
+		return
+	}
+	if platforms == 0 {
+		respondError(w, http.StatusNotFound, "not_found", "Provider version not found")
+		return
+	}
+
+	// Cached index and version documents still list the old state
+	s.invalidateProviderIndexCache(ctx, req.Namespace, req.Type)
+
+	s.logAuditEvent(r, action, "provider", resourceID, true, "", map[string]interface{}{
+		"platforms": platforms,
+	})
+
+	respondJSON(w, http.StatusOK, ProviderVersionBlockResponse{
+		Namespace: req.Namespace,
+		Type:      req.Type,
+		Version:   req.Version,
+		Blocked:   blocked,
+		Platforms: platforms,
+	})
+}
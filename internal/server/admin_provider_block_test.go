@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+)
+
+func TestProviderVersionBlock(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+	ctx := context.Background()
+	token := getAuthToken(t, server)
+
+	for _, v := range []struct{ version, platform string }{
+		{"5.0.0", "linux_amd64"},
+		{"5.0.0", "darwin_arm64"},
+		{"5.0.0", "windows_amd64"},
+		{"5.1.0", "linux_amd64"},
+	} {
+		filename := "terraform-provider-aws_" + v.version + "_" + v.platform + ".zip"
+		require.NoError(t, server.providerRepo.Create(ctx, &database.Provider{
+			Namespace: "hashicorp",
+			Type:      "aws",
+			Version:   v.version,
+			Platform:  v.platform,
+			Filename:  filename,
+			Shasum:    "abcdef1234567890",
+			S3Key:     "providers/registry.terraform.io/hashicorp/aws/" + v.version + "/" + v.platform + "/" + filename,
+		}))
+	}
+
+	admin := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		addAuthHeader(req, token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+	blockedPlatforms := func(version string) map[string]bool {
+		providers, err := server.providerRepo.ListVersions(ctx, "hashicorp", "aws")
+		require.NoError(t, err)
+		result := make(map[string]bool)
+		for _, p := range providers {
+			if p.Version == version {
+				result[p.Platform] = p.Blocked
+			}
+		}
+		return result
+	}
+	mirror := func(path string) int {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr.Code
+	}
+
+	// Warm the document cache so eviction is exercised
+	require.Equal(t, http.StatusOK, mirror("/registry.terraform.io/hashicorp/aws/5.0.0.json"))
+
+	body := `{"namespace": "hashicorp", "type": "aws", "version": "5.0.0"}`
+	rr := admin("/admin/api/providers/block-version", body)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp ProviderVersionBlockResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.True(t, resp.Blocked)
+	assert.Equal(t, int64(3), resp.Platforms)
+
+	assert.Equal(t, map[string]bool{
+		"linux_amd64":   true,
+		"darwin_arm64":  true,
+		"windows_amd64": true,
+	}, blockedPlatforms("5.0.0"))
+	assert.Equal(t, map[string]bool{"linux_amd64": false}, blockedPlatforms("5.1.0"))
+	assert.Equal(t, http.StatusNotFound, mirror("/registry.terraform.io/hashicorp/aws/5.0.0.json"))
+
+	rr = admin("/admin/api/providers/unblock-version", body)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.False(t, resp.Blocked)
+	assert.Equal(t, int64(3), resp.Platforms)
+	for platform, blocked := range blockedPlatforms("5.0.0") {
+		assert.False(t, blocked, platform)
+	}
+	assert.Equal(t, http.StatusOK, mirror("/registry.terraform.io/hashicorp/aws/5.0.0.json"))
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{"invalid body", `{`, http.StatusBadRequest, "invalid_body"},
+		{"invalid address", `{"namespace": "hashicorp", "version": "5.0.0"}`, http.StatusBadRequest, "invalid_address"},
+		{"missing version", `{"namespace": "hashicorp", "type": "aws"}`, http.StatusBadRequest, "invalid_request"},
+		{"unknown version", `{"namespace": "hashicorp", "type": "aws", "version": "9.9.9"}`, http.StatusNotFound, "not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := admin("/admin/api/providers/block-version", tt.body)
+			require.Equal(t, tt.status, rr.Code, rr.Body.String())
+
+			var errResp ErrorResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&errResp))
+			assert.Equal(t, tt.code, errResp.Error)
+		})
+	}
+}
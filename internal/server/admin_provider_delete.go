@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ned1313/terraform-mirror/internal/provider"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+)
+
+// DeleteAllProviderVersionsResponse reports what deleting a whole provider removed
+type DeleteAllProviderVersionsResponse struct {
+	Namespace  string   `json:"namespace"`
+	Type       string   `json:"type"`
+	Versions   []string `json:"versions"`
+	Deleted    int64    `json:"deleted"`
+	FreedBytes int64    `json:"freed_bytes"`
+}
+
+// handleDeleteAllProviderVersions deletes every version and platform of a
+// provider. Versions whose archives are pinned (explicitly requested rather
+// than auto-downloaded) are protected unless force=true.
+// DELETE /admin/api/providers/{namespace}/{type}?force=true
+func (s *Server) handleDeleteAllProviderVersions(w http.ResponseWriter, r *http.Request) {
+	namespace := chi.URLParam(r, "namespace")
+	providerType := chi.URLParam(r, "type")
+	address := namespace + "/" + providerType
+	if !providerAddressPattern.MatchString(address) {
+		respondError(w, http.StatusBadRequest, "invalid_address",
+			fmt.Sprintf("Invalid provider address %q: expected namespace/type", address))
+		return
+	}
+
+	force, ok := parseForce(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	providers, err := s.providerRepo.ListVersions(ctx, namespace, providerType)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to query providers")
+		return
+	}
+	if len(providers) == 0 {
+		respondError(w, http.StatusNotFound, "not_found", "Provider not found")
+		return
+	}
+
+	versionSet := make(map[string]bool)
+	pinnedSet := make(map[string]bool)
+	var freedBytes int64
+	for _, p := range providers {
+		versionSet[p.Version] = true
+		freedBytes += p.SizeBytes
+		if force || p.S3Key == "" || pinnedSet[p.Version] {
+			continue
+		}
+		metadata, err := s.storage.GetMetadata(ctx, p.S3Key)
+		if err != nil {
+			// An archive that cannot be read is not protected; it is
+			// deleted with the rest
+			s.logger.Printf("Warning: failed to read metadata of %s: %v", p.S3Key, err)
+			continue
+		}
+		if metadata[storage.MetadataPinned] == "true" {
+			pinnedSet[p.Version] = true
+		}
+	}
+	if len(pinnedSet) > 0 {
+		respondError(w, http.StatusConflict, "provider_pinned",
+			fmt.Sprintf("%s has pinned versions (%s); retry with force=true to delete them", address, strings.Join(sortedKeys(pinnedSet), ", ")))
+		return
+	}
+	versions := sortedKeys(versionSet)
+
+	// Rows go first and together, so the mirror never lists an archive that
+	// was already removed from storage
+	deleted, err := s.providerRepo.DeleteAllVersions(ctx, namespace, providerType)
+	if err != nil {
+		s.logAuditEvent(r, "delete_provider_all", "provider", address, false, err.Error(), nil)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to delete provider")
+		return
+	}
+
+	for _, p := range providers {
+		if p.S3Key == "" {
+			continue
+		}
+		if err := s.storage.Delete(ctx, p.S3Key); err != nil {
+			// Log but don't fail - the rows are gone and the storage file might be too
+			s.logger.Printf("Warning: failed to delete storage object %s: %v", p.S3Key, err)
+		}
+	}
+	s.pruneStaleShasums(ctx)
+
+	// Stop serving cached archives and mirror documents of the provider
+	archiveKeys := make([]string, 0, len(providers))
+	for _, p := range providers {
+		archiveKeys = append(archiveKeys, p.S3Key)
+	}
+	s.evictCacheKeys(ctx, archiveKeys)
+	s.invalidateProviderIndexCache(ctx, namespace, providerType)
+
+	s.logAuditEvent(r, "delete_provider_all", "provider", address, true, "", map[string]interface{}{
+		"versions":    versions,
+		"deleted":     deleted,
+		"freed_bytes": freedBytes,
+		"force":       force,
+	})
+
+	respondJSON(w, http.StatusOK, DeleteAllProviderVersionsResponse{
+		Namespace:  namespace,
+		Type:       providerType,
+		Versions:   versions,
+		Deleted:    deleted,
+		FreedBytes: freedBytes,
+	})
+}
+
+// pruneStaleShasums removes stored SHA256SUMS documents of versions that
+// lost platforms. Failures are logged; the next job retries them.
+func (s *Server) pruneStaleShasums(ctx context.Context) {
+	if _, err := provider.PruneStaleShasums(ctx, s.providerRepo, s.storage); err != nil {
+		s.logger.Printf("Warning: failed to remove stale SHA256SUMS documents: %v", err)
+	}
+}
+
+// sortedKeys returns the keys of a set in ascending order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+)
+
+func TestHandleDeleteAllProviderVersions(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+	ctx := context.Background()
+	token := getAuthToken(t, server)
+
+	// Versions 3.5.0 and 3.6.0 of random; 3.6.0 was explicitly requested
+	var keys []string
+	for _, p := range []struct {
+		version, platform string
+		pinned            bool
+	}{
+		{"3.5.0", "linux_amd64", false},
+		{"3.5.0", "darwin_arm64", false},
+		{"3.6.0", "linux_amd64", true},
+	} {
+		filename := "terraform-provider-random_" + p.version + "_" + p.platform + ".zip"
+		key := "providers/registry.terraform.io/hashicorp/random/" + p.version + "/" + p.platform + "/" + filename
+		metadata := storage.ProviderMetadata("hashicorp", "random", p.version, p.platform, filename, "abc123", "", p.pinned)
+		require.NoError(t, server.storage.Upload(ctx, key, strings.NewReader("provider-binary"), "application/zip", metadata))
+		require.NoError(t, server.providerRepo.Create(ctx, &database.Provider{
+			Namespace: "hashicorp",
+			Type:      "random",
+			Version:   p.version,
+			Platform:  p.platform,
+			Filename:  filename,
+			Shasum:    "abc123",
+			S3Key:     key,
+			SizeBytes: 100,
+		}))
+		keys = append(keys, key)
+	}
+	// 3.5.0 has a stored SHA256SUMS document
+	shasumsKey := storage.BuildProviderShasumsKey("registry.terraform.io", "hashicorp", "random", "3.5.0")
+	require.NoError(t, server.storage.Upload(ctx, shasumsKey, strings.NewReader("abc123  terraform-provider-random_3.5.0_linux_amd64.zip\n"), "text/plain; charset=utf-8", nil))
+	require.NoError(t, server.providerRepo.SetShasumsKey(ctx, "hashicorp", "random", "3.5.0", shasumsKey))
+	keys = append(keys, shasumsKey)
+	require.NoError(t, server.providerRepo.Create(ctx, &database.Provider{
+		Namespace: "hashicorp", Type: "null", Version: "3.2.0", Platform: "linux_amd64",
+		Filename: "terraform-provider-null_3.2.0_linux_amd64.zip",
+		S3Key:    "providers/registry.terraform.io/hashicorp/null/3.2.0/linux_amd64/terraform-provider-null_3.2.0_linux_amd64.zip",
+	}))
+
+	remove := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, path, nil)
+		addAuthHeader(req, token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The pinned version protects the whole provider
+	rr := remove("/admin/api/providers/hashicorp/random")
+	require.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&errResp))
+	assert.Equal(t, "provider_pinned", errResp.Error)
+	assert.Contains(t, errResp.Message, "3.6.0")
+	remaining, err := server.providerRepo.ListVersions(ctx, "hashicorp", "random")
+	require.NoError(t, err)
+	assert.Len(t, remaining, 3)
+
+	rr = remove("/admin/api/providers/hashicorp/random?force=true")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp DeleteAllProviderVersionsResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, int64(3), resp.Deleted)
+	assert.Equal(t, int64(300), resp.FreedBytes)
+	assert.Equal(t, []string{"3.5.0", "3.6.0"}, resp.Versions)
+
+	remaining, err = server.providerRepo.ListVersions(ctx, "hashicorp", "random")
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+	for _, key := range keys {
+		exists, err := server.storage.Exists(ctx, key)
+		require.NoError(t, err)
+		assert.False(t, exists, key)
+	}
+	recorded, err := server.providerRepo.ListShasumsKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, recorded)
+	other, err := server.providerRepo.ListVersions(ctx, "hashicorp", "null")
+	require.NoError(t, err)
+	assert.Len(t, other, 1)
+
+	assert.Equal(t, http.StatusNotFound, remove("/admin/api/providers/hashicorp/random").Code)
+	assert.Equal(t, http.StatusBadRequest, remove("/admin/api/providers/hashicorp/null?force=maybe").Code)
+}
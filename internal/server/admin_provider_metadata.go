@@ -0,0 +1,110 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/provider"
+)
+
+// RefreshMetadataResponse represents the response after refreshing a provider's metadata
+type RefreshMetadataResponse struct {
+	Provider *database.Provider `json:"provider"`
+	Changed  []string           `json:"changed"`
+}
+
+// handleRefreshProviderMetadata re-queries the upstream registry for a
+// provider's metadata and updates the stored record. The stored blob is not
+// re-downloaded, so a changed upstream shasum is reported as a conflict
+// and leaves the record untouched.
+// POST /admin/api/providers/{id}/refresh-metadata
+func (s *Server) handleRefreshProviderMetadata(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_id", "Invalid provider ID")
+		return
+	}
+
+	p, err := s.providerRepo.GetByID(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to get provider")
+		return
+	}
+	if p == nil {
+		respondError(w, http.StatusNotFound, "not_found", "Provider not found")
+		return
+	}
+
+	os, arch, err := provider.ParsePlatform(p.Platform)
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, "invalid_platform",
+			fmt.Sprintf("Provider has an invalid platform %q", p.Platform))
+		return
+	}
+
+	info, err := s.providerMetadata.GetDownloadInfo(r.Context(), p.Namespace, p.Type, p.Version, os, arch)
+	if err != nil {
+		s.logAuditEvent(r, "refresh_provider_metadata", "provider", idStr, false, err.Error(), nil)
+		respondError(w, http.StatusBadGateway, "registry_error",
+			fmt.Sprintf("Failed to fetch metadata from registry: %v", err))
+		return
+	}
+
+	// The stored blob was verified against the recorded shasum. Recording a
+	// new upstream shasum would make the record vouch for a file it does not
+	// describe, so the provider has to be re-downloaded instead.
+	if info.Shasum != p.Shasum {
+		msg := fmt.Sprintf("Upstream shasum %s differs from the shasum %s the stored file was verified against; re-download the provider", info.Shasum, p.Shasum)
+		s.logger.Printf("Provider %s/%s %s %s: %s", p.Namespace, p.Type, p.Version, p.Platform, msg)
+		s.logAuditEvent(r, "refresh_provider_metadata", "provider", idStr, false, msg, nil)
+		respondError(w, http.StatusConflict, "artifact_conflict", msg)
+		return
+	}
+
+	var changed []string
+	signingKeys := provider.EncodeSigningKeys(info.SigningKeys)
+	protocols := provider.EncodeProtocols(info.Protocols)
+	if info.DownloadURL != p.DownloadURL {
+		changed = append(changed, "download_url")
+	}
+	if signingKeys != p.SigningKeys {
+		changed = append(changed, "signing_keys")
+	}
+	if !slices.Equal(info.Protocols, provider.DecodeProtocols(p.Protocols)) {
+		changed = append(changed, "protocols")
+	}
+
+	p.DownloadURL = info.DownloadURL
+	p.SigningKeys = signingKeys
+	p.Protocols = protocols
+
+	if err := s.providerRepo.UpdateMetadata(r.Context(), p); err != nil {
+		s.logAuditEvent(r, "refresh_provider_metadata", "provider", idStr, false, err.Error(), nil)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to update provider metadata")
+		return
+	}
+
+	// Mirror documents carry the protocols and signing keys
+	s.invalidateProviderIndexCache(r.Context(), p.Namespace, p.Type)
+
+	s.logAuditEvent(r, "refresh_provider_metadata", "provider", idStr, true, "", map[string]interface{}{
+		"namespace": p.Namespace,
+		"type":      p.Type,
+		"version":   p.Version,
+		"platform":  p.Platform,
+		"changed":   changed,
+	})
+
+	if changed == nil {
+		changed = []string{}
+	}
+	respondJSON(w, http.StatusOK, RefreshMetadataResponse{
+		Provider: p,
+		Changed:  changed,
+	})
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/cache"
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metadataRegistry returns fixed download metadata for any provider
+type metadataRegistry struct {
+	info *provider.ProviderDownloadInfo
+}
+
+func (r *metadataRegistry) GetDownloadInfo(ctx context.Context, namespace, providerType, version, os, arch string) (*provider.ProviderDownloadInfo, error) {
+	info := *r.info
+	return &info, nil
+}
+
+func TestHandleRefreshProviderMetadata(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	p := &database.Provider{
+		Namespace:   "hashicorp",
+		Type:        "aws",
+		Version:     "5.0.0",
+		Platform:    "linux_amd64",
+		Filename:    "terraform-provider-aws_5.0.0_linux_amd64.zip",
+		DownloadURL: "https://example.com/old.zip",
+		Shasum:      "abc123",
+		S3Key:       "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip",
+		SizeBytes:   1024,
+	}
+	require.NoError(t, server.providerRepo.Create(ctx, p))
+
+	keys := []provider.GPGPublicKey{{KeyID: "34365D9472D7468F", ASCIIArmor: "-----BEGIN PGP PUBLIC KEY BLOCK-----"}}
+	server.providerMetadata = &metadataRegistry{info: &provider.ProviderDownloadInfo{
+		DownloadURL: "https://example.com/new.zip",
+		Shasum:      "abc123",
+		SigningKeys: keys,
+		Protocols:   []string{"5.0", "6.0"},
+	}}
+
+	token := getAuthToken(t, server)
+
+	refresh := func(id int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/api/providers/"+strconv.FormatInt(id, 10)+"/refresh-metadata", nil)
+		addAuthHeader(req, token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("updates metadata without touching the blob", func(t *testing.T) {
+		w := refresh(p.ID)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp RefreshMetadataResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.ElementsMatch(t, []string{"download_url", "signing_keys", "protocols"}, resp.Changed)
+
+		stored, err := server.providerRepo.GetByID(ctx, p.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/new.zip", stored.DownloadURL)
+		assert.Equal(t, "abc123", stored.Shasum)
+		assert.Equal(t, []string{"5.0", "6.0"}, provider.DecodeProtocols(stored.Protocols))
+		assert.Equal(t, keys, provider.DecodeSigningKeys(stored.SigningKeys))
+		assert.Equal(t, p.S3Key, stored.S3Key)
+		assert.Equal(t, int64(1024), stored.SizeBytes)
+	})
+
+	t.Run("unchanged metadata", func(t *testing.T) {
+		w := refresh(p.ID)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp RefreshMetadataResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Empty(t, resp.Changed)
+	})
+
+	t.Run("conflict when upstream shasum differs", func(t *testing.T) {
+		info := server.providerMetadata.(*metadataRegistry).info
+		info.Shasum = "def456"
+		info.DownloadURL = "https://example.com/newer.zip"
+		defer func() { info.Shasum = "abc123" }()
+
+		w := refresh(p.ID)
+		require.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "def456")
+
+		// The record still describes the stored file
+		stored, err := server.providerRepo.GetByID(ctx, p.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "abc123", stored.Shasum)
+		assert.Equal(t, "https://example.com/new.zip", stored.DownloadURL)
+	})
+
+	t.Run("provider not found", func(t *testing.T) {
+		w := refresh(99999)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestHandleRefreshProviderMetadata_InvalidatesCache(t *testing.T) {
+	mc, err := cache.NewMemoryCache(cache.MemoryCacheConfig{MaxSizeMB: 1})
+	require.NoError(t, err)
+	srv, _ := setupBlobTest(t, mc)
+	ctx := context.Background()
+
+	p := &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "random",
+		Version:   "3.0.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-random_3.0.0_linux_amd64.zip",
+		Shasum:    "abc123",
+		S3Key:     "providers/registry.terraform.io/hashicorp/random/3.0.0/linux_amd64/terraform-provider-random_3.0.0_linux_amd64.zip",
+	}
+	require.NoError(t, srv.providerRepo.Create(ctx, p))
+	srv.providerMetadata = &metadataRegistry{info: &provider.ProviderDownloadInfo{
+		Shasum:    "abc123",
+		Protocols: []string{"6.0"},
+	}}
+
+	versionKey := mirrorIndexCacheKey("hashicorp", "random", "/registry.terraform.io/hashicorp/random/3.0.0.json")
+	require.NoError(t, mc.Set(ctx, versionKey, bytes.NewReader([]byte("{}")), "application/json", 2, time.Hour))
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/api/providers/"+strconv.FormatInt(p.ID, 10)+"/refresh-metadata", nil)
+	addAuthHeader(req, getAuthToken(t, srv))
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.False(t, mc.Exists(ctx, versionKey), "cached mirror documents carry the old protocols")
+}
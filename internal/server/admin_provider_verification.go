@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+)
+
+// UnverifiedProviderInfo describes a provider archive that was stored
+// without passing signature verification
+type UnverifiedProviderInfo struct {
+	ID             int64     `json:"id"`
+	Namespace      string    `json:"namespace"`
+	Type           string    `json:"type"`
+	Version        string    `json:"version"`
+	Platform       string    `json:"platform"`
+	Shasum         string    `json:"shasum"`
+	StorageKey     string    `json:"storage_key"`
+	UpstreamURL    string    `json:"upstream_url"`
+	HasSigningKeys bool      `json:"has_signing_keys"`
+	CreatedAt      Timestamp `json:"created_at"`
+}
+
+// UnverifiedProvidersResponse lists unverified provider archives alongside
+// how many of the mirrored archives did pass verification
+type UnverifiedProvidersResponse struct {
+	Providers []UnverifiedProviderInfo `json:"providers"`
+	Count     int                      `json:"count"`
+	Total     int64                    `json:"total"`
+	Verified  int64                    `json:"verified"`
+}
+
+// handleListUnverifiedProviders lists provider archives that did not match a
+// SHA256SUMS document signed by the release's signing keys: archives
+// re-indexed from storage, mirrored while GPG verification was off or before
+// it existed, or whose upstream signature did not verify.
+// GET /admin/api/providers/unverified
+func (s *Server) handleListUnverifiedProviders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	providers, err := s.providerRepo.ListUnverified(ctx)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list unverified providers")
+		return
+	}
+	total, err := s.providerRepo.Count(ctx)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to count providers")
+		return
+	}
+
+	resp := UnverifiedProvidersResponse{
+		Providers: make([]UnverifiedProviderInfo, 0, len(providers)),
+		Count:     len(providers),
+		Total:     total,
+		Verified:  total - int64(len(providers)),
+	}
+	for _, p := range providers {
+		resp.Providers = append(resp.Providers, UnverifiedProviderInfo{
+			ID:             p.ID,
+			Namespace:      p.Namespace,
+			Type:           p.Type,
+			Version:        p.Version,
+			Platform:       p.Platform,
+			Shasum:         p.Shasum,
+			StorageKey:     p.S3Key,
+			UpstreamURL:    p.DownloadURL,
+			HasSigningKeys: p.SigningKeys.Valid && p.SigningKeys.String != "",
+			CreatedAt:      Timestamp{p.CreatedAt},
+		})
+	}
+
+	respondJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+)
+
+func TestHandleListUnverifiedProviders(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+	ctx := context.Background()
+	token := getAuthToken(t, server)
+
+	// A download that matched the signed SHA256SUMS, and an archive that was
+	// placed in storage directly and indexed without a signature check
+	for _, p := range []*database.Provider{
+		{Namespace: "hashicorp", Type: "aws", Version: "5.0.0", Platform: "linux_amd64", Verified: true,
+			DownloadURL: "https://releases.hashicorp.com/terraform-provider-aws_5.0.0_linux_amd64.zip"},
+		{Namespace: "acme", Type: "internal", Version: "1.0.0", Platform: "linux_amd64"},
+	} {
+		p.Filename = "terraform-provider-" + p.Type + "_" + p.Version + "_" + p.Platform + ".zip"
+		p.Shasum = "abcdef1234567890"
+		p.S3Key = "providers/registry.terraform.io/" + p.Namespace + "/" + p.Type + "/" + p.Version + "/" + p.Platform + "/" + p.Filename
+		require.NoError(t, server.providerRepo.Create(ctx, p))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/providers/unverified", nil)
+	addAuthHeader(req, token)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp UnverifiedProvidersResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, int64(2), resp.Total)
+	assert.Equal(t, int64(1), resp.Verified)
+	require.Equal(t, 1, resp.Count)
+	require.Len(t, resp.Providers, 1)
+	assert.Equal(t, "acme", resp.Providers[0].Namespace)
+	assert.Equal(t, "internal", resp.Providers[0].Type)
+	assert.Empty(t, resp.Providers[0].UpstreamURL)
+	assert.False(t, resp.Providers[0].HasSigningKeys)
+
+	// The verified status is part of the provider itself
+	verified, err := server.providerRepo.GetByIdentity(ctx, "hashicorp", "aws", "5.0.0", "linux_amd64")
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodGet, "/admin/api/providers/"+strconv.FormatInt(verified.ID, 10), nil)
+	addAuthHeader(req, token)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var provider map[string]interface{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&provider))
+	assert.Equal(t, true, provider["Verified"])
+}
@@ -23,7 +23,8 @@ type LoadProvidersResponse struct {
 
 // handleLoadProviders handles the provider definition upload and loading
 // POST /admin/api/providers/load
-// Accepts multipart/form-data with "file" field containing HCL content
+// Accepts multipart/form-data with "file" field containing HCL content and an
+// optional "platforms" field (comma-separated) for providers that omit platforms
 // Creates a job and processes providers, returning the job ID for tracking
 func (s *Server) handleLoadProviders(w http.ResponseWriter, r *http.Request) {
 	// Parse multipart form (max 10MB)
@@ -32,6 +33,12 @@ func (s *Server) handleLoadProviders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	key, err := idempotencyKey(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_idempotency_key", err.Error())
+		return
+	}
+
 	// Get the uploaded file
 	file, header, err := r.FormFile("file")
 	if err != nil {
@@ -73,9 +80,45 @@ func (s *Server) handleLoadProviders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Fill in platforms for providers that omit them, using the optional
+	// "platforms" form field or the configured defaults
+	platforms, err := s.requestPlatforms(r.FormValue("platforms"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_platforms", err.Error())
+		return
+	}
+	defs.ApplyDefaultPlatforms(platforms)
+
 	// Calculate total items (each version+platform combination)
 	totalItems := defs.CountItems()
 
+	// A retry with the same Idempotency-Key returns the job it already created
+	if key != "" {
+		s.idempotencyMu.Lock()
+		defer s.idempotencyMu.Unlock()
+
+		jobID, found, err := s.findIdempotentJob(r.Context(), "providers/load", key)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "database_error", "Failed to look up idempotency key")
+			return
+		}
+		if found {
+			w.Header().Set("Idempotent-Replayed", "true")
+			respondJSON(w, http.StatusAccepted, LoadProvidersResponse{
+				JobID:   jobID,
+				Message: fmt.Sprintf("Provider loading job %d already created for this Idempotency-Key", jobID),
+				Total:   len(defs.Providers),
+			})
+			return
+		}
+	}
+
+	s.backlogMu.Lock()
+	defer s.backlogMu.Unlock()
+	if !s.checkJobBacklog(w, r, totalItems) {
+		return
+	}
+
 	// Create download job
 	job := &database.DownloadJob{
 		UserID:     sql.NullInt64{}, // No auth yet, leave null
@@ -123,6 +166,10 @@ func (s *Server) handleLoadProviders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if key != "" {
+		s.recordIdempotentJob(r.Context(), "providers/load", key, job.ID)
+	}
+
 	// Log the job creation
 	s.logAuditEvent(r, "load_providers", "job", fmt.Sprintf("%d", job.ID), true, "", map[string]interface{}{
 		"total_providers": len(defs.Providers),
@@ -153,6 +200,9 @@ func (s *Server) processProviderLoadJob(job *database.DownloadJob, defs *provide
 
 	// Create provider service
 	providerSvc := provider.NewService(s.storage, s.db)
+	providerSvc.SetUpstreams(s.upstreams)
+	providerSvc.SetRegistry(s.providerRegistry)
+	providerSvc.SetImmutableArtifacts(s.config.Providers.ImmutableArtifacts)
 
 	// Track progress during processing
 	var completedCount, failedCount int
@@ -342,3 +342,86 @@ provider "hashicorp/random" {
 
 	assert.Equal(t, "parse_error", errResp.Error)
 }
+
+func TestHandleLoadProviders_DefaultPlatforms(t *testing.T) {
+	hcl := `
+provider "hashicorp/random" {
+  versions = ["3.5.0"]
+}
+`
+
+	tests := []struct {
+		name      string
+		override  string
+		platforms []string
+	}{
+		{name: "omitted platforms use configured default", platforms: []string{"linux_amd64", "darwin_arm64"}},
+		{name: "request override", override: "windows_amd64", platforms: []string{"windows_amd64"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, cleanup := setupAdminTest(t)
+			defer cleanup()
+
+			server.config.Providers.Platforms = []string{"linux_amd64", "darwin_arm64"}
+			token := getAuthToken(t, server)
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, err := writer.CreateFormFile("file", "providers.hcl")
+			require.NoError(t, err)
+			_, err = io.WriteString(part, hcl)
+			require.NoError(t, err)
+			if tt.override != "" {
+				require.NoError(t, writer.WriteField("platforms", tt.override))
+			}
+			require.NoError(t, writer.Close())
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/api/providers/load", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			addAuthHeader(req, token)
+			rr := httptest.NewRecorder()
+
+			server.router.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+
+			var response LoadProvidersResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+
+			items, err := server.jobRepo.GetItems(context.Background(), response.JobID)
+			require.NoError(t, err)
+
+			var platforms []string
+			for _, item := range items {
+				platforms = append(platforms, item.Platform)
+			}
+			assert.ElementsMatch(t, tt.platforms, platforms)
+		})
+	}
+}
+
+func TestHandleLoadProviders_InvalidPlatformOverride(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	token := getAuthToken(t, server)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "providers.hcl")
+	require.NoError(t, err)
+	_, err = io.WriteString(part, "provider \"hashicorp/random\" {\n  versions = [\"3.5.0\"]\n}\n")
+	require.NoError(t, err)
+	require.NoError(t, writer.WriteField("platforms", "linux"))
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/api/providers/load", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	addAuthHeader(req, token)
+	rr := httptest.NewRecorder()
+
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid_platforms")
+}
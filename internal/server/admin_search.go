@@ -0,0 +1,67 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultSearchLimit and maxSearchLimit bound the results of a search
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchResult is a provider or module matching a search; Kind tells them apart
+type SearchResult struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	System    string `json:"system,omitempty"`
+	Versions  int64  `json:"versions"`
+}
+
+// SearchResponse lists the best matches for a query
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+	Total   int            `json:"total"`
+}
+
+// handleSearch searches providers and modules together for the admin UI's
+// search box. Providers match on namespace or type and modules on namespace
+// or name; exact names rank first, then prefixes, then other substrings.
+// GET /admin/api/search?q=aws&limit=20
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		respondError(w, http.StatusBadRequest, "missing_query", "q is required")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= maxSearchLimit {
+		limit = l
+	}
+
+	matches, err := s.searchRepo.Search(r.Context(), q, limit)
+	if err != nil {
+		log.Printf("Error searching for %q: %v", q, err)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to search")
+		return
+	}
+
+	results := make([]SearchResult, len(matches))
+	for i, m := range matches {
+		results[i] = SearchResult{
+			Kind:      m.Kind,
+			Namespace: m.Namespace,
+			Name:      m.Name,
+			System:    m.System,
+			Versions:  m.Versions,
+		}
+	}
+
+	respondJSON(w, http.StatusOK, SearchResponse{Query: q, Results: results, Total: len(results)})
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSearch(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	require.NoError(t, server.providerRepo.Create(ctx, &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "consul",
+		Version:   "2.20.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-consul_2.20.0_linux_amd64.zip",
+		S3Key:     "providers/hashicorp/consul/2.20.0/linux_amd64.zip",
+	}))
+	require.NoError(t, server.moduleRepo.Create(ctx, &database.Module{
+		Namespace: "hashicorp",
+		Name:      "consul",
+		System:    "aws",
+		Version:   "0.1.0",
+		S3Key:     "modules/hashicorp/consul/aws/0.1.0.tar.gz",
+		Filename:  "0.1.0.tar.gz",
+	}))
+	require.NoError(t, server.providerRepo.Create(ctx, &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "random",
+		Version:   "3.5.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-random_3.5.0_linux_amd64.zip",
+		S3Key:     "providers/hashicorp/random/3.5.0/linux_amd64.zip",
+	}))
+
+	token := getAuthToken(t, server)
+	search := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api/search"+query, nil)
+		addAuthHeader(req, token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := search("?q=consul")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response SearchResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "consul", response.Query)
+	require.Equal(t, 2, response.Total)
+
+	kinds := map[string]SearchResult{}
+	for _, result := range response.Results {
+		kinds[result.Kind] = result
+	}
+	assert.Equal(t, SearchResult{Kind: "provider", Namespace: "hashicorp", Name: "consul", Versions: 1}, kinds["provider"])
+	assert.Equal(t, SearchResult{Kind: "module", Namespace: "hashicorp", Name: "consul", System: "aws", Versions: 1}, kinds["module"])
+
+	t.Run("limit", func(t *testing.T) {
+		w := search("?q=hashicorp&limit=1")
+		require.Equal(t, http.StatusOK, w.Code)
+		var response SearchResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Len(t, response.Results, 1)
+	})
+
+	t.Run("missing query", func(t *testing.T) {
+		w := search("?q=%20")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
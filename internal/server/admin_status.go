@@ -0,0 +1,140 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// MirrorStatusResponse is a single snapshot of the whole mirror, for
+// dashboards and chat integrations that do not scrape Prometheus
+type MirrorStatusResponse struct {
+	GeneratedAt    string `json:"generated_at"`
+	Providers      int64  `json:"providers"`
+	Modules        int64  `json:"modules"`
+	TotalSizeBytes int64  `json:"total_size_bytes"`
+	TotalSizeHuman string `json:"total_size_human"`
+
+	Jobs         JobStatusCounts       `json:"jobs"`
+	Cache        CacheStatusSummary    `json:"cache"`
+	AutoDownload AutoDownloadSummary   `json:"auto_download"`
+	Processor    ProcessorStatusDetail `json:"processor"`
+	Quota        QuotaStatus           `json:"quota"`
+}
+
+// JobStatusCounts counts download jobs in the states that need attention
+type JobStatusCounts struct {
+	Active  int64 `json:"active"`
+	Pending int64 `json:"pending"`
+	Failed  int64 `json:"failed"`
+}
+
+// CacheStatusSummary reports how well the cache is serving requests
+type CacheStatusSummary struct {
+	Enabled bool    `json:"enabled"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"` // Percentage
+}
+
+// AutoDownloadSummary reports on-demand download activity since startup
+type AutoDownloadSummary struct {
+	Enabled             bool  `json:"enabled"`
+	TotalRequests       int64 `json:"total_requests"`
+	SuccessfulDownloads int64 `json:"successful_downloads"`
+	FailedDownloads     int64 `json:"failed_downloads"`
+	NamespaceBlocked    int64 `json:"namespace_blocked"`
+	BytesDownloaded     int64 `json:"bytes_downloaded"`
+}
+
+// ProcessorStatusDetail reports whether the job processor is running
+type ProcessorStatusDetail struct {
+	Running           bool `json:"running"`
+	ActiveJobs        int  `json:"active_jobs"`
+	MaxConcurrentJobs int  `json:"max_concurrent_jobs"`
+}
+
+// QuotaStatus reports storage usage against the configured quota
+type QuotaStatus struct {
+	Enabled      bool    `json:"enabled"`
+	UsedBytes    int64   `json:"used_bytes"`
+	LimitBytes   int64   `json:"limit_bytes,omitempty"`
+	UsagePercent float64 `json:"usage_percent"`
+}
+
+// handleMirrorStatus assembles provider, module, job, cache, auto-download,
+// processor and quota figures into one response
+// GET /admin/api/status
+func (s *Server) handleMirrorStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	providerStats, err := s.providerRepo.GetStorageStats(ctx)
+	if err != nil {
+		log.Printf("Error getting provider stats: %v", err)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to get provider stats")
+		return
+	}
+	moduleStats, err := s.moduleRepo.GetStorageStats(ctx)
+	if err != nil {
+		log.Printf("Error getting module stats: %v", err)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to get module stats")
+		return
+	}
+
+	var jobs JobStatusCounts
+	for status, count := range map[string]*int64{
+		"running": &jobs.Active,
+		"pending": &jobs.Pending,
+		"failed":  &jobs.Failed,
+	} {
+		if *count, err = s.jobRepo.CountByStatus(ctx, status); err != nil {
+			log.Printf("Error counting %s jobs: %v", status, err)
+			respondError(w, http.StatusInternalServerError, "database_error", "Failed to count jobs")
+			return
+		}
+	}
+
+	totalSize := providerStats.TotalSizeBytes + moduleStats.TotalSizeBytes
+	response := MirrorStatusResponse{
+		GeneratedAt:    formatTimestamp(time.Now()),
+		Providers:      providerStats.TotalProviders,
+		Modules:        moduleStats.TotalModules,
+		TotalSizeBytes: totalSize,
+		TotalSizeHuman: formatBytes(totalSize),
+		Jobs:           jobs,
+		Quota:          QuotaStatus{Enabled: s.config.Quota.Enabled, UsedBytes: totalSize},
+	}
+
+	cacheStats := s.cache.Stats()
+	response.Cache = CacheStatusSummary{
+		// A NoOp cache reports no size and never records a lookup
+		Enabled: cacheStats.MaxSize != 0 || cacheStats.Hits != 0 || cacheStats.Misses != 0,
+		Hits:    cacheStats.Hits,
+		Misses:  cacheStats.Misses,
+		HitRate: cacheStats.HitRate(),
+	}
+
+	if s.autoDownloadService != nil {
+		stats := s.autoDownloadService.GetStats()
+		response.AutoDownload = AutoDownloadSummary{
+			Enabled:             s.autoDownloadService.IsEnabled(),
+			TotalRequests:       stats.TotalRequests,
+			SuccessfulDownloads: stats.SuccessfulDownloads,
+			FailedDownloads:     stats.FailedDownloads,
+			NamespaceBlocked:    stats.NamespaceBlocked,
+			BytesDownloaded:     stats.BytesDownloaded,
+		}
+	}
+
+	processorStatus := s.processorService.GetStatus()
+	response.Processor.Running, _ = processorStatus["running"].(bool)
+	response.Processor.ActiveJobs, _ = processorStatus["active_jobs"].(int)
+	response.Processor.MaxConcurrentJobs, _ = processorStatus["max_concurrent_jobs"].(int)
+
+	if s.config.Quota.Enabled && s.config.Quota.MaxStorageGB > 0 {
+		response.Quota.LimitBytes = int64(s.config.Quota.MaxStorageGB) << 30
+		response.Quota.UsagePercent = float64(totalSize) / float64(response.Quota.LimitBytes) * 100
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/cache"
+	"github.com/ned1313/terraform-mirror/internal/config"
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleMirrorStatus(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, platform := range []string{"linux_amd64", "darwin_arm64"} {
+		require.NoError(t, server.providerRepo.Create(ctx, &database.Provider{
+			Namespace: "hashicorp",
+			Type:      "random",
+			Version:   "3.5.0",
+			Platform:  platform,
+			Filename:  "terraform-provider-random_3.5.0_" + platform + ".zip",
+			Shasum:    "abc123",
+			S3Key:     "providers/hashicorp/random/3.5.0/" + platform + ".zip",
+			SizeBytes: 1000,
+		}))
+	}
+	require.NoError(t, server.moduleRepo.Create(ctx, &database.Module{
+		Namespace: "hashicorp",
+		Name:      "consul",
+		System:    "aws",
+		Version:   "1.0.0",
+		S3Key:     "modules/hashicorp/consul/aws/1.0.0.tar.gz",
+		Filename:  "1.0.0.tar.gz",
+		SizeBytes: 500,
+	}))
+	for _, status := range []string{"pending", "pending", "running", "failed", "completed"} {
+		require.NoError(t, server.jobRepo.Create(ctx, &database.DownloadJob{SourceType: "hcl", SourceData: "{}", Status: status}))
+	}
+
+	// One hit and one miss
+	mc, err := cache.NewMemoryCache(cache.MemoryCacheConfig{MaxSizeMB: 1})
+	require.NoError(t, err)
+	server.cache = mc
+	require.NoError(t, mc.Set(ctx, "key", bytes.NewReader([]byte("value")), "text/plain", 5, time.Minute))
+	reader, _, found := mc.Get(ctx, "key")
+	require.True(t, found)
+	reader.Close()
+	_, _, found = mc.Get(ctx, "missing")
+	require.False(t, found)
+
+	server.autoDownloadService = provider.NewAutoDownloadService(&config.AutoDownloadConfig{
+		Enabled:            true,
+		Platforms:          []string{"linux_amd64"},
+		RateLimitPerMinute: 600,
+		MaxConcurrentDL:    1,
+		QueueSize:          1,
+		TimeoutSeconds:     30,
+		BlockedNamespaces:  []string{"blocked"},
+	}, &server.config.Providers, server.storage, server.db)
+	_, err = server.autoDownloadService.DownloadProvider(ctx, "blocked", "example", "1.0.0", "linux", "amd64")
+	require.Error(t, err)
+
+	server.config.Quota = config.QuotaConfig{Enabled: true, MaxStorageGB: 1}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/status", nil)
+	addAuthHeader(req, getAuthToken(t, server))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response MirrorStatusResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+
+	assert.NotEmpty(t, response.GeneratedAt)
+	assert.Equal(t, int64(2), response.Providers)
+	assert.Equal(t, int64(1), response.Modules)
+	assert.Equal(t, int64(2500), response.TotalSizeBytes)
+	assert.Equal(t, JobStatusCounts{Active: 1, Pending: 2, Failed: 1}, response.Jobs)
+
+	assert.True(t, response.Cache.Enabled)
+	assert.Equal(t, int64(1), response.Cache.Hits)
+	assert.Equal(t, int64(1), response.Cache.Misses)
+	assert.InDelta(t, 50.0, response.Cache.HitRate, 0.001)
+
+	assert.True(t, response.AutoDownload.Enabled)
+	assert.Equal(t, int64(1), response.AutoDownload.TotalRequests)
+	assert.Equal(t, int64(1), response.AutoDownload.NamespaceBlocked)
+
+	assert.False(t, response.Processor.Running)
+	assert.Equal(t, 3, response.Processor.MaxConcurrentJobs)
+
+	assert.True(t, response.Quota.Enabled)
+	assert.Equal(t, int64(2500), response.Quota.UsedBytes)
+	assert.Equal(t, int64(1)<<30, response.Quota.LimitBytes)
+	assert.InDelta(t, 2500.0/float64(int64(1)<<30)*100, response.Quota.UsagePercent, 1e-9)
+}
+
+func TestHandleMirrorStatus_Empty(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/status", nil)
+	addAuthHeader(req, getAuthToken(t, server))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response MirrorStatusResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Zero(t, response.Providers)
+	assert.False(t, response.Cache.Enabled)
+	assert.False(t, response.AutoDownload.Enabled)
+	assert.False(t, response.Quota.Enabled)
+	assert.Zero(t, response.Quota.LimitBytes)
+}
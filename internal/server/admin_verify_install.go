@@ -0,0 +1,256 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+)
+
+// defaultInstallHostname is the registry hostname Terraform puts in mirror
+// paths for providers without an explicit source host
+const defaultInstallHostname = "registry.terraform.io"
+
+// VerifyInstallResponse reports whether Terraform could install a provider
+// version from the mirror. Error is set when the version could not be found
+// in the mirror documents, before any archive was checked.
+type VerifyInstallResponse struct {
+	Provider  string                 `json:"provider"`
+	Version   string                 `json:"version"`
+	Status    string                 `json:"status"` // pass or fail
+	Error     string                 `json:"error,omitempty"`
+	Platforms []PlatformInstallCheck `json:"platforms"`
+}
+
+// PlatformInstallCheck is the result of downloading one platform's archive
+// from the URL the mirror advertises
+type PlatformInstallCheck struct {
+	Platform     string `json:"platform"`
+	Status       string `json:"status"` // pass or fail
+	URL          string `json:"url"`
+	ExpectedHash string `json:"expected_hash,omitempty"`
+	ActualHash   string `json:"actual_hash,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// handleVerifyProviderInstall repeats what Terraform does to install a
+// provider from the mirror: it reads index.json and the version document as
+// an anonymous client, downloads every advertised archive from its URL, and
+// compares each with its zh: hash. This catches broken storage keys or URL
+// generation before a user does. Archives served by this mirror's blob
+// endpoint are fetched in-process; other URLs, such as presigned S3 URLs,
+// are fetched over HTTP.
+// POST /admin/api/providers/{namespace}/{type}/{version}/verify-install
+// Optional query parameter "hostname" (default registry.terraform.io)
+func (s *Server) handleVerifyProviderInstall(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace := chi.URLParam(r, "namespace")
+	providerType := chi.URLParam(r, "type")
+	version := chi.URLParam(r, "version")
+
+	hostname := r.URL.Query().Get("hostname")
+	if hostname == "" {
+		hostname = defaultInstallHostname
+	}
+
+	// Only verify what is mirrored, so the check never triggers auto-download
+	existing, err := s.providerRepo.ListVersions(ctx, namespace, providerType)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list provider versions")
+		return
+	}
+	var platforms []string
+	for _, p := range existing {
+		if p.Version == version && !p.Blocked {
+			platforms = append(platforms, p.Platform)
+		}
+	}
+	if len(platforms) == 0 {
+		respondError(w, http.StatusNotFound, "not_found",
+			fmt.Sprintf("Provider %s/%s version %s is not mirrored", namespace, providerType, version))
+		return
+	}
+
+	report := &VerifyInstallResponse{
+		Provider:  hostname + "/" + namespace + "/" + providerType,
+		Version:   version,
+		Status:    "pass",
+		Platforms: []PlatformInstallCheck{},
+	}
+	s.verifyInstall(ctx, report, platforms)
+
+	s.logAuditEvent(r, "verify_install", "provider", report.Provider+"/"+version, report.Status == "pass", report.Error,
+		map[string]interface{}{"platforms": len(report.Platforms)})
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// verifyInstall fills in report, marking it failed on the first document
+// error or any failed platform. Mirrored platforms missing from the version
+// document fail too, since Terraform could not install them.
+func (s *Server) verifyInstall(ctx context.Context, report *VerifyInstallResponse, mirrored []string) {
+	base := "/" + report.Provider + "/"
+
+	var index struct {
+		Versions map[string]json.RawMessage `json:"versions"`
+	}
+	if err := s.fetchMirrorDocument(ctx, base+"index.json", &index); err != nil {
+		report.Status, report.Error = "fail", err.Error()
+		return
+	}
+	if _, ok := index.Versions[report.Version]; !ok {
+		report.Status, report.Error = "fail", "version is not listed in index.json"
+		return
+	}
+
+	var document struct {
+		Archives map[string]struct {
+			URL    string   `json:"url"`
+			Hashes []string `json:"hashes"`
+		} `json:"archives"`
+	}
+	if err := s.fetchMirrorDocument(ctx, base+report.Version+".json", &document); err != nil {
+		report.Status, report.Error = "fail", err.Error()
+		return
+	}
+
+	platforms := append([]string(nil), mirrored...)
+	for platform := range document.Archives {
+		platforms = append(platforms, platform)
+	}
+	sort.Strings(platforms)
+
+	for i, platform := range platforms {
+		if i > 0 && platform == platforms[i-1] {
+			continue
+		}
+		archive, advertised := document.Archives[platform]
+		check := PlatformInstallCheck{Platform: platform, Status: "pass", URL: archive.URL}
+		if !advertised {
+			check.Status, check.Error = "fail", "mirrored platform is not listed in "+report.Version+".json"
+			report.Status = "fail"
+			report.Platforms = append(report.Platforms, check)
+			continue
+		}
+		for _, h := range archive.Hashes {
+			if strings.HasPrefix(h, "zh:") {
+				check.ExpectedHash = h
+			}
+		}
+
+		if check.ExpectedHash == "" {
+			check.Status, check.Error = "fail", "no zh: hash advertised"
+		} else if actual, err := s.hashArchiveURL(ctx, archive.URL); err != nil {
+			check.Status, check.Error = "fail", err.Error()
+		} else {
+			check.ActualHash = "zh:" + actual
+			if !strings.EqualFold(check.ActualHash, check.ExpectedHash) {
+				check.Status, check.Error = "fail", "archive does not match its advertised hash"
+			}
+		}
+
+		if check.Status == "fail" {
+			report.Status = "fail"
+		}
+		report.Platforms = append(report.Platforms, check)
+	}
+}
+
+// fetchMirrorDocument serves a mirror protocol document in-process as an
+// anonymous client would receive it and decodes it into v
+func (s *Server) fetchMirrorDocument(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	rec := &indexResponseRecorder{header: make(http.Header), status: http.StatusOK}
+	s.handleMirrorCatchAll(rec, req)
+	if rec.status != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", path, rec.status)
+	}
+
+	// The request does not accept gzip, so cached documents come back plain
+	if err := json.Unmarshal(rec.body.Bytes(), v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// hashArchiveURL downloads an archive from its advertised URL and returns
+// its SHA256 hex digest
+func (s *Server) hashArchiveURL(ctx context.Context, archiveURL string) (string, error) {
+	if blobPath, ok := s.localBlobPath(ctx, archiveURL); ok {
+		// The route state of the admin request would otherwise be reused,
+		// sending the download to the wrong handler
+		routeCtx := context.WithValue(ctx, chi.RouteCtxKey, nil)
+		req, err := http.NewRequestWithContext(routeCtx, http.MethodGet, blobPath, nil)
+		if err != nil {
+			return "", err
+		}
+		hw := &hashingResponseWriter{header: make(http.Header), status: http.StatusOK, hash: sha256.New()}
+		s.router.ServeHTTP(hw, req)
+		if hw.status != http.StatusOK {
+			return "", fmt.Errorf("download returned status %d", hw.status)
+		}
+		return hex.EncodeToString(hw.hash.Sum(nil)), nil
+	}
+
+	if !strings.HasPrefix(archiveURL, "http://") && !strings.HasPrefix(archiveURL, "https://") {
+		return "", fmt.Errorf("archive URL is not an HTTP URL Terraform can download")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, archiveURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// localBlobPath returns the /blobs/ path of a URL served by this mirror,
+// whether built from server.public_url or from the request host
+func (s *Server) localBlobPath(ctx context.Context, archiveURL string) (string, bool) {
+	for _, base := range []string{storage.BaseURLFromContext(ctx), s.config.Server.PublicURL} {
+		if base == "" {
+			continue
+		}
+		prefix := strings.TrimSuffix(base, "/") + "/blobs/"
+		if strings.HasPrefix(archiveURL, prefix) {
+			return "/blobs/" + strings.TrimPrefix(archiveURL, prefix), true
+		}
+	}
+	return "", false
+}
+
+// hashingResponseWriter hashes a response body instead of keeping it
+type hashingResponseWriter struct {
+	header http.Header
+	status int
+	hash   hash.Hash
+}
+
+func (hw *hashingResponseWriter) Header() http.Header { return hw.header }
+
+func (hw *hashingResponseWriter) WriteHeader(status int) { hw.status = status }
+
+func (hw *hashingResponseWriter) Write(b []byte) (int, error) { return hw.hash.Write(b) }
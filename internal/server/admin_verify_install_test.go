@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleVerifyProviderInstall(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	// Blob URLs follow the request host, as they do for local storage in main
+	server.config.Storage.Type = "local"
+	server.setupRouter()
+
+	ctx := context.Background()
+	content := map[string]string{
+		"linux_amd64":  "linux-provider-binary",
+		"darwin_arm64": "darwin-provider-binary",
+	}
+	keys := make(map[string]string)
+	for platform, data := range content {
+		filename := "terraform-provider-random_3.5.0_" + platform + ".zip"
+		key := "providers/registry.terraform.io/hashicorp/random/3.5.0/" + platform + "/" + filename
+		keys[platform] = key
+		require.NoError(t, server.storage.Upload(ctx, key, strings.NewReader(data), "application/zip", nil))
+
+		sum := sha256.Sum256([]byte(data))
+		require.NoError(t, server.providerRepo.Create(ctx, &database.Provider{
+			Namespace: "hashicorp",
+			Type:      "random",
+			Version:   "3.5.0",
+			Platform:  platform,
+			Filename:  filename,
+			Shasum:    hex.EncodeToString(sum[:]),
+			S3Key:     key,
+		}))
+	}
+
+	token := getAuthToken(t, server)
+	verify := func(path string) (*httptest.ResponseRecorder, VerifyInstallResponse) {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		addAuthHeader(req, token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		var report VerifyInstallResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		}
+		return w, report
+	}
+
+	t.Run("correctly mirrored version passes", func(t *testing.T) {
+		w, report := verify("/admin/api/providers/hashicorp/random/3.5.0/verify-install")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "pass", report.Status)
+		assert.Equal(t, "registry.terraform.io/hashicorp/random", report.Provider)
+		require.Len(t, report.Platforms, 2)
+		for _, check := range report.Platforms {
+			assert.Equal(t, "pass", check.Status, check.Error)
+			assert.Equal(t, check.ExpectedHash, check.ActualHash)
+			assert.Contains(t, check.URL, "/blobs/"+keys[check.Platform])
+		}
+	})
+
+	t.Run("missing blob fails its platform", func(t *testing.T) {
+		require.NoError(t, server.storage.Delete(ctx, keys["darwin_arm64"]))
+
+		w, report := verify("/admin/api/providers/hashicorp/random/3.5.0/verify-install")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "fail", report.Status)
+		require.Len(t, report.Platforms, 2)
+		assert.Equal(t, "darwin_arm64", report.Platforms[0].Platform)
+		assert.Equal(t, "fail", report.Platforms[0].Status)
+		assert.Contains(t, report.Platforms[0].Error, "not listed in 3.5.0.json")
+		assert.Equal(t, "pass", report.Platforms[1].Status)
+	})
+
+	t.Run("changed blob fails its hash", func(t *testing.T) {
+		require.NoError(t, server.storage.Upload(ctx, keys["linux_amd64"], strings.NewReader("tampered"), "application/zip", nil))
+
+		_, report := verify("/admin/api/providers/hashicorp/random/3.5.0/verify-install")
+		assert.Equal(t, "fail", report.Status)
+		require.Len(t, report.Platforms, 2)
+		linux := report.Platforms[1]
+		assert.Equal(t, "fail", linux.Status)
+		assert.NotEqual(t, linux.ExpectedHash, linux.ActualHash)
+	})
+
+	t.Run("version not mirrored", func(t *testing.T) {
+		w, _ := verify("/admin/api/providers/hashicorp/random/9.9.9/verify-install")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
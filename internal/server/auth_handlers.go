@@ -28,7 +28,7 @@ type LoginRequest struct {
 // LoginResponse represents the login response
 type LoginResponse struct {
 	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
+	ExpiresAt Timestamp `json:"expires_at"`
 	User      UserInfo  `json:"user"`
 }
 
@@ -62,12 +62,14 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if user == nil || !user.Active {
+		s.recordAuthAttempt("failure")
 		respondError(w, http.StatusUnauthorized, "invalid_credentials", "Invalid username or password")
 		return
 	}
 
 	// Verify password
 	if err := s.authService.VerifyPassword(user.PasswordHash, req.Password); err != nil {
+		s.recordAuthAttempt("failure")
 		respondError(w, http.StatusUnauthorized, "invalid_credentials", "Invalid username or password")
 		return
 	}
@@ -107,6 +109,9 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.recordAuthAttempt("success")
+	s.updateActiveSessions(r.Context())
+
 	// Update last login timestamp
 	if err := userRepo.UpdateLastLogin(r.Context(), user.ID); err != nil {
 		// Log but don't fail
@@ -116,7 +121,7 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	// Return token
 	response := LoginResponse{
 		Token:     token,
-		ExpiresAt: expiresAt,
+		ExpiresAt: Timestamp{expiresAt},
 		User: UserInfo{
 			ID:       user.ID,
 			Username: user.Username,
@@ -159,6 +164,8 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.updateActiveSessions(r.Context())
+
 	// Log successful logout
 	s.logAuditEvent(r, "logout", "session", claims.ID, true, "", map[string]interface{}{
 		"username": claims.Username,
@@ -171,6 +178,27 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// recordAuthAttempt records the result of a login attempt when metrics are enabled
+func (s *Server) recordAuthAttempt(result string) {
+	if s.metrics != nil {
+		s.metrics.RecordAuthAttempt(result)
+	}
+}
+
+// updateActiveSessions refreshes the active session gauge when metrics are enabled
+func (s *Server) updateActiveSessions(ctx context.Context) {
+	if s.metrics == nil {
+		return
+	}
+
+	count, err := database.NewSessionRepository(s.db).CountActive(ctx)
+	if err != nil {
+		s.logger.Printf("Failed to count active sessions: %v", err)
+		return
+	}
+	s.metrics.SetActiveSessions(count)
+}
+
 // authMiddleware validates JWT tokens and injects user context
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
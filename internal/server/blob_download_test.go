@@ -0,0 +1,617 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/cache"
+	"github.com/ned1313/terraform-mirror/internal/config"
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/metrics"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingCache is a cache whose writes always fail, as with a full disk
+type failingCache struct {
+	setCalls int
+}
+
+func (f *failingCache) Get(ctx context.Context, key string) (io.ReadCloser, string, bool) {
+	return nil, "", false
+}
+
+func (f *failingCache) Set(ctx context.Context, key string, data io.Reader, contentType string, size int64, ttl time.Duration) error {
+	f.setCalls++
+	return fmt.Errorf("failed to write cache file: %w", syscall.ENOSPC)
+}
+
+func (f *failingCache) Delete(ctx context.Context, key string) error { return nil }
+func (f *failingCache) Exists(ctx context.Context, key string) bool  { return false }
+func (f *failingCache) Clear(ctx context.Context) error              { return nil }
+func (f *failingCache) Stats() cache.CacheStats                      { return cache.CacheStats{} }
+func (f *failingCache) Close() error                                 { return nil }
+
+// setupBlobTest creates a server backed by mock storage and the given cache
+func setupBlobTest(t *testing.T, c cache.Cache) (*Server, *storage.MockStorage) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		Auth: config.AuthConfig{
+			JWTExpirationHours: 24,
+			BCryptCost:         10,
+			JWTSecret:          "test-secret-key-for-testing",
+		},
+		Processor: config.ProcessorConfig{
+			PollingIntervalSeconds: 10,
+			MaxConcurrentJobs:      3,
+			RetryAttempts:          3,
+			RetryDelaySeconds:      5,
+			WorkerShutdownSeconds:  30,
+		},
+	}
+
+	store := storage.NewMockStorage()
+	srv := NewWithCache(cfg, db, store, c)
+
+	t.Cleanup(func() {
+		srv.Shutdown(context.Background())
+		db.Close()
+	})
+
+	return srv, store
+}
+
+func TestHandleBlobDownload_CacheWriteFailure(t *testing.T) {
+	fc := &failingCache{}
+	srv, store := setupBlobTest(t, fc)
+
+	key := "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip"
+	store.SetData(key, []byte("provider-binary"))
+
+	req := httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil)
+	w := httptest.NewRecorder()
+
+	srv.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "provider-binary", w.Body.String())
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Equal(t, 1, fc.setCalls)
+}
+
+func TestHandleBlobDownload_ServesFromCache(t *testing.T) {
+	mc, err := cache.NewMemoryCache(cache.MemoryCacheConfig{MaxSizeMB: 1})
+	require.NoError(t, err)
+	srv, store := setupBlobTest(t, mc)
+
+	key := "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip"
+	store.SetData(key, []byte("provider-binary"))
+
+	// First request populates the cache from storage
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Remove the object from storage; the second request must be served from cache
+	require.NoError(t, store.Delete(context.Background(), key))
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "provider-binary", w.Body.String())
+}
+
+func TestHandleBlobDownload_ServesStaleOnStorageFailure(t *testing.T) {
+	mc, err := cache.NewMemoryCache(cache.MemoryCacheConfig{MaxSizeMB: 1, StaleRetention: time.Hour})
+	require.NoError(t, err)
+	srv, store := setupBlobTest(t, mc)
+	srv.storage = &brokenDownloadStorage{Storage: store}
+
+	key := "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip"
+	require.NoError(t, mc.Set(context.Background(), key, bytes.NewReader([]byte("stale-binary")), "application/zip", 12, time.Millisecond))
+	time.Sleep(10 * time.Millisecond)
+
+	// Without serve_stale_on_error the expired entry is not used
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	srv.config.Cache.ServeStaleOnError = true
+	srv.config.Cache.MaxStaleSeconds = 3600
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "stale-binary", w.Body.String())
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+	// Entries older than max_stale_seconds are not served
+	srv.config.Cache.MaxStaleSeconds = 0
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestHandleBlobDownload_DoesNotServeStaleDeletedBlob(t *testing.T) {
+	mc, err := cache.NewMemoryCache(cache.MemoryCacheConfig{MaxSizeMB: 1, StaleRetention: time.Hour})
+	require.NoError(t, err)
+	srv, _ := setupBlobTest(t, mc)
+	srv.config.Cache.ServeStaleOnError = true
+	srv.config.Cache.MaxStaleSeconds = 3600
+
+	// Storage has no such object, so the expired entry must not revive it
+	key := "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip"
+	require.NoError(t, mc.Set(context.Background(), key, bytes.NewReader([]byte("stale-binary")), "application/zip", 12, time.Millisecond))
+	time.Sleep(10 * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandleBlobDownload_NotFound(t *testing.T) {
+	srv, _ := setupBlobTest(t, nil)
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/providers/missing.zip", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// countingStorage wraps a storage backend and counts downloads, delaying each
+// one so concurrent requests overlap
+type countingStorage struct {
+	storage.Storage
+	downloads atomic.Int64
+	delay     time.Duration
+}
+
+func (c *countingStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	c.downloads.Add(1)
+	time.Sleep(c.delay)
+	return c.Storage.Download(ctx, key)
+}
+
+func TestHandleBlobDownload_CoalescesConcurrentMisses(t *testing.T) {
+	srv, store := setupBlobTest(t, nil)
+
+	key := "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip"
+	store.SetData(key, []byte("provider-binary"))
+
+	counting := &countingStorage{Storage: store, delay: 200 * time.Millisecond}
+	srv.storage = counting
+
+	const requests = 20
+	var wg sync.WaitGroup
+	codes := make([]int, requests)
+	bodies := make([]string, requests)
+
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+			codes[i] = w.Code
+			bodies[i] = w.Body.String()
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < requests; i++ {
+		assert.Equal(t, http.StatusOK, codes[i])
+		assert.Equal(t, "provider-binary", bodies[i])
+	}
+	assert.Equal(t, int64(1), counting.downloads.Load())
+}
+
+func TestCacheTTL_IndexExpiresBeforeBlob(t *testing.T) {
+	mc, err := cache.NewMemoryCache(cache.MemoryCacheConfig{MaxSizeMB: 1})
+	require.NoError(t, err)
+	srv, store := setupBlobTest(t, mc)
+	srv.config.Cache = config.CacheConfig{
+		TTLSeconds:      3600,
+		BlobTTLSeconds:  86400,
+		IndexTTLSeconds: 1,
+	}
+
+	key := "providers/registry.terraform.io/hashicorp/random/3.0.0/linux_amd64/terraform-provider-random_3.0.0_linux_amd64.zip"
+	store.SetData(key, []byte("provider-binary"))
+	require.NoError(t, srv.providerRepo.Create(context.Background(), &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "random",
+		Version:   "3.0.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-random_3.0.0_linux_amd64.zip",
+		S3Key:     key,
+	}))
+
+	indexPath := "/registry.terraform.io/hashicorp/random/index.json"
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, indexPath, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	indexItem, ok := mc.GetItem(mirrorIndexCacheKey("hashicorp", "random", indexPath))
+	require.True(t, ok, "index response should be cached")
+	blobItem, ok := mc.GetItem(key)
+	require.True(t, ok, "blob should be cached")
+	assert.True(t, indexItem.ExpiresAt.Before(blobItem.ExpiresAt))
+
+	// A cached index is served without consulting the database
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, indexPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"versions": {"3.0.0": {}}}`, w.Body.String())
+
+	// Once the index TTL passes the index is gone while the blob remains
+	assert.Eventually(t, func() bool {
+		return !mc.Exists(context.Background(), mirrorIndexCacheKey("hashicorp", "random", indexPath))
+	}, 3*time.Second, 50*time.Millisecond)
+	assert.True(t, mc.Exists(context.Background(), key))
+}
+
+func TestHandleDeleteProvider_InvalidatesCache(t *testing.T) {
+	mc, err := cache.NewMemoryCache(cache.MemoryCacheConfig{MaxSizeMB: 1})
+	require.NoError(t, err)
+	srv, store := setupBlobTest(t, mc)
+	ctx := context.Background()
+
+	key := "providers/registry.terraform.io/hashicorp/random/3.0.0/linux_amd64/terraform-provider-random_3.0.0_linux_amd64.zip"
+	store.SetData(key, []byte("provider-binary"))
+	p := &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "random",
+		Version:   "3.0.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-random_3.0.0_linux_amd64.zip",
+		S3Key:     key,
+	}
+	require.NoError(t, srv.providerRepo.Create(ctx, p))
+
+	// Populate the cache with the archive and the index document
+	indexPath := "/registry.terraform.io/hashicorp/random/index.json"
+	for _, path := range []string{indexPath, "/blobs/" + key} {
+		w := httptest.NewRecorder()
+		srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	require.True(t, mc.Exists(ctx, key))
+	require.True(t, mc.Exists(ctx, mirrorIndexCacheKey("hashicorp", "random", indexPath)))
+
+	// A cached document served under another hostname is evicted too
+	otherKey := mirrorIndexCacheKey("hashicorp", "random", "https://mirror.example.com/other.example.com/hashicorp/random/3.0.0.json")
+	require.NoError(t, mc.Set(ctx, otherKey, bytes.NewReader([]byte("{}")), "application/json", 2, time.Hour))
+	unrelatedKey := mirrorIndexCacheKey("hashicorp", "aws", "/registry.terraform.io/hashicorp/aws/index.json")
+	require.NoError(t, mc.Set(ctx, unrelatedKey, bytes.NewReader([]byte("{}")), "application/json", 2, time.Hour))
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/admin/api/providers/%d", p.ID), nil)
+	addAuthHeader(req, getAuthToken(t, srv))
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	assert.False(t, mc.Exists(ctx, key))
+	assert.Empty(t, mc.HasPrefix(mirrorIndexCachePrefix("hashicorp", "random")))
+	assert.True(t, mc.Exists(ctx, unrelatedKey), "other providers stay cached")
+
+	// The deleted provider is no longer served
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, indexPath, nil))
+	assert.NotContains(t, w.Body.String(), "3.0.0")
+}
+
+func TestHandleDeleteModule_InvalidatesCache(t *testing.T) {
+	mc, err := cache.NewMemoryCache(cache.MemoryCacheConfig{MaxSizeMB: 1})
+	require.NoError(t, err)
+	srv, store := setupBlobTest(t, mc)
+	ctx := context.Background()
+
+	key := "modules/hashicorp/consul/aws/0.1.0/hashicorp-consul-aws-0.1.0.tar.gz"
+	store.SetData(key, []byte("module-archive"))
+	m := &database.Module{
+		Namespace: "hashicorp",
+		Name:      "consul",
+		System:    "aws",
+		Version:   "0.1.0",
+		S3Key:     key,
+		Filename:  "hashicorp-consul-aws-0.1.0.tar.gz",
+	}
+	require.NoError(t, srv.moduleRepo.Create(ctx, m))
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.True(t, mc.Exists(ctx, key))
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/admin/api/modules/%d", m.ID), nil)
+	addAuthHeader(req, getAuthToken(t, srv))
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	assert.False(t, mc.Exists(ctx, key))
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// blockingStorage holds every download open until released, tracking the
+// peak number of downloads in progress at once
+type blockingStorage struct {
+	storage.Storage
+	release chan struct{}
+	active  atomic.Int64
+	peak    atomic.Int64
+}
+
+// blockingStorageTimeout bounds how long a download waits for release, so a
+// test that never releases fails instead of hanging
+const blockingStorageTimeout = 10 * time.Second
+
+func (b *blockingStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	n := b.active.Add(1)
+	defer b.active.Add(-1)
+	for {
+		peak := b.peak.Load()
+		if n <= peak || b.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(blockingStorageTimeout):
+		return nil, fmt.Errorf("download of %s was never released", key)
+	}
+	return b.Storage.Download(ctx, key)
+}
+
+func TestHandleBlobDownload_ConcurrencyLimit(t *testing.T) {
+	srv, store := setupBlobTest(t, nil)
+	blocking := &blockingStorage{Storage: store, release: make(chan struct{})}
+	srv.storage = blocking
+	srv.downloadLimiter = newDownloadLimiter(2, 0)
+	srv.setupRouter()
+
+	// Distinct keys so requests are not coalesced into one storage read
+	const requests = 5
+	keys := make([]string, requests)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("providers/registry.terraform.io/hashicorp/aws/5.%d.0/linux_amd64/provider.zip", i)
+		store.SetData(keys[i], []byte("provider-binary"))
+	}
+
+	var wg sync.WaitGroup
+	recorders := make([]*httptest.ResponseRecorder, requests)
+	for i := range keys {
+		recorders[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			srv.router.ServeHTTP(recorders[i], httptest.NewRequest(http.MethodGet, "/blobs/"+keys[i], nil))
+		}(i)
+	}
+
+	// Two downloads hold the slots; the rest are rejected without queueing
+	require.Eventually(t, func() bool { return blocking.active.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	close(blocking.release)
+	wg.Wait()
+
+	var ok, rejected int
+	for _, w := range recorders {
+		switch w.Code {
+		case http.StatusOK:
+			ok++
+		case http.StatusServiceUnavailable:
+			rejected++
+			assert.NotEmpty(t, w.Header().Get("Retry-After"))
+		}
+	}
+	assert.Equal(t, 2, ok)
+	assert.Equal(t, 3, rejected)
+	assert.Equal(t, int64(2), blocking.peak.Load())
+}
+
+func TestHandleBlobDownload_ConcurrencyLimitQueues(t *testing.T) {
+	srv, store := setupBlobTest(t, nil)
+	blocking := &blockingStorage{Storage: store, release: make(chan struct{})}
+	srv.storage = blocking
+	srv.downloadLimiter = newDownloadLimiter(1, 5*time.Second)
+	srv.setupRouter()
+
+	const requests = 3
+	var wg sync.WaitGroup
+	codes := make([]int, requests)
+	for i := 0; i < requests; i++ {
+		key := fmt.Sprintf("providers/registry.terraform.io/hashicorp/aws/5.%d.0/linux_amd64/provider.zip", i)
+		store.SetData(key, []byte("provider-binary"))
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+			codes[i] = w.Code
+		}(i)
+	}
+
+	// Queued requests proceed one at a time as slots free up
+	require.Eventually(t, func() bool { return blocking.active.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	close(blocking.release)
+	wg.Wait()
+
+	for _, code := range codes {
+		assert.Equal(t, http.StatusOK, code)
+	}
+	assert.Equal(t, int64(1), blocking.peak.Load())
+}
+
+func TestShutdown_WaitsForInFlightBlobDownload(t *testing.T) {
+	srv, store := setupBlobTest(t, nil)
+	srv.config.Server.ShutdownDownloadWaitSeconds = 5
+
+	key := "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip"
+	payload := bytes.Repeat([]byte("provider-binary"), 64*1024)
+	store.SetData(key, payload)
+
+	blocking := &blockingStorage{Storage: store, release: make(chan struct{})}
+	srv.storage = blocking
+
+	ts := httptest.NewServer(srv.router)
+	defer ts.Close()
+	srv.server = ts.Config
+
+	type download struct {
+		code int
+		body []byte
+		err  error
+	}
+	result := make(chan download, 1)
+	go func() {
+		resp, err := http.Get(ts.URL + "/blobs/" + key)
+		if err != nil {
+			result <- download{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		result <- download{code: resp.StatusCode, body: body, err: err}
+	}()
+	require.Eventually(t, func() bool { return blocking.active.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// The HTTP drain deadline passes while the download is still in progress
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- srv.Shutdown(ctx) }()
+
+	// New downloads are refused once draining. Probing earlier would start
+	// a download that waits on the blocked one.
+	require.Eventually(t, func() bool {
+		srv.blobDrain.mu.Lock()
+		defer srv.blobDrain.mu.Unlock()
+		return srv.blobDrain.draining
+	}, 5*time.Second, 10*time.Millisecond)
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	close(blocking.release)
+
+	select {
+	case got := <-result:
+		require.NoError(t, got.err)
+		assert.Equal(t, http.StatusOK, got.code)
+		assert.Equal(t, len(payload), len(got.body))
+	case <-time.After(10 * time.Second):
+		t.Fatal("in-flight download did not finish")
+	}
+	select {
+	case err := <-shutdownErr:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("shutdown did not return")
+	}
+}
+
+func TestBlobDownload_AuditLog(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled=%t", enabled), func(t *testing.T) {
+			srv, store := setupBlobTest(t, nil)
+			srv.config.Logging.AuditDownloads = enabled
+
+			key := "providers/registry.terraform.io/hashicorp/random/3.0.0/linux_amd64/terraform-provider-random_3.0.0_linux_amd64.zip"
+			store.SetData(key, []byte("provider-binary"))
+
+			req := httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil)
+			req.RemoteAddr = "198.51.100.20:51234"
+			w := httptest.NewRecorder()
+			srv.router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			downloads := func() []*database.AdminAction {
+				actions, err := srv.auditRepo.ListByAction(context.Background(), "download", 10, 0)
+				require.NoError(t, err)
+				return actions
+			}
+
+			if !enabled {
+				// The write is asynchronous, so give it time to (not) land
+				assert.Never(t, func() bool { return len(downloads()) > 0 }, 300*time.Millisecond, 50*time.Millisecond)
+				return
+			}
+
+			require.Eventually(t, func() bool { return len(downloads()) == 1 }, 2*time.Second, 20*time.Millisecond)
+			entry := downloads()[0]
+			assert.Equal(t, "blob", entry.ResourceType)
+			assert.Equal(t, key, entry.ResourceID.String)
+			assert.Equal(t, "198.51.100.20", entry.IPAddress.String)
+			assert.False(t, entry.UserID.Valid)
+			assert.False(t, entry.CreatedAt.IsZero())
+		})
+	}
+}
+
+func TestHandleBlobDownload_RecordsTransferMetrics(t *testing.T) {
+	mc, err := cache.NewMemoryCache(cache.MemoryCacheConfig{MaxSizeMB: 1})
+	require.NoError(t, err)
+	srv, store := setupBlobTest(t, mc)
+
+	// Use a private registry so observations are not shared with other tests
+	reg := prometheus.NewRegistry()
+	srv.metrics = metrics.NewWithRegistry(reg)
+
+	key := "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip"
+	payload := bytes.Repeat([]byte("x"), 4096)
+	store.SetData(key, payload)
+
+	// The first download reads storage, the second is served from cache
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, len(payload), w.Body.Len())
+	}
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	observed := make(map[string]map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			if h := metric.GetHistogram(); h != nil && h.GetSampleCount() == 1 {
+				source := metric.GetLabel()[0].GetValue()
+				if observed[family.GetName()] == nil {
+					observed[family.GetName()] = make(map[string]float64)
+				}
+				observed[family.GetName()][source] = h.GetSampleSum()
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]float64{"storage": 4096, "cache": 4096}, observed["terraform_mirror_blob_download_bytes"])
+	assert.Len(t, observed["terraform_mirror_blob_download_duration_seconds"], 2)
+}
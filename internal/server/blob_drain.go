@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// blobDrain tracks in-flight blob downloads so shutdown can let them finish.
+// The mutex orders every wg.Add before the draining flag is set, so Wait never
+// races with a new download starting.
+type blobDrain struct {
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	draining bool
+}
+
+// begin registers a download, returning false once draining has started
+func (d *blobDrain) begin() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.draining {
+		return false
+	}
+	d.wg.Add(1)
+	return true
+}
+
+// end marks a download as finished
+func (d *blobDrain) end() {
+	d.wg.Done()
+}
+
+// drain refuses new downloads and waits up to timeout for in-flight ones,
+// reporting whether they all finished
+func (d *blobDrain) drain(timeout time.Duration) bool {
+	d.mu.Lock()
+	d.draining = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Middleware tracks downloads and rejects new ones with 503 while draining
+func (d *blobDrain) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !d.begin() {
+			w.Header().Set("Connection", "close")
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		defer d.end()
+
+		next.ServeHTTP(w, r)
+	})
+}
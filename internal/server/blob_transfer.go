@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+// countingWriter counts the bytes written through a response writer
+type countingWriter struct {
+	http.ResponseWriter
+	bytes int64
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	n, err := cw.ResponseWriter.Write(b)
+	cw.bytes += int64(n)
+	return n, err
+}
+
+// serveBlob writes a blob and records the transfer. The blob has already been
+// read from source, so the time before the write is spent on the cache or
+// storage and the time writing it is spent on the client.
+func (s *Server) serveBlob(w http.ResponseWriter, key, source, contentType string, data []byte, started time.Time) {
+	fetched := time.Now()
+	cw := &countingWriter{ResponseWriter: w}
+	s.writeBlob(cw, key, contentType, data)
+	transfer := time.Since(fetched)
+
+	if s.metrics != nil {
+		s.metrics.RecordBlobDownload(source, cw.bytes, transfer.Seconds())
+	}
+
+	threshold := time.Duration(s.config.Server.SlowDownloadSeconds) * time.Second
+	if total := fetched.Sub(started) + transfer; threshold > 0 && total >= threshold {
+		s.logger.Printf("Slow blob download %s: %d bytes from %s in %s (read %s, client %s)",
+			key, cw.bytes, source, total.Round(time.Millisecond),
+			fetched.Sub(started).Round(time.Millisecond), transfer.Round(time.Millisecond))
+	}
+}
@@ -1,31 +1,49 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 
 	"github.com/ned1313/terraform-mirror/internal/cache"
+	"github.com/ned1313/terraform-mirror/internal/database"
 )
 
 // CacheStatsResponse represents cache statistics
 type CacheStatsResponse struct {
-	Enabled      bool              `json:"enabled"`
-	Hits         int64             `json:"hits"`
-	Misses       int64             `json:"misses"`
-	HitRate      float64           `json:"hit_rate"`
-	HitRateStr   string            `json:"hit_rate_str"`
-	Size         int64             `json:"size"`
-	SizeHuman    string            `json:"size_human"`
-	MaxSize      int64             `json:"max_size"`
-	MaxSizeHuman string            `json:"max_size_human"`
-	UsagePercent float64           `json:"usage_percent"`
-	ItemCount    int64             `json:"item_count"`
-	Evictions    int64             `json:"evictions"`
-	Expirations  int64             `json:"expirations"`
-	Efficiency   *CacheEfficiency  `json:"efficiency"`
-	Config       *CacheConfigInfo  `json:"config"`
-	Tiered       *TieredCacheStats `json:"tiered,omitempty"`
+	Enabled        bool              `json:"enabled"`
+	Hits           int64             `json:"hits"`
+	Misses         int64             `json:"misses"`
+	HitRate        float64           `json:"hit_rate"`
+	HitRateStr     string            `json:"hit_rate_str"`
+	Size           int64             `json:"size"`
+	SizeHuman      string            `json:"size_human"`
+	MaxSize        int64             `json:"max_size"`
+	MaxSizeHuman   string            `json:"max_size_human"`
+	UsagePercent   float64           `json:"usage_percent"`
+	ItemCount      int64             `json:"item_count"`
+	Evictions      int64             `json:"evictions"`
+	Expirations    int64             `json:"expirations"`
+	EvictionPolicy string            `json:"eviction_policy,omitempty"`
+	Efficiency     *CacheEfficiency  `json:"efficiency"`
+	Config         *CacheConfigInfo  `json:"config"`
+	Tiered         *TieredCacheStats `json:"tiered,omitempty"`
+
+	IndexCompression *IndexCompressionStats `json:"index_compression,omitempty"`
+}
+
+// IndexCompressionStats reports the savings of storing mirror protocol
+// documents compressed, counted over every document written to the cache
+type IndexCompressionStats struct {
+	DocumentsCompressed int64   `json:"documents_compressed"`
+	UncompressedBytes   int64   `json:"uncompressed_bytes"`
+	CompressedBytes     int64   `json:"compressed_bytes"`
+	BytesSaved          int64   `json:"bytes_saved"`
+	BytesSavedHuman     string  `json:"bytes_saved_human"`
+	SavingsPercent      float64 `json:"savings_percent"`
+	SavingsPercentStr   string  `json:"savings_percent_str"`
 }
 
 // CacheEfficiency contains efficiency metrics
@@ -57,18 +75,19 @@ type CacheConfigInfo struct {
 // TieredCacheStats contains detailed tiered cache statistics
 type TieredCacheStats struct {
 	// Memory cache stats
-	MemoryHits         int64   `json:"memory_hits"`
-	MemoryMisses       int64   `json:"memory_misses"`
-	MemoryHitRate      float64 `json:"memory_hit_rate"`
-	MemoryHitRateStr   string  `json:"memory_hit_rate_str"`
-	MemorySize         int64   `json:"memory_size"`
-	MemorySizeHuman    string  `json:"memory_size_human"`
-	MemoryMaxSize      int64   `json:"memory_max_size"`
-	MemoryMaxSizeHuman string  `json:"memory_max_size_human"`
-	MemoryUsagePercent float64 `json:"memory_usage_percent"`
-	MemoryItemCount    int64   `json:"memory_item_count"`
-	MemoryEvictions    int64   `json:"memory_evictions"`
-	MemoryExpirations  int64   `json:"memory_expirations"`
+	MemoryHits           int64   `json:"memory_hits"`
+	MemoryMisses         int64   `json:"memory_misses"`
+	MemoryHitRate        float64 `json:"memory_hit_rate"`
+	MemoryHitRateStr     string  `json:"memory_hit_rate_str"`
+	MemorySize           int64   `json:"memory_size"`
+	MemorySizeHuman      string  `json:"memory_size_human"`
+	MemoryMaxSize        int64   `json:"memory_max_size"`
+	MemoryMaxSizeHuman   string  `json:"memory_max_size_human"`
+	MemoryUsagePercent   float64 `json:"memory_usage_percent"`
+	MemoryItemCount      int64   `json:"memory_item_count"`
+	MemoryEvictions      int64   `json:"memory_evictions"`
+	MemoryExpirations    int64   `json:"memory_expirations"`
+	MemoryEvictionPolicy string  `json:"memory_eviction_policy"`
 
 	// Disk cache stats
 	DiskHits         int64   `json:"disk_hits"`
@@ -97,19 +116,20 @@ func (s *Server) handleCacheStats(w http.ResponseWriter, r *http.Request) {
 
 	hitRate := stats.HitRate()
 	response := CacheStatsResponse{
-		Enabled:      true,
-		Hits:         stats.Hits,
-		Misses:       stats.Misses,
-		HitRate:      hitRate,
-		HitRateStr:   formatPercent(hitRate),
-		Size:         stats.Size,
-		SizeHuman:    formatBytes(stats.Size),
-		MaxSize:      stats.MaxSize,
-		MaxSizeHuman: formatBytes(stats.MaxSize),
-		UsagePercent: stats.UsagePercent(),
-		ItemCount:    stats.ItemCount,
-		Evictions:    stats.Evictions,
-		Expirations:  stats.Expirations,
+		Enabled:        true,
+		Hits:           stats.Hits,
+		Misses:         stats.Misses,
+		HitRate:        hitRate,
+		HitRateStr:     formatPercent(hitRate),
+		Size:           stats.Size,
+		SizeHuman:      formatBytes(stats.Size),
+		MaxSize:        stats.MaxSize,
+		MaxSizeHuman:   formatBytes(stats.MaxSize),
+		UsagePercent:   stats.UsagePercent(),
+		ItemCount:      stats.ItemCount,
+		Evictions:      stats.Evictions,
+		Expirations:    stats.Expirations,
+		EvictionPolicy: stats.EvictionPolicy,
 	}
 
 	// Check if it's a NoOp cache (disabled)
@@ -151,6 +171,10 @@ func (s *Server) handleCacheStats(w http.ResponseWriter, r *http.Request) {
 		AverageItemSizeHuman: formatBytes(avgItemSize),
 	}
 
+	if s.config.Cache.CompressIndex {
+		response.IndexCompression = s.indexCompressionStats()
+	}
+
 	// If it's a tiered cache, get detailed stats
 	if tieredCache, ok := s.cache.(*cache.TieredCache); ok {
 		detailed := tieredCache.DetailedStats()
@@ -179,18 +203,19 @@ func (s *Server) handleCacheStats(w http.ResponseWriter, r *http.Request) {
 		}
 
 		response.Tiered = &TieredCacheStats{
-			MemoryHits:         detailed.MemoryHits,
-			MemoryMisses:       detailed.MemoryMisses,
-			MemoryHitRate:      memoryHitRate,
-			MemoryHitRateStr:   formatPercent(memoryHitRate),
-			MemorySize:         detailed.MemorySize,
-			MemorySizeHuman:    formatBytes(detailed.MemorySize),
-			MemoryMaxSize:      detailed.MemoryMaxSize,
-			MemoryMaxSizeHuman: formatBytes(detailed.MemoryMaxSize),
-			MemoryUsagePercent: memoryUsagePercent,
-			MemoryItemCount:    detailed.MemoryItemCount,
-			MemoryEvictions:    detailed.MemoryEvictions,
-			MemoryExpirations:  detailed.MemoryExpirations,
+			MemoryHits:           detailed.MemoryHits,
+			MemoryMisses:         detailed.MemoryMisses,
+			MemoryHitRate:        memoryHitRate,
+			MemoryHitRateStr:     formatPercent(memoryHitRate),
+			MemorySize:           detailed.MemorySize,
+			MemorySizeHuman:      formatBytes(detailed.MemorySize),
+			MemoryMaxSize:        detailed.MemoryMaxSize,
+			MemoryMaxSizeHuman:   formatBytes(detailed.MemoryMaxSize),
+			MemoryUsagePercent:   memoryUsagePercent,
+			MemoryItemCount:      detailed.MemoryItemCount,
+			MemoryEvictions:      detailed.MemoryEvictions,
+			MemoryExpirations:    detailed.MemoryExpirations,
+			MemoryEvictionPolicy: detailed.MemoryEvictionPolicy,
 
 			DiskHits:         detailed.DiskHits,
 			DiskMisses:       detailed.DiskMisses,
@@ -215,6 +240,28 @@ func (s *Server) handleCacheStats(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// indexCompressionStats summarizes the savings of compressed index entries
+func (s *Server) indexCompressionStats() *IndexCompressionStats {
+	uncompressed := s.indexCompression.uncompressedBytes.Load()
+	compressed := s.indexCompression.compressedBytes.Load()
+	saved := uncompressed - compressed
+
+	var savingsPercent float64
+	if uncompressed > 0 {
+		savingsPercent = float64(saved) / float64(uncompressed) * 100
+	}
+
+	return &IndexCompressionStats{
+		DocumentsCompressed: s.indexCompression.documents.Load(),
+		UncompressedBytes:   uncompressed,
+		CompressedBytes:     compressed,
+		BytesSaved:          saved,
+		BytesSavedHuman:     formatBytes(saved),
+		SavingsPercent:      savingsPercent,
+		SavingsPercentStr:   formatPercent(savingsPercent),
+	}
+}
+
 // formatPercent formats a percentage value as a string
 func formatPercent(percent float64) string {
 	return fmt.Sprintf("%.2f%%", percent)
@@ -252,3 +299,173 @@ func (s *Server) handleClearCache(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// CacheEntryResponse describes a single cached item
+type CacheEntryResponse struct {
+	Key          string    `json:"key"`
+	Tier         string    `json:"tier"` // "memory" or "disk"
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	SizeHuman    string    `json:"size_human"`
+	CreatedAt    Timestamp `json:"created_at"`
+	ExpiresAt    Timestamp `json:"expires_at"`
+	LastAccessed Timestamp `json:"last_accessed"`
+	AccessCount  int64     `json:"access_count"`
+	Encrypted    bool      `json:"encrypted"`
+}
+
+// CacheKeysResponse lists cached keys
+type CacheKeysResponse struct {
+	Prefix string   `json:"prefix"`
+	Keys   []string `json:"keys"`
+	Count  int      `json:"count"`
+}
+
+// handleCacheEntry returns the metadata for one cache entry. Tiered caches
+// are searched in read order, so the reported tier is the one a request
+// would be served from.
+// GET /admin/api/cache/entry?key=...
+func (s *Server) handleCacheEntry(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		respondError(w, http.StatusBadRequest, "missing_key", "key query parameter is required")
+		return
+	}
+
+	var memory *cache.MemoryCache
+	var disk *cache.DiskCache
+	switch c := s.cache.(type) {
+	case *cache.TieredCache:
+		memory, disk = c.Memory(), c.Disk()
+	case *cache.MemoryCache:
+		memory = c
+	case *cache.DiskCache:
+		disk = c
+	default:
+		respondError(w, http.StatusNotImplemented, "cache_not_inspectable", "The configured cache does not support entry inspection")
+		return
+	}
+
+	if memory != nil {
+		if item, found := memory.GetItem(key); found {
+			respondJSON(w, http.StatusOK, CacheEntryResponse{
+				Key:          item.Key,
+				Tier:         "memory",
+				ContentType:  item.ContentType,
+				Size:         item.Size,
+				SizeHuman:    formatBytes(item.Size),
+				CreatedAt:    Timestamp{item.CreatedAt},
+				ExpiresAt:    Timestamp{item.ExpiresAt},
+				LastAccessed: Timestamp{item.LastAccessed},
+				AccessCount:  item.AccessCount,
+			})
+			return
+		}
+	}
+
+	if disk != nil {
+		if entry, found := disk.GetEntry(key); found {
+			respondJSON(w, http.StatusOK, CacheEntryResponse{
+				Key:          entry.Key,
+				Tier:         "disk",
+				ContentType:  entry.ContentType,
+				Size:         entry.Size,
+				SizeHuman:    formatBytes(entry.Size),
+				CreatedAt:    Timestamp{entry.CreatedAt},
+				ExpiresAt:    Timestamp{entry.ExpiresAt},
+				LastAccessed: Timestamp{entry.LastAccessed},
+				AccessCount:  entry.AccessCount,
+				Encrypted:    entry.Encrypted,
+			})
+			return
+		}
+	}
+
+	respondError(w, http.StatusNotFound, "not_found", "Cache entry not found")
+}
+
+// handleCacheKeys lists cached keys, optionally filtered by prefix. Keys
+// held in both tiers of a tiered cache are listed once.
+// GET /admin/api/cache/keys?prefix=...
+func (s *Server) handleCacheKeys(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+
+	keys, ok := s.cacheKeys(prefix)
+	if !ok {
+		respondError(w, http.StatusNotImplemented, "cache_not_inspectable", "The configured cache does not support listing keys")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, CacheKeysResponse{
+		Prefix: prefix,
+		Keys:   keys,
+		Count:  len(keys),
+	})
+}
+
+// cacheKeys lists the sorted, unique keys of the configured cache that start
+// with prefix. It reports false when the cache cannot list its keys.
+func (s *Server) cacheKeys(prefix string) ([]string, bool) {
+	var keys []string
+	switch c := s.cache.(type) {
+	case *cache.TieredCache:
+		keys = append(c.Memory().HasPrefix(prefix), diskKeys(c.Disk(), prefix)...)
+	case *cache.MemoryCache:
+		keys = c.HasPrefix(prefix)
+	case *cache.DiskCache:
+		keys = diskKeys(c, prefix)
+	default:
+		return nil, false
+	}
+
+	sort.Strings(keys)
+	unique := make([]string, 0, len(keys))
+	for i, key := range keys {
+		if i == 0 || key != keys[i-1] {
+			unique = append(unique, key)
+		}
+	}
+	return unique, true
+}
+
+// diskKeys lists a disk cache's keys, filtered by prefix when one is given
+func diskKeys(dc *cache.DiskCache, prefix string) []string {
+	if prefix == "" {
+		return dc.ListKeys()
+	}
+	return dc.HasPrefix(prefix)
+}
+
+// invalidateProviderCache evicts a deleted provider's archive and every cached
+// mirror protocol document for its namespace and type. Caches that cannot
+// list their keys only lose the archive; their documents expire with the
+// index TTL.
+func (s *Server) invalidateProviderCache(ctx context.Context, p *database.Provider) {
+	keys := []string{p.S3Key}
+	if indexKeys, ok := s.cacheKeys(mirrorIndexCachePrefix(p.Namespace, p.Type)); ok {
+		keys = append(keys, indexKeys...)
+	}
+	s.evictCacheKeys(ctx, keys)
+}
+
+// invalidateProviderIndexCache evicts the cached mirror protocol documents
+// of a provider address, including those served through its aliases, keeping
+// its archives
+func (s *Server) invalidateProviderIndexCache(ctx context.Context, namespace, providerType string) {
+	if keys, ok := s.cacheKeys(mirrorIndexCachePrefix(namespace, providerType)); ok {
+		s.evictCacheKeys(ctx, keys)
+	}
+}
+
+// evictCacheKeys deletes keys from the cache, logging rather than failing
+// on errors since the underlying data is already gone
+func (s *Server) evictCacheKeys(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := s.cache.Delete(ctx, key); err != nil {
+			s.logger.Printf("Warning: failed to evict cache entry %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheInspection(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	tiered, err := cache.NewTieredCache(cache.TieredCacheConfig{
+		MemorySizeMB: 1,
+		DiskPath:     t.TempDir(),
+		DiskSizeGB:   1,
+		DefaultTTL:   time.Hour,
+	})
+	require.NoError(t, err)
+	server.cache = tiered
+
+	ctx := context.Background()
+	blobKey := "providers/registry.terraform.io/hashicorp/random/3.6.0/linux_amd64/terraform-provider-random_3.6.0_linux_amd64.zip"
+	require.NoError(t, tiered.SetToDisk(ctx, blobKey, bytes.NewReader([]byte("archive")), "application/zip", 7, 0))
+	require.NoError(t, tiered.SetToMemory(ctx, "modules/hashicorp/consul/aws/0.1.0.tar.gz", bytes.NewReader([]byte("module")), "application/gzip", 6, 0))
+
+	token := getAuthToken(t, server)
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("existing disk entry", func(t *testing.T) {
+		w := get("/admin/api/cache/entry?key=" + blobKey)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp CacheEntryResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, blobKey, resp.Key)
+		assert.Equal(t, "disk", resp.Tier)
+		assert.Equal(t, "application/zip", resp.ContentType)
+		assert.Equal(t, int64(7), resp.Size)
+		assert.False(t, resp.CreatedAt.IsZero())
+		assert.True(t, resp.ExpiresAt.After(resp.CreatedAt.Time))
+	})
+
+	t.Run("existing memory entry", func(t *testing.T) {
+		w := get("/admin/api/cache/entry?key=modules/hashicorp/consul/aws/0.1.0.tar.gz")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp CacheEntryResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, "memory", resp.Tier)
+		assert.Equal(t, int64(6), resp.Size)
+	})
+
+	t.Run("missing entry", func(t *testing.T) {
+		w := get("/admin/api/cache/entry?key=providers/missing.zip")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("missing key parameter", func(t *testing.T) {
+		w := get("/admin/api/cache/entry")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("list keys", func(t *testing.T) {
+		w := get("/admin/api/cache/keys")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp CacheKeysResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, 2, resp.Count)
+		assert.Equal(t, []string{"modules/hashicorp/consul/aws/0.1.0.tar.gz", blobKey}, resp.Keys)
+	})
+
+	t.Run("list keys by prefix", func(t *testing.T) {
+		w := get("/admin/api/cache/keys?prefix=providers/")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp CacheKeysResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, "providers/", resp.Prefix)
+		assert.Equal(t, []string{blobKey}, resp.Keys)
+	})
+
+	t.Run("requires authentication", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api/cache/keys", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestCacheInspection_Unsupported(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	token := getAuthToken(t, server)
+	for _, path := range []string{"/admin/api/cache/entry?key=x", "/admin/api/cache/keys"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotImplemented, w.Code, path)
+	}
+}
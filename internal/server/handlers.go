@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/ned1313/terraform-mirror/internal/config"
 	"github.com/ned1313/terraform-mirror/internal/database"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -50,6 +51,31 @@ type jobItemResponse struct {
 	ErrorMessage *string `json:"error_message,omitempty"`
 }
 
+// jobItemDetailResponse is the full record of a single provider or module job item
+type jobItemDetailResponse struct {
+	ID              int64   `json:"id"`
+	JobID           int64   `json:"job_id"`
+	ItemType        string  `json:"item_type"` // provider or module
+	Namespace       string  `json:"namespace"`
+	Type            string  `json:"type,omitempty"`
+	Name            string  `json:"name,omitempty"`
+	System          string  `json:"system,omitempty"`
+	Version         string  `json:"version"`
+	Platform        string  `json:"platform,omitempty"`
+	Status          string  `json:"status"`
+	DownloadURL     *string `json:"download_url,omitempty"`
+	SizeBytes       *int64  `json:"size_bytes,omitempty"`
+	DownloadedBytes *int64  `json:"downloaded_bytes,omitempty"`
+	ProviderID      *int64  `json:"provider_id,omitempty"`
+	ModuleID        *int64  `json:"module_id,omitempty"`
+	ErrorMessage    *string `json:"error_message,omitempty"`
+	RetryCount      int     `json:"retry_count"`
+	CreatedAt       string  `json:"created_at"`
+	StartedAt       *string `json:"started_at,omitempty"`
+	CompletedAt     *string `json:"completed_at,omitempty"`
+	DurationMs      *int64  `json:"duration_ms,omitempty"`
+}
+
 // jobListResponse represents a list of jobs
 type jobListResponse struct {
 	Jobs   []jobResponse `json:"jobs"`
@@ -68,7 +94,7 @@ func convertJobToResponse(job *database.DownloadJob, items []*database.DownloadJ
 		TotalItems:     job.TotalItems,
 		CompletedItems: job.CompletedItems,
 		FailedItems:    job.FailedItems,
-		CreatedAt:      job.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		CreatedAt:      formatTimestamp(job.CreatedAt),
 	}
 
 	if job.ErrorMessage.Valid {
@@ -76,12 +102,12 @@ func convertJobToResponse(job *database.DownloadJob, items []*database.DownloadJ
 	}
 
 	if job.StartedAt.Valid {
-		startedStr := job.StartedAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		startedStr := formatTimestamp(job.StartedAt.Time)
 		response.StartedAt = &startedStr
 	}
 
 	if job.CompletedAt.Valid {
-		completedStr := job.CompletedAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		completedStr := formatTimestamp(job.CompletedAt.Time)
 		response.CompletedAt = &completedStr
 	}
 
@@ -132,12 +158,8 @@ func (s *Server) logAuditEvent(r *http.Request, action, resourceType, resourceID
 		entry.ResourceID.Valid = true
 	}
 
-	// Get IP address
-	ip := r.RemoteAddr
-	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-		ip = forwarded
-	}
-	entry.IPAddress.String = ip
+	// Client IP as resolved by realIPMiddleware
+	entry.IPAddress.String = r.RemoteAddr
 	entry.IPAddress.Valid = true
 
 	// Get User-Agent
@@ -174,6 +196,16 @@ func (s *Server) logAuditEvent(r *http.Request, action, resourceType, resourceID
 	}()
 }
 
+// logDownload records a public download in the audit log when download
+// auditing is enabled. The write happens in the background like every audit
+// entry, so it never holds up the download itself.
+func (s *Server) logDownload(r *http.Request, resourceType, resourceID string, metadata map[string]interface{}) {
+	if !s.config.Logging.AuditDownloads {
+		return
+	}
+	s.logAuditEvent(r, "download", resourceType, resourceID, true, "", metadata)
+}
+
 // handleHealth returns the health status of the server
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	response := HealthResponse{
@@ -192,11 +224,17 @@ type ServiceDiscoveryResponse struct {
 	ModulesV1   string `json:"modules.v1,omitempty"`
 }
 
-// handleServiceDiscovery implements the .well-known/terraform.json endpoint
+// handleServiceDiscovery implements the .well-known/terraform.json endpoint.
+// modules.v1 is only advertised when the module registry protocol is enabled.
+// login.v1 is never advertised since the mirror does not implement Terraform's
+// OAuth login protocol; admin authentication is separate from client access.
 func (s *Server) handleServiceDiscovery(w http.ResponseWriter, r *http.Request) {
+	discovery := s.config.Discovery
 	response := ServiceDiscoveryResponse{
-		ProvidersV1: "/v1/providers/",
-		ModulesV1:   "/v1/modules/",
+		ProvidersV1: discovery.GetProvidersPath(),
+	}
+	if discovery.IsModulesEnabled() {
+		response.ModulesV1 = discovery.GetModulesPath()
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -207,8 +245,14 @@ func (s *Server) handleServiceDiscovery(w http.ResponseWriter, r *http.Request)
 // Provider Mirror Protocol handlers are now in provider_mirror.go
 // Authentication handlers are now in auth_handlers.go
 
+// requestSort reads the sort and order query parameters, checking the sort
+// field against the allowlist of the listed table
+func requestSort(r *http.Request, allowed []string) (database.Sort, error) {
+	return database.ParseSort(r.URL.Query().Get("sort"), r.URL.Query().Get("order"), allowed)
+}
+
 // handleListProviders lists all providers
-// TODO: Implement full logic
+// GET /admin/api/providers?sort=size_bytes&order=desc
 func (s *Server) handleListProviders(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -216,8 +260,14 @@ func (s *Server) handleListProviders(w http.ResponseWriter, r *http.Request) {
 	namespace := r.URL.Query().Get("namespace")
 	providerType := r.URL.Query().Get("type")
 
+	order, err := requestSort(r, database.ProviderSortFields)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_sort", err.Error())
+		return
+	}
+
 	// Get all providers from database (with a reasonable limit)
-	providers, err := s.providerRepo.List(ctx, 1000, 0)
+	providers, err := s.providerRepo.ListSorted(ctx, order, 1000, 0)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list providers")
 		return
@@ -243,9 +293,15 @@ func (s *Server) handleListProviders(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	labeled, err := s.labelProviders(ctx, filtered, strings.ToLower(r.URL.Query().Get("label")))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list provider labels")
+		return
+	}
+
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"providers": filtered,
-		"count":     len(filtered),
+		"providers": labeled,
+		"count":     len(labeled),
 	})
 }
 
@@ -281,7 +337,13 @@ func (s *Server) handleGetProvider(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, provider)
+	labels, err := s.labelsOf(r.Context(), database.LabelProvider, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list provider labels")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, newLabeledProvider(provider, labels))
 }
 
 // UpdateProviderRequest represents the request body for updating a provider
@@ -323,6 +385,7 @@ func (s *Server) handleUpdateProvider(w http.ResponseWriter, r *http.Request) {
 	if req.Deprecated != nil {
 		provider.Deprecated = *req.Deprecated
 	}
+	blockedChanged := req.Blocked != nil && *req.Blocked != provider.Blocked
 	if req.Blocked != nil {
 		provider.Blocked = *req.Blocked
 	}
@@ -334,6 +397,11 @@ func (s *Server) handleUpdateProvider(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Mirror documents leave out blocked versions, so cached ones are stale
+	if blockedChanged {
+		s.invalidateProviderIndexCache(r.Context(), provider.Namespace, provider.Type)
+	}
+
 	// Log successful update
 	s.logAuditEvent(r, "update_provider", "provider", idStr, true, "", map[string]interface{}{
 		"namespace":  provider.Namespace,
@@ -382,6 +450,10 @@ func (s *Server) handleDeleteProvider(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "database_error", "Failed to delete provider")
 		return
 	}
+	s.pruneStaleShasums(r.Context())
+
+	// Stop serving cached copies of the deleted provider
+	s.invalidateProviderCache(r.Context(), provider)
 
 	// Log successful deletion
 	s.logAuditEvent(r, "delete_provider", "provider", idStr, true, "", map[string]interface{}{
@@ -396,8 +468,8 @@ func (s *Server) handleDeleteProvider(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleListJobs retrieves all jobs with pagination and optional status filter
-// GET /admin/api/jobs?limit=10&offset=0&status=pending
+// handleListJobs retrieves all jobs with pagination, sorting and optional status filter
+// GET /admin/api/jobs?limit=10&offset=0&status=pending&sort=created_at&order=asc
 func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
 	limitStr := r.URL.Query().Get("limit")
@@ -419,16 +491,14 @@ func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Get jobs from database (with optional status filter)
-	var jobs []*database.DownloadJob
-	var err error
-
-	if statusFilter != "" {
-		jobs, err = s.jobRepo.ListByStatus(r.Context(), statusFilter, limit, offset)
-	} else {
-		jobs, err = s.jobRepo.List(r.Context(), limit, offset)
+	order, err := requestSort(r, database.JobSortFields)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_sort", err.Error())
+		return
 	}
 
+	// Get jobs from database (with optional status filter)
+	jobs, err := s.jobRepo.ListSorted(r.Context(), statusFilter, order, limit, offset)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "database_error",
 			"Failed to retrieve jobs")
@@ -492,6 +562,131 @@ func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleGetJobItem retrieves the full detail of one job item
+// GET /admin/api/jobs/{id}/items/{itemId}
+func (s *Server) handleGetJobItem(w http.ResponseWriter, r *http.Request) {
+	jobID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_job_id", "Invalid job ID")
+		return
+	}
+	itemID, err := strconv.ParseInt(chi.URLParam(r, "itemId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_item_id", "Invalid item ID")
+		return
+	}
+
+	job, err := s.jobRepo.GetByID(r.Context(), jobID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve job")
+		return
+	}
+	if job == nil {
+		respondError(w, http.StatusNotFound, "job_not_found", "Job not found")
+		return
+	}
+
+	var response *jobItemDetailResponse
+	if job.JobType == "module" {
+		item, err := database.NewModuleJobRepository(s.db).GetItem(r.Context(), itemID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve job item")
+			return
+		}
+		if item != nil && item.JobID == jobID {
+			response = moduleJobItemDetail(item)
+		}
+	} else {
+		item, err := s.jobRepo.GetItem(r.Context(), itemID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "database_error", "Failed to retrieve job item")
+			return
+		}
+		if item != nil && item.JobID == jobID {
+			response = providerJobItemDetail(item)
+		}
+	}
+
+	if response == nil {
+		respondError(w, http.StatusNotFound, "item_not_found", "Job item not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// providerJobItemDetail converts a provider job item to its detail response
+func providerJobItemDetail(item *database.DownloadJobItem) *jobItemDetailResponse {
+	response := &jobItemDetailResponse{
+		ID:         item.ID,
+		JobID:      item.JobID,
+		ItemType:   "provider",
+		Namespace:  item.Namespace,
+		Type:       item.Type,
+		Version:    item.Version,
+		Platform:   item.Platform,
+		Status:     item.Status,
+		RetryCount: item.RetryCount,
+		CreatedAt:  formatTimestamp(item.CreatedAt),
+	}
+	if item.DownloadURL.Valid {
+		response.DownloadURL = &item.DownloadURL.String
+	}
+	if item.SizeBytes.Valid {
+		response.SizeBytes = &item.SizeBytes.Int64
+	}
+	if item.DownloadedBytes.Valid {
+		response.DownloadedBytes = &item.DownloadedBytes.Int64
+	}
+	if item.ProviderID.Valid {
+		response.ProviderID = &item.ProviderID.Int64
+	}
+	if item.ErrorMessage.Valid {
+		response.ErrorMessage = &item.ErrorMessage.String
+	}
+	if item.StartedAt.Valid {
+		started := formatTimestamp(item.StartedAt.Time)
+		response.StartedAt = &started
+	}
+	if item.CompletedAt.Valid {
+		completed := formatTimestamp(item.CompletedAt.Time)
+		response.CompletedAt = &completed
+		if item.StartedAt.Valid {
+			duration := item.CompletedAt.Time.Sub(item.StartedAt.Time).Milliseconds()
+			response.DurationMs = &duration
+		}
+	}
+	return response
+}
+
+// moduleJobItemDetail converts a module job item to its detail response.
+// Module items don't record a download URL, sizes, or a start time.
+func moduleJobItemDetail(item *database.ModuleJobItem) *jobItemDetailResponse {
+	response := &jobItemDetailResponse{
+		ID:         item.ID,
+		JobID:      item.JobID,
+		ItemType:   "module",
+		Namespace:  item.Namespace,
+		Name:       item.Name,
+		System:     item.System,
+		Version:    item.Version,
+		Status:     item.Status,
+		RetryCount: item.RetryCount,
+		CreatedAt:  formatTimestamp(item.CreatedAt),
+	}
+	if item.ModuleID.Valid {
+		response.ModuleID = &item.ModuleID.Int64
+	}
+	if item.ErrorMessage.Valid {
+		response.ErrorMessage = &item.ErrorMessage.String
+	}
+	if item.CompletedAt.Valid {
+		completed := formatTimestamp(item.CompletedAt.Time)
+		response.CompletedAt = &completed
+	}
+	return response
+}
+
 // handleRetryJob retries failed items in a job
 // POST /admin/api/jobs/{id}/retry
 func (s *Server) handleRetryJob(w http.ResponseWriter, r *http.Request) {
@@ -620,6 +815,68 @@ func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// CancelAllJobsResponse reports the jobs stopped by a cancel-all
+type CancelAllJobsResponse struct {
+	Message          string  `json:"message"`
+	CancelledPending int64   `json:"cancelled_pending"`
+	CancelledRunning int     `json:"cancelled_running"`
+	RunningJobIDs    []int64 `json:"running_job_ids"`
+}
+
+// handleCancelAllJobs cancels every pending and running job, leaving
+// finished jobs untouched
+// POST /admin/api/jobs/cancel-all
+func (s *Server) handleCancelAllJobs(w http.ResponseWriter, r *http.Request) {
+	const reason = "Job cancelled by cancel-all"
+
+	// Cancel pending jobs in one statement first. A job the processor has
+	// listed but not yet claimed then fails its claim instead of starting, so
+	// no job can become running after this point.
+	pending, err := s.jobRepo.CancelPending(r.Context(), reason)
+	if err != nil {
+		s.logAuditEvent(r, "cancel_all_jobs", "job", "", false, err.Error(), nil)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to cancel pending jobs")
+		return
+	}
+
+	// A limit of -1 lists every running job
+	running, err := s.jobRepo.ListByStatus(r.Context(), "running", -1, 0)
+	if err != nil {
+		s.logAuditEvent(r, "cancel_all_jobs", "job", "", false, err.Error(), nil)
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list running jobs")
+		return
+	}
+
+	response := CancelAllJobsResponse{CancelledPending: pending, RunningJobIDs: []int64{}}
+	for _, job := range running {
+		// Stop the worker before recording the cancellation, so it cannot
+		// overwrite the status with its own result
+		s.processorService.CancelJob(job.ID)
+
+		// Jobs that finished in the meantime keep their final status
+		cancelled, err := s.jobRepo.Cancel(r.Context(), job.ID, reason)
+		if err != nil {
+			s.logAuditEvent(r, "cancel_all_jobs", "job", "", false, err.Error(), nil)
+			respondError(w, http.StatusInternalServerError, "database_error",
+				fmt.Sprintf("Failed to cancel job %d", job.ID))
+			return
+		}
+		if cancelled {
+			response.CancelledRunning++
+			response.RunningJobIDs = append(response.RunningJobIDs, job.ID)
+		}
+	}
+
+	s.logAuditEvent(r, "cancel_all_jobs", "job", "", true, "", map[string]interface{}{
+		"cancelled_pending": response.CancelledPending,
+		"cancelled_running": response.CancelledRunning,
+	})
+
+	response.Message = fmt.Sprintf("Cancelled %d pending and %d running jobs",
+		response.CancelledPending, response.CancelledRunning)
+	respondJSON(w, http.StatusOK, response)
+}
+
 // StorageStatsResponse represents storage statistics
 type StorageStatsResponse struct {
 	TotalProviders   int64  `json:"total_providers"`
@@ -737,36 +994,182 @@ func (s *Server) handleAuditLogs(w http.ResponseWriter, r *http.Request) {
 	// Convert to response format
 	entries := make([]AuditLogEntry, len(logs))
 	for i, log := range logs {
-		entry := AuditLogEntry{
-			ID:           log.ID,
-			Action:       log.Action,
-			ResourceType: log.ResourceType,
-			Success:      log.Success,
-			CreatedAt:    log.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		}
+		entries[i] = auditLogEntry(log)
+	}
+
+	respondJSON(w, http.StatusOK, AuditLogResponse{
+		Logs:   entries,
+		Total:  len(entries),
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// auditLogEntry converts an audit log row to its API form
+func auditLogEntry(log *database.AdminAction) AuditLogEntry {
+	entry := AuditLogEntry{
+		ID:           log.ID,
+		Action:       log.Action,
+		ResourceType: log.ResourceType,
+		Success:      log.Success,
+		CreatedAt:    formatTimestamp(log.CreatedAt),
+	}
+
+	if log.UserID.Valid {
+		uid := log.UserID.Int64
+		entry.UserID = &uid
+	}
+	if log.ResourceID.Valid {
+		entry.ResourceID = &log.ResourceID.String
+	}
+	if log.IPAddress.Valid {
+		entry.IPAddress = &log.IPAddress.String
+	}
+	if log.ErrorMessage.Valid {
+		entry.ErrorMessage = &log.ErrorMessage.String
+	}
+	return entry
+}
 
-		if log.UserID.Valid {
-			uid := log.UserID.Int64
-			entry.UserID = &uid
+// auditExportBatchSize bounds the audit entries held in memory while exporting
+const auditExportBatchSize = 500
+
+// parseAuditTime parses an RFC 3339 timestamp or a YYYY-MM-DD date (UTC
+// midnight); an empty value leaves that end of the range open
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// handleExportAuditLogs streams the whole audit log, oldest first, as
+// newline-delimited JSON. Entries are read in batches so memory stays bounded
+// however large the log is.
+// GET /admin/api/stats/audit/export?from=2025-01-01&to=2025-02-01
+func (s *Server) handleExportAuditLogs(w http.ResponseWriter, r *http.Request) {
+	var filter database.AuditFilter
+	var err error
+	if filter.From, err = parseAuditTime(r.URL.Query().Get("from")); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_time_range", "from must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		return
+	}
+	if filter.To, err = parseAuditTime(r.URL.Query().Get("to")); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_time_range", "to must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		return
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.To.After(filter.From) {
+		respondError(w, http.StatusBadRequest, "invalid_time_range", "to must be after from")
+		return
+	}
+
+	ctx := r.Context()
+	controller := http.NewResponseController(w)
+	encoder := json.NewEncoder(w)
+	var afterID int64
+	started := false
+
+	for {
+		batch, err := s.auditRepo.ListAfter(ctx, filter, afterID, auditExportBatchSize)
+		if err != nil {
+			// Once streaming has begun the status is sent; ending early is
+			// all that is left, and the client sees a truncated stream
+			if !started {
+				respondError(w, http.StatusInternalServerError, "database_error", "Failed to export audit logs")
+				return
+			}
+			log.Printf("Audit log export stopped after entry %d: %v", afterID, err)
+			return
 		}
-		if log.ResourceID.Valid {
-			entry.ResourceID = &log.ResourceID.String
+
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Content-Disposition", `attachment; filename="audit-log.ndjson"`)
+			w.WriteHeader(http.StatusOK)
+			started = true
 		}
-		if log.IPAddress.Valid {
-			entry.IPAddress = &log.IPAddress.String
+
+		for _, action := range batch {
+			if err := encoder.Encode(auditLogEntry(action)); err != nil {
+				return
+			}
+			afterID = action.ID
 		}
-		if log.ErrorMessage.Valid {
-			entry.ErrorMessage = &log.ErrorMessage.String
+		_ = controller.Flush()
+
+		if len(batch) < auditExportBatchSize {
+			return
 		}
+	}
+}
+
+// FailureStatsResponse lists provider artifacts that repeatedly fail to download
+type FailureStatsResponse struct {
+	Failures []FailedDownloadEntry `json:"failures"`
+	Total    int                   `json:"total"`
+	Limit    int                   `json:"limit"`
+}
+
+// FailedDownloadEntry aggregates failed job items for one provider artifact
+type FailedDownloadEntry struct {
+	Namespace    string  `json:"namespace"`
+	Type         string  `json:"type"`
+	Version      string  `json:"version"`
+	Platform     string  `json:"platform"`
+	FailureCount int64   `json:"failure_count"`
+	JobCount     int64   `json:"job_count"`
+	LastJobID    int64   `json:"last_job_id"`
+	LastError    *string `json:"last_error,omitempty"`
+	LastFailedAt *string `json:"last_failed_at,omitempty"`
+}
 
+// handleFailureStats returns failed job items grouped by provider artifact
+// GET /admin/api/stats/failures?min_failures=2&limit=50
+func (s *Server) handleFailureStats(w http.ResponseWriter, r *http.Request) {
+	limit := 50 // default
+	minFailures := 1
+
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+	if m, err := strconv.Atoi(r.URL.Query().Get("min_failures")); err == nil && m > 0 {
+		minFailures = m
+	}
+
+	failures, err := s.jobRepo.ListFailedDownloads(r.Context(), minFailures, limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to get download failures")
+		return
+	}
+
+	entries := make([]FailedDownloadEntry, len(failures))
+	for i, f := range failures {
+		entry := FailedDownloadEntry{
+			Namespace:    f.Namespace,
+			Type:         f.Type,
+			Version:      f.Version,
+			Platform:     f.Platform,
+			FailureCount: f.FailureCount,
+			JobCount:     f.JobCount,
+			LastJobID:    f.LastJobID,
+		}
+		if f.LastError.Valid {
+			entry.LastError = &f.LastError.String
+		}
+		if f.LastFailedAt.Valid {
+			ts := formatTimestamp(f.LastFailedAt.Time)
+			entry.LastFailedAt = &ts
+		}
 		entries[i] = entry
 	}
 
-	respondJSON(w, http.StatusOK, AuditLogResponse{
-		Logs:   entries,
-		Total:  len(entries),
-		Limit:  limit,
-		Offset: offset,
+	respondJSON(w, http.StatusOK, FailureStatsResponse{
+		Failures: entries,
+		Total:    len(entries),
+		Limit:    limit,
 	})
 }
 
@@ -828,6 +1231,7 @@ type SanitizedConfig struct {
 	Storage   SanitizedStorageConfig   `json:"storage"`
 	Database  SanitizedDatabaseConfig  `json:"database"`
 	Cache     SanitizedCacheConfig     `json:"cache"`
+	Providers SanitizedProvidersConfig `json:"providers"`
 	Features  SanitizedFeaturesConfig  `json:"features"`
 	Processor SanitizedProcessorConfig `json:"processor"`
 	Logging   SanitizedLoggingConfig   `json:"logging"`
@@ -860,6 +1264,34 @@ type SanitizedCacheConfig struct {
 	DiskPath     string `json:"disk_path"`
 	DiskSizeGB   int    `json:"disk_size_gb"`
 	TTLSeconds   int    `json:"ttl_seconds"`
+
+	MemoryEvictionPolicy string `json:"memory_eviction_policy"`
+}
+
+type SanitizedProvidersConfig struct {
+	Upstreams []SanitizedUpstreamConfig `json:"upstreams"`
+}
+
+// SanitizedUpstreamConfig shows whether an upstream has credentials without
+// revealing them
+type SanitizedUpstreamConfig struct {
+	Hostname   string   `json:"hostname"`
+	URL        string   `json:"url"`
+	Namespaces []string `json:"namespaces"`
+	Token      string   `json:"token,omitempty"`
+	Username   string   `json:"username,omitempty"`
+	Password   string   `json:"password,omitempty"`
+}
+
+// redactedSecret replaces configured secrets in sanitized output
+const redactedSecret = config.RedactedSecret
+
+// redact hides a secret, keeping whether it is set visible
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedSecret
 }
 
 type SanitizedFeaturesConfig struct {
@@ -876,9 +1308,10 @@ type SanitizedProcessorConfig struct {
 }
 
 type SanitizedLoggingConfig struct {
-	Level  string `json:"level"`
-	Format string `json:"format"`
-	Output string `json:"output"`
+	Level         string `json:"level"`
+	Format        string `json:"format"`
+	Output        string `json:"output"`
+	BufferEntries int    `json:"buffer_entries"`
 }
 
 type SanitizedTelemetryConfig struct {
@@ -915,6 +1348,11 @@ func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 			DiskPath:     s.config.Cache.DiskPath,
 			DiskSizeGB:   s.config.Cache.DiskSizeGB,
 			TTLSeconds:   s.config.Cache.TTLSeconds,
+
+			MemoryEvictionPolicy: s.config.Cache.MemoryEvictionPolicy,
+		},
+		Providers: SanitizedProvidersConfig{
+			Upstreams: make([]SanitizedUpstreamConfig, 0, len(s.config.Providers.Upstreams)),
 		},
 		Features: SanitizedFeaturesConfig{
 			AutoDownloadProviders: s.config.Features.AutoDownloadProviders,
@@ -923,14 +1361,15 @@ func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 		},
 		Processor: SanitizedProcessorConfig{
 			PollingIntervalSeconds: s.config.Processor.PollingIntervalSeconds,
-			MaxConcurrentJobs:      s.config.Processor.MaxConcurrentJobs,
+			MaxConcurrentJobs:      s.processorService.GetConfig().MaxConcurrentJobs,
 			RetryAttempts:          s.config.Processor.RetryAttempts,
 			RetryDelaySeconds:      s.config.Processor.RetryDelaySeconds,
 		},
 		Logging: SanitizedLoggingConfig{
-			Level:  s.config.Logging.Level,
-			Format: s.config.Logging.Format,
-			Output: s.config.Logging.Output,
+			Level:         s.config.Logging.Level,
+			Format:        s.config.Logging.Format,
+			Output:        s.config.Logging.Output,
+			BufferEntries: s.config.Logging.BufferEntries,
 		},
 		Telemetry: SanitizedTelemetryConfig{
 			Enabled:       s.config.Telemetry.Enabled,
@@ -939,10 +1378,29 @@ func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 			ExportMetrics: s.config.Telemetry.ExportMetrics,
 		},
 	}
+	for _, upstream := range s.config.Providers.Upstreams {
+		sanitized.Providers.Upstreams = append(sanitized.Providers.Upstreams, SanitizedUpstreamConfig{
+			Hostname:   upstream.Hostname,
+			URL:        upstream.GetURL(),
+			Namespaces: upstream.Namespaces,
+			Token:      redact(upstream.Token),
+			Username:   upstream.Username,
+			Password:   redact(upstream.Password),
+		})
+	}
 
 	respondJSON(w, http.StatusOK, sanitized)
 }
 
+// handleGetConfigHCL returns the live configuration rendered as HCL, with
+// secrets redacted. Unlike the JSON view it covers every setting.
+// GET /admin/api/config/hcl
+func (s *Server) handleGetConfigHCL(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(config.RenderHCL(s.config))
+}
+
 // BackupResponse represents the backup response
 type BackupResponse struct {
 	Message    string `json:"message"`
@@ -963,6 +1421,14 @@ func (s *Server) handleTriggerBackup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Backups and restores must not race on the database file
+	release, err := s.db.BeginExclusive("backup")
+	if err != nil {
+		respondError(w, http.StatusConflict, "operation_in_progress", err.Error())
+		return
+	}
+	defer release()
+
 	// Generate backup filename with timestamp
 	timestamp := time.Now().Format("20060102-150405")
 	backupFilename := fmt.Sprintf("terraform-mirror-backup-%s.db", timestamp)
@@ -970,11 +1436,23 @@ func (s *Server) handleTriggerBackup(w http.ResponseWriter, r *http.Request) {
 	// Create local backup first
 	localBackupPath := filepath.Join(filepath.Dir(s.config.Database.Path), "backups", backupFilename)
 
-	if err := s.db.Backup(ctx, localBackupPath); err != nil {
+	if err := s.backupFunc(ctx, localBackupPath); err != nil {
 		respondError(w, http.StatusInternalServerError, "backup_error", "Failed to create backup: "+err.Error())
 		return
 	}
 
+	// Never report or upload a backup that cannot be restored
+	if err := database.VerifyBackup(ctx, localBackupPath); err != nil {
+		if removeErr := os.Remove(localBackupPath); removeErr != nil {
+			s.logger.Printf("Warning: failed to remove unverified backup %s: %v", localBackupPath, removeErr)
+		}
+		s.logAuditEvent(r, "trigger_backup", "database", "", false, err.Error(), map[string]interface{}{
+			"backup_path": localBackupPath,
+		})
+		respondError(w, http.StatusInternalServerError, "backup_verification_failed", "Backup failed verification: "+err.Error())
+		return
+	}
+
 	// Get backup file size
 	fileInfo, err := os.Stat(localBackupPath)
 	if err != nil {
@@ -986,7 +1464,7 @@ func (s *Server) handleTriggerBackup(w http.ResponseWriter, r *http.Request) {
 		Message:    "Backup created successfully",
 		BackupPath: localBackupPath,
 		SizeBytes:  fileInfo.Size(),
-		CreatedAt:  time.Now().Format("2006-01-02T15:04:05Z07:00"),
+		CreatedAt:  formatTimestamp(time.Now()),
 	}
 
 	// Upload to S3 if configured
@@ -1056,6 +1534,9 @@ func (s *Server) updateMetricsGauges() {
 	failedCount, _ := s.jobRepo.CountByStatus(ctx, "failed")
 	s.metrics.UpdateJobCounts(int(pendingCount), int(runningCount), int(completedCount), int(failedCount))
 
+	// Update active sessions; expired sessions drop out without a logout
+	s.updateActiveSessions(ctx)
+
 	// Update processor status
 	processorStatus := s.processorService.GetStatus()
 	if running, ok := processorStatus["running"].(bool); ok {
@@ -1073,6 +1554,75 @@ func (s *Server) updateMetricsGauges() {
 func (s *Server) handleProcessorStatus(w http.ResponseWriter, r *http.Request) {
 	status := s.processorService.GetStatus()
 
+	pending, err := s.jobRepo.CountPendingItems(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to count pending job items")
+		return
+	}
+	status["pending_items"] = pending
+	status["max_pending_items"] = s.config.Processor.MaxPendingItems
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
 }
+
+// maxProcessorConcurrency bounds the processor concurrency set at runtime
+const maxProcessorConcurrency = 100
+
+// UpdateProcessorConfigRequest changes processor settings at runtime
+type UpdateProcessorConfigRequest struct {
+	MaxConcurrentJobs *int `json:"max_concurrent_jobs,omitempty"`
+}
+
+// processorConfigResponse returns the processor configuration in effect
+func (s *Server) processorConfigResponse() SanitizedProcessorConfig {
+	cfg := s.processorService.GetConfig()
+	return SanitizedProcessorConfig{
+		PollingIntervalSeconds: int(cfg.PollingInterval / time.Second),
+		MaxConcurrentJobs:      cfg.MaxConcurrentJobs,
+		RetryAttempts:          cfg.RetryAttempts,
+		RetryDelaySeconds:      int(cfg.RetryDelay / time.Second),
+	}
+}
+
+// handleGetProcessorConfig returns the processor configuration in effect,
+// including changes made since startup
+// GET /admin/api/processor/config
+func (s *Server) handleGetProcessorConfig(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, s.processorConfigResponse())
+}
+
+// handleUpdateProcessorConfig changes processor settings without a restart.
+// Changes last until the server restarts.
+// PUT /admin/api/processor/config
+func (s *Server) handleUpdateProcessorConfig(w http.ResponseWriter, r *http.Request) {
+	var req UpdateProcessorConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_body", "Invalid request body")
+		return
+	}
+
+	if req.MaxConcurrentJobs == nil {
+		respondError(w, http.StatusBadRequest, "invalid_body", "No settings to change")
+		return
+	}
+	if n := *req.MaxConcurrentJobs; n < 1 || n > maxProcessorConcurrency {
+		respondError(w, http.StatusBadRequest, "invalid_max_concurrent_jobs",
+			fmt.Sprintf("max_concurrent_jobs must be between 1 and %d", maxProcessorConcurrency))
+		return
+	}
+
+	previous := s.processorService.GetConfig().MaxConcurrentJobs
+	if err := s.processorService.SetMaxConcurrentJobs(*req.MaxConcurrentJobs); err != nil {
+		s.logAuditEvent(r, "update_processor_config", "processor", "", false, err.Error(), nil)
+		respondError(w, http.StatusBadRequest, "invalid_max_concurrent_jobs", err.Error())
+		return
+	}
+
+	s.logAuditEvent(r, "update_processor_config", "processor", "", true, "", map[string]interface{}{
+		"max_concurrent_jobs":          *req.MaxConcurrentJobs,
+		"previous_max_concurrent_jobs": previous,
+	})
+
+	respondJSON(w, http.StatusOK, s.processorConfigResponse())
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// handleBlobHead answers HEAD on a blob with the headers of a download,
+// taking the length from storage instead of reading the object
+func (s *Server) handleBlobHead(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/blobs/")
+	if key == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	exists, err := s.storage.Exists(ctx, key)
+	if err == nil && !exists {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	size, err := s.storage.GetObjectSize(ctx, key)
+	if err != nil {
+		s.logger.Printf("Failed to get size of blob %s: %v", key, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Report the content type GET would serve: the cached one when the blob
+	// is cached, otherwise the one it gets when read from storage
+	contentType := blobContentType(key)
+	if cached, cachedType, found := s.cache.Get(ctx, key); found {
+		cached.Close()
+		if cachedType != "" {
+			contentType = cachedType
+		}
+	}
+	setBlobHeaders(w, key, contentType, size)
+	w.WriteHeader(http.StatusOK)
+}
+
+// headOnly serves HEAD with a GET handler. The body is discarded and its
+// length reported in Content-Length, so the headers match those of GET.
+func headOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hw := &headWriter{header: make(http.Header), status: http.StatusOK}
+		next(hw, r)
+
+		for k, v := range hw.header {
+			w.Header()[k] = v
+		}
+		if w.Header().Get("Content-Length") == "" && hw.bytes > 0 {
+			w.Header().Set("Content-Length", strconv.FormatInt(hw.bytes, 10))
+		}
+		w.WriteHeader(hw.status)
+	}
+}
+
+// headWriter records the status and headers of a response and counts the
+// bytes of its body without keeping them
+type headWriter struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	bytes       int64
+}
+
+func (hw *headWriter) Header() http.Header { return hw.header }
+
+func (hw *headWriter) WriteHeader(status int) {
+	if !hw.wroteHeader {
+		hw.status = status
+		hw.wroteHeader = true
+	}
+}
+
+func (hw *headWriter) Write(b []byte) (int, error) {
+	hw.wroteHeader = true
+	hw.bytes += int64(len(b))
+	return len(b), nil
+}
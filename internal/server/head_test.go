@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ned1313/terraform-mirror/internal/cache"
+	"github.com/ned1313/terraform-mirror/internal/database"
+)
+
+func TestHandleBlobHead(t *testing.T) {
+	srv, store := setupBlobTest(t, nil)
+
+	key := "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip"
+	store.SetData(key, []byte("provider-binary"))
+
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/blobs/"+key, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, strconv.Itoa(len("provider-binary")), w.Header().Get("Content-Length"))
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Body.Bytes())
+
+	// The headers match those of a download
+	get := httptest.NewRecorder()
+	srv.router.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+	require.Equal(t, http.StatusOK, get.Code)
+	for _, header := range []string{"Content-Length", "Content-Type", "Content-Disposition"} {
+		assert.Equal(t, get.Header().Get(header), w.Header().Get(header), header)
+	}
+
+	w = httptest.NewRecorder()
+	srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/blobs/providers/missing.zip", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+}
+
+func TestHandleBlobHead_CachedContentType(t *testing.T) {
+	mc, err := cache.NewMemoryCache(cache.MemoryCacheConfig{MaxSizeMB: 1})
+	require.NoError(t, err)
+	srv, store := setupBlobTest(t, mc)
+
+	// The blob was cached with a type its extension does not give
+	key := "modules/hashicorp/consul/aws/0.1.0/module.tar.gz"
+	store.SetData(key, []byte("module-archive"))
+	require.NoError(t, mc.Set(context.Background(), key, strings.NewReader("module-archive"), "application/gzip", int64(len("module-archive")), 0))
+
+	head := httptest.NewRecorder()
+	srv.router.ServeHTTP(head, httptest.NewRequest(http.MethodHead, "/blobs/"+key, nil))
+	require.Equal(t, http.StatusOK, head.Code)
+
+	get := httptest.NewRecorder()
+	srv.router.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+	require.Equal(t, http.StatusOK, get.Code)
+	assert.Equal(t, "application/gzip", get.Header().Get("Content-Type"))
+	assert.Equal(t, get.Header().Get("Content-Type"), head.Header().Get("Content-Type"))
+}
+
+func TestHandleMirrorHead(t *testing.T) {
+	srv, _ := setupBlobTest(t, nil)
+	require.NoError(t, srv.providerRepo.Create(context.Background(), &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "aws",
+		Version:   "5.0.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-aws_5.0.0_linux_amd64.zip",
+		Shasum:    "abcdef1234567890",
+		S3Key:     "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip",
+	}))
+
+	for _, path := range []string{
+		"/registry.terraform.io/hashicorp/aws/index.json",
+		"/registry.terraform.io/hashicorp/aws/5.0.0.json",
+	} {
+		get := httptest.NewRecorder()
+		srv.router.ServeHTTP(get, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, get.Code, path)
+
+		head := httptest.NewRecorder()
+		srv.router.ServeHTTP(head, httptest.NewRequest(http.MethodHead, path, nil))
+		require.Equal(t, http.StatusOK, head.Code, path)
+		assert.Empty(t, head.Body.Bytes(), path)
+		assert.Equal(t, get.Header().Get("Content-Type"), head.Header().Get("Content-Type"), path)
+		assert.Equal(t, strconv.Itoa(get.Body.Len()), head.Header().Get("Content-Length"), path)
+	}
+
+	head := httptest.NewRecorder()
+	srv.router.ServeHTTP(head, httptest.NewRequest(http.MethodHead, "/registry.terraform.io/hashicorp/missing/index.json", nil))
+	assert.Equal(t, http.StatusNotFound, head.Code)
+	assert.Empty(t, head.Body.Bytes())
+}
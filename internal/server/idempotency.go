@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// idempotencyKeyHeader lets a client retry a job-creating request safely
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyWindow is how long a key keeps returning the job it created
+const idempotencyWindow = 24 * time.Hour
+
+// maxIdempotencyKeyLength bounds the keys stored in the database
+const maxIdempotencyKeyLength = 255
+
+// idempotencyKey returns the request's Idempotency-Key, or "" when it has none
+func idempotencyKey(r *http.Request) (string, error) {
+	key := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+	if len(key) > maxIdempotencyKeyLength {
+		return "", fmt.Errorf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength)
+	}
+	return key, nil
+}
+
+// findIdempotentJob returns the job created for key within the window,
+// expiring older keys first. Callers hold idempotencyMu from the lookup
+// until the new job is recorded so concurrent retries create one job.
+func (s *Server) findIdempotentJob(ctx context.Context, scope, key string) (int64, bool, error) {
+	cutoff := time.Now().Add(-idempotencyWindow)
+	if _, err := s.idempotencyRepo.DeleteOlderThan(ctx, cutoff); err != nil {
+		return 0, false, err
+	}
+	return s.idempotencyRepo.Find(ctx, scope, key, cutoff)
+}
+
+// recordIdempotentJob remembers the job created for key. A failure only
+// costs the protection against duplicates, so it is logged rather than
+// failing a request whose job already exists.
+func (s *Server) recordIdempotentJob(ctx context.Context, scope, key string, jobID int64) {
+	if err := s.idempotencyRepo.Record(ctx, scope, key, jobID); err != nil {
+		log.Printf("Warning: failed to record idempotency key for job %d: %v", jobID, err)
+	}
+}
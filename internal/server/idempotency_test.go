@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadRequest posts an HCL definitions file to a load endpoint with an
+// optional Idempotency-Key
+func loadRequest(t *testing.T, server *Server, token, path, content, key string) *httptest.ResponseRecorder {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "definitions.hcl")
+	require.NoError(t, err)
+	_, err = io.WriteString(part, content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, path, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+	addAuthHeader(req, token)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestHandleLoadProviders_IdempotencyKey(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	token := getAuthToken(t, server)
+	hcl := `
+provider "hashicorp/random" {
+  versions = ["3.5.0"]
+  platforms = ["linux_amd64"]
+}
+`
+	load := func(key string) (LoadProvidersResponse, *httptest.ResponseRecorder) {
+		rr := loadRequest(t, server, token, "/admin/api/providers/load", hcl, key)
+		require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+		var response LoadProvidersResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return response, rr
+	}
+
+	first, rr := load("retry-1")
+	assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+
+	second, rr := load("retry-1")
+	assert.Equal(t, first.JobID, second.JobID)
+	assert.Equal(t, "true", rr.Header().Get("Idempotent-Replayed"))
+
+	other, _ := load("retry-2")
+	assert.NotEqual(t, first.JobID, other.JobID)
+
+	// Requests without a key always create a job
+	unkeyed, _ := load("")
+	again, _ := load("")
+	assert.NotEqual(t, unkeyed.JobID, again.JobID)
+
+	jobs, err := server.jobRepo.List(context.Background(), 100, 0)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 4)
+}
+
+func TestHandleLoadModules_IdempotencyKey(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	token := getAuthToken(t, server)
+	hcl := `
+module "hashicorp/consul/aws" {
+  versions = ["0.1.0"]
+}
+`
+	load := func(path, key string) LoadModulesResponse {
+		rr := loadRequest(t, server, token, path, hcl, key)
+		require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+		var response LoadModulesResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return response
+	}
+
+	first := load("/admin/api/modules/load", "retry-1")
+	assert.Equal(t, first.JobID, load("/admin/api/modules/load", "retry-1").JobID)
+	assert.NotEqual(t, first.JobID, load("/admin/api/modules/load", "retry-2").JobID)
+}
+
+func TestHandleLoadProviders_IdempotencyKeyTooLong(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	rr := loadRequest(t, server, getAuthToken(t, server), "/admin/api/providers/load",
+		`provider "hashicorp/random" { versions = ["3.5.0"] }`, strings.Repeat("k", maxIdempotencyKeyLength+1))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&errResp))
+	assert.Equal(t, "invalid_idempotency_key", errResp.Error)
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// gzipMagic starts every gzip stream. JSON documents never start with it, so
+// compressed index cache entries can be told apart from plain ones.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// indexCompressionStats counts the bytes written to the cache for mirror
+// protocol documents before and after compression
+type indexCompressionStats struct {
+	documents         atomic.Int64
+	uncompressedBytes atomic.Int64
+	compressedBytes   atomic.Int64
+}
+
+// encodeIndexForCache returns the bytes to cache for a mirror protocol
+// document: gzip-compressed when cache.compress_index is enabled and that
+// makes it smaller, otherwise the document as is
+func (s *Server) encodeIndexForCache(data []byte) []byte {
+	if !s.config.Cache.CompressIndex {
+		return data
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return data
+	}
+	if err := zw.Close(); err != nil {
+		return data
+	}
+	if buf.Len() >= len(data) {
+		return data
+	}
+
+	s.indexCompression.documents.Add(1)
+	s.indexCompression.uncompressedBytes.Add(int64(len(data)))
+	s.indexCompression.compressedBytes.Add(int64(buf.Len()))
+	return buf.Bytes()
+}
+
+// decodeCachedIndex prepares a cached mirror protocol document for a client.
+// Compressed entries are passed through when the client accepts gzip, which
+// is reported by the returned flag, and decompressed otherwise.
+func decodeCachedIndex(data []byte, acceptGzip bool) ([]byte, bool, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, false, nil
+	}
+	if acceptGzip {
+		return data, true, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, false, err
+	}
+	defer zr.Close()
+
+	plain, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, false, err
+	}
+	return plain, false, nil
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip. An
+// explicit gzip entry takes precedence over a "*" wildcard.
+func acceptsGzip(r *http.Request) bool {
+	wildcard := false
+	for _, value := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(value, ",") {
+			coding, params, _ := strings.Cut(part, ";")
+			allowed := !isZeroQuality(params)
+			switch strings.ToLower(strings.TrimSpace(coding)) {
+			case "gzip", "x-gzip":
+				return allowed
+			case "*":
+				wildcard = allowed
+			}
+		}
+	}
+	return wildcard
+}
+
+// isZeroQuality reports whether Accept-Encoding parameters carry q=0
+func isZeroQuality(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if strings.EqualFold(strings.TrimSpace(name), "q") {
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			return err == nil && q == 0
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/cache"
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorIndex_CompressedCache(t *testing.T) {
+	mc, err := cache.NewMemoryCache(cache.MemoryCacheConfig{MaxSizeMB: 1})
+	require.NoError(t, err)
+	srv, _ := setupBlobTest(t, mc)
+	srv.config.Cache.CompressIndex = true
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		version := fmt.Sprintf("3.%d.0", i)
+		require.NoError(t, srv.providerRepo.Create(ctx, &database.Provider{
+			Namespace: "hashicorp",
+			Type:      "random",
+			Version:   version,
+			Platform:  "linux_amd64",
+			Filename:  "terraform-provider-random_" + version + "_linux_amd64.zip",
+			S3Key:     "providers/hashicorp/random/" + version + "/linux_amd64.zip",
+		}))
+	}
+
+	indexPath := "/registry.terraform.io/hashicorp/random/index.json"
+	get := func(acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, indexPath, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		srv.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w
+	}
+
+	// The first request builds the document and caches it compressed
+	fresh := get("")
+	item, ok := mc.GetItem(mirrorIndexCacheKey("hashicorp", "random", indexPath))
+	require.True(t, ok, "index response should be cached")
+	assert.True(t, bytes.HasPrefix(item.Data, gzipMagic), "cached index should be gzip-compressed")
+	assert.Less(t, len(item.Data), fresh.Body.Len())
+
+	// Clients without gzip support get the decompressed document
+	plain := get("")
+	assert.Empty(t, plain.Header().Get("Content-Encoding"))
+	assert.Equal(t, "application/json", plain.Header().Get("Content-Type"))
+	assert.JSONEq(t, fresh.Body.String(), plain.Body.String())
+
+	// Clients that accept gzip get the cached bytes as they are
+	encoded := get("gzip, deflate")
+	assert.Equal(t, "gzip", encoded.Header().Get("Content-Encoding"))
+	assert.Contains(t, encoded.Header().Values("Vary"), "Accept-Encoding")
+	zr, err := gzip.NewReader(encoded.Body)
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.JSONEq(t, fresh.Body.String(), string(decoded))
+
+	// The savings are reported in the cache stats
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/stats/cache", nil)
+	addAuthHeader(req, getAuthToken(t, srv))
+	w := httptest.NewRecorder()
+	srv.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stats CacheStatsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&stats))
+	require.NotNil(t, stats.IndexCompression)
+	assert.Equal(t, int64(1), stats.IndexCompression.DocumentsCompressed)
+	assert.Equal(t, int64(fresh.Body.Len()), stats.IndexCompression.UncompressedBytes)
+	assert.Equal(t, int64(len(item.Data)), stats.IndexCompression.CompressedBytes)
+	assert.Greater(t, stats.IndexCompression.BytesSaved, int64(0))
+}
+
+func TestMirrorIndex_UncompressedByDefault(t *testing.T) {
+	mc, err := cache.NewMemoryCache(cache.MemoryCacheConfig{MaxSizeMB: 1})
+	require.NoError(t, err)
+	srv, _ := setupBlobTest(t, mc)
+
+	indexPath := "/registry.terraform.io/hashicorp/random/index.json"
+	require.NoError(t, srv.providerRepo.Create(context.Background(), &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "random",
+		Version:   "3.0.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-random_3.0.0_linux_amd64.zip",
+		S3Key:     "providers/hashicorp/random/3.0.0/linux_amd64.zip",
+	}))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, indexPath, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		srv.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.JSONEq(t, `{"versions": {"3.0.0": {}}}`, w.Body.String())
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.5", true},
+		{"GZIP", true},
+		{"gzip;q=0", false},
+		{"br", false},
+		{"*", true},
+		{"gzip;q=0, *", false},
+		{"*;q=0", false},
+		{"identity", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Accept-Encoding", tt.header)
+			}
+			assert.Equal(t, tt.want, acceptsGzip(req))
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// backlogRetryAfterSeconds is suggested to clients refused by a full backlog
+const backlogRetryAfterSeconds = 60
+
+// checkJobBacklog refuses a load of newItems that would push the pending
+// item backlog past processor.max_pending_items, writing the error response.
+// A load larger than the limit can never fit and is a bad request; otherwise
+// the client should retry once the processor has caught up. Callers hold
+// backlogMu until their items are created.
+func (s *Server) checkJobBacklog(w http.ResponseWriter, r *http.Request, newItems int) bool {
+	limit := s.config.Processor.MaxPendingItems
+	if limit <= 0 {
+		return true
+	}
+
+	if newItems > limit {
+		respondError(w, http.StatusBadRequest, "too_many_items",
+			fmt.Sprintf("Load has %d items, more than max_pending_items (%d); split it into smaller files", newItems, limit))
+		return false
+	}
+
+	pending, err := s.jobRepo.CountPendingItems(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "Failed to count pending job items")
+		return false
+	}
+	if pending+int64(newItems) > int64(limit) {
+		w.Header().Set("Retry-After", strconv.Itoa(backlogRetryAfterSeconds))
+		respondError(w, http.StatusTooManyRequests, "backlog_full",
+			fmt.Sprintf("%d items are already pending; adding %d would exceed max_pending_items (%d)", pending, newItems, limit))
+		return false
+	}
+	return true
+}
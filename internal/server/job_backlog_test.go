@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleLoadProviders_BacklogLimit(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+	server.config.Processor.MaxPendingItems = 4
+
+	ctx := context.Background()
+	token := getAuthToken(t, server)
+
+	// A queued job with three items still waiting
+	job := &database.DownloadJob{SourceType: "hcl", SourceData: "{}", Status: "pending", TotalItems: 3}
+	require.NoError(t, server.jobRepo.Create(ctx, job))
+	for _, version := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+		require.NoError(t, server.jobRepo.CreateItem(ctx, &database.DownloadJobItem{
+			JobID: job.ID, Namespace: "hashicorp", Type: "null", Version: version, Platform: "linux_amd64", Status: "pending",
+		}))
+	}
+
+	twoItems := `
+provider "hashicorp/random" {
+  versions = ["3.5.0"]
+  platforms = ["linux_amd64", "darwin_arm64"]
+}
+`
+	t.Run("processor status reports the backlog", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api/processor/status", nil)
+		addAuthHeader(req, token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var status map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		assert.Equal(t, float64(3), status["pending_items"])
+		assert.Equal(t, float64(4), status["max_pending_items"])
+	})
+
+	t.Run("load over the backlog is rejected", func(t *testing.T) {
+		rr := loadRequest(t, server, token, "/admin/api/providers/load", twoItems, "")
+		require.Equal(t, http.StatusTooManyRequests, rr.Code, rr.Body.String())
+		assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+		var errResp ErrorResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, "backlog_full", errResp.Error)
+
+		rr = loadRequest(t, server, token, "/admin/api/modules/load", `
+module "hashicorp/consul/aws" {
+  versions = ["0.1.0", "0.2.0"]
+}
+`, "")
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code, rr.Body.String())
+
+		jobs, err := server.jobRepo.List(ctx, 100, 0)
+		require.NoError(t, err)
+		assert.Len(t, jobs, 1, "rejected loads must not create jobs")
+	})
+
+	t.Run("load larger than the limit is a bad request", func(t *testing.T) {
+		rr := loadRequest(t, server, token, "/admin/api/providers/load", `
+provider "hashicorp/random" {
+  versions = ["3.4.0", "3.5.0", "3.6.0"]
+  platforms = ["linux_amd64", "darwin_arm64"]
+}
+`, "")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		var errResp ErrorResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, "too_many_items", errResp.Error)
+	})
+
+	t.Run("load fits once the backlog drains", func(t *testing.T) {
+		_, err := server.jobRepo.Cancel(ctx, job.ID, "cancelled by test")
+		require.NoError(t, err)
+
+		rr := loadRequest(t, server, token, "/admin/api/providers/load", twoItems, "")
+		assert.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+	})
+}
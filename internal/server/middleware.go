@@ -1,8 +1,14 @@
 package server
 
 import (
+	"context"
 	"net/http"
+	"net/netip"
 	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/ned1313/terraform-mirror/internal/storage"
 )
 
 // corsMiddleware adds CORS headers for requests from trusted proxies
@@ -39,3 +45,234 @@ func isTrustedOrigin(origin string, trustedProxies []string) bool {
 	}
 	return false
 }
+
+// downloadLimiter caps the number of concurrent blob downloads so that bursts
+// of clients cannot overwhelm the storage backend
+type downloadLimiter struct {
+	slots        chan struct{}
+	queueTimeout time.Duration
+}
+
+// newDownloadLimiter returns nil (no limit) when max is not positive
+func newDownloadLimiter(max int, queueTimeout time.Duration) *downloadLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &downloadLimiter{
+		slots:        make(chan struct{}, max),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// Middleware queues requests over the limit for up to the queue timeout and
+// rejects them with 503 and Retry-After if no slot frees up
+func (l *downloadLimiter) Middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.acquire(r.Context()) {
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "Too many concurrent downloads, retry later", http.StatusServiceUnavailable)
+			return
+		}
+		defer l.release()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acquire takes a slot, waiting up to the queue timeout
+func (l *downloadLimiter) acquire(ctx context.Context) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if l.queueTimeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release frees a slot
+func (l *downloadLimiter) release() {
+	<-l.slots
+}
+
+// requestTimeoutMiddleware bounds a route group: the request context is
+// cancelled after timeout, answering 504 when the handler gives up because of
+// it, and the request body must arrive within the same window. Routes that
+// stream large responses are left out of every group rather than given a
+// large value.
+func requestTimeoutMiddleware(timeout time.Duration) func(next http.Handler) http.Handler {
+	withTimeout := middleware.Timeout(timeout)
+	return func(next http.Handler) http.Handler {
+		h := withTimeout(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Not every ResponseWriter supports deadlines; the context timeout still applies
+			_ = http.NewResponseController(w).SetReadDeadline(time.Now().Add(timeout))
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// realIPMiddleware resolves the client IP into r.RemoteAddr. Forwarded headers
+// are only honored when the immediate peer is a trusted proxy; otherwise they
+// could be set by the client to spoof its address in audit logs and sessions.
+func realIPMiddleware(trustedProxies []string) func(next http.Handler) http.Handler {
+	trusted := parseIPPrefixes(trustedProxies)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := clientIP(r, trusted); ip != "" {
+				r.RemoteAddr = ip
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestBaseURLMiddleware derives the mirror's base URL from each request and
+// stores it on the context, so local storage download URLs point back at the
+// host the client used. X-Forwarded-Proto and X-Forwarded-Host are only honored
+// from trusted proxies; it must run before realIPMiddleware rewrites RemoteAddr.
+func requestBaseURLMiddleware(trustedProxies []string) func(next http.Handler) http.Handler {
+	trusted := parseIPPrefixes(trustedProxies)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := storage.WithBaseURL(r.Context(), requestBaseURL(r, trusted))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// requestBaseURL returns scheme://host for the request as the client sent it
+func requestBaseURL(r *http.Request, trusted []netip.Prefix) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	host := r.Host
+
+	peer, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err == nil && prefixesContain(trusted, peer.Addr()) {
+		// Proxies append to these headers, so the first value is the client's
+		if proto := firstHeaderValue(r, "X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if fwdHost := firstHeaderValue(r, "X-Forwarded-Host"); fwdHost != "" {
+			host = fwdHost
+		}
+	}
+
+	return scheme + "://" + host
+}
+
+// firstHeaderValue returns the first comma-separated value of a header
+func firstHeaderValue(r *http.Request, name string) string {
+	value, _, _ := strings.Cut(r.Header.Get(name), ",")
+	return strings.TrimSpace(value)
+}
+
+// parseIPPrefixes parses CIDR ranges and bare IPs, skipping invalid entries
+func parseIPPrefixes(entries []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return prefixes
+}
+
+// prefixesContain reports whether addr is within any of the prefixes
+func prefixesContain(prefixes []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the client address for a request. When the peer is a trusted
+// proxy, X-Forwarded-For is walked from the right, skipping trusted hops, so the
+// first untrusted address is used; X-Real-IP is the fallback.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer, err := netip.ParseAddrPort(r.RemoteAddr)
+	var peerAddr netip.Addr
+	if err == nil {
+		peerAddr = peer.Addr()
+	} else if peerAddr, err = netip.ParseAddr(r.RemoteAddr); err != nil {
+		return ""
+	}
+	peerIP := peerAddr.Unmap().String()
+
+	if !prefixesContain(trusted, peerAddr) {
+		return peerIP
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				// A malformed hop can't be trusted further
+				break
+			}
+			if !prefixesContain(trusted, addr) || i == 0 {
+				return addr.Unmap().String()
+			}
+		}
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		if addr, err := netip.ParseAddr(realIP); err == nil {
+			return addr.Unmap().String()
+		}
+	}
+
+	return peerIP
+}
+
+// adminAllowlistMiddleware rejects admin requests whose client IP is outside
+// the allowed ranges. It relies on realIPMiddleware having already resolved
+// r.RemoteAddr, so forwarded headers are only honored from trusted proxies.
+// An empty allowlist permits every address.
+func adminAllowlistMiddleware(allowedCIDRs []string) func(next http.Handler) http.Handler {
+	allowed := parseIPPrefixes(allowedCIDRs)
+	return func(next http.Handler) http.Handler {
+		if len(allowed) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addr, err := netip.ParseAddr(r.RemoteAddr)
+			if err != nil {
+				var addrPort netip.AddrPort
+				addrPort, err = netip.ParseAddrPort(r.RemoteAddr)
+				addr = addrPort.Addr()
+			}
+			if err != nil || !prefixesContain(allowed, addr) {
+				respondError(w, http.StatusForbidden, "forbidden", "Admin access is not allowed from this address")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
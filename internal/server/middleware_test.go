@@ -0,0 +1,224 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRealIPMiddleware(t *testing.T) {
+	handler := realIPMiddleware([]string{"10.0.0.0/8", "192.168.1.5"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "untrusted peer ignores forwarded headers",
+			remoteAddr: "203.0.113.7:51234",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Real-IP": "1.2.3.4"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "trusted peer uses forwarded client",
+			remoteAddr: "10.1.2.3:51234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.20"},
+			want:       "198.51.100.20",
+		},
+		{
+			name:       "trusted single-address proxy",
+			remoteAddr: "192.168.1.5:51234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.20"},
+			want:       "198.51.100.20",
+		},
+		{
+			name:       "spoofed leftmost entry is skipped",
+			remoteAddr: "10.1.2.3:51234",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.20, 10.9.9.9"},
+			want:       "198.51.100.20",
+		},
+		{
+			name:       "trusted peer with X-Real-IP",
+			remoteAddr: "10.1.2.3:51234",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.20"},
+			want:       "198.51.100.20",
+		},
+		{
+			name:       "trusted peer without headers",
+			remoteAddr: "10.1.2.3:51234",
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "malformed forwarded header",
+			remoteAddr: "10.1.2.3:51234",
+			headers:    map[string]string{"X-Forwarded-For": "not-an-ip"},
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "IPv6 peer",
+			remoteAddr: "[2001:db8::1]:51234",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4"},
+			want:       "2001:db8::1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Body.String())
+		})
+	}
+}
+
+func TestRealIPMiddleware_NoTrustedProxies(t *testing.T) {
+	handler := realIPMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "127.0.0.1:51234"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, "127.0.0.1", w.Body.String())
+}
+
+func TestRequestBaseURL(t *testing.T) {
+	trusted := parseIPPrefixes([]string{"10.0.0.0/8"})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "request host",
+			remoteAddr: "198.51.100.20:51234",
+			want:       "http://mirror.example.com:8080",
+		},
+		{
+			name:       "forwarded headers from trusted proxy",
+			remoteAddr: "10.1.2.3:51234",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "mirror.example.org, internal:8080"},
+			want:       "https://mirror.example.org",
+		},
+		{
+			name:       "forwarded headers from untrusted peer",
+			remoteAddr: "198.51.100.20:51234",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.example.com"},
+			want:       "http://mirror.example.com:8080",
+		},
+		{
+			name:       "unknown forwarded scheme",
+			remoteAddr: "10.1.2.3:51234",
+			headers:    map[string]string{"X-Forwarded-Proto": "ftp"},
+			want:       "http://mirror.example.com:8080",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = "mirror.example.com:8080"
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			assert.Equal(t, tt.want, requestBaseURL(req, trusted))
+		})
+	}
+}
+
+func TestAdminAllowlistMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	// Chain with realIPMiddleware the same way the router does
+	handler := realIPMiddleware([]string{"10.0.0.0/8"})(adminAllowlistMiddleware([]string{"192.168.0.0/16", "203.0.113.9"})(ok))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       int
+	}{
+		{
+			name:       "allowed direct client",
+			remoteAddr: "192.168.4.20:51234",
+			want:       http.StatusOK,
+		},
+		{
+			name:       "allowed single address",
+			remoteAddr: "203.0.113.9:51234",
+			want:       http.StatusOK,
+		},
+		{
+			name:       "denied direct client",
+			remoteAddr: "198.51.100.20:51234",
+			want:       http.StatusForbidden,
+		},
+		{
+			name:       "allowed client behind trusted proxy",
+			remoteAddr: "10.1.2.3:51234",
+			headers:    map[string]string{"X-Forwarded-For": "192.168.4.20"},
+			want:       http.StatusOK,
+		},
+		{
+			name:       "denied client behind trusted proxy",
+			remoteAddr: "10.1.2.3:51234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.20"},
+			want:       http.StatusForbidden,
+		},
+		{
+			name:       "spoofed header from untrusted peer",
+			remoteAddr: "198.51.100.20:51234",
+			headers:    map[string]string{"X-Forwarded-For": "192.168.4.20"},
+			want:       http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/api/providers", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestAdminAllowlist_PublicEndpointsOpen(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	server.config.Server.AdminAllowedCIDRs = []string{"192.168.0.0/16"}
+	server.setupRouter()
+
+	request := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "198.51.100.20:51234"
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, request("/admin/api/providers"))
+	assert.Equal(t, http.StatusOK, request("/health"))
+}
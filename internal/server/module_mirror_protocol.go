@@ -138,6 +138,8 @@ func (s *Server) handleModuleDownload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.logDownload(r, "module", namespace+"/"+name+"/"+system+"/"+version, nil)
+
 	// Return the download URL via X-Terraform-Get header
 	// This is how the module registry protocol works - it returns a 204 with the header
 	w.Header().Set("X-Terraform-Get", downloadURL)
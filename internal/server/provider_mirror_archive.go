@@ -0,0 +1,48 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// handleMirrorPlatformArchive redirects to one platform's archive at
+// /{hostname}/{namespace}/{type}/{version}/{os}/{arch}.zip, so a client that
+// knows its platform can fetch the package without reading the version
+// document first. Blocked and unmirrored platforms are not found.
+func (s *Server) handleMirrorPlatformArchive(w http.ResponseWriter, r *http.Request, parts []string) {
+	ctx := r.Context()
+	namespace, providerType := s.resolveProviderAlias(ctx, parts[1], parts[2])
+	version := parts[3]
+	platform := parts[4] + "_" + strings.TrimSuffix(parts[5], ".zip")
+
+	p, err := s.providerRepo.GetByIdentity(ctx, namespace, providerType, version, platform)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "failed to query provider")
+		return
+	}
+	if p == nil || p.Blocked {
+		respondError(w, http.StatusNotFound, "not_found",
+			fmt.Sprintf("provider %s/%s %s is not available for %s", namespace, providerType, version, platform))
+		return
+	}
+
+	downloadURL, err := s.storage.GetPresignedURL(ctx, p.S3Key, 24*time.Hour)
+	if err != nil {
+		s.logger.Printf("Failed to get presigned URL for provider %s: %v", p.S3Key, err)
+		respondError(w, http.StatusInternalServerError, "storage_error", "failed to generate download URL")
+		return
+	}
+	// As in version documents, the upstream URL is never handed out
+	if p.DownloadURL != "" && downloadURL == p.DownloadURL {
+		s.logger.Printf("Refusing to serve upstream URL for %s/%s %s (%s)", namespace, providerType, version, platform)
+		respondError(w, http.StatusNotFound, "not_found",
+			fmt.Sprintf("provider %s/%s %s is not available for %s", namespace, providerType, version, platform))
+		return
+	}
+
+	s.logDownload(r, "provider", strings.Join([]string{parts[0], namespace, providerType, version, platform}, "/"),
+		map[string]interface{}{"protocol": "mirror", "filename": p.Filename})
+	http.Redirect(w, r, downloadURL, http.StatusFound)
+}
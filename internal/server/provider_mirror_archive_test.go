@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorProtocol_PlatformArchive(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	srv.config.Server.PublicURL = "https://mirror.example.com/"
+	store, err := storage.NewLocalStorage(storage.LocalConfig{
+		BasePath: t.TempDir(),
+		BaseURL:  srv.config.Server.GetPublicURL(),
+	})
+	require.NoError(t, err)
+	srv.storage = store
+	srv.setupRouter()
+
+	ctx := context.Background()
+	repo := database.NewProviderRepository(srv.db)
+	for _, p := range []database.Provider{
+		{Platform: "linux_amd64"},
+		{Platform: "darwin_arm64", Blocked: true},
+	} {
+		p.Namespace = "hashicorp"
+		p.Type = "random"
+		p.Version = "3.5.0"
+		p.Filename = "terraform-provider-random_3.5.0_" + p.Platform + ".zip"
+		p.Shasum = "abcdef1234567890"
+		p.S3Key = "providers/registry.terraform.io/hashicorp/random/3.5.0/" + p.Platform + "/" + p.Filename
+		require.NoError(t, store.Upload(ctx, p.S3Key, strings.NewReader("provider"), "application/zip", nil))
+		require.NoError(t, repo.Create(ctx, &p))
+	}
+
+	get := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	t.Run("existing platform", func(t *testing.T) {
+		w := get(http.MethodGet, "/registry.terraform.io/hashicorp/random/3.5.0/linux/amd64.zip")
+		require.Equal(t, http.StatusFound, w.Code, w.Body.String())
+		assert.Equal(t,
+			"https://mirror.example.com/blobs/providers/registry.terraform.io/hashicorp/random/3.5.0/linux_amd64/terraform-provider-random_3.5.0_linux_amd64.zip",
+			w.Header().Get("Location"))
+
+		w = get(http.MethodHead, "/registry.terraform.io/hashicorp/random/3.5.0/linux/amd64.zip")
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Location"))
+	})
+
+	t.Run("platform not available", func(t *testing.T) {
+		for _, path := range []string{
+			"/registry.terraform.io/hashicorp/random/3.5.0/windows/amd64.zip", // not mirrored
+			"/registry.terraform.io/hashicorp/random/3.5.0/darwin/arm64.zip",  // blocked
+			"/registry.terraform.io/hashicorp/random/9.9.9/linux/amd64.zip",   // unknown version
+			"/registry.terraform.io/hashicorp/random/3.5.0/linux_amd64.zip",   // malformed
+		} {
+			w := get(http.MethodGet, path)
+			assert.Equal(t, http.StatusNotFound, w.Code, path)
+			assert.Empty(t, w.Header().Get("Location"), path)
+		}
+	})
+
+	t.Run("never redirects upstream", func(t *testing.T) {
+		require.NoError(t, repo.Create(ctx, &database.Provider{
+			Namespace:   "hashicorp",
+			Type:        "random",
+			Version:     "3.6.0",
+			Platform:    "linux_amd64",
+			Filename:    "terraform-provider-random_3.6.0_linux_amd64.zip",
+			DownloadURL: "https://releases.hashicorp.com/terraform-provider-random/3.6.0/terraform-provider-random_3.6.0_linux_amd64.zip",
+			Shasum:      "abcdef1234567890",
+			S3Key:       "providers/registry.terraform.io/hashicorp/random/3.6.0/linux_amd64/terraform-provider-random_3.6.0_linux_amd64.zip",
+		}))
+		srv.storage = &upstreamURLStorage{Storage: store, url: "https://releases.hashicorp.com/terraform-provider-random/3.6.0/terraform-provider-random_3.6.0_linux_amd64.zip"}
+
+		w := get(http.MethodGet, "/registry.terraform.io/hashicorp/random/3.6.0/linux/amd64.zip")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Empty(t, w.Header().Get("Location"))
+	})
+}
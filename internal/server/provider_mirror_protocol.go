@@ -1,12 +1,17 @@
 package server
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/provider"
+	"github.com/ned1313/terraform-mirror/internal/storage"
 )
 
 // handleMirrorCatchAll is a debug handler to route mirror protocol requests
@@ -14,6 +19,17 @@ func (s *Server) handleMirrorCatchAll(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	fmt.Printf("Mirror catchall: %s\n", path)
 
+	// Platform archives are addressed by os and arch below the version
+	if strings.HasSuffix(path, ".zip") {
+		parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+		if len(parts) != 6 {
+			http.NotFound(w, r)
+			return
+		}
+		s.handleMirrorPlatformArchive(w, r, parts)
+		return
+	}
+
 	// Check if it ends with .json
 	if !strings.HasSuffix(path, ".json") {
 		http.NotFound(w, r)
@@ -24,23 +40,153 @@ func (s *Server) handleMirrorCatchAll(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
 	fmt.Printf("Path parts: %v (len=%d)\n", parts, len(parts))
 
-	if len(parts) == 4 && parts[3] == "index.json" {
-		// Handle index.json
-		s.handleMirrorProviderVersionsFromParts(w, r, parts[0], parts[1], parts[2])
+	if len(parts) != 4 {
+		http.NotFound(w, r)
 		return
 	}
 
-	if len(parts) == 4 && strings.HasSuffix(parts[3], ".json") {
-		// Handle version.json
-		version := strings.TrimSuffix(parts[3], ".json")
-		s.handleMirrorProviderPackagesFromParts(w, r, parts[0], parts[1], parts[2], version)
+	// An aliased address is served from the provider it points to. Cache keys
+	// keep the requested path but use the target's prefix, so invalidating
+	// the target also drops documents served through its aliases.
+	parts[1], parts[2] = s.resolveProviderAlias(r.Context(), parts[1], parts[2])
+
+	// The verbose index is an authenticated extension and bypasses the cache
+	if parts[3] == "index.json" && wantsVerboseIndex(r) {
+		s.handleMirrorVerboseIndex(w, r, parts[1], parts[2])
 		return
 	}
 
-	http.NotFound(w, r)
+	// Admins may be shown blocked versions. That view depends on the caller,
+	// so it is private and bypasses the cache.
+	if s.config.Providers.IncludeBlockedForAdmins {
+		w.Header().Add("Vary", "Authorization")
+		if s.isAdminRequest(r) {
+			w.Header().Set("Cache-Control", "private, no-store")
+			s.serveMirrorDocument(w, r, parts, true)
+			return
+		}
+	}
+
+	// Serve from the index cache when available. Archive URLs derived from the
+	// request host differ per host, so version documents are cached per host.
+	cacheKey := mirrorIndexCacheKey(parts[1], parts[2], path)
+	if baseURL := storage.BaseURLFromContext(r.Context()); baseURL != "" && parts[3] != "index.json" {
+		cacheKey = mirrorIndexCacheKey(parts[1], parts[2], baseURL+path)
+	}
+	if s.config.Cache.CompressIndex {
+		// Cached documents may be sent gzip-encoded
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+	if cached, contentType, found := s.cache.Get(r.Context(), cacheKey); found {
+		data, err := io.ReadAll(cached)
+		cached.Close()
+		var gzipped bool
+		if err == nil {
+			data, gzipped, err = decodeCachedIndex(data, acceptsGzip(r))
+		}
+		if err == nil {
+			s.logMirrorDownload(r, parts)
+			w.Header().Set("Content-Type", contentType)
+			if gzipped {
+				w.Header().Set("Content-Encoding", "gzip")
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(data)
+			return
+		}
+		s.logger.Printf("Failed to read cached index %s, rebuilding: %v", path, err)
+	}
+
+	rec := &indexResponseRecorder{header: make(http.Header), status: http.StatusOK}
+	s.serveMirrorDocument(rec, r, parts, false)
+
+	// Only successful responses are cached; index documents change as new
+	// versions are mirrored, so they use the short index TTL
+	if rec.status == http.StatusOK {
+		data := s.encodeIndexForCache(rec.body.Bytes())
+		if err := s.cache.Set(r.Context(), cacheKey, bytes.NewReader(data), "application/json", int64(len(data)), s.config.Cache.GetIndexTTL()); err != nil {
+			s.logger.Printf("Failed to cache index %s: %v", path, err)
+		}
+	}
+
+	if rec.status == http.StatusOK {
+		s.logMirrorDownload(r, parts)
+	}
+
+	for k, v := range rec.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(rec.status)
+	w.Write(rec.body.Bytes())
+}
+
+// serveMirrorDocument builds index.json or a version document, optionally
+// including blocked versions
+func (s *Server) serveMirrorDocument(w http.ResponseWriter, r *http.Request, parts []string, includeBlocked bool) {
+	if parts[3] == "index.json" {
+		s.handleMirrorProviderVersionsFromParts(w, r, parts[0], parts[1], parts[2], includeBlocked)
+		return
+	}
+	version := strings.TrimSuffix(parts[3], ".json")
+	s.handleMirrorProviderPackagesFromParts(w, r, parts[0], parts[1], parts[2], version, includeBlocked)
+}
+
+// isAdminRequest reports whether the request carries a valid admin token
+// and comes from an address allowed to use the admin API. It runs the same
+// middleware as the admin routes, discarding their error responses.
+func (s *Server) isAdminRequest(r *http.Request) bool {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		return false
+	}
+	admin := false
+	check := adminAllowlistMiddleware(s.config.Server.AdminAllowedCIDRs)(s.authMiddleware(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) { admin = true }),
+	))
+	check.ServeHTTP(&indexResponseRecorder{header: make(http.Header)}, r)
+	return admin
+}
+
+// logMirrorDownload audits a served version document. Archives may be fetched
+// straight from object storage, so this is the last point the mirror sees a
+// network mirror client before it downloads a provider.
+func (s *Server) logMirrorDownload(r *http.Request, parts []string) {
+	if parts[3] == "index.json" {
+		return
+	}
+	version := strings.TrimSuffix(parts[3], ".json")
+	s.logDownload(r, "provider", strings.Join([]string{parts[0], parts[1], parts[2], version}, "/"),
+		map[string]interface{}{"protocol": "mirror"})
 }
 
-func (s *Server) handleMirrorProviderVersionsFromParts(w http.ResponseWriter, r *http.Request, hostname, namespace, providerType string) {
+// mirrorIndexCacheKey returns the cache key for a mirror protocol JSON document.
+// The prefix keeps index entries from colliding with blob keys.
+func mirrorIndexCacheKey(namespace, providerType, path string) string {
+	return mirrorIndexCachePrefix(namespace, providerType) + path
+}
+
+// mirrorIndexCachePrefix returns the key prefix shared by every cached mirror
+// protocol document of a provider, whatever the hostname or request host
+func mirrorIndexCachePrefix(namespace, providerType string) string {
+	return "mirror-index:" + namespace + "/" + providerType + ":"
+}
+
+// indexResponseRecorder buffers a mirror protocol response so it can be cached
+type indexResponseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *indexResponseRecorder) Header() http.Header { return rec.header }
+
+func (rec *indexResponseRecorder) WriteHeader(status int) { rec.status = status }
+
+func (rec *indexResponseRecorder) Write(b []byte) (int, error) { return rec.body.Write(b) }
+
+// handleMirrorProviderVersionsFromParts serves index.json. Blocked versions
+// are left out unless includeBlocked is set, in which case versions with
+// every platform blocked are flagged.
+func (s *Server) handleMirrorProviderVersionsFromParts(w http.ResponseWriter, r *http.Request, hostname, namespace, providerType string, includeBlocked bool) {
 	ctx := r.Context()
 
 	// Query database for all versions of this provider
@@ -77,19 +223,27 @@ func (s *Server) handleMirrorProviderVersionsFromParts(w http.ResponseWriter, r
 		return
 	}
 
-	if len(providers) == 0 {
-		w.WriteHeader(http.StatusNotFound)
-		return
+	// Build versions map; a version is blocked when all its platforms are
+	blocked := make(map[string]bool)
+	for _, p := range providers {
+		wasBlocked, seen := blocked[p.Version]
+		blocked[p.Version] = p.Blocked && (wasBlocked || !seen)
 	}
-
-	// Build versions map
 	versions := make(map[string]interface{})
-	for _, p := range providers {
-		if _, exists := versions[p.Version]; !exists {
-			versions[p.Version] = map[string]interface{}{}
+	for version, isBlocked := range blocked {
+		switch {
+		case !isBlocked:
+			versions[version] = map[string]interface{}{}
+		case includeBlocked:
+			versions[version] = map[string]interface{}{"blocked": true}
 		}
 	}
 
+	if len(versions) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
 	response := map[string]interface{}{
 		"versions": versions,
 	}
@@ -97,7 +251,10 @@ func (s *Server) handleMirrorProviderVersionsFromParts(w http.ResponseWriter, r
 	respondJSON(w, http.StatusOK, response)
 }
 
-func (s *Server) handleMirrorProviderPackagesFromParts(w http.ResponseWriter, r *http.Request, hostname, namespace, providerType, version string) {
+// handleMirrorProviderPackagesFromParts serves a version document. Blocked
+// archives are left out unless includeBlocked is set, in which case they are
+// flagged.
+func (s *Server) handleMirrorProviderPackagesFromParts(w http.ResponseWriter, r *http.Request, hostname, namespace, providerType, version string, includeBlocked bool) {
 	ctx := r.Context()
 
 	// Query database for all platforms of this specific version
@@ -109,23 +266,31 @@ func (s *Server) handleMirrorProviderPackagesFromParts(w http.ResponseWriter, r
 
 	// Filter to only the requested version
 	var versionProviders []*database.Provider
+	mirrored := false
 	for _, p := range providers {
-		if p.Version == version {
+		if p.Version != version {
+			continue
+		}
+		mirrored = true
+		if !p.Blocked || includeBlocked {
 			versionProviders = append(versionProviders, p)
 		}
 	}
 
-	// If no providers found for this version, try auto-download
-	if len(versionProviders) == 0 {
+	// If the version is not mirrored at all, try auto-download. A blocked
+	// version must not be fetched again.
+	if !mirrored {
 		if s.autoDownloadService != nil && s.autoDownloadService.IsEnabled() {
 			s.logger.Printf("Provider %s/%s %s not found in mirror, attempting auto-download for all platforms",
 				namespace, providerType, version)
 
 			// Download for all configured platforms
 			platforms := s.config.AutoDownload.GetPlatforms()
+			conflict := false
 			for _, platform := range platforms {
-				platformOS, platformArch := parsePlatformString(platform)
-				if platformOS == "" || platformArch == "" {
+				platformOS, platformArch, err := provider.ParsePlatform(platform)
+				if err != nil {
+					s.logger.Printf("Skipping auto-download: %v", err)
 					continue
 				}
 
@@ -135,10 +300,17 @@ func (s *Server) handleMirrorProviderPackagesFromParts(w http.ResponseWriter, r
 				if downloadErr != nil {
 					s.logger.Printf("Auto-download failed for %s/%s %s (%s): %v",
 						namespace, providerType, version, platform, downloadErr)
+					conflict = conflict || errors.Is(downloadErr, database.ErrShasumMismatch)
 					continue
 				}
 				versionProviders = append(versionProviders, downloadedProvider)
 			}
+
+			if len(versionProviders) == 0 && conflict {
+				respondError(w, http.StatusConflict, "artifact_conflict",
+					"upstream content differs from the mirrored artifact")
+				return
+			}
 		}
 	}
 
@@ -149,37 +321,62 @@ func (s *Server) handleMirrorProviderPackagesFromParts(w http.ResponseWriter, r
 
 	// Build archives map
 	archives := make(map[string]interface{})
+	var signingKeys []ProviderRegistryGPGKey
+	seenKeys := make(map[string]bool)
 
 	for _, p := range versionProviders {
+		// Every platform of a release is signed by the same keys
+		for _, key := range provider.DecodeSigningKeys(p.SigningKeys) {
+			if !seenKeys[key.KeyID] {
+				seenKeys[key.KeyID] = true
+				signingKeys = append(signingKeys, ProviderRegistryGPGKey{KeyID: key.KeyID, ASCIIArmor: key.ASCIIArmor})
+			}
+		}
+
+		// Archives are always served from the mirror's own storage. The
+		// recorded upstream DownloadURL is unreachable from air-gapped
+		// clients, so it is never emitted, even if storage hands it back.
 		downloadURL, err := s.storage.GetPresignedURL(ctx, p.S3Key, 24*time.Hour)
 		if err != nil {
 			continue
 		}
+		if p.DownloadURL != "" && downloadURL == p.DownloadURL {
+			s.logger.Printf("Refusing to serve upstream URL for %s/%s %s (%s)",
+				namespace, providerType, version, p.Platform)
+			continue
+		}
 
+		// Providers mirrored before h1: hashes were recorded only have zh:
 		var hashes []string
+		if p.H1Hash.Valid && p.H1Hash.String != "" {
+			hashes = append(hashes, p.H1Hash.String)
+		}
 		if p.Shasum != "" {
 			hashes = append(hashes, fmt.Sprintf("zh:%s", p.Shasum))
 		}
 
-		archives[p.Platform] = map[string]interface{}{
+		archive := map[string]interface{}{
 			"url":    downloadURL,
 			"hashes": hashes,
 		}
+		if p.Blocked {
+			archive["blocked"] = true
+		}
+		// With local storage, air-gapped installers can copy the file straight
+		// from the storage directory instead of fetching the URL
+		if s.config.Storage.Type == "local" {
+			archive["local_path"] = p.S3Key
+		}
+		archives[p.Platform] = archive
 	}
 
 	response := map[string]interface{}{
 		"archives": archives,
 	}
+	// Omitted when the keys were not captured at download time
+	if len(signingKeys) > 0 {
+		response["signing_keys"] = ProviderRegistrySigningKeys{GPGPublicKeys: signingKeys}
+	}
 
 	respondJSON(w, http.StatusOK, response)
 }
-
-// parsePlatformString splits a platform string (e.g., "linux_amd64") into os and arch
-func parsePlatformString(platform string) (os, arch string) {
-	for i := len(platform) - 1; i >= 0; i-- {
-		if platform[i] == '_' {
-			return platform[:i], platform[i+1:]
-		}
-	}
-	return "", ""
-}
@@ -2,12 +2,17 @@ package server
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/provider"
+	"github.com/ned1313/terraform-mirror/internal/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -444,3 +449,350 @@ func TestMirrorProtocol_HashFormat(t *testing.T) {
 	assert.Contains(t, hash, "zh:", "hash should have zh: prefix")
 	assert.Equal(t, "zh:abcdef1234567890", hash, "hash should be in zh:hex format")
 }
+
+func TestMirrorProtocol_H1Hash(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := database.NewProviderRepository(srv.db)
+
+	h1 := "h1:xZI3mwl3y9eMOlY5HzkUOWScTOezYcbNnsNS0UVRgJE="
+	require.NoError(t, repo.Create(ctx, &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "random",
+		Version:   "3.5.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-random_3.5.0_linux_amd64.zip",
+		Shasum:    "abcdef1234567890",
+		H1Hash:    sql.NullString{String: h1, Valid: true},
+		S3Key:     "providers/hashicorp/random/3.5.0/linux_amd64.zip",
+	}))
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/registry.terraform.io/hashicorp/random/3.5.0.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Archives map[string]struct {
+			Hashes []string `json:"hashes"`
+		} `json:"archives"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, []string{h1, "zh:abcdef1234567890"}, response.Archives["linux_amd64"].Hashes)
+}
+
+func TestMirrorProtocol_SigningKeys(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := database.NewProviderRepository(srv.db)
+
+	armor := "-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nmQINBGB...\n-----END PGP PUBLIC KEY BLOCK-----"
+	keys := provider.EncodeSigningKeys([]provider.GPGPublicKey{{KeyID: "34365D9472D7468F", ASCIIArmor: armor}})
+	for _, platform := range []string{"linux_amd64", "darwin_arm64"} {
+		require.NoError(t, repo.Create(ctx, &database.Provider{
+			Namespace:   "hashicorp",
+			Type:        "random",
+			Version:     "3.5.0",
+			Platform:    platform,
+			Filename:    "terraform-provider-random_3.5.0_" + platform + ".zip",
+			Shasum:      "abcdef1234567890",
+			SigningKeys: keys,
+			S3Key:       "providers/hashicorp/random/3.5.0/" + platform + ".zip",
+		}))
+	}
+	require.NoError(t, repo.Create(ctx, &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "random",
+		Version:   "3.4.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-random_3.4.0_linux_amd64.zip",
+		Shasum:    "1234567890abcdef",
+		S3Key:     "providers/hashicorp/random/3.4.0/linux_amd64.zip",
+	}))
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/registry.terraform.io/hashicorp/random/3.5.0.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Archives    map[string]interface{}      `json:"archives"`
+		SigningKeys ProviderRegistrySigningKeys `json:"signing_keys"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Len(t, response.Archives, 2)
+
+	// The key is shared by both platforms and listed once
+	require.Len(t, response.SigningKeys.GPGPublicKeys, 1)
+	assert.Equal(t, "34365D9472D7468F", response.SigningKeys.GPGPublicKeys[0].KeyID)
+	assert.Equal(t, armor, response.SigningKeys.GPGPublicKeys[0].ASCIIArmor)
+
+	t.Run("unknown keys", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/registry.terraform.io/hashicorp/random/3.4.0.json", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]interface{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Contains(t, response, "archives")
+		assert.NotContains(t, response, "signing_keys")
+	})
+}
+
+func TestMirrorProtocol_PublicURL(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	// Local storage is built from the public URL the same way main does
+	srv.config.Server.PublicURL = "https://mirror.example.com/"
+	store, err := storage.NewLocalStorage(storage.LocalConfig{
+		BasePath: t.TempDir(),
+		BaseURL:  srv.config.Server.GetPublicURL(),
+	})
+	require.NoError(t, err)
+	srv.storage = store
+	srv.setupRouter()
+
+	ctx := context.Background()
+	key := "providers/registry.terraform.io/hashicorp/random/3.5.0/linux_amd64/terraform-provider-random_3.5.0_linux_amd64.zip"
+	require.NoError(t, store.Upload(ctx, key, strings.NewReader("provider"), "application/zip", nil))
+	require.NoError(t, database.NewProviderRepository(srv.db).Create(ctx, &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "random",
+		Version:   "3.5.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-random_3.5.0_linux_amd64.zip",
+		Shasum:    "abcdef1234567890",
+		S3Key:     key,
+	}))
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/registry.terraform.io/hashicorp/random/3.5.0.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Archives map[string]struct {
+			URL       string   `json:"url"`
+			LocalPath string   `json:"local_path"`
+			Hashes    []string `json:"hashes"`
+		} `json:"archives"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+
+	archive, ok := response.Archives["linux_amd64"]
+	require.True(t, ok)
+	assert.Equal(t, "https://mirror.example.com/blobs/"+key, archive.URL)
+	assert.Equal(t, key, archive.LocalPath)
+}
+
+func TestMirrorProtocol_RequestHostFallback(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	// Without public_url, main still gives local storage a localhost base URL
+	store, err := storage.NewLocalStorage(storage.LocalConfig{
+		BasePath: t.TempDir(),
+		BaseURL:  srv.config.Server.GetPublicURL(),
+	})
+	require.NoError(t, err)
+	srv.storage = store
+	srv.setupRouter()
+
+	ctx := context.Background()
+	key := "providers/registry.terraform.io/hashicorp/random/3.5.0/linux_amd64/terraform-provider-random_3.5.0_linux_amd64.zip"
+	require.NoError(t, store.Upload(ctx, key, strings.NewReader("provider"), "application/zip", nil))
+	require.NoError(t, database.NewProviderRepository(srv.db).Create(ctx, &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "random",
+		Version:   "3.5.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-random_3.5.0_linux_amd64.zip",
+		S3Key:     key,
+	}))
+
+	archiveURL := func(host string) string {
+		req := httptest.NewRequest(http.MethodGet, "/registry.terraform.io/hashicorp/random/3.5.0.json", nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Archives map[string]struct {
+				URL string `json:"url"`
+			} `json:"archives"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		return response.Archives["linux_amd64"].URL
+	}
+
+	assert.Equal(t, "http://mirror.internal:8080/blobs/"+key, archiveURL("mirror.internal:8080"))
+	// Cached documents are per host, so another host gets its own URLs
+	assert.Equal(t, "http://10.0.0.5:8080/blobs/"+key, archiveURL("10.0.0.5:8080"))
+
+	// The blob handler serves the path the archive URL points at
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "provider", w.Body.String())
+}
+
+func TestMirrorProtocol_BlockedVersions(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, p := range []database.Provider{
+		{Version: "3.0.0", Platform: "linux_amd64", Blocked: true},
+		{Version: "3.1.0", Platform: "linux_amd64"},
+		{Version: "3.1.0", Platform: "darwin_arm64", Blocked: true},
+	} {
+		p.Namespace = "hashicorp"
+		p.Type = "random"
+		p.Filename = "terraform-provider-random_" + p.Version + "_" + p.Platform + ".zip"
+		p.S3Key = "providers/registry.terraform.io/hashicorp/random/" + p.Version + "/" + p.Platform + "/" + p.Filename
+		require.NoError(t, server.storage.Upload(ctx, p.S3Key, strings.NewReader("provider"), "application/zip", nil))
+		require.NoError(t, server.providerRepo.Create(ctx, &p))
+	}
+
+	token := getAuthToken(t, server)
+	get := func(path string, admin bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if admin {
+			addAuthHeader(req, token)
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	archives := func(w *httptest.ResponseRecorder) map[string]map[string]interface{} {
+		var response struct {
+			Archives map[string]map[string]interface{} `json:"archives"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Archives
+	}
+
+	indexPath := "/registry.terraform.io/hashicorp/random/index.json"
+
+	t.Run("anonymous clients never see blocked versions", func(t *testing.T) {
+		w := get(indexPath, false)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"versions": {"3.1.0": {}}}`, w.Body.String())
+
+		assert.Equal(t, http.StatusNotFound, get("/registry.terraform.io/hashicorp/random/3.0.0.json", false).Code)
+
+		w = get("/registry.terraform.io/hashicorp/random/3.1.0.json", false)
+		require.Equal(t, http.StatusOK, w.Code)
+		got := archives(w)
+		assert.Len(t, got, 1)
+		assert.NotContains(t, got["linux_amd64"], "blocked")
+	})
+
+	t.Run("admins get the filtered view unless enabled", func(t *testing.T) {
+		w := get(indexPath, true)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"versions": {"3.1.0": {}}}`, w.Body.String())
+	})
+
+	server.config.Providers.IncludeBlockedForAdmins = true
+
+	t.Run("admins see blocked versions flagged", func(t *testing.T) {
+		w := get(indexPath, true)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"versions": {"3.0.0": {"blocked": true}, "3.1.0": {}}}`, w.Body.String())
+		assert.Equal(t, "private, no-store", w.Header().Get("Cache-Control"))
+		assert.Contains(t, w.Header().Values("Vary"), "Authorization")
+
+		w = get("/registry.terraform.io/hashicorp/random/3.0.0.json", true)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, true, archives(w)["linux_amd64"]["blocked"])
+
+		w = get("/registry.terraform.io/hashicorp/random/3.1.0.json", true)
+		require.Equal(t, http.StatusOK, w.Code)
+		got := archives(w)
+		assert.Len(t, got, 2)
+		assert.Equal(t, true, got["darwin_arm64"]["blocked"])
+		assert.NotContains(t, got["linux_amd64"], "blocked")
+	})
+
+	t.Run("anonymous view is unchanged when enabled", func(t *testing.T) {
+		w := get(indexPath, false)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"versions": {"3.1.0": {}}}`, w.Body.String())
+
+		req := httptest.NewRequest(http.MethodGet, indexPath, nil)
+		addAuthHeader(req, "not-a-valid-token")
+		w = httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"versions": {"3.1.0": {}}}`, w.Body.String())
+	})
+}
+
+// upstreamURLStorage hands back a fixed URL in place of its own
+type upstreamURLStorage struct {
+	storage.Storage
+	url string
+}
+
+func (s *upstreamURLStorage) GetPresignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	return s.url, nil
+}
+
+func TestMirrorProtocol_NeverServesUpstreamURL(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	srv.config.Server.PublicURL = "https://mirror.example.com/"
+	store, err := storage.NewLocalStorage(storage.LocalConfig{
+		BasePath: t.TempDir(),
+		BaseURL:  srv.config.Server.GetPublicURL(),
+	})
+	require.NoError(t, err)
+	srv.storage = store
+	srv.setupRouter()
+
+	ctx := context.Background()
+	upstreamURL := "https://releases.hashicorp.com/terraform-provider-random/3.5.0/terraform-provider-random_3.5.0_linux_amd64.zip"
+	key := "providers/registry.terraform.io/hashicorp/random/3.5.0/linux_amd64/terraform-provider-random_3.5.0_linux_amd64.zip"
+	require.NoError(t, store.Upload(ctx, key, strings.NewReader("provider"), "application/zip", nil))
+	require.NoError(t, database.NewProviderRepository(srv.db).Create(ctx, &database.Provider{
+		Namespace:   "hashicorp",
+		Type:        "random",
+		Version:     "3.5.0",
+		Platform:    "linux_amd64",
+		Filename:    "terraform-provider-random_3.5.0_linux_amd64.zip",
+		DownloadURL: upstreamURL,
+		Shasum:      "abcdef1234567890",
+		S3Key:       key,
+	}))
+
+	archives := func() map[string]struct {
+		URL string `json:"url"`
+	} {
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/registry.terraform.io/hashicorp/random/3.5.0.json", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "releases.hashicorp.com")
+
+		var response struct {
+			Archives map[string]struct {
+				URL string `json:"url"`
+			} `json:"archives"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		return response.Archives
+	}
+
+	got := archives()
+	require.Contains(t, got, "linux_amd64")
+	assert.Equal(t, "https://mirror.example.com/blobs/"+key, got["linux_amd64"].URL)
+
+	// Even a storage backend that returns the upstream URL cannot leak it
+	srv.storage = &upstreamURLStorage{Storage: store, url: upstreamURL}
+	srv.cache.Clear(ctx)
+	assert.Empty(t, archives())
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// verboseIndexMediaType requests the verbose index.json form via the Accept header
+const verboseIndexMediaType = "application/vnd.tf-mirror.verbose+json"
+
+// MirrorVerboseVersion describes one mirrored version in the verbose index
+type MirrorVerboseVersion struct {
+	Platforms  []string `json:"platforms"`
+	Deprecated bool     `json:"deprecated"`
+}
+
+// MirrorVerboseIndexResponse is the verbose form of index.json. It is an
+// extension for operators and tooling; Terraform only ever gets the
+// protocol-compliant form.
+type MirrorVerboseIndexResponse struct {
+	Versions map[string]MirrorVerboseVersion `json:"versions"`
+}
+
+// wantsVerboseIndex reports whether the client asked for the verbose index,
+// either with ?view=verbose or the verbose media type in Accept
+func wantsVerboseIndex(r *http.Request) bool {
+	if r.URL.Query().Get("view") == "verbose" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), verboseIndexMediaType)
+}
+
+// handleMirrorVerboseIndex serves the verbose index.json form. It carries
+// admin-only detail, so it sits behind the same checks as the admin API and
+// is never cached.
+func (s *Server) handleMirrorVerboseIndex(w http.ResponseWriter, r *http.Request, namespace, providerType string) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		providers, err := s.providerRepo.ListVersions(r.Context(), namespace, providerType)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "database_error", "failed to query provider versions")
+			return
+		}
+		if len(providers) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		versions := make(map[string]MirrorVerboseVersion)
+		for _, p := range providers {
+			v := versions[p.Version]
+			v.Platforms = append(v.Platforms, p.Platform)
+			v.Deprecated = v.Deprecated || p.Deprecated
+			versions[p.Version] = v
+		}
+		for version, v := range versions {
+			sort.Strings(v.Platforms)
+			versions[version] = v
+		}
+
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.Header().Set("Vary", "Accept, Authorization")
+		respondJSON(w, http.StatusOK, MirrorVerboseIndexResponse{Versions: versions})
+	})
+
+	adminAllowlistMiddleware(s.config.Server.AdminAllowedCIDRs)(s.authMiddleware(handler)).ServeHTTP(w, r)
+}
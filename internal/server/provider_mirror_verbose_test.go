@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorIndex_VerboseForm(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, p := range []database.Provider{
+		{Version: "3.0.0", Platform: "linux_amd64", Deprecated: true},
+		{Version: "3.1.0", Platform: "linux_amd64"},
+		{Version: "3.1.0", Platform: "darwin_arm64"},
+	} {
+		p.Namespace = "hashicorp"
+		p.Type = "random"
+		p.Filename = "terraform-provider-random_" + p.Version + "_" + p.Platform + ".zip"
+		p.S3Key = "providers/registry.terraform.io/hashicorp/random/" + p.Version + "/" + p.Platform + "/" + p.Filename
+		require.NoError(t, server.providerRepo.Create(ctx, &p))
+	}
+
+	token := getAuthToken(t, server)
+	indexPath := "/registry.terraform.io/hashicorp/random/index.json"
+
+	get := func(path string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("public form matches the protocol", func(t *testing.T) {
+		w := get(indexPath, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"versions": {"3.0.0": {}, "3.1.0": {}}}`, w.Body.String())
+	})
+
+	t.Run("verbose requires authentication", func(t *testing.T) {
+		w := get(indexPath+"?view=verbose", nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.NotContains(t, w.Body.String(), "platforms")
+
+		w = get(indexPath, map[string]string{"Accept": verboseIndexMediaType})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	want := MirrorVerboseIndexResponse{Versions: map[string]MirrorVerboseVersion{
+		"3.0.0": {Platforms: []string{"linux_amd64"}, Deprecated: true},
+		"3.1.0": {Platforms: []string{"darwin_arm64", "linux_amd64"}},
+	}}
+
+	t.Run("verbose via query", func(t *testing.T) {
+		w := get(indexPath+"?view=verbose", map[string]string{"Authorization": "Bearer " + token})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "private, no-store", w.Header().Get("Cache-Control"))
+
+		var resp MirrorVerboseIndexResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, want, resp)
+	})
+
+	t.Run("verbose via accept header", func(t *testing.T) {
+		w := get(indexPath, map[string]string{
+			"Authorization": "Bearer " + token,
+			"Accept":        verboseIndexMediaType,
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp MirrorVerboseIndexResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, want, resp)
+	})
+
+	t.Run("authenticated default stays compliant", func(t *testing.T) {
+		w := get(indexPath, map[string]string{"Authorization": "Bearer " + token})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"versions": {"3.0.0": {}, "3.1.0": {}}}`, w.Body.String())
+	})
+}
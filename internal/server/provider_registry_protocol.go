@@ -0,0 +1,269 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/provider"
+)
+
+// registryProtocols are the plugin protocol versions advertised for providers
+// whose protocols were not captured from upstream; 5.0 is understood by every
+// Terraform release since 0.12.
+var registryProtocols = []string{"5.0"}
+
+// providerProtocols returns the protocols captured when the provider was
+// downloaded, falling back to registryProtocols
+func providerProtocols(p *database.Provider) []string {
+	if protocols := provider.DecodeProtocols(p.Protocols); len(protocols) > 0 {
+		return protocols
+	}
+	return registryProtocols
+}
+
+// ProviderRegistryVersionsResponse is the registry protocol response for listing provider versions
+type ProviderRegistryVersionsResponse struct {
+	Versions []ProviderRegistryVersion `json:"versions"`
+}
+
+// ProviderRegistryVersion is a single version in the registry versions list
+type ProviderRegistryVersion struct {
+	Version   string                     `json:"version"`
+	Protocols []string                   `json:"protocols"`
+	Platforms []ProviderRegistryPlatform `json:"platforms"`
+}
+
+// ProviderRegistryPlatform is an os/arch pair a version is available for
+type ProviderRegistryPlatform struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+}
+
+// ProviderRegistryDownloadResponse is the registry protocol download descriptor.
+// The SHA256SUMS document is rebuilt from the mirrored platforms, so no
+// upstream signature can match it and shasums_signature_url is left out.
+type ProviderRegistryDownloadResponse struct {
+	Protocols   []string                    `json:"protocols"`
+	OS          string                      `json:"os"`
+	Arch        string                      `json:"arch"`
+	Filename    string                      `json:"filename"`
+	DownloadURL string                      `json:"download_url"`
+	ShasumsURL  string                      `json:"shasums_url"`
+	Shasum      string                      `json:"shasum"`
+	SigningKeys ProviderRegistrySigningKeys `json:"signing_keys"`
+}
+
+// ProviderRegistrySigningKeys lists the keys that signed the SHA256SUMS document
+type ProviderRegistrySigningKeys struct {
+	GPGPublicKeys []ProviderRegistryGPGKey `json:"gpg_public_keys"`
+}
+
+// ProviderRegistryGPGKey is an ASCII-armored GPG public key
+type ProviderRegistryGPGKey struct {
+	KeyID      string `json:"key_id"`
+	ASCIIArmor string `json:"ascii_armor"`
+}
+
+// registrySigningKeys returns the signing keys captured when the provider was
+// downloaded, or an empty list when they are unknown
+func registrySigningKeys(p *database.Provider) ProviderRegistrySigningKeys {
+	keys := []ProviderRegistryGPGKey{}
+	for _, key := range provider.DecodeSigningKeys(p.SigningKeys) {
+		keys = append(keys, ProviderRegistryGPGKey{KeyID: key.KeyID, ASCIIArmor: key.ASCIIArmor})
+	}
+	return ProviderRegistrySigningKeys{GPGPublicKeys: keys}
+}
+
+// providerRegistryPath returns the registry protocol path for a provider version resource
+func (s *Server) providerRegistryPath(namespace, providerType, version, resource string) string {
+	base := strings.TrimSuffix(s.config.Discovery.GetProvidersPath(), "/")
+	return fmt.Sprintf("%s/%s/%s/%s/%s", base, namespace, providerType, version, resource)
+}
+
+// handleProviderRegistryVersions handles GET /v1/providers/{namespace}/{type}/versions
+func (s *Server) handleProviderRegistryVersions(w http.ResponseWriter, r *http.Request) {
+	namespace := chi.URLParam(r, "namespace")
+	providerType := chi.URLParam(r, "type")
+
+	providers, err := s.providerRepo.ListVersions(r.Context(), namespace, providerType)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "failed to query provider versions")
+		return
+	}
+
+	// Group platforms by version, keeping the repository's version order.
+	// Blocked platforms are left out, as in the mirror protocol.
+	var order []string
+	platforms := make(map[string][]ProviderRegistryPlatform)
+	protocols := make(map[string][]string)
+	for _, p := range providers {
+		if p.Blocked {
+			continue
+		}
+		os, arch, err := provider.ParsePlatform(p.Platform)
+		if err != nil {
+			continue
+		}
+		if _, seen := platforms[p.Version]; !seen {
+			order = append(order, p.Version)
+		}
+		// Every platform of a release speaks the same protocols
+		if captured := provider.DecodeProtocols(p.Protocols); len(captured) > 0 {
+			protocols[p.Version] = captured
+		}
+		platforms[p.Version] = append(platforms[p.Version], ProviderRegistryPlatform{OS: os, Arch: arch})
+	}
+
+	if len(order) == 0 {
+		respondError(w, http.StatusNotFound, "not_found",
+			fmt.Sprintf("provider %s/%s not found", namespace, providerType))
+		return
+	}
+
+	response := ProviderRegistryVersionsResponse{Versions: make([]ProviderRegistryVersion, 0, len(order))}
+	for _, version := range order {
+		list := platforms[version]
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].OS != list[j].OS {
+				return list[i].OS < list[j].OS
+			}
+			return list[i].Arch < list[j].Arch
+		})
+		versionProtocols := protocols[version]
+		if versionProtocols == nil {
+			versionProtocols = registryProtocols
+		}
+		response.Versions = append(response.Versions, ProviderRegistryVersion{
+			Version:   version,
+			Protocols: versionProtocols,
+			Platforms: list,
+		})
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// handleProviderRegistryDownload handles GET /v1/providers/{namespace}/{type}/{version}/download/{os}/{arch}
+func (s *Server) handleProviderRegistryDownload(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace := chi.URLParam(r, "namespace")
+	providerType := chi.URLParam(r, "type")
+	version := chi.URLParam(r, "version")
+	os := chi.URLParam(r, "os")
+	arch := chi.URLParam(r, "arch")
+
+	p, err := s.providerRepo.GetByIdentity(ctx, namespace, providerType, version, os+"_"+arch)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "failed to query provider")
+		return
+	}
+	if p == nil || p.Blocked {
+		respondError(w, http.StatusNotFound, "not_found",
+			fmt.Sprintf("provider %s/%s %s is not available for %s_%s", namespace, providerType, version, os, arch))
+		return
+	}
+
+	downloadURL, err := s.storage.GetPresignedURL(ctx, p.S3Key, 24*time.Hour)
+	if err != nil {
+		s.logger.Printf("Failed to get presigned URL for provider %s: %v", p.S3Key, err)
+		respondError(w, http.StatusInternalServerError, "storage_error", "failed to generate download URL")
+		return
+	}
+
+	s.logDownload(r, "provider", fmt.Sprintf("%s/%s/%s/%s_%s", namespace, providerType, version, os, arch),
+		map[string]interface{}{"protocol": "registry", "filename": p.Filename})
+
+	respondJSON(w, http.StatusOK, ProviderRegistryDownloadResponse{
+		Protocols:   providerProtocols(p),
+		OS:          os,
+		Arch:        arch,
+		Filename:    p.Filename,
+		DownloadURL: downloadURL,
+		ShasumsURL:  s.providerRegistryPath(namespace, providerType, version, "SHA256SUMS"),
+		Shasum:      p.Shasum,
+		SigningKeys: registrySigningKeys(p),
+	})
+}
+
+// handleProviderRegistryShasums handles GET /v1/providers/{namespace}/{type}/{version}/SHA256SUMS
+// A document stored when the version was mirrored is served as is while none
+// of its platforms is blocked. Otherwise it is built from the unblocked
+// platforms in the same format as upstream releases.
+func (s *Server) handleProviderRegistryShasums(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	namespace := chi.URLParam(r, "namespace")
+	providerType := chi.URLParam(r, "type")
+	version := chi.URLParam(r, "version")
+
+	providers, err := s.providerRepo.ListVersions(ctx, namespace, providerType)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database_error", "failed to query provider")
+		return
+	}
+
+	var versionProviders []*database.Provider
+	anyBlocked := false
+	for _, p := range providers {
+		if p.Version != version {
+			continue
+		}
+		if p.Blocked {
+			anyBlocked = true
+			continue
+		}
+		if p.Shasum != "" {
+			versionProviders = append(versionProviders, p)
+		}
+	}
+	if len(versionProviders) == 0 {
+		respondError(w, http.StatusNotFound, "not_found",
+			fmt.Sprintf("provider %s/%s %s not found", namespace, providerType, version))
+		return
+	}
+
+	if !anyBlocked && s.serveStoredShasums(w, r, namespace, providerType, version) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(provider.BuildShasums(versionProviders))
+}
+
+// serveStoredShasums writes the SHA256SUMS document stored for a version,
+// reporting whether it did. A missing or unreadable object falls back to
+// building the document.
+func (s *Server) serveStoredShasums(w http.ResponseWriter, r *http.Request, namespace, providerType, version string) bool {
+	ctx := r.Context()
+	key, err := s.providerRepo.GetShasumsKey(ctx, namespace, providerType, version)
+	if err != nil {
+		s.logger.Printf("Failed to look up stored SHA256SUMS for %s/%s %s: %v", namespace, providerType, version, err)
+		return false
+	}
+	if key == "" {
+		return false
+	}
+
+	reader, err := s.storage.Download(ctx, key)
+	if err != nil {
+		s.logger.Printf("Failed to read stored SHA256SUMS %s, building it instead: %v", key, err)
+		return false
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		s.logger.Printf("Failed to read stored SHA256SUMS %s, building it instead: %v", key, err)
+		return false
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+	return true
+}
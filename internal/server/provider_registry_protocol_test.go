@@ -0,0 +1,216 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedRegistryProviders stores two versions of hashicorp/random
+func seedRegistryProviders(t *testing.T, srv *Server) {
+	ctx := context.Background()
+	for _, p := range []database.Provider{
+		{Version: "3.0.0", Platform: "linux_amd64", Shasum: "aaa111"},
+		{Version: "3.0.0", Platform: "darwin_arm64", Shasum: "bbb222"},
+		{Version: "3.1.0", Platform: "linux_amd64", Shasum: "ccc333"},
+	} {
+		p.Namespace = "hashicorp"
+		p.Type = "random"
+		p.Filename = "terraform-provider-random_" + p.Version + "_" + p.Platform + ".zip"
+		p.S3Key = "providers/registry.terraform.io/hashicorp/random/" + p.Version + "/" + p.Platform + "/" + p.Filename
+		require.NoError(t, srv.providerRepo.Create(ctx, &p))
+	}
+}
+
+func TestProviderRegistry_Versions(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+	seedRegistryProviders(t, srv)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/providers/hashicorp/random/versions", nil)
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp ProviderRegistryVersionsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Versions, 2)
+
+	byVersion := make(map[string]ProviderRegistryVersion)
+	for _, v := range resp.Versions {
+		byVersion[v.Version] = v
+	}
+	assert.Equal(t, []ProviderRegistryPlatform{{OS: "darwin", Arch: "arm64"}, {OS: "linux", Arch: "amd64"}}, byVersion["3.0.0"].Platforms)
+	assert.Equal(t, []ProviderRegistryPlatform{{OS: "linux", Arch: "amd64"}}, byVersion["3.1.0"].Platforms)
+	assert.Equal(t, []string{"5.0"}, byVersion["3.1.0"].Protocols)
+
+	t.Run("unknown provider", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/providers/hashicorp/nothing/versions", nil)
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestProviderRegistry_Download(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+	seedRegistryProviders(t, srv)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/providers/hashicorp/random/3.0.0/download/darwin/arm64", nil)
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// The rebuilt SHA256SUMS cannot carry the upstream signature
+	assert.NotContains(t, w.Body.String(), "shasums_signature_url")
+
+	var resp ProviderRegistryDownloadResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "darwin", resp.OS)
+	assert.Equal(t, "arm64", resp.Arch)
+	assert.Equal(t, "terraform-provider-random_3.0.0_darwin_arm64.zip", resp.Filename)
+	assert.Equal(t, "bbb222", resp.Shasum)
+	assert.Contains(t, resp.DownloadURL, "providers/registry.terraform.io/hashicorp/random/3.0.0/darwin_arm64/")
+	assert.Equal(t, "/v1/providers/hashicorp/random/3.0.0/SHA256SUMS", resp.ShasumsURL)
+	assert.NotNil(t, resp.SigningKeys.GPGPublicKeys)
+
+	t.Run("shasums document", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, resp.ShasumsURL, nil)
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t,
+			"bbb222  terraform-provider-random_3.0.0_darwin_arm64.zip\n"+
+				"aaa111  terraform-provider-random_3.0.0_linux_amd64.zip\n",
+			w.Body.String())
+	})
+
+	t.Run("stored shasums document", func(t *testing.T) {
+		ctx := context.Background()
+		key := "providers/registry.terraform.io/hashicorp/random/3.0.0/terraform-provider-random_3.0.0_SHA256SUMS"
+		stored := "ddd444  terraform-provider-random_3.0.0_windows_amd64.zip\n"
+		require.NoError(t, srv.storage.Upload(ctx, key, strings.NewReader(stored), "text/plain; charset=utf-8", nil))
+		require.NoError(t, srv.providerRepo.SetShasumsKey(ctx, "hashicorp", "random", "3.0.0", key))
+
+		req := httptest.NewRequest(http.MethodGet, resp.ShasumsURL, nil)
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, stored, w.Body.String())
+
+		// A stored key whose object is gone falls back to building the document
+		require.NoError(t, srv.storage.Delete(ctx, key))
+		w = httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, resp.ShasumsURL, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "aaa111  terraform-provider-random_3.0.0_linux_amd64.zip\n")
+	})
+
+	t.Run("missing platform", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/providers/hashicorp/random/3.1.0/download/darwin/arm64", nil)
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestProviderRegistry_SkipsBlocked(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+	seedRegistryProviders(t, srv)
+
+	ctx := context.Background()
+	_, err := srv.providerRepo.SetVersionBlocked(ctx, "hashicorp", "random", "3.1.0", true)
+	require.NoError(t, err)
+	blocked, err := srv.providerRepo.GetByIdentity(ctx, "hashicorp", "random", "3.0.0", "linux_amd64")
+	require.NoError(t, err)
+	blocked.Blocked = true
+	require.NoError(t, srv.providerRepo.Update(ctx, blocked))
+
+	// A stored document still lists the blocked platform and must not be served
+	key := "providers/registry.terraform.io/hashicorp/random/3.0.0/terraform-provider-random_3.0.0_SHA256SUMS"
+	stored := "bbb222  terraform-provider-random_3.0.0_darwin_arm64.zip\n" +
+		"aaa111  terraform-provider-random_3.0.0_linux_amd64.zip\n"
+	require.NoError(t, srv.storage.Upload(ctx, key, strings.NewReader(stored), "text/plain; charset=utf-8", nil))
+	require.NoError(t, srv.providerRepo.SetShasumsKey(ctx, "hashicorp", "random", "3.0.0", key))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/v1/providers/hashicorp/random/versions")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ProviderRegistryVersionsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Versions, 1)
+	assert.Equal(t, "3.0.0", resp.Versions[0].Version)
+	assert.Equal(t, []ProviderRegistryPlatform{{OS: "darwin", Arch: "arm64"}}, resp.Versions[0].Platforms)
+
+	assert.Equal(t, http.StatusNotFound, get("/v1/providers/hashicorp/random/3.0.0/download/linux/amd64").Code)
+	assert.Equal(t, http.StatusNotFound, get("/v1/providers/hashicorp/random/3.1.0/download/linux/amd64").Code)
+	assert.Equal(t, http.StatusNotFound, get("/v1/providers/hashicorp/random/3.1.0/SHA256SUMS").Code)
+
+	w = get("/v1/providers/hashicorp/random/3.0.0/SHA256SUMS")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "bbb222  terraform-provider-random_3.0.0_darwin_arm64.zip\n", w.Body.String())
+}
+
+func TestProviderRegistry_CapturedProtocols(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	err := srv.providerRepo.Create(context.Background(), &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "aws",
+		Version:   "5.0.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-aws_5.0.0_linux_amd64.zip",
+		Protocols: sql.NullString{String: "5.0,6.0", Valid: true},
+		S3Key:     "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip",
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/providers/hashicorp/aws/versions", nil)
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp ProviderRegistryVersionsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.Versions, 1)
+	assert.Equal(t, []string{"5.0", "6.0"}, resp.Versions[0].Protocols)
+}
+
+func TestProviderRegistry_DownloadAudit(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+	seedRegistryProviders(t, srv)
+	srv.config.Logging.AuditDownloads = true
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/providers/hashicorp/random/3.0.0/download/linux/amd64", nil)
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var actions []*database.AdminAction
+	require.Eventually(t, func() bool {
+		var err error
+		actions, err = srv.auditRepo.ListByAction(context.Background(), "download", 10, 0)
+		return err == nil && len(actions) == 1
+	}, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, "provider", actions[0].ResourceType)
+	assert.Equal(t, "hashicorp/random/3.0.0/linux_amd64", actions[0].ResourceID.String)
+	assert.JSONEq(t, `{"protocol": "registry", "filename": "terraform-provider-random_3.0.0_linux_amd64.zip"}`, actions[0].Metadata.String)
+}
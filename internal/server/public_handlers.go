@@ -124,44 +124,22 @@ func (s *Server) handlePublicListProviders(w http.ResponseWriter, r *http.Reques
 func (s *Server) handlePublicListModules(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	// Get query parameters for filtering
-	namespace := r.URL.Query().Get("namespace")
-	name := r.URL.Query().Get("name")
-	system := r.URL.Query().Get("system")
+	filter := database.ModuleFilter{
+		Namespace: r.URL.Query().Get("namespace"),
+		Name:      r.URL.Query().Get("name"),
+		System:    r.URL.Query().Get("system"),
+	}
 
-	// Get all modules from database (we need all to aggregate)
-	modules, err := s.moduleRepo.List(ctx, 10000, 0)
+	// Get all matching modules from database (we need all to aggregate)
+	modules, err := s.moduleRepo.List(ctx, filter, 10000, 0)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "database_error", "Failed to list modules")
 		return
 	}
 
-	// Ensure modules is never nil
-	if modules == nil {
-		modules = make([]*database.Module, 0)
-	}
-
-	// Filter if parameters provided
-	filtered := modules
-	if namespace != "" || name != "" || system != "" {
-		filtered = make([]*database.Module, 0)
-		for _, m := range modules {
-			if namespace != "" && m.Namespace != namespace {
-				continue
-			}
-			if name != "" && m.Name != name {
-				continue
-			}
-			if system != "" && m.System != system {
-				continue
-			}
-			filtered = append(filtered, m)
-		}
-	}
-
 	// Aggregate by namespace/name/system
 	aggregated := make(map[string]*AggregatedModuleResponse)
-	for _, m := range filtered {
+	for _, m := range modules {
 		key := m.Namespace + "/" + m.Name + "/" + m.System
 		if agg, exists := aggregated[key]; exists {
 			// Add version if not already present
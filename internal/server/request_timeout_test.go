@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ned1313/terraform-mirror/internal/cache"
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deadlineCache records whether each lookup's context carried a deadline;
+// handlers look up the cache with the request context
+type deadlineCache struct {
+	cache.Cache
+	mu        sync.Mutex
+	deadlines map[string]time.Duration // 0 when the context had no deadline
+}
+
+func (d *deadlineCache) Get(ctx context.Context, key string) (io.ReadCloser, string, bool) {
+	var remaining time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining = time.Until(deadline)
+	}
+	d.mu.Lock()
+	d.deadlines[key] = remaining
+	d.mu.Unlock()
+	return nil, "", false
+}
+
+// slowStorage delays downloads and blocks presigned URLs until the request
+// context gives up
+type slowStorage struct {
+	storage.Storage
+	delay time.Duration
+}
+
+func (s *slowStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return s.Storage.Download(ctx, key)
+}
+
+func (s *slowStorage) GetPresignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestRequestTimeouts(t *testing.T) {
+	dc := &deadlineCache{Cache: cache.NewNoOpCache(), deadlines: make(map[string]time.Duration)}
+	srv, store := setupBlobTest(t, dc)
+	srv.storage = &slowStorage{Storage: store, delay: 1500 * time.Millisecond}
+	srv.config.Server.RequestTimeoutSeconds = 1
+	srv.setupRouter()
+
+	t.Run("metadata request times out quickly", func(t *testing.T) {
+		require.NoError(t, srv.moduleRepo.Create(context.Background(), &database.Module{
+			Namespace: "hashicorp",
+			Name:      "consul",
+			System:    "aws",
+			Version:   "0.1.0",
+			S3Key:     "modules/hashicorp/consul/aws/0.1.0.tar.gz",
+			Filename:  "0.1.0.tar.gz",
+		}))
+
+		start := time.Now()
+		w := httptest.NewRecorder()
+		srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/modules/hashicorp/consul/aws/0.1.0/download", nil))
+
+		assert.Less(t, time.Since(start), 5*time.Second)
+		assert.NotEqual(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Header().Get("X-Terraform-Get"))
+	})
+
+	t.Run("mirror metadata carries the short deadline", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/registry.terraform.io/hashicorp/aws/index.json", nil))
+
+		dc.mu.Lock()
+		defer dc.mu.Unlock()
+		remaining, ok := dc.deadlines[mirrorIndexCacheKey("hashicorp", "aws", "/registry.terraform.io/hashicorp/aws/index.json")]
+		require.True(t, ok, "mirror lookup did not reach the cache")
+		assert.Greater(t, remaining, time.Duration(0))
+		assert.LessOrEqual(t, remaining, time.Second)
+	})
+
+	t.Run("blob download outlives the request timeout", func(t *testing.T) {
+		key := "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip"
+		store.SetData(key, []byte("provider-binary"))
+
+		w := httptest.NewRecorder()
+		srv.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/blobs/"+key, nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "provider-binary", w.Body.String())
+
+		dc.mu.Lock()
+		defer dc.mu.Unlock()
+		remaining, ok := dc.deadlines[key]
+		require.True(t, ok, "blob lookup did not reach the cache")
+		assert.Zero(t, remaining, "blob downloads must not carry a request deadline")
+	})
+}
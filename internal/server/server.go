@@ -1,7 +1,9 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -9,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -17,11 +20,14 @@ import (
 	"github.com/ned1313/terraform-mirror/internal/cache"
 	"github.com/ned1313/terraform-mirror/internal/config"
 	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/logging"
 	"github.com/ned1313/terraform-mirror/internal/metrics"
 	"github.com/ned1313/terraform-mirror/internal/module"
 	"github.com/ned1313/terraform-mirror/internal/processor"
 	"github.com/ned1313/terraform-mirror/internal/provider"
+	"github.com/ned1313/terraform-mirror/internal/schedule"
 	"github.com/ned1313/terraform-mirror/internal/storage"
+	"golang.org/x/sync/singleflight"
 )
 
 // Server represents the HTTP server
@@ -35,10 +41,35 @@ type Server struct {
 	logger  *log.Logger
 	metrics *metrics.Metrics
 
+	// blobFlight coalesces concurrent cache misses for the same blob
+	blobFlight singleflight.Group
+
+	// downloadLimiter bounds concurrent blob downloads; nil when unlimited
+	downloadLimiter *downloadLimiter
+
+	// blobDrain lets in-flight blob downloads finish during shutdown
+	blobDrain blobDrain
+
+	// indexCompression tracks the savings of compressed index cache entries
+	indexCompression indexCompressionStats
+
+	// logBuffer holds recent log output for the admin API; nil when disabled
+	logBuffer *logging.RingBuffer
+
+	// backupFunc writes a database backup to a path; tests replace it to
+	// inject corrupt backups
+	backupFunc func(ctx context.Context, path string) error
+
 	// Services
 	authService               *auth.Service
 	processorService          *processor.Service
+	syncScheduler             *processor.SyncScheduler
 	autoDownloadService       *provider.AutoDownloadService
+	providerRegistry          provider.RegistryDownloader
+	providerMetadata          provider.MetadataFetcher
+	providerPlatforms         provider.PlatformLister
+	registryPinger            provider.RegistryPinger
+	upstreams                 *provider.UpstreamRouter // Names the registry each provider namespace is mirrored from
 	moduleAutoDownloadService *module.AutoDownloadService
 
 	// Repositories
@@ -46,6 +77,18 @@ type Server struct {
 	moduleRepo   *database.ModuleRepository
 	jobRepo      *database.JobRepository
 	auditRepo    *database.AuditRepository
+	labelRepo    *database.LabelRepository
+	searchRepo   *database.SearchRepository
+	aliasRepo    *database.ProviderAliasRepository
+
+	// idempotencyRepo and idempotencyMu let retried job-creating requests
+	// return the job they already created
+	idempotencyRepo *database.IdempotencyRepository
+	idempotencyMu   sync.Mutex
+
+	// backlogMu is held from the backlog check until a load's items exist,
+	// so concurrent loads cannot both fit under max_pending_items
+	backlogMu sync.Mutex
 }
 
 // New creates a new HTTP server instance
@@ -56,23 +99,51 @@ func New(cfg *config.Config, db *database.DB, storage storage.Storage) *Server {
 // NewWithCache creates a new HTTP server instance with an optional cache
 func NewWithCache(cfg *config.Config, db *database.DB, storageBackend storage.Storage, c cache.Cache) *Server {
 	// Create auth service
-	authService := auth.NewService(
-		cfg.Auth.JWTSecret,
+	authService := auth.NewServiceWithSecrets(
+		cfg.Auth.GetJWTSecrets(),
 		cfg.Auth.JWTExpirationHours,
 		cfg.Auth.BCryptCost,
 	)
 
+	// Initialize metrics if telemetry is enabled
+	var m *metrics.Metrics
+	if cfg.Telemetry.Enabled {
+		m = metrics.New()
+		db.SetMetrics(m)
+		log.Printf("Telemetry enabled: metrics available at /metrics")
+	}
+
+	// Record storage operations for every consumer of the backend
+	storageBackend = storage.WithMetrics(storageBackend, m)
+
 	// Create processor service
 	processorConfig := processor.Config{
 		PollingInterval:    time.Duration(cfg.Processor.PollingIntervalSeconds) * time.Second,
+		MaxPollingInterval: time.Duration(cfg.Processor.MaxPollingIntervalSeconds) * time.Second,
 		MaxConcurrentJobs:  cfg.Processor.MaxConcurrentJobs,
 		RetryAttempts:      cfg.Processor.RetryAttempts,
 		RetryDelay:         time.Duration(cfg.Processor.RetryDelaySeconds) * time.Second,
 		WorkerShutdownTime: time.Duration(cfg.Processor.WorkerShutdownSeconds) * time.Second,
+		ImmutableArtifacts: cfg.Providers.ImmutableArtifacts,
+		ReindexFromStorage: cfg.Providers.ReindexFromStorage,
+		VerifyReindex:      cfg.Providers.ReindexVerifyShasum,
+		MaxDownloadSize:    cfg.Features.GetMaxDownloadSize(),
+		StoreShasums:       cfg.Providers.StoreShasums,
+		DedupModules:       cfg.Modules.DeduplicateArchives,
 	}
-	// Default hostname for provider storage keys
+	// Providers are downloaded from, and stored under, the upstream registry
+	// serving their namespace
+	upstreams := provider.NewUpstreamRouter(cfg.Providers.Upstreams)
+	upstreams.SetMaxDownloadSize(cfg.Features.GetMaxDownloadSize())
+	upstreams.SetSignatureVerification(cfg.Providers.GPGVerificationEnabled)
+
+	// Default hostname for storage keys
 	hostname := "registry.terraform.io"
 	processorService := processor.NewService(processorConfig, db, storageBackend, hostname)
+	processorService.SetUpstreams(upstreams)
+	if m != nil {
+		processorService.SetMetrics(m)
+	}
 
 	// Create auto-download service if enabled
 	var autoDownloadSvc *provider.AutoDownloadService
@@ -83,6 +154,8 @@ func NewWithCache(cfg *config.Config, db *database.DB, storageBackend storage.St
 			storageBackend,
 			db,
 		)
+		autoDownloadSvc.SetUpstreams(upstreams)
+		autoDownloadSvc.SetMetrics(m)
 		log.Printf("Auto-download enabled: rate limit %d/min, max concurrent %d",
 			cfg.AutoDownload.RateLimitPerMinute, cfg.AutoDownload.MaxConcurrentDL)
 	}
@@ -96,21 +169,50 @@ func NewWithCache(cfg *config.Config, db *database.DB, storageBackend storage.St
 			storageBackend,
 			db,
 		)
+		moduleAutoDownloadSvc.SetMaxDownloadSize(cfg.Features.GetMaxDownloadSize())
 		log.Printf("Module auto-download enabled: rate limit %d/min, max concurrent %d",
 			cfg.AutoDownloadModules.RateLimitPerMinute, cfg.AutoDownloadModules.MaxConcurrentDL)
 	}
 
+	// Create the scheduled upstream re-sync if enabled
+	var syncScheduler *processor.SyncScheduler
+	if cfg.Sync.IsEnabled() {
+		sched, err := schedule.Parse(cfg.Sync.GetSchedule())
+		if err != nil {
+			log.Printf("Warning: upstream sync disabled: %v", err)
+		} else {
+			syncConfig := processor.SyncConfig{
+				Schedule:  sched,
+				Providers: cfg.Sync.SyncProviders(),
+				Modules:   cfg.Sync.SyncModules(),
+				Platforms: cfg.Providers.GetPlatforms(),
+			}
+			if cfg.Quota.Enabled {
+				syncConfig.MaxStorageBytes = int64(cfg.Quota.MaxStorageGB) << 30
+			}
+			// Re-sync honors the same allow/block lists as auto-download
+			if autoDownloadSvc != nil {
+				syncConfig.ProviderAllowed = autoDownloadSvc.IsProviderAllowed
+			} else if cfg.AutoDownload != nil {
+				syncConfig.ProviderAllowed = cfg.AutoDownload.IsProviderAllowed
+			}
+			if cfg.AutoDownloadModules != nil {
+				syncConfig.ModuleNamespaceAllowed = cfg.AutoDownloadModules.IsNamespaceAllowed
+			}
+			syncScheduler = processor.NewSyncScheduler(syncConfig, db, cfg.Modules.GetUpstreamRegistry())
+			syncScheduler.SetProviderRegistry(upstreams)
+		}
+	}
+
 	// Use NoOp cache if none provided
 	if c == nil {
 		c = cache.NewNoOpCache()
 	}
 
-	// Initialize metrics if telemetry is enabled
-	var m *metrics.Metrics
-	if cfg.Telemetry.Enabled {
-		m = metrics.New()
-		log.Printf("Telemetry enabled: metrics available at /metrics")
-	}
+	downloadLimiter := newDownloadLimiter(
+		cfg.Server.MaxConcurrentDownloads,
+		time.Duration(cfg.Server.DownloadQueueTimeoutSeconds)*time.Second,
+	)
 
 	s := &Server{
 		config:                    cfg,
@@ -119,14 +221,26 @@ func NewWithCache(cfg *config.Config, db *database.DB, storageBackend storage.St
 		cache:                     c,
 		logger:                    log.Default(),
 		metrics:                   m,
+		downloadLimiter:           downloadLimiter,
 		authService:               authService,
 		processorService:          processorService,
+		syncScheduler:             syncScheduler,
 		autoDownloadService:       autoDownloadSvc,
+		providerRegistry:          upstreams,
+		providerMetadata:          upstreams,
+		providerPlatforms:         upstreams,
+		registryPinger:            upstreams,
+		upstreams:                 upstreams,
 		moduleAutoDownloadService: moduleAutoDownloadSvc,
 		providerRepo:              database.NewProviderRepository(db),
 		moduleRepo:                database.NewModuleRepository(db),
 		jobRepo:                   database.NewJobRepository(db),
 		auditRepo:                 database.NewAuditRepository(db),
+		labelRepo:                 database.NewLabelRepository(db),
+		searchRepo:                database.NewSearchRepository(db),
+		aliasRepo:                 database.NewProviderAliasRepository(db),
+		idempotencyRepo:           database.NewIdempotencyRepository(db),
+		backupFunc:                db.Backup,
 	}
 
 	s.setupRouter()
@@ -139,29 +253,48 @@ func (s *Server) setupRouter() {
 
 	// Standard middleware
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	// Without a public URL, local blob URLs follow the host the client used.
+	// This needs the original peer address, so it runs before realIPMiddleware.
+	if s.config.Storage.Type == "local" && s.config.Server.PublicURL == "" {
+		r.Use(requestBaseURLMiddleware(s.config.Server.TrustedProxies))
+	}
+	r.Use(realIPMiddleware(s.config.Server.TrustedProxies))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
-	// Set a timeout for all requests
-	r.Use(middleware.Timeout(60 * time.Second))
-
 	// CORS middleware (if behind proxy)
 	if s.config.Server.BehindProxy {
 		r.Use(corsMiddleware(s.config.Server.TrustedProxies))
 	}
 
+	// Timeouts are applied per route group: short for admin JSON and protocol
+	// metadata, long for uploads and bulk operations, and none for blob
+	// downloads, which stream for as long as the archive takes
+	shortTimeout := requestTimeoutMiddleware(s.config.Server.GetRequestTimeout())
+	longTimeout := requestTimeoutMiddleware(s.config.Server.GetLongRequestTimeout())
+	providerMetadataTimeout := requestTimeoutMiddleware(s.metadataTimeout(
+		s.autoDownloadService != nil && s.autoDownloadService.IsEnabled()))
+	moduleMetadataTimeout := requestTimeoutMiddleware(s.metadataTimeout(
+		s.moduleAutoDownloadService != nil && s.moduleAutoDownloadService.IsEnabled()))
+
 	// Health check endpoint (no auth required)
-	r.Get("/health", s.handleHealth)
+	r.With(shortTimeout).Get("/health", s.handleHealth)
 
 	// Terraform Provider Network Mirror Protocol endpoints
 	r.Route("/.well-known", func(r chi.Router) {
+		r.Use(shortTimeout)
 		r.Get("/terraform.json", s.handleServiceDiscovery)
 	})
 
 	// Blob download endpoint for local storage (public, no auth)
-	// This serves provider files when using local storage instead of S3
-	r.Get("/blobs/*", s.handleBlobDownload)
+	// This serves provider files when using local storage instead of S3.
+	// It has no request timeout so large archives are never cut off.
+	r.With(s.blobDrain.Middleware, s.downloadLimiter.Middleware).Get("/blobs/*", s.handleBlobDownload)
+	// HEAD only reads the object size, so it takes no download slot
+	r.Head("/blobs/*", s.handleBlobHead)
+
+	// Admin UI and API are restricted to the configured source ranges
+	adminAccess := adminAllowlistMiddleware(s.config.Server.AdminAllowedCIDRs)
 
 	// Admin UI static files - served from web/dist directory
 	// Must be before the catch-all route
@@ -169,6 +302,7 @@ func (s *Server) setupRouter() {
 	if webDir != "" {
 		log.Printf("Serving admin UI from: %s", webDir)
 		r.Route("/admin", func(r chi.Router) {
+			r.Use(adminAccess, shortTimeout)
 			r.Get("/*", s.serveAdminUI(webDir))
 		})
 	} else {
@@ -177,81 +311,182 @@ func (s *Server) setupRouter() {
 
 	// Metrics endpoint (if telemetry is enabled) - must be before catch-all
 	if s.config.Telemetry.Enabled {
-		r.Get("/metrics", s.handleMetrics)
+		r.With(shortTimeout).Get("/metrics", s.handleMetrics)
 	}
 
 	// Public API endpoints (no authentication required)
 	// These are for browsing providers and modules without logging in
 	r.Route("/api/public", func(r chi.Router) {
+		r.Use(shortTimeout)
 		r.Get("/providers", s.handlePublicListProviders)
 		r.Get("/modules", s.handlePublicListModules)
 	})
 
 	// Module Registry Protocol endpoints (public, no auth)
+	// Mounted at the advertised modules.v1 path (default /v1/modules)
 	// Pattern: /v1/modules/{namespace}/{name}/{system}/versions
 	// Pattern: /v1/modules/{namespace}/{name}/{system}/{version}/download
-	r.Route("/v1/modules", func(r chi.Router) {
-		r.Get("/{namespace}/{name}/{system}/versions", s.handleModuleVersions)
-		r.Get("/{namespace}/{name}/{system}/{version}/download", s.handleModuleDownload)
+	if s.config.Discovery.IsModulesEnabled() {
+		r.Route(strings.TrimSuffix(s.config.Discovery.GetModulesPath(), "/"), func(r chi.Router) {
+			r.Use(moduleMetadataTimeout)
+			r.Get("/{namespace}/{name}/{system}/versions", s.handleModuleVersions)
+			r.Get("/{namespace}/{name}/{system}/{version}/download", s.handleModuleDownload)
+		})
+	}
+
+	// Provider Registry Protocol endpoints (public, no auth)
+	// Mounted at the advertised providers.v1 path (default /v1/providers)
+	// Pattern: /v1/providers/{namespace}/{type}/versions
+	// Pattern: /v1/providers/{namespace}/{type}/{version}/download/{os}/{arch}
+	r.Route(strings.TrimSuffix(s.config.Discovery.GetProvidersPath(), "/"), func(r chi.Router) {
+		r.Use(providerMetadataTimeout)
+		r.Get("/{namespace}/{type}/versions", s.handleProviderRegistryVersions)
+		r.Get("/{namespace}/{type}/{version}/download/{os}/{arch}", s.handleProviderRegistryDownload)
+		r.Get("/{namespace}/{type}/{version}/SHA256SUMS", s.handleProviderRegistryShasums)
 	})
 
 	// Provider Network Mirror Protocol endpoints (public, no auth)
 	// Pattern: /{hostname}/{namespace}/{type}/index.json
 	// Pattern: /{hostname}/{namespace}/{type}/{version}.json
-	r.Get("/*", s.handleMirrorCatchAll)
+	// Pattern: /{hostname}/{namespace}/{type}/{version}/{os}/{arch}.zip
+	r.With(providerMetadataTimeout).Get("/*", s.handleMirrorCatchAll)
+	// HEAD builds the same document and drops the body
+	r.With(providerMetadataTimeout).Head("/*", headOnly(s.handleMirrorCatchAll))
 
 	// Admin API endpoints (authentication required)
 	r.Route("/admin/api", func(r chi.Router) {
+		r.Use(adminAccess)
+
 		// Authentication endpoints (no auth required)
-		r.Post("/login", s.handleLogin)
-		r.Post("/logout", s.handleLogout)
+		r.With(shortTimeout).Post("/login", s.handleLogin)
+		r.With(shortTimeout).Post("/logout", s.handleLogout)
 
 		// Protected routes (authentication required)
 		r.Group(func(r chi.Router) {
 			r.Use(s.authMiddleware)
 
-			// Provider management
-			r.Post("/providers/load", s.handleLoadProviders)
-			r.Get("/providers", s.handleListProviders)
-			r.Post("/providers", s.handleUploadProvider)
-			r.Get("/providers/{id}", s.handleGetProvider)
-			r.Put("/providers/{id}", s.handleUpdateProvider)
-			r.Delete("/providers/{id}", s.handleDeleteProvider)
-
-			// Module management
-			r.Post("/modules/load", s.handleLoadModules)
-			r.Get("/modules", s.handleListModules)
-			r.Get("/modules/{id}", s.handleGetModule)
-			r.Put("/modules/{id}", s.handleUpdateModule)
-			r.Delete("/modules/{id}", s.handleDeleteModule)
-
-			// Job management
-			r.Get("/jobs", s.handleListJobs)
-			r.Get("/jobs/{id}", s.handleGetJob)
-			r.Post("/jobs/{id}/retry", s.handleRetryJob)
-			r.Post("/jobs/{id}/cancel", s.handleCancelJob)
-
-			// Processor status
-			r.Get("/processor/status", s.handleProcessorStatus)
-
-			// Statistics
-			r.Get("/stats/storage", s.handleStorageStats)
-			r.Get("/stats/audit", s.handleAuditLogs)
-			r.Get("/stats/cache", s.handleCacheStats)
-			r.Post("/stats/recalculate", s.handleRecalculateStats)
-			r.Post("/stats/cache/clear", s.handleClearCache)
-
-			// Configuration
-			r.Get("/config", s.handleGetConfig)
-
-			// Backup
-			r.Post("/backup", s.handleTriggerBackup)
+			// Uploads, bulk operations and full scans, which can legitimately
+			// run for minutes
+			r.Group(func(r chi.Router) {
+				r.Use(longTimeout)
+
+				r.Post("/providers/load", s.handleLoadProviders)
+				r.Post("/providers/mirror-all", s.handleMirrorAllProvider)
+				r.Post("/providers/from-lockfile", s.handleProvidersFromLockfile)
+				r.Post("/providers/backfill-platform", s.handleBackfillPlatform)
+				r.Post("/providers/verify-integrity", s.handleVerifyIntegrity)
+				r.Post("/providers", s.handleUploadProvider)
+				r.Post("/providers/{namespace}/{type}/{version}/platforms/fill", s.handleFillProviderPlatforms)
+				r.Post("/providers/{namespace}/{type}/{version}/verify-install", s.handleVerifyProviderInstall)
+				r.Delete("/providers/{namespace}/{type}", s.handleDeleteAllProviderVersions)
+				r.Post("/modules/load", s.handleLoadModules)
+				r.Post("/modules/upload", s.handleUploadModule)
+				r.Post("/modules/rewrite", s.handleRewriteModules)
+				r.Post("/modules/{id}/rewrite", s.handleRewriteModule)
+				r.Delete("/modules/{namespace}/{name}/{system}", s.handleDeleteAllModuleVersions)
+				r.Post("/stats/recalculate", s.handleRecalculateStats)
+				r.Get("/storage/verify", s.handleStorageVerify)
+				r.Get("/export", s.handleExport)
+				r.Get("/stats/audit/export", s.handleExportAuditLogs)
+				r.Post("/import", s.handleImport)
+				r.Post("/backup", s.handleTriggerBackup)
+			})
+
+			// Everything else is a quick JSON call
+			r.Group(func(r chi.Router) {
+				r.Use(shortTimeout)
+
+				// Provider management
+				r.Get("/providers", s.handleListProviders)
+				r.Get("/providers/unverified", s.handleListUnverifiedProviders)
+				r.Get("/providers/{id}", s.handleGetProvider)
+				r.Put("/providers/{id}", s.handleUpdateProvider)
+				r.Delete("/providers/{id}", s.handleDeleteProvider)
+				r.Post("/providers/block-version", s.handleBlockProviderVersion)
+				r.Post("/providers/unblock-version", s.handleUnblockProviderVersion)
+				r.Post("/providers/{id}/refresh-metadata", s.handleRefreshProviderMetadata)
+				r.Post("/providers/{id}/labels", s.handleAddProviderLabels)
+				r.Delete("/providers/{id}/labels/{label}", s.handleRemoveProviderLabel)
+				r.Get("/providers/{namespace}/{type}/{version}/platforms", s.handleProviderPlatforms)
+
+				// Provider aliases
+				r.Get("/provider-aliases", s.handleListProviderAliases)
+				r.Post("/provider-aliases", s.handleCreateProviderAlias)
+				r.Delete("/provider-aliases/{id}", s.handleDeleteProviderAlias)
+
+				// Module management
+				r.Get("/modules", s.handleListModules)
+				r.Get("/modules/{id}", s.handleGetModule)
+				r.Put("/modules/{id}", s.handleUpdateModule)
+				r.Delete("/modules/{id}", s.handleDeleteModule)
+				r.Delete("/modules/{namespace}/{name}/{system}/{version}", s.handleDeleteModuleVersion)
+				r.Post("/modules/{id}/labels", s.handleAddModuleLabels)
+				r.Delete("/modules/{id}/labels/{label}", s.handleRemoveModuleLabel)
+
+				// Search across providers and modules
+				r.Get("/search", s.handleSearch)
+
+				// Job management
+				r.Get("/jobs", s.handleListJobs)
+				r.Post("/jobs/cancel-all", s.handleCancelAllJobs)
+				r.Get("/jobs/{id}", s.handleGetJob)
+				r.Get("/jobs/{id}/items/{itemId}", s.handleGetJobItem)
+				r.Post("/jobs/{id}/retry", s.handleRetryJob)
+				r.Post("/jobs/{id}/cancel", s.handleCancelJob)
+
+				// Processor status and runtime configuration
+				r.Get("/processor/status", s.handleProcessorStatus)
+				r.Get("/processor/config", s.handleGetProcessorConfig)
+				r.Put("/processor/config", s.handleUpdateProcessorConfig)
+
+				// Statistics
+				r.Get("/stats/storage", s.handleStorageStats)
+				r.Get("/stats/audit", s.handleAuditLogs)
+				r.Get("/stats/failures", s.handleFailureStats)
+				r.Get("/stats/cache", s.handleCacheStats)
+				r.Post("/stats/cache/clear", s.handleClearCache)
+
+				// Cache inspection
+				r.Get("/cache/entry", s.handleCacheEntry)
+				r.Get("/cache/keys", s.handleCacheKeys)
+
+				// Configuration
+				r.Get("/config", s.handleGetConfig)
+				r.Get("/config/hcl", s.handleGetConfigHCL)
+
+				// Subsystem self-test
+				r.Get("/diagnostics", s.handleDiagnostics)
+
+				// Aggregate snapshot for dashboards and chat integrations
+				r.Get("/status", s.handleMirrorStatus)
+
+				// Recent server logs
+				r.Get("/logs", s.handleGetLogs)
+
+				// Auto-download namespace lists (runtime, not persisted)
+				r.Get("/autodownload/namespaces", s.handleGetAutoDownloadNamespaces)
+				r.Put("/autodownload/namespaces", s.handleUpdateAutoDownloadNamespaces)
+
+				// In-flight auto-downloads, for unsticking coalesced requests
+				r.Get("/autodownload/inflight", s.handleGetAutoDownloadInFlight)
+				r.Post("/autodownload/inflight/clear", s.handleClearAutoDownloadInFlight)
+			})
 		})
 	})
 
 	s.router = r
 }
 
+// metadataTimeout returns the timeout for protocol metadata routes. A lookup
+// that can auto-download from upstream has to outlive the download, so it
+// gets the long timeout.
+func (s *Server) metadataTimeout(autoDownload bool) time.Duration {
+	if autoDownload {
+		return s.config.Server.GetLongRequestTimeout()
+	}
+	return s.config.Server.GetRequestTimeout()
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	// Start the background processor
@@ -259,14 +494,22 @@ func (s *Server) Start() error {
 		return fmt.Errorf("failed to start processor: %w", err)
 	}
 
+	// Start the scheduled upstream re-sync
+	if s.syncScheduler != nil {
+		if err := s.syncScheduler.Start(context.Background()); err != nil {
+			return fmt.Errorf("failed to start sync scheduler: %w", err)
+		}
+	}
+
 	addr := fmt.Sprintf(":%d", s.config.Server.Port)
 
 	s.server = &http.Server{
-		Addr:         addr,
-		Handler:      s.router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:    addr,
+		Handler: s.router,
+		// Only headers have a server-wide deadline; bodies and responses are
+		// bounded per route group so blob downloads can stream indefinitely
+		ReadHeaderTimeout: 15 * time.Second,
+		IdleTimeout:       60 * time.Second,
 	}
 
 	fmt.Printf("Starting server on %s\n", addr)
@@ -289,20 +532,48 @@ func (s *Server) Shutdown(ctx context.Context) error {
 
 	fmt.Println("Shutting down server...")
 
-	// Stop the processor first to prevent new job processing
+	// Stop the sync scheduler and processor first to prevent new job processing
+	if s.syncScheduler != nil {
+		if err := s.syncScheduler.Stop(); err != nil {
+			s.logger.Printf("Error stopping sync scheduler: %v", err)
+		}
+	}
 	if err := s.processorService.Stop(); err != nil {
 		s.logger.Printf("Error stopping processor: %v", err)
 	}
 
-	// Close the cache
+	// Shutdown the HTTP server. Large blob downloads may outlast ctx, so they
+	// are given up to the configured drain time to finish on their own.
+	err := s.server.Shutdown(ctx)
+	drainTimeout := time.Duration(s.config.Server.ShutdownDownloadWaitSeconds) * time.Second
+	if !s.blobDrain.drain(drainTimeout) {
+		s.logger.Printf("Blob downloads still in progress after %s; shutting down anyway", drainTimeout)
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		// The connections holding up the drain may have been those downloads
+		finishCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = s.server.Shutdown(finishCtx)
+		cancel()
+	}
+
+	// Stop background auto-downloads once no request can queue more
+	if s.autoDownloadService != nil {
+		s.autoDownloadService.Close()
+	}
+
+	// Close the cache once nothing is reading from it
 	if s.cache != nil {
 		if err := s.cache.Close(); err != nil {
 			s.logger.Printf("Error closing cache: %v", err)
 		}
 	}
 
-	// Shutdown the HTTP server
-	return s.server.Shutdown(ctx)
+	return err
+}
+
+// SetLogBuffer exposes recent log output through GET /admin/api/logs. The
+// caller is responsible for directing log output into the buffer.
+func (s *Server) SetLogBuffer(buf *logging.RingBuffer) {
+	s.logBuffer = buf
 }
 
 // Router returns the underlying Chi router (useful for testing)
@@ -377,33 +648,135 @@ func (s *Server) handleBlobDownload(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	started := time.Now()
+
+	contentType := blobContentType(key)
+
+	// Serve from cache when available
+	if cached, cachedType, found := s.cache.Get(r.Context(), key); found {
+		data, err := io.ReadAll(cached)
+		cached.Close()
+		if err == nil {
+			if cachedType != "" {
+				contentType = cachedType
+			}
+			s.logDownload(r, "blob", key, nil)
+			s.serveBlob(w, key, "cache", contentType, data, started)
+			return
+		}
+		s.logger.Printf("Failed to read cached blob %s, falling back to storage: %v", key, err)
+	}
 
-	// Download from storage
-	reader, err := s.storage.Download(r.Context(), key)
+	// Load from storage, sharing a single read among concurrent misses for
+	// the same key. The load is detached from the leading request's context so
+	// that one client disconnecting does not fail the others waiting on it.
+	v, err, _ := s.blobFlight.Do(key, func() (interface{}, error) {
+		return s.loadBlob(context.WithoutCancel(r.Context()), key, contentType)
+	})
 	if err != nil {
-		s.logger.Printf("Failed to download blob %s: %v", key, err)
-		http.NotFound(w, r)
+		// A blob storage reports deleted is gone, not temporarily unavailable
+		if errors.Is(err, errBlobNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		// Fall back to an expired cache entry, if stale serving allows it
+		if data, staleType, ok := s.staleBlob(r.Context(), key); ok {
+			s.logger.Printf("Serving stale cached blob %s after storage failure: %v", key, err)
+			s.logDownload(r, "blob", key, nil)
+			s.serveBlob(w, key, "stale", staleType, data, started)
+			return
+		}
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
 		return
 	}
+
+	result := v.(blobResult)
+	s.logDownload(r, "blob", key, nil)
+	s.serveBlob(w, key, "storage", result.contentType, result.data, started)
+}
+
+// blobContentType returns the content type of a blob read from storage,
+// based on its file extension. Cached blobs carry the type they were cached with.
+func blobContentType(key string) string {
+	if strings.HasSuffix(key, ".zip") {
+		return "application/zip"
+	}
+	return "application/octet-stream"
+}
+
+// errBlobNotFound indicates storage reported the blob does not exist
+var errBlobNotFound = errors.New("blob not found")
+
+// blobResult holds a blob loaded from storage
+type blobResult struct {
+	data        []byte
+	contentType string
+}
+
+// loadBlob reads a blob from storage and populates the cache with it
+func (s *Server) loadBlob(ctx context.Context, key, contentType string) (blobResult, error) {
+	reader, err := s.storage.Download(ctx, key)
+	if err != nil {
+		s.logger.Printf("Failed to download blob %s: %v", key, err)
+		if errors.Is(err, storage.ErrNotFound) {
+			return blobResult{}, errBlobNotFound
+		}
+		return blobResult{}, err
+	}
 	defer reader.Close()
 
 	// Read the data
 	data, err := io.ReadAll(reader)
 	if err != nil {
 		s.logger.Printf("Failed to read blob %s: %v", key, err)
-		http.Error(w, "Failed to read file", http.StatusInternalServerError)
-		return
+		return blobResult{}, err
 	}
 
-	// Set content type based on file extension
-	contentType := "application/octet-stream"
-	if strings.HasSuffix(key, ".zip") {
-		contentType = "application/zip"
+	// Populate the cache on a best-effort basis; a full or failing cache
+	// must not prevent serving a blob that was read from storage
+	if err := s.cache.Set(ctx, key, bytes.NewReader(data), contentType, int64(len(data)), s.config.Cache.GetBlobTTL()); err != nil {
+		s.logger.Printf("Failed to cache blob %s: %v", key, err)
 	}
 
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(key)))
+	return blobResult{data: data, contentType: contentType}, nil
+}
+
+// staleBlob returns a cached blob that expired within cache.max_stale_seconds,
+// when cache.serve_stale_on_error is enabled and the cache keeps stale entries
+func (s *Server) staleBlob(ctx context.Context, key string) ([]byte, string, bool) {
+	maxStale := s.config.Cache.GetMaxStale()
+	if maxStale <= 0 {
+		return nil, "", false
+	}
+	staleCache, ok := s.cache.(cache.StaleCache)
+	if !ok {
+		return nil, "", false
+	}
+
+	reader, contentType, found := staleCache.GetStale(ctx, key, maxStale)
+	if !found {
+		return nil, "", false
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		s.logger.Printf("Failed to read stale cached blob %s: %v", key, err)
+		return nil, "", false
+	}
+	return data, contentType, true
+}
+
+// writeBlob writes blob data as a file download response
+func (s *Server) writeBlob(w http.ResponseWriter, key, contentType string, data []byte) {
+	setBlobHeaders(w, key, contentType, int64(len(data)))
 	w.WriteHeader(http.StatusOK)
 	w.Write(data)
 }
+
+// setBlobHeaders sets the headers of a file download response
+func setBlobHeaders(w http.ResponseWriter, key, contentType string, size int64) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", size))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(key)))
+}
@@ -7,12 +7,16 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ned1313/terraform-mirror/internal/config"
 	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/metrics"
 	"github.com/ned1313/terraform-mirror/internal/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -168,6 +172,139 @@ func TestHandleServiceDiscovery(t *testing.T) {
 	require.NoError(t, err)
 
 	assert.Equal(t, "/v1/providers/", response.ProvidersV1)
+	assert.Equal(t, "/v1/modules/", response.ModulesV1)
+}
+
+func TestHandleServiceDiscovery_Configured(t *testing.T) {
+	tests := []struct {
+		name         string
+		discovery    *config.DiscoveryConfig
+		expectedKeys []string
+		expected     map[string]string
+	}{
+		{
+			name:         "defaults when unset",
+			discovery:    nil,
+			expectedKeys: []string{"providers.v1", "modules.v1"},
+			expected: map[string]string{
+				"providers.v1": "/v1/providers/",
+				"modules.v1":   "/v1/modules/",
+			},
+		},
+		{
+			name: "custom paths",
+			discovery: &config.DiscoveryConfig{
+				ProvidersPath: "/mirror/providers/",
+				ModulesPath:   "/mirror/modules/",
+			},
+			expectedKeys: []string{"providers.v1", "modules.v1"},
+			expected: map[string]string{
+				"providers.v1": "/mirror/providers/",
+				"modules.v1":   "/mirror/modules/",
+			},
+		},
+		{
+			name: "modules disabled",
+			discovery: &config.DiscoveryConfig{
+				DisableModules: true,
+			},
+			expectedKeys: []string{"providers.v1"},
+			expected: map[string]string{
+				"providers.v1": "/v1/providers/",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, cleanup := setupTestServer(t)
+			defer cleanup()
+
+			srv.config.Discovery = tt.discovery
+
+			req := httptest.NewRequest(http.MethodGet, "/.well-known/terraform.json", nil)
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]string
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+
+			keys := make([]string, 0, len(response))
+			for k := range response {
+				keys = append(keys, k)
+			}
+			assert.ElementsMatch(t, tt.expectedKeys, keys)
+			assert.Equal(t, tt.expected, response)
+			assert.NotContains(t, response, "login.v1")
+		})
+	}
+}
+
+func TestModuleRoutes_FollowDiscoveryConfig(t *testing.T) {
+	t.Run("custom modules path", func(t *testing.T) {
+		srv, cleanup := setupTestServer(t)
+		defer cleanup()
+
+		srv.config.Discovery = &config.DiscoveryConfig{ModulesPath: "/mirror/modules/"}
+		srv.setupRouter()
+
+		err := srv.moduleRepo.Create(context.Background(), &database.Module{
+			Namespace: "hashicorp",
+			Name:      "consul",
+			System:    "aws",
+			Version:   "0.1.0",
+			S3Key:     "modules/hashicorp/consul/aws/0.1.0/hashicorp-consul-aws-0.1.0.tar.gz",
+			Filename:  "hashicorp-consul-aws-0.1.0.tar.gz",
+		})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/mirror/modules/hashicorp/consul/aws/versions", nil)
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("modules disabled", func(t *testing.T) {
+		srv, cleanup := setupTestServer(t)
+		defer cleanup()
+
+		srv.config.Discovery = &config.DiscoveryConfig{DisableModules: true}
+		srv.setupRouter()
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/modules/hashicorp/consul/aws/versions", nil)
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestProviderRoutes_FollowDiscoveryConfig(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	srv.config.Discovery = &config.DiscoveryConfig{ProvidersPath: "/mirror/providers/"}
+	srv.setupRouter()
+	seedRegistryProviders(t, srv)
+
+	// The advertised path is the one the registry protocol is served at
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/terraform.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var discovery ServiceDiscoveryResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&discovery))
+	require.Equal(t, "/mirror/providers/", discovery.ProvidersV1)
+
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, discovery.ProvidersV1+"hashicorp/random/versions", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/providers/hashicorp/random/versions", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
 func TestHandleLogin_MissingBody(t *testing.T) {
@@ -183,6 +320,47 @@ func TestHandleLogin_MissingBody(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestHandleLogin_RecordsAuthMetrics(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	// Use a private registry so counts are not shared with other tests
+	m := metrics.NewWithRegistry(prometheus.NewRegistry())
+	srv.metrics = m
+
+	// Creates the "testadmin" user with one active session
+	createTestToken(t, srv)
+
+	login := func(password string) *httptest.ResponseRecorder {
+		body := `{"username":"testadmin","password":"` + password + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/admin/api/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := login("wrongpass")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.AuthAttempts.WithLabelValues("failure")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.AuthAttempts.WithLabelValues("success")))
+
+	w = login("testpass")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.AuthAttempts.WithLabelValues("success")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.ActiveSessions))
+
+	var resp LoginResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/api/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+resp.Token)
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.ActiveSessions))
+}
+
 func TestHandleListProviders_Success(t *testing.T) {
 	srv, cleanup := setupTestServer(t)
 	defer cleanup()
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+)
+
+const (
+	// verifyPageSize is the number of database records checked per query
+	verifyPageSize = 500
+
+	// defaultVerifyLimit is the default number of entries returned per report list
+	defaultVerifyLimit = 1000
+
+	// maxVerifyLimit is the maximum number of entries returned per report list
+	maxVerifyLimit = 10000
+)
+
+// StorageVerifyResponse reports drift between database records and storage objects
+type StorageVerifyResponse struct {
+	ProvidersChecked int64               `json:"providers_checked"`
+	ModulesChecked   int64               `json:"modules_checked"`
+	MissingCount     int64               `json:"missing_count"`
+	OrphanedCount    int64               `json:"orphaned_count"`
+	OrphansChecked   bool                `json:"orphans_checked"`
+	MissingObjects   []MissingObjectInfo `json:"missing_objects"`
+	OrphanedObjects  []string            `json:"orphaned_objects"`
+	Limit            int                 `json:"limit"`
+	Truncated        bool                `json:"truncated"`
+}
+
+// MissingObjectInfo describes a database record whose storage object is missing
+type MissingObjectInfo struct {
+	ResourceType string `json:"resource_type"`
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	Platform     string `json:"platform,omitempty"`
+	StorageKey   string `json:"storage_key"`
+	Error        string `json:"error,omitempty"`
+}
+
+// handleStorageVerify cross-checks provider and module records against storage
+// and reports records whose objects are missing. With orphans=true, storage
+// objects under providers/, modules/ and blobs/ that have no database record are also
+// reported. Counts always cover the full dataset; the lists are capped at limit.
+// GET /admin/api/storage/verify?orphans=true&limit=1000
+func (s *Server) handleStorageVerify(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := defaultVerifyLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= maxVerifyLimit {
+			limit = l
+		}
+	}
+	checkOrphans, _ := strconv.ParseBool(r.URL.Query().Get("orphans"))
+
+	report := &StorageVerifyResponse{
+		OrphansChecked:  checkOrphans,
+		MissingObjects:  []MissingObjectInfo{},
+		OrphanedObjects: []string{},
+		Limit:           limit,
+	}
+
+	// Keys referenced by the database, only tracked when looking for orphans
+	var knownKeys map[string]struct{}
+	if checkOrphans {
+		knownKeys = make(map[string]struct{})
+	}
+
+	addMissing := func(info MissingObjectInfo) {
+		report.MissingCount++
+		if len(report.MissingObjects) < limit {
+			report.MissingObjects = append(report.MissingObjects, info)
+		} else {
+			report.Truncated = true
+		}
+	}
+
+	// Check providers page by page
+	for offset := 0; ; offset += verifyPageSize {
+		providers, err := s.providerRepo.List(ctx, verifyPageSize, offset)
+		if err != nil {
+			log.Printf("Error listing providers for storage verification: %v", err)
+			respondError(w, http.StatusInternalServerError, "database_error", "Failed to list providers")
+			return
+		}
+
+		for _, p := range providers {
+			report.ProvidersChecked++
+			if knownKeys != nil {
+				knownKeys[p.S3Key] = struct{}{}
+			}
+			if errMsg, ok := s.verifyObjectExists(ctx, p.S3Key); !ok {
+				addMissing(MissingObjectInfo{
+					ResourceType: "provider",
+					ID:           p.ID,
+					Name:         p.Namespace + "/" + p.Type,
+					Version:      p.Version,
+					Platform:     p.Platform,
+					StorageKey:   p.S3Key,
+					Error:        errMsg,
+				})
+			}
+		}
+
+		if len(providers) < verifyPageSize {
+			break
+		}
+	}
+
+	// Check modules page by page
+	for offset := 0; ; offset += verifyPageSize {
+		modules, err := s.moduleRepo.List(ctx, database.ModuleFilter{}, verifyPageSize, offset)
+		if err != nil {
+			log.Printf("Error listing modules for storage verification: %v", err)
+			respondError(w, http.StatusInternalServerError, "database_error", "Failed to list modules")
+			return
+		}
+
+		for _, m := range modules {
+			report.ModulesChecked++
+			if knownKeys != nil {
+				knownKeys[m.S3Key] = struct{}{}
+			}
+			if errMsg, ok := s.verifyObjectExists(ctx, m.S3Key); !ok {
+				addMissing(MissingObjectInfo{
+					ResourceType: "module",
+					ID:           m.ID,
+					Name:         m.Namespace + "/" + m.Name + "/" + m.System,
+					Version:      m.Version,
+					StorageKey:   m.S3Key,
+					Error:        errMsg,
+				})
+			}
+		}
+
+		if len(modules) < verifyPageSize {
+			break
+		}
+	}
+
+	// Look for storage objects without a database record
+	if checkOrphans {
+		// Stored SHA256SUMS documents belong to their version's providers
+		shasumsKeys, err := s.providerRepo.ListShasumsKeys(ctx)
+		if err != nil {
+			log.Printf("Error listing SHA256SUMS documents for storage verification: %v", err)
+			respondError(w, http.StatusInternalServerError, "database_error", "Failed to list SHA256SUMS documents")
+			return
+		}
+		for _, key := range shasumsKeys {
+			knownKeys[key] = struct{}{}
+		}
+
+		// Walk the bucket rather than listing it, so only a page of keys is
+		// held in memory however many objects the mirror stores
+		for _, prefix := range []string{"providers/", "modules/", "blobs/"} {
+			err := s.storage.WalkObjects(ctx, prefix, func(key string) error {
+				if _, ok := knownKeys[key]; ok {
+					return nil
+				}
+				report.OrphanedCount++
+				if len(report.OrphanedObjects) < limit {
+					report.OrphanedObjects = append(report.OrphanedObjects, key)
+				} else {
+					report.Truncated = true
+				}
+				return nil
+			})
+			if err != nil {
+				log.Printf("Error listing storage objects with prefix %s: %v", prefix, err)
+				respondError(w, http.StatusInternalServerError, "storage_error", "Failed to list storage objects")
+				return
+			}
+		}
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// verifyObjectExists reports whether the object exists in storage. When the
+// check itself fails the object is treated as missing and the error returned.
+func (s *Server) verifyObjectExists(ctx context.Context, key string) (string, bool) {
+	exists, err := s.storage.Exists(ctx, key)
+	if err != nil {
+		return err.Error(), false
+	}
+	return "", exists
+}
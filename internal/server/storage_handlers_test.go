@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+	"github.com/ned1313/terraform-mirror/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleStorageVerify(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	store := srv.storage.(*storage.MetricsStorage).Unwrap().(*storage.MockStorage)
+
+	// Provider with its object present
+	present := &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "aws",
+		Version:   "5.0.0",
+		Platform:  "linux_amd64",
+		Filename:  "terraform-provider-aws_5.0.0_linux_amd64.zip",
+		S3Key:     "providers/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64/terraform-provider-aws_5.0.0_linux_amd64.zip",
+	}
+	require.NoError(t, srv.providerRepo.Create(ctx, present))
+	store.SetData(present.S3Key, []byte("present"))
+
+	// Provider whose object is missing from storage
+	missing := &database.Provider{
+		Namespace: "hashicorp",
+		Type:      "aws",
+		Version:   "5.0.0",
+		Platform:  "darwin_arm64",
+		Filename:  "terraform-provider-aws_5.0.0_darwin_arm64.zip",
+		S3Key:     "providers/registry.terraform.io/hashicorp/aws/5.0.0/darwin_arm64/terraform-provider-aws_5.0.0_darwin_arm64.zip",
+	}
+	require.NoError(t, srv.providerRepo.Create(ctx, missing))
+
+	// Storage object with no database record
+	orphanKey := "modules/registry.terraform.io/acme/vpc/aws/1.0.0/acme-vpc-aws-1.0.0.tar.gz"
+	store.SetData(orphanKey, []byte("orphan"))
+
+	token := createTestToken(t, srv)
+
+	t.Run("reports missing and orphaned objects", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api/storage/verify?orphans=true", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+
+		srv.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var report StorageVerifyResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+
+		assert.Equal(t, int64(2), report.ProvidersChecked)
+		assert.Equal(t, int64(1), report.MissingCount)
+		require.Len(t, report.MissingObjects, 1)
+		assert.Equal(t, "provider", report.MissingObjects[0].ResourceType)
+		assert.Equal(t, missing.ID, report.MissingObjects[0].ID)
+		assert.Equal(t, missing.S3Key, report.MissingObjects[0].StorageKey)
+
+		assert.True(t, report.OrphansChecked)
+		assert.Equal(t, int64(1), report.OrphanedCount)
+		assert.Equal(t, []string{orphanKey}, report.OrphanedObjects)
+		assert.False(t, report.Truncated)
+	})
+
+	t.Run("skips orphan scan by default", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api/storage/verify", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+
+		srv.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var report StorageVerifyResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+
+		assert.Equal(t, int64(1), report.MissingCount)
+		assert.False(t, report.OrphansChecked)
+		assert.Empty(t, report.OrphanedObjects)
+	})
+
+	t.Run("requires authentication", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api/storage/verify", nil)
+		w := httptest.NewRecorder()
+
+		srv.router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
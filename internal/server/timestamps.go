@@ -0,0 +1,25 @@
+package server
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// formatTimestamp renders a time for API responses. Rows written with
+// time.Now() carry the server's local zone, so every timestamp is converted
+// to UTC to keep responses comparable across hosts.
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Timestamp is a time.Time that marshals as an RFC3339 UTC string. Response
+// structs use it instead of time.Time so nanoseconds and local offsets never
+// reach clients.
+type Timestamp struct {
+	time.Time
+}
+
+// MarshalJSON implements json.Marshaler
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(formatTimestamp(t.Time))
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ned1313/terraform-mirror/internal/database"
+)
+
+// assertUTCTimestamp checks a response timestamp is RFC3339 in UTC
+func assertUTCTimestamp(t *testing.T, name, value string) time.Time {
+	t.Helper()
+	assert.True(t, strings.HasSuffix(value, "Z"), "%s should be UTC: %s", name, value)
+	parsed, err := time.Parse(time.RFC3339, value)
+	require.NoError(t, err, "%s should be RFC3339", name)
+	return parsed
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	local := time.Date(2024, 3, 1, 9, 30, 15, 123456789, time.FixedZone("EST", -5*60*60))
+	assert.Equal(t, "2024-03-01T14:30:15Z", formatTimestamp(local))
+
+	data, err := json.Marshal(Timestamp{local})
+	require.NoError(t, err)
+	assert.Equal(t, `"2024-03-01T14:30:15Z"`, string(data))
+
+	var decoded Timestamp
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.True(t, decoded.Equal(local.Truncate(time.Second)))
+}
+
+func TestAPIResponses_UseUTCTimestamps(t *testing.T) {
+	server, cleanup := setupAdminTest(t)
+	defer cleanup()
+	ctx := context.Background()
+	token := getAuthToken(t, server)
+
+	get := func(path string, v interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		addAuthHeader(req, token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), v))
+	}
+
+	// A start time recorded in a non-UTC zone is reported as the same instant in UTC
+	started := time.Now().In(time.FixedZone("EST", -5*60*60)).Truncate(time.Second)
+	job := &database.DownloadJob{
+		SourceType: "hcl",
+		SourceData: "{}",
+		Status:     "running",
+		StartedAt:  sql.NullTime{Time: started, Valid: true},
+	}
+	require.NoError(t, server.jobRepo.Create(ctx, job))
+
+	t.Run("job", func(t *testing.T) {
+		var resp jobResponse
+		get(fmt.Sprintf("/admin/api/jobs/%d", job.ID), &resp)
+		assertUTCTimestamp(t, "created_at", resp.CreatedAt)
+		require.NotNil(t, resp.StartedAt)
+		assert.True(t, assertUTCTimestamp(t, "started_at", *resp.StartedAt).Equal(started))
+	})
+
+	t.Run("module", func(t *testing.T) {
+		m := &database.Module{
+			Namespace: "hashicorp",
+			Name:      "consul",
+			System:    "aws",
+			Version:   "0.1.0",
+			S3Key:     "modules/hashicorp/consul/aws/0.1.0.tar.gz",
+			Filename:  "0.1.0.tar.gz",
+		}
+		require.NoError(t, server.moduleRepo.Create(ctx, m))
+
+		var resp map[string]interface{}
+		get(fmt.Sprintf("/admin/api/modules/%d", m.ID), &resp)
+		for _, field := range []string{"created_at", "updated_at"} {
+			value, ok := resp[field].(string)
+			require.True(t, ok, "%s should be a string", field)
+			assertUTCTimestamp(t, field, value)
+		}
+	})
+
+	t.Run("audit", func(t *testing.T) {
+		require.NoError(t, server.auditRepo.Log(ctx, &database.AdminAction{
+			Action:       "test",
+			ResourceType: "job",
+			Success:      true,
+		}))
+
+		var resp AuditLogResponse
+		get("/admin/api/stats/audit?action=test", &resp)
+		require.NotEmpty(t, resp.Logs)
+		for _, entry := range resp.Logs {
+			assertUTCTimestamp(t, "created_at", entry.CreatedAt)
+		}
+	})
+}
@@ -46,6 +46,14 @@ func BuildProviderKey(hostname, namespace, providerType, version, os, arch, file
 		hostname, namespace, providerType, version, os, arch, filename)
 }
 
+// BuildProviderShasumsKey generates the S3 key for the SHA256SUMS document of
+// a provider version, named as in upstream releases
+// Format: providers/{hostname}/{namespace}/{type}/{version}/terraform-provider-{type}_{version}_SHA256SUMS
+func BuildProviderShasumsKey(hostname, namespace, providerType, version string) string {
+	return fmt.Sprintf("providers/%s/%s/%s/%s/terraform-provider-%s_%s_SHA256SUMS",
+		hostname, namespace, providerType, version, providerType, version)
+}
+
 // BuildModuleKey generates the S3 key for a module archive
 // Format: modules/{hostname}/{namespace}/{name}/{provider}/{version}/{filename}
 func BuildModuleKey(hostname, namespace, name, provider, version, filename string) string {
@@ -53,6 +61,13 @@ func BuildModuleKey(hostname, namespace, name, provider, version, filename strin
 		hostname, namespace, name, provider, version, filename)
 }
 
+// BuildModuleBlobKey generates the S3 key for a content-addressed module
+// archive, shared by every module version with the same content
+// Format: blobs/sha256/{hash}
+func BuildModuleBlobKey(hash string) string {
+	return fmt.Sprintf("blobs/sha256/%s", hash)
+}
+
 // BuildBackupKey generates the S3 key for a database backup
 // Format: backups/{timestamp}.db
 func BuildBackupKey(timestamp string) string {
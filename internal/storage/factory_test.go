@@ -140,6 +140,12 @@ func TestBuildProviderKey(t *testing.T) {
 	}
 }
 
+func TestBuildProviderShasumsKey(t *testing.T) {
+	assert.Equal(t,
+		"providers/registry.terraform.io/hashicorp/aws/5.31.0/terraform-provider-aws_5.31.0_SHA256SUMS",
+		BuildProviderShasumsKey("registry.terraform.io", "hashicorp", "aws", "5.31.0"))
+}
+
 func TestBuildModuleKey(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -178,6 +184,10 @@ func TestBuildModuleKey(t *testing.T) {
 	}
 }
 
+func TestBuildModuleBlobKey(t *testing.T) {
+	assert.Equal(t, "blobs/sha256/abc123", BuildModuleBlobKey("abc123"))
+}
+
 func TestBuildBackupKey(t *testing.T) {
 	timestamp := "2024-01-15T10-30-00Z"
 	expected := "backups/2024-01-15T10-30-00Z.db"
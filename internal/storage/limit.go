@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrSizeLimitExceeded is returned when a download grows past its size limit
+var ErrSizeLimitExceeded = errors.New("download size limit exceeded")
+
+// SizeLimitError reports a download larger than limit bytes. It matches
+// ErrSizeLimitExceeded with errors.Is.
+func SizeLimitError(limit int64) error {
+	return fmt.Errorf("%w: larger than %d bytes", ErrSizeLimitExceeded, limit)
+}
+
+// LimitReader returns a reader that reads at most limit bytes from r and then
+// fails with a SizeLimitError if r has more to give, so an oversized upstream
+// body is aborted rather than truncated. A limit of zero or less means
+// unlimited and returns r unchanged.
+func LimitReader(r io.Reader, limit int64) io.Reader {
+	if limit <= 0 {
+		return r
+	}
+	return &limitReader{r: r, limit: limit, remaining: limit}
+}
+
+// limitReader implements LimitReader
+type limitReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, SizeLimitError(l.limit)
+	}
+
+	// Read one byte past the limit to tell an exact fit from an overrun
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n - 1, SizeLimitError(l.limit)
+	}
+	return n, err
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		limit   int64
+		wantErr bool
+	}{
+		{name: "under the limit", content: "abc", limit: 4},
+		{name: "exactly the limit", content: "abcd", limit: 4},
+		{name: "over the limit", content: "abcde", limit: 4, wantErr: true},
+		{name: "unlimited", content: strings.Repeat("x", 1<<16), limit: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := io.ReadAll(LimitReader(strings.NewReader(tt.content), tt.limit))
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrSizeLimitExceeded)
+				assert.LessOrEqual(t, int64(len(data)), tt.limit)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.content, string(data))
+		})
+	}
+}
+
+func TestLimitReader_AbortsStreamingCopy(t *testing.T) {
+	// An endless upstream body must not be read past the limit
+	var dst bytes.Buffer
+	_, err := io.Copy(&dst, LimitReader(endless{}, 1000))
+	require.ErrorIs(t, err, ErrSizeLimitExceeded)
+	assert.Contains(t, err.Error(), "larger than 1000 bytes")
+	assert.LessOrEqual(t, dst.Len(), 1000)
+}
+
+// endless is a reader that never runs out
+type endless struct{}
+
+func (endless) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
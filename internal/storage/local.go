@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -106,7 +107,7 @@ func (l *LocalStorage) Download(ctx context.Context, key string) (io.ReadCloser,
 	file, err := os.Open(fullPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("object not found: %s", key)
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
 		}
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
@@ -166,8 +167,8 @@ func (l *LocalStorage) Exists(ctx context.Context, key string) (bool, error) {
 }
 
 // GetPresignedURL generates a download URL for local storage
-// Returns an HTTP URL via the /blobs/ endpoint if baseURL is configured,
-// otherwise falls back to file:// URL (which only works for local testing)
+// Returns an HTTP URL via the /blobs/ endpoint if baseURL is configured or
+// set on the context with WithBaseURL, otherwise falls back to file:// URL (which only works for local testing)
 func (l *LocalStorage) GetPresignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
 	if key == "" {
 		return "", fmt.Errorf("key cannot be empty")
@@ -186,11 +187,17 @@ func (l *LocalStorage) GetPresignedURL(ctx context.Context, key string, expirati
 		return "", fmt.Errorf("file not found: %w", err)
 	}
 
+	// A per-request base URL takes precedence over the configured one
+	baseURL := l.baseURL
+	if override := BaseURLFromContext(ctx); override != "" {
+		baseURL = override
+	}
+
 	// If baseURL is configured, return HTTP URL via blob endpoint
-	if l.baseURL != "" {
+	if baseURL != "" {
 		// Normalize key for URL (use forward slashes)
 		urlKey := strings.ReplaceAll(key, "\\", "/")
-		return fmt.Sprintf("%s/blobs/%s", strings.TrimSuffix(l.baseURL, "/"), urlKey), nil
+		return fmt.Sprintf("%s/blobs/%s", strings.TrimSuffix(baseURL, "/"), urlKey), nil
 	}
 
 	// Fall back to file:// URL (only works for local testing)
@@ -241,16 +248,31 @@ func (l *LocalStorage) GetMetadata(ctx context.Context, key string) (map[string]
 
 // ListObjects lists objects with a given prefix
 func (l *LocalStorage) ListObjects(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := l.WalkObjects(ctx, prefix, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// WalkObjects calls fn for each object with a given prefix as the directory
+// tree is read, so no list of keys is built up
+func (l *LocalStorage) WalkObjects(ctx context.Context, prefix string, fn func(key string) error) error {
 	// Sanitize prefix
 	prefix = filepath.Clean(prefix)
 	if strings.Contains(prefix, "..") {
-		return nil, fmt.Errorf("invalid prefix: contains directory traversal")
+		return fmt.Errorf("invalid prefix: contains directory traversal")
 	}
 
 	searchPath := filepath.Join(l.basePath, prefix)
-	var keys []string
 
-	err := filepath.Walk(searchPath, func(path string, info os.FileInfo, err error) error {
+	// fnErr keeps errors from fn apart from errors reading the tree
+	var fnErr error
+	err := filepath.WalkDir(searchPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			// Ignore errors for non-existent paths
 			if os.IsNotExist(err) {
@@ -258,9 +280,12 @@ func (l *LocalStorage) ListObjects(ctx context.Context, prefix string) ([]string
 			}
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		// Skip directories and metadata files
-		if info.IsDir() || strings.HasSuffix(path, ".metadata") {
+		if d.IsDir() || strings.HasSuffix(path, ".metadata") {
 			return nil
 		}
 
@@ -271,15 +296,21 @@ func (l *LocalStorage) ListObjects(ctx context.Context, prefix string) ([]string
 		}
 
 		// Convert to forward slashes for consistency
-		keys = append(keys, filepath.ToSlash(relPath))
+		if err := fn(filepath.ToSlash(relPath)); err != nil {
+			fnErr = err
+			return err
+		}
 		return nil
 	})
 
+	if fnErr != nil {
+		return fnErr
+	}
 	if err != nil && !os.IsNotExist(err) {
-		return nil, fmt.Errorf("failed to list objects: %w", err)
+		return fmt.Errorf("failed to list objects: %w", err)
 	}
 
-	return keys, nil
+	return nil
 }
 
 // GetObjectSize returns the size of an object in bytes
@@ -304,6 +335,73 @@ func (l *LocalStorage) GetObjectSize(ctx context.Context, key string) (int64, er
 	return info.Size(), nil
 }
 
+// Copy copies a file and its metadata to a new key in local storage
+func (l *LocalStorage) Copy(ctx context.Context, srcKey, dstKey string) error {
+	if srcKey == "" || dstKey == "" {
+		return fmt.Errorf("key cannot be empty")
+	}
+
+	// Sanitize keys
+	srcKey = filepath.Clean(srcKey)
+	dstKey = filepath.Clean(dstKey)
+	if strings.Contains(srcKey, "..") || strings.Contains(dstKey, "..") {
+		return fmt.Errorf("invalid key: contains directory traversal")
+	}
+
+	srcPath := filepath.Join(l.basePath, srcKey)
+	dstPath := filepath.Join(l.basePath, dstKey)
+	if srcPath == dstPath {
+		return nil
+	}
+
+	if err := copyFile(srcPath, dstPath); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("object not found: %s", srcKey)
+		}
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+
+	// Carry the metadata file over, or clear a stale one at the destination
+	if err := copyFile(srcPath+".metadata", dstPath+".metadata"); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to copy metadata: %w", err)
+		}
+		os.Remove(dstPath + ".metadata") // Ignore error
+	}
+
+	return nil
+}
+
+// copyFile copies src to dst, creating dst's parent directories. The content
+// is written to a temporary file and renamed so readers never see a partial copy.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), dst)
+}
+
 // Close closes any open connections (no-op for local storage)
 func (l *LocalStorage) Close() error {
 	return nil
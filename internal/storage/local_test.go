@@ -3,6 +3,8 @@ package storage
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -101,6 +103,7 @@ func TestLocalStorage_Download_NotFound(t *testing.T) {
 	_, err = storage.Download(ctx, "nonexistent.txt")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not found")
+	assert.ErrorIs(t, err, ErrNotFound)
 }
 
 func TestLocalStorage_Delete(t *testing.T) {
@@ -172,6 +175,26 @@ func TestLocalStorage_GetPresignedURL(t *testing.T) {
 	assert.Contains(t, url, "test/file.txt")
 }
 
+func TestLocalStorage_GetPresignedURL_BaseURL(t *testing.T) {
+	tempDir := t.TempDir()
+	storage, err := NewLocalStorage(LocalConfig{BasePath: tempDir, BaseURL: "http://localhost:8080/"})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	ctx := context.Background()
+	err = storage.Upload(ctx, "test/file.txt", bytes.NewReader([]byte("test")), "text/plain", nil)
+	require.NoError(t, err)
+
+	url, err := storage.GetPresignedURL(ctx, "test/file.txt", 1*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/blobs/test/file.txt", url)
+
+	// A base URL on the context overrides the configured one
+	url, err = storage.GetPresignedURL(WithBaseURL(ctx, "https://mirror.example.com"), "test/file.txt", 1*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "https://mirror.example.com/blobs/test/file.txt", url)
+}
+
 func TestLocalStorage_GetMetadata(t *testing.T) {
 	tempDir := t.TempDir()
 	storage, err := NewLocalStorage(LocalConfig{BasePath: tempDir})
@@ -266,6 +289,59 @@ func TestLocalStorage_ListObjects_EmptyPrefix(t *testing.T) {
 	assert.Contains(t, keys, "test.txt")
 }
 
+func TestLocalStorage_WalkObjects(t *testing.T) {
+	tempDir := t.TempDir()
+	storage, err := NewLocalStorage(LocalConfig{BasePath: tempDir})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	// Spread many objects over nested directories, with metadata sidecars
+	const count = 1500
+	want := make(map[string]bool, count)
+	for i := 0; i < count; i++ {
+		key := fmt.Sprintf("providers/ns%d/type%d/1.0.%d/file.zip", i%10, i%7, i)
+		require.NoError(t, storage.Upload(ctx, key, bytes.NewReader([]byte("test")), "application/zip",
+			map[string]string{"index": fmt.Sprint(i)}))
+		want[key] = true
+	}
+	require.NoError(t, storage.Upload(ctx, "modules/other.tar.gz", bytes.NewReader([]byte("test")), "application/gzip", nil))
+
+	// Every object under the prefix is visited exactly once
+	seen := make(map[string]bool, count)
+	err = storage.WalkObjects(ctx, "providers", func(key string) error {
+		assert.False(t, seen[key], "visited %s twice", key)
+		seen[key] = true
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, want, seen)
+
+	// An error from fn stops the walk and is returned as is
+	stop := errors.New("stop")
+	visited := 0
+	err = storage.WalkObjects(ctx, "providers", func(key string) error {
+		visited++
+		if visited == 10 {
+			return stop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 10, visited)
+
+	// A missing prefix has nothing to walk
+	err = storage.WalkObjects(ctx, "missing", func(key string) error {
+		t.Errorf("unexpected key %s", key)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	err = storage.WalkObjects(ctx, "../outside", func(key string) error { return nil })
+	assert.Error(t, err)
+}
+
 func TestLocalStorage_GetObjectSize(t *testing.T) {
 	tempDir := t.TempDir()
 	storage, err := NewLocalStorage(LocalConfig{BasePath: tempDir})
@@ -283,6 +359,66 @@ func TestLocalStorage_GetObjectSize(t *testing.T) {
 	assert.Equal(t, int64(len(content)), size)
 }
 
+func TestLocalStorage_Copy(t *testing.T) {
+	tempDir := t.TempDir()
+	storage, err := NewLocalStorage(LocalConfig{BasePath: tempDir})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	ctx := context.Background()
+	content := []byte("test content")
+
+	err = storage.Upload(ctx, "src/file.txt", bytes.NewReader(content), "text/plain", map[string]string{"foo": "bar"})
+	require.NoError(t, err)
+
+	err = storage.Copy(ctx, "src/file.txt", "dst/nested/file.txt")
+	require.NoError(t, err)
+
+	// Destination has the content and metadata
+	reader, err := storage.Download(ctx, "dst/nested/file.txt")
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	metadata, err := storage.GetMetadata(ctx, "dst/nested/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "bar", metadata["foo"])
+
+	// Source is left in place
+	exists, err := storage.Exists(ctx, "src/file.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// No temporary files are left behind
+	keys, err := storage.ListObjects(ctx, "dst")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dst/nested/file.txt"}, keys)
+}
+
+func TestLocalStorage_Copy_NotFound(t *testing.T) {
+	tempDir := t.TempDir()
+	storage, err := NewLocalStorage(LocalConfig{BasePath: tempDir})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	err = storage.Copy(context.Background(), "missing.txt", "dst.txt")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "object not found")
+}
+
+func TestLocalStorage_Copy_DirectoryTraversal(t *testing.T) {
+	tempDir := t.TempDir()
+	storage, err := NewLocalStorage(LocalConfig{BasePath: tempDir})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	err = storage.Copy(context.Background(), "file.txt", "../../../etc/passwd")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "directory traversal")
+}
+
 func TestNewLocalStorage_EmptyBasePath(t *testing.T) {
 	_, err := NewLocalStorage(LocalConfig{BasePath: ""})
 	assert.Error(t, err)
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"strconv"
+	"time"
+)
+
+// Object metadata keys written with every mirrored provider and module
+// archive. Together they describe the object without the database, so
+// external lifecycle policies can select on them and records can be rebuilt
+// from the bucket alone.
+const (
+	MetadataArtifact   = "artifact" // "provider" or "module"
+	MetadataNamespace  = "namespace"
+	MetadataType       = "type" // provider type
+	MetadataName       = "name" // module name
+	MetadataSystem     = "system"
+	MetadataVersion    = "version"
+	MetadataPlatform   = "platform"
+	MetadataFilename   = "filename"
+	MetadataShasum     = "shasum"
+	MetadataSourceURL  = "source-url"
+	MetadataMirroredAt = "mirrored-at" // RFC 3339, UTC
+	MetadataPinned     = "pinned"      // "true" when explicitly requested rather than auto-downloaded
+)
+
+// Artifact kinds stored under MetadataArtifact
+const (
+	ArtifactProvider = "provider"
+	ArtifactModule   = "module"
+)
+
+// ProviderMetadata returns the metadata for a provider archive upload.
+// pinned marks artifacts an operator asked for, as opposed to ones fetched
+// on demand by auto-download.
+func ProviderMetadata(namespace, providerType, version, platform, filename, shasum, sourceURL string, pinned bool) map[string]string {
+	metadata := map[string]string{
+		MetadataArtifact:  ArtifactProvider,
+		MetadataNamespace: namespace,
+		MetadataType:      providerType,
+		MetadataVersion:   version,
+		MetadataPlatform:  platform,
+		MetadataFilename:  filename,
+		MetadataShasum:    shasum,
+	}
+	addCommonMetadata(metadata, sourceURL, pinned)
+	return metadata
+}
+
+// ModuleMetadata returns the metadata for a module archive upload. sourceURL
+// is empty for modules imported directly rather than downloaded.
+func ModuleMetadata(namespace, name, system, version, filename, sourceURL string, pinned bool) map[string]string {
+	metadata := map[string]string{
+		MetadataArtifact:  ArtifactModule,
+		MetadataNamespace: namespace,
+		MetadataName:      name,
+		MetadataSystem:    system,
+		MetadataVersion:   version,
+		MetadataFilename:  filename,
+	}
+	addCommonMetadata(metadata, sourceURL, pinned)
+	return metadata
+}
+
+func addCommonMetadata(metadata map[string]string, sourceURL string, pinned bool) {
+	metadata[MetadataMirroredAt] = time.Now().UTC().Format(time.RFC3339)
+	metadata[MetadataPinned] = strconv.FormatBool(pinned)
+	if sourceURL != "" {
+		metadata[MetadataSourceURL] = sourceURL
+	}
+}
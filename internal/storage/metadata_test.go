@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderMetadata(t *testing.T) {
+	metadata := ProviderMetadata("hashicorp", "aws", "5.0.0", "linux_amd64",
+		"terraform-provider-aws_5.0.0_linux_amd64.zip", "abc123", "https://releases.example.com/aws.zip", false)
+
+	assert.Equal(t, ArtifactProvider, metadata[MetadataArtifact])
+	assert.Equal(t, "hashicorp", metadata[MetadataNamespace])
+	assert.Equal(t, "aws", metadata[MetadataType])
+	assert.Equal(t, "5.0.0", metadata[MetadataVersion])
+	assert.Equal(t, "linux_amd64", metadata[MetadataPlatform])
+	assert.Equal(t, "terraform-provider-aws_5.0.0_linux_amd64.zip", metadata[MetadataFilename])
+	assert.Equal(t, "abc123", metadata[MetadataShasum])
+	assert.Equal(t, "https://releases.example.com/aws.zip", metadata[MetadataSourceURL])
+	assert.Equal(t, "false", metadata[MetadataPinned])
+
+	mirroredAt, err := time.Parse(time.RFC3339, metadata[MetadataMirroredAt])
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), mirroredAt, time.Minute)
+}
+
+func TestModuleMetadata(t *testing.T) {
+	metadata := ModuleMetadata("hashicorp", "consul", "aws", "1.0.0", "hashicorp-consul-aws-1.0.0.tar.gz", "", true)
+
+	assert.Equal(t, ArtifactModule, metadata[MetadataArtifact])
+	assert.Equal(t, "consul", metadata[MetadataName])
+	assert.Equal(t, "aws", metadata[MetadataSystem])
+	assert.Equal(t, "true", metadata[MetadataPinned])
+	assert.NotContains(t, metadata, MetadataSourceURL)
+	assert.NotContains(t, metadata, MetadataType)
+}
+
+func TestMockStorage_MetadataRoundTrip(t *testing.T) {
+	store := NewMockStorage()
+	ctx := context.Background()
+
+	metadata := ModuleMetadata("hashicorp", "consul", "aws", "1.0.0", "consul.tar.gz", "", true)
+	require.NoError(t, store.Upload(ctx, "modules/consul.tar.gz", bytes.NewReader([]byte("x")), "application/gzip", metadata))
+	require.NoError(t, store.Copy(ctx, "modules/consul.tar.gz", "modules/copy.tar.gz"))
+
+	got, err := store.GetMetadata(ctx, "modules/copy.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, metadata, got)
+
+	require.NoError(t, store.Delete(ctx, "modules/copy.tar.gz"))
+	got, err = store.GetMetadata(ctx, "modules/copy.tar.gz")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"io"
+
+	"github.com/ned1313/terraform-mirror/internal/metrics"
+)
+
+// MetricsStorage wraps a Storage and records an operation metric for every
+// upload, download, delete, copy, and existence check
+type MetricsStorage struct {
+	Storage
+	metrics *metrics.Metrics
+}
+
+// WithMetrics wraps a storage backend so that its operations are recorded.
+// If m is nil the backend is returned unchanged.
+func WithMetrics(s Storage, m *metrics.Metrics) Storage {
+	if m == nil {
+		return s
+	}
+	return &MetricsStorage{Storage: s, metrics: m}
+}
+
+// Unwrap returns the underlying storage backend
+func (s *MetricsStorage) Unwrap() Storage {
+	return s.Storage
+}
+
+// record records the outcome of a storage operation
+func (s *MetricsStorage) record(operation string, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	s.metrics.RecordStorageOperation(operation, status)
+}
+
+// Upload uploads a file to storage and records the operation
+func (s *MetricsStorage) Upload(ctx context.Context, key string, reader io.Reader, contentType string, metadata map[string]string) error {
+	err := s.Storage.Upload(ctx, key, reader, contentType, metadata)
+	s.record("upload", err)
+	return err
+}
+
+// Download downloads a file from storage and records the operation
+func (s *MetricsStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	reader, err := s.Storage.Download(ctx, key)
+	s.record("download", err)
+	return reader, err
+}
+
+// Delete removes a file from storage and records the operation
+func (s *MetricsStorage) Delete(ctx context.Context, key string) error {
+	err := s.Storage.Delete(ctx, key)
+	s.record("delete", err)
+	return err
+}
+
+// Exists checks if a file exists in storage and records the operation
+func (s *MetricsStorage) Exists(ctx context.Context, key string) (bool, error) {
+	exists, err := s.Storage.Exists(ctx, key)
+	s.record("exists", err)
+	return exists, err
+}
+
+// Copy copies an object within storage and records the operation
+func (s *MetricsStorage) Copy(ctx context.Context, srcKey, dstKey string) error {
+	err := s.Storage.Copy(ctx, srcKey, dstKey)
+	s.record("copy", err)
+	return err
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ned1313/terraform-mirror/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMetrics_RecordsOperations(t *testing.T) {
+	m := metrics.NewWithRegistry(prometheus.NewRegistry())
+	mock := NewMockStorage()
+	store := WithMetrics(mock, m)
+	ctx := context.Background()
+
+	require.NoError(t, store.Upload(ctx, "test/file.txt", bytes.NewReader([]byte("content")), "text/plain", nil))
+	require.NoError(t, store.Delete(ctx, "test/file.txt"))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.StorageOperations.WithLabelValues("upload", "success")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.StorageOperations.WithLabelValues("delete", "success")))
+
+	// Failures are recorded with an error status
+	_, err := store.Download(ctx, "test/file.txt")
+	require.Error(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.StorageOperations.WithLabelValues("download", "error")))
+
+	// The wrapped backend is still reachable
+	assert.Same(t, mock, store.(*MetricsStorage).Unwrap())
+}
+
+func TestWithMetrics_NilMetrics(t *testing.T) {
+	mock := NewMockStorage()
+	assert.Same(t, mock, WithMetrics(mock, nil))
+}
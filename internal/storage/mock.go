@@ -2,7 +2,9 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"sort"
 	"time"
 )
 
@@ -10,6 +12,7 @@ import (
 // It provides a simple in-memory storage with configurable behavior
 type MockStorage struct {
 	data          map[string][]byte
+	metadata      map[string]map[string]string
 	PresignedBase string // Base URL for presigned URLs
 }
 
@@ -17,17 +20,19 @@ type MockStorage struct {
 func NewMockStorage() *MockStorage {
 	return &MockStorage{
 		data:          make(map[string][]byte),
+		metadata:      make(map[string]map[string]string),
 		PresignedBase: "http://example.com/storage",
 	}
 }
 
-// Upload stores data in memory
+// Upload stores data and its metadata in memory
 func (m *MockStorage) Upload(ctx context.Context, key string, reader io.Reader, contentType string, metadata map[string]string) error {
 	data, err := io.ReadAll(reader)
 	if err != nil {
 		return err
 	}
 	m.data[key] = data
+	m.metadata[key] = copyMetadata(metadata)
 	return nil
 }
 
@@ -35,7 +40,7 @@ func (m *MockStorage) Upload(ctx context.Context, key string, reader io.Reader,
 func (m *MockStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
 	data, ok := m.data[key]
 	if !ok {
-		return nil, io.EOF
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
 	}
 	return io.NopCloser(&sectionReader{data: data}), nil
 }
@@ -43,6 +48,7 @@ func (m *MockStorage) Download(ctx context.Context, key string) (io.ReadCloser,
 // Delete removes data from memory
 func (m *MockStorage) Delete(ctx context.Context, key string) error {
 	delete(m.data, key)
+	delete(m.metadata, key)
 	return nil
 }
 
@@ -57,9 +63,9 @@ func (m *MockStorage) GetPresignedURL(ctx context.Context, key string, expiratio
 	return m.PresignedBase + "/" + key, nil
 }
 
-// GetMetadata returns empty metadata
+// GetMetadata returns the metadata stored with the object
 func (m *MockStorage) GetMetadata(ctx context.Context, key string) (map[string]string, error) {
-	return make(map[string]string), nil
+	return copyMetadata(m.metadata[key]), nil
 }
 
 // ListObjects returns stored keys with prefix
@@ -73,6 +79,18 @@ func (m *MockStorage) ListObjects(ctx context.Context, prefix string) ([]string,
 	return keys, nil
 }
 
+// WalkObjects calls fn for each stored key with prefix, in sorted order
+func (m *MockStorage) WalkObjects(ctx context.Context, prefix string, fn func(key string) error) error {
+	keys, _ := m.ListObjects(ctx, prefix)
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetObjectSize returns the size of stored data
 func (m *MockStorage) GetObjectSize(ctx context.Context, key string) (int64, error) {
 	data, ok := m.data[key]
@@ -82,6 +100,17 @@ func (m *MockStorage) GetObjectSize(ctx context.Context, key string) (int64, err
 	return int64(len(data)), nil
 }
 
+// Copy duplicates stored data under a new key
+func (m *MockStorage) Copy(ctx context.Context, srcKey, dstKey string) error {
+	data, ok := m.data[srcKey]
+	if !ok {
+		return io.EOF
+	}
+	m.data[dstKey] = append([]byte(nil), data...)
+	m.metadata[dstKey] = copyMetadata(m.metadata[srcKey])
+	return nil
+}
+
 // Close does nothing for mock storage
 func (m *MockStorage) Close() error {
 	return nil
@@ -98,6 +127,15 @@ func (m *MockStorage) GetData(key string) ([]byte, bool) {
 	return data, ok
 }
 
+// copyMetadata returns a non-nil copy so callers can't mutate stored metadata
+func copyMetadata(metadata map[string]string) map[string]string {
+	out := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		out[k] = v
+	}
+	return out
+}
+
 // sectionReader helper for Download
 type sectionReader struct {
 	data []byte
@@ -46,7 +46,7 @@ func TestMockStorage_Download_NotFound(t *testing.T) {
 	ctx := context.Background()
 
 	_, err := storage.Download(ctx, "nonexistent.txt")
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrNotFound)
 }
 
 func TestMockStorage_Delete(t *testing.T) {
@@ -113,6 +113,23 @@ func TestMockStorage_ListObjects(t *testing.T) {
 	assert.Len(t, keys, 2)
 }
 
+func TestMockStorage_WalkObjects(t *testing.T) {
+	storage := NewMockStorage()
+	ctx := context.Background()
+
+	storage.SetData("prefix/file2.txt", []byte("test2"))
+	storage.SetData("prefix/file1.txt", []byte("test1"))
+	storage.SetData("other/file3.txt", []byte("test3"))
+
+	var keys []string
+	err := storage.WalkObjects(ctx, "prefix", func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prefix/file1.txt", "prefix/file2.txt"}, keys)
+}
+
 func TestMockStorage_GetObjectSize(t *testing.T) {
 	storage := NewMockStorage()
 	ctx := context.Background()
@@ -134,6 +151,20 @@ func TestMockStorage_GetObjectSize_NotFound(t *testing.T) {
 	assert.Equal(t, int64(0), size)
 }
 
+func TestStreamCopy(t *testing.T) {
+	storage := NewMockStorage()
+	ctx := context.Background()
+
+	storage.SetData("src.txt", []byte("test content"))
+
+	err := StreamCopy(ctx, storage, "src.txt", "dst.txt", "text/plain")
+	require.NoError(t, err)
+
+	data, ok := storage.GetData("dst.txt")
+	require.True(t, ok)
+	assert.Equal(t, []byte("test content"), data)
+}
+
 func TestMockStorage_Close(t *testing.T) {
 	storage := NewMockStorage()
 	err := storage.Close()
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -126,6 +128,10 @@ func (s *S3Storage) Download(ctx context.Context, key string) (io.ReadCloser, er
 	})
 
 	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
 		return nil, fmt.Errorf("failed to download object %s: %w", key, err)
 	}
 
@@ -214,7 +220,19 @@ func (s *S3Storage) GetMetadata(ctx context.Context, key string) (map[string]str
 // ListObjects lists objects with a given prefix
 func (s *S3Storage) ListObjects(ctx context.Context, prefix string) ([]string, error) {
 	var keys []string
+	err := s.WalkObjects(ctx, prefix, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
 
+// WalkObjects calls fn for each object with a given prefix, holding only
+// one ListObjectsV2 page of keys at a time
+func (s *S3Storage) WalkObjects(ctx context.Context, prefix string, fn func(key string) error) error {
 	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
 		Bucket: aws.String(s.bucket),
 		Prefix: aws.String(prefix),
@@ -223,17 +241,20 @@ func (s *S3Storage) ListObjects(ctx context.Context, prefix string) ([]string, e
 	for paginator.HasMorePages() {
 		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list objects with prefix %s: %w", prefix, err)
+			return fmt.Errorf("failed to list objects with prefix %s: %w", prefix, err)
 		}
 
 		for _, obj := range page.Contents {
-			if obj.Key != nil {
-				keys = append(keys, *obj.Key)
+			if obj.Key == nil {
+				continue
+			}
+			if err := fn(*obj.Key); err != nil {
+				return err
 			}
 		}
 	}
 
-	return keys, nil
+	return nil
 }
 
 // GetObjectSize returns the size of an object in bytes
@@ -258,6 +279,50 @@ func (s *S3Storage) GetObjectSize(ctx context.Context, key string) (int64, error
 	return *result.ContentLength, nil
 }
 
+// maxCopyObjectSize is the largest object S3 can copy in a single CopyObject call
+const maxCopyObjectSize = 5 << 30
+
+// Copy copies an object server-side with CopyObject, so the bytes never leave
+// S3. Objects too large for a single CopyObject are stream-copied instead.
+func (s *S3Storage) Copy(ctx context.Context, srcKey, dstKey string) error {
+	if srcKey == "" || dstKey == "" {
+		return fmt.Errorf("key cannot be empty")
+	}
+
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(srcKey),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy object %s: %w", srcKey, err)
+	}
+
+	if head.ContentLength != nil && *head.ContentLength > maxCopyObjectSize {
+		return StreamCopy(ctx, s, srcKey, dstKey, aws.ToString(head.ContentType))
+	}
+
+	_, err = s.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:            aws.String(s.bucket),
+		Key:               aws.String(dstKey),
+		CopySource:        aws.String(url.PathEscape(s.bucket) + "/" + escapeKey(srcKey)),
+		MetadataDirective: types.MetadataDirectiveCopy,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy object %s to %s: %w", srcKey, dstKey, err)
+	}
+
+	return nil
+}
+
+// escapeKey URL-encodes each segment of an object key for use in CopySource
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
 // Close closes any open connections (S3 client doesn't require explicit closing)
 func (s *S3Storage) Close() error {
 	// AWS SDK v2 clients don't require explicit closing
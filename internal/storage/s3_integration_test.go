@@ -6,6 +6,7 @@ package storage
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"testing"
 	"time"
@@ -207,6 +208,38 @@ func TestS3Integration_GetMetadata(t *testing.T) {
 	assert.Equal(t, "aws", retrieved["provider"])
 }
 
+func TestS3Integration_Copy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	storage := createMinIOStorage(t)
+	defer storage.Close()
+	ensureBucketExists(t, storage)
+
+	ctx := context.Background()
+	content := []byte("server-side copy content")
+
+	err := storage.Upload(ctx, "test/copy source.txt", bytes.NewReader(content), "text/plain", map[string]string{"version": "1.0.0"})
+	require.NoError(t, err)
+	defer storage.Delete(ctx, "test/copy source.txt")
+
+	err = storage.Copy(ctx, "test/copy source.txt", "test/copied/dest.txt")
+	require.NoError(t, err)
+	defer storage.Delete(ctx, "test/copied/dest.txt")
+
+	reader, err := storage.Download(ctx, "test/copied/dest.txt")
+	require.NoError(t, err)
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	metadata, err := storage.GetMetadata(ctx, "test/copied/dest.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", metadata["version"])
+}
+
 func TestS3Integration_ListObjects(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test")
@@ -249,6 +282,40 @@ func TestS3Integration_ListObjects(t *testing.T) {
 	assert.GreaterOrEqual(t, len(awsKeys), 2)
 }
 
+func TestS3Integration_WalkObjects(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	storage := createMinIOStorage(t)
+	defer storage.Close()
+	ensureBucketExists(t, storage)
+
+	ctx := context.Background()
+
+	// More objects than one ListObjectsV2 page holds
+	const count = 1100
+	want := make(map[string]bool, count)
+	for i := 0; i < count; i++ {
+		key := fmt.Sprintf("walk-test/%04d.zip", i)
+		require.NoError(t, storage.Upload(ctx, key, bytes.NewReader([]byte("test")), "application/zip", nil))
+		want[key] = true
+	}
+	defer func() {
+		for key := range want {
+			storage.Delete(ctx, key)
+		}
+	}()
+
+	seen := make(map[string]bool, count)
+	err := storage.WalkObjects(ctx, "walk-test/", func(key string) error {
+		seen[key] = true
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, want, seen)
+}
+
 func TestS3Integration_GetObjectSize(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test")
@@ -2,6 +2,10 @@ package storage
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"time"
 )
@@ -17,7 +21,8 @@ type Storage interface {
 
 	// Download downloads a file from storage
 	// key: the object key/path in storage
-	// Returns a ReadCloser that must be closed by the caller
+	// Returns a ReadCloser that must be closed by the caller, or an error
+	// wrapping ErrNotFound when the object does not exist
 	Download(ctx context.Context, key string) (io.ReadCloser, error)
 
 	// Delete removes a file from storage
@@ -39,13 +44,100 @@ type Storage interface {
 	// Returns a list of object keys
 	ListObjects(ctx context.Context, prefix string) ([]string, error)
 
+	// WalkObjects calls fn with the key of each object with a given prefix.
+	// Keys are fetched a page at a time, so walking a large bucket never
+	// holds every key in memory. The walk stops at the first error from fn,
+	// which WalkObjects returns unchanged.
+	WalkObjects(ctx context.Context, prefix string, fn func(key string) error) error
+
 	// GetObjectSize returns the size of an object in bytes
 	GetObjectSize(ctx context.Context, key string) (int64, error)
 
+	// Copy copies an object to a new key within the same backend, along with
+	// its metadata. Backends copy natively where possible so the bytes never
+	// pass through this process.
+	Copy(ctx context.Context, srcKey, dstKey string) error
+
 	// Close closes any open connections
 	Close() error
 }
 
+// ErrNotFound is wrapped by Download errors when the object does not exist,
+// as opposed to the backend failing to serve it
+var ErrNotFound = errors.New("object not found")
+
+// baseURLKey is the context key for a per-request base URL override
+type baseURLKey struct{}
+
+// WithBaseURL returns a context that makes local storage build download URLs
+// from baseURL instead of its configured base URL. The server uses it to
+// derive blob URLs from the request host when no public URL is configured.
+func WithBaseURL(ctx context.Context, baseURL string) context.Context {
+	return context.WithValue(ctx, baseURLKey{}, baseURL)
+}
+
+// BaseURLFromContext returns the base URL set by WithBaseURL, or "" if none
+func BaseURLFromContext(ctx context.Context) string {
+	baseURL, _ := ctx.Value(baseURLKey{}).(string)
+	return baseURL
+}
+
+// StreamCopy copies an object by downloading it and uploading the content
+// under dstKey. It is the fallback for backends without a native copy.
+func StreamCopy(ctx context.Context, s Storage, srcKey, dstKey string, contentType string) error {
+	if srcKey == "" || dstKey == "" {
+		return fmt.Errorf("key cannot be empty")
+	}
+
+	metadata, err := s.GetMetadata(ctx, srcKey)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata for %s: %w", srcKey, err)
+	}
+
+	reader, err := s.Download(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if err := s.Upload(ctx, dstKey, reader, contentType, metadata); err != nil {
+		return fmt.Errorf("failed to copy %s to %s: %w", srcKey, dstKey, err)
+	}
+
+	return nil
+}
+
+// ObjectShasum returns the hex SHA-256 of an object's content. The shasum
+// metadata written at upload is used when present; otherwise the object is
+// downloaded and hashed.
+func ObjectShasum(ctx context.Context, s Storage, key string) (string, error) {
+	metadata, err := s.GetMetadata(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read metadata for %s: %w", key, err)
+	}
+	if shasum := metadata[MetadataShasum]; shasum != "" {
+		return shasum, nil
+	}
+
+	return HashObject(ctx, s, key)
+}
+
+// HashObject streams an object from storage and returns the hex SHA-256 of
+// its content, ignoring any recorded metadata
+func HashObject(ctx context.Context, s Storage, key string) (string, error) {
+	reader, err := s.Download(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", key, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // ObjectInfo contains metadata about a stored object
 type ObjectInfo struct {
 	Key          string
@@ -35,8 +35,10 @@ export interface Provider {
   SizeBytes: number
   Deprecated: boolean
   Blocked: boolean
+  Verified: boolean
   CreatedAt: string
   UpdatedAt: string
+  upstream_url: string
 }
 
 export interface ProviderListResponse {
@@ -213,6 +215,7 @@ export interface Module {
   s3_key: string
   filename: string
   size_bytes: number
+  content_hash?: string
   original_source_url?: string
   deprecated: boolean
   blocked: boolean